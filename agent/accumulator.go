@@ -5,6 +5,7 @@ import (
 	"log"
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -33,6 +34,12 @@ type accumulator struct {
 	inputConfig *internal_models.InputConfig
 
 	prefix string
+
+	// precision rounds down the timestamps of the metrics if not zero
+	precision time.Duration
+
+	// count is the number of metrics added to the accumulator
+	count int64
 }
 
 func (ac *accumulator) Add(
@@ -51,6 +58,34 @@ func (ac *accumulator) AddFields(
 	fields map[string]interface{},
 	tags map[string]string,
 	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (ac *accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (ac *accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (ac *accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	valueType telegraf.ValueType,
+	t ...time.Time,
 ) {
 	if len(fields) == 0 || len(measurement) == 0 {
 		return
@@ -72,6 +107,10 @@ func (ac *accumulator) AddFields(
 		measurement = measurement + ac.inputConfig.MeasurementSuffix
 	}
 
+	if !ac.inputConfig.Filter.ShouldNamePass(measurement) {
+		return
+	}
+
 	if tags == nil {
 		tags = make(map[string]string)
 	}
@@ -87,12 +126,14 @@ func (ac *accumulator) AddFields(
 			tags[k] = v
 		}
 	}
+	ac.inputConfig.Filter.FilterTags(tags)
 
 	result := make(map[string]interface{})
 	for k, v := range fields {
 		// Filter out any filtered fields
 		if ac.inputConfig != nil {
-			if !ac.inputConfig.Filter.ShouldPass(k) {
+			if !ac.inputConfig.Filter.ShouldPass(k) ||
+				!ac.inputConfig.Filter.ShouldFieldsPass(k) {
 				continue
 			}
 		}
@@ -130,12 +171,16 @@ func (ac *accumulator) AddFields(
 	} else {
 		timestamp = time.Now()
 	}
+	if ac.precision > 0 {
+		timestamp = timestamp.Truncate(ac.precision)
+	}
 
 	if ac.prefix != "" {
 		measurement = ac.prefix + measurement
 	}
 
-	m, err := telegraf.NewMetric(measurement, tags, result, timestamp)
+	m, err := telegraf.NewTypedMetric(valueType, measurement, tags, result,
+		timestamp)
 	if err != nil {
 		log.Printf("Error adding point [%s]: %s\n", measurement, err.Error())
 		return
@@ -143,7 +188,43 @@ func (ac *accumulator) AddFields(
 	if ac.debug {
 		fmt.Println("> " + m.String())
 	}
-	ac.metrics <- m
+	atomic.AddInt64(&ac.count, 1)
+	ac.metrics <- &inputMetric{Metric: m, input: ac.inputConfig}
+}
+
+// inputMetric is a metric tagged with the input which gathered it, for the
+// agent to route it to the outputs subscribed to that input.
+type inputMetric struct {
+	telegraf.Metric
+	input *internal_models.InputConfig
+}
+
+// unwrapMetric returns the metric and the input which gathered it, nil if it
+// was not added by an accumulator.
+func unwrapMetric(
+	m telegraf.Metric,
+) (telegraf.Metric, *internal_models.InputConfig) {
+	if im, ok := m.(*inputMetric); ok {
+		return im.Metric, im.input
+	}
+	return m, nil
+}
+
+// wrapMetrics tags the metrics not tagged yet with the input, the metrics
+// created from a gathered one by a processor being tagged this way.
+func wrapMetrics(
+	metrics []telegraf.Metric,
+	input *internal_models.InputConfig,
+) []telegraf.Metric {
+	if input == nil {
+		return metrics
+	}
+	for i, m := range metrics {
+		if _, ok := m.(*inputMetric); !ok {
+			metrics[i] = &inputMetric{Metric: m, input: input}
+		}
+	}
+	return metrics
 }
 
 func (ac *accumulator) Debug() bool {
@@ -154,6 +235,12 @@ func (ac *accumulator) SetDebug(debug bool) {
 	ac.debug = debug
 }
 
+// SetPrecision sets the precision the timestamps of the metrics are rounded
+// down to, no rounding if zero.
+func (ac *accumulator) SetPrecision(precision time.Duration) {
+	ac.precision = precision
+}
+
 func (ac *accumulator) setDefaultTags(tags map[string]string) {
 	ac.defaultTags = tags
 }
@@ -2,72 +2,214 @@ package agent
 
 import (
 	cryptorand "crypto/rand"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
-	"math/rand"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/persister"
 )
 
+// warnAfterGathers is the number of consecutive slow or failed gathers of an
+// input after which a warning is logged
+const warnAfterGathers = 3
+
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// precision is the agent's timestamp precision
+	precision time.Duration
+
+	// gatherSlots limits the number of concurrent gathers if not nil, a gather
+	// holding a slot while it runs
+	gatherSlots chan struct{}
+
+	// persister keeps the state of the stateful plugins if a statefile is
+	// configured
+	persister *persister.Persister
+
+	// settings and tags are the agent settings and global tags of the config
+	// the agent was created with, which a reload can't change
+	settings config.AgentConfig
+	tags     map[string]string
+
+	// reloadC passes the configs to reload to Run
+	reloadC chan reloadRequest
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:   config,
+		settings: *config.Agent,
+		tags:     make(map[string]string),
+		reloadC:  make(chan reloadRequest),
 	}
+	for k, v := range config.Tags {
+		a.tags[k] = v
+	}
+
+	if !a.Config.Agent.OmitHostname {
+		if a.Config.Agent.Hostname == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return nil, err
+			}
+
+			a.Config.Agent.Hostname = hostname
+		}
 
-	if a.Config.Agent.Hostname == "" {
-		hostname, err := os.Hostname()
+		config.Tags["host"] = a.Config.Agent.Hostname
+	}
+
+	precision, err := internal.ParsePrecision(a.Config.Agent.Precision)
+	if err != nil {
+		return nil, err
+	}
+	a.precision = precision
+
+	if a.Config.Agent.MaxConcurrentGathers > 0 {
+		a.gatherSlots = make(chan struct{}, a.Config.Agent.MaxConcurrentGathers)
+	}
+
+	if a.Config.Agent.Statefile != "" {
+		p, err := newPersister(a.Config)
 		if err != nil {
 			return nil, err
 		}
-
-		a.Config.Agent.Hostname = hostname
+		a.persister = p
 	}
 
-	config.Tags["host"] = a.Config.Agent.Hostname
+	internal_models.SetRunning(config.Inputs, config.Outputs)
 
 	return a, nil
 }
 
+// newPersister returns a Persister keeping the state of the stateful plugins
+// of the config in its statefile.
+func newPersister(c *config.Config) (*persister.Persister, error) {
+	p := persister.NewPersister(c.Agent.Statefile)
+	register := func(id, name string, plugin interface{}) error {
+		if sp, ok := plugin.(telegraf.StatefulPlugin); ok {
+			if err := p.Register(id, sp); err != nil {
+				return fmt.Errorf("%s: %s, plugin instances keeping a state "+
+					"must have different options", name, err)
+			}
+		}
+		return nil
+	}
+
+	for _, input := range c.Inputs {
+		err := register(input.Config.ID, "inputs."+input.LogName(), input.Input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, processor := range c.Processors {
+		err := register(processor.Config.ID, "processors."+processor.LogName(),
+			processor.Processor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		err := register(aggregator.Config.ID,
+			"aggregators."+aggregator.LogName(), aggregator.Aggregator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, output := range c.Outputs {
+		err := register(output.Config.ID, "outputs."+output.LogName(),
+			output.Output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// loadState restores the state of the stateful plugins, to be called before
+// they are started.
+func (a *Agent) loadState() error {
+	if a.persister == nil {
+		return nil
+	}
+	if err := a.persister.Load(); err != nil {
+		log.Printf("ERROR: loading plugin states, exiting\n%s\n", err)
+		return err
+	}
+	return nil
+}
+
+// storeState saves the state of the stateful plugins, to be called once they
+// are stopped.
+func (a *Agent) storeState() {
+	if a.persister == nil {
+		return
+	}
+	if err := a.persister.Store(); err != nil {
+		log.Printf("ERROR: storing plugin states: %s\n", err)
+	}
+}
+
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
-	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
+	return a.connectOutputs(a.Config.Outputs)
+}
+
+// connectOutputs connects to the outputs, closing the ones already connected
+// if one fails to connect.
+func (a *Agent) connectOutputs(outputs []*internal_models.RunningOutput) error {
+	for i, o := range outputs {
+		if err := a.connectOutput(o); err != nil {
+			for _, connected := range outputs[:i] {
+				closeOutput(connected)
 			}
+			return err
 		}
+	}
+	return nil
+}
 
-		if a.Config.Agent.Debug {
-			log.Printf("Attempting connection to output: %s\n", o.Name)
+func (a *Agent) connectOutput(o *internal_models.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("Service for output %s failed to start, exiting\n%s\n",
+				o.LogName(), err.Error())
+			return err
 		}
-		err := o.Output.Connect()
+	}
+
+	if a.Config.Agent.Debug {
+		log.Printf("Attempting connection to output: %s\n", o.LogName())
+	}
+	err := o.Output.Connect()
+	if err != nil {
+		log.Printf("Failed to connect to output %s, retrying in 15s, error was '%s' \n", o.LogName(), err)
+		time.Sleep(15 * time.Second)
+		err = o.Output.Connect()
 		if err != nil {
-			log.Printf("Failed to connect to output %s, retrying in 15s, error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
+			if ot, ok := o.Output.(telegraf.ServiceOutput); ok {
+				ot.Stop()
 			}
+			return err
 		}
-		if a.Config.Agent.Debug {
-			log.Printf("Successfully connected to output: %s\n", o.Name)
-		}
+	}
+	if a.Config.Agent.Debug {
+		log.Printf("Successfully connected to output: %s\n", o.LogName())
 	}
 	return nil
 }
@@ -76,21 +218,28 @@ func (a *Agent) Connect() error {
 func (a *Agent) Close() error {
 	var err error
 	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+		err = closeOutput(o)
+	}
+	return err
+}
+
+// closeOutput closes the connection to the output and stops its service
+func closeOutput(o *internal_models.RunningOutput) error {
+	err := o.Output.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
 	}
 	return err
 }
 
 func panicRecover(input *internal_models.RunningInput) {
 	if err := recover(); err != nil {
+		input.GatherPanicked()
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
 		log.Printf("FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
-			input.Name, err, trace)
+			input.LogName(), err, trace)
 		log.Println("PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new")
@@ -99,12 +248,15 @@ func panicRecover(input *internal_models.RunningInput) {
 
 // gatherParallel runs the inputs that are using the same reporting interval
 // as the telegraf agent.
-func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
+func (a *Agent) gatherParallel(
+	shutdown chan struct{},
+	metricC chan telegraf.Metric,
+) error {
 	var wg sync.WaitGroup
 
 	start := time.Now()
 	counter := 0
-	jitter := a.Config.Agent.CollectionJitter.Duration.Nanoseconds()
+	jitter := a.Config.Agent.CollectionJitter.Duration
 	for _, input := range a.Config.Inputs {
 		if input.Config.Interval != 0 {
 			continue
@@ -113,28 +265,9 @@ func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
 		wg.Add(1)
 		counter++
 		go func(input *internal_models.RunningInput) {
-			defer panicRecover(input)
 			defer wg.Done()
-
-			acc := NewAccumulator(input.Config, metricC)
-			acc.SetDebug(a.Config.Agent.Debug)
-			acc.setDefaultTags(a.Config.Tags)
-
-			if jitter != 0 {
-				nanoSleep := rand.Int63n(jitter)
-				d, err := time.ParseDuration(fmt.Sprintf("%dns", nanoSleep))
-				if err != nil {
-					log.Printf("Jittering collection interval failed for plugin %s",
-						input.Name)
-				} else {
-					time.Sleep(d)
-				}
-			}
-
-			if err := input.Input.Gather(acc); err != nil {
-				log.Printf("Error in input [%s]: %s", input.Name, err)
-			}
-
+			internal.RandomSleep(jitter, shutdown)
+			a.gather(input, metricC, a.gatherTimeout(input))
 		}(input)
 	}
 
@@ -152,6 +285,159 @@ func (a *Agent) gatherParallel(metricC chan telegraf.Metric) error {
 	return nil
 }
 
+// gather runs a single collection of the input, once one of the
+// max_concurrent_gathers slots is free. If timeout is not zero, gather
+// returns once it elapses, the wait for a slot included, leaving the
+// collection running in the background, and the input is skipped until it
+// completes. The slot is held until the collection completes. A panic in the
+// input is recovered, so the input is collected again on the next interval.
+//
+// gather returns the error of the collection, or an error if it panicked or
+// did not complete in time. It returns nil if the input was skipped because
+// its previous collection is still running.
+func (a *Agent) gather(
+	input *internal_models.RunningInput,
+	metricC chan telegraf.Metric,
+	timeout time.Duration,
+) error {
+	if input.Disabled() {
+		return nil
+	}
+	if !input.StartGather() {
+		log.Printf("WARNING: input [%s] is still gathering metrics from a "+
+			"previous interval, skipping this interval\n", input.LogName())
+		return nil
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	if a.gatherSlots != nil {
+		select {
+		case a.gatherSlots <- struct{}{}:
+		case <-expired:
+			input.EndGather()
+			input.GatherTimedOut()
+			log.Printf("WARNING: input [%s] did not get to gather within %s, "+
+				"max_concurrent_gathers gathers are running\n",
+				input.LogName(), timeout)
+			err := fmt.Errorf("did not get to gather within %s", timeout)
+			a.checkFailures(input, true)
+			return err
+		}
+	}
+
+	// gatherErr is only read once done is closed. It is set before the
+	// gather, so that a panic fails the gather.
+	gatherErr := errors.New("input panicked")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if a.gatherSlots != nil {
+			defer func() { <-a.gatherSlots }()
+		}
+		defer input.EndGather()
+		defer panicRecover(input)
+
+		acc := NewAccumulator(input.Config, metricC)
+		acc.SetDebug(a.Config.Agent.Debug)
+		acc.setDefaultTags(a.Config.Tags)
+		acc.SetPrecision(a.inputPrecision(input))
+
+		goroutines := runtime.NumGoroutine()
+		start := time.Now()
+		err := input.Input.Gather(acc)
+		elapsed := time.Since(start)
+		started := int64(runtime.NumGoroutine() - goroutines)
+		if started < 0 {
+			started = 0
+		}
+		input.GatherComplete(atomic.LoadInt64(&acc.count), elapsed, started,
+			err)
+		gatherErr = err
+		if err != nil {
+			log.Printf("Error in input [%s]: %s", input.LogName(), err)
+		}
+		if timeout > 0 && elapsed > timeout {
+			log.Printf("WARNING: input [%s] completed after %s\n",
+				input.LogName(), elapsed)
+		}
+		interval := a.inputInterval(input)
+		slow := interval > 0 && elapsed > interval
+		if input.GatherSlow(slow) == warnAfterGathers {
+			log.Printf("WARNING: input [%s] took longer than its interval of "+
+				"%s in its last %d gathers\n", input.LogName(), interval,
+				warnAfterGathers)
+		}
+	}()
+
+	var err error
+	if timeout <= 0 {
+		<-done
+		err = gatherErr
+	} else {
+		select {
+		case <-done:
+			err = gatherErr
+		case <-expired:
+			input.GatherTimedOut()
+			log.Printf("WARNING: input [%s] did not complete within %s, it "+
+				"is skipped until it completes\n", input.LogName(), timeout)
+			err = fmt.Errorf("did not complete within %s", timeout)
+		}
+	}
+	a.checkFailures(input, err != nil)
+	return err
+}
+
+// checkFailures records whether a gather of the input failed, warning about
+// inputs failing repeatedly and disabling them after max_consecutive_failures
+// failed gathers.
+func (a *Agent) checkFailures(input *internal_models.RunningInput, failed bool) {
+	failures := input.GatherFailed(failed)
+	max := int64(a.Config.Agent.MaxConsecutiveFailures)
+	switch {
+	case max > 0 && failures >= max:
+		input.Disable()
+		log.Printf("ERROR: input [%s] failed %d consecutive gathers, it is "+
+			"disabled until telegraf restarts\n", input.LogName(), failures)
+	case failures == warnAfterGathers:
+		log.Printf("WARNING: input [%s] failed its last %d gathers\n",
+			input.LogName(), failures)
+	}
+}
+
+// inputPrecision returns the timestamp precision of the input, its own
+// precision if set, otherwise the agent's.
+func (a *Agent) inputPrecision(input *internal_models.RunningInput) time.Duration {
+	if input.Config.Precision > 0 {
+		return input.Config.Precision
+	}
+	return a.precision
+}
+
+// gatherTimeout returns how long to wait for a gather of the input, its
+// gather_timeout if set, otherwise its collection interval.
+func (a *Agent) gatherTimeout(input *internal_models.RunningInput) time.Duration {
+	if input.Config.GatherTimeout > 0 {
+		return input.Config.GatherTimeout
+	}
+	return a.inputInterval(input)
+}
+
+// inputInterval returns the collection interval of the input, its own
+// interval if set, otherwise the agent's.
+func (a *Agent) inputInterval(input *internal_models.RunningInput) time.Duration {
+	if input.Config.Interval > 0 {
+		return input.Config.Interval
+	}
+	return a.Config.Agent.Interval.Duration
+}
+
 // gatherSeparate runs the inputs that have been configured with their own
 // reporting interval.
 func (a *Agent) gatherSeparate(
@@ -159,30 +445,28 @@ func (a *Agent) gatherSeparate(
 	input *internal_models.RunningInput,
 	metricC chan telegraf.Metric,
 ) error {
-	defer panicRecover(input)
+	// Round collection to the nearest boundary of the input's own interval
+	if a.Config.Agent.RoundInterval {
+		select {
+		case <-shutdown:
+			return nil
+		case <-time.After(alignDuration(time.Now(), input.Config.Interval)):
+		}
+	}
 
 	ticker := time.NewTicker(input.Config.Interval)
+	defer ticker.Stop()
 
 	for {
-		var outerr error
+		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
 		start := time.Now()
 
-		acc := NewAccumulator(input.Config, metricC)
-		acc.SetDebug(a.Config.Agent.Debug)
-		acc.setDefaultTags(a.Config.Tags)
-
-		if err := input.Input.Gather(acc); err != nil {
-			log.Printf("Error in input [%s]: %s", input.Name, err)
-		}
+		a.gather(input, metricC, a.gatherTimeout(input))
 
 		elapsed := time.Since(start)
 		if !a.Config.Agent.Quiet {
 			log.Printf("Gathered metrics, (separate %s interval), from %s in %s\n",
-				input.Config.Interval, input.Name, elapsed)
-		}
-
-		if outerr != nil {
-			return outerr
+				input.Config.Interval, input.LogName(), elapsed)
 		}
 
 		select {
@@ -216,10 +500,12 @@ func (a *Agent) Test() error {
 	for _, input := range a.Config.Inputs {
 		acc := NewAccumulator(input.Config, metricC)
 		acc.SetDebug(true)
+		acc.setDefaultTags(a.Config.Tags)
+		acc.SetPrecision(a.inputPrecision(input))
 
-		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name)
+		fmt.Printf("* Plugin: %s, Collection 1\n", input.LogName())
 		if input.Config.Interval != 0 {
-			fmt.Printf("* Internal: %s\n", input.Config.Interval)
+			fmt.Printf("* Interval: %s\n", input.Config.Interval)
 		}
 
 		if err := input.Input.Gather(acc); err != nil {
@@ -231,7 +517,7 @@ func (a *Agent) Test() error {
 		switch input.Name {
 		case "cpu", "mongodb", "procstat":
 			time.Sleep(500 * time.Millisecond)
-			fmt.Printf("* Plugin: %s, Collection 2\n", input.Name)
+			fmt.Printf("* Plugin: %s, Collection 2\n", input.LogName())
 			if err := input.Input.Gather(acc); err != nil {
 				return err
 			}
@@ -241,49 +527,443 @@ func (a *Agent) Test() error {
 	return nil
 }
 
-// flush writes a list of points to all configured outputs
-func (a *Agent) flush() {
+// Once runs a single collection of all inputs and writes the metrics to the
+// outputs, passing them through the processors and aggregators, which are
+// pushed once all inputs have been gathered. The outputs must be connected.
+// An error naming the inputs and outputs which failed is returned if any
+// input failed to gather or any output failed to write.
+func (a *Agent) Once() error {
+	if err := a.loadState(); err != nil {
+		return err
+	}
+	defer a.storeState()
+
+	if err := a.startProcessors(a.Config.Processors); err != nil {
+		return err
+	}
+
+	metricC := make(chan telegraf.Metric, 1000)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range metricC {
+			a.addMetric(m)
+		}
+	}()
+
 	var wg sync.WaitGroup
+	var failedLock sync.Mutex
+	var failedInputs []string
+	for _, input := range a.Config.Inputs {
+		switch p := input.Input.(type) {
+		case telegraf.ServiceInput:
+			if err := p.Start(); err != nil {
+				log.Printf("Service for input %s failed to start, exiting\n%s\n",
+					input.LogName(), err.Error())
+				a.stopProcessors(a.Config.Processors)
+				return err
+			}
+			defer p.Stop()
+		}
+
+		wg.Add(1)
+		go func(input *internal_models.RunningInput) {
+			defer wg.Done()
+			// Wait for every input, as no metric may be sent after
+			// metricC is closed
+			if err := a.gather(input, metricC, 0); err != nil {
+				failedLock.Lock()
+				failedInputs = append(failedInputs, input.LogName())
+				failedLock.Unlock()
+			}
+		}(input)
+	}
+	wg.Wait()
+	close(metricC)
+	<-done
+	a.stopProcessors(a.Config.Processors)
+
+	for _, ra := range a.Config.Aggregators {
+		a.addToOutputs(ra.Push())
+	}
+	failedOutputs := a.flush()
+	for _, o := range a.Config.Outputs {
+		o.Close()
+	}
+
+	switch {
+	case len(failedInputs) > 0 && len(failedOutputs) > 0:
+		return fmt.Errorf("inputs %s failed to gather metrics and outputs "+
+			"%s failed to write metrics", strings.Join(failedInputs, ", "),
+			strings.Join(failedOutputs, ", "))
+	case len(failedInputs) > 0:
+		return fmt.Errorf("inputs %s failed to gather metrics",
+			strings.Join(failedInputs, ", "))
+	case len(failedOutputs) > 0:
+		return fmt.Errorf("outputs %s failed to write metrics",
+			strings.Join(failedOutputs, ", "))
+	}
+	return nil
+}
+
+// flush writes a list of points to all configured outputs, returning the
+// names of the outputs which failed to write.
+func (a *Agent) flush() []string {
+	var wg sync.WaitGroup
+	var failedLock sync.Mutex
+	var failed []string
 
 	wg.Add(len(a.Config.Outputs))
 	for _, o := range a.Config.Outputs {
 		go func(output *internal_models.RunningOutput) {
 			defer wg.Done()
-			err := output.Write()
-			if err != nil {
-				log.Printf("Error writing to output [%s]: %s\n",
-					output.Name, err.Error())
+			if err := writeOutput(output); err != nil {
+				failedLock.Lock()
+				failed = append(failed, output.LogName())
+				failedLock.Unlock()
 			}
 		}(o)
 	}
 
 	wg.Wait()
+	return failed
+}
+
+func writeOutput(output *internal_models.RunningOutput) error {
+	err := output.Write()
+	if err != nil {
+		log.Printf("Error writing to output [%s]: %s\n", output.LogName(), err.Error())
+	}
+	return err
+}
+
+// outputFlusher writes to the output every flush interval of the output, and
+// every time a full batch of metrics is signaled on batchC, until shutdown.
+func (a *Agent) outputFlusher(
+	shutdown chan struct{},
+	output *internal_models.RunningOutput,
+	batchC chan struct{},
+) {
+	interval := a.Config.Agent.FlushInterval.Duration
+	if output.Config.FlushInterval != 0 {
+		interval = jitterInterval(output.Config.FlushInterval,
+			a.Config.Agent.FlushJitter.Duration)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+		case <-batchC:
+		}
+		writeOutput(output)
+	}
 }
 
-// flusher monitors the points input channel and flushes on the minimum interval
-func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) error {
+// flusher monitors the points input channel and writes the metrics to the
+// outputs, every flush interval of each output and as soon as an output has
+// metric_batch_size metrics waiting to be written. Once inputsDone is closed,
+// the metrics left in the channel are added to the outputs, to be written by
+// the caller.
+func (a *Agent) flusher(
+	shutdown chan struct{},
+	inputsDone chan struct{},
+	metricC chan telegraf.Metric,
+) error {
 	// Inelegant, but this sleep is to allow the Gather threads to run, so that
 	// the flusher will flush after metrics are collected.
 	time.Sleep(time.Millisecond * 200)
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
+	// Every output is written from its own goroutine, so a slow output does
+	// not delay the others. batchCs signal them that a batch is ready.
+	var wg sync.WaitGroup
+	batchCs := make([]chan struct{}, len(a.Config.Outputs))
+	for i, o := range a.Config.Outputs {
+		batchCs[i] = make(chan struct{}, 1)
+		wg.Add(1)
+		go func(output *internal_models.RunningOutput, batchC chan struct{}) {
+			defer wg.Done()
+			a.outputFlusher(shutdown, output, batchC)
+		}(o, batchCs[i])
+	}
+
+	// Aggregators are only ever accessed from this goroutine, their tickers
+	// signal on pushC when the aggregates of a period should be pushed.
+	pushC := make(chan *internal_models.RunningAggregator)
+	for _, ra := range a.Config.Aggregators {
+		go a.aggregatorTicker(shutdown, ra, pushC)
+	}
 
 	for {
 		select {
-		case <-shutdown:
-			log.Println("Hang on, flushing any cached points before shutdown")
-			a.flush()
+		case <-inputsDone:
+			wg.Wait()
+			// No metric is gathered anymore, drain the ones left
+			for drained := false; !drained; {
+				select {
+				case m := <-metricC:
+					a.addMetric(m)
+				default:
+					drained = true
+				}
+			}
 			return nil
-		case <-ticker.C:
-			a.flush()
+		case ra := <-pushC:
+			a.addToOutputs(ra.Push())
 		case m := <-metricC:
-			for _, o := range a.Config.Outputs {
-				o.AddPoint(m)
+			a.addMetric(m)
+		}
+
+		for i, o := range a.Config.Outputs {
+			if o.BatchReady() {
+				// Don't block if the output is already signaled or writing
+				select {
+				case batchCs[i] <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// finalFlush writes the buffered metrics to all outputs and closes them,
+// giving up once the agent's shutdown_timeout elapses.
+func (a *Agent) finalFlush() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.flush()
+		for _, o := range a.Config.Outputs {
+			o.Close()
+		}
+	}()
+
+	timeout := a.Config.Agent.ShutdownTimeout.Duration
+	if timeout <= 0 {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		var unwritten int
+		for _, o := range a.Config.Outputs {
+			unwritten += o.BufferSize()
+		}
+		log.Printf("WARNING: outputs did not complete the final flush within "+
+			"%s, %d buffered metrics were not written\n", timeout, unwritten)
+	}
+}
+
+// addMetric runs a gathered metric through the processors and aggregators,
+// adding the resulting metrics to the outputs.
+func (a *Agent) addMetric(m telegraf.Metric) {
+	for _, metric := range applyProcessors(a.Config.Processors, m) {
+		a.addProcessed(metric)
+	}
+}
+
+// addProcessed adds a metric returned by the processors to the aggregators,
+// or to the outputs subscribed to the input which gathered it.
+func (a *Agent) addProcessed(m telegraf.Metric) {
+	m, input := unwrapMetric(m)
+	if a.applyAggregators(m) {
+		return
+	}
+	a.routeToOutputs(input, []telegraf.Metric{m})
+}
+
+// addToOutputs adds the metrics, which come from no input, to the buffers of
+// all configured outputs not subscribed to specific inputs
+func (a *Agent) addToOutputs(metrics []telegraf.Metric) {
+	a.routeToOutputs(nil, metrics)
+}
+
+// routeToOutputs adds the metrics gathered by the input to the buffers of the
+// configured outputs subscribed to it
+func (a *Agent) routeToOutputs(
+	input *internal_models.InputConfig,
+	metrics []telegraf.Metric,
+) {
+	for _, o := range a.Config.Outputs {
+		if !o.ShouldInputPass(input) {
+			continue
+		}
+		for _, metric := range metrics {
+			o.AddPoint(metric)
+		}
+	}
+}
+
+// applyAggregators adds a metric to all configured aggregators, returning
+// true if the original metric should be dropped.
+func (a *Agent) applyAggregators(m telegraf.Metric) bool {
+	drop := false
+	for _, ra := range a.Config.Aggregators {
+		if ra.Add(m) {
+			drop = true
+		}
+	}
+	return drop
+}
+
+// aggregatorTicker signals the flusher on pushC at the end of every period
+// of the aggregator, until shutdown.
+func (a *Agent) aggregatorTicker(
+	shutdown chan struct{},
+	ra *internal_models.RunningAggregator,
+	pushC chan *internal_models.RunningAggregator,
+) {
+	period := ra.Config.Period
+
+	// Round the end of the first period to the nearest period boundary
+	if a.Config.Agent.RoundInterval {
+		select {
+		case <-shutdown:
+			return
+		case <-time.After(alignDuration(time.Now(), period)):
+		}
+		select {
+		case <-shutdown:
+			return
+		case pushC <- ra:
+		}
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-shutdown:
+			return
+		case <-ticker.C:
+			select {
+			case <-shutdown:
+				return
+			case pushC <- ra:
+			}
+		}
+	}
+}
+
+// applyProcessors runs a metric through the processors, in order, returning
+// the resulting metrics. The metrics keep the input which gathered them, the
+// ones created by a processor being given the input of the metric applied.
+// A metric held by a ServiceProcessor thus keeps its input until returned.
+func applyProcessors(
+	processors []*internal_models.RunningProcessor,
+	m telegraf.Metric,
+) []telegraf.Metric {
+	_, input := unwrapMetric(m)
+	metrics := []telegraf.Metric{m}
+	for _, processor := range processors {
+		metrics = wrapMetrics(processor.Apply(metrics...), input)
+	}
+	return metrics
+}
+
+// startProcessors starts the service of the ServiceProcessors, stopping the
+// ones already started if one fails to start.
+func (a *Agent) startProcessors(
+	processors []*internal_models.RunningProcessor,
+) error {
+	for i, processor := range processors {
+		p, ok := processor.Processor.(telegraf.ServiceProcessor)
+		if !ok {
+			continue
+		}
+		if err := p.Start(); err != nil {
+			log.Printf("Service for processor %s failed to start, exiting\n%s\n",
+				processor.LogName(), err.Error())
+			for _, started := range processors[:i] {
+				if p, ok := started.Processor.(telegraf.ServiceProcessor); ok {
+					p.Stop()
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// stopProcessors stops the service of the given ServiceProcessors, in the
+// order of the configured processors, once no metric is added anymore. The
+// metrics a processor still returns once stopped are run through the
+// following processors and the aggregators, and added to the outputs
+// subscribed to the input which gathered them.
+func (a *Agent) stopProcessors(processors []*internal_models.RunningProcessor) {
+	for i, processor := range a.Config.Processors {
+		p, ok := processor.Processor.(telegraf.ServiceProcessor)
+		if !ok || !containsProcessor(processors, processor) {
+			continue
+		}
+		p.Stop()
+		for _, held := range p.Apply() {
+			next := applyProcessors(a.Config.Processors[i+1:], held)
+			for _, metric := range next {
+				a.addProcessed(metric)
 			}
 		}
 	}
 }
 
+func containsProcessor(
+	processors []*internal_models.RunningProcessor,
+	processor *internal_models.RunningProcessor,
+) bool {
+	for _, p := range processors {
+		if p == processor {
+			return true
+		}
+	}
+	return false
+}
+
+// startInputs starts the service of the ServiceInputs, stopping the ones
+// already started if one fails to start.
+func startInputs(inputs []*internal_models.RunningInput) error {
+	for i, input := range inputs {
+		p, ok := input.Input.(telegraf.ServiceInput)
+		if !ok {
+			continue
+		}
+		if err := p.Start(); err != nil {
+			log.Printf("Service for input %s failed to start, exiting\n%s\n",
+				input.LogName(), err.Error())
+			stopInputs(inputs[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// stopInputs stops the service of the ServiceInputs
+func stopInputs(inputs []*internal_models.RunningInput) {
+	for _, input := range inputs {
+		if p, ok := input.Input.(telegraf.ServiceInput); ok {
+			p.Stop()
+		}
+	}
+}
+
+// alignDuration returns the duration from now until the next multiple of
+// interval, so that collections happen on wall-clock interval boundaries.
+func alignDuration(now time.Time, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	i := int64(interval)
+	offset := now.UnixNano() % i
+	if offset == 0 {
+		return 0
+	}
+	return time.Duration(i - offset)
+}
+
 // jitterInterval applies the the interval jitter to the flush interval using
 // crypto/rand number generator
 func jitterInterval(ininterval, injitter time.Duration) time.Duration {
@@ -305,10 +985,9 @@ func jitterInterval(ininterval, injitter time.Duration) time.Duration {
 	return outinterval
 }
 
-// Run runs the agent daemon, gathering every Interval
+// Run runs the agent daemon, gathering every Interval, until shutdown is
+// closed. The config may be switched meanwhile with Reload.
 func (a *Agent) Run(shutdown chan struct{}) error {
-	var wg sync.WaitGroup
-
 	a.Config.Agent.FlushInterval.Duration = jitterInterval(
 		a.Config.Agent.FlushInterval.Duration,
 		a.Config.Agent.FlushJitter.Duration)
@@ -318,63 +997,139 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Debug, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
+	var health *healthHandler
+	if a.Config.Agent.HealthAddress != "" {
+		h, err := a.startHealthServer(a.Config.Agent.HealthAddress, shutdown)
+		if err != nil {
+			log.Printf("Health endpoints failed to start, exiting\n%s\n", err)
+			return err
+		}
+		health = h
+	}
+
+	// The state is stored once the processors are stopped, and the inputs
+	// before returning
+	if err := a.loadState(); err != nil {
+		return err
+	}
+	defer a.storeState()
+
 	// channel shared between all input threads for accumulating points
 	metricC := make(chan telegraf.Metric, 1000)
 
+	// Start service of any ServiceProcessors and ServicePlugins, they are
+	// stopped on shutdown, or once a reload removes them
+	if err := a.startProcessors(a.Config.Processors); err != nil {
+		return err
+	}
+	if err := startInputs(a.Config.Inputs); err != nil {
+		a.stopProcessors(a.Config.Processors)
+		return err
+	}
+
 	// Round collection to nearest interval by sleeping
 	if a.Config.Agent.RoundInterval {
-		i := int64(a.Config.Agent.Interval.Duration)
-		time.Sleep(time.Duration(i - (time.Now().UnixNano() % i)))
+		time.Sleep(alignDuration(time.Now(), a.Config.Agent.Interval.Duration))
 	}
 	ticker := time.NewTicker(a.Config.Agent.Interval.Duration)
+	defer ticker.Stop()
+
+	if health != nil {
+		health.setReady(true)
+	}
+
+	var err error
+	for {
+		req, diff := a.runPlugins(shutdown, ticker, metricC)
+		if req == nil {
+			break
+		}
+		err = a.switchConfig(diff)
+		req.errC <- err
+		if err != nil {
+			// The config holds the plugin instances left running
+			stopInputs(a.Config.Inputs)
+			break
+		}
+	}
 
-	wg.Add(1)
+	log.Println("Hang on, flushing any cached points before shutdown")
+	a.stopProcessors(a.Config.Processors)
+	for _, ra := range a.Config.Aggregators {
+		a.addToOutputs(ra.Push())
+	}
+	a.finalFlush()
+	return err
+}
+
+// runPlugins gathers the inputs and writes to the outputs until shutdown is
+// closed, or until a reload is requested, returning the request and the
+// difference between the running config and the requested one. Once it
+// returns, no input is gathered anymore, the service inputs are stopped,
+// only the ones removed by the config for a reload, and the metrics gathered
+// until then are added to the outputs.
+func (a *Agent) runPlugins(
+	shutdown chan struct{},
+	ticker *time.Ticker,
+	metricC chan telegraf.Metric,
+) (*reloadRequest, *configDiff) {
+	var wg sync.WaitGroup
+
+	// stop is closed on shutdown or reload, and inputsDone once the inputs
+	// are stopped, for the flusher to add the remaining metrics
+	stop := make(chan struct{})
+	inputsDone := make(chan struct{})
+	var flusherWg sync.WaitGroup
+	flusherWg.Add(1)
 	go func() {
-		defer wg.Done()
-		if err := a.flusher(shutdown, metricC); err != nil {
+		defer flusherWg.Done()
+		if err := a.flusher(stop, inputsDone, metricC); err != nil {
 			log.Printf("Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
 	}()
 
 	for _, input := range a.Config.Inputs {
-
-		// Start service of any ServicePlugins
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			if err := p.Start(); err != nil {
-				log.Printf("Service for input %s failed to start, exiting\n%s\n",
-					input.Name, err.Error())
-				return err
-			}
-			defer p.Stop()
-		}
-
 		// Special handling for inputs that have their own collection interval
 		// configured. Default intervals are handled below with gatherParallel
 		if input.Config.Interval != 0 {
 			wg.Add(1)
 			go func(input *internal_models.RunningInput) {
 				defer wg.Done()
-				if err := a.gatherSeparate(shutdown, input, metricC); err != nil {
+				if err := a.gatherSeparate(stop, input, metricC); err != nil {
 					log.Printf(err.Error())
 				}
 			}(input)
 		}
 	}
 
-	defer wg.Wait()
-
 	for {
-		if err := a.gatherParallel(metricC); err != nil {
+		if err := a.gatherParallel(stop, metricC); err != nil {
 			log.Printf(err.Error())
 		}
 
+		var req *reloadRequest
 		select {
 		case <-shutdown:
-			return nil
+		case r := <-a.reloadC:
+			req = &r
 		case <-ticker.C:
 			continue
 		}
+
+		// Stop the inputs before the flush, so that the metrics they
+		// gathered until then are written
+		close(stop)
+		wg.Wait()
+		var diff *configDiff
+		if req == nil {
+			stopInputs(a.Config.Inputs)
+		} else {
+			diff = a.diffConfig(req.config)
+			stopInputs(diff.removedInputs)
+		}
+		close(inputsDone)
+		flusherWg.Wait()
+		return req, diff
 	}
 }
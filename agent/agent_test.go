@@ -1,11 +1,19 @@
 package agent
 
 import (
-	"github.com/stretchr/testify/assert"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -102,6 +110,22 @@ func TestAgent_LoadOutput(t *testing.T) {
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
 
+func TestAgent_OmitHostname(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	_, err := NewAgent(c)
+	assert.NoError(t, err)
+	assert.NotContains(t, c.Tags, "host")
+}
+
+func TestAgent_Hostname(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Hostname = "example"
+	_, err := NewAgent(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "example", c.Tags["host"])
+}
+
 func TestAgent_ZeroJitter(t *testing.T) {
 	flushinterval := jitterInterval(time.Duration(10*time.Second),
 		time.Duration(0*time.Second))
@@ -173,3 +197,821 @@ func TestAgent_JitterMin(t *testing.T) {
 		}
 	}
 }
+
+func TestAgent_AlignDuration(t *testing.T) {
+	now := time.Date(2016, 2, 1, 10, 0, 3, 0, time.UTC)
+
+	actual := alignDuration(now, 10*time.Second)
+	if exp := 7 * time.Second; actual != exp {
+		t.Errorf("Actual %v, expected %v", actual, exp)
+	}
+
+	actual = alignDuration(now, time.Minute)
+	if exp := 57 * time.Second; actual != exp {
+		t.Errorf("Actual %v, expected %v", actual, exp)
+	}
+
+	// already aligned
+	actual = alignDuration(now.Add(7*time.Second), 10*time.Second)
+	if actual != 0 {
+		t.Errorf("Actual %v, expected 0", actual)
+	}
+}
+
+type onceInput struct{}
+
+func (i *onceInput) SampleConfig() string { return "" }
+func (i *onceInput) Description() string  { return "" }
+func (i *onceInput) Gather(acc telegraf.Accumulator) error {
+	acc.Add("once", 1, map[string]string{"tag": "value"})
+	return nil
+}
+
+type onceOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (o *onceOutput) Connect() error       { return nil }
+func (o *onceOutput) Close() error         { return nil }
+func (o *onceOutput) Description() string  { return "" }
+func (o *onceOutput) SampleConfig() string { return "" }
+func (o *onceOutput) Write(metrics []telegraf.Metric) error {
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func TestAgent_Once(t *testing.T) {
+	c := config.NewConfig()
+	c.Tags["dc"] = "us-east-1"
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "once",
+		Input:  &onceInput{},
+		Config: &internal_models.InputConfig{Name: "once"},
+	})
+	output := &onceOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput("once",
+		output, &internal_models.OutputConfig{Name: "once"}, 0, 0))
+
+	a, _ := NewAgent(c)
+	assert.NoError(t, a.Once())
+
+	if assert.Equal(t, 1, len(output.metrics)) {
+		m := output.metrics[0]
+		assert.Equal(t, "once", m.Name())
+		assert.Equal(t, "us-east-1", m.Tags()["dc"])
+		assert.Equal(t, "value", m.Tags()["tag"])
+	}
+}
+
+func TestAgent_OutputInputs(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs,
+		&internal_models.RunningInput{
+			Name:   "once",
+			Input:  &onceInput{},
+			Config: &internal_models.InputConfig{Name: "once", Alias: "a"},
+		},
+		&internal_models.RunningInput{
+			Name:   "counter",
+			Input:  &counterInput{},
+			Config: &internal_models.InputConfig{Name: "counter"},
+		})
+	outputs := make([]*onceOutput, 3)
+	for i, inputs := range [][]string{nil, {"once::a"}, {"count*"}} {
+		outputs[i] = &onceOutput{}
+		c.Outputs = append(c.Outputs, internal_models.NewRunningOutput("once",
+			outputs[i], &internal_models.OutputConfig{
+				Name:   "once",
+				Inputs: inputs,
+			}, 0, 0))
+	}
+
+	a, _ := NewAgent(c)
+	assert.NoError(t, a.Once())
+
+	assert.Equal(t, 2, len(outputs[0].metrics))
+	if assert.Equal(t, 1, len(outputs[1].metrics)) {
+		assert.Equal(t, "once", outputs[1].metrics[0].Name())
+	}
+	if assert.Equal(t, 1, len(outputs[2].metrics)) {
+		assert.Equal(t, "counter", outputs[2].metrics[0].Name())
+	}
+}
+
+// holdingProcessor holds the metrics until it is stopped
+type holdingProcessor struct {
+	metrics []telegraf.Metric
+	stopped bool
+}
+
+func (p *holdingProcessor) SampleConfig() string { return "" }
+func (p *holdingProcessor) Description() string  { return "" }
+func (p *holdingProcessor) Start() error         { return nil }
+func (p *holdingProcessor) Stop()                { p.stopped = true }
+func (p *holdingProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	p.metrics = append(p.metrics, in...)
+	if !p.stopped {
+		return nil
+	}
+	out := p.metrics
+	p.metrics = nil
+	return out
+}
+
+func TestAgent_OnceServiceProcessor(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "once",
+		Input:  &onceInput{},
+		Config: &internal_models.InputConfig{Name: "once"},
+	})
+	processor := &holdingProcessor{}
+	c.Processors = append(c.Processors, &internal_models.RunningProcessor{
+		Name:      "holding",
+		Processor: processor,
+		Config:    &internal_models.ProcessorConfig{Name: "holding"},
+	})
+	output := &onceOutput{}
+	c.Outputs = append(c.Outputs, internal_models.NewRunningOutput("once",
+		output, &internal_models.OutputConfig{Name: "once"}, 0, 0))
+
+	a, _ := NewAgent(c)
+	assert.NoError(t, a.Once())
+
+	// The metric held by the processor is written once it is stopped
+	assert.True(t, processor.stopped)
+	if assert.Equal(t, 1, len(output.metrics)) {
+		assert.Equal(t, "once", output.metrics[0].Name())
+	}
+}
+
+func TestAgent_ServiceProcessorOutputInputs(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs,
+		&internal_models.RunningInput{
+			Name:   "once",
+			Input:  &onceInput{},
+			Config: &internal_models.InputConfig{Name: "once"},
+		},
+		&internal_models.RunningInput{
+			Name:   "counter",
+			Input:  &counterInput{},
+			Config: &internal_models.InputConfig{Name: "counter"},
+		})
+	c.Processors = append(c.Processors, &internal_models.RunningProcessor{
+		Name:      "holding",
+		Processor: &holdingProcessor{},
+		Config:    &internal_models.ProcessorConfig{Name: "holding"},
+	})
+	outputs := make([]*onceOutput, 2)
+	for i, inputs := range [][]string{{"once"}, {"counter"}} {
+		outputs[i] = &onceOutput{}
+		c.Outputs = append(c.Outputs, internal_models.NewRunningOutput("once",
+			outputs[i], &internal_models.OutputConfig{
+				Name:   "once",
+				Inputs: inputs,
+			}, 0, 0))
+	}
+
+	a, _ := NewAgent(c)
+	assert.NoError(t, a.Once())
+
+	// The metrics held until the processor is stopped still go to the
+	// outputs subscribed to their input only
+	if assert.Equal(t, 1, len(outputs[0].metrics)) {
+		assert.Equal(t, "once", outputs[0].metrics[0].Name())
+	}
+	if assert.Equal(t, 1, len(outputs[1].metrics)) {
+		assert.Equal(t, "counter", outputs[1].metrics[0].Name())
+	}
+}
+
+type failingInput struct{}
+
+func (i *failingInput) SampleConfig() string { return "" }
+func (i *failingInput) Description() string  { return "" }
+func (i *failingInput) Gather(acc telegraf.Accumulator) error {
+	return errors.New("failed gather")
+}
+
+type panickingInput struct{}
+
+func (i *panickingInput) SampleConfig() string { return "" }
+func (i *panickingInput) Description() string  { return "" }
+func (i *panickingInput) Gather(acc telegraf.Accumulator) error {
+	panic("gather")
+}
+
+func TestAgent_OnceFailures(t *testing.T) {
+	c := config.NewConfig()
+	for name, input := range map[string]telegraf.Input{
+		"once":    &onceInput{},
+		"failing": &failingInput{},
+		"panic":   &panickingInput{},
+	} {
+		c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+			Name:   name,
+			Input:  input,
+			Config: &internal_models.InputConfig{Name: name},
+		})
+	}
+	output := internal_models.NewRunningOutput("failing", &failingOutput{fail: true},
+		&internal_models.OutputConfig{Name: "failing"}, 0, 0)
+	output.Quiet = true
+	c.Outputs = append(c.Outputs, output)
+
+	a, _ := NewAgent(c)
+	err := a.Once()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "failing")
+		assert.Contains(t, err.Error(), "panic")
+		assert.NotContains(t, err.Error(), "once")
+		assert.Contains(t, err.Error(), "outputs failing failed to write")
+	}
+}
+
+// blockingInput blocks in Gather until release is closed
+type blockingInput struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (i *blockingInput) Description() string  { return "" }
+func (i *blockingInput) SampleConfig() string { return "" }
+func (i *blockingInput) Gather(acc telegraf.Accumulator) error {
+	i.started <- struct{}{}
+	<-i.release
+	acc.Add("blocking", 1, nil)
+	return nil
+}
+
+func TestAgent_GatherTimeout(t *testing.T) {
+	input := &blockingInput{
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	ri := &internal_models.RunningInput{
+		Name:   "blocking",
+		Input:  input,
+		Config: &internal_models.InputConfig{Name: "blocking"},
+	}
+	c := config.NewConfig()
+	a, _ := NewAgent(c)
+	metricC := make(chan telegraf.Metric, 10)
+
+	a.gather(ri, metricC, 10*time.Millisecond)
+	<-input.started
+	assert.Equal(t, int64(1), ri.GatherTimeouts())
+
+	// The previous gather is still running, so the input is skipped
+	a.gather(ri, metricC, 10*time.Millisecond)
+	assert.Equal(t, 0, len(input.started))
+	assert.Equal(t, int64(1), ri.GatherTimeouts())
+
+	close(input.release)
+	m := <-metricC
+	assert.Equal(t, "blocking", m.Name())
+
+	// Once complete, the input is gathered again
+	a.gather(ri, metricC, time.Second)
+	<-input.started
+	<-metricC
+	assert.Equal(t, int64(1), ri.GatherTimeouts())
+	assert.Equal(t, int64(2), ri.MetricsGathered())
+}
+
+func TestAgent_MaxConsecutiveFailures(t *testing.T) {
+	ri := &internal_models.RunningInput{
+		Name:   "panic",
+		Input:  &panickingInput{},
+		Config: &internal_models.InputConfig{Name: "panic"},
+	}
+	c := config.NewConfig()
+	c.Agent.MaxConsecutiveFailures = 2
+	a, _ := NewAgent(c)
+	metricC := make(chan telegraf.Metric, 10)
+
+	assert.Error(t, a.gather(ri, metricC, 0))
+	assert.Equal(t, int64(1), ri.GatherPanics())
+	assert.Equal(t, int64(1), ri.ConsecutiveFailures())
+	assert.False(t, ri.Disabled())
+
+	// The input is disabled after its second failed gather in a row
+	assert.Error(t, a.gather(ri, metricC, 0))
+	assert.True(t, ri.Disabled())
+	assert.NoError(t, a.gather(ri, metricC, 0))
+	assert.Equal(t, int64(2), ri.GatherPanics())
+}
+
+func TestAgent_SlowGathers(t *testing.T) {
+	input := &blockingInput{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	close(input.release)
+	ri := &internal_models.RunningInput{
+		Name:  "blocking",
+		Input: input,
+		Config: &internal_models.InputConfig{
+			Name:     "blocking",
+			Interval: time.Nanosecond,
+		},
+	}
+	a, _ := NewAgent(config.NewConfig())
+	metricC := make(chan telegraf.Metric, 10)
+
+	// A successful gather taking longer than the interval is slow, but not
+	// failed
+	assert.NoError(t, a.gather(ri, metricC, 0))
+	<-input.started
+	assert.Equal(t, int64(1), ri.SlowGathers())
+	assert.Equal(t, int64(0), ri.ConsecutiveFailures())
+}
+
+func TestAgent_MaxConcurrentGathers(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.MaxConcurrentGathers = 1
+	a, _ := NewAgent(c)
+	metricC := make(chan telegraf.Metric, 10)
+
+	first := &blockingInput{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	second := &blockingInput{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	close(second.release)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.gather(&internal_models.RunningInput{
+			Name:   "first",
+			Input:  first,
+			Config: &internal_models.InputConfig{Name: "first"},
+		}, metricC, 0)
+	}()
+	<-first.started
+
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		a.gather(&internal_models.RunningInput{
+			Name:   "second",
+			Input:  second,
+			Config: &internal_models.InputConfig{Name: "second"},
+		}, metricC, 0)
+	}()
+
+	// The second input waits for the first gather to release its slot
+	select {
+	case <-second.started:
+		t.Fatal("second input gathered while the first was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(first.release)
+	<-done
+	<-secondDone
+	assert.Equal(t, 1, len(second.started))
+	assert.Equal(t, 2, len(metricC))
+}
+
+func TestAgent_MaxConcurrentGathersTimeout(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.MaxConcurrentGathers = 1
+	a, _ := NewAgent(c)
+	metricC := make(chan telegraf.Metric, 10)
+
+	first := &blockingInput{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	second := &blockingInput{
+		started: make(chan struct{}, 2),
+		release: make(chan struct{}),
+	}
+	close(second.release)
+	secondInput := &internal_models.RunningInput{
+		Name:   "second",
+		Input:  second,
+		Config: &internal_models.InputConfig{Name: "second"},
+	}
+
+	// The first gather keeps its slot once it timed out
+	assert.Error(t, a.gather(&internal_models.RunningInput{
+		Name:   "first",
+		Input:  first,
+		Config: &internal_models.InputConfig{Name: "first"},
+	}, metricC, 10*time.Millisecond))
+	<-first.started
+
+	// The wait for the slot counts toward the timeout of the second gather
+	assert.Error(t, a.gather(secondInput, metricC, 10*time.Millisecond))
+	assert.Equal(t, 0, len(second.started))
+	assert.Equal(t, int64(1), secondInput.GatherTimeouts())
+
+	// The slot is released once the first gather completes
+	close(first.release)
+	assert.NoError(t, a.gather(secondInput, metricC, time.Second))
+	assert.Equal(t, 1, len(second.started))
+}
+
+// chanOutput sends every batch written to it on writes
+type chanOutput struct {
+	writes chan []telegraf.Metric
+}
+
+func (o *chanOutput) Connect() error       { return nil }
+func (o *chanOutput) Close() error         { return nil }
+func (o *chanOutput) Description() string  { return "" }
+func (o *chanOutput) SampleConfig() string { return "" }
+func (o *chanOutput) Write(metrics []telegraf.Metric) error {
+	if len(metrics) > 0 {
+		o.writes <- metrics
+	}
+	return nil
+}
+
+func testMetric(t *testing.T) telegraf.Metric {
+	m, err := telegraf.NewMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": int64(1)}, time.Now())
+	assert.NoError(t, err)
+	return m
+}
+
+func TestAgent_FlushBatchReady(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.FlushInterval.Duration = time.Hour
+	output := &chanOutput{writes: make(chan []telegraf.Metric, 10)}
+	ro := internal_models.NewRunningOutput("chan", output,
+		&internal_models.OutputConfig{Name: "chan"}, 2, 10)
+	ro.Quiet = true
+	c.Outputs = append(c.Outputs, ro)
+	a, _ := NewAgent(c)
+
+	shutdown := make(chan struct{})
+	metricC := make(chan telegraf.Metric, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.flusher(shutdown, shutdown, metricC)
+	}()
+
+	// A full batch is written long before the flush interval
+	metricC <- testMetric(t)
+	metricC <- testMetric(t)
+	select {
+	case metrics := <-output.writes:
+		assert.Equal(t, 2, len(metrics))
+	case <-time.After(5 * time.Second):
+		t.Fatal("full batch was not written")
+	}
+
+	// A partial batch waits for the flush interval, or the shutdown
+	metricC <- testMetric(t)
+	select {
+	case <-output.writes:
+		t.Fatal("partial batch was written before the flush interval")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(shutdown)
+	<-done
+	// The flusher leaves the final flush to Run
+	a.finalFlush()
+	metrics := <-output.writes
+	assert.Equal(t, 1, len(metrics))
+}
+
+func TestAgent_OutputFlushInterval(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.FlushInterval.Duration = time.Hour
+	c.Agent.FlushJitter.Duration = 0
+	output := &chanOutput{writes: make(chan []telegraf.Metric, 10)}
+	ro := internal_models.NewRunningOutput("chan", output,
+		&internal_models.OutputConfig{
+			Name:          "chan",
+			FlushInterval: 500 * time.Millisecond,
+		}, 0, 0)
+	ro.Quiet = true
+	c.Outputs = append(c.Outputs, ro)
+	a, _ := NewAgent(c)
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	metricC := make(chan telegraf.Metric, 10)
+	go a.flusher(shutdown, shutdown, metricC)
+
+	metricC <- testMetric(t)
+	select {
+	case metrics := <-output.writes:
+		assert.Equal(t, 1, len(metrics))
+	case <-time.After(5 * time.Second):
+		t.Fatal("output was not written on its own flush interval")
+	}
+}
+
+// timeInput adds a metric with the timestamp ts
+type timeInput struct {
+	ts time.Time
+}
+
+func (i *timeInput) Description() string  { return "" }
+func (i *timeInput) SampleConfig() string { return "" }
+func (i *timeInput) Gather(acc telegraf.Accumulator) error {
+	acc.Add("time", 1, nil, i.ts)
+	return nil
+}
+
+func TestAgent_Precision(t *testing.T) {
+	ts := time.Unix(1456328457, 123456789)
+	c := config.NewConfig()
+	c.Agent.Precision = "s"
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+	metricC := make(chan telegraf.Metric, 10)
+
+	ri := &internal_models.RunningInput{
+		Name:   "time",
+		Input:  &timeInput{ts: ts},
+		Config: &internal_models.InputConfig{Name: "time"},
+	}
+	a.gather(ri, metricC, 0)
+	m := <-metricC
+	assert.Equal(t, time.Unix(1456328457, 0), m.Time())
+
+	// The precision of the input overrides the agent's
+	ri.Config.Precision = time.Millisecond
+	a.gather(ri, metricC, 0)
+	m = <-metricC
+	assert.Equal(t, time.Unix(1456328457, 123000000), m.Time())
+
+	c.Agent.Precision = "x"
+	_, err = NewAgent(c)
+	assert.Error(t, err)
+}
+
+func TestAccumulator_ValueType(t *testing.T) {
+	metricC := make(chan telegraf.Metric, 10)
+	acc := NewAccumulator(&internal_models.InputConfig{Name: "test"}, metricC)
+	fields := map[string]interface{}{"value": int64(1)}
+
+	acc.AddFields("test", fields, nil)
+	acc.AddGauge("test", fields, nil)
+	acc.AddCounter("test", fields, nil)
+	assert.Equal(t, telegraf.Untyped, (<-metricC).Type())
+	assert.Equal(t, telegraf.Gauge, (<-metricC).Type())
+	assert.Equal(t, telegraf.Counter, (<-metricC).Type())
+}
+
+// blockingOutput blocks in Write until release is closed
+type blockingOutput struct {
+	release chan struct{}
+}
+
+func (o *blockingOutput) Connect() error       { return nil }
+func (o *blockingOutput) Close() error         { return nil }
+func (o *blockingOutput) Description() string  { return "" }
+func (o *blockingOutput) SampleConfig() string { return "" }
+func (o *blockingOutput) Write(metrics []telegraf.Metric) error {
+	<-o.release
+	return nil
+}
+
+func runAgent(t *testing.T, c *config.Config) (chan struct{}, chan struct{}) {
+	c.Agent.Interval.Duration = time.Hour
+	c.Agent.RoundInterval = false
+	c.Agent.FlushInterval.Duration = time.Hour
+	c.Agent.FlushJitter.Duration = 0
+	c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+		Name:   "time",
+		Input:  &timeInput{ts: time.Now()},
+		Config: &internal_models.InputConfig{Name: "time"},
+	})
+	a, err := NewAgent(c)
+	assert.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run(shutdown)
+	}()
+	return shutdown, done
+}
+
+func TestAgent_RunFinalFlush(t *testing.T) {
+	c := config.NewConfig()
+	output := &chanOutput{writes: make(chan []telegraf.Metric, 10)}
+	ro := internal_models.NewRunningOutput("chan", output,
+		&internal_models.OutputConfig{Name: "chan"}, 0, 0)
+	ro.Quiet = true
+	c.Outputs = append(c.Outputs, ro)
+
+	shutdown, done := runAgent(t, c)
+	time.Sleep(50 * time.Millisecond)
+	close(shutdown)
+	<-done
+
+	// The metric gathered before the shutdown is written in the final flush
+	select {
+	case metrics := <-output.writes:
+		assert.Equal(t, 1, len(metrics))
+	default:
+		t.Fatal("metrics were not flushed on shutdown")
+	}
+}
+
+func TestAgent_RunShutdownTimeout(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.ShutdownTimeout.Duration = 50 * time.Millisecond
+	output := &blockingOutput{release: make(chan struct{})}
+	defer close(output.release)
+	ro := internal_models.NewRunningOutput("blocking", output,
+		&internal_models.OutputConfig{Name: "blocking"}, 0, 0)
+	ro.Quiet = true
+	c.Outputs = append(c.Outputs, ro)
+
+	shutdown, done := runAgent(t, c)
+	close(shutdown)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("agent did not give up the final flush after shutdown_timeout")
+	}
+}
+
+// counterInput counts its gathers, keeping the count across restarts
+type counterInput struct {
+	count int64
+}
+
+func (i *counterInput) Description() string  { return "" }
+func (i *counterInput) SampleConfig() string { return "" }
+func (i *counterInput) Gather(acc telegraf.Accumulator) error {
+	i.count++
+	acc.Add("counter", i.count, nil)
+	return nil
+}
+func (i *counterInput) GetState() interface{} { return i.count }
+func (i *counterInput) SetState(state interface{}) error {
+	i.count = state.(int64)
+	return nil
+}
+
+func TestAgent_OnceStatefile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statefile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	once := func() *counterInput {
+		c := config.NewConfig()
+		c.Agent.Statefile = filepath.Join(dir, "state.json")
+		input := &counterInput{}
+		c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+			Name:   "counter",
+			Input:  input,
+			Config: &internal_models.InputConfig{Name: "counter", ID: "abc"},
+		})
+		a, err := NewAgent(c)
+		require.NoError(t, err)
+		require.NoError(t, a.Once())
+		return input
+	}
+
+	// The second run resumes from the count stored by the first one
+	assert.Equal(t, int64(1), once().count)
+	assert.Equal(t, int64(2), once().count)
+}
+
+func TestAgent_StatefileDuplicateID(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.Statefile = "state.json"
+	for i := 0; i < 2; i++ {
+		c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+			Name:   "counter",
+			Input:  &counterInput{},
+			Config: &internal_models.InputConfig{Name: "counter", ID: "abc"},
+		})
+	}
+	_, err := NewAgent(c)
+	assert.Error(t, err)
+}
+
+// serviceInput records whether its service is running
+type serviceInput struct {
+	running bool
+}
+
+func (i *serviceInput) Description() string  { return "" }
+func (i *serviceInput) SampleConfig() string { return "" }
+func (i *serviceInput) Start() error         { i.running = true; return nil }
+func (i *serviceInput) Stop()                { i.running = false }
+func (i *serviceInput) Gather(acc telegraf.Accumulator) error {
+	acc.Add("service", 1, nil)
+	return nil
+}
+
+// closingOutput counts the metrics written to it, and records whether it is
+// closed
+type closingOutput struct {
+	written int
+	closed  bool
+}
+
+func (o *closingOutput) Connect() error       { return nil }
+func (o *closingOutput) Close() error         { o.closed = true; return nil }
+func (o *closingOutput) Description() string  { return "" }
+func (o *closingOutput) SampleConfig() string { return "" }
+func (o *closingOutput) Write(metrics []telegraf.Metric) error {
+	o.written += len(metrics)
+	return nil
+}
+
+func reloadConfig(
+	inputs map[string]*serviceInput,
+	outputs map[string]*closingOutput,
+) *config.Config {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = time.Hour
+	c.Agent.RoundInterval = false
+	c.Agent.FlushInterval.Duration = time.Hour
+	c.Agent.FlushJitter.Duration = 0
+	for _, name := range []string{"kept", "removed", "added"} {
+		if input, ok := inputs[name]; ok {
+			c.Inputs = append(c.Inputs, &internal_models.RunningInput{
+				Name:   "service",
+				Input:  input,
+				Config: &internal_models.InputConfig{Name: "service", ID: name},
+			})
+		}
+		if output, ok := outputs[name]; ok {
+			ro := internal_models.NewRunningOutput("closing", output,
+				&internal_models.OutputConfig{Name: "closing", ID: name}, 0, 0)
+			ro.Quiet = true
+			c.Outputs = append(c.Outputs, ro)
+		}
+	}
+	return c
+}
+
+func TestAgent_Reload(t *testing.T) {
+	keptInput, removedInput := &serviceInput{}, &serviceInput{}
+	keptOutput, removedOutput := &closingOutput{}, &closingOutput{}
+	a, err := NewAgent(reloadConfig(
+		map[string]*serviceInput{"kept": keptInput, "removed": removedInput},
+		map[string]*closingOutput{"kept": keptOutput, "removed": removedOutput}))
+	require.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Run(shutdown)
+	}()
+
+	addedInput := &serviceInput{}
+	c := reloadConfig(
+		map[string]*serviceInput{"kept": &serviceInput{}, "added": addedInput},
+		map[string]*closingOutput{"kept": &closingOutput{},
+			"added": &closingOutput{}})
+	require.True(t, a.CanReload(c))
+	require.NoError(t, a.Reload(c))
+
+	// The unchanged instances keep running, the metrics gathered until the
+	// reload being written before the removed output is closed
+	require.Equal(t, 2, len(a.Config.Inputs))
+	assert.True(t, a.Config.Inputs[0].Input == keptInput)
+	assert.True(t, a.Config.Inputs[1].Input == addedInput)
+	assert.True(t, keptInput.running)
+	assert.True(t, addedInput.running)
+	assert.False(t, removedInput.running)
+	require.Equal(t, 2, len(a.Config.Outputs))
+	assert.True(t, a.Config.Outputs[0].Output == keptOutput)
+	assert.False(t, keptOutput.closed)
+	assert.Equal(t, 2, keptOutput.written)
+	assert.True(t, removedOutput.closed)
+	assert.Equal(t, 2, removedOutput.written)
+
+	close(shutdown)
+	<-done
+	assert.False(t, keptInput.running)
+	assert.False(t, addedInput.running)
+}
+
+func TestAgent_CanReload(t *testing.T) {
+	c := reloadConfig(nil, nil)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	assert.True(t, a.CanReload(reloadConfig(nil, nil)))
+
+	// Changing the agent settings or the global tags restarts the agent
+	c = reloadConfig(nil, nil)
+	c.Agent.Interval.Duration = time.Minute
+	assert.False(t, a.CanReload(c))
+	c = reloadConfig(nil, nil)
+	c.Tags["dc"] = "eu"
+	assert.False(t, a.CanReload(c))
+}
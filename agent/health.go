@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// outputHealth is the status of an output reported by the health endpoints
+type outputHealth struct {
+	Name              string `json:"name"`
+	BufferSize        int    `json:"buffer_size"`
+	BufferLimit       int    `json:"buffer_limit"`
+	MetricsDropped    int64  `json:"metrics_dropped"`
+	WriteErrors       int64  `json:"write_errors"`
+	ConsecutiveErrors int64  `json:"consecutive_write_errors"`
+	Healthy           bool   `json:"healthy"`
+}
+
+type healthStatus struct {
+	Status  string         `json:"status"`
+	Outputs []outputHealth `json:"outputs"`
+}
+
+// healthHandler serves /healthz, reporting telegraf as unhealthy if an
+// output failed its last HealthMaxFailedWrites writes, and /readyz, reporting
+// whether the agent is running.
+type healthHandler struct {
+	outputs   []*internal_models.RunningOutput
+	maxFailed int64
+
+	// ready is set to 1 while the agent is running
+	ready int32
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		status := healthStatus{Status: "ok"}
+		for _, o := range h.outputs {
+			oh := outputHealth{
+				Name:              o.LogName(),
+				BufferSize:        o.BufferSize(),
+				BufferLimit:       o.BufferLimit(),
+				MetricsDropped:    o.MetricsDropped(),
+				WriteErrors:       o.WriteErrors(),
+				ConsecutiveErrors: o.ConsecutiveWriteErrors(),
+				Healthy:           true,
+			}
+			if h.maxFailed > 0 && oh.ConsecutiveErrors >= h.maxFailed {
+				oh.Healthy = false
+				status.Status = "failing"
+			}
+			status.Outputs = append(status.Outputs, oh)
+		}
+		code := http.StatusOK
+		if status.Status != "ok" {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, status)
+	case "/readyz":
+		if atomic.LoadInt32(&h.ready) == 1 {
+			writeJSON(w, http.StatusOK, healthStatus{Status: "ready"})
+		} else {
+			writeJSON(w, http.StatusServiceUnavailable,
+				healthStatus{Status: "not ready"})
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *healthHandler) setReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&h.ready, 1)
+	} else {
+		atomic.StoreInt32(&h.ready, 0)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("ERROR: writing health response: %s\n", err)
+	}
+}
+
+// startHealthServer starts serving the health endpoints on address, over TLS
+// if health_tls_cert or health_tls_pkcs12 is set, until the shutdown channel is closed.
+func (a *Agent) startHealthServer(
+	address string,
+	shutdown chan struct{},
+) (*healthHandler, error) {
+	h := &healthHandler{
+		outputs:   a.Config.Outputs,
+		maxFailed: int64(a.Config.Agent.HealthMaxFailedWrites),
+	}
+
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        a.Config.Agent.HealthTLSCert,
+		TLSKey:         a.Config.Agent.HealthTLSKey,
+		TLSPKCS12:      a.Config.Agent.HealthTLSPKCS12,
+		TLSKeyPassword: a.Config.Agent.HealthTLSKeyPassword,
+		AllowedCACerts: a.Config.Agent.HealthTLSAllowedCACerts,
+		ReloadInterval: a.Config.Agent.HealthTLSReloadInterval.Duration,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	go func() {
+		<-shutdown
+		listener.Close()
+	}()
+	go http.Serve(listener, h)
+
+	log.Printf("Serving health endpoints on %s\n", listener.Addr())
+	return h, nil
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingOutput struct {
+	onceOutput
+	fail bool
+}
+
+func (o *failingOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func getHealth(t *testing.T, h http.Handler, path string) (int, healthStatus) {
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", path, nil)
+	require.NoError(t, err)
+	h.ServeHTTP(w, req)
+
+	var status healthStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	return w.Code, status
+}
+
+func TestHealth_Healthz(t *testing.T) {
+	output := &failingOutput{fail: true}
+	ro := internal_models.NewRunningOutput("failing", output,
+		&internal_models.OutputConfig{Name: "failing"}, 0, 0)
+	ro.Quiet = true
+	h := &healthHandler{
+		outputs:   []*internal_models.RunningOutput{ro},
+		maxFailed: 2,
+	}
+
+	code, status := getHealth(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", status.Status)
+
+	ro.Write()
+	code, _ = getHealth(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	ro.Write()
+	code, status = getHealth(t, h, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "failing", status.Status)
+	require.Equal(t, 1, len(status.Outputs))
+	assert.Equal(t, "failing", status.Outputs[0].Name)
+	assert.Equal(t, int64(2), status.Outputs[0].ConsecutiveErrors)
+	assert.False(t, status.Outputs[0].Healthy)
+
+	// a successful write makes the output healthy again
+	output.fail = false
+	ro.Write()
+	code, status = getHealth(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), status.Outputs[0].WriteErrors)
+}
+
+func TestHealth_Readyz(t *testing.T) {
+	h := &healthHandler{}
+
+	code, _ := getHealth(t, h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	h.setReady(true)
+	code, status := getHealth(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", status.Status)
+}
+
+func TestHealth_TLSConfig(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.HealthTLSCert = "testdata/missing.pem"
+	c.Agent.HealthTLSKey = "testdata/missing.key"
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	_, err = a.startHealthServer("localhost:0", shutdown)
+	assert.Error(t, err)
+}
@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// reloadRequest asks Run to switch to config, the result of the switch being
+// sent on errC
+type reloadRequest struct {
+	config *config.Config
+	errC   chan error
+}
+
+// configDiff is the difference between the running config and a new one. For
+// each kind of plugin, it holds the instances of the new config, the running
+// instances with the same ID and settings being kept in place of the new
+// ones, and the instances added and removed by the new config.
+type configDiff struct {
+	inputs        []*internal_models.RunningInput
+	keptInputs    []*internal_models.RunningInput
+	addedInputs   []*internal_models.RunningInput
+	removedInputs []*internal_models.RunningInput
+
+	processors        []*internal_models.RunningProcessor
+	keptProcessors    []*internal_models.RunningProcessor
+	addedProcessors   []*internal_models.RunningProcessor
+	removedProcessors []*internal_models.RunningProcessor
+
+	aggregators        []*internal_models.RunningAggregator
+	addedAggregators   []*internal_models.RunningAggregator
+	removedAggregators []*internal_models.RunningAggregator
+
+	outputs        []*internal_models.RunningOutput
+	keptOutputs    []*internal_models.RunningOutput
+	addedOutputs   []*internal_models.RunningOutput
+	removedOutputs []*internal_models.RunningOutput
+	// unusedOutputs are the outputs of the new config replaced by running
+	// ones
+	unusedOutputs []*internal_models.RunningOutput
+}
+
+// CanReload returns true if the agent can switch to the config c with Reload,
+// that is if c has the same agent settings and global tags as the config the
+// agent was created with. Otherwise the agent must be restarted.
+func (a *Agent) CanReload(c *config.Config) bool {
+	return reflect.DeepEqual(a.settings, *c.Agent) &&
+		reflect.DeepEqual(a.tags, c.Tags)
+}
+
+// Reload switches the agent running with Run to the config c, whose plugins
+// have been initialized. The plugin instances with the same ID and settings
+// in both configs keep running. The other running instances are stopped,
+// once the metrics gathered until then are written, and the new ones are
+// started. If one fails to start, Reload returns its error and Run stops.
+func (a *Agent) Reload(c *config.Config) error {
+	errC := make(chan error)
+	a.reloadC <- reloadRequest{config: c, errC: errC}
+	return <-errC
+}
+
+// matchInstances matches the n plugin instances of a new config with the m
+// running ones, same(i, j) returning true if the running instance i and the
+// new instance j have the same ID and settings. For each new instance, it
+// returns the index of the running instance kept in its place, or -1, and
+// for each running instance whether it is kept.
+func matchInstances(m, n int, same func(i, j int) bool) ([]int, []bool) {
+	match := make([]int, n)
+	kept := make([]bool, m)
+	for j := range match {
+		match[j] = -1
+		for i := range kept {
+			if !kept[i] && same(i, j) {
+				match[j] = i
+				kept[i] = true
+				break
+			}
+		}
+	}
+	return match, kept
+}
+
+// diffConfig returns the difference between the running config and c
+func (a *Agent) diffConfig(c *config.Config) *configDiff {
+	d := &configDiff{}
+
+	match, kept := matchInstances(len(a.Config.Inputs), len(c.Inputs),
+		func(i, j int) bool {
+			return reflect.DeepEqual(a.Config.Inputs[i].Config,
+				c.Inputs[j].Config)
+		})
+	for j, input := range c.Inputs {
+		if match[j] < 0 {
+			d.addedInputs = append(d.addedInputs, input)
+		} else {
+			input = a.Config.Inputs[match[j]]
+			d.keptInputs = append(d.keptInputs, input)
+		}
+		d.inputs = append(d.inputs, input)
+	}
+	for i, input := range a.Config.Inputs {
+		if !kept[i] {
+			d.removedInputs = append(d.removedInputs, input)
+		}
+	}
+
+	match, kept = matchInstances(len(a.Config.Processors), len(c.Processors),
+		func(i, j int) bool {
+			return reflect.DeepEqual(a.Config.Processors[i].Config,
+				c.Processors[j].Config)
+		})
+	for j, processor := range c.Processors {
+		if match[j] < 0 {
+			d.addedProcessors = append(d.addedProcessors, processor)
+		} else {
+			processor = a.Config.Processors[match[j]]
+			d.keptProcessors = append(d.keptProcessors, processor)
+		}
+		d.processors = append(d.processors, processor)
+	}
+	for i, processor := range a.Config.Processors {
+		if !kept[i] {
+			d.removedProcessors = append(d.removedProcessors, processor)
+		}
+	}
+
+	match, kept = matchInstances(len(a.Config.Aggregators), len(c.Aggregators),
+		func(i, j int) bool {
+			return reflect.DeepEqual(a.Config.Aggregators[i].Config,
+				c.Aggregators[j].Config)
+		})
+	for j, aggregator := range c.Aggregators {
+		if match[j] < 0 {
+			d.addedAggregators = append(d.addedAggregators, aggregator)
+		} else {
+			aggregator = a.Config.Aggregators[match[j]]
+		}
+		d.aggregators = append(d.aggregators, aggregator)
+	}
+	for i, aggregator := range a.Config.Aggregators {
+		if !kept[i] {
+			d.removedAggregators = append(d.removedAggregators, aggregator)
+		}
+	}
+
+	match, kept = matchInstances(len(a.Config.Outputs), len(c.Outputs),
+		func(i, j int) bool {
+			running, output := a.Config.Outputs[i], c.Outputs[j]
+			return reflect.DeepEqual(running.Config, output.Config) &&
+				running.MetricBatchSize == output.MetricBatchSize &&
+				running.BufferLimit() == output.BufferLimit()
+		})
+	for j, output := range c.Outputs {
+		if match[j] < 0 {
+			d.addedOutputs = append(d.addedOutputs, output)
+		} else {
+			d.unusedOutputs = append(d.unusedOutputs, output)
+			output = a.Config.Outputs[match[j]]
+			d.keptOutputs = append(d.keptOutputs, output)
+		}
+		d.outputs = append(d.outputs, output)
+	}
+	for i, output := range a.Config.Outputs {
+		if !kept[i] {
+			d.removedOutputs = append(d.removedOutputs, output)
+		}
+	}
+	return d
+}
+
+// switchConfig switches the agent to the config of the diff, once runPlugins
+// returned. The processors and aggregators removed by the new config are
+// stopped, and all outputs written to before the removed ones are closed.
+// The added plugin instances are then started. If one fails to start, the
+// config of the agent is left with the instances still running.
+func (a *Agent) switchConfig(d *configDiff) error {
+	a.stopProcessors(d.removedProcessors)
+	for _, ra := range d.removedAggregators {
+		a.addToOutputs(ra.Push())
+	}
+	a.flush()
+	for _, o := range d.removedOutputs {
+		o.Close()
+		closeOutput(o)
+	}
+	for _, o := range d.unusedOutputs {
+		if o.WAL != nil {
+			o.WAL.Close()
+		}
+	}
+	a.storeState()
+
+	a.Config.Inputs = d.keptInputs
+	a.Config.Processors = d.keptProcessors
+	a.Config.Aggregators = d.aggregators
+	a.Config.Outputs = d.keptOutputs
+	internal_models.SetRunning(a.Config.Inputs, a.Config.Outputs)
+
+	// The WAL of an added output is opened again, a removed output with the
+	// same WAL directory having spooled its metrics to it when closed
+	for _, o := range d.addedOutputs {
+		if o.WAL == nil {
+			continue
+		}
+		o.WAL.Close()
+		wal, err := buffer.OpenWAL(o.Config.WALDirectory, o.Config.WALMaxSize,
+			o.Config.WALSegmentSize)
+		if err != nil {
+			o.WAL = nil
+			log.Printf("ERROR: output %s: %s\n", o.LogName(), err)
+			return err
+		}
+		o.WAL = wal
+	}
+
+	// Restore the state of the added instances, the state of the kept ones
+	// staying in the running plugins
+	if a.persister != nil {
+		p, err := newPersister(&config.Config{
+			Agent:       a.Config.Agent,
+			Inputs:      d.inputs,
+			Processors:  d.processors,
+			Aggregators: d.aggregators,
+			Outputs:     d.outputs,
+		})
+		if err != nil {
+			log.Printf("ERROR: %s\n", err)
+			return err
+		}
+		added, err := newPersister(&config.Config{
+			Agent:       a.Config.Agent,
+			Inputs:      d.addedInputs,
+			Processors:  d.addedProcessors,
+			Aggregators: d.addedAggregators,
+			Outputs:     d.addedOutputs,
+		})
+		if err != nil {
+			log.Printf("ERROR: %s\n", err)
+			return err
+		}
+		if err := added.Load(); err != nil {
+			log.Printf("ERROR: loading plugin states\n%s\n", err)
+			return err
+		}
+		a.persister = p
+	}
+
+	if err := a.connectOutputs(d.addedOutputs); err != nil {
+		return err
+	}
+	a.Config.Outputs = d.outputs
+	if err := a.startProcessors(d.addedProcessors); err != nil {
+		return err
+	}
+	a.Config.Processors = d.processors
+	if err := startInputs(d.addedInputs); err != nil {
+		return err
+	}
+	a.Config.Inputs = d.inputs
+	internal_models.SetRunning(a.Config.Inputs, a.Config.Outputs)
+
+	log.Printf("Reloaded config: %d plugins added, %d removed\n",
+		len(d.addedInputs)+len(d.addedProcessors)+len(d.addedAggregators)+
+			len(d.addedOutputs),
+		len(d.removedInputs)+len(d.removedProcessors)+
+			len(d.removedAggregators)+len(d.removedOutputs))
+	return nil
+}
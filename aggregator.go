@@ -0,0 +1,20 @@
+package telegraf
+
+type Aggregator interface {
+	// SampleConfig returns the default configuration of the Aggregator
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Aggregator
+	Description() string
+
+	// Add the metric to the aggregator
+	Add(in Metric)
+
+	// Push pushes the current aggregates to the accumulator. This is called
+	// at the end of every "period"
+	Push(acc Accumulator)
+
+	// Reset resets the aggregator's caches and aggregates. This is called
+	// after every Push
+	Reset()
+}
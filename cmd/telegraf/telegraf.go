@@ -4,15 +4,24 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/logger"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/all"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
 )
 
 var fDebug = flag.Bool("debug", false,
@@ -20,9 +29,15 @@ var fDebug = flag.Bool("debug", false,
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fOnce = flag.Bool("once", false,
+	"gather metrics once, write them to the outputs, and exit")
+var fValidate = flag.Bool("validate", false,
+	"validate the configuration, without gathering metrics, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the config when the config file or directory changes")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -33,6 +48,12 @@ var fOutputFilters = flag.String("output-filter", "",
 	"filter the outputs to enable, separator is :")
 var fUsage = flag.String("usage", "",
 	"print usage for a plugin, ie, 'telegraf -usage mysql'")
+var fPprofAddr = flag.String("pprof-addr", "",
+	"pprof address to listen on, don't activate pprof if empty")
+var fService = flag.String("service", "",
+	"operate on the Windows service (install|uninstall|start|stop)")
+var fServiceName = flag.String("service-name", "telegraf",
+	"name of the Windows service")
 
 var fInputFiltersLegacy = flag.String("filter", "",
 	"filter the inputs to enable, separator is :")
@@ -41,6 +62,21 @@ var fOutputFiltersLegacy = flag.String("outputfilter", "",
 var fConfigDirectoryLegacy = flag.String("configdirectory", "",
 	"directory containing additional *.conf files")
 
+// Exit codes, telling the scripts and timers running telegraf why it failed
+const (
+	// exitError is returned on runtime errors, ie an output failing to
+	// connect, or an input failing to gather or an output failing to write
+	// with -once
+	exitError = 1
+	// exitConfigError is returned if the config cannot be loaded or is
+	// invalid, like the flag package does for invalid flags
+	exitConfigError = 2
+)
+
+// watchInterval is how often the config files are checked for changes with
+// -watch-config
+const watchInterval = 5 * time.Second
+
 // Telegraf version
 //	-ldflags "-X main.Version=`git describe --always --tags`"
 var Version string
@@ -50,20 +86,58 @@ const usage = `Telegraf, The plugin-driven server agent for collecting and repor
 Usage:
 
   telegraf <flags>
+  telegraf config <filter flags>
+  telegraf plugins <plugins flags>
 
 The flags are:
 
   -config <file>     configuration file to load
   -test              gather metrics once, print them to stdout, and exit
+  -once              gather metrics once, write them to the outputs, and exit,
+                     with a non-zero status if an input or output failed
+  -validate          load the configuration and initialize all plugins,
+                     reporting any error, without gathering metrics, and exit
   -sample-config     print out full sample configuration to stdout
-  -config-directory  directory containing additional *.conf files
+  -config-directory  directory containing additional *.conf files, defaults
+                     to $TELEGRAF_CONFIG_DIRECTORY
+  -watch-config      reload the config when the config file or the *.conf
+                     files of the config directory change
   -input-filter      filter the input plugins to enable, separator is :
   -output-filter     filter the output plugins to enable, separator is :
   -usage             print usage for a plugin, ie, 'telegraf -usage mysql'
+  -pprof-addr        pprof address to listen on, ie, 'localhost:6060', format
+                     is host:port, pprof is not activated if empty
   -debug             print metrics as they're generated to stdout
   -quiet             run in quiet mode
   -version           print the version to stdout
 
+The config command prints a sample configuration, limited by the filters:
+
+  -section-filter    sections to include, separator is :, out of
+                     global_tags:agent:outputs:processors:aggregators:
+                     secretstores:inputs
+  -input-filter      input plugins to include, separator is :
+  -output-filter     output plugins to include, separator is :
+  -processor-filter  processor plugins to include, separator is :
+  -aggregator-filter aggregator plugins to include, separator is :
+
+The plugins command lists the plugins compiled into telegraf, all of them
+unless limited to some categories:
+
+  -inputs            list the input plugins
+  -outputs           list the output plugins
+  -processors        list the processor plugins
+  -aggregators       list the aggregator plugins
+  -secretstores      list the secret stores
+  -deprecated        list only the deprecated plugins
+  -sample-config     print the sample config of the plugins instead of their
+                     description
+
+On Windows only:
+
+  -service           operate on the service (install|uninstall|start|stop)
+  -service-name      name of the service, defaults to telegraf
+
 Examples:
 
   # generate a telegraf config file:
@@ -72,25 +146,91 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf -sample-config -input-filter cpu -output-filter influxdb
 
+  # generate only the inputs section, with the cpu and mem inputs
+  telegraf config -section-filter inputs -input-filter cpu:mem
+
+  # list the input and output plugins of this telegraf binary
+  telegraf plugins -inputs -outputs
+
   # run a single telegraf collection, outputing metrics to stdout
   telegraf -config telegraf.conf -test
 
+  # run a single telegraf collection, writing metrics to the outputs
+  telegraf -config telegraf.conf -once
+
+  # check a config file, exiting with a non-zero status if it is invalid
+  telegraf -config telegraf.conf -validate
+
   # run telegraf with all plugins defined in config file
   telegraf -config telegraf.conf
 
+  # run telegraf with the config file and the *.conf files in telegraf.d
+  telegraf -config telegraf.conf -config-directory /etc/telegraf/telegraf.d
+
+  # run telegraf, reloading the config files when they change
+  telegraf -config telegraf.conf -watch-config
+
   # run telegraf, enabling the cpu & memory input, and influxdb output plugins
   telegraf -config telegraf.conf -input-filter cpu:mem -output-filter influxdb
+
+  # install telegraf as a Windows service, running with the given config
+  telegraf -service install -config "C:\Program Files\Telegraf\telegraf.conf"
 `
 
+// eventLogSource is the Windows event log source telegraf logs to, if set
+// and no logfile is configured
+var eventLogSource string
+
 func main() {
+	flag.Usage = func() { usageExit(0) }
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "config":
+		printConfig(flag.Args()[1:])
+		return
+	case "plugins":
+		printPlugins(flag.Args()[1:])
+		return
+	}
+
+	// runService handles the -service flag and running under the Windows
+	// service control manager
+	if runService() {
+		return
+	}
+	reloadLoop(make(chan struct{}))
+}
+
+// reloadLoop runs telegraf until it is interrupted or stop is closed,
+// reloading the config on SIGHUP.
+func reloadLoop(stop chan struct{}) {
 	reload := make(chan bool, 1)
 	reload <- true
+	pprofStarted := false
 	for <-reload {
 		reload <- false
-		flag.Usage = func() { usageExit(0) }
-		flag.Parse()
 
-		if flag.NFlag() == 0 {
+		// The pprof server keeps running across config reloads
+		if *fPprofAddr != "" && !pprofStarted {
+			pprofStarted = true
+			go func() {
+				pprofHostPort := *fPprofAddr
+				log.Printf("Starting pprof HTTP server at: %s\n", pprofHostPort)
+				log.Printf("Profiles are available at: http://%s/debug/pprof\n",
+					pprofHostPort)
+				if err := http.ListenAndServe(pprofHostPort, nil); err != nil {
+					log.Printf("ERROR: pprof server failed: %s\n", err)
+				}
+			}()
+		}
+
+		if *fConfigDirectory == "" {
+			*fConfigDirectory = os.Getenv("TELEGRAF_CONFIG_DIRECTORY")
+		}
+
+		// The config directory of the environment counts as a flag
+		if flag.NFlag() == 0 && *fConfigDirectory == "" {
 			usageExit(0)
 		}
 
@@ -128,53 +268,51 @@ func main() {
 		if *fUsage != "" {
 			if err := config.PrintInputConfig(*fUsage); err != nil {
 				if err2 := config.PrintOutputConfig(*fUsage); err2 != nil {
-					log.Fatalf("%s and %s", err, err2)
+					if err3 := config.PrintProcessorConfig(*fUsage); err3 != nil {
+						if err4 := config.PrintAggregatorConfig(*fUsage); err4 != nil {
+							if err5 := config.PrintSecretStoreConfig(*fUsage); err5 != nil {
+								log.Fatalf("%s, %s, %s, %s and %s",
+									err, err2, err3, err4, err5)
+							}
+						}
+					}
 				}
 			}
 			return
 		}
 
-		var (
-			c   *config.Config
-			err error
-		)
-
-		if *fConfig != "" {
-			c = config.NewConfig()
-			c.OutputFilters = outputFilters
-			c.InputFilters = inputFilters
-			err = c.LoadConfig(*fConfig)
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
+		if *fConfig == "" && *fConfigDirectory == "" &&
+			*fConfigDirectoryLegacy == "" {
 			fmt.Println("You must specify a config file. See telegraf --help")
-			os.Exit(1)
+			os.Exit(exitConfigError)
 		}
 
-		if *fConfigDirectoryLegacy != "" {
-			err = c.LoadDirectory(*fConfigDirectoryLegacy)
-			if err != nil {
-				log.Fatal(err)
+		if *fValidate {
+			if err := validateConfig(inputFilters, outputFilters); err != nil {
+				fmt.Println(err)
+				os.Exit(exitConfigError)
 			}
+			return
 		}
 
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal(err)
-			}
+		// The watcher is created before the config is loaded, so that no
+		// change made while loading it is missed
+		var watcher *config.Watcher
+		if *fWatchConfig {
+			watcher = config.NewWatcher(configPaths()...)
 		}
-		if len(c.Outputs) == 0 {
-			log.Fatalf("Error: no outputs found, did you provide a valid config file?")
+
+		c, err := loadConfig(inputFilters, outputFilters)
+		if err != nil {
+			fatal(exitConfigError, err)
 		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("Error: no inputs found, did you provide a valid config file?")
+		if err := c.InitPlugins(); err != nil {
+			fatal(exitConfigError, err)
 		}
 
 		ag, err := agent.NewAgent(c)
 		if err != nil {
-			log.Fatal(err)
+			fatal(exitConfigError, err)
 		}
 
 		if *fDebug {
@@ -193,30 +331,109 @@ func main() {
 			return
 		}
 
+		err = logger.SetupLogging(logger.LogConfig{
+			Debug:               c.Agent.Debug,
+			Quiet:               c.Agent.Quiet,
+			LogFormat:           c.Agent.LogFormat,
+			Logfile:             c.Agent.Logfile,
+			RotationInterval:    c.Agent.LogfileRotationInterval.Duration,
+			RotationMaxSize:     c.Agent.LogfileRotationMaxSize.Size,
+			RotationMaxArchives: c.Agent.LogfileRotationMaxArchives,
+			EventLog:            eventLogSource,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		err = ag.Connect()
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		if *fOnce {
+			err = ag.Once()
+			ag.Close()
+			if err != nil {
+				fatal(exitError, err)
+			}
+			return
+		}
+
 		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
-		go func() {
-			sig := <-signals
-			if sig == os.Interrupt {
-				close(shutdown)
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+
+		configChanged := make(chan struct{}, 1)
+		if watcher != nil {
+			go watcher.Watch(watchInterval, configChanged, shutdown)
+		}
+
+		// reloadConfig reloads the config only if the new config is valid,
+		// otherwise telegraf keeps running with the current one. Only the
+		// plugin instances that changed are restarted, unless the agent
+		// settings or the global tags changed, or an added instance fails to
+		// start, which restart the whole agent. It returns true if the agent
+		// is restarted.
+		reloadConfig := func() bool {
+			newConfig, err := loadConfig(inputFilters, outputFilters)
+			if err == nil {
+				err = newConfig.InitPlugins()
+			}
+			if err != nil {
+				log.Printf("ERROR: not reloading Telegraf config: %s\n", err)
+				return false
+			}
+			log.Printf("Reloading Telegraf config\n")
+			<-reload
+			reload <- true
+			if ag.CanReload(newConfig) {
+				err := ag.Reload(newConfig)
+				if err == nil {
+					<-reload
+					reload <- false
+					return false
+				}
+				log.Printf("ERROR: reloading Telegraf config failed, "+
+					"restarting Telegraf: %s\n", err)
 			}
-			if sig == syscall.SIGHUP {
-				log.Printf("Reloading Telegraf config\n")
-				<-reload
-				reload <- true
-				close(shutdown)
+			close(shutdown)
+			return true
+		}
+
+		go func() {
+			for {
+				select {
+				case sig := <-signals:
+					if sig == os.Interrupt || sig == syscall.SIGTERM {
+						close(shutdown)
+						return
+					}
+					if sig == syscall.SIGHUP && reloadConfig() {
+						return
+					}
+				case <-configChanged:
+					log.Printf("Telegraf config changed\n")
+					if reloadConfig() {
+						return
+					}
+				case <-stop:
+					close(shutdown)
+					return
+				}
 			}
 		}()
 
 		log.Printf("Starting Telegraf (version %s)\n", Version)
 		log.Printf("Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
 		log.Printf("Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+		if len(c.Processors) > 0 {
+			log.Printf("Loaded processors: %s",
+				strings.Join(c.ProcessorNames(), " "))
+		}
+		if len(c.Aggregators) > 0 {
+			log.Printf("Loaded aggregators: %s",
+				strings.Join(c.AggregatorNames(), " "))
+		}
 		log.Printf("Tags enabled: %s", c.ListTags())
 
 		if *fPidfile != "" {
@@ -231,7 +448,160 @@ func main() {
 		}
 
 		ag.Run(shutdown)
+		signal.Stop(signals)
+	}
+}
+
+// loadConfig loads the config file and config directories given on the
+// command line, returning an error if they are invalid or define no inputs
+// or outputs.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if *fConfig != "" {
+		if err := c.LoadConfig(*fConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	if *fConfigDirectoryLegacy != "" {
+		if err := c.LoadDirectory(*fConfigDirectoryLegacy); err != nil {
+			return nil, err
+		}
+	}
+
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.Outputs) == 0 {
+		return nil, fmt.Errorf("Error: no outputs found, " +
+			"did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, fmt.Errorf("Error: no inputs found, " +
+			"did you provide a valid config file?")
+	}
+	return c, nil
+}
+
+// configPaths returns the config file and config directories given on the
+// command line
+func configPaths() []string {
+	var paths []string
+	for _, path := range []string{
+		*fConfig, *fConfigDirectoryLegacy, *fConfigDirectory,
+	} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// printConfig runs the config command, printing the sample config limited by
+// the filters given in args.
+func printConfig(args []string) {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	fs.Usage = func() { usageExit(0) }
+	sectionFilters := fs.String("section-filter", "", "")
+	inputFilters := fs.String("input-filter", "", "")
+	outputFilters := fs.String("output-filter", "", "")
+	processorFilters := fs.String("processor-filter", "", "")
+	aggregatorFilters := fs.String("aggregator-filter", "", "")
+	fs.Parse(args)
+
+	filters := config.SampleConfigFilters{
+		Sections:    splitFilter(*sectionFilters),
+		Inputs:      splitFilter(*inputFilters),
+		Outputs:     splitFilter(*outputFilters),
+		Processors:  splitFilter(*processorFilters),
+		Aggregators: splitFilter(*aggregatorFilters),
 	}
+	for _, section := range filters.Sections {
+		if !sliceContains(section, config.SampleConfigSections) {
+			fmt.Printf("Unknown section %q, the sections are %s\n", section,
+				strings.Join(config.SampleConfigSections, ":"))
+			os.Exit(1)
+		}
+	}
+	config.PrintFilteredSampleConfig(os.Stdout, filters)
+}
+
+// printPlugins runs the plugins command, listing the plugins compiled into
+// telegraf.
+func printPlugins(args []string) {
+	fs := flag.NewFlagSet("plugins", flag.ExitOnError)
+	fs.Usage = func() { usageExit(0) }
+	categories := make(map[string]*bool)
+	for _, category := range config.PluginCategories {
+		categories[category] = fs.Bool(category, false, "")
+	}
+	deprecated := fs.Bool("deprecated", false, "")
+	sampleConfig := fs.Bool("sample-config", false, "")
+	fs.Parse(args)
+
+	opts := config.PluginListOptions{
+		Deprecated:   *deprecated,
+		SampleConfig: *sampleConfig,
+	}
+	for _, category := range config.PluginCategories {
+		if *categories[category] {
+			opts.Categories = append(opts.Categories, category)
+		}
+	}
+	config.PrintPluginList(os.Stdout, opts)
+}
+
+// splitFilter splits a filter flag on ":"
+func splitFilter(filter string) []string {
+	var names []string
+	for _, name := range strings.Split(strings.TrimSpace(filter), ":") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func sliceContains(name string, list []string) bool {
+	for _, b := range list {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateConfig loads the config and initializes its plugins, printing a
+// summary of the plugins if the config is valid.
+func validateConfig(inputFilters, outputFilters []string) error {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
+	if err := c.InitPlugins(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Configuration is valid\n")
+	fmt.Printf("Inputs: %s\n", strings.Join(c.InputNames(), " "))
+	fmt.Printf("Outputs: %s\n", strings.Join(c.OutputNames(), " "))
+	if len(c.Processors) > 0 {
+		fmt.Printf("Processors: %s\n", strings.Join(c.ProcessorNames(), " "))
+	}
+	if len(c.Aggregators) > 0 {
+		fmt.Printf("Aggregators: %s\n", strings.Join(c.AggregatorNames(), " "))
+	}
+	return nil
+}
+
+// fatal logs err and exits with the given exit code
+func fatal(code int, err error) {
+	log.Println(err)
+	os.Exit(code)
 }
 
 func usageExit(rc int) {
@@ -0,0 +1,12 @@
+// +build !windows
+
+package main
+
+import "log"
+
+func runService() bool {
+	if *fService != "" {
+		log.Fatalf("-service is only supported on Windows")
+	}
+	return false
+}
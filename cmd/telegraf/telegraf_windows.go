@@ -0,0 +1,197 @@
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// runService executes the -service command, or runs telegraf as a service if
+// it was started by the Windows service control manager. It returns false if
+// telegraf should run in the foreground.
+func runService() bool {
+	if *fService != "" {
+		if err := controlService(*fService, *fServiceName); err != nil {
+			log.Fatalf("Unable to %s service %s: %s", *fService,
+				*fServiceName, err)
+		}
+		return true
+	}
+
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		log.Fatalf("Unable to determine if running as a service: %s", err)
+	}
+	if !isService {
+		return false
+	}
+
+	eventLogSource = *fServiceName
+	if err := svc.Run(*fServiceName, &service{}); err != nil {
+		log.Fatalf("Unable to run service %s: %s", *fServiceName, err)
+	}
+	return true
+}
+
+// service runs telegraf under the service control manager, stopping the
+// agent cleanly when the service is stopped or the system shuts down.
+type service struct{}
+
+func (s *service) Execute(
+	args []string,
+	requests <-chan svc.ChangeRequest,
+	changes chan<- svc.Status,
+) (bool, uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+	changes <- svc.Status{State: svc.StartPending}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		reloadLoop(stop)
+		close(done)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: accepted}
+	for {
+		select {
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				changes <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				close(stop)
+				<-done
+				return false, 0
+			}
+		case <-done:
+			return false, 0
+		}
+	}
+}
+
+func controlService(command, name string) error {
+	switch command {
+	case "install":
+		return installService(name)
+	case "uninstall":
+		return uninstallService(name)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	switch command {
+	case "start":
+		return s.Start()
+	case "stop":
+		status, err := s.Control(svc.Stop)
+		if err != nil {
+			return err
+		}
+		timeout := time.Now().Add(30 * time.Second)
+		for status.State != svc.Stopped {
+			if time.Now().After(timeout) {
+				return fmt.Errorf("timed out waiting for the service to stop")
+			}
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, must be one of "+
+			"install, uninstall, start or stop", command)
+	}
+}
+
+// installService installs telegraf as a service starting automatically, with
+// the config given on the command line, and registers it as an event log
+// source.
+func installService(name string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	if *fConfig != "" {
+		path, err := filepath.Abs(*fConfig)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-config", path)
+	}
+	if *fConfigDirectory != "" {
+		path, err := filepath.Abs(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-config-directory", path)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("a config file or directory is required")
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "Telegraf Data Collector Service",
+		Description: "Collects data using a series of plugins and " +
+			"publishes it to another series of plugins.",
+		StartType: mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = eventlog.InstallAsEventCreate(name,
+		eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil {
+		s.Delete()
+		return err
+	}
+	return nil
+}
+
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	return eventlog.Remove(name)
+}
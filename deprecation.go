@@ -0,0 +1,14 @@
+package telegraf
+
+// DeprecationInfo describes a deprecated plugin. Deprecated plugin options
+// are described with the `deprecated:"since;removal;notice"` struct tag on
+// the option's field instead, and may name the field replacing them with the
+// `migrate:"Field"` struct tag.
+type DeprecationInfo struct {
+	// Since is the version the plugin was deprecated in
+	Since string
+	// RemovalIn is the version the plugin will be removed in
+	RemovalIn string
+	// Notice is a hint about the replacement, ie "use 'inputs.diskio' instead"
+	Notice string
+}
@@ -0,0 +1,9 @@
+package telegraf
+
+// Initializer is implemented by plugins needing to validate their config or
+// set themselves up before they are used.
+type Initializer interface {
+	// Init is called once, after the config of the plugin is loaded and
+	// before the plugin is started or used. An error aborts the startup.
+	Init() error
+}
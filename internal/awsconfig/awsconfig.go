@@ -0,0 +1,98 @@
+// Package awsconfig creates the configs of the AWS clients of the plugins
+// from the options they share: region, credentials, endpoint and proxies.
+//
+// The config is not embedded in the plugins, which declare the options as
+// their own fields and copy them to a Config:
+//
+//	type MyPlugin struct {
+//		Region      string `toml:"region"`
+//		AccessKey   string `toml:"access_key"`
+//		SecretKey   string `toml:"secret_key"`
+//		Token       string `toml:"token"`
+//		RoleARN     string `toml:"role_arn"`
+//		Profile     string `toml:"profile"`
+//		Filename    string `toml:"shared_credential_file"`
+//		EndpointURL string `toml:"endpoint_url"`
+//	}
+package awsconfig
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Config are the options of an AWS client
+type Config struct {
+	Region string
+
+	// Static credentials, used instead of the credential chain if set
+	AccessKey string
+	SecretKey string
+	Token     string
+	// RoleARN is a role assumed with the credentials
+	RoleARN string
+	// Profile and Filename of the shared credentials file, the default
+	// profile of ~/.aws/credentials if empty
+	Profile  string
+	Filename string
+
+	// EndpointURL overrides the endpoint of the service, ie to use a local
+	// mock or a VPC endpoint
+	EndpointURL string
+	Proxy       internal.HTTPProxyOptions
+}
+
+// Credentials returns the credentials of the config: the static
+// credentials if set, else the first of the chain of the EC2 instance role,
+// the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables and
+// the shared credentials file. With a role ARN, the credentials assume that
+// role.
+func (c *Config) Credentials() *credentials.Credentials {
+	var creds *credentials.Credentials
+	if c.AccessKey != "" || c.SecretKey != "" {
+		creds = credentials.NewStaticCredentials(c.AccessKey, c.SecretKey,
+			c.Token)
+	} else if c.Profile != "" || c.Filename != "" {
+		creds = credentials.NewSharedCredentials(c.Filename, c.Profile)
+	} else {
+		creds = credentials.NewChainCredentials(
+			[]credentials.Provider{
+				&ec2rolecreds.EC2RoleProvider{
+					Client: ec2metadata.New(session.New()),
+				},
+				&credentials.EnvProvider{},
+				&credentials.SharedCredentialsProvider{},
+			})
+	}
+	if c.RoleARN == "" {
+		return creds
+	}
+	return stscreds.NewCredentials(session.New(&aws.Config{
+		Region:      aws.String(c.Region),
+		Credentials: creds,
+	}), c.RoleARN)
+}
+
+// NewSession returns a session with the region, credentials, endpoint and
+// proxies of the config, the clients of the services being created from it
+func (c *Config) NewSession() (*session.Session, error) {
+	client, err := internal.NewHTTPClient(0, c.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	config := &aws.Config{
+		Region:      aws.String(c.Region),
+		HTTPClient:  client,
+		Credentials: c.Credentials(),
+	}
+	if c.EndpointURL != "" {
+		config.Endpoint = aws.String(c.EndpointURL)
+	}
+	return session.New(config), nil
+}
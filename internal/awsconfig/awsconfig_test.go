@@ -0,0 +1,57 @@
+package awsconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentials_Static(t *testing.T) {
+	c := Config{AccessKey: "AKID", SecretKey: "SECRET", Token: "TOKEN"}
+	value, err := c.Credentials().Get()
+	require.NoError(t, err)
+	assert.Equal(t, "AKID", value.AccessKeyID)
+	assert.Equal(t, "SECRET", value.SecretAccessKey)
+	assert.Equal(t, "TOKEN", value.SessionToken)
+}
+
+func TestCredentials_SharedFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "credentials")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("[default]\n" +
+		"aws_access_key_id = DEFAULT\n" +
+		"aws_secret_access_key = DEFAULT_SECRET\n" +
+		"[telegraf]\n" +
+		"aws_access_key_id = TELEGRAF\n" +
+		"aws_secret_access_key = TELEGRAF_SECRET\n")
+	require.NoError(t, err)
+	f.Close()
+
+	c := Config{Profile: "telegraf", Filename: f.Name()}
+	value, err := c.Credentials().Get()
+	require.NoError(t, err)
+	assert.Equal(t, "TELEGRAF", value.AccessKeyID)
+	assert.Equal(t, "TELEGRAF_SECRET", value.SecretAccessKey)
+
+	c = Config{Filename: f.Name()}
+	value, err = c.Credentials().Get()
+	require.NoError(t, err)
+	assert.Equal(t, "DEFAULT", value.AccessKeyID)
+}
+
+func TestNewSession(t *testing.T) {
+	c := Config{
+		Region:      "us-east-1",
+		AccessKey:   "AKID",
+		SecretKey:   "SECRET",
+		EndpointURL: "http://localhost:4568",
+	}
+	s, err := c.NewSession()
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", *s.Config.Region)
+	assert.Equal(t, "http://localhost:4568", *s.Config.Endpoint)
+}
@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Buffer is a fixed size buffer of metrics. When it is full, adding metrics
+// drops the oldest metrics in the buffer.
+type Buffer struct {
+	sync.Mutex
+	buf []telegraf.Metric
+	// first is the index of the oldest metric in buf
+	first int
+	// size is the number of metrics in buf
+	size int
+	// batched is the number of the oldest metrics returned by the last
+	// Batch that are still in buf, metrics added meanwhile to a full buffer
+	// overwriting them
+	batched int
+
+	dropped int64
+	total   int64
+}
+
+// NewBuffer returns a Buffer holding at most size metrics.
+func NewBuffer(size int) *Buffer {
+	if size < 1 {
+		size = 1
+	}
+	return &Buffer{
+		buf: make([]telegraf.Metric, size),
+	}
+}
+
+// IsEmpty returns true if Buffer is empty.
+func (b *Buffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the current length of the buffer.
+func (b *Buffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+	return b.size
+}
+
+// Cap returns the maximum number of metrics the buffer can hold.
+func (b *Buffer) Cap() int {
+	return len(b.buf)
+}
+
+// Dropped returns the total number of metrics dropped because the buffer
+// was full.
+func (b *Buffer) Dropped() int64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.dropped
+}
+
+// Total returns the total number of metrics added to the buffer.
+func (b *Buffer) Total() int64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.total
+}
+
+// Add adds metrics to the buffer, dropping the oldest metrics if the buffer
+// is full. It returns the number of metrics dropped.
+func (b *Buffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		b.total++
+		if b.size == len(b.buf) {
+			// overwrite the oldest metric
+			b.buf[b.first] = m
+			b.first = (b.first + 1) % len(b.buf)
+			if b.batched > 0 {
+				b.batched--
+			}
+			dropped++
+			continue
+		}
+		b.buf[(b.first+b.size)%len(b.buf)] = m
+		b.size++
+	}
+	b.dropped += int64(dropped)
+	return dropped
+}
+
+// Batch returns up to batchSize of the oldest metrics in the buffer, without
+// removing them. Once the batch has been written, Remove must be called
+// to remove it from the buffer. Metrics may be added while the batch is
+// being written.
+func (b *Buffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	n := batchSize
+	if n > b.size || n < 0 {
+		n = b.size
+	}
+	out := make([]telegraf.Metric, n)
+	for i := range out {
+		out[i] = b.buf[(b.first+i)%len(b.buf)]
+	}
+	b.batched = n
+	return out
+}
+
+// Remove removes the n oldest metrics of the last batch from the buffer.
+// The metrics of the batch already dropped by Add, to make room for newer
+// metrics, are not removed again.
+func (b *Buffer) Remove(n int) {
+	b.Lock()
+	defer b.Unlock()
+
+	if n > b.batched {
+		n = b.batched
+	}
+	for i := 0; i < n; i++ {
+		b.buf[(b.first+i)%len(b.buf)] = nil
+	}
+	b.first = (b.first + n) % len(b.buf)
+	b.size -= n
+	b.batched = 0
+}
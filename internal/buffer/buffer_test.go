@@ -0,0 +1,92 @@
+package buffer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(value int64) telegraf.Metric {
+	m, _ := telegraf.NewMetric("test",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": value},
+		time.Unix(value, 0))
+	return m
+}
+
+func values(metrics []telegraf.Metric) []int64 {
+	var out []int64
+	for _, m := range metrics {
+		out = append(out, m.Fields()["value"].(int64))
+	}
+	return out
+}
+
+func TestNewBufferBasicFuncs(t *testing.T) {
+	b := NewBuffer(10)
+
+	assert.True(t, b.IsEmpty())
+	assert.Equal(t, 0, b.Len())
+	assert.Equal(t, 10, b.Cap())
+	assert.Equal(t, int64(0), b.Dropped())
+	assert.Equal(t, int64(0), b.Total())
+
+	b.Add(newMetric(1))
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 1, b.Len())
+	assert.Equal(t, int64(1), b.Total())
+}
+
+func TestBufferBatchAndRemove(t *testing.T) {
+	b := NewBuffer(5)
+	b.Add(newMetric(1), newMetric(2), newMetric(3))
+
+	assert.Equal(t, []int64{1, 2}, values(b.Batch(2)))
+	// Batch doesn't remove metrics
+	assert.Equal(t, []int64{1, 2, 3}, values(b.Batch(10)))
+
+	b.Remove(2)
+	assert.Equal(t, 1, b.Len())
+	assert.Equal(t, []int64{3}, values(b.Batch(10)))
+
+	b.Remove(10)
+	assert.True(t, b.IsEmpty())
+	assert.Equal(t, 0, len(b.Batch(10)))
+}
+
+// Test that the oldest metrics are dropped when the buffer is full
+func TestBufferOverflow(t *testing.T) {
+	b := NewBuffer(3)
+	assert.Equal(t, 0, b.Add(newMetric(1), newMetric(2)))
+	assert.Equal(t, 2, b.Add(newMetric(3), newMetric(4), newMetric(5)))
+
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, int64(2), b.Dropped())
+	assert.Equal(t, int64(5), b.Total())
+	assert.Equal(t, []int64{3, 4, 5}, values(b.Batch(10)))
+
+	// Wrap around after a partial removal
+	b.Remove(1)
+	b.Add(newMetric(6), newMetric(7))
+	assert.Equal(t, int64(3), b.Dropped())
+	assert.Equal(t, []int64{5, 6, 7}, values(b.Batch(10)))
+}
+
+// Test that metrics added while a batch is written are not removed with it
+func TestBufferAddDuringBatch(t *testing.T) {
+	b := NewBuffer(3)
+	b.Add(newMetric(1), newMetric(2), newMetric(3))
+	assert.Equal(t, []int64{1, 2}, values(b.Batch(2)))
+
+	// 4 and 5 overwrite 1 and 2, which are not removed again
+	b.Add(newMetric(4), newMetric(5))
+	b.Remove(2)
+	assert.Equal(t, []int64{3, 4, 5}, values(b.Batch(10)))
+
+	// 6 only overwrites 3, the rest of the batch is removed
+	b.Add(newMetric(6))
+	b.Remove(3)
+	assert.Equal(t, []int64{6}, values(b.Batch(10)))
+}
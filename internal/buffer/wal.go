@@ -0,0 +1,217 @@
+package buffer
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+const walSuffix = ".wal"
+
+// WAL is a write-ahead log spooling metrics to disk, in segment files of
+// line protocol. Metrics are read back one segment at a time, oldest first.
+// When the total size of the segments exceeds the maximum size, the oldest
+// segments are deleted.
+//
+// WAL is not safe for concurrent use.
+type WAL struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	// segments are the segment files, oldest first. The last one is open
+	// for writing if current is not nil.
+	segments []*walSegment
+	current  *os.File
+	nextID   uint64
+}
+
+type walSegment struct {
+	id   uint64
+	path string
+	size int64
+}
+
+// OpenWAL opens the WAL in dir, creating the directory if needed. Segments
+// left in the directory by a previous run are kept, to be read first.
+func OpenWAL(dir string, maxSize, segmentSize int64) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create WAL directory: %s", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read WAL directory: %s", err)
+	}
+
+	w := &WAL{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		nextID:      1,
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), walSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(f.Name(), walSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments = append(w.segments, &walSegment{
+			id:   id,
+			path: filepath.Join(dir, f.Name()),
+			size: f.Size(),
+		})
+		if id >= w.nextID {
+			w.nextID = id + 1
+		}
+	}
+	sort.Sort(byID(w.segments))
+	return w, nil
+}
+
+// IsEmpty returns true if there are no metrics in the WAL.
+func (w *WAL) IsEmpty() bool {
+	return len(w.segments) == 0
+}
+
+// Size returns the total size of the segments in bytes.
+func (w *WAL) Size() int64 {
+	var size int64
+	for _, s := range w.segments {
+		size += s.size
+	}
+	return size
+}
+
+// Append writes the metrics to the current segment, starting a new segment
+// when the current one is full.
+func (w *WAL) Append(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	if w.current == nil {
+		if err := w.newSegment(); err != nil {
+			return err
+		}
+	}
+
+	buf := bufio.NewWriter(w.current)
+	var n int64
+	for _, m := range metrics {
+		line := m.String()
+		if _, err := buf.WriteString(line); err != nil {
+			return err
+		}
+		if err := buf.WriteByte('\n'); err != nil {
+			return err
+		}
+		n += int64(len(line)) + 1
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	seg := w.segments[len(w.segments)-1]
+	seg.size += n
+	if seg.size >= w.segmentSize {
+		if err := w.closeSegment(); err != nil {
+			return err
+		}
+	}
+
+	w.enforceMaxSize()
+	return nil
+}
+
+// Oldest returns the metrics of the oldest segment. If it is the segment
+// being written, the segment is closed so it can be removed afterwards.
+func (w *WAL) Oldest() ([]telegraf.Metric, error) {
+	if len(w.segments) == 0 {
+		return nil, nil
+	}
+	if len(w.segments) == 1 && w.current != nil {
+		if err := w.closeSegment(); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := ioutil.ReadFile(w.segments[0].path)
+	if err != nil {
+		return nil, err
+	}
+	return telegraf.ParseMetrics(b)
+}
+
+// RemoveOldest deletes the oldest segment, once its metrics were written.
+func (w *WAL) RemoveOldest() error {
+	if len(w.segments) == 0 {
+		return nil
+	}
+	if len(w.segments) == 1 && w.current != nil {
+		if err := w.closeSegment(); err != nil {
+			return err
+		}
+	}
+
+	seg := w.segments[0]
+	w.segments = w.segments[1:]
+	return os.Remove(seg.path)
+}
+
+// Close closes the segment being written.
+func (w *WAL) Close() error {
+	if w.current == nil {
+		return nil
+	}
+	return w.closeSegment()
+}
+
+func (w *WAL) newSegment() error {
+	path := filepath.Join(w.dir, fmt.Sprintf("%020d%s", w.nextID, walSuffix))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to create WAL segment: %s", err)
+	}
+	w.segments = append(w.segments, &walSegment{id: w.nextID, path: path})
+	w.current = f
+	w.nextID++
+	return nil
+}
+
+func (w *WAL) closeSegment() error {
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// enforceMaxSize deletes the oldest segments until the WAL is within its
+// maximum size. The segment being written is never deleted.
+func (w *WAL) enforceMaxSize() {
+	for w.Size() > w.maxSize && len(w.segments) > 1 {
+		seg := w.segments[0]
+		log.Printf("WARNING: WAL %s is over its maximum size, dropping "+
+			"%d bytes of metrics\n", w.dir, seg.size)
+		if err := os.Remove(seg.path); err != nil {
+			log.Printf("ERROR: unable to remove WAL segment %s: %s\n",
+				seg.path, err)
+		}
+		w.segments = w.segments[1:]
+	}
+}
+
+type byID []*walSegment
+
+func (s byID) Len() int           { return len(s) }
+func (s byID) Less(i, j int) bool { return s[i].id < s[j].id }
+func (s byID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
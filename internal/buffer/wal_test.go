@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestWALAppendAndRead(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWAL(dir, 1024*1024, 1024*1024)
+	require.NoError(t, err)
+	assert.True(t, w.IsEmpty())
+
+	require.NoError(t, w.Append([]telegraf.Metric{newMetric(1), newMetric(2)}))
+	require.NoError(t, w.Append([]telegraf.Metric{newMetric(3)}))
+	assert.False(t, w.IsEmpty())
+
+	metrics, err := w.Oldest()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, values(metrics))
+	assert.Equal(t, newMetric(1).Tags(), metrics[0].Tags())
+	assert.Equal(t, newMetric(1).UnixNano(), metrics[0].UnixNano())
+
+	require.NoError(t, w.RemoveOldest())
+	assert.True(t, w.IsEmpty())
+
+	// New metrics go to a new segment
+	require.NoError(t, w.Append([]telegraf.Metric{newMetric(4)}))
+	metrics, err = w.Oldest()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4}, values(metrics))
+}
+
+// Test that segments are rotated, and read back in order after reopening
+func TestWALSegmentsSurviveReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	w, err := OpenWAL(dir, 1024*1024, 1)
+	require.NoError(t, err)
+	for i := int64(1); i <= 3; i++ {
+		require.NoError(t, w.Append([]telegraf.Metric{newMetric(i)}))
+	}
+	require.NoError(t, w.Close())
+
+	w, err = OpenWAL(dir, 1024*1024, 1)
+	require.NoError(t, err)
+	for i := int64(1); i <= 3; i++ {
+		metrics, err := w.Oldest()
+		require.NoError(t, err)
+		assert.Equal(t, []int64{i}, values(metrics))
+		require.NoError(t, w.RemoveOldest())
+	}
+	assert.True(t, w.IsEmpty())
+}
+
+// Test that the oldest segments are dropped over the maximum size
+func TestWALMaxSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	line := int64(len(newMetric(1).String()) + 1)
+	w, err := OpenWAL(dir, 2*line, 1)
+	require.NoError(t, err)
+	for i := int64(1); i <= 4; i++ {
+		require.NoError(t, w.Append([]telegraf.Metric{newMetric(i)}))
+	}
+
+	assert.Equal(t, 2*line, w.Size())
+	metrics, err := w.Oldest()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, values(metrics))
+}
@@ -1,22 +1,35 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/buffer"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
+	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 
 	"github.com/influxdata/config"
+	"github.com/naoina/toml"
 	"github.com/naoina/toml/ast"
 )
 
@@ -28,9 +41,14 @@ type Config struct {
 	InputFilters  []string
 	OutputFilters []string
 
-	Agent   *AgentConfig
-	Inputs  []*internal_models.RunningInput
-	Outputs []*internal_models.RunningOutput
+	Agent       *AgentConfig
+	Inputs      []*internal_models.RunningInput
+	Outputs     []*internal_models.RunningOutput
+	Processors  []*internal_models.RunningProcessor
+	Aggregators []*internal_models.RunningAggregator
+
+	// SecretStores maps the ids of the configured secret stores to the stores
+	SecretStores map[string]telegraf.SecretStore
 }
 
 func NewConfig() *Config {
@@ -41,11 +59,22 @@ func NewConfig() *Config {
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
 			FlushJitter:   internal.Duration{Duration: 5 * time.Second},
+
+			ShutdownTimeout: internal.Duration{Duration: 30 * time.Second},
+
+			MetricBatchSize:   1000,
+			MetricBufferLimit: 10000,
+
+			HealthMaxFailedWrites:      3,
+			LogfileRotationMaxArchives: 5,
 		},
 
 		Tags:          make(map[string]string),
 		Inputs:        make([]*internal_models.RunningInput, 0),
 		Outputs:       make([]*internal_models.RunningOutput, 0),
+		Processors:    make([]*internal_models.RunningProcessor, 0),
+		Aggregators:   make([]*internal_models.RunningAggregator, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 	}
@@ -66,6 +95,14 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// MaxConcurrentGathers is the max number of inputs gathered at the same
+	// time, 0 meaning no limit.
+	MaxConcurrentGathers int
+
+	// MaxConsecutiveFailures is the number of consecutive failed gathers
+	// after which an input is disabled, 0 meaning never.
+	MaxConsecutiveFailures int
+
 	// Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -75,15 +112,26 @@ type AgentConfig struct {
 	// ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
 	FlushJitter internal.Duration
 
+	// ShutdownTimeout is how long the final flush of the outputs may take
+	// on shutdown, no limit if zero.
+	ShutdownTimeout internal.Duration
+
+	// MetricBatchSize is the max number of metrics that each output plugin
+	// is sent in one write. Larger buffers are written in several batches.
+	MetricBatchSize int
+
 	// MetricBufferLimit is the max number of metrics that each output plugin
-	// will cache. The buffer is cleared when a successful write occurs. When
-	// full, the oldest metrics will be overwritten.
+	// will cache. The buffer is cleared when a successful write occurs, failed
+	// writes are retried on the next flush. When full, the oldest metrics are
+	// dropped.
 	MetricBufferLimit int
 
-	// TODO(cam): Remove UTC and Precision parameters, they are no longer
-	// valid for the agent config. Leaving them here for now for backwards-
-	// compatability
-	UTC       bool `toml:"utc"`
+	// TODO(cam): Remove the UTC parameter, it is no longer valid for the
+	// agent config. Leaving it here for now for backwards-compatability
+	UTC bool `toml:"utc"`
+
+	// Precision rounds down the timestamps of collected metrics, given as a
+	// duration or a unit, ie "1s" or "s". No rounding if empty.
 	Precision string
 
 	// Debug is the option for running in debug mode
@@ -92,6 +140,83 @@ type AgentConfig struct {
 	// Quiet is the option for running in quiet mode
 	Quiet    bool
 	Hostname string
+
+	// OmitHostname disables the host tag added to every metric
+	OmitHostname bool
+
+	// Logfile is the file to log to, stderr if empty
+	Logfile string
+
+	// LogfileRotationInterval rotates the logfile once it is older, 0
+	// disables rotation by age
+	LogfileRotationInterval internal.Duration
+
+	// LogfileRotationMaxSize rotates the logfile once it is larger, 0
+	// disables rotation by size
+	LogfileRotationMaxSize internal.Size
+
+	// LogfileRotationMaxArchives is the number of rotated logfiles to keep,
+	// -1 keeps all of them
+	LogfileRotationMaxArchives int
+
+	// LogFormat is the format of the log, either "text" or "json"
+	LogFormat string
+
+	// HealthAddress, if set, is the address of the HTTP listener serving the
+	// /healthz and /readyz endpoints
+	HealthAddress string
+
+	// HealthMaxFailedWrites is the number of consecutive failed writes of an
+	// output after which /healthz reports telegraf as unhealthy
+	HealthMaxFailedWrites int
+
+	// HealthTLSCert and HealthTLSKey, or their PKCS#12 bundle
+	// HealthTLSPKCS12, if set, serve the health endpoints over TLS, requiring
+	// a client certificate signed by one of the HealthTLSAllowedCACerts if
+	// set. HealthTLSKeyPassword is the password of an encrypted key or bundle.
+	// If HealthTLSReloadInterval is set, the certificate is reloaded once its
+	// files are modified, checking them at this interval.
+	HealthTLSCert           string            `toml:"health_tls_cert"`
+	HealthTLSKey            string            `toml:"health_tls_key"`
+	HealthTLSPKCS12         string            `toml:"health_tls_pkcs12"`
+	HealthTLSKeyPassword    string            `toml:"health_tls_key_password"`
+	HealthTLSAllowedCACerts []string          `toml:"health_tls_allowed_cacerts"`
+	HealthTLSReloadInterval internal.Duration `toml:"health_tls_reload_interval"`
+
+	// Statefile is the file in which the state of the stateful plugins is
+	// kept across restarts, not kept if empty
+	Statefile string
+}
+
+// InitPlugins calls the Init method of every configured plugin implementing
+// telegraf.Initializer, returning the errors of all failing plugins.
+func (c *Config) InitPlugins() error {
+	var errs []string
+	initPlugin := func(category, name string, plugin interface{}) {
+		if p, ok := plugin.(telegraf.Initializer); ok {
+			if err := p.Init(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s.%s: %s", category, name, err))
+			}
+		}
+	}
+
+	for _, input := range c.Inputs {
+		initPlugin("inputs", input.LogName(), input.Input)
+	}
+	for _, processor := range c.Processors {
+		initPlugin("processors", processor.LogName(), processor.Processor)
+	}
+	for _, aggregator := range c.Aggregators {
+		initPlugin("aggregators", aggregator.LogName(), aggregator.Aggregator)
+	}
+	for _, output := range c.Outputs {
+		initPlugin("outputs", output.LogName(), output.Output)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -112,6 +237,24 @@ func (c *Config) OutputNames() []string {
 	return name
 }
 
+// ProcessorNames returns a list of strings of the configured processors.
+func (c *Config) ProcessorNames() []string {
+	var name []string
+	for _, processor := range c.Processors {
+		name = append(name, processor.Name)
+	}
+	return name
+}
+
+// AggregatorNames returns a list of strings of the configured aggregators.
+func (c *Config) AggregatorNames() []string {
+	var name []string
+	for _, aggregator := range c.Aggregators {
+		name = append(name, aggregator.Name)
+	}
+	return name
+}
+
 // ListTags returns a string of tags specified in the config,
 // line-protocol style
 func (c *Config) ListTags() string {
@@ -137,11 +280,19 @@ var header = `# Telegraf configuration
 # Use 'telegraf -config telegraf.conf -test' to see what metrics a config
 # file would generate.
 
-# Global tags can be specified here in key="value" format.
-[tags]
+# Environment variables can be used anywhere in the config file as ${VAR},
+# or as ${VAR:-default} to fall back to a default if VAR is unset or empty.
+`
+
+var globalTagsConfig = `
+# Global tags can be specified here in key="value" format. They are added to
+# every metric gathered by the inputs, unless the metric already has the tag.
+[global_tags]
   # dc = "us-east-1" # will tag all metrics with dc=us-east-1
   # rack = "1a"
+`
 
+var agentConfig = `
 # Configuration for telegraf agent
 [agent]
   # Default data collection interval for all inputs
@@ -150,8 +301,23 @@ var header = `# Telegraf configuration
   # ie, if interval="10s" then always collect on :00, :10, :20, etc.
   round_interval = true
 
+  # Maximum number of inputs gathered at the same time, 0 for no limit
+  max_concurrent_gathers = 0
+  # Disable an input after this many consecutive failed gathers, failing
+  # with an error, a panic or a timeout. Never disabled if 0.
+  max_consecutive_failures = 0
+
+  # Round down the timestamps of collected metrics to precision, ie "1s" or
+  # "ms". Coarser timestamps compress better in time series databases.
+  precision = ""
+
+  # Telegraf will send metrics to outputs in batches of at most
+  # metric_batch_size metrics. An output is flushed as soon as a full batch
+  # is buffered.
+  metric_batch_size = 1000
   # Telegraf will cache metric_buffer_limit metrics for each output, and will
-  # flush this buffer on a successful write.
+  # flush this buffer on a successful write. Metrics that failed to be written
+  # are retried on the next flush, the oldest are dropped when it is full.
   metric_buffer_limit = 10000
 
   # Collection jitter is used to jitter the collection by a random amount.
@@ -167,6 +333,9 @@ var header = `# Telegraf configuration
   # large write spikes for users running a large number of telegraf instances.
   # ie, a jitter of 5s and interval 10s means flushes will happen every 10-15s
   flush_jitter = "0s"
+  # On shutdown, the inputs are stopped and the metrics left are written in a
+  # final flush of the outputs, given up after shutdown_timeout.
+  shutdown_timeout = "30s"
 
   # Run telegraf in debug mode
   debug = false
@@ -174,7 +343,47 @@ var header = `# Telegraf configuration
   quiet = false
   # Override default hostname, if empty use os.Hostname()
   hostname = ""
+  # If set to true, do not set the "host" tag in the telegraf agent.
+  omit_hostname = false
+
+  # Log to this file instead of stderr if set
+  logfile = ""
+  # Rotate the logfile once it is older than this interval, or larger than
+  # this size, ie "10MB". Disabled if zero.
+  logfile_rotation_interval = "0s"
+  logfile_rotation_max_size = "0MB"
+  # Number of rotated logfiles to keep, older ones are removed. If -1, no
+  # logfiles are removed.
+  logfile_rotation_max_archives = 5
+  # Log format, either "text" or "json". In the json format every message is
+  # an object with the time, level, plugin and msg keys.
+  log_format = "text"
+
+  # Address of an HTTP listener serving /healthz and /readyz, ie ":8080".
+  # Disabled if empty.
+  health_address = ""
+  # /healthz reports telegraf as unhealthy once an output failed this many
+  # consecutive writes.
+  health_max_failed_writes = 3
+  # Serve the health endpoints over TLS, only to the clients presenting a
+  # certificate signed by one of the allowed CAs if set.
+  # health_tls_cert = "/etc/telegraf/cert.pem"
+  # health_tls_key = "/etc/telegraf/key.pem"
+  # Certificate and key bundled as PKCS#12, instead of health_tls_cert and
+  # health_tls_key, and password of an encrypted key or bundle.
+  # health_tls_pkcs12 = "/etc/telegraf/server.p12"
+  # health_tls_key_password = ""
+  # health_tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+  # Reload the certificate once its files are modified, ie rotated short-lived
+  # certificates, checking them at this interval. Disabled if empty.
+  # health_tls_reload_interval = "1m"
+
+  # File in which stateful plugins, ie consumers, keep their position across
+  # restarts. Their state is not kept if empty.
+  statefile = ""
+`
 
+var outputHeader = `
 
 ###############################################################################
 #                                  OUTPUTS                                    #
@@ -182,6 +391,27 @@ var header = `# Telegraf configuration
 
 `
 
+var processorHeader = `
+
+###############################################################################
+#                                 PROCESSORS                                  #
+###############################################################################
+`
+
+var aggregatorHeader = `
+
+###############################################################################
+#                                 AGGREGATORS                                 #
+###############################################################################
+`
+
+var secretStoreHeader = `
+
+###############################################################################
+#                                SECRET STORES                                #
+###############################################################################
+`
+
 var pluginHeader = `
 
 ###############################################################################
@@ -199,54 +429,125 @@ var serviceInputHeader = `
 
 // PrintSampleConfig prints the sample config
 func PrintSampleConfig(pluginFilters []string, outputFilters []string) {
-	fmt.Printf(header)
+	PrintFilteredSampleConfig(os.Stdout, SampleConfigFilters{
+		Inputs:  pluginFilters,
+		Outputs: outputFilters,
+	})
+}
 
-	// Filter outputs
-	var onames []string
-	for oname := range outputs.Outputs {
-		if len(outputFilters) == 0 || sliceContains(oname, outputFilters) {
-			onames = append(onames, oname)
+// SampleConfigSections are the sections of the sample config, in order
+var SampleConfigSections = []string{"global_tags", "agent", "outputs",
+	"processors", "aggregators", "secretstores", "inputs"}
+
+// SampleConfigFilters select the sections and plugins included in the
+// sample config. An empty filter includes everything.
+type SampleConfigFilters struct {
+	Sections     []string
+	Inputs       []string
+	Outputs      []string
+	Processors   []string
+	Aggregators  []string
+	SecretStores []string
+}
+
+// PrintFilteredSampleConfig writes the sample config of the sections and
+// plugins selected by filters to w.
+func PrintFilteredSampleConfig(w io.Writer, filters SampleConfigFilters) {
+	fmt.Fprint(w, header)
+
+	printSection := func(section string) bool {
+		return len(filters.Sections) == 0 ||
+			sliceContains(section, filters.Sections)
+	}
+
+	if printSection("global_tags") {
+		fmt.Fprint(w, globalTagsConfig)
+	}
+	if printSection("agent") {
+		fmt.Fprint(w, agentConfig)
+	}
+
+	if printSection("outputs") {
+		fmt.Fprint(w, outputHeader)
+		var names []string
+		for name := range outputs.Outputs {
+			names = append(names, name)
+		}
+		for _, name := range filterNames(names, filters.Outputs) {
+			printConfig(w, name, outputs.Outputs[name](), "outputs")
 		}
 	}
-	sort.Strings(onames)
 
-	// Print Outputs
-	for _, oname := range onames {
-		creator := outputs.Outputs[oname]
-		output := creator()
-		printConfig(oname, output, "outputs")
+	if printSection("processors") {
+		fmt.Fprint(w, processorHeader)
+		var names []string
+		for name := range processors.Processors {
+			names = append(names, name)
+		}
+		for _, name := range filterNames(names, filters.Processors) {
+			printConfig(w, name, processors.Processors[name](), "processors")
+		}
 	}
 
-	// Filter inputs
-	var pnames []string
-	for pname := range inputs.Inputs {
-		if len(pluginFilters) == 0 || sliceContains(pname, pluginFilters) {
-			pnames = append(pnames, pname)
+	if printSection("aggregators") {
+		fmt.Fprint(w, aggregatorHeader)
+		var names []string
+		for name := range aggregators.Aggregators {
+			names = append(names, name)
+		}
+		for _, name := range filterNames(names, filters.Aggregators) {
+			printConfig(w, name, aggregators.Aggregators[name](), "aggregators")
 		}
 	}
-	sort.Strings(pnames)
 
-	// Print Inputs
-	fmt.Printf(pluginHeader)
-	servInputs := make(map[string]telegraf.ServiceInput)
-	for _, pname := range pnames {
-		creator := inputs.Inputs[pname]
-		input := creator()
+	if printSection("secretstores") {
+		fmt.Fprint(w, secretStoreHeader)
+		var names []string
+		for name := range secretstores.SecretStores {
+			names = append(names, name)
+		}
+		for _, name := range filterNames(names, filters.SecretStores) {
+			printConfig(w, name, secretstores.SecretStores[name](),
+				"secretstores")
+		}
+	}
 
-		switch p := input.(type) {
-		case telegraf.ServiceInput:
-			servInputs[pname] = p
-			continue
+	if printSection("inputs") {
+		var names []string
+		for name := range inputs.Inputs {
+			names = append(names, name)
 		}
 
-		printConfig(pname, input, "inputs")
+		// Service inputs are printed in their own section, after the others
+		fmt.Fprint(w, pluginHeader)
+		var servNames []string
+		for _, name := range filterNames(names, filters.Inputs) {
+			input := inputs.Inputs[name]()
+			if _, ok := input.(telegraf.ServiceInput); ok {
+				servNames = append(servNames, name)
+				continue
+			}
+			printConfig(w, name, input, "inputs")
+		}
+
+		fmt.Fprint(w, serviceInputHeader)
+		for _, name := range servNames {
+			printConfig(w, name, inputs.Inputs[name](), "inputs")
+		}
 	}
+}
 
-	// Print Service Inputs
-	fmt.Printf(serviceInputHeader)
-	for name, input := range servInputs {
-		printConfig(name, input, "inputs")
+// filterNames returns the sorted names matching the filter, all of them if
+// the filter is empty.
+func filterNames(names []string, filter []string) []string {
+	var filtered []string
+	for _, name := range names {
+		if len(filter) == 0 || sliceContains(name, filter) {
+			filtered = append(filtered, name)
+		}
 	}
+	sort.Strings(filtered)
+	return filtered
 }
 
 type printer interface {
@@ -254,13 +555,13 @@ type printer interface {
 	SampleConfig() string
 }
 
-func printConfig(name string, p printer, op string) {
-	fmt.Printf("\n# %s\n[[%s.%s]]", p.Description(), op, name)
+func printConfig(w io.Writer, name string, p printer, op string) {
+	fmt.Fprintf(w, "\n# %s\n[[%s.%s]]", p.Description(), op, name)
 	config := p.SampleConfig()
 	if config == "" {
-		fmt.Printf("\n  # no configuration\n")
+		fmt.Fprintf(w, "\n  # no configuration\n")
 	} else {
-		fmt.Printf(config)
+		io.WriteString(w, config)
 	}
 }
 
@@ -276,17 +577,47 @@ func sliceContains(name string, list []string) bool {
 // PrintInputConfig prints the config usage of a single input.
 func PrintInputConfig(name string) error {
 	if creator, ok := inputs.Inputs[name]; ok {
-		printConfig(name, creator(), "inputs")
+		printConfig(os.Stdout, name, creator(), "inputs")
 	} else {
 		return errors.New(fmt.Sprintf("Input %s not found", name))
 	}
 	return nil
 }
 
+// PrintProcessorConfig prints the config usage of a single processor.
+func PrintProcessorConfig(name string) error {
+	if creator, ok := processors.Processors[name]; ok {
+		printConfig(os.Stdout, name, creator(), "processors")
+	} else {
+		return errors.New(fmt.Sprintf("Processor %s not found", name))
+	}
+	return nil
+}
+
+// PrintAggregatorConfig prints the config usage of a single aggregator.
+func PrintAggregatorConfig(name string) error {
+	if creator, ok := aggregators.Aggregators[name]; ok {
+		printConfig(os.Stdout, name, creator(), "aggregators")
+	} else {
+		return errors.New(fmt.Sprintf("Aggregator %s not found", name))
+	}
+	return nil
+}
+
+// PrintSecretStoreConfig prints the config usage of a single secret store.
+func PrintSecretStoreConfig(name string) error {
+	if creator, ok := secretstores.SecretStores[name]; ok {
+		printConfig(os.Stdout, name, creator(), "secretstores")
+	} else {
+		return errors.New(fmt.Sprintf("Secret store %s not found", name))
+	}
+	return nil
+}
+
 // PrintOutputConfig prints the config usage of a single output.
 func PrintOutputConfig(name string) error {
 	if creator, ok := outputs.Outputs[name]; ok {
-		printConfig(name, creator(), "outputs")
+		printConfig(os.Stdout, name, creator(), "outputs")
 	} else {
 		return errors.New(fmt.Sprintf("Output %s not found", name))
 	}
@@ -314,13 +645,72 @@ func (c *Config) LoadDirectory(path string) error {
 	return nil
 }
 
+// envVarRe matches ${VAR} and ${VAR:-default} references in config files.
+var envVarRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// substituteEnvVars replaces ${VAR} references in the config file contents
+// with the value of the environment variable VAR. ${VAR:-default} is replaced
+// with default if VAR is unset or empty.
+func substituteEnvVars(contents []byte) []byte {
+	return envVarRe.ReplaceAllFunc(contents, func(ref []byte) []byte {
+		m := envVarRe.FindSubmatch(ref)
+		if val := os.Getenv(string(m[1])); val != "" || len(m[2]) == 0 {
+			return []byte(val)
+		}
+		return m[3]
+	})
+}
+
 // LoadConfig loads the given config file and applies it to c
 func (c *Config) LoadConfig(path string) error {
-	tbl, err := config.ParseFile(path)
+	if err := c.loadConfig(path); err != nil {
+		return fmt.Errorf("Error loading %s: %s", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadConfig(path string) error {
+	contents, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
+	tbl, err := toml.Parse(substituteEnvVars(contents))
+	if err != nil {
+		return err
+	}
+
+	// Secret stores are added first, so that secrets can be resolved in the
+	// tables of all other plugins.
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return errors.New("invalid configuration")
+		}
+		for pluginName, pluginVal := range subTable.Fields {
+			switch pluginSubTable := pluginVal.(type) {
+			case *ast.Table:
+				if err = c.addSecretStore(pluginName, pluginSubTable); err != nil {
+					return err
+				}
+			case []*ast.Table:
+				for _, t := range pluginSubTable {
+					if err = c.addSecretStore(pluginName, t); err != nil {
+						return err
+					}
+				}
+			default:
+				return fmt.Errorf("Unsupported config format: %s",
+					pluginName)
+			}
+		}
+		delete(tbl.Fields, "secretstores")
+	}
+
+	if err = c.resolveSecrets(tbl); err != nil {
+		return err
+	}
+
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
 		if !ok {
@@ -333,9 +723,13 @@ func (c *Config) LoadConfig(path string) error {
 				log.Printf("Could not parse [agent] config\n")
 				return err
 			}
-		case "tags":
+			if _, err = internal.ParsePrecision(c.Agent.Precision); err != nil {
+				return fmt.Errorf("[agent] precision: %s", err)
+			}
+		// Legacy support for the [tags] table, renamed to [global_tags]
+		case "global_tags", "tags":
 			if err = config.UnmarshalTable(subTable, c.Tags); err != nil {
-				log.Printf("Could not parse [tags] config\n")
+				log.Printf("Could not parse [%s] config\n", name)
 				return err
 			}
 		case "outputs":
@@ -356,6 +750,42 @@ func (c *Config) LoadConfig(path string) error {
 						pluginName)
 				}
 			}
+		case "processors":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case *ast.Table:
+					if err = c.addProcessor(pluginName, pluginSubTable); err != nil {
+						return err
+					}
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addProcessor(pluginName, t); err != nil {
+							return err
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s",
+						pluginName)
+				}
+			}
+		case "aggregators":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case *ast.Table:
+					if err = c.addAggregator(pluginName, pluginSubTable); err != nil {
+						return err
+					}
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addAggregator(pluginName, t); err != nil {
+							return err
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s",
+						pluginName)
+				}
+			}
 		case "inputs", "plugins":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
@@ -382,6 +812,164 @@ func (c *Config) LoadConfig(path string) error {
 			}
 		}
 	}
+
+	// Processors are applied in the order given by their "order" option.
+	sort.Stable(processorsByOrder(c.Processors))
+	return nil
+}
+
+type processorsByOrder []*internal_models.RunningProcessor
+
+func (p processorsByOrder) Len() int      { return len(p) }
+func (p processorsByOrder) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p processorsByOrder) Less(i, j int) bool {
+	return p[i].Config.Order < p[j].Config.Order
+}
+
+func (c *Config) addAggregator(name string, table *ast.Table) error {
+	creator, ok := aggregators.Aggregators[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested aggregator: %s", name)
+	}
+	aggregator := creator()
+
+	aggregatorConfig, err := buildAggregator(name, table)
+	if err != nil {
+		return err
+	}
+	aggregatorConfig.ID = pluginID("aggregators", name, table)
+	l := logger.NewLogger("aggregators", name, aggregatorConfig.Alias)
+	logger.SetLoggerOnPlugin(aggregator, l)
+
+	if err := config.UnmarshalTable(table, aggregator); err != nil {
+		return fmt.Errorf("aggregators.%s: %s", name, err)
+	}
+	err = checkDeprecations("aggregators", name, aggregator, aggregators.Deprecations, l)
+	if err != nil {
+		return err
+	}
+
+	ra := internal_models.NewRunningAggregator(name, aggregator,
+		aggregatorConfig)
+	c.Aggregators = append(c.Aggregators, ra)
+	return nil
+}
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secret store: %s", name)
+	}
+	store := creator()
+	logger.SetLoggerOnPlugin(store, logger.NewLogger("secretstores", name, ""))
+
+	id := name
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "id")
+
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("secret store %s: duplicate id %s", name, id)
+	}
+
+	if err := config.UnmarshalTable(table, store); err != nil {
+		return fmt.Errorf("secretstores.%s: %s", name, err)
+	}
+
+	c.SecretStores[id] = store
+	return nil
+}
+
+// secretRe matches @{id:key} references to the secret key of the secret
+// store with the given id.
+var secretRe = regexp.MustCompile(`@\{([^:}]+):([^}]+)\}`)
+
+// resolveSecrets replaces the secret references in all string values of the
+// given ast value with the secrets read from the secret stores.
+func (c *Config) resolveSecrets(val interface{}) error {
+	switch v := val.(type) {
+	case *ast.Table:
+		for _, field := range v.Fields {
+			if err := c.resolveSecrets(field); err != nil {
+				return err
+			}
+		}
+	case []*ast.Table:
+		for _, t := range v {
+			if err := c.resolveSecrets(t); err != nil {
+				return err
+			}
+		}
+	case *ast.KeyValue:
+		return c.resolveSecrets(v.Value)
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := c.resolveSecrets(elem); err != nil {
+				return err
+			}
+		}
+	case *ast.String:
+		if !secretRe.MatchString(v.Value) {
+			return nil
+		}
+		var err error
+		resolved := secretRe.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			m := secretRe.FindStringSubmatch(ref)
+			store, ok := c.SecretStores[m[1]]
+			if !ok {
+				err = fmt.Errorf("Undefined but referenced secret store: %s", m[1])
+				return ref
+			}
+			secret, serr := store.Get(m[2])
+			if serr != nil {
+				err = fmt.Errorf("secret %s of store %s: %s", m[2], m[1], serr)
+				return ref
+			}
+			return secret
+		})
+		if err != nil {
+			return err
+		}
+		v.Value = resolved
+		v.Data = []rune(strconv.Quote(resolved))
+	}
+	return nil
+}
+
+func (c *Config) addProcessor(name string, table *ast.Table) error {
+	creator, ok := processors.Processors[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested processor: %s", name)
+	}
+	processor := creator()
+
+	processorConfig, err := buildProcessor(name, table)
+	if err != nil {
+		return err
+	}
+	processorConfig.ID = pluginID("processors", name, table)
+	l := logger.NewLogger("processors", name, processorConfig.Alias)
+	logger.SetLoggerOnPlugin(processor, l)
+
+	if err := config.UnmarshalTable(table, processor); err != nil {
+		return fmt.Errorf("processors.%s: %s", name, err)
+	}
+	err = checkDeprecations("processors", name, processor, processors.Deprecations, l)
+	if err != nil {
+		return err
+	}
+
+	rp := &internal_models.RunningProcessor{
+		Name:      name,
+		Processor: processor,
+		Config:    processorConfig,
+	}
+	c.Processors = append(c.Processors, rp)
 	return nil
 }
 
@@ -399,14 +987,27 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	outputConfig.ID = pluginID("outputs", name, table)
+	l := logger.NewLogger("outputs", name, outputConfig.Alias)
+	logger.SetLoggerOnPlugin(output, l)
 
 	if err := config.UnmarshalTable(table, output); err != nil {
+		return fmt.Errorf("outputs.%s: %s", name, err)
+	}
+	err = checkDeprecations("outputs", name, output, outputs.Deprecations, l)
+	if err != nil {
 		return err
 	}
 
-	ro := internal_models.NewRunningOutput(name, output, outputConfig)
-	if c.Agent.MetricBufferLimit > 0 {
-		ro.PointBufferLimit = c.Agent.MetricBufferLimit
+	ro := internal_models.NewRunningOutput(name, output, outputConfig,
+		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	if outputConfig.WALDirectory != "" {
+		wal, err := buffer.OpenWAL(outputConfig.WALDirectory,
+			outputConfig.WALMaxSize, outputConfig.WALSegmentSize)
+		if err != nil {
+			return fmt.Errorf("output %s: %s", ro.LogName(), err)
+		}
+		ro.WAL = wal
 	}
 	ro.Quiet = c.Agent.Quiet
 	c.Outputs = append(c.Outputs, ro)
@@ -419,6 +1020,11 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 	// Legacy support renaming io input to diskio
 	if name == "io" {
+		logger.NewLogger("inputs", name, "").Warn(deprecationNotice(
+			`Plugin "inputs.io"`, telegraf.DeprecationInfo{
+				Since:  "0.10.0",
+				Notice: "use 'inputs.diskio' instead",
+			}))
 		name = "diskio"
 	}
 
@@ -432,8 +1038,15 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	pluginConfig.ID = pluginID("inputs", name, table)
+	l := logger.NewLogger("inputs", name, pluginConfig.Alias)
+	logger.SetLoggerOnPlugin(input, l)
 
 	if err := config.UnmarshalTable(table, input); err != nil {
+		return fmt.Errorf("inputs.%s: %s", name, err)
+	}
+	err = checkDeprecations("inputs", name, input, inputs.Deprecations, l)
+	if err != nil {
 		return err
 	}
 
@@ -446,12 +1059,91 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	return nil
 }
 
+// buildAlias parses the alias of a plugin instance from the ast.Table, used to
+// tell apart several instances of a plugin in logs and internal metrics
+func buildAlias(tbl *ast.Table) string {
+	var alias string
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				alias = str.Value
+			}
+		}
+	}
+	delete(tbl.Fields, "alias")
+	return alias
+}
+
+// pluginID returns the ID of a plugin instance, a hash of its category, name
+// and options, used to restore the state of the instance after a restart. The
+// options common to all plugins, such as the interval or the filters, must
+// have been removed from the table so that changing them keeps the ID.
+func pluginID(category, name string, tbl *ast.Table) string {
+	h := sha256.New()
+	io.WriteString(h, category+"."+name+"\n")
+	hashTable(h, tbl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashTable writes the keys and values of the table to h, sorted by key
+func hashTable(h hash.Hash, tbl *ast.Table) {
+	io.WriteString(h, "{")
+	defer io.WriteString(h, "}")
+
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		io.WriteString(h, key+"=")
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			io.WriteString(h, v.Value.Source())
+		case *ast.Table:
+			hashTable(h, v)
+		case []*ast.Table:
+			for _, t := range v {
+				hashTable(h, t)
+			}
+		}
+		io.WriteString(h, "\n")
+	}
+}
+
 // buildFilter builds a Filter (tagpass/tagdrop/pass/drop) to
 // be inserted into the internal_models.OutputConfig/internal_models.InputConfig to be used for prefix
 // filtering on tags and measurements
 func buildFilter(tbl *ast.Table) internal_models.Filter {
 	f := internal_models.Filter{}
 
+	if node, ok := tbl.Fields["namepass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.NamePass = append(f.NamePass, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["namedrop"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.NameDrop = append(f.NameDrop, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["pass"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if ary, ok := kv.Value.(*ast.Array); ok {
@@ -478,6 +1170,32 @@ func buildFilter(tbl *ast.Table) internal_models.Filter {
 		}
 	}
 
+	if node, ok := tbl.Fields["fieldpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.FieldPass = append(f.FieldPass, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["fielddrop"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.FieldDrop = append(f.FieldDrop, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["tagpass"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
 			for name, val := range subtbl.Fields {
@@ -516,10 +1234,42 @@ func buildFilter(tbl *ast.Table) internal_models.Filter {
 		}
 	}
 
+	delete(tbl.Fields, "namedrop")
+	delete(tbl.Fields, "namepass")
+	if node, ok := tbl.Fields["taginclude"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.TagInclude = append(f.TagInclude, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["tagexclude"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						f.TagExclude = append(f.TagExclude, str.Value)
+						f.IsActive = true
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "drop")
 	delete(tbl.Fields, "pass")
+	delete(tbl.Fields, "fielddrop")
+	delete(tbl.Fields, "fieldpass")
 	delete(tbl.Fields, "tagdrop")
 	delete(tbl.Fields, "tagpass")
+	delete(tbl.Fields, "tagexclude")
+	delete(tbl.Fields, "taginclude")
 	return f
 }
 
@@ -527,7 +1277,10 @@ func buildFilter(tbl *ast.Table) internal_models.Filter {
 // builds the filter and returns a
 // internal_models.InputConfig to be inserted into internal_models.RunningInput
 func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, error) {
-	cp := &internal_models.InputConfig{Name: name}
+	cp := &internal_models.InputConfig{
+		Name:  name,
+		Alias: buildAlias(tbl),
+	}
 	if node, ok := tbl.Fields["interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -535,12 +1288,46 @@ func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, erro
 				if err != nil {
 					return nil, err
 				}
+				if dur < 0 {
+					return nil, fmt.Errorf("input %s: interval must not be negative",
+						name)
+				}
 
 				cp.Interval = dur
 			}
 		}
 	}
 
+	if node, ok := tbl.Fields["precision"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				precision, err := internal.ParsePrecision(str.Value)
+				if err != nil {
+					return nil, fmt.Errorf("input %s: %s", name, err)
+				}
+
+				cp.Precision = precision
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["gather_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				if dur < 0 {
+					return nil, fmt.Errorf("input %s: gather_timeout must not "+
+						"be negative", name)
+				}
+
+				cp.GatherTimeout = dur
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -578,18 +1365,225 @@ func buildInput(name string, tbl *ast.Table) (*internal_models.InputConfig, erro
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "gather_timeout")
+	delete(tbl.Fields, "precision")
 	delete(tbl.Fields, "tags")
 	cp.Filter = buildFilter(tbl)
 	return cp, nil
 }
 
+// buildAggregator parses aggregator specific items from the ast.Table,
+// builds the filter and returns an internal_models.AggregatorConfig to be
+// inserted into internal_models.RunningAggregator
+func buildAggregator(name string, tbl *ast.Table) (*internal_models.AggregatorConfig, error) {
+	conf := &internal_models.AggregatorConfig{
+		Name:  name,
+		Alias: buildAlias(tbl),
+	}
+	if node, ok := tbl.Fields["period"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				conf.Period = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["drop_original"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				conf.DropOriginal, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_prefix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.MeasurementPrefix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_suffix"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.MeasurementSuffix = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["name_override"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.NameOverride = str.Value
+			}
+		}
+	}
+
+	conf.Tags = make(map[string]string)
+	if node, ok := tbl.Fields["tags"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			if err := config.UnmarshalTable(subtbl, conf.Tags); err != nil {
+				log.Printf("Could not parse tags for aggregator %s\n", name)
+			}
+		}
+	}
+
+	delete(tbl.Fields, "period")
+	delete(tbl.Fields, "drop_original")
+	delete(tbl.Fields, "name_prefix")
+	delete(tbl.Fields, "name_suffix")
+	delete(tbl.Fields, "name_override")
+	delete(tbl.Fields, "tags")
+	conf.Filter = buildFilter(tbl)
+	return conf, nil
+}
+
+// buildProcessor parses processor specific items from the ast.Table, builds
+// the filter and returns an internal_models.ProcessorConfig to be inserted into
+// internal_models.RunningProcessor
+func buildProcessor(name string, tbl *ast.Table) (*internal_models.ProcessorConfig, error) {
+	pc := &internal_models.ProcessorConfig{
+		Name:  name,
+		Alias: buildAlias(tbl),
+	}
+	if node, ok := tbl.Fields["order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				order, err := b.Int()
+				if err != nil {
+					return nil, err
+				}
+				pc.Order = order
+			}
+		}
+	}
+
+	delete(tbl.Fields, "order")
+	pc.Filter = buildFilter(tbl)
+	return pc, nil
+}
+
 // buildOutput parses output specific items from the ast.Table, builds the filter and returns an
 // internal_models.OutputConfig to be inserted into internal_models.RunningInput
 // Note: error exists in the return for future calls that might require error
 func buildOutput(name string, tbl *ast.Table) (*internal_models.OutputConfig, error) {
 	oc := &internal_models.OutputConfig{
-		Name:   name,
-		Filter: buildFilter(tbl),
+		Name:           name,
+		Alias:          buildAlias(tbl),
+		WALMaxSize:     internal_models.DEFAULT_WAL_MAX_SIZE,
+		WALSegmentSize: internal_models.DEFAULT_WAL_SEGMENT_SIZE,
 	}
+
+	if node, ok := tbl.Fields["flush_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				if dur < 0 {
+					return nil, fmt.Errorf("output %s: flush_interval must not "+
+						"be negative", name)
+				}
+
+				oc.FlushInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["rate_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				limit, err := b.Int()
+				if err != nil {
+					return nil, err
+				}
+				if limit < 0 {
+					return nil, fmt.Errorf("output %s: rate_limit must not be "+
+						"negative", name)
+				}
+				oc.RateLimit = limit
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["rate_limit_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				limit, err := b.Int()
+				if err != nil {
+					return nil, err
+				}
+				if limit < 0 {
+					return nil, fmt.Errorf("output %s: rate_limit_bytes must not be "+
+						"negative", name)
+				}
+				oc.RateLimitBytes = limit
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["wal_directory"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.WALDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["wal_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				size, err := b.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.WALMaxSize = size
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["wal_segment_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				size, err := b.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.WALSegmentSize = size
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["inputs"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						oc.Inputs = append(oc.Inputs, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	delete(tbl.Fields, "flush_interval")
+	delete(tbl.Fields, "inputs")
+	delete(tbl.Fields, "rate_limit")
+	delete(tbl.Fields, "rate_limit_bytes")
+	delete(tbl.Fields, "wal_directory")
+	delete(tbl.Fields, "wal_max_size")
+	delete(tbl.Fields, "wal_segment_size")
+	oc.Filter = buildFilter(tbl)
 	return oc, nil
 }
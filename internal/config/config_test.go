@@ -1,14 +1,21 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	_ "github.com/influxdata/telegraf/plugins/outputs/influxdb"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/env"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,8 +29,10 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 	mConfig := &internal_models.InputConfig{
 		Name: "memcached",
 		Filter: internal_models.Filter{
-			Drop: []string{"other", "stuff"},
-			Pass: []string{"some", "strings"},
+			NameDrop: []string{"metricname2"},
+			NamePass: []string{"metricname1"},
+			Drop:     []string{"other", "stuff"},
+			Pass:     []string{"some", "strings"},
 			TagDrop: []internal_models.TagFilter{
 				internal_models.TagFilter{
 					Name:   "badtag",
@@ -44,6 +53,9 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	// The ID is a hash of the options, checked by TestConfig_PluginID
+	assert.NotEmpty(t, c.Inputs[0].Config.ID)
+	mConfig.ID = c.Inputs[0].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 }
@@ -65,8 +77,10 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	mConfig := &internal_models.InputConfig{
 		Name: "memcached",
 		Filter: internal_models.Filter{
-			Drop: []string{"other", "stuff"},
-			Pass: []string{"some", "strings"},
+			NameDrop: []string{"metricname2"},
+			NamePass: []string{"metricname1"},
+			Drop:     []string{"other", "stuff"},
+			Pass:     []string{"some", "strings"},
 			TagDrop: []internal_models.TagFilter{
 				internal_models.TagFilter{
 					Name:   "badtag",
@@ -87,6 +101,9 @@ func TestConfig_LoadDirectory(t *testing.T) {
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	// The ID is a hash of the options, checked by TestConfig_PluginID
+	assert.NotEmpty(t, c.Inputs[0].Config.ID)
+	mConfig.ID = c.Inputs[0].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 
@@ -97,6 +114,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 		MeasurementSuffix: "_myothercollector",
 	}
 	eConfig.Tags = make(map[string]string)
+	eConfig.ID = c.Inputs[1].Config.ID
 	assert.Equal(t, ex, c.Inputs[1].Input,
 		"Merged Testdata did not produce a correct exec struct.")
 	assert.Equal(t, eConfig, c.Inputs[1].Config,
@@ -105,17 +123,244 @@ func TestConfig_LoadDirectory(t *testing.T) {
 	memcached.Servers = []string{"192.168.1.1"}
 	assert.Equal(t, memcached, c.Inputs[2].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.ID = c.Inputs[2].Config.ID
 	assert.Equal(t, mConfig, c.Inputs[2].Config,
 		"Testdata did not produce correct memcached metadata.")
 
 	pstat := inputs.Inputs["procstat"]().(*procstat.Procstat)
 	pstat.PidFile = "/var/run/grafana-server.pid"
+	pstat.Log = logger.NewLogger("inputs", "procstat", "")
 
 	pConfig := &internal_models.InputConfig{Name: "procstat"}
 	pConfig.Tags = make(map[string]string)
+	pConfig.ID = c.Inputs[3].Config.ID
 
 	assert.Equal(t, pstat, c.Inputs[3].Input,
 		"Merged Testdata did not produce a correct procstat struct.")
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_LoadGlobalTags(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/global_tags.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"dc": "us-east-1", "rack": "1a"}, c.Tags)
+}
+
+func TestConfig_LoadNegativeInterval(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/negative_interval.toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_LoadGatherTimeout(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/gather_timeout.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(c.Inputs)) {
+		assert.Equal(t, time.Minute, c.Inputs[0].Config.Interval)
+		assert.Equal(t, 30*time.Second, c.Inputs[0].Config.GatherTimeout)
+	}
+}
+
+func TestConfig_LoadPrecision(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/input_precision.toml")
+	assert.NoError(t, err)
+	assert.Equal(t, "1s", c.Agent.Precision)
+	if assert.Equal(t, 1, len(c.Inputs)) {
+		assert.Equal(t, time.Millisecond, c.Inputs[0].Config.Precision)
+	}
+}
+
+func TestConfig_LoadOutputFlushInterval(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_flush_interval.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(c.Outputs)) {
+		assert.Equal(t, time.Second, c.Outputs[0].Config.FlushInterval)
+	}
+}
+
+func TestConfig_LoadOutputRateLimit(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_rate_limit.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(c.Outputs)) {
+		assert.Equal(t, int64(100), c.Outputs[0].Config.RateLimit)
+		assert.Equal(t, int64(65536), c.Outputs[0].Config.RateLimitBytes)
+	}
+}
+
+func TestConfig_LoadOutputInputs(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/output_inputs.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(c.Outputs)) {
+		assert.Equal(t, []string{"cpu", "exec::business"},
+			c.Outputs[0].Config.Inputs)
+		assert.Empty(t, c.Outputs[1].Config.Inputs)
+	}
+}
+
+func TestConfig_LoadAlias(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/alias.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(c.Inputs)) {
+		assert.Equal(t, "first", c.Inputs[0].Config.Alias)
+		assert.Equal(t, "memcached::first", c.Inputs[0].LogName())
+		assert.Equal(t, "", c.Inputs[1].Config.Alias)
+		assert.Equal(t, "memcached", c.Inputs[1].LogName())
+	}
+	if assert.Equal(t, 1, len(c.Outputs)) {
+		assert.Equal(t, "influxdb::archive", c.Outputs[0].LogName())
+	}
+}
+
+func TestConfig_PluginID(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/plugin_id.toml")
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(c.Inputs)) {
+		// The common options do not change the ID, the plugin's options do
+		assert.NotEmpty(t, c.Inputs[0].Config.ID)
+		assert.Equal(t, c.Inputs[0].Config.ID, c.Inputs[1].Config.ID)
+		assert.NotEqual(t, c.Inputs[0].Config.ID, c.Inputs[2].Config.ID)
+	}
+}
+
+func TestConfig_LoadEnvVars(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_DC", "us-east-1")
+	os.Setenv("TELEGRAF_TEST_SERVER", "192.168.1.1")
+	defer os.Unsetenv("TELEGRAF_TEST_DC")
+	defer os.Unsetenv("TELEGRAF_TEST_SERVER")
+
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/env_vars.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"dc": "us-east-1", "rack": "1a"}, c.Tags)
+
+	memcached := inputs.Inputs["memcached"]().(*memcached.Memcached)
+	memcached.Servers = []string{"192.168.1.1"}
+	assert.Equal(t, memcached, c.Inputs[0].Input)
+}
+
+func TestConfig_LoadSecrets(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_DC", "us-east-1")
+	os.Setenv("TELEGRAF_TEST_SERVER", "192.168.1.1")
+	defer os.Unsetenv("TELEGRAF_TEST_DC")
+	defer os.Unsetenv("TELEGRAF_TEST_SERVER")
+
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secrets.toml")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"dc": "us-east-1"}, c.Tags)
+
+	memcached := inputs.Inputs["memcached"]().(*memcached.Memcached)
+	memcached.Servers = []string{"192.168.1.1:11211"}
+	assert.Equal(t, memcached, c.Inputs[0].Input)
+
+	// A missing secret is a config error
+	os.Unsetenv("TELEGRAF_TEST_SERVER")
+	c = NewConfig()
+	err = c.LoadConfig("./testdata/secrets.toml")
+	assert.Error(t, err)
+}
+
+func TestConfig_LoadSecretsUndefinedStore(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secrets_undefined_store.toml")
+	assert.Error(t, err)
+}
+
+type initInput struct {
+	err         error
+	initialized bool
+}
+
+func (i *initInput) Description() string                   { return "" }
+func (i *initInput) SampleConfig() string                  { return "" }
+func (i *initInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *initInput) Init() error {
+	i.initialized = true
+	return i.err
+}
+
+func TestConfig_InitPlugins(t *testing.T) {
+	ok := &initInput{}
+	failing := &initInput{err: errors.New("invalid servers")}
+	c := NewConfig()
+	c.Inputs = []*internal_models.RunningInput{
+		{
+			Name:   "ok",
+			Input:  ok,
+			Config: &internal_models.InputConfig{Name: "ok"},
+		},
+		{
+			Name:   "failing",
+			Input:  failing,
+			Config: &internal_models.InputConfig{Name: "failing", Alias: "a"},
+		},
+	}
+
+	err := c.InitPlugins()
+	assert.True(t, ok.initialized)
+	assert.True(t, failing.initialized)
+	if assert.Error(t, err) {
+		assert.Equal(t, "inputs.failing::a: invalid servers", err.Error())
+	}
+}
+
+func TestConfig_LoadUnknownOption(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/unknown_option.toml")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown_option.toml")
+		assert.Contains(t, err.Error(), "inputs.memcached")
+	}
+}
+
+func TestPrintFilteredSampleConfig(t *testing.T) {
+	var buf bytes.Buffer
+	PrintFilteredSampleConfig(&buf, SampleConfigFilters{
+		Sections: []string{"inputs"},
+		Inputs:   []string{"memcached"},
+	})
+	out := buf.String()
+	assert.Contains(t, out, "[[inputs.memcached]]")
+	assert.NotContains(t, out, "[[inputs.exec]]")
+	assert.NotContains(t, out, "[agent]")
+	assert.NotContains(t, out, "[global_tags]")
+	assert.NotContains(t, out, "OUTPUTS")
+
+	buf.Reset()
+	PrintFilteredSampleConfig(&buf, SampleConfigFilters{
+		Sections: []string{"agent", "outputs"},
+		Outputs:  []string{"influxdb"},
+	})
+	out = buf.String()
+	assert.Contains(t, out, "[agent]")
+	assert.Contains(t, out, "[[outputs.influxdb]]")
+	assert.NotContains(t, out, "[[inputs.")
+}
+
+type percentPlugin struct{}
+
+func (p *percentPlugin) Description() string { return "Percent signs" }
+func (p *percentPlugin) SampleConfig() string {
+	return `
+  pattern = "%{WORD} %d %s 100%"
+`
+}
+
+func TestPrintConfig_VerbatimSampleConfig(t *testing.T) {
+	var buf bytes.Buffer
+	printConfig(&buf, "percent", &percentPlugin{}, "inputs")
+	assert.Equal(t, "\n# Percent signs\n[[inputs.percent]]\n"+
+		`  pattern = "%{WORD} %d %s 100%"`+"\n", buf.String())
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+)
+
+// deprecationNotice formats the warning logged for something deprecated
+func deprecationNotice(what string, info telegraf.DeprecationInfo) string {
+	msg := what + " is deprecated"
+	if info.Since != "" {
+		msg += " since version " + info.Since
+	}
+	if info.RemovalIn != "" {
+		msg += " and will be removed in version " + info.RemovalIn
+	}
+	if info.Notice != "" {
+		msg += ", " + info.Notice
+	}
+	return msg
+}
+
+// checkDeprecations warns if the plugin of the given category, ie "inputs",
+// is deprecated, and migrates its deprecated options.
+func checkDeprecations(
+	category string,
+	name string,
+	plugin interface{},
+	deprecations map[string]telegraf.DeprecationInfo,
+	l telegraf.Logger,
+) error {
+	if info, ok := deprecations[name]; ok {
+		l.Warn(deprecationNotice(
+			fmt.Sprintf("Plugin %q", category+"."+name), info))
+	}
+	if err := migrateOptions(plugin, l); err != nil {
+		return fmt.Errorf("%s.%s: %s", category, name, err)
+	}
+	return nil
+}
+
+// parseDeprecatedTag parses a `deprecated:"since;removal;notice"` struct tag
+func parseDeprecatedTag(tag string) telegraf.DeprecationInfo {
+	parts := strings.SplitN(tag, ";", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return telegraf.DeprecationInfo{
+		Since:     strings.TrimSpace(parts[0]),
+		RemovalIn: strings.TrimSpace(parts[1]),
+		Notice:    strings.TrimSpace(parts[2]),
+	}
+}
+
+// migrateOptions warns about the deprecated options set on the plugin, the
+// fields with a `deprecated` struct tag, and moves their value to the field
+// named by their `migrate` struct tag. A value is moved if that field is not
+// set, or appended to it if it is a slice of the deprecated option's type.
+func migrateOptions(plugin interface{}, l telegraf.Logger) error {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("deprecated")
+		if tag == "" {
+			continue
+		}
+		value := v.Field(i)
+		if isZero(value) {
+			continue
+		}
+
+		l.Warn(deprecationNotice(fmt.Sprintf("Option %q", optionName(field)),
+			parseDeprecatedTag(tag)))
+
+		target := field.Tag.Get("migrate")
+		if target == "" {
+			continue
+		}
+		dest := v.FieldByName(target)
+		if !dest.IsValid() || !dest.CanSet() {
+			return fmt.Errorf("option %q migrates to unknown field %s",
+				optionName(field), target)
+		}
+		switch {
+		case dest.Type() == value.Type():
+			if isZero(dest) {
+				dest.Set(value)
+			}
+		case dest.Kind() == reflect.Slice &&
+			dest.Type().Elem() == value.Type():
+			dest.Set(reflect.Append(dest, value))
+		default:
+			return fmt.Errorf("option %q of type %s cannot be migrated to "+
+				"field %s of type %s", optionName(field), value.Type(),
+				target, dest.Type())
+		}
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// optionName returns the name of the option set by the field in the config
+// file, its toml tag or its snake_cased name, ie "ssl_cert" for SSLCert.
+func optionName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("toml"), ",")[0]; name != "" {
+		return name
+	}
+
+	runes := []rune(field.Name)
+	var name []rune
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				name = append(name, '_')
+			}
+		}
+		name = append(name, unicode.ToLower(r))
+	}
+	return string(name)
+}
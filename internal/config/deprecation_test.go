@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type deprecatedPlugin struct {
+	Cert    string `deprecated:"0.10.2;0.12.0;use 'ssl_cert' instead" migrate:"SSLCert"`
+	SSLCert string `toml:"ssl_cert"`
+	URL     string `deprecated:"0.10.1;;use 'urls' instead" migrate:"URLs"`
+	URLs    []string
+	Old     int `deprecated:"0.10.0"`
+}
+
+func TestMigrateOptions(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	p := &deprecatedPlugin{
+		Cert: "/etc/cert.pem",
+		URL:  "http://localhost:8086",
+		URLs: []string{"http://localhost:8087"},
+	}
+	assert.NoError(t, migrateOptions(p, testutil.Logger{Name: "outputs.test"}))
+
+	assert.Equal(t, "/etc/cert.pem", p.SSLCert)
+	assert.Equal(t, []string{"http://localhost:8087", "http://localhost:8086"},
+		p.URLs)
+	assert.Contains(t, buf.String(), `Option "cert" is deprecated since `+
+		`version 0.10.2 and will be removed in version 0.12.0, use 'ssl_cert' `+
+		`instead`)
+	assert.Contains(t, buf.String(), `Option "url" is deprecated since `+
+		`version 0.10.1, use 'urls' instead`)
+	// Options which are not set are not warned about
+	assert.NotContains(t, buf.String(), `"old"`)
+}
+
+func TestMigrateOptions_NewOptionWins(t *testing.T) {
+	p := &deprecatedPlugin{Cert: "/etc/old.pem", SSLCert: "/etc/new.pem"}
+	assert.NoError(t, migrateOptions(p, testutil.Logger{}))
+	assert.Equal(t, "/etc/new.pem", p.SSLCert)
+}
+
+func TestMigrateOptions_InvalidTarget(t *testing.T) {
+	p := &struct {
+		Old string `deprecated:"0.10.0" migrate:"Missing"`
+	}{Old: "value"}
+	assert.Error(t, migrateOptions(p, testutil.Logger{}))
+
+	q := &struct {
+		Old int `deprecated:"0.10.0" migrate:"New"`
+		New string
+	}{Old: 1}
+	assert.Error(t, migrateOptions(q, testutil.Logger{}))
+}
+
+func TestOptionName(t *testing.T) {
+	typ := reflect.TypeOf(struct {
+		SSLCert     string
+		URLs        []string `toml:"urls"`
+		MountPoints []string
+		VerifySsl   bool
+		CA          string
+	}{})
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		names = append(names, optionName(typ.Field(i)))
+	}
+	assert.Equal(t,
+		[]string{"ssl_cert", "urls", "mount_points", "verify_ssl", "ca"}, names)
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+// PluginCategories are the categories of plugins, in the order they are
+// listed
+var PluginCategories = []string{"inputs", "outputs", "processors",
+	"aggregators", "secretstores"}
+
+// PluginListOptions select the plugins listed by PrintPluginList
+type PluginListOptions struct {
+	// Categories are the categories of plugins to list, ie "inputs", all of
+	// them if empty
+	Categories []string
+	// Deprecated lists only the deprecated plugins
+	Deprecated bool
+	// SampleConfig prints the sample config of the plugins instead of their
+	// description
+	SampleConfig bool
+}
+
+// PrintPluginList writes the names and descriptions of the plugins compiled
+// into telegraf to w, or their sample config, sorted by category and name.
+func PrintPluginList(w io.Writer, opts PluginListOptions) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+
+	for _, category := range PluginCategories {
+		if len(opts.Categories) > 0 &&
+			!sliceContains(category, opts.Categories) {
+			continue
+		}
+		plugins, deprecations := registeredPlugins(category)
+
+		var names []string
+		for name := range plugins {
+			if _, ok := deprecations[name]; ok || !opts.Deprecated {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+
+		if opts.SampleConfig {
+			for _, name := range names {
+				printConfig(w, name, plugins[name](), category)
+			}
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s:\n", category)
+		for _, name := range names {
+			fmt.Fprintf(tw, "  %s\t%s\n", name, plugins[name]().Description())
+			if info, ok := deprecations[name]; ok {
+				fmt.Fprintf(tw, "  \t%s\n", deprecationNotice("Plugin", info))
+			}
+		}
+	}
+}
+
+// registeredPlugins returns the creators and deprecations of the plugins of
+// the given category
+func registeredPlugins(
+	category string,
+) (map[string]func() printer, map[string]telegraf.DeprecationInfo) {
+	plugins := make(map[string]func() printer)
+	switch category {
+	case "inputs":
+		for name, creator := range inputs.Inputs {
+			creator := creator
+			plugins[name] = func() printer { return creator() }
+		}
+		return plugins, inputs.Deprecations
+	case "outputs":
+		for name, creator := range outputs.Outputs {
+			creator := creator
+			plugins[name] = func() printer { return creator() }
+		}
+		return plugins, outputs.Deprecations
+	case "processors":
+		for name, creator := range processors.Processors {
+			creator := creator
+			plugins[name] = func() printer { return creator() }
+		}
+		return plugins, processors.Deprecations
+	case "aggregators":
+		for name, creator := range aggregators.Aggregators {
+			creator := creator
+			plugins[name] = func() printer { return creator() }
+		}
+		return plugins, aggregators.Deprecations
+	case "secretstores":
+		for name, creator := range secretstores.SecretStores {
+			creator := creator
+			plugins[name] = func() printer { return creator() }
+		}
+	}
+	return plugins, nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintPluginList(t *testing.T) {
+	var buf bytes.Buffer
+	PrintPluginList(&buf, PluginListOptions{Categories: []string{"inputs"}})
+	out := buf.String()
+	assert.Contains(t, out, "inputs:\n")
+	assert.Contains(t, out, "  memcached ")
+	assert.Contains(t, out, inputs.Inputs["memcached"]().Description())
+	assert.NotContains(t, out, "outputs:")
+
+	buf.Reset()
+	PrintPluginList(&buf, PluginListOptions{
+		Categories:   []string{"outputs"},
+		SampleConfig: true,
+	})
+	out = buf.String()
+	assert.Contains(t, out, "[[outputs.influxdb]]")
+	assert.NotContains(t, out, "[[inputs.")
+}
+
+func TestPrintPluginList_Deprecated(t *testing.T) {
+	inputs.AddDeprecated("deprecated_test", func() telegraf.Input {
+		return &initInput{}
+	}, telegraf.DeprecationInfo{Since: "0.10.2", Notice: "use 'memcached'"})
+	defer func() {
+		delete(inputs.Inputs, "deprecated_test")
+		delete(inputs.Deprecations, "deprecated_test")
+	}()
+
+	var buf bytes.Buffer
+	PrintPluginList(&buf, PluginListOptions{Deprecated: true})
+	out := buf.String()
+	assert.Contains(t, out, "deprecated_test")
+	assert.Contains(t, out, "Plugin is deprecated since version 0.10.2, "+
+		"use 'memcached'")
+	assert.NotContains(t, out, "memcached ")
+}
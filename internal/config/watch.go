@@ -0,0 +1,110 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileState is what a Watcher compares to detect that a file changed
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher detects changes to config files and to the *.conf files of config
+// directories by polling them. Symlinks are followed, so that configs
+// updated by swapping a symlink, like Kubernetes mounted ConfigMaps, are
+// detected as well.
+type Watcher struct {
+	paths []string
+	state map[string]fileState
+}
+
+// NewWatcher returns a Watcher of the given config files and directories,
+// taking their current state as the reference for Changed.
+func NewWatcher(paths ...string) *Watcher {
+	w := &Watcher{paths: paths}
+	w.state = w.snapshot()
+	return w
+}
+
+// Changed returns true if a watched file was modified, created or removed
+// since the previous call, or since the Watcher was created.
+func (w *Watcher) Changed() bool {
+	state := w.snapshot()
+	changed := len(state) != len(w.state)
+	if !changed {
+		for path, s := range state {
+			if prev, ok := w.state[path]; !ok || prev != s {
+				changed = true
+				break
+			}
+		}
+	}
+	w.state = state
+	return changed
+}
+
+// Watch calls Changed every interval until done is closed, sending on
+// changed every time the config changed. A change is not sent while the
+// previous one has not been received.
+func (w *Watcher) Watch(
+	interval time.Duration,
+	changed chan<- struct{},
+	done <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !w.Changed() {
+				continue
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// snapshot returns the state of the watched files, missing files having no
+// state
+func (w *Watcher) snapshot() map[string]fileState {
+	state := make(map[string]fileState)
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			state[path] = fileState{info.ModTime(), info.Size()}
+			continue
+		}
+
+		// Only the files loaded by LoadDirectory are watched
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasSuffix(name, ".conf") {
+				continue
+			}
+			file := filepath.Join(path, name)
+			info, err := os.Stat(file)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			state[file] = fileState{info.ModTime(), info.Size()}
+		}
+	}
+	return state
+}
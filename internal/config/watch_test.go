@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Changed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "telegraf.conf")
+	confDir := filepath.Join(dir, "telegraf.d")
+	require.NoError(t, os.Mkdir(confDir, 0755))
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n"), 0644))
+
+	w := NewWatcher(file, confDir)
+	assert.False(t, w.Changed())
+
+	// Modified file
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n  debug = true\n"),
+		0644))
+	assert.True(t, w.Changed())
+	assert.False(t, w.Changed())
+
+	// Created file in the config directory, files not ending in .conf are
+	// not watched
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "README"),
+		[]byte("readme"), 0644))
+	assert.False(t, w.Changed())
+	cpu := filepath.Join(confDir, "cpu.conf")
+	require.NoError(t, ioutil.WriteFile(cpu, []byte("[[inputs.cpu]]\n"), 0644))
+	assert.True(t, w.Changed())
+
+	// Removed file
+	require.NoError(t, os.Remove(cpu))
+	assert.True(t, w.Changed())
+	assert.False(t, w.Changed())
+}
+
+// Test that swapping a symlink, as done by Kubernetes for mounted ConfigMaps,
+// is detected
+func TestWatcher_Symlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	v1 := filepath.Join(dir, "v1.conf.data")
+	v2 := filepath.Join(dir, "v2.conf.data")
+	require.NoError(t, ioutil.WriteFile(v1, []byte("[agent]\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(v2, []byte("[agent]\n  debug = true\n"),
+		0644))
+
+	link := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, os.Symlink(v1, link))
+	w := NewWatcher(link)
+
+	tmp := filepath.Join(dir, "telegraf.conf.tmp")
+	require.NoError(t, os.Symlink(v2, tmp))
+	require.NoError(t, os.Rename(tmp, link))
+	assert.True(t, w.Changed())
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "telegraf.conf")
+
+	w := NewWatcher(file)
+	changed := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go w.Watch(10*time.Millisecond, changed, done)
+
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n"), 0644))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change of the config file was not detected")
+	}
+}
@@ -0,0 +1,127 @@
+// Package globpath matches the paths of files with glob patterns, the
+// patterns of filepath.Match extended with "**" matching any number of
+// directories, ie "/var/log/**.log" or "/var/spool/**/*.msg".
+package globpath
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GlobPath is a compiled glob pattern of paths
+type GlobPath struct {
+	path string
+	// root is the directory walked to match a pattern with "**", its longest
+	// directory without wildcards
+	root  string
+	regex *regexp.Regexp
+}
+
+// Compile returns the glob pattern of the path.
+func Compile(path string) (*GlobPath, error) {
+	path = filepath.Clean(path)
+	g := &GlobPath{path: path}
+	if !strings.Contains(path, "**") {
+		// Check the pattern now rather than on every match
+		_, err := filepath.Match(path, "")
+		return g, err
+	}
+
+	g.root = path[:strings.Index(path, "**")]
+	if i := strings.IndexAny(g.root, "*?["); i >= 0 {
+		g.root = g.root[:i]
+	}
+	g.root = filepath.Dir(g.root + "x")
+
+	regex, err := regexp.Compile("^" + toRegexp(path) + "$")
+	if err != nil {
+		return nil, err
+	}
+	g.regex = regex
+	return g, nil
+}
+
+// toRegexp returns the regular expression of the glob pattern
+func toRegexp(pattern string) string {
+	var b []string
+	sep := regexp.QuoteMeta(string(filepath.Separator))
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" also matches no directory at all
+				if i+1 < len(pattern) && pattern[i+1] == filepath.Separator {
+					i++
+					b = append(b, "(.*"+sep+")?")
+				} else {
+					b = append(b, ".*")
+				}
+			} else {
+				b = append(b, "[^"+sep+"]*")
+			}
+		case '?':
+			b = append(b, "[^"+sep+"]")
+		case '[':
+			j := strings.IndexByte(pattern[i:], ']')
+			if j < 0 {
+				b = append(b, regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			class := pattern[i+1 : i+j]
+			if strings.HasPrefix(class, "^") || strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b = append(b, "["+class+"]")
+			i += j
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b = append(b, regexp.QuoteMeta(pattern[i:i+1]))
+		default:
+			b = append(b, regexp.QuoteMeta(string(c)))
+		}
+	}
+	return strings.Join(b, "")
+}
+
+// HasMeta returns whether the pattern has wildcards, or is a single path
+func (g *GlobPath) HasMeta() bool {
+	return strings.ContainsAny(g.path, "*?[")
+}
+
+// Match returns the paths of the existing files and directories matching the
+// pattern, in lexical order.
+func (g *GlobPath) Match() []string {
+	if g.regex == nil {
+		matches, _ := filepath.Glob(g.path)
+		return matches
+	}
+
+	var matches []string
+	filepath.Walk(g.root, func(path string, info os.FileInfo, err error) error {
+		// The unreadable directories are skipped
+		if err != nil {
+			return nil
+		}
+		if g.regex.MatchString(path) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches
+}
+
+// MatchString returns whether the path matches the pattern, without checking
+// the file exists.
+func (g *GlobPath) MatchString(path string) bool {
+	if g.regex == nil {
+		ok, _ := filepath.Match(g.path, path)
+		return ok
+	}
+	return g.regex.MatchString(path)
+}
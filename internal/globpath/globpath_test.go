@@ -0,0 +1,69 @@
+package globpath
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tree creates the files in a temporary directory, returning the directory
+func tree(t *testing.T, files ...string) string {
+	dir, err := ioutil.TempDir("", "globpath")
+	require.NoError(t, err)
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	}
+	return dir
+}
+
+func TestMatch(t *testing.T) {
+	dir := tree(t, "a.log", "b.log", "c.txt", "sub/d.log", "sub/deep/e.log")
+	defer os.RemoveAll(dir)
+
+	for pattern, exp := range map[string][]string{
+		"*.log":       {"a.log", "b.log"},
+		"?.txt":       {"c.txt"},
+		"[ab].log":    {"a.log", "b.log"},
+		"a.log":       {"a.log"},
+		"missing.log": nil,
+		"**.log": {"a.log", "b.log", "sub/d.log",
+			"sub/deep/e.log"},
+		"**/*.log": {"a.log", "b.log", "sub/d.log",
+			"sub/deep/e.log"},
+		"sub/**/e.log": {"sub/deep/e.log"},
+		"s*/**.log":    {"sub/d.log", "sub/deep/e.log"},
+	} {
+		g, err := Compile(filepath.Join(dir, pattern))
+		require.NoError(t, err)
+
+		var matches []string
+		for _, match := range g.Match() {
+			rel, err := filepath.Rel(dir, match)
+			require.NoError(t, err)
+			matches = append(matches, filepath.ToSlash(rel))
+		}
+		assert.Equal(t, exp, matches, pattern)
+	}
+}
+
+func TestHasMeta(t *testing.T) {
+	g, err := Compile("/var/log/syslog")
+	require.NoError(t, err)
+	assert.False(t, g.HasMeta())
+	assert.True(t, g.MatchString("/var/log/syslog"))
+
+	g, err = Compile("/var/log/**.log")
+	require.NoError(t, err)
+	assert.True(t, g.HasMeta())
+	assert.True(t, g.MatchString("/var/log/nginx/access.log"))
+	assert.False(t, g.MatchString("/var/log/nginx/access.log.1"))
+
+	_, err = Compile("/var/log/[.log")
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPProxyOptions are the proxy options of the plugins making HTTP requests:
+// http_proxy and https_proxy, the proxies of the http and https requests, and
+// use_system_proxy, using the proxy of the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables for the requests without a proxy set.
+type HTTPProxyOptions struct {
+	HTTPProxy      string
+	HTTPSProxy     string
+	UseSystemProxy bool
+}
+
+// GetProxy returns the function selecting the proxy of a request, to be set
+// as the Proxy of an http.Transport. The requests are sent directly if it
+// returns nil.
+func GetProxy(
+	o HTTPProxyOptions,
+) (func(*http.Request) (*url.URL, error), error) {
+	httpProxy, err := parseProxy("http_proxy", o.HTTPProxy)
+	if err != nil {
+		return nil, err
+	}
+	httpsProxy, err := parseProxy("https_proxy", o.HTTPSProxy)
+	if err != nil {
+		return nil, err
+	}
+	if httpProxy == nil && httpsProxy == nil && !o.UseSystemProxy {
+		return nil, nil
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		switch {
+		case req.URL.Scheme == "https" && httpsProxy != nil:
+			return httpsProxy, nil
+		case req.URL.Scheme == "http" && httpProxy != nil:
+			return httpProxy, nil
+		case o.UseSystemProxy:
+			return http.ProxyFromEnvironment(req)
+		}
+		return nil, nil
+	}, nil
+}
+
+// NewHTTPClient returns an HTTP client with the given timeout, sending its
+// requests through the proxies of the options. It also sends the requests of
+// "unix://" URLs over unix sockets, see RegisterUnixSockets.
+func NewHTTPClient(
+	timeout time.Duration,
+	o HTTPProxyOptions,
+) (*http.Client, error) {
+	proxy, err := GetProxy(o)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: RegisterUnixSockets(&http.Transport{
+			Proxy: proxy,
+			Dial: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).Dial,
+			TLSHandshakeTimeout: 10 * time.Second,
+		}),
+		Timeout: timeout,
+	}, nil
+}
+
+// RegisterUnixSockets registers the "unix" scheme on the transport, sending
+// the requests of "unix:///path/to.sock/request/path" URLs to /request/path
+// over the unix socket /path/to.sock, and returns the transport. The requests
+// over unix sockets use the ResponseHeaderTimeout of the transport.
+func RegisterUnixSockets(t *http.Transport) *http.Transport {
+	t.RegisterProtocol("unix", &unixTransport{
+		responseHeaderTimeout: t.ResponseHeaderTimeout,
+		transports:            make(map[string]*http.Transport),
+	})
+	return t
+}
+
+// SplitUnixSocketPath splits the path of a "unix://" URL into the path of the
+// unix socket, its longest prefix which is a socket, and the path of the
+// request, ie "/var/run/nginx.sock/status" into "/var/run/nginx.sock" and
+// "/status".
+func SplitUnixSocketPath(path string) (string, string, error) {
+	for i := len(path); i > 0; i = strings.LastIndex(path[:i], "/") {
+		info, err := os.Stat(path[:i])
+		if err == nil && info.Mode()&os.ModeSocket != 0 {
+			request := path[i:]
+			if request == "" {
+				request = "/"
+			}
+			return path[:i], request, nil
+		}
+	}
+	return "", "", fmt.Errorf("no unix socket found in %s", path)
+}
+
+// unixTransport sends the requests over the unix socket of their URL, with
+// one transport per socket
+type unixTransport struct {
+	responseHeaderTimeout time.Duration
+
+	sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func (u *unixTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	socket, path, err := SplitUnixSocketPath(req.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Lock()
+	t, ok := u.transports[socket]
+	if !ok {
+		t = &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.DialTimeout("unix", socket, 30*time.Second)
+			},
+			ResponseHeaderTimeout: u.responseHeaderTimeout,
+		}
+		u.transports[socket] = t
+	}
+	u.Unlock()
+
+	// The request must not be modified, send a copy with an http URL
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.URL.Scheme = "http"
+	r.URL.Host = "localhost"
+	r.URL.Path = path
+	r.Host = "localhost"
+	return t.RoundTrip(r)
+}
+
+// parseProxy parses the URL of a proxy, nil if empty. URLs without a scheme,
+// ie "proxy:3128", are http proxies.
+func parseProxy(option, proxy string) (*url.URL, error) {
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s %q", option, proxy)
+	}
+	return u, nil
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyOf(
+	t *testing.T,
+	proxy func(*http.Request) (*url.URL, error),
+	rawurl string,
+) string {
+	req, err := http.NewRequest("GET", rawurl, nil)
+	require.NoError(t, err)
+	u, err := proxy(req)
+	require.NoError(t, err)
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+func TestGetProxy(t *testing.T) {
+	proxy, err := GetProxy(HTTPProxyOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, proxy)
+
+	proxy, err = GetProxy(HTTPProxyOptions{
+		HTTPProxy:  "proxy:3128",
+		HTTPSProxy: "https://secure-proxy:3129",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", proxyOf(t, proxy, "http://example.com"))
+	assert.Equal(t, "https://secure-proxy:3129",
+		proxyOf(t, proxy, "https://example.com"))
+
+	// Requests without a proxy set are sent directly
+	proxy, err = GetProxy(HTTPProxyOptions{HTTPProxy: "proxy:3128"})
+	require.NoError(t, err)
+	assert.Equal(t, "", proxyOf(t, proxy, "https://example.com"))
+
+	_, err = GetProxy(HTTPProxyOptions{HTTPSProxy: "http://"})
+	assert.Error(t, err)
+}
+
+func TestGetProxy_System(t *testing.T) {
+	os.Setenv("HTTPS_PROXY", "http://system-proxy:3128")
+	defer os.Unsetenv("HTTPS_PROXY")
+
+	proxy, err := GetProxy(HTTPProxyOptions{
+		HTTPProxy:      "proxy:3128",
+		UseSystemProxy: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://proxy:3128", proxyOf(t, proxy, "http://example.com"))
+	assert.Equal(t, "http://system-proxy:3128",
+		proxyOf(t, proxy, "https://example.com"))
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	client, err := NewHTTPClient(5*time.Second,
+		HTTPProxyOptions{HTTPProxy: "proxy:3128"})
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, client.Timeout)
+	assert.NotNil(t, client.Transport.(*http.Transport).Proxy)
+
+	_, err = NewHTTPClient(0, HTTPProxyOptions{HTTPProxy: "http://"})
+	assert.Error(t, err)
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "server.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go http.Serve(listener, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", r.URL.Path, r.URL.RawQuery)
+		}))
+
+	s, path, err := SplitUnixSocketPath(socket + "/status/json")
+	require.NoError(t, err)
+	assert.Equal(t, socket, s)
+	assert.Equal(t, "/status/json", path)
+	_, _, err = SplitUnixSocketPath(dir + "/missing.sock/status")
+	assert.Error(t, err)
+
+	client, err := NewHTTPClient(5*time.Second, HTTPProxyOptions{})
+	require.NoError(t, err)
+	for u, expected := range map[string]string{
+		"unix://" + socket + "/status?full": "/status full",
+		"unix://" + socket:                  "/ ",
+	} {
+		resp, err := client.Get(u)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(body))
+	}
+}
@@ -0,0 +1,295 @@
+// Package httpconfig creates the HTTP clients of the plugins making HTTP
+// requests from the options they share: timeouts, connection reuse, custom
+// headers, authentication, TLS and proxies.
+//
+// The config is not embedded in the plugins, which declare the options as
+// their own fields and copy them to a Config:
+//
+//	type MyPlugin struct {
+//		Timeout            internal.Duration `toml:"timeout"`
+//		KeepAlive          internal.Duration `toml:"keep_alive"`
+//		MaxIdleConns       int               `toml:"max_idle_conns"`
+//		Headers            map[string]string `toml:"headers"`
+//		Username           string            `toml:"username"`
+//		Password           string            `toml:"password"`
+//		BearerToken        string            `toml:"bearer_token"`
+//		OAuth2ClientID     string            `toml:"oauth2_client_id"`
+//		OAuth2ClientSecret string            `toml:"oauth2_client_secret"`
+//		OAuth2TokenURL     string            `toml:"oauth2_token_url"`
+//		OAuth2Scopes       []string          `toml:"oauth2_scopes"`
+//	}
+package httpconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Config are the options of an HTTP client
+type Config struct {
+	// Timeout of the requests, including reading the response, no timeout
+	// if zero
+	Timeout time.Duration
+	// KeepAlive is the period of the TCP keep-alives of the connections, 30s
+	// if zero
+	KeepAlive time.Duration
+	// MaxIdleConns is the number of idle connections kept open per host for
+	// the next requests, http.DefaultMaxIdleConnsPerHost if zero. Negative
+	// values disable the reuse of connections.
+	MaxIdleConns int
+
+	// Headers are added to every request, a "Host" header setting the host
+	// of the request
+	Headers map[string]string
+
+	// Credentials of the requests, either a username and password for basic
+	// auth, a bearer token, or the OAuth2 client credentials requesting the
+	// bearer tokens
+	Username    string
+	Password    string
+	BearerToken string
+	OAuth2      OAuth2Config
+
+	// TLS options of the https requests, see internal.GetTLSConfig
+	TLS   internal.TLSOptions
+	Proxy internal.HTTPProxyOptions
+}
+
+// OAuth2Config are the credentials of an OAuth2 client credentials grant. The
+// access tokens are requested from TokenURL, for the given scopes, and kept
+// until they expire.
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	Scopes       []string
+}
+
+// CreateClient returns an HTTP client with the options of the config. It
+// sends the requests of "unix://" URLs over unix sockets, see
+// internal.RegisterUnixSockets.
+func (c *Config) CreateClient() (*http.Client, error) {
+	auths := 0
+	for _, set := range []bool{
+		c.Username != "" || c.Password != "",
+		c.BearerToken != "",
+		c.OAuth2.ClientID != "" || c.OAuth2.TokenURL != "",
+	} {
+		if set {
+			auths++
+		}
+	}
+	if auths > 1 {
+		return nil, errors.New("only one of username and password, " +
+			"bearer_token or the oauth2 options can be set")
+	}
+	if (c.OAuth2.ClientID != "") != (c.OAuth2.TokenURL != "") {
+		return nil, errors.New("oauth2_client_id and oauth2_token_url must " +
+			"be set together")
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(c.TLS)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := internal.GetProxy(c.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	keepAlive := c.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = 30 * time.Second
+	}
+	base := internal.RegisterUnixSockets(&http.Transport{
+		Proxy: proxy,
+		Dial: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: keepAlive,
+		}).Dial,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: c.MaxIdleConns,
+		DisableKeepAlives:   c.MaxIdleConns < 0,
+	})
+
+	t := &transport{
+		base:     base,
+		headers:  c.Headers,
+		username: c.Username,
+		password: c.Password,
+		token:    c.BearerToken,
+		copies:   make(map[*http.Request]*http.Request),
+	}
+	if c.OAuth2.ClientID != "" {
+		t.oauth2 = &tokenSource{
+			config: c.OAuth2,
+			client: &http.Client{Transport: base, Timeout: c.Timeout},
+		}
+	}
+	return &http.Client{Transport: t, Timeout: c.Timeout}, nil
+}
+
+// transport adds the headers and credentials of the config to the requests
+type transport struct {
+	base *http.Transport
+
+	headers  map[string]string
+	username string
+	password string
+	token    string
+	oauth2   *tokenSource
+
+	// copies are the copies of the requests in flight, sent to base
+	sync.Mutex
+	copies map[*http.Request]*http.Request
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.token
+	if t.oauth2 != nil {
+		var err error
+		if token, err = t.oauth2.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The request must not be modified, send a copy with its own headers
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+len(t.headers)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	for k, v := range t.headers {
+		if strings.ToLower(k) == "host" {
+			r.Host = v
+		} else {
+			r.Header.Set(k, v)
+		}
+	}
+	switch {
+	case t.username != "" || t.password != "":
+		r.SetBasicAuth(t.username, t.password)
+	case token != "":
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	t.setCopy(req, r)
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		t.setCopy(req, nil)
+		return nil, err
+	}
+	resp.Body = &closeNotifier{ReadCloser: resp.Body, closed: func() {
+		t.setCopy(req, nil)
+	}}
+	return resp, nil
+}
+
+// CancelRequest cancels the requests timing out, see http.Client.Timeout
+func (t *transport) CancelRequest(req *http.Request) {
+	t.Lock()
+	r, ok := t.copies[req]
+	t.Unlock()
+	if ok {
+		t.base.CancelRequest(r)
+	}
+}
+
+func (t *transport) setCopy(req *http.Request, r *http.Request) {
+	t.Lock()
+	defer t.Unlock()
+	if r == nil {
+		delete(t.copies, req)
+	} else {
+		t.copies[req] = r
+	}
+}
+
+// closeNotifier calls closed once the body of a response is closed
+type closeNotifier struct {
+	io.ReadCloser
+	once   sync.Once
+	closed func()
+}
+
+func (c *closeNotifier) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.closed)
+	return err
+}
+
+// tokenSource requests the access tokens of an OAuth2 client credentials
+// grant, requesting a new token once the last one expired
+type tokenSource struct {
+	config OAuth2Config
+	client *http.Client
+
+	sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns the current access token, requesting a new one if expired.
+func (s *tokenSource) Token() (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	// Tokens are renewed a little before they expire, so that they don't
+	// expire in flight
+	if s.token != "" &&
+		(s.expiry.IsZero() || time.Now().Add(10*time.Second).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.config.Scopes, " "))
+	}
+	req, err := http.NewRequest("POST", s.config.TokenURL,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.config.ClientID),
+		url.QueryEscape(s.config.ClientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not request OAuth2 token: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not request OAuth2 token: %s returned "+
+			"HTTP status %s", s.config.TokenURL, resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("could not decode OAuth2 token: %s", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access_token returned by %s",
+			s.config.TokenURL)
+	}
+
+	s.token = token.AccessToken
+	s.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return s.token, nil
+}
@@ -0,0 +1,135 @@
+package httpconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// get returns the body of a GET request of u with the client of c
+func get(t *testing.T, c Config, u string) string {
+	client, err := c.CreateClient()
+	require.NoError(t, err)
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCreateClient_HeadersAndAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s|%s|%s", r.Host, r.Header.Get("X-Api-Version"),
+				r.Header.Get("Authorization"))
+		}))
+	defer ts.Close()
+
+	assert.Equal(t, "example.com|v1|", get(t, Config{
+		Headers: map[string]string{
+			"Host":          "example.com",
+			"X-Api-Version": "v1",
+		},
+	}, ts.URL))
+	host := strings.TrimPrefix(ts.URL, "http://")
+	assert.Equal(t, host+"|v1|Basic dXNlcjpwYXNz", get(t, Config{
+		Headers:  map[string]string{"X-Api-Version": "v1"},
+		Username: "user",
+		Password: "pass",
+	}, ts.URL))
+	assert.Equal(t, host+"||Bearer secret", get(t, Config{
+		BearerToken: "secret",
+	}, ts.URL))
+}
+
+func TestCreateClient_OAuth2(t *testing.T) {
+	tokens := 0
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/token" {
+				fmt.Fprint(w, r.Header.Get("Authorization"))
+				return
+			}
+			id, secret, _ := r.BasicAuth()
+			if id != "telegraf" || secret != "secret" ||
+				r.FormValue("grant_type") != "client_credentials" ||
+				r.FormValue("scope") != "read write" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			tokens++
+			fmt.Fprintf(w, `{"access_token":"token%d","expires_in":3600}`,
+				tokens)
+		}))
+	defer ts.Close()
+
+	c := Config{
+		Timeout: 5 * time.Second,
+		OAuth2: OAuth2Config{
+			ClientID:     "telegraf",
+			ClientSecret: "secret",
+			TokenURL:     ts.URL + "/token",
+			Scopes:       []string{"read", "write"},
+		},
+	}
+	client, err := c.CreateClient()
+	require.NoError(t, err)
+
+	// The token is requested once and reused until it expires
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL + "/metrics")
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token1", string(body))
+	}
+	assert.Equal(t, 1, tokens)
+
+	c.OAuth2.ClientSecret = "wrong"
+	client, err = c.CreateClient()
+	require.NoError(t, err)
+	_, err = client.Get(ts.URL + "/metrics")
+	assert.Error(t, err)
+}
+
+func TestCreateClient_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "ok")
+		}))
+	defer ts.Close()
+
+	client, err := (&Config{}).CreateClient()
+	require.NoError(t, err)
+	_, err = client.Get(ts.URL)
+	assert.Error(t, err)
+
+	assert.Equal(t, "ok", get(t, Config{
+		TLS: internal.TLSOptions{InsecureSkipVerify: true},
+	}, ts.URL))
+}
+
+func TestCreateClient_Invalid(t *testing.T) {
+	for _, c := range []Config{
+		{TLS: internal.TLSOptions{SSLCert: "cert.pem"}},
+		{Username: "user", BearerToken: "secret"},
+		{BearerToken: "secret", OAuth2: OAuth2Config{
+			ClientID: "telegraf",
+			TokenURL: "http://localhost/token",
+		}},
+		{OAuth2: OAuth2Config{ClientID: "telegraf"}},
+	} {
+		_, err := c.CreateClient()
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package intern deduplicates strings, so that the tag keys and values
+// repeated by every metric of a series share their backing storage instead of
+// being allocated again for every metric.
+package intern
+
+import "sync"
+
+// DefaultMaxSize is the number of strings held by the default Pool
+const DefaultMaxSize = 100000
+
+// Pool holds a bounded set of interned strings. Once it holds maxSize
+// strings it is emptied, so that high cardinality values, like request IDs
+// in tags, do not grow it forever.
+type Pool struct {
+	sync.RWMutex
+
+	strings map[string]string
+	maxSize int
+}
+
+// NewPool returns a Pool holding at most maxSize strings
+func NewPool(maxSize int) *Pool {
+	return &Pool{
+		strings: make(map[string]string),
+		maxSize: maxSize,
+	}
+}
+
+// String returns the interned copy of s, interning s if it is not yet
+func (p *Pool) String(s string) string {
+	p.RLock()
+	interned, ok := p.strings[s]
+	p.RUnlock()
+	if ok {
+		return interned
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if interned, ok := p.strings[s]; ok {
+		return interned
+	}
+	if len(p.strings) >= p.maxSize {
+		p.strings = make(map[string]string)
+	}
+	p.strings[s] = s
+	return s
+}
+
+// Bytes returns the interned string of b, only allocating it if it is not
+// yet interned.
+func (p *Pool) Bytes(b []byte) string {
+	p.RLock()
+	// The conversion in the map index does not allocate
+	interned, ok := p.strings[string(b)]
+	p.RUnlock()
+	if ok {
+		return interned
+	}
+	return p.String(string(b))
+}
+
+// Len returns the number of strings held by the pool
+func (p *Pool) Len() int {
+	p.RLock()
+	defer p.RUnlock()
+	return len(p.strings)
+}
+
+var defaultPool = NewPool(DefaultMaxSize)
+
+// String interns s in the default Pool
+func String(s string) string {
+	return defaultPool.String(s)
+}
+
+// Bytes interns b in the default Pool
+func Bytes(b []byte) string {
+	return defaultPool.Bytes(b)
+}
@@ -0,0 +1,53 @@
+package intern
+
+import (
+	"fmt"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// data returns the address of the backing storage of s
+func data(s string) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&s))[0]
+}
+
+func TestPool_String(t *testing.T) {
+	p := NewPool(10)
+	first := p.String(fmt.Sprintf("host-%d", 1))
+	second := p.String(fmt.Sprintf("host-%d", 1))
+	assert.Equal(t, "host-1", second)
+	assert.Equal(t, data(first), data(second))
+	assert.Equal(t, 1, p.Len())
+}
+
+func TestPool_Bytes(t *testing.T) {
+	p := NewPool(10)
+	first := p.Bytes([]byte("us-east-1"))
+	second := p.Bytes([]byte("us-east-1"))
+	assert.Equal(t, "us-east-1", second)
+	assert.Equal(t, data(first), data(second))
+}
+
+func TestPool_MaxSize(t *testing.T) {
+	p := NewPool(2)
+	p.String("a")
+	p.String("b")
+	assert.Equal(t, 2, p.Len())
+
+	// The pool is emptied once full
+	p.String("c")
+	assert.Equal(t, 1, p.Len())
+	assert.Equal(t, "a", p.String("a"))
+}
+
+func BenchmarkPool_Bytes(b *testing.B) {
+	p := NewPool(DefaultMaxSize)
+	value := []byte("us-east-1")
+	p.Bytes(value)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Bytes(value)
+	}
+}
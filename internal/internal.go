@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"math/rand"
 	"os"
 	"strconv"
 	"strings"
@@ -27,6 +28,81 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 	return nil
 }
 
+// Size is a size in bytes, given in the TOML config file either as an
+// integer or as a string with a unit, ie "10MB" or "1GiB". The sizes can be
+// negative, ie "-10MB", for the options with a meaning for the sign.
+type Size struct {
+	Size int64
+}
+
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KIB": 1024,
+	"MIB": 1024 * 1024,
+	"GIB": 1024 * 1024 * 1024,
+}
+
+// UnmarshalTOML parses the size from the TOML config file
+func (s *Size) UnmarshalTOML(b []byte) error {
+	str := strings.TrimSpace(string(b))
+	if n, err := strconv.ParseInt(str, 10, 64); err == nil {
+		s.Size = n
+		return nil
+	}
+
+	str = strings.Trim(str, `"'`)
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-'
+	})
+	if i == -1 {
+		i = len(str)
+	}
+	n, err := strconv.ParseFloat(str[:i], 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", str)
+	}
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(str[i:]))]
+	if !ok {
+		return fmt.Errorf("invalid size unit in %q", str)
+	}
+	s.Size = int64(n * float64(unit))
+	return nil
+}
+
+// precisionUnits are the precisions accepted by ParsePrecision as a unit
+// alone, as used by the line protocol
+var precisionUnits = map[string]time.Duration{
+	"n":  time.Nanosecond,
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// ParsePrecision parses a timestamp precision, given either as a duration,
+// ie "1s" or "100ms", or as a unit, ie "s" or "ms". An empty precision is 0,
+// meaning timestamps are not rounded.
+func ParsePrecision(precision string) (time.Duration, error) {
+	if precision == "" {
+		return 0, nil
+	}
+	if d, ok := precisionUnits[precision]; ok {
+		return d, nil
+	}
+	d, err := time.ParseDuration(precision)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid precision %q", precision)
+	}
+	return d, nil
+}
+
 var NotImplementedError = errors.New("not implemented yet")
 
 type JSONFlattener struct {
@@ -160,3 +236,24 @@ func Glob(pattern, measurement string) bool {
 	// All parts of the pattern matched
 	return true
 }
+
+func init() {
+	// Seed the shared source so that agents started at the same time don't
+	// all pick the same random jitter.
+	rand.Seed(time.Now().UnixNano())
+}
+
+// RandomSleep sleeps for a random duration between 0 and max. It returns
+// early if the shutdown channel is closed.
+func RandomSleep(max time.Duration, shutdown chan struct{}) {
+	if max <= 0 {
+		return
+	}
+
+	t := time.NewTimer(time.Duration(rand.Int63n(max.Nanoseconds())))
+	select {
+	case <-t.C:
+	case <-shutdown:
+		t.Stop()
+	}
+}
@@ -1,6 +1,9 @@
 package internal
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func testGlobMatch(t *testing.T, pattern, subj string) {
 	if !Glob(pattern, subj) {
@@ -42,3 +45,84 @@ func TestGlob(t *testing.T) {
 		testGlobNoMatch(t, pattern, "this_is_a_test")
 	}
 }
+
+func TestRandomSleep(t *testing.T) {
+	// test that zero max returns immediately
+	s := time.Now()
+	RandomSleep(0, make(chan struct{}))
+	if elapsed := time.Since(s); elapsed > 10*time.Millisecond {
+		t.Errorf("RandomSleep(0) took %s", elapsed)
+	}
+
+	// test that the sleep is at most max
+	s = time.Now()
+	RandomSleep(50*time.Millisecond, make(chan struct{}))
+	if elapsed := time.Since(s); elapsed > 100*time.Millisecond {
+		t.Errorf("RandomSleep(50ms) took %s", elapsed)
+	}
+
+	// test that closing shutdown returns early
+	shutdown := make(chan struct{})
+	close(shutdown)
+	s = time.Now()
+	RandomSleep(time.Hour, shutdown)
+	if elapsed := time.Since(s); elapsed > 10*time.Millisecond {
+		t.Errorf("RandomSleep with closed shutdown took %s", elapsed)
+	}
+}
+
+func TestSizeUnmarshalTOML(t *testing.T) {
+	for input, exp := range map[string]int64{
+		`1024`:     1024,
+		`"1024"`:   1024,
+		`"10MB"`:   10 * 1000 * 1000,
+		`"10 MiB"`: 10 * 1024 * 1024,
+		`"1.5kb"`:  1500,
+		`"2GiB"`:   2 * 1024 * 1024 * 1024,
+		`-10`:      -10,
+		`"-1KB"`:   -1000,
+	} {
+		var s Size
+		if err := s.UnmarshalTOML([]byte(input)); err != nil {
+			t.Errorf("%s: unexpected error %s", input, err)
+			continue
+		}
+		if s.Size != exp {
+			t.Errorf("%s: actual %d, expected %d", input, s.Size, exp)
+		}
+	}
+
+	for _, input := range []string{`"10XB"`, `"MB"`, `""`} {
+		var s Size
+		if err := s.UnmarshalTOML([]byte(input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	for input, exp := range map[string]time.Duration{
+		"":      0,
+		"n":     time.Nanosecond,
+		"u":     time.Microsecond,
+		"ms":    time.Millisecond,
+		"s":     time.Second,
+		"1s":    time.Second,
+		"100ms": 100 * time.Millisecond,
+	} {
+		d, err := ParsePrecision(input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", input, err)
+			continue
+		}
+		if d != exp {
+			t.Errorf("%s: actual %s, expected %s", input, d, exp)
+		}
+	}
+
+	for _, input := range []string{"x", "-1s", "0s"} {
+		if _, err := ParsePrecision(input); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
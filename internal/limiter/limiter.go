@@ -0,0 +1,61 @@
+// Package limiter limits the rate of an activity, such as the number of
+// metrics or bytes written to an output per second.
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket refilled with rate tokens per second. The
+// bucket holds at most one second worth of tokens, smoothing bursts over
+// time instead of letting a backlog through at once.
+//
+// Taking more tokens than available puts the bucket in debt, so that a large
+// batch is let through after a wait proportional to its size.
+type RateLimiter struct {
+	sync.Mutex
+
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a full RateLimiter allowing rate tokens per second
+func NewRateLimiter(rate int64) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// Reserve takes n tokens from the bucket and returns how long to wait before
+// they can be used.
+func (r *RateLimiter) Reserve(n int64) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.rate {
+		r.tokens = r.rate
+	}
+	r.last = now
+
+	r.tokens -= float64(n)
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.rate * float64(time.Second))
+}
+
+// Wait takes n tokens from the bucket, sleeping until they can be used, and
+// returns how long it waited.
+func (r *RateLimiter) Wait(n int64) time.Duration {
+	wait := r.Reserve(n)
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return wait
+}
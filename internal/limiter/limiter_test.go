@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	r := NewRateLimiter(10)
+
+	// The bucket starts full
+	assert.Equal(t, time.Duration(0), r.Reserve(10))
+
+	// Taking more than available waits for the bucket to refill
+	wait := r.Reserve(5)
+	assert.InDelta(t, 500*time.Millisecond, wait, float64(50*time.Millisecond))
+
+	// The debt is paid before new tokens are available
+	wait = r.Reserve(20)
+	assert.InDelta(t, 2500*time.Millisecond, wait, float64(50*time.Millisecond))
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	r := NewRateLimiter(100)
+	assert.Equal(t, time.Duration(0), r.Reserve(100))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), r.Reserve(5))
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	r := NewRateLimiter(100)
+
+	// The bucket holds at most one second worth of tokens
+	time.Sleep(50 * time.Millisecond)
+	wait := r.Reserve(150)
+	assert.InDelta(t, 500*time.Millisecond, wait, float64(50*time.Millisecond))
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	r := NewRateLimiter(100)
+	r.Reserve(100)
+
+	start := time.Now()
+	wait := r.Wait(10)
+	assert.True(t, time.Since(start) >= wait)
+	assert.InDelta(t, 100*time.Millisecond, wait, float64(20*time.Millisecond))
+}
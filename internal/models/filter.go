@@ -13,24 +13,58 @@ type TagFilter struct {
 	Filter []string
 }
 
-// Filter containing drop/pass and tagdrop/tagpass rules
+// Filter containing namedrop/namepass, drop/pass, fielddrop/fieldpass,
+// tagdrop/tagpass and tagexclude/taginclude rules
 type Filter struct {
+	NameDrop []string
+	NamePass []string
+
 	Drop []string
 	Pass []string
 
+	FieldDrop []string
+	FieldPass []string
+
 	TagDrop []TagFilter
 	TagPass []TagFilter
 
+	TagExclude []string
+	TagInclude []string
+
 	IsActive bool
 }
 
 func (f Filter) ShouldMetricPass(metric telegraf.Metric) bool {
-	if f.ShouldPass(metric.Name()) && f.ShouldTagsPass(metric.Tags()) {
+	if f.ShouldNamePass(metric.Name()) &&
+		f.ShouldPass(metric.Name()) &&
+		f.ShouldTagsPass(metric.Tags()) {
 		return true
 	}
 	return false
 }
 
+// ShouldNamePass returns true if the measurement name should pass, false if
+// should drop based on the namedrop/namepass filter parameters
+func (f Filter) ShouldNamePass(name string) bool {
+	if f.NamePass != nil {
+		for _, pat := range f.NamePass {
+			if internal.Glob(pat, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if f.NameDrop != nil {
+		for _, pat := range f.NameDrop {
+			if internal.Glob(pat, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // ShouldPass returns true if the metric should pass, false if should drop
 // based on the drop/pass filter parameters
 func (f Filter) ShouldPass(key string) bool {
@@ -90,3 +124,90 @@ func (f Filter) ShouldTagsPass(tags map[string]string) bool {
 
 	return true
 }
+
+// ShouldFieldsPass returns true if the field should pass, false if should drop
+// based on the fielddrop/fieldpass filter parameters
+func (f Filter) ShouldFieldsPass(key string) bool {
+	if f.FieldPass != nil {
+		for _, pat := range f.FieldPass {
+			if internal.Glob(pat, key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if f.FieldDrop != nil {
+		for _, pat := range f.FieldDrop {
+			if internal.Glob(pat, key) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FilterTags removes the tags that don't match the taginclude parameter, or
+// that match the tagexclude parameter, from the given tags
+func (f Filter) FilterTags(tags map[string]string) {
+	if f.TagInclude != nil {
+		for k := range tags {
+			if !globAny(f.TagInclude, k) {
+				delete(tags, k)
+			}
+		}
+	}
+
+	if f.TagExclude != nil {
+		for k := range tags {
+			if globAny(f.TagExclude, k) {
+				delete(tags, k)
+			}
+		}
+	}
+}
+
+// ModifiesMetrics returns true if the filter removes fields or tags from the
+// metrics passing it
+func (f Filter) ModifiesMetrics() bool {
+	return f.FieldPass != nil || f.FieldDrop != nil ||
+		f.TagInclude != nil || f.TagExclude != nil
+}
+
+// FilterMetric returns the metric with the fields and tags removed according
+// to the fielddrop/fieldpass and tagexclude/taginclude filter parameters. It
+// returns nil if no field is left.
+func (f Filter) FilterMetric(metric telegraf.Metric) telegraf.Metric {
+	if !f.ModifiesMetrics() {
+		return metric
+	}
+
+	fields := make(map[string]interface{})
+	for k, v := range metric.Fields() {
+		if f.ShouldFieldsPass(k) {
+			fields[k] = v
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	tags := metric.Tags()
+	f.FilterTags(tags)
+
+	m, err := telegraf.NewTypedMetric(metric.Type(), metric.Name(), tags, fields,
+		metric.Time())
+	if err != nil {
+		return nil
+	}
+	return m
+}
+
+func globAny(patterns []string, key string) bool {
+	for _, pat := range patterns {
+		if internal.Glob(pat, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,11 @@
 package internal_models
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
 )
 
 func TestFilter_Empty(t *testing.T) {
@@ -90,6 +94,67 @@ func TestFilter_Drop(t *testing.T) {
 	}
 }
 
+func TestFilter_NamePass(t *testing.T) {
+	f := Filter{
+		NamePass: []string{"foo*", "cpu"},
+	}
+
+	passes := []string{
+		"foo",
+		"foo_bar",
+		"cpu",
+	}
+
+	drops := []string{
+		"bar",
+		"barfoo",
+		// unlike pass, namepass only matches globs and not prefixes
+		"cpu_usage",
+	}
+
+	for _, measurement := range passes {
+		if !f.ShouldNamePass(measurement) {
+			t.Errorf("Expected measurement %s to pass", measurement)
+		}
+	}
+
+	for _, measurement := range drops {
+		if f.ShouldNamePass(measurement) {
+			t.Errorf("Expected measurement %s to drop", measurement)
+		}
+	}
+}
+
+func TestFilter_NameDrop(t *testing.T) {
+	f := Filter{
+		NameDrop: []string{"foo*", "cpu"},
+	}
+
+	drops := []string{
+		"foo",
+		"foo_bar",
+		"cpu",
+	}
+
+	passes := []string{
+		"bar",
+		"barfoo",
+		"cpu_usage",
+	}
+
+	for _, measurement := range passes {
+		if !f.ShouldNamePass(measurement) {
+			t.Errorf("Expected measurement %s to pass", measurement)
+		}
+	}
+
+	for _, measurement := range drops {
+		if f.ShouldNamePass(measurement) {
+			t.Errorf("Expected measurement %s to drop", measurement)
+		}
+	}
+}
+
 func TestFilter_TagPass(t *testing.T) {
 	filters := []TagFilter{
 		TagFilter{
@@ -175,3 +240,98 @@ func TestFilter_TagDrop(t *testing.T) {
 		}
 	}
 }
+
+func TestFilter_FieldPass(t *testing.T) {
+	f := Filter{
+		FieldPass: []string{"foo*", "cpu_usage_idle"},
+	}
+
+	passes := []string{"foo", "foo_bar", "cpu_usage_idle"}
+	drops := []string{"bar", "barfoo", "cpu_usage_busy"}
+
+	for _, field := range passes {
+		if !f.ShouldFieldsPass(field) {
+			t.Errorf("Expected field %s to pass", field)
+		}
+	}
+
+	for _, field := range drops {
+		if f.ShouldFieldsPass(field) {
+			t.Errorf("Expected field %s to drop", field)
+		}
+	}
+}
+
+func TestFilter_FieldDrop(t *testing.T) {
+	f := Filter{
+		FieldDrop: []string{"foo*", "cpu_usage_idle"},
+	}
+
+	drops := []string{"foo", "foo_bar", "cpu_usage_idle"}
+	passes := []string{"bar", "barfoo", "cpu_usage_busy"}
+
+	for _, field := range passes {
+		if !f.ShouldFieldsPass(field) {
+			t.Errorf("Expected field %s to pass", field)
+		}
+	}
+
+	for _, field := range drops {
+		if f.ShouldFieldsPass(field) {
+			t.Errorf("Expected field %s to drop", field)
+		}
+	}
+}
+
+func TestFilter_FilterTags(t *testing.T) {
+	f := Filter{
+		TagInclude: []string{"cpu*", "host"},
+		TagExclude: []string{"cpu_internal"},
+	}
+
+	tags := map[string]string{
+		"cpu":          "cpu0",
+		"cpu_internal": "x",
+		"host":         "localhost",
+		"dc":           "us-east-1",
+	}
+	f.FilterTags(tags)
+
+	expected := map[string]string{
+		"cpu":  "cpu0",
+		"host": "localhost",
+	}
+	if !reflect.DeepEqual(expected, tags) {
+		t.Errorf("Expected tags %v, got %v", expected, tags)
+	}
+}
+
+func TestFilter_FilterMetric(t *testing.T) {
+	f := Filter{
+		FieldDrop:  []string{"usage_guest*"},
+		TagExclude: []string{"dc"},
+	}
+
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"cpu": "cpu0", "dc": "us-east-1"},
+		map[string]interface{}{"usage_idle": 1.0, "usage_guest": 2.0},
+		time.Now())
+	out := f.FilterMetric(m)
+	if out == nil {
+		t.Fatal("Expected metric to pass")
+	}
+	if !reflect.DeepEqual(map[string]string{"cpu": "cpu0"}, out.Tags()) {
+		t.Errorf("Unexpected tags %v", out.Tags())
+	}
+	if !reflect.DeepEqual(map[string]interface{}{"usage_idle": 1.0},
+		out.Fields()) {
+		t.Errorf("Unexpected fields %v", out.Fields())
+	}
+
+	// Metrics without fields left are dropped
+	m, _ = telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"usage_guest": 2.0}, time.Now())
+	if f.FilterMetric(m) != nil {
+		t.Error("Expected metric without fields to drop")
+	}
+}
@@ -0,0 +1,26 @@
+package internal_models
+
+import "sync"
+
+// running holds the inputs and outputs run by the agent, so that plugins
+// such as the internal input can report their stats.
+var running struct {
+	sync.Mutex
+	inputs  []*RunningInput
+	outputs []*RunningOutput
+}
+
+// SetRunning sets the inputs and outputs run by the agent
+func SetRunning(inputs []*RunningInput, outputs []*RunningOutput) {
+	running.Lock()
+	defer running.Unlock()
+	running.inputs = inputs
+	running.outputs = outputs
+}
+
+// Running returns the inputs and outputs run by the agent
+func Running() ([]*RunningInput, []*RunningOutput) {
+	running.Lock()
+	defer running.Unlock()
+	return running.inputs, running.outputs
+}
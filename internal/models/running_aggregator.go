@@ -0,0 +1,185 @@
+package internal_models
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const DEFAULT_AGGREGATOR_PERIOD = 30 * time.Second
+
+type RunningAggregator struct {
+	Name       string
+	Aggregator telegraf.Aggregator
+	Config     *AggregatorConfig
+}
+
+// AggregatorConfig containing configuration parameters for the running
+// aggregator plugin.
+type AggregatorConfig struct {
+	Name  string
+	Alias string
+	ID    string
+
+	DropOriginal      bool
+	NameOverride      string
+	MeasurementPrefix string
+	MeasurementSuffix string
+	Tags              map[string]string
+	Filter            Filter
+
+	Period time.Duration
+}
+
+func NewRunningAggregator(
+	name string,
+	aggregator telegraf.Aggregator,
+	conf *AggregatorConfig,
+) *RunningAggregator {
+	if conf.Period == 0 {
+		conf.Period = DEFAULT_AGGREGATOR_PERIOD
+	}
+	return &RunningAggregator{
+		Name:       name,
+		Aggregator: aggregator,
+		Config:     conf,
+	}
+}
+
+// LogName returns the name of the aggregator, with its alias if set
+func (ra *RunningAggregator) LogName() string {
+	return logName(ra.Name, ra.Config.Alias)
+}
+
+// Add applies the aggregator to the given metric. It returns true if the
+// original metric should be dropped instead of being written to the outputs.
+func (ra *RunningAggregator) Add(m telegraf.Metric) bool {
+	if ra.Config.Filter.IsActive {
+		if !ra.Config.Filter.ShouldMetricPass(m) {
+			return false
+		}
+	}
+
+	ra.Aggregator.Add(m)
+	return ra.Config.DropOriginal
+}
+
+// Push returns the aggregates of the current period and resets the
+// aggregator for the next period.
+func (ra *RunningAggregator) Push() []telegraf.Metric {
+	acc := &aggregateAccumulator{config: ra.Config}
+	ra.Aggregator.Push(acc)
+	ra.Aggregator.Reset()
+	return acc.metrics
+}
+
+// aggregateAccumulator collects the metrics pushed by an aggregator,
+// applying the name and tag modifications of the aggregator's config.
+type aggregateAccumulator struct {
+	config  *AggregatorConfig
+	metrics []telegraf.Metric
+	debug   bool
+}
+
+func (ac *aggregateAccumulator) Add(
+	measurement string,
+	value interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	fields := make(map[string]interface{})
+	fields["value"] = value
+	ac.AddFields(measurement, fields, tags, t...)
+}
+
+func (ac *aggregateAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (ac *aggregateAccumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (ac *aggregateAccumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.addFields(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (ac *aggregateAccumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	valueType telegraf.ValueType,
+	t ...time.Time,
+) {
+	if len(fields) == 0 || len(measurement) == 0 {
+		return
+	}
+
+	// Override measurement name if set
+	if len(ac.config.NameOverride) != 0 {
+		measurement = ac.config.NameOverride
+	}
+	// Apply measurement prefix and suffix if set
+	measurement = ac.config.MeasurementPrefix + measurement +
+		ac.config.MeasurementSuffix
+
+	mtags := make(map[string]string)
+	for k, v := range tags {
+		mtags[k] = v
+	}
+	for k, v := range ac.config.Tags {
+		if _, ok := mtags[k]; !ok {
+			mtags[k] = v
+		}
+	}
+
+	result := make(map[string]interface{})
+	for k, v := range fields {
+		// NaNs are invalid values in influxdb, skip the field
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			continue
+		}
+		result[k] = v
+	}
+	if len(result) == 0 {
+		return
+	}
+
+	timestamp := time.Now()
+	if len(t) > 0 {
+		timestamp = t[0]
+	}
+
+	m, err := telegraf.NewTypedMetric(valueType, measurement, mtags, result,
+		timestamp)
+	if err != nil {
+		log.Printf("Error adding aggregate [%s]: %s\n", measurement, err.Error())
+		return
+	}
+	ac.metrics = append(ac.metrics, m)
+}
+
+func (ac *aggregateAccumulator) Debug() bool {
+	return ac.debug
+}
+
+func (ac *aggregateAccumulator) SetDebug(debug bool) {
+	ac.debug = debug
+}
@@ -0,0 +1,81 @@
+package internal_models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sumAggregator sums the "value" field of all metrics added to it
+type sumAggregator struct {
+	sum int64
+}
+
+func (s *sumAggregator) SampleConfig() string { return "" }
+func (s *sumAggregator) Description() string  { return "" }
+func (s *sumAggregator) Add(in telegraf.Metric) {
+	if v, ok := in.Fields()["value"].(int64); ok {
+		s.sum += v
+	}
+}
+func (s *sumAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("sum", map[string]interface{}{"value": s.sum},
+		map[string]string{"foo": "bar"})
+}
+func (s *sumAggregator) Reset() {
+	s.sum = 0
+}
+
+func newValueMetric(t *testing.T, name string, value int64) telegraf.Metric {
+	m, err := telegraf.NewMetric(name, nil,
+		map[string]interface{}{"value": value}, time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func TestRunningAggregator_AddPush(t *testing.T) {
+	ra := NewRunningAggregator("sum", &sumAggregator{}, &AggregatorConfig{
+		Name:              "sum",
+		MeasurementPrefix: "agg_",
+		Tags:              map[string]string{"added": "tag"},
+		Filter: Filter{
+			Pass:     []string{"cpu"},
+			IsActive: true,
+		},
+	})
+	assert.Equal(t, DEFAULT_AGGREGATOR_PERIOD, ra.Config.Period)
+
+	assert.False(t, ra.Add(newValueMetric(t, "cpu", 1)))
+	assert.False(t, ra.Add(newValueMetric(t, "cpu", 2)))
+	assert.False(t, ra.Add(newValueMetric(t, "mem", 3)))
+
+	out := ra.Push()
+	require.Len(t, out, 1)
+	assert.Equal(t, "agg_sum", out[0].Name())
+	assert.Equal(t, map[string]string{"foo": "bar", "added": "tag"},
+		out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(3)}, out[0].Fields())
+
+	// The aggregator is reset after a push
+	out = ra.Push()
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{"value": int64(0)}, out[0].Fields())
+}
+
+func TestRunningAggregator_DropOriginal(t *testing.T) {
+	ra := NewRunningAggregator("sum", &sumAggregator{}, &AggregatorConfig{
+		Name:         "sum",
+		DropOriginal: true,
+		Filter: Filter{
+			Pass:     []string{"cpu"},
+			IsActive: true,
+		},
+	})
+
+	assert.True(t, ra.Add(newValueMetric(t, "cpu", 1)))
+	// Metrics that don't pass the filter are never dropped
+	assert.False(t, ra.Add(newValueMetric(t, "mem", 1)))
+}
@@ -1,6 +1,7 @@
 package internal_models
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -10,15 +11,168 @@ type RunningInput struct {
 	Name   string
 	Input  telegraf.Input
 	Config *InputConfig
+
+	metricsGathered int64
+	gatherErrors    int64
+	gatherTime      int64
+	gatherTimeouts  int64
+	gatherPanics    int64
+	slowGathers     int64
+	goroutines      int64
+
+	consecutiveSlow     int64
+	consecutiveFailures int64
+
+	// gathering is set while a gather of the input is running
+	gathering int32
+	// disabled is set once the input is no longer gathered
+	disabled int32
+}
+
+// LogName returns the name of the input, with its alias if set
+func (ri *RunningInput) LogName() string {
+	return logName(ri.Name, ri.Config.Alias)
+}
+
+// StartGather marks the input as being gathered. It returns false if the
+// previous gather of the input is still running.
+func (ri *RunningInput) StartGather() bool {
+	return atomic.CompareAndSwapInt32(&ri.gathering, 0, 1)
+}
+
+// EndGather marks the gather of the input as complete
+func (ri *RunningInput) EndGather() {
+	atomic.StoreInt32(&ri.gathering, 0)
+}
+
+// GatherTimedOut records a gather of the input exceeding its timeout
+func (ri *RunningInput) GatherTimedOut() {
+	atomic.AddInt64(&ri.gatherTimeouts, 1)
+}
+
+// GatherComplete records the number of metrics and the duration of a gather
+// of the input, the number of goroutines it left running, and whether it
+// failed.
+func (ri *RunningInput) GatherComplete(
+	metrics int64,
+	elapsed time.Duration,
+	goroutines int64,
+	err error,
+) {
+	atomic.AddInt64(&ri.metricsGathered, metrics)
+	atomic.StoreInt64(&ri.gatherTime, int64(elapsed))
+	atomic.StoreInt64(&ri.goroutines, goroutines)
+	if err != nil {
+		atomic.AddInt64(&ri.gatherErrors, 1)
+	}
+}
+
+// GatherPanicked records a gather of the input which panicked
+func (ri *RunningInput) GatherPanicked() {
+	atomic.AddInt64(&ri.gatherPanics, 1)
+}
+
+// GatherSlow records whether a gather of the input took longer than its
+// interval, returning the number of consecutive slow gathers.
+func (ri *RunningInput) GatherSlow(slow bool) int64 {
+	if !slow {
+		atomic.StoreInt64(&ri.consecutiveSlow, 0)
+		return 0
+	}
+	atomic.AddInt64(&ri.slowGathers, 1)
+	return atomic.AddInt64(&ri.consecutiveSlow, 1)
+}
+
+// GatherFailed records whether a gather of the input failed, returning the
+// number of consecutive failed gathers.
+func (ri *RunningInput) GatherFailed(failed bool) int64 {
+	if !failed {
+		atomic.StoreInt64(&ri.consecutiveFailures, 0)
+		return 0
+	}
+	return atomic.AddInt64(&ri.consecutiveFailures, 1)
+}
+
+// Disable stops the input from being gathered
+func (ri *RunningInput) Disable() {
+	atomic.StoreInt32(&ri.disabled, 1)
+}
+
+// Disabled returns true if the input is no longer gathered
+func (ri *RunningInput) Disabled() bool {
+	return atomic.LoadInt32(&ri.disabled) == 1
+}
+
+// MetricsGathered returns the total number of metrics gathered by the input
+func (ri *RunningInput) MetricsGathered() int64 {
+	return atomic.LoadInt64(&ri.metricsGathered)
+}
+
+// GatherErrors returns the total number of failed gathers
+func (ri *RunningInput) GatherErrors() int64 {
+	return atomic.LoadInt64(&ri.gatherErrors)
+}
+
+// GatherTimeouts returns the total number of gathers exceeding their timeout
+func (ri *RunningInput) GatherTimeouts() int64 {
+	return atomic.LoadInt64(&ri.gatherTimeouts)
+}
+
+// GatherTime returns the duration of the last gather
+func (ri *RunningInput) GatherTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&ri.gatherTime))
+}
+
+// GatherPanics returns the total number of gathers which panicked
+func (ri *RunningInput) GatherPanics() int64 {
+	return atomic.LoadInt64(&ri.gatherPanics)
+}
+
+// SlowGathers returns the total number of gathers which took longer than
+// the input's interval
+func (ri *RunningInput) SlowGathers() int64 {
+	return atomic.LoadInt64(&ri.slowGathers)
+}
+
+// ConsecutiveFailures returns the number of failed gathers since the last
+// successful one
+func (ri *RunningInput) ConsecutiveFailures() int64 {
+	return atomic.LoadInt64(&ri.consecutiveFailures)
+}
+
+// Goroutines returns the number of goroutines started by the last gather
+// and still running once it completed. It is approximate when other inputs
+// are gathered at the same time.
+func (ri *RunningInput) Goroutines() int64 {
+	return atomic.LoadInt64(&ri.goroutines)
 }
 
 // InputConfig containing a name, interval, and filter
 type InputConfig struct {
 	Name              string
+	Alias             string
+	ID                string
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+
+	// Precision rounds down the timestamps of the input's metrics, the
+	// agent's precision if zero
+	Precision time.Duration
+
+	// GatherTimeout is how long the agent waits for a gather of the input
+	// before moving on, the input's interval if zero
+	GatherTimeout time.Duration
+}
+
+// logName returns the name of a plugin as shown in logs, ie "exec::myalias"
+// for the exec plugin with the alias "myalias".
+func logName(name, alias string) string {
+	if alias == "" {
+		return name
+	}
+	return name + "::" + alias
 }
@@ -2,77 +2,340 @@ package internal_models
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/internal/limiter"
 )
 
-const DEFAULT_POINT_BUFFER_LIMIT = 10000
+const (
+	DEFAULT_METRIC_BATCH_SIZE   = 1000
+	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	DEFAULT_WAL_MAX_SIZE     = 256 * 1024 * 1024
+	DEFAULT_WAL_SEGMENT_SIZE = 16 * 1024 * 1024
+)
 
 type RunningOutput struct {
-	Name             string
-	Output           telegraf.Output
-	Config           *OutputConfig
-	Quiet            bool
-	PointBufferLimit int
+	Name   string
+	Output telegraf.Output
+	Config *OutputConfig
+	Quiet  bool
+
+	// MetricBatchSize is the maximum number of metrics passed to one call of
+	// the output's Write
+	MetricBatchSize int
+
+	// metrics holds the metrics waiting to be written. Metrics that failed
+	// to be written stay in the buffer and are retried on the next write.
+	metrics *buffer.Buffer
 
-	metrics          []telegraf.Metric
-	overwriteCounter int
+	// WAL, if set, spools the metrics that failed to be written to disk. They
+	// are written before any buffered metric once the output recovers.
+	WAL *buffer.WAL
+
+	// metricLimiter and byteLimiter, if set, delay the writes to keep under
+	// the rate limits of the output
+	metricLimiter *limiter.RateLimiter
+	byteLimiter   *limiter.RateLimiter
+
+	metricsWritten int64
+	writeErrors    int64
+	writeTime      int64
+	rateLimitDelay int64
+
+	// consecutiveErrors is the number of failed writes since the last
+	// successful write
+	consecutiveErrors int64
 }
 
 func NewRunningOutput(
 	name string,
 	output telegraf.Output,
 	conf *OutputConfig,
+	batchSize int,
+	bufferLimit int,
 ) *RunningOutput {
+	if batchSize <= 0 {
+		batchSize = DEFAULT_METRIC_BATCH_SIZE
+	}
+	if bufferLimit <= 0 {
+		bufferLimit = DEFAULT_METRIC_BUFFER_LIMIT
+	}
 	ro := &RunningOutput{
-		Name:             name,
-		metrics:          make([]telegraf.Metric, 0),
-		Output:           output,
-		Config:           conf,
-		PointBufferLimit: DEFAULT_POINT_BUFFER_LIMIT,
+		Name:            name,
+		MetricBatchSize: batchSize,
+		metrics:         buffer.NewBuffer(bufferLimit),
+		Output:          output,
+		Config:          conf,
+	}
+	if conf.RateLimit > 0 {
+		ro.metricLimiter = limiter.NewRateLimiter(conf.RateLimit)
+	}
+	if conf.RateLimitBytes > 0 {
+		ro.byteLimiter = limiter.NewRateLimiter(conf.RateLimitBytes)
 	}
 	return ro
 }
 
+// LogName returns the name of the output, with its alias if set
+func (ro *RunningOutput) LogName() string {
+	return logName(ro.Name, ro.Config.Alias)
+}
+
+// AddPoint adds a metric to the output's buffer, dropping the oldest
+// buffered metric if the buffer is full.
 func (ro *RunningOutput) AddPoint(point telegraf.Metric) {
 	if ro.Config.Filter.IsActive {
 		if !ro.Config.Filter.ShouldMetricPass(point) {
 			return
 		}
+		point = ro.Config.Filter.FilterMetric(point)
+		if point == nil {
+			return
+		}
 	}
 
-	if len(ro.metrics) < ro.PointBufferLimit {
-		ro.metrics = append(ro.metrics, point)
-	} else {
-		log.Printf("WARNING: overwriting cached metrics, you may want to " +
-			"increase the metric_buffer_limit setting in your [agent] config " +
-			"if you do not wish to overwrite metrics.\n")
-		if ro.overwriteCounter == len(ro.metrics) {
-			ro.overwriteCounter = 0
+	ro.metrics.Add(point)
+}
+
+// ShouldInputPass returns true if the output accepts the metrics of the
+// input, matching its name, or its name and alias as "name::alias", against
+// the globs of the output's inputs option. An output without inputs accepts
+// every metric, while metrics not coming from an input, ie those of the
+// aggregators, have a nil input and only go to such outputs.
+func (ro *RunningOutput) ShouldInputPass(input *InputConfig) bool {
+	if len(ro.Config.Inputs) == 0 {
+		return true
+	}
+	if input == nil {
+		return false
+	}
+	name := logName(input.Name, input.Alias)
+	for _, pat := range ro.Config.Inputs {
+		if internal.Glob(pat, input.Name) || internal.Glob(pat, name) {
+			return true
 		}
-		ro.metrics[ro.overwriteCounter] = point
-		ro.overwriteCounter++
 	}
+	return false
 }
 
+// Write writes all buffered metrics to the output, in batches of at most
+// MetricBatchSize metrics. On failure the unwritten metrics are kept in the
+// buffer, or spooled to the WAL, to be retried on the next call.
 func (ro *RunningOutput) Write() error {
+	if ro.WAL != nil {
+		if err := ro.replayWAL(); err != nil {
+			atomic.AddInt64(&ro.consecutiveErrors, 1)
+			ro.spool()
+			return err
+		}
+	}
+
+	dropped := ro.metrics.Dropped()
+	if dropped > 0 && ro.metrics.Len() == ro.metrics.Cap() {
+		log.Printf("WARNING: output %s buffer is full, %d metrics have been "+
+			"dropped so far, you may want to increase the metric_buffer_limit "+
+			"setting in your [agent] config if you do not wish to drop "+
+			"metrics.\n", ro.LogName(), dropped)
+	}
+
+	// Only write the metrics buffered when the flush started, metrics added
+	// meanwhile are written on the next flush. The output is written to even
+	// if the buffer is empty.
+	nBatches := (ro.metrics.Len() + ro.MetricBatchSize - 1) / ro.MetricBatchSize
+	if nBatches == 0 {
+		nBatches = 1
+	}
+	for i := 0; i < nBatches; i++ {
+		batch := ro.metrics.Batch(ro.MetricBatchSize)
+		if err := ro.writeBatch(batch); err != nil {
+			if ro.WAL != nil {
+				ro.spool()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBatch writes a batch of buffered metrics to the output, removing them
+// from the buffer on success.
+func (ro *RunningOutput) writeBatch(batch []telegraf.Metric) error {
+	ro.limitRate(batch)
+
 	start := time.Now()
-	err := ro.Output.Write(ro.metrics)
+	err := ro.Output.Write(batch)
 	elapsed := time.Since(start)
-	if err == nil {
-		if !ro.Quiet {
-			log.Printf("Wrote %d metrics to output %s in %s\n",
-				len(ro.metrics), ro.Name, elapsed)
+	atomic.StoreInt64(&ro.writeTime, int64(elapsed))
+
+	if err != nil {
+		atomic.AddInt64(&ro.writeErrors, 1)
+		atomic.AddInt64(&ro.consecutiveErrors, 1)
+		return err
+	}
+
+	ro.metrics.Remove(len(batch))
+	atomic.AddInt64(&ro.metricsWritten, int64(len(batch)))
+	atomic.StoreInt64(&ro.consecutiveErrors, 0)
+	if !ro.Quiet {
+		log.Printf("Wrote %d metrics to output %s in %s\n",
+			len(batch), ro.LogName(), elapsed)
+	}
+	return nil
+}
+
+// Close spools the metrics that could not be written to the WAL, if set, so
+// they are written after a restart.
+func (ro *RunningOutput) Close() {
+	if ro.WAL == nil {
+		return
+	}
+	ro.spool()
+	if err := ro.WAL.Close(); err != nil {
+		log.Printf("ERROR: closing WAL of output %s: %s\n", ro.LogName(), err)
+	}
+}
+
+// replayWAL writes the spooled metrics to the output, oldest segment first,
+// until the WAL is empty or a write fails.
+func (ro *RunningOutput) replayWAL() error {
+	for !ro.WAL.IsEmpty() {
+		metrics, err := ro.WAL.Oldest()
+		if err != nil {
+			log.Printf("ERROR: reading WAL of output %s, dropping segment: %s\n",
+				ro.LogName(), err)
+		}
+
+		if len(metrics) > 0 {
+			ro.limitRate(metrics)
+			if err := ro.Output.Write(metrics); err != nil {
+				atomic.AddInt64(&ro.writeErrors, 1)
+				return err
+			}
+			atomic.AddInt64(&ro.metricsWritten, int64(len(metrics)))
+			if !ro.Quiet {
+				log.Printf("Wrote %d metrics from WAL to output %s\n",
+					len(metrics), ro.LogName())
+			}
+		}
+
+		if err := ro.WAL.RemoveOldest(); err != nil {
+			log.Printf("ERROR: removing WAL segment of output %s: %s\n",
+				ro.LogName(), err)
+		}
+	}
+	return nil
+}
+
+// limitRate waits until the metrics can be written within the rate limits of
+// the output. The size in bytes of the metrics is the size of their line
+// protocol.
+func (ro *RunningOutput) limitRate(metrics []telegraf.Metric) {
+	var delay time.Duration
+	if ro.metricLimiter != nil {
+		delay += ro.metricLimiter.Wait(int64(len(metrics)))
+	}
+	if ro.byteLimiter != nil {
+		var size int64
+		for _, m := range metrics {
+			size += int64(len(m.String())) + 1
 		}
-		ro.metrics = make([]telegraf.Metric, 0)
-		ro.overwriteCounter = 0
+		delay += ro.byteLimiter.Wait(size)
+	}
+	if delay > 0 {
+		atomic.AddInt64(&ro.rateLimitDelay, int64(delay))
+	}
+}
+
+// spool moves the buffered metrics to the WAL
+func (ro *RunningOutput) spool() {
+	batch := ro.metrics.Batch(ro.metrics.Len())
+	if err := ro.WAL.Append(batch); err != nil {
+		log.Printf("ERROR: writing to WAL of output %s: %s\n", ro.LogName(), err)
+		return
 	}
-	return err
+	ro.metrics.Remove(len(batch))
 }
 
-// OutputConfig containing name and filter
+// BatchReady returns true if a full batch of metrics is waiting to be written
+func (ro *RunningOutput) BatchReady() bool {
+	return ro.metrics.Len() >= ro.MetricBatchSize
+}
+
+// BufferSize returns the number of metrics waiting to be written
+func (ro *RunningOutput) BufferSize() int {
+	return ro.metrics.Len()
+}
+
+// BufferLimit returns the maximum number of metrics in the buffer
+func (ro *RunningOutput) BufferLimit() int {
+	return ro.metrics.Cap()
+}
+
+// MetricsAdded returns the total number of metrics added to the buffer
+func (ro *RunningOutput) MetricsAdded() int64 {
+	return ro.metrics.Total()
+}
+
+// MetricsDropped returns the total number of metrics dropped because the
+// buffer was full
+func (ro *RunningOutput) MetricsDropped() int64 {
+	return ro.metrics.Dropped()
+}
+
+// MetricsWritten returns the total number of metrics successfully written
+func (ro *RunningOutput) MetricsWritten() int64 {
+	return atomic.LoadInt64(&ro.metricsWritten)
+}
+
+// WriteErrors returns the total number of failed writes
+func (ro *RunningOutput) WriteErrors() int64 {
+	return atomic.LoadInt64(&ro.writeErrors)
+}
+
+// ConsecutiveWriteErrors returns the number of failed writes since the last
+// successful write
+func (ro *RunningOutput) ConsecutiveWriteErrors() int64 {
+	return atomic.LoadInt64(&ro.consecutiveErrors)
+}
+
+// RateLimitDelay returns the total time writes were delayed by the rate limits
+func (ro *RunningOutput) RateLimitDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&ro.rateLimitDelay))
+}
+
+// WriteTime returns the duration of the last write
+func (ro *RunningOutput) WriteTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&ro.writeTime))
+}
+
+// OutputConfig containing name, filter, subscribed inputs, flush interval,
+// rate limits and the WAL settings
 type OutputConfig struct {
 	Name   string
+	Alias  string
+	ID     string
 	Filter Filter
+
+	// Inputs are the globs of the inputs whose metrics are sent to the
+	// output, matching either "name" or "name::alias", all if empty
+	Inputs []string
+
+	// FlushInterval is how often the output is written to, the agent's
+	// flush_interval if zero
+	FlushInterval time.Duration
+
+	// RateLimit and RateLimitBytes are the maximum number of metrics and of
+	// bytes written to the output per second, unlimited if zero
+	RateLimit      int64
+	RateLimitBytes int64
+
+	// WALDirectory enables the WAL of the output if set
+	WALDirectory   string
+	WALMaxSize     int64
+	WALSegmentSize int64
 }
@@ -0,0 +1,291 @@
+package internal_models
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOutput records the metrics written to it, and fails the writes while
+// failWrite is set
+type mockOutput struct {
+	metrics   []telegraf.Metric
+	failWrite bool
+}
+
+func (m *mockOutput) Connect() error       { return nil }
+func (m *mockOutput) Close() error         { return nil }
+func (m *mockOutput) Description() string  { return "" }
+func (m *mockOutput) SampleConfig() string { return "" }
+func (m *mockOutput) Write(metrics []telegraf.Metric) error {
+	if m.failWrite {
+		return errors.New("failed write")
+	}
+	m.metrics = append(m.metrics, metrics...)
+	return nil
+}
+
+func newTestMetric(t *testing.T, value int64) telegraf.Metric {
+	m, err := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": value}, time.Unix(value, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestRunningOutput_Write(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 0, 0)
+	ro.Quiet = true
+	assert.Equal(t, DEFAULT_METRIC_BUFFER_LIMIT, ro.BufferLimit())
+
+	ro.AddPoint(newTestMetric(t, 1))
+	ro.AddPoint(newTestMetric(t, 2))
+	require.NoError(t, ro.Write())
+
+	assert.Len(t, m.metrics, 2)
+	assert.Equal(t, 0, ro.BufferSize())
+	assert.Equal(t, int64(2), ro.MetricsWritten())
+}
+
+func TestRunningOutput_ShouldInputPass(t *testing.T) {
+	ro := NewRunningOutput("test", &mockOutput{}, &OutputConfig{}, 0, 0)
+	assert.True(t, ro.ShouldInputPass(&InputConfig{Name: "cpu"}))
+	assert.True(t, ro.ShouldInputPass(nil))
+
+	ro.Config.Inputs = []string{"cpu", "exec::business*"}
+	assert.True(t, ro.ShouldInputPass(&InputConfig{Name: "cpu"}))
+	assert.True(t, ro.ShouldInputPass(&InputConfig{Name: "cpu", Alias: "a"}))
+	assert.True(t, ro.ShouldInputPass(
+		&InputConfig{Name: "exec", Alias: "business_sales"}))
+	assert.False(t, ro.ShouldInputPass(&InputConfig{Name: "exec"}))
+	assert.False(t, ro.ShouldInputPass(&InputConfig{Name: "mem"}))
+	// Metrics of the aggregators come from no input
+	assert.False(t, ro.ShouldInputPass(nil))
+}
+
+// Test that metrics are retried after a failed write
+func TestRunningOutput_WriteFailRetry(t *testing.T) {
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 0, 10)
+	ro.Quiet = true
+
+	ro.AddPoint(newTestMetric(t, 1))
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 1, ro.BufferSize())
+	assert.Equal(t, int64(1), ro.WriteErrors())
+
+	ro.AddPoint(newTestMetric(t, 2))
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+
+	require.Len(t, m.metrics, 2)
+	assert.Equal(t, int64(1), m.metrics[0].Fields()["value"])
+	assert.Equal(t, int64(2), m.metrics[1].Fields()["value"])
+	assert.Equal(t, 0, ro.BufferSize())
+}
+
+// Test that the oldest metrics are dropped when the buffer overflows
+func TestRunningOutput_BufferOverflow(t *testing.T) {
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 0, 3)
+	ro.Quiet = true
+
+	for i := int64(1); i <= 5; i++ {
+		ro.AddPoint(newTestMetric(t, i))
+	}
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 3, ro.BufferSize())
+	assert.Equal(t, int64(2), ro.MetricsDropped())
+	assert.Equal(t, int64(5), ro.MetricsAdded())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.metrics, 3)
+	assert.Equal(t, int64(3), m.metrics[0].Fields()["value"])
+	assert.Equal(t, int64(5), m.metrics[2].Fields()["value"])
+}
+
+func TestRunningOutput_Filter(t *testing.T) {
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{
+		Filter: Filter{
+			Pass:     []string{"mem"},
+			IsActive: true,
+		},
+	}, 0, 0)
+	ro.Quiet = true
+
+	ro.AddPoint(newTestMetric(t, 1))
+	assert.Equal(t, 0, ro.BufferSize())
+}
+
+// Test that failed writes are spooled to the WAL, and written first once the
+// output recovers
+func TestRunningOutput_WAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wal, err := buffer.OpenWAL(dir, DEFAULT_WAL_MAX_SIZE, DEFAULT_WAL_SEGMENT_SIZE)
+	require.NoError(t, err)
+
+	m := &mockOutput{failWrite: true}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 0, 10)
+	ro.Quiet = true
+	ro.WAL = wal
+
+	ro.AddPoint(newTestMetric(t, 1))
+	assert.Error(t, ro.Write())
+	assert.Equal(t, 0, ro.BufferSize())
+	assert.False(t, wal.IsEmpty())
+
+	// Unwritten metrics are spooled when closing, and replayed by a new
+	// running output using the same directory
+	ro.AddPoint(newTestMetric(t, 2))
+	ro.Close()
+
+	wal, err = buffer.OpenWAL(dir, DEFAULT_WAL_MAX_SIZE, DEFAULT_WAL_SEGMENT_SIZE)
+	require.NoError(t, err)
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, &OutputConfig{}, 0, 10)
+	ro.Quiet = true
+	ro.WAL = wal
+
+	ro.AddPoint(newTestMetric(t, 3))
+	require.NoError(t, ro.Write())
+	require.Len(t, m.metrics, 3)
+	for i, metric := range m.metrics {
+		assert.Equal(t, int64(i+1), metric.Fields()["value"])
+	}
+	assert.True(t, wal.IsEmpty())
+}
+
+// countingOutput records the size of every batch written to it
+type countingOutput struct {
+	mockOutput
+	batches []int
+}
+
+func (c *countingOutput) Write(metrics []telegraf.Metric) error {
+	c.batches = append(c.batches, len(metrics))
+	return c.mockOutput.Write(metrics)
+}
+
+func TestRunningOutput_WriteBatches(t *testing.T) {
+	m := &countingOutput{}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 2, 10)
+	ro.Quiet = true
+
+	for i := int64(1); i <= 5; i++ {
+		ro.AddPoint(newTestMetric(t, i))
+	}
+	require.NoError(t, ro.Write())
+
+	assert.Equal(t, []int{2, 2, 1}, m.batches)
+	assert.Len(t, m.metrics, 5)
+	assert.Equal(t, 0, ro.BufferSize())
+}
+
+// slowOutput signals when a write started, and blocks it until released
+type slowOutput struct {
+	mockOutput
+	started  chan bool
+	released chan bool
+}
+
+func (s *slowOutput) Write(metrics []telegraf.Metric) error {
+	s.started <- true
+	<-s.released
+	return s.mockOutput.Write(metrics)
+}
+
+// Test that metrics added to a full buffer during a write are not removed
+// with the written batch
+func TestRunningOutput_AddPointDuringWrite(t *testing.T) {
+	m := &slowOutput{started: make(chan bool), released: make(chan bool)}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 0, 4)
+	ro.Quiet = true
+
+	for i := int64(1); i <= 4; i++ {
+		ro.AddPoint(newTestMetric(t, i))
+	}
+	done := make(chan error)
+	go func() {
+		done <- ro.Write()
+	}()
+
+	// 5 and 6 overwrite 1 and 2 while the batch is written
+	<-m.started
+	ro.AddPoint(newTestMetric(t, 5))
+	ro.AddPoint(newTestMetric(t, 6))
+	close(m.released)
+	require.NoError(t, <-done)
+	assert.Equal(t, int64(2), ro.MetricsDropped())
+
+	require.Len(t, m.metrics, 4)
+	assert.Equal(t, 2, ro.BufferSize())
+	go func() {
+		<-m.started
+	}()
+	require.NoError(t, ro.Write())
+	require.Len(t, m.metrics, 6)
+	assert.Equal(t, int64(5), m.metrics[4].Fields()["value"])
+	assert.Equal(t, int64(6), m.metrics[5].Fields()["value"])
+	assert.Equal(t, 0, ro.BufferSize())
+}
+
+// Test that a failed batch stops the write, keeping the unwritten metrics
+func TestRunningOutput_WriteBatchFail(t *testing.T) {
+	m := &countingOutput{mockOutput: mockOutput{failWrite: true}}
+	ro := NewRunningOutput("test", m, &OutputConfig{}, 2, 10)
+	ro.Quiet = true
+
+	for i := int64(1); i <= 5; i++ {
+		ro.AddPoint(newTestMetric(t, i))
+	}
+	assert.Error(t, ro.Write())
+	assert.Equal(t, []int{2}, m.batches)
+	assert.Equal(t, 5, ro.BufferSize())
+}
+
+// Test that writes are delayed to keep under the rate limit
+func TestRunningOutput_RateLimit(t *testing.T) {
+	m := &mockOutput{}
+	conf := &OutputConfig{RateLimit: 100}
+	ro := NewRunningOutput("test", m, conf, 100, 0)
+	ro.Quiet = true
+
+	for i := int64(0); i < 110; i++ {
+		ro.AddPoint(newTestMetric(t, i))
+	}
+	start := time.Now()
+	require.NoError(t, ro.Write())
+
+	// The first batch is within the burst, the next 10 metrics wait 100ms
+	assert.Len(t, m.metrics, 110)
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+	assert.True(t, ro.RateLimitDelay() >= 90*time.Millisecond)
+}
+
+func TestRunningOutput_RateLimitBytes(t *testing.T) {
+	m := &mockOutput{}
+	metric := newTestMetric(t, 1)
+	size := int64(len(metric.String()) + 1)
+	conf := &OutputConfig{RateLimitBytes: 10 * size}
+	ro := NewRunningOutput("test", m, conf, 0, 0)
+	ro.Quiet = true
+
+	for i := 0; i < 11; i++ {
+		ro.AddPoint(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.metrics, 11)
+	assert.True(t, ro.RateLimitDelay() >= 90*time.Millisecond)
+}
@@ -0,0 +1,43 @@
+package internal_models
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+type RunningProcessor struct {
+	Name      string
+	Processor telegraf.Processor
+	Config    *ProcessorConfig
+}
+
+// ProcessorConfig containing a name, order and filter
+type ProcessorConfig struct {
+	Name   string
+	Alias  string
+	ID     string
+	Order  int64
+	Filter Filter
+}
+
+// LogName returns the name of the processor, with its alias if set
+func (rp *RunningProcessor) LogName() string {
+	return logName(rp.Name, rp.Config.Alias)
+}
+
+// Apply runs the processor over the given metrics. Metrics that do not
+// match the processor's filter are passed through untouched.
+func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	ret := []telegraf.Metric{}
+
+	for _, metric := range in {
+		if rp.Config.Filter.IsActive {
+			if !rp.Config.Filter.ShouldMetricPass(metric) {
+				ret = append(ret, metric)
+				continue
+			}
+		}
+		ret = append(ret, rp.Processor.Apply(metric)...)
+	}
+
+	return ret
+}
@@ -0,0 +1,67 @@
+package internal_models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// renameProcessor prefixes the name of every metric it is applied to
+type renameProcessor struct{}
+
+func (p *renameProcessor) SampleConfig() string { return "" }
+func (p *renameProcessor) Description() string  { return "" }
+func (p *renameProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		renamed, _ := telegraf.NewMetric("renamed_"+m.Name(), m.Tags(),
+			m.Fields(), m.Time())
+		out = append(out, renamed)
+	}
+	return out
+}
+
+func TestRunningProcessor_Apply(t *testing.T) {
+	rp := &RunningProcessor{
+		Name:      "rename",
+		Processor: &renameProcessor{},
+		Config:    &ProcessorConfig{Name: "rename"},
+	}
+
+	m, err := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+
+	out := rp.Apply(m)
+	require.Len(t, out, 1)
+	assert.Equal(t, "renamed_cpu", out[0].Name())
+}
+
+func TestRunningProcessor_ApplyFiltered(t *testing.T) {
+	rp := &RunningProcessor{
+		Name:      "rename",
+		Processor: &renameProcessor{},
+		Config: &ProcessorConfig{
+			Name: "rename",
+			Filter: Filter{
+				Pass:     []string{"cpu"},
+				IsActive: true,
+			},
+		},
+	}
+
+	cpu, err := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+	mem, err := telegraf.NewMetric("mem", nil,
+		map[string]interface{}{"value": 1}, time.Now())
+	require.NoError(t, err)
+
+	out := rp.Apply(cpu, mem)
+	require.Len(t, out, 2)
+	assert.Equal(t, "renamed_cpu", out[0].Name())
+	assert.Equal(t, "mem", out[1].Name())
+}
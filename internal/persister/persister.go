@@ -0,0 +1,122 @@
+// Package persister stores the state of stateful plugins in a file, so that
+// it can be restored when telegraf restarts.
+package persister
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Persister loads and stores the state of the plugins registered to it in a
+// JSON file, keyed by the ID of every plugin.
+type Persister struct {
+	Filename string
+
+	plugins map[string]telegraf.StatefulPlugin
+}
+
+// NewPersister returns a Persister storing the states in the given file
+func NewPersister(filename string) *Persister {
+	return &Persister{
+		Filename: filename,
+		plugins:  make(map[string]telegraf.StatefulPlugin),
+	}
+}
+
+// Register adds a plugin whose state is persisted under the given ID, which
+// must be unique and stable across restarts.
+func (p *Persister) Register(id string, plugin telegraf.StatefulPlugin) error {
+	if _, ok := p.plugins[id]; ok {
+		return fmt.Errorf("duplicate plugin ID %s", id)
+	}
+	p.plugins[id] = plugin
+	return nil
+}
+
+// Load restores the state of the registered plugins from the file. It is not
+// an error for the file not to exist, nor for a plugin to have no stored
+// state, ie when it was added since the last stop.
+func (p *Persister) Load() error {
+	data, err := ioutil.ReadFile(p.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(data, &states); err != nil {
+		return fmt.Errorf("decoding state file %s: %s", p.Filename, err)
+	}
+
+	for id, plugin := range p.plugins {
+		raw, ok := states[id]
+		if !ok {
+			continue
+		}
+		state, err := decodeState(raw, plugin.GetState())
+		if err != nil {
+			return fmt.Errorf("decoding state of plugin %s: %s", id, err)
+		}
+		if err := plugin.SetState(state); err != nil {
+			return fmt.Errorf("restoring state of plugin %s: %s", id, err)
+		}
+	}
+	return nil
+}
+
+// Store writes the state of the registered plugins to the file, replacing it
+// atomically so that a crash does not leave a partial file behind.
+func (p *Persister) Store() error {
+	states := make(map[string]interface{}, len(p.plugins))
+	for id, plugin := range p.plugins {
+		states[id] = plugin.GetState()
+	}
+	data, err := json.Marshal(states)
+	if err != nil {
+		return fmt.Errorf("encoding states: %s", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p.Filename),
+		filepath.Base(p.Filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p.Filename)
+}
+
+// decodeState decodes the stored state into a new value of the type of the
+// plugin's current state.
+func decodeState(raw json.RawMessage, current interface{}) (interface{}, error) {
+	if current == nil {
+		var state interface{}
+		err := json.Unmarshal(raw, &state)
+		return state, err
+	}
+
+	t := reflect.TypeOf(current)
+	if t.Kind() == reflect.Ptr {
+		state := reflect.New(t.Elem())
+		err := json.Unmarshal(raw, state.Interface())
+		return state.Interface(), err
+	}
+	state := reflect.New(t)
+	err := json.Unmarshal(raw, state.Interface())
+	return state.Elem().Interface(), err
+}
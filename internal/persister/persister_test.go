@@ -0,0 +1,103 @@
+package persister
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type offsets struct {
+	Offsets map[string]int64
+}
+
+type offsetPlugin struct {
+	state offsets
+}
+
+func (p *offsetPlugin) GetState() interface{} {
+	return p.state
+}
+
+func (p *offsetPlugin) SetState(state interface{}) error {
+	p.state = state.(offsets)
+	return nil
+}
+
+type timestampPlugin struct {
+	last *int64
+}
+
+func (p *timestampPlugin) GetState() interface{} {
+	return p.last
+}
+
+func (p *timestampPlugin) SetState(state interface{}) error {
+	p.last = state.(*int64)
+	return nil
+}
+
+func tempStatefile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "persister")
+	require.NoError(t, err)
+	return filepath.Join(dir, "state.json"), func() { os.RemoveAll(dir) }
+}
+
+func TestPersister_StoreLoad(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	last := int64(1454000000)
+	p := NewPersister(filename)
+	require.NoError(t, p.Register("tail", &offsetPlugin{
+		state: offsets{Offsets: map[string]int64{"/var/log/syslog": 42}},
+	}))
+	require.NoError(t, p.Register("api", &timestampPlugin{last: &last}))
+	require.NoError(t, p.Store())
+
+	// A restarted telegraf restores the state into new plugin instances
+	tail := &offsetPlugin{}
+	api := &timestampPlugin{last: new(int64)}
+	p = NewPersister(filename)
+	require.NoError(t, p.Register("tail", tail))
+	require.NoError(t, p.Register("api", api))
+	require.NoError(t, p.Load())
+
+	assert.Equal(t, map[string]int64{"/var/log/syslog": 42}, tail.state.Offsets)
+	assert.Equal(t, last, *api.last)
+}
+
+func TestPersister_LoadMissing(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	// Neither a missing file nor a plugin without stored state are errors
+	p := NewPersister(filename)
+	require.NoError(t, p.Register("tail", &offsetPlugin{}))
+	require.NoError(t, p.Load())
+	require.NoError(t, p.Store())
+
+	tail := &offsetPlugin{}
+	p = NewPersister(filename)
+	require.NoError(t, p.Register("other", tail))
+	require.NoError(t, p.Load())
+	assert.Nil(t, tail.state.Offsets)
+}
+
+func TestPersister_LoadInvalid(t *testing.T) {
+	filename, cleanup := tempStatefile(t)
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("{"), 0644))
+	p := NewPersister(filename)
+	assert.Error(t, p.Load())
+}
+
+func TestPersister_RegisterDuplicate(t *testing.T) {
+	p := NewPersister("state.json")
+	require.NoError(t, p.Register("tail", &offsetPlugin{}))
+	assert.Error(t, p.Register("tail", &offsetPlugin{}))
+}
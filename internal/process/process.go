@@ -0,0 +1,186 @@
+package process
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// ErrNotRunning is returned when writing to a program which has exited and
+// is waiting to be restarted.
+var ErrNotRunning = errors.New("process is not running")
+
+// Process runs an external program as a daemon, restarting it after
+// RestartDelay if it exits before Stop is called. Anything the program writes
+// to stderr is logged as an error.
+type Process struct {
+	Command      []string
+	RestartDelay time.Duration
+	// ReadStdout is called with the stdout of every started instance of the
+	// program and should return once it is closed.
+	ReadStdout func(io.Reader)
+	Log        telegraf.Logger
+
+	sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a Process running the given command, the first element being
+// the executable and the remaining elements its arguments.
+func New(command []string, log telegraf.Logger) (*Process, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no command specified")
+	}
+	return &Process{
+		Command:      command,
+		RestartDelay: 10 * time.Second,
+		ReadStdout:   func(r io.Reader) { io.Copy(ioutil.Discard, r) },
+		Log:          log,
+	}, nil
+}
+
+// Start launches the program and returns once it is running.
+func (p *Process) Start() error {
+	p.done = make(chan struct{})
+
+	stdout, stderr, err := p.startProcess()
+	if err != nil {
+		return err
+	}
+
+	p.wg.Add(1)
+	go p.run(stdout, stderr)
+	return nil
+}
+
+// Stop closes the program's stdin and waits for it to exit, killing it if it
+// does not exit within 5 seconds.
+func (p *Process) Stop() {
+	close(p.done)
+
+	p.Lock()
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+	cmd := p.cmd
+	p.Unlock()
+
+	stopped := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		if cmd != nil && cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		<-stopped
+	}
+}
+
+// Write writes to the stdin of the program, returning ErrNotRunning if it is
+// being restarted.
+func (p *Process) Write(b []byte) (int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.stdin == nil {
+		return 0, ErrNotRunning
+	}
+	return p.stdin.Write(b)
+}
+
+func (p *Process) startProcess() (io.Reader, io.Reader, error) {
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening stdin pipe: %s", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening stdout pipe: %s", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening stderr pipe: %s", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("error starting %s: %s", p.Command[0], err)
+	}
+
+	p.Lock()
+	p.cmd = cmd
+	p.stdin = stdin
+	p.Unlock()
+
+	return stdout, stderr, nil
+}
+
+// run reads from the running program until it exits, then restarts it after
+// RestartDelay unless the process is being stopped.
+func (p *Process) run(stdout, stderr io.Reader) {
+	defer p.wg.Done()
+
+	for {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.logStderr(stderr)
+		}()
+		p.ReadStdout(stdout)
+		wg.Wait()
+
+		p.Lock()
+		cmd := p.cmd
+		p.stdin = nil
+		p.Unlock()
+		err := cmd.Wait()
+
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		p.Log.Errorf("%s exited (%v), restarting in %s",
+			p.Command[0], err, p.RestartDelay)
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-time.After(p.RestartDelay):
+			}
+
+			stdout, stderr, err = p.startProcess()
+			if err == nil {
+				break
+			}
+			p.Log.Error(err)
+		}
+	}
+}
+
+func (p *Process) logStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.Log.Errorf("%s: %s", p.Command[0], scanner.Text())
+	}
+}
@@ -0,0 +1,70 @@
+package process
+
+import (
+	"bufio"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessNoCommand(t *testing.T) {
+	_, err := New(nil, testutil.Logger{})
+	assert.Error(t, err)
+}
+
+func TestProcessWrite(t *testing.T) {
+	p, err := New([]string{"cat"}, testutil.Logger{})
+	require.NoError(t, err)
+
+	lines := make(chan string, 1)
+	p.ReadStdout = func(r io.Reader) {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	_, err = io.WriteString(p, "hello\n")
+	require.NoError(t, err)
+	select {
+	case line := <-lines:
+		assert.Equal(t, "hello", line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for output")
+	}
+}
+
+func TestProcessRestart(t *testing.T) {
+	// The program exits right away, so it is restarted over and over
+	p, err := New([]string{"echo", "started"}, testutil.Logger{})
+	require.NoError(t, err)
+	p.RestartDelay = 10 * time.Millisecond
+
+	starts := make(chan string, 10)
+	p.ReadStdout = func(r io.Reader) {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case starts <- scanner.Text():
+			default:
+			}
+		}
+	}
+	require.NoError(t, p.Start())
+	defer p.Stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-starts:
+			assert.Equal(t, "started", line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for start %d", i+1)
+		}
+	}
+}
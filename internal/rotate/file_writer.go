@@ -0,0 +1,150 @@
+package rotate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileWriter is a thread-safe io.WriteCloser writing to a file, which is
+// rotated once it is older than a given interval or larger than a given
+// size. The rotated files are archived next to it as
+// <name>.<unix nano timestamp><ext>, keeping at most a given number of them.
+type FileWriter struct {
+	sync.Mutex
+
+	filename    string
+	interval    time.Duration
+	maxSize     int64
+	maxArchives int
+
+	current      *os.File
+	expireTime   time.Time
+	bytesWritten int64
+}
+
+// NewFileWriter opens filename for appending. interval and maxSize disable
+// rotation by age and size if they are zero. maxArchives is the number of
+// rotated files to keep, -1 keeps all of them.
+func NewFileWriter(
+	filename string,
+	interval time.Duration,
+	maxSize int64,
+	maxArchives int,
+) (io.WriteCloser, error) {
+	w := &FileWriter{
+		filename:    filename,
+		interval:    interval,
+		maxSize:     maxSize,
+		maxArchives: maxArchives,
+	}
+	if err := w.openCurrent(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes p to the current file, rotating it first if it is due
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.rotationDue(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.current.Write(p)
+	w.bytesWritten += int64(n)
+	return n, err
+}
+
+// Close closes the current file
+func (w *FileWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.current.Close()
+}
+
+func (w *FileWriter) rotationDue(n int) bool {
+	if w.interval > 0 && time.Now().After(w.expireTime) {
+		return true
+	}
+	// Never rotate an empty file, so that writes larger than maxSize are
+	// written at all
+	return w.maxSize > 0 && w.bytesWritten > 0 &&
+		w.bytesWritten+int64(n) > w.maxSize
+}
+
+func (w *FileWriter) openCurrent() error {
+	f, err := os.OpenFile(w.filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.current = f
+	w.bytesWritten = stat.Size()
+	w.expireTime = time.Now().Add(w.interval)
+	return nil
+}
+
+func (w *FileWriter) rotate() error {
+	if err := w.current.Close(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(w.filename)
+	stem := strings.TrimSuffix(w.filename, ext)
+	archive := fmt.Sprintf("%s.%d%s", stem, time.Now().UnixNano(), ext)
+	if err := os.Rename(w.filename, archive); err != nil {
+		return err
+	}
+
+	if err := w.openCurrent(); err != nil {
+		return err
+	}
+	return w.purgeArchives(stem, ext)
+}
+
+// purgeArchives removes the oldest archives beyond maxArchives
+func (w *FileWriter) purgeArchives(stem, ext string) error {
+	if w.maxArchives < 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(stem + ".*" + ext)
+	if err != nil {
+		return err
+	}
+	var archives []string
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, stem+"."), ext)
+		if ts != "" && strings.Trim(ts, "0123456789") == "" {
+			archives = append(archives, m)
+		}
+	}
+	if len(archives) <= w.maxArchives {
+		return nil
+	}
+
+	// The timestamps have the same number of digits, so sorting the names
+	// sorts the archives from oldest to newest.
+	sort.Strings(archives)
+	for _, archive := range archives[:len(archives)-w.maxArchives] {
+		if err := os.Remove(archive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
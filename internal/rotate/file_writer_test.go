@@ -0,0 +1,119 @@
+package rotate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func archives(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "telegraf.*.log"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestFileWriter_NoRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewFileWriter(filepath.Join(dir, "telegraf.log"), 0, 0, -1)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("Hello World"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("Hello World 2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, len(archives(t, dir)))
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "telegraf.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello WorldHello World 2", string(contents))
+}
+
+func TestFileWriter_RotateByTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewFileWriter(filepath.Join(dir, "telegraf.log"),
+		10*time.Millisecond, 0, -1)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("Hello World"))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = w.Write([]byte("Hello World 2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, len(archives(t, dir)))
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "telegraf.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World 2", string(contents))
+}
+
+func TestFileWriter_RotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewFileWriter(filepath.Join(dir, "telegraf.log"), 0, 20, -1)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("Hello World"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("Hello World 2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, len(archives(t, dir)))
+}
+
+func TestFileWriter_RotateBySizeOfExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "telegraf.log")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("Hello World"), 0644))
+
+	w, err := NewFileWriter(filename, 0, 20, -1)
+	require.NoError(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("Hello World 2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, len(archives(t, dir)))
+}
+
+func TestFileWriter_MaxArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := NewFileWriter(filepath.Join(dir, "telegraf.log"), 0, 5, 2)
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, s := range []string{"First", "Second", "Third", "Fourth"} {
+		_, err = w.Write([]byte(s))
+		require.NoError(t, err)
+	}
+
+	files := archives(t, dir)
+	require.Equal(t, 2, len(files))
+	contents, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Second", string(contents))
+	contents, err = ioutil.ReadFile(files[1])
+	require.NoError(t, err)
+	assert.Equal(t, "Third", string(contents))
+}
@@ -0,0 +1,431 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/pkcs12"
+)
+
+// TLSOptions are the TLS options of the plugins connecting to a server:
+// ssl_ca, ssl_cert, ssl_key and insecure_skip_verify, ssl_pkcs12, a PKCS#12
+// bundle of the client certificate and key replacing ssl_cert and ssl_key,
+// ssl_key_password, the password of an encrypted ssl_key or of the bundle,
+// tls_server_name, the name verified against the certificate of the server
+// instead of the host connected to, and the tls_min_version, tls_max_version
+// and tls_cipher_suites restricting the TLS versions and cipher suites
+// negotiated with it.
+type TLSOptions struct {
+	SSLCA              string
+	SSLCert            string
+	SSLKey             string
+	SSLPKCS12          string
+	SSLKeyPassword     string
+	InsecureSkipVerify bool
+	ServerName         string
+
+	MinVersion   string
+	MaxVersion   string
+	CipherSuites []string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+}
+
+var tlsCipherSuites = map[string]uint16{
+	"TLS_RSA_WITH_RC4_128_SHA":                tls.TLS_RSA_WITH_RC4_128_SHA,
+	"TLS_RSA_WITH_3DES_EDE_CBC_SHA":           tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_RC4_128_SHA":        tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":    tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_RC4_128_SHA":          tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+	"TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA":     tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":      tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":   tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":   tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+}
+
+// GetTLSConfig returns the TLS config of a client with the given options. It
+// returns nil if no option is set, in which case the plugin should connect
+// without TLS.
+func GetTLSConfig(o TLSOptions) (*tls.Config, error) {
+	if o.SSLCA == "" && o.SSLCert == "" && o.SSLKey == "" &&
+		o.SSLPKCS12 == "" && !o.InsecureSkipVerify && o.ServerName == "" && o.MinVersion == "" &&
+		o.MaxVersion == "" && len(o.CipherSuites) == 0 {
+		return nil, nil
+	}
+
+	t := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		ServerName:         o.ServerName,
+	}
+
+	if o.SSLCA != "" {
+		ca, err := ioutil.ReadFile(o.SSLCA)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA %s: %s", o.SSLCA, err)
+		}
+		t.RootCAs = x509.NewCertPool()
+		if !t.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in CA %s", o.SSLCA)
+		}
+	}
+
+	if o.SSLCert != "" || o.SSLKey != "" || o.SSLPKCS12 != "" {
+		cert, err := loadCertificate("ssl", o.SSLCert, o.SSLKey, o.SSLPKCS12,
+			o.SSLKeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		t.Certificates = []tls.Certificate{cert}
+	}
+
+	if err := restrictTLS(t, o.MinVersion, o.MaxVersion,
+		o.CipherSuites); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ServerTLSOptions are the TLS options of the plugins listening for
+// connections: tls_cert and tls_key, the certificate served to the clients,
+// or tls_pkcs12, a PKCS#12 bundle of both, tls_key_password, the password of
+// an encrypted tls_key or of the bundle, tls_allowed_cacerts, the CAs of the
+// client certificates, tls_reload_interval, the period at which the
+// certificate is reloaded if its files were modified, and the
+// tls_min_version, tls_max_version and tls_cipher_suites.
+type ServerTLSOptions struct {
+	TLSCert        string
+	TLSKey         string
+	TLSPKCS12      string
+	TLSKeyPassword string
+	AllowedCACerts []string
+	ReloadInterval time.Duration
+
+	MinVersion   string
+	MaxVersion   string
+	CipherSuites []string
+}
+
+// GetServerTLSConfig returns the TLS config of a server with the given
+// options, requiring the clients to present a certificate signed by one of
+// the allowed CAs if any is set. It returns nil if neither the certificate,
+// the key nor the bundle is set, in which case the plugin should listen
+// without TLS. If the reload interval is set, the certificate served is
+// reloaded once its files are modified, so that short-lived certificates are
+// rotated without restarting the listener.
+func GetServerTLSConfig(o ServerTLSOptions) (*tls.Config, error) {
+	if o.TLSCert == "" && o.TLSKey == "" && o.TLSPKCS12 == "" {
+		if len(o.AllowedCACerts) > 0 {
+			return nil, fmt.Errorf("tls_allowed_cacerts requires tls_cert " +
+				"and tls_key, or tls_pkcs12")
+		}
+		return nil, nil
+	}
+
+	cert, err := loadCertificate("tls", o.TLSCert, o.TLSKey, o.TLSPKCS12,
+		o.TLSKeyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("could not load server certificate: %s", err)
+	}
+	t := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if o.ReloadInterval > 0 {
+		r := &certificateReloader{
+			cert: &cert,
+			load: func() (tls.Certificate, error) {
+				return loadCertificate("tls", o.TLSCert, o.TLSKey, o.TLSPKCS12,
+					o.TLSKeyPassword)
+			},
+			watcher: NewFileWatcher(o.ReloadInterval, o.TLSCert, o.TLSKey,
+				o.TLSPKCS12),
+		}
+		// GetCertificate is only called without certificates
+		t.Certificates = nil
+		t.GetCertificate = r.GetCertificate
+	}
+
+	if len(o.AllowedCACerts) > 0 {
+		t.ClientCAs = x509.NewCertPool()
+		for _, file := range o.AllowedCACerts {
+			ca, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("could not read CA %s: %s", file, err)
+			}
+			if !t.ClientCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificate found in CA %s", file)
+			}
+		}
+		t.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if err := restrictTLS(t, o.MinVersion, o.MaxVersion,
+		o.CipherSuites); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// FileWatcher reports the modifications of files, ie to reload rotated
+// certificates. The modification times of the files are checked at most once
+// per interval.
+type FileWatcher struct {
+	files    []string
+	interval time.Duration
+
+	sync.Mutex
+	modTimes []time.Time
+	checked  time.Time
+}
+
+// NewFileWatcher returns a watcher of the given files, the empty names being
+// ignored, reporting the modifications made from now on.
+func NewFileWatcher(interval time.Duration, files ...string) *FileWatcher {
+	w := &FileWatcher{interval: interval, checked: time.Now()}
+	for _, file := range files {
+		if file != "" {
+			w.files = append(w.files, file)
+		}
+	}
+	w.modTimes = w.stat()
+	return w
+}
+
+// Changed returns true if any file was modified, created or removed since the
+// last modification reported, if the interval elapsed since the last check.
+func (w *FileWatcher) Changed() bool {
+	w.Lock()
+	defer w.Unlock()
+	if time.Since(w.checked) < w.interval {
+		return false
+	}
+	w.checked = time.Now()
+
+	modTimes := w.stat()
+	changed := false
+	for i := range modTimes {
+		if !modTimes[i].Equal(w.modTimes[i]) {
+			changed = true
+		}
+	}
+	w.modTimes = modTimes
+	return changed
+}
+
+// stat returns the modification times of the files, zero if missing
+func (w *FileWatcher) stat() []time.Time {
+	modTimes := make([]time.Time, len(w.files))
+	for i, file := range w.files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// certificateReloader serves a certificate, reloading it when its files are
+// modified. The last certificate loaded is kept if the new one cannot be
+// loaded, ie while only the certificate or the key was replaced, and loading
+// it is retried once per interval.
+type certificateReloader struct {
+	load    func() (tls.Certificate, error)
+	watcher *FileWatcher
+
+	sync.Mutex
+	cert   *tls.Certificate
+	failed bool
+	retry  time.Time
+}
+
+func (r *certificateReloader) GetCertificate(
+	*tls.ClientHelloInfo,
+) (*tls.Certificate, error) {
+	r.Lock()
+	defer r.Unlock()
+	changed := r.watcher.Changed()
+	if changed || (r.failed && time.Now().After(r.retry)) {
+		cert, err := r.load()
+		if err != nil {
+			if !r.failed {
+				log.Printf("WARNING: could not reload server certificate, "+
+					"keeping the current one: %s\n", err)
+			}
+			r.failed = true
+			r.retry = time.Now().Add(r.watcher.interval)
+		} else {
+			r.cert = &cert
+			r.failed = false
+		}
+	}
+	return r.cert, nil
+}
+
+// loadCertificate loads a certificate and its key, either from the PEM files
+// certFile and keyFile, decrypting the key with password if it is encrypted,
+// or from the PKCS#12 bundle pkcs12File, decrypted with password. prefix is
+// the prefix of the options, "ssl" or "tls", used in the errors.
+func loadCertificate(
+	prefix string,
+	certFile string,
+	keyFile string,
+	pkcs12File string,
+	password string,
+) (tls.Certificate, error) {
+	if pkcs12File != "" {
+		if certFile != "" || keyFile != "" {
+			return tls.Certificate{}, fmt.Errorf("%s_pkcs12 cannot be set "+
+				"with %s_cert and %s_key", prefix, prefix, prefix)
+		}
+		return loadPKCS12(pkcs12File, password)
+	}
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, fmt.Errorf("%s_cert and %s_key must be set "+
+			"together", prefix, prefix)
+	}
+
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	switch {
+	case block == nil:
+		return tls.Certificate{}, fmt.Errorf("no PEM key found in %s", keyFile)
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		// Encrypted PKCS#8 keys, the default of openssl since 1.1, are not
+		// supported by crypto/x509
+		return tls.Certificate{}, fmt.Errorf("%s is an encrypted PKCS#8 key, "+
+			"which is not supported: convert it to a PKCS#12 bundle, or to a "+
+			"traditional encrypted PEM key with openssl's -traditional option",
+			keyFile)
+	case x509.IsEncryptedPEMBlock(block):
+		if password == "" {
+			return tls.Certificate{}, fmt.Errorf("%s is encrypted but "+
+				"%s_key_password is not set", keyFile, prefix)
+		}
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not decrypt %s: %s",
+				keyFile, err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s: %s", certFile, err)
+	}
+	return cert, nil
+}
+
+// loadPKCS12 loads the certificate, its chain and its key from a PKCS#12
+// bundle
+func loadPKCS12(file string, password string) (tls.Certificate, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not decode %s: %s", file,
+			err)
+	}
+
+	var key *pem.Block
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			key = block
+		}
+	}
+	if key == nil {
+		return tls.Certificate{}, fmt.Errorf("no key found in %s", file)
+	}
+
+	// The certificate of the key, sharing its local key id, must come first,
+	// followed by the rest of the chain
+	var leaf, chain []byte
+	for _, block := range blocks {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		id := block.Headers["localKeyId"]
+		block.Headers = nil
+		if id != "" && id == key.Headers["localKeyId"] {
+			leaf = append(leaf, pem.EncodeToMemory(block)...)
+		} else {
+			chain = append(chain, pem.EncodeToMemory(block)...)
+		}
+	}
+	key.Headers = nil
+
+	cert, err := tls.X509KeyPair(bytes.Join([][]byte{leaf, chain}, nil),
+		pem.EncodeToMemory(key))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s: %s", file, err)
+	}
+	return cert, nil
+}
+
+// restrictTLS sets the TLS versions and cipher suites allowed by t
+func restrictTLS(
+	t *tls.Config,
+	minVersion string,
+	maxVersion string,
+	cipherSuites []string,
+) error {
+	var err error
+	if t.MinVersion, err = parseTLSVersion(minVersion); err != nil {
+		return err
+	}
+	if t.MaxVersion, err = parseTLSVersion(maxVersion); err != nil {
+		return err
+	}
+	if t.MinVersion != 0 && t.MaxVersion != 0 && t.MinVersion > t.MaxVersion {
+		return fmt.Errorf("tls_min_version %s is greater than "+
+			"tls_max_version %s", minVersion, maxVersion)
+	}
+
+	for _, name := range cipherSuites {
+		suite, ok := tlsCipherSuites[strings.ToUpper(name)]
+		if !ok {
+			return fmt.Errorf("unsupported TLS cipher suite %q", name)
+		}
+		t.CipherSuites = append(t.CipherSuites, suite)
+	}
+	return nil
+}
+
+// parseTLSVersion parses a TLS version, ie "1.2", 0 if empty
+func parseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(version), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, must be one of "+
+			"1.0, 1.1 or 1.2", version)
+	}
+	return v, nil
+}
@@ -0,0 +1,352 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate and its key to dir, returning
+// their paths
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "telegraf"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:    []string{"telegraf.local"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		0600))
+	return certFile, keyFile
+}
+
+func TestGetTLSConfig_Empty(t *testing.T) {
+	tlsConfig, err := GetTLSConfig(TLSOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+}
+
+func TestGetTLSConfig_Certificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	tlsConfig, err := GetTLSConfig(TLSOptions{
+		SSLCA:   certFile,
+		SSLCert: certFile,
+		SSLKey:  keyFile,
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, tlsConfig.RootCAs)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = GetTLSConfig(TLSOptions{SSLCert: certFile})
+	assert.Error(t, err)
+	_, err = GetTLSConfig(TLSOptions{SSLCA: filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+	_, err = GetTLSConfig(TLSOptions{SSLCA: keyFile})
+	assert.Error(t, err)
+}
+
+func TestGetTLSConfig_EncryptedKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	data, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	block, _ := pem.Decode(data)
+	block, err = x509.EncryptPEMBlock(rand.Reader, block.Type, block.Bytes,
+		[]byte("secret"), x509.PEMCipherAES256)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(block),
+		0600))
+
+	tlsConfig, err := GetTLSConfig(TLSOptions{
+		SSLCert:        certFile,
+		SSLKey:         keyFile,
+		SSLKeyPassword: "secret",
+	})
+	require.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+
+	for _, password := range []string{"", "wrong"} {
+		_, err = GetTLSConfig(TLSOptions{
+			SSLCert:        certFile,
+			SSLKey:         keyFile,
+			SSLKeyPassword: password,
+		})
+		assert.Error(t, err)
+	}
+}
+
+func TestGetTLSConfig_PKCS12(t *testing.T) {
+	// client.p12 bundles the certificate of the client, signed by a CA, its
+	// key and the CA, with the password "telegraf"
+	tlsConfig, err := GetTLSConfig(TLSOptions{
+		SSLPKCS12:      "testdata/client.p12",
+		SSLKeyPassword: "telegraf",
+	})
+	require.NoError(t, err)
+	require.Len(t, tlsConfig.Certificates, 1)
+	chain := tlsConfig.Certificates[0].Certificate
+	require.Len(t, chain, 2)
+	leaf, err := x509.ParseCertificate(chain[0])
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf", leaf.Subject.CommonName)
+
+	_, err = GetTLSConfig(TLSOptions{
+		SSLPKCS12:      "testdata/client.p12",
+		SSLKeyPassword: "wrong",
+	})
+	assert.Error(t, err)
+	_, err = GetTLSConfig(TLSOptions{
+		SSLCert:        "testdata/client.pem",
+		SSLPKCS12:      "testdata/client.p12",
+		SSLKeyPassword: "telegraf",
+	})
+	assert.Error(t, err)
+
+	serverConfig, err := GetServerTLSConfig(ServerTLSOptions{
+		TLSPKCS12:      "testdata/client.p12",
+		TLSKeyPassword: "telegraf",
+	})
+	require.NoError(t, err)
+	assert.Len(t, serverConfig.Certificates, 1)
+}
+
+func TestGetTLSConfig_Versions(t *testing.T) {
+	tlsConfig, err := GetTLSConfig(TLSOptions{
+		MinVersion: "1.1",
+		MaxVersion: "TLS1.2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS11), tlsConfig.MinVersion)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MaxVersion)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	_, err = GetTLSConfig(TLSOptions{MinVersion: "1.2", MaxVersion: "1.0"})
+	assert.Error(t, err)
+	_, err = GetTLSConfig(TLSOptions{MinVersion: "3.0"})
+	assert.Error(t, err)
+}
+
+func TestGetTLSConfig_CipherSuites(t *testing.T) {
+	tlsConfig, err := GetTLSConfig(TLSOptions{
+		CipherSuites: []string{
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			"tls_ecdhe_ecdsa_with_aes_256_gcm_sha384",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	}, tlsConfig.CipherSuites)
+
+	_, err = GetTLSConfig(TLSOptions{CipherSuites: []string{"TLS_NULL"}})
+	assert.Error(t, err)
+}
+
+func TestGetServerTLSConfig(t *testing.T) {
+	tlsConfig, err := GetServerTLSConfig(ServerTLSOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	tlsConfig, err = GetServerTLSConfig(ServerTLSOptions{
+		TLSCert:    certFile,
+		TLSKey:     keyFile,
+		MinVersion: "1.2",
+	})
+	require.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+
+	_, err = GetServerTLSConfig(ServerTLSOptions{TLSKey: keyFile})
+	assert.Error(t, err)
+	_, err = GetServerTLSConfig(ServerTLSOptions{
+		AllowedCACerts: []string{certFile},
+	})
+	assert.Error(t, err)
+}
+
+func TestGetServerTLSConfig_ClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	serverConfig, err := GetServerTLSConfig(ServerTLSOptions{
+		TLSCert:        certFile,
+		TLSKey:         keyFile,
+		AllowedCACerts: []string{certFile},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, serverConfig.ClientAuth)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// A client presenting a certificate signed by an allowed CA is accepted
+	clientConfig, err := GetTLSConfig(TLSOptions{
+		SSLCA:   certFile,
+		SSLCert: certFile,
+		SSLKey:  keyFile,
+	})
+	require.NoError(t, err)
+	conn, err := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+
+	// The name verified against the certificate of the server can differ
+	// from the host connected to
+	for name, valid := range map[string]bool{
+		"telegraf.local":       true,
+		"telegraf.example.com": false,
+	} {
+		clientConfig, err = GetTLSConfig(TLSOptions{
+			SSLCA:      certFile,
+			SSLCert:    certFile,
+			SSLKey:     keyFile,
+			ServerName: name,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, name, clientConfig.ServerName)
+		conn, err = tls.Dial("tcp", listener.Addr().String(), clientConfig)
+		if valid && assert.NoError(t, err) {
+			conn.Close()
+		}
+		if !valid {
+			assert.Error(t, err)
+		}
+	}
+
+	// A client without certificate is rejected
+	clientConfig, err = GetTLSConfig(TLSOptions{SSLCA: certFile})
+	require.NoError(t, err)
+	conn, err = tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if err == nil {
+		// The rejection may only be seen on the first read
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	assert.Error(t, err)
+}
+
+// touch sets the modification time of the files to t
+func touch(t *testing.T, modTime time.Time, files ...string) {
+	for _, file := range files {
+		require.NoError(t, os.Chtimes(file, modTime, modTime))
+	}
+}
+
+func TestFileWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+	missing := filepath.Join(dir, "missing.pem")
+
+	w := NewFileWatcher(0, certFile, "", keyFile, missing)
+	assert.False(t, w.Changed())
+	touch(t, time.Now().Add(time.Minute), keyFile)
+	assert.True(t, w.Changed())
+	assert.False(t, w.Changed())
+
+	require.NoError(t, ioutil.WriteFile(missing, nil, 0600))
+	assert.True(t, w.Changed())
+
+	// The files are not checked again before the interval elapsed
+	w = NewFileWatcher(time.Hour, certFile)
+	touch(t, time.Now().Add(2*time.Minute), certFile)
+	assert.False(t, w.Changed())
+}
+
+func TestGetServerTLSConfig_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	tlsConfig, err := GetServerTLSConfig(ServerTLSOptions{
+		TLSCert:        certFile,
+		TLSKey:         keyFile,
+		ReloadInterval: time.Nanosecond,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.GetCertificate)
+	first, err := tlsConfig.GetCertificate(nil)
+	require.NoError(t, err)
+
+	// A certificate replaced by a new one is served once reloaded
+	writeCert(t, dir)
+	touch(t, time.Now().Add(time.Minute), certFile, keyFile)
+	second, err := tlsConfig.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// The current certificate is kept while the new one cannot be loaded
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("invalid"), 0600))
+	touch(t, time.Now().Add(2*time.Minute), keyFile)
+	cert, err := tlsConfig.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate[0], cert.Certificate[0])
+}
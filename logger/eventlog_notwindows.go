@@ -0,0 +1,12 @@
+// +build !windows
+
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+func newEventLogWriter(source string) (io.WriteCloser, error) {
+	return nil, errors.New("logging to the event log is only supported on Windows")
+}
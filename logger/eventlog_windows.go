@@ -0,0 +1,53 @@
+// +build windows
+
+package logger
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// eventID is the ID of all events logged by telegraf
+const eventID = 1
+
+// eventLogWriter writes the messages of the standard logger to the Windows
+// event log, with the event type matching the level of the message.
+type eventLogWriter struct {
+	elog *eventlog.Log
+}
+
+func newEventLogWriter(source string) (io.WriteCloser, error) {
+	elog, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogWriter{elog: elog}, nil
+}
+
+func (e *eventLogWriter) Write(p []byte) (int, error) {
+	entry := parseEntry(strings.TrimRight(string(p), "\n"))
+	msg := entry.Message
+	if entry.Plugin != "" {
+		msg = "[" + entry.Plugin + "] " + msg
+	}
+
+	var err error
+	switch entry.Level {
+	case "error":
+		err = e.elog.Error(eventID, msg)
+	case "warn":
+		err = e.elog.Warning(eventID, msg)
+	default:
+		err = e.elog.Info(eventID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *eventLogWriter) Close() error {
+	return e.elog.Close()
+}
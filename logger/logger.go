@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/rotate"
+)
+
+// LogConfig configures where and how telegraf logs
+type LogConfig struct {
+	// Debug enables the debug messages of plugins
+	Debug bool
+	// Quiet disables the information messages of plugins
+	Quiet bool
+	// LogFormat is either "text", the default, or "json"
+	LogFormat string
+	// Logfile is the file to log to, stderr if empty
+	Logfile string
+	// RotationInterval rotates the logfile once it is older, 0 disables it
+	RotationInterval time.Duration
+	// RotationMaxSize rotates the logfile once it is larger, 0 disables it
+	RotationMaxSize int64
+	// RotationMaxArchives is the number of rotated logfiles to keep, -1
+	// keeps all of them
+	RotationMaxArchives int
+	// EventLog is the Windows event log source to log to if no Logfile is
+	// set, ie when running as a Windows service
+	EventLog string
+}
+
+var state struct {
+	sync.Mutex
+	debug bool
+	quiet bool
+	// current is the writer set up by the last call to SetupLogging
+	current io.Writer
+}
+
+func debug() bool {
+	state.Lock()
+	defer state.Unlock()
+	return state.debug
+}
+
+func quiet() bool {
+	state.Lock()
+	defer state.Unlock()
+	return state.quiet
+}
+
+// SetupLogging redirects the standard logger, used by the agent and all
+// plugins, according to the config. The logfile of a previous call is closed.
+func SetupLogging(config LogConfig) error {
+	switch config.LogFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("unknown log_format %q, must be text or json",
+			config.LogFormat)
+	}
+
+	var w io.Writer = os.Stderr
+	eventLog := false
+	if config.Logfile != "" {
+		fw, err := rotate.NewFileWriter(config.Logfile, config.RotationInterval,
+			config.RotationMaxSize, config.RotationMaxArchives)
+		if err != nil {
+			return err
+		}
+		w = fw
+	} else if config.EventLog != "" {
+		ew, err := newEventLogWriter(config.EventLog)
+		if err != nil {
+			return err
+		}
+		w = ew
+		eventLog = true
+	}
+
+	state.Lock()
+	defer state.Unlock()
+
+	if eventLog {
+		// The event log keeps the time and level of every message itself
+		log.SetFlags(0)
+		log.SetOutput(w)
+	} else if config.LogFormat == "json" {
+		log.SetFlags(0)
+		log.SetOutput(&jsonWriter{w: w})
+	} else {
+		log.SetFlags(log.LstdFlags)
+		log.SetOutput(w)
+	}
+
+	if c, ok := state.current.(io.Closer); ok && state.current != os.Stderr {
+		c.Close()
+	}
+	state.current = w
+	state.debug = config.Debug
+	state.quiet = config.Quiet
+	return nil
+}
+
+// jsonEntry is a log message in the JSON log format
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Plugin  string `json:"plugin,omitempty"`
+	Message string `json:"msg"`
+}
+
+// levelPrefixes maps the prefixes of log messages to their level. Besides
+// the prefixes of the plugin loggers, the prefixes used with the standard
+// logger are recognized.
+var levelPrefixes = []struct {
+	prefix string
+	level  string
+}{
+	{errorPrefix, "error"},
+	{warnPrefix, "warn"},
+	{infoPrefix, "info"},
+	{debugPrefix, "debug"},
+	{"FATAL: ", "error"},
+	{"ERROR: ", "error"},
+	{"Error: ", "error"},
+	{"WARNING: ", "warn"},
+}
+
+// jsonWriter writes the messages of the standard logger as JSON, one object
+// per line. The standard logger calls Write once per message.
+type jsonWriter struct {
+	w io.Writer
+}
+
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	entry := parseEntry(strings.TrimRight(string(p), "\n"))
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := j.w.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func parseEntry(msg string) jsonEntry {
+	entry := jsonEntry{Level: "info"}
+	for _, lp := range levelPrefixes {
+		if strings.HasPrefix(msg, lp.prefix) {
+			entry.Level = lp.level
+			msg = msg[len(lp.prefix):]
+			break
+		}
+	}
+	if strings.HasPrefix(msg, "[") {
+		if i := strings.Index(msg, "] "); i > 0 {
+			entry.Plugin = msg[1:i]
+			msg = msg[i+2:]
+		}
+	}
+	entry.Message = msg
+	return entry
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupLogging_Logfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logfile := filepath.Join(dir, "telegraf.log")
+	require.NoError(t, SetupLogging(LogConfig{Logfile: logfile}))
+	defer SetupLogging(LogConfig{})
+
+	log.Printf("ERROR: test message\n")
+
+	contents, err := ioutil.ReadFile(logfile)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(string(contents), "ERROR: test message\n"))
+}
+
+func TestPluginLogger(t *testing.T) {
+	require.NoError(t, SetupLogging(LogConfig{}))
+	defer SetupLogging(LogConfig{})
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	l := NewLogger("inputs", "cpu", "")
+	l.Errorf("failed %d times", 2)
+	l.Warn("warning")
+	l.Info("info")
+	l.Debug("debug is disabled")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, 3, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], "E! [inputs.cpu] failed 2 times"))
+	assert.True(t, strings.HasSuffix(lines[1], "W! [inputs.cpu] warning"))
+	assert.True(t, strings.HasSuffix(lines[2], "I! [inputs.cpu] info"))
+}
+
+func TestPluginLogger_Alias(t *testing.T) {
+	require.NoError(t, SetupLogging(LogConfig{}))
+	defer SetupLogging(LogConfig{})
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	NewLogger("inputs", "exec", "disk_usage").Error("failed")
+	assert.True(t, strings.HasSuffix(strings.TrimSpace(buf.String()),
+		"E! [inputs.exec::disk_usage] failed"))
+}
+
+func TestJSONFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logfile := filepath.Join(dir, "telegraf.log")
+	require.NoError(t, SetupLogging(LogConfig{
+		Logfile:   logfile,
+		LogFormat: "json",
+		Debug:     true,
+	}))
+	defer SetupLogging(LogConfig{})
+
+	NewLogger("outputs", "influxdb", "").Debugf("wrote %d metrics", 10)
+	log.Printf("WARNING: buffer is full\n")
+	log.Printf("Starting Telegraf\n")
+
+	contents, err := ioutil.ReadFile(logfile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	require.Equal(t, 3, len(lines))
+
+	var entries []jsonEntry
+	for _, line := range lines {
+		var e jsonEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		assert.NotEmpty(t, e.Time)
+		e.Time = ""
+		entries = append(entries, e)
+	}
+	assert.Equal(t, []jsonEntry{
+		{Level: "debug", Plugin: "outputs.influxdb", Message: "wrote 10 metrics"},
+		{Level: "warn", Message: "buffer is full"},
+		{Level: "info", Message: "Starting Telegraf"},
+	}, entries)
+}
+
+type pluginWithLog struct {
+	Log telegraf.Logger `toml:"-"`
+}
+
+type pluginWithoutLog struct {
+	Name string
+}
+
+func TestSetLoggerOnPlugin(t *testing.T) {
+	l := NewLogger("inputs", "test", "")
+
+	p := &pluginWithLog{}
+	SetLoggerOnPlugin(p, l)
+	assert.Equal(t, l, p.Log)
+
+	// no panic on plugins without a Log field
+	SetLoggerOnPlugin(&pluginWithoutLog{}, l)
+	SetLoggerOnPlugin(pluginWithoutLog{}, l)
+}
+
+func TestSetupLogging_UnknownFormat(t *testing.T) {
+	assert.Error(t, SetupLogging(LogConfig{LogFormat: "xml"}))
+}
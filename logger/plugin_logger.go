@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Level prefixes of log messages, parsed back by the JSON log format
+const (
+	errorPrefix = "E! "
+	warnPrefix  = "W! "
+	infoPrefix  = "I! "
+	debugPrefix = "D! "
+)
+
+// pluginLogger logs through the standard logger, prefixing messages with
+// the level and the name of the plugin, ie "E! [inputs.cpu] message".
+type pluginLogger struct {
+	name string
+}
+
+// NewLogger returns the logger of the plugin with the given name, of the
+// given category, ie "inputs". If the plugin has an alias, it is added to the
+// name, ie "inputs.exec::myalias".
+func NewLogger(category, name, alias string) telegraf.Logger {
+	if alias != "" {
+		name += "::" + alias
+	}
+	return &pluginLogger{name: category + "." + name}
+}
+
+func (l *pluginLogger) output(prefix, msg string) {
+	log.Print(prefix + "[" + l.name + "] " + msg)
+}
+
+func (l *pluginLogger) Errorf(format string, args ...interface{}) {
+	l.output(errorPrefix, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Error(args ...interface{}) {
+	l.output(errorPrefix, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Warnf(format string, args ...interface{}) {
+	l.output(warnPrefix, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Warn(args ...interface{}) {
+	l.output(warnPrefix, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Infof(format string, args ...interface{}) {
+	if quiet() {
+		return
+	}
+	l.output(infoPrefix, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Info(args ...interface{}) {
+	if quiet() {
+		return
+	}
+	l.output(infoPrefix, fmt.Sprint(args...))
+}
+
+func (l *pluginLogger) Debugf(format string, args ...interface{}) {
+	if !debug() {
+		return
+	}
+	l.output(debugPrefix, fmt.Sprintf(format, args...))
+}
+
+func (l *pluginLogger) Debug(args ...interface{}) {
+	if !debug() {
+		return
+	}
+	l.output(debugPrefix, fmt.Sprint(args...))
+}
+
+var loggerType = reflect.TypeOf((*telegraf.Logger)(nil)).Elem()
+
+// SetLoggerOnPlugin sets the Log field of the plugin to l, if the plugin is
+// a pointer to a struct with a Log field of type telegraf.Logger.
+func SetLoggerOnPlugin(plugin interface{}, l telegraf.Logger) {
+	v := reflect.ValueOf(plugin)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	field := v.Elem().FieldByName("Log")
+	if !field.IsValid() || !field.CanSet() || field.Type() != loggerType {
+		return
+	}
+	field.Set(reflect.ValueOf(l))
+}
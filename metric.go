@@ -2,12 +2,44 @@ package telegraf
 
 import (
 	"bytes"
+	"hash/fnv"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/client/v2"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/telegraf/internal/intern"
 )
 
+// ValueType is the type of the values of a metric, telling outputs how to
+// interpret them
+type ValueType int
+
+const (
+	_ ValueType = iota
+	// Counter values are cumulative, only increasing until they are reset
+	Counter
+	// Gauge values are the current state of something, which can go up and
+	// down
+	Gauge
+	// Untyped values are of an unknown type, the type of the metrics created
+	// by NewMetric
+	Untyped
+)
+
+// String returns the name of the value type, ie "counter"
+func (t ValueType) String() string {
+	switch t {
+	case Counter:
+		return "counter"
+	case Gauge:
+		return "gauge"
+	default:
+		return "untyped"
+	}
+}
+
 type Metric interface {
 	// Name returns the measurement name of the metric
 	Name() string
@@ -24,19 +56,42 @@ type Metric interface {
 	// Fields returns the fields for the metric
 	Fields() map[string]interface{}
 
+	// Type returns the value type of the fields of the metric
+	Type() ValueType
+
 	// String returns a line-protocol string of the metric
 	String() string
 
+	// AppendTo appends the line-protocol string of the metric to b and
+	// returns the extended buffer. Outputs serializing many metrics should
+	// use it with a reused buffer rather than String.
+	AppendTo(b []byte) []byte
+
 	// PrecisionString returns a line-protocol string of the metric, at precision
 	PrecisionString(precison string) string
 
 	// Point returns a influxdb client.Point object
 	Point() *client.Point
+
+	// HashID returns a hash of the name and tags of the metric, identifying
+	// the series it belongs to
+	HashID() uint64
 }
 
 // metric is a wrapper of the influxdb client.Point struct
 type metric struct {
-	pt *client.Point
+	pt        *client.Point
+	valueType ValueType
+
+	// tags holds the interned tags of the point, which would otherwise be
+	// parsed into newly allocated strings every time they are read
+	tags map[string]string
+
+	// line is the line-protocol string of the metric, serialized once by
+	// the first call to String or AppendTo, as a metric is serialized by
+	// every output and by the write-ahead log
+	line     string
+	lineOnce sync.Once
 }
 
 // NewMetric returns a metric with the given timestamp. If a timestamp is not
@@ -48,6 +103,38 @@ func NewMetric(
 	tags map[string]string,
 	fields map[string]interface{},
 	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(Untyped, name, tags, fields, t...)
+}
+
+// NewGaugeMetric returns a metric whose fields are gauges, see NewMetric
+func NewGaugeMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(Gauge, name, tags, fields, t...)
+}
+
+// NewCounterMetric returns a metric whose fields are counters, see NewMetric
+func NewCounterMetric(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
+) (Metric, error) {
+	return NewTypedMetric(Counter, name, tags, fields, t...)
+}
+
+// NewTypedMetric returns a metric of the given value type, see NewMetric.
+// Code creating a metric from another one should use it to keep its type.
+func NewTypedMetric(
+	valueType ValueType,
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	t ...time.Time,
 ) (Metric, error) {
 	var T time.Time
 	if len(t) > 0 {
@@ -59,10 +146,25 @@ func NewMetric(
 		return nil, err
 	}
 	return &metric{
-		pt: pt,
+		pt:        pt,
+		valueType: valueType,
+		tags:      internTags(tags),
 	}, nil
 }
 
+// internTags returns a copy of the tags with interned keys and values. Tags
+// with an empty value are dropped, as they are by the point.
+func internTags(tags map[string]string) map[string]string {
+	interned := make(map[string]string, len(tags))
+	for k, v := range tags {
+		if v == "" {
+			continue
+		}
+		interned[intern.String(k)] = intern.String(v)
+	}
+	return interned
+}
+
 // ParseMetrics returns a slice of Metrics from a text representation of a
 // metric (in line-protocol format)
 // with each metric separated by newlines. If any metrics fail to parse,
@@ -86,8 +188,13 @@ func (m *metric) Name() string {
 	return m.pt.Name()
 }
 
+// Tags returns a copy of the tags, which the caller may modify
 func (m *metric) Tags() map[string]string {
-	return m.pt.Tags()
+	tags := make(map[string]string, len(m.tags))
+	for k, v := range m.tags {
+		tags[k] = v
+	}
+	return tags
 }
 
 func (m *metric) Time() time.Time {
@@ -102,8 +209,19 @@ func (m *metric) Fields() map[string]interface{} {
 	return m.pt.Fields()
 }
 
+func (m *metric) Type() ValueType {
+	return m.valueType
+}
+
 func (m *metric) String() string {
-	return m.pt.String()
+	m.lineOnce.Do(func() {
+		m.line = m.pt.String()
+	})
+	return m.line
+}
+
+func (m *metric) AppendTo(b []byte) []byte {
+	return append(b, m.String()...)
 }
 
 func (m *metric) PrecisionString(precison string) string {
@@ -113,3 +231,22 @@ func (m *metric) PrecisionString(precison string) string {
 func (m *metric) Point() *client.Point {
 	return m.pt
 }
+
+func (m *metric) HashID() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(m.Name()))
+
+	tags := m.tags
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte("\n"))
+		h.Write([]byte(k))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tags[k]))
+	}
+	return h.Sum64()
+}
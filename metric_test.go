@@ -80,6 +80,25 @@ func TestNewMetric(t *testing.T) {
 	assert.Equal(t, "cpu", m.Name())
 	assert.Equal(t, now, m.Time())
 	assert.Equal(t, now.UnixNano(), m.UnixNano())
+	assert.Equal(t, Untyped, m.Type())
+}
+
+func TestNewMetricValueType(t *testing.T) {
+	fields := map[string]interface{}{"value": int64(1)}
+
+	m, err := NewGaugeMetric("cpu", nil, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, Gauge, m.Type())
+	assert.Equal(t, "gauge", m.Type().String())
+
+	m, err = NewCounterMetric("cpu", nil, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, Counter, m.Type())
+	assert.Equal(t, "counter", m.Type().String())
+
+	m, err = NewTypedMetric(m.Type(), "cpu", nil, fields)
+	assert.NoError(t, err)
+	assert.Equal(t, Counter, m.Type())
 }
 
 func TestNewMetricString(t *testing.T) {
@@ -133,3 +152,127 @@ func TestNewMetricFailNaN(t *testing.T) {
 	_, err := NewMetric("cpu", tags, fields, now)
 	assert.Error(t, err)
 }
+
+func TestMetricHashID(t *testing.T) {
+	now := time.Now()
+	fields := map[string]interface{}{"value": float64(1)}
+
+	m1, err := NewMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"}, fields, now)
+	assert.NoError(t, err)
+	m2, err := NewMetric("cpu",
+		map[string]string{"cpu": "cpu0", "host": "localhost"},
+		map[string]interface{}{"other": float64(2)}, now.Add(time.Second))
+	assert.NoError(t, err)
+	m3, err := NewMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu1"}, fields, now)
+	assert.NoError(t, err)
+	m4, err := NewMetric("mem",
+		map[string]string{"host": "localhost", "cpu": "cpu0"}, fields, now)
+	assert.NoError(t, err)
+
+	// Fields and timestamps are not part of the series
+	assert.Equal(t, m1.HashID(), m2.HashID())
+	assert.NotEqual(t, m1.HashID(), m3.HashID())
+	assert.NotEqual(t, m1.HashID(), m4.HashID())
+}
+
+func TestMetricTags(t *testing.T) {
+	m, err := NewMetric("cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0", "empty": ""},
+		map[string]interface{}{"value": float64(1)}, time.Now())
+	assert.NoError(t, err)
+
+	// Empty tags are dropped, as in the line protocol
+	tags := m.Tags()
+	assert.Equal(t, map[string]string{"host": "localhost", "cpu": "cpu0"}, tags)
+	assert.Equal(t, m.Point().Tags(), tags)
+
+	// The tags returned are a copy
+	tags["host"] = "other"
+	assert.Equal(t, "localhost", m.Tags()["host"])
+}
+
+func benchmarkTags() map[string]string {
+	return map[string]string{
+		"host":       "server01.example.com",
+		"datacenter": "us-east-1",
+		"cpu":        "cpu0",
+		"role":       "database",
+	}
+}
+
+func BenchmarkNewMetric(b *testing.B) {
+	tags := benchmarkTags()
+	fields := map[string]interface{}{"usage_idle": float64(99)}
+	now := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewMetric("cpu", tags, fields, now)
+	}
+}
+
+// BenchmarkMetricTags reads the interned tags of a metric, compared to
+// BenchmarkMetricPointTags parsing them from the point into new strings.
+func BenchmarkMetricTags(b *testing.B) {
+	m, _ := NewMetric("cpu", benchmarkTags(),
+		map[string]interface{}{"usage_idle": float64(99)}, time.Now())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Tags()
+	}
+}
+
+func BenchmarkMetricPointTags(b *testing.B) {
+	m, _ := NewMetric("cpu", benchmarkTags(),
+		map[string]interface{}{"usage_idle": float64(99)}, time.Now())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.Point().Tags()
+	}
+}
+
+func BenchmarkMetricHashID(b *testing.B) {
+	m, _ := NewMetric("cpu", benchmarkTags(),
+		map[string]interface{}{"usage_idle": float64(99)}, time.Now())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m.HashID()
+	}
+}
+
+func TestMetricAppendTo(t *testing.T) {
+	now := time.Now()
+	m, err := NewMetric("cpu", map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": float64(1)}, now)
+	assert.NoError(t, err)
+
+	buf := []byte("mem value=2i\n")
+	buf = m.AppendTo(buf)
+	assert.Equal(t, "mem value=2i\n"+m.String(), string(buf))
+	assert.Equal(t, m.Point().String(), m.String())
+}
+
+// BenchmarkMetricAppendTo serializes a metric into a reused buffer, compared
+// to BenchmarkMetricPointString serializing the point into a new string.
+func BenchmarkMetricAppendTo(b *testing.B) {
+	m, _ := NewMetric("cpu", benchmarkTags(),
+		map[string]interface{}{"usage_idle": float64(99)}, time.Now())
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = m.AppendTo(buf[:0])
+	}
+}
+
+// benchmarkLine keeps the result of BenchmarkMetricPointString
+var benchmarkLine string
+
+func BenchmarkMetricPointString(b *testing.B) {
+	m, _ := NewMetric("cpu", benchmarkTags(),
+		map[string]interface{}{"usage_idle": float64(99)}, time.Now())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchmarkLine = m.Point().String()
+	}
+}
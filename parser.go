@@ -0,0 +1,8 @@
+package telegraf
+
+type Parser interface {
+	// Parse returns the metrics of the data in buf, read by a plugin in the
+	// data format of the Parser. If some metrics fail to parse, a non-nil
+	// error is returned in addition to the metrics that parsed successfully.
+	Parse(buf []byte) ([]Metric, error)
+}
@@ -0,0 +1,187 @@
+package basicstats
+
+import (
+	"math"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # Configures which basic stats to push as fields. Supported stats are
+  # count, min, max, mean, stdev, s2 (variance) and sum.
+  # stats = ["count", "min", "max", "mean", "stdev", "s2"]
+`
+
+var defaultStats = []string{"count", "min", "max", "mean", "stdev", "s2"}
+
+type BasicStats struct {
+	Stats []string
+	Log   telegraf.Logger `toml:"-"`
+
+	// stats to push, parsed from Stats on the first call to Add
+	configured map[string]bool
+	cache      map[uint64]aggregate
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*basicstats
+}
+
+type basicstats struct {
+	count float64
+	min   float64
+	max   float64
+	sum   float64
+	mean  float64
+	// M2 is the running sum of squared differences from the mean
+	M2 float64
+}
+
+func NewBasicStats() *BasicStats {
+	return &BasicStats{
+		cache: make(map[uint64]aggregate),
+	}
+}
+
+func (b *BasicStats) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BasicStats) Description() string {
+	return "Keep the aggregate basicstats of each metric passing through."
+}
+
+func (b *BasicStats) Add(in telegraf.Metric) {
+	if b.configured == nil {
+		b.configured = b.parseStats(b.Stats)
+	}
+
+	id := in.HashID()
+	a, ok := b.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*basicstats),
+		}
+		b.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+
+		s, ok := a.fields[k]
+		if !ok {
+			a.fields[k] = &basicstats{
+				count: 1,
+				min:   fv,
+				max:   fv,
+				sum:   fv,
+				mean:  fv,
+			}
+			continue
+		}
+
+		// Welford's online algorithm for the mean and variance
+		s.count++
+		delta := fv - s.mean
+		s.mean += delta / s.count
+		s.M2 += delta * (fv - s.mean)
+		s.sum += fv
+		if fv < s.min {
+			s.min = fv
+		}
+		if fv > s.max {
+			s.max = fv
+		}
+	}
+}
+
+func (b *BasicStats) Push(acc telegraf.Accumulator) {
+	for _, a := range b.cache {
+		fields := make(map[string]interface{})
+		for k, s := range a.fields {
+			if b.configured["count"] {
+				fields[k+"_count"] = s.count
+			}
+			if b.configured["min"] {
+				fields[k+"_min"] = s.min
+			}
+			if b.configured["max"] {
+				fields[k+"_max"] = s.max
+			}
+			if b.configured["mean"] {
+				fields[k+"_mean"] = s.mean
+			}
+			if b.configured["sum"] {
+				fields[k+"_sum"] = s.sum
+			}
+			// The variance is undefined for a single sample
+			if s.count > 1 {
+				variance := s.M2 / (s.count - 1)
+				if b.configured["s2"] {
+					fields[k+"_s2"] = variance
+				}
+				if b.configured["stdev"] {
+					fields[k+"_stdev"] = math.Sqrt(variance)
+				}
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+func (b *BasicStats) Reset() {
+	b.cache = make(map[uint64]aggregate)
+}
+
+func (b *BasicStats) parseStats(names []string) map[string]bool {
+	if names == nil {
+		names = defaultStats
+	}
+
+	stats := make(map[string]bool)
+	for _, name := range names {
+		switch name {
+		case "count", "min", "max", "mean", "stdev", "s2", "sum":
+			stats[name] = true
+		default:
+			b.Log.Warnf("Unrecognized stat '%s', ignoring", name)
+		}
+	}
+	return stats
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("basicstats", func() telegraf.Aggregator {
+		return NewBasicStats()
+	})
+}
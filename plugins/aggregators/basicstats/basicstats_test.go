@@ -0,0 +1,127 @@
+package basicstats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var m1, _ = telegraf.NewMetric("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a": int64(1),
+		"b": int64(1),
+		"c": float64(2),
+		"d": "not a number",
+	},
+	time.Now(),
+)
+var m2, _ = telegraf.NewMetric("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a": int64(1),
+		"b": int64(3),
+		"c": float64(4),
+		"e": int64(200),
+	},
+	time.Now(),
+)
+
+// Test that the default stats are pushed for every numeric field
+func TestBasicStatsWithDefaultStats(t *testing.T) {
+	acc := testutil.Accumulator{}
+	b := NewBasicStats()
+	b.Log = testutil.Logger{}
+	b.Add(m1)
+	b.Add(m2)
+	b.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"a_count": float64(2),
+		"a_min":   float64(1),
+		"a_max":   float64(1),
+		"a_mean":  float64(1),
+		"a_stdev": float64(0),
+		"a_s2":    float64(0),
+		"b_count": float64(2),
+		"b_min":   float64(1),
+		"b_max":   float64(3),
+		"b_mean":  float64(2),
+		"b_stdev": math.Sqrt(2),
+		"b_s2":    float64(2),
+		"c_count": float64(2),
+		"c_min":   float64(2),
+		"c_max":   float64(4),
+		"c_mean":  float64(3),
+		"c_stdev": math.Sqrt(2),
+		"c_s2":    float64(2),
+		// variance is not pushed for a single sample
+		"e_count": float64(1),
+		"e_min":   float64(200),
+		"e_max":   float64(200),
+		"e_mean":  float64(200),
+	}
+	acc.AssertContainsTaggedFields(t, "m1", expectedFields,
+		map[string]string{"foo": "bar"})
+}
+
+// Test that only the configured stats are pushed
+func TestBasicStatsWithSelectedStats(t *testing.T) {
+	acc := testutil.Accumulator{}
+	b := NewBasicStats()
+	b.Log = testutil.Logger{}
+	b.Stats = []string{"sum", "max", "bogus"}
+	b.Add(m1)
+	b.Add(m2)
+	b.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"a_sum": float64(2),
+		"a_max": float64(1),
+		"b_sum": float64(4),
+		"b_max": float64(3),
+		"c_sum": float64(6),
+		"c_max": float64(4),
+		"e_sum": float64(200),
+		"e_max": float64(200),
+	}
+	acc.AssertContainsTaggedFields(t, "m1", expectedFields,
+		map[string]string{"foo": "bar"})
+}
+
+// Test that series are aggregated separately, and that Reset clears them
+func TestBasicStatsSeriesAndReset(t *testing.T) {
+	m3, _ := telegraf.NewMetric("m1",
+		map[string]string{"foo": "baz"},
+		map[string]interface{}{"a": float64(10)},
+		time.Now(),
+	)
+
+	acc := testutil.Accumulator{}
+	b := NewBasicStats()
+	b.Log = testutil.Logger{}
+	b.Stats = []string{"count"}
+	b.Add(m1)
+	b.Add(m3)
+	b.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{
+			"a_count": float64(1),
+			"b_count": float64(1),
+			"c_count": float64(1),
+		},
+		map[string]string{"foo": "bar"})
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_count": float64(1)},
+		map[string]string{"foo": "baz"})
+
+	b.Reset()
+	acc = testutil.Accumulator{}
+	b.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
@@ -0,0 +1,217 @@
+package derivative
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # Fields to compute the derivative of, all numeric fields if empty
+  # fields = ["bytes_sent", "bytes_recv"]
+
+  # Whether to emit the per second "rate" or the "delta" of the fields
+  # over each period
+  mode = "rate"
+
+  # Suffix added to the field names, "_rate" or "_delta" by default
+  # suffix = "_rate"
+
+  # Counters decreasing between two samples have either been reset or have
+  # rolled over. If counter_max is set, a decrease is treated as a roll over
+  # at this value (eg. 4294967296 for 32-bit counters), otherwise the
+  # interval is skipped.
+  # counter_max = 4294967296.0
+`
+
+type Derivative struct {
+	Fields     []string
+	Mode       string
+	Suffix     string
+	CounterMax float64
+	Log        telegraf.Logger `toml:"-"`
+
+	cache map[uint64]*aggregate
+}
+
+type aggregate struct {
+	name    string
+	tags    map[string]string
+	fields  map[string]*derivative
+	updated bool
+}
+
+// derivative holds the last sample of a field, and the sum of the deltas
+// between successive samples over the current period
+type derivative struct {
+	last     float64
+	lastTime time.Time
+	delta    float64
+	elapsed  time.Duration
+	// valid is true once a delta was computed during the period
+	valid bool
+}
+
+func NewDerivative() *Derivative {
+	return &Derivative{
+		Mode:  "rate",
+		cache: make(map[uint64]*aggregate),
+	}
+}
+
+func (d *Derivative) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Derivative) Description() string {
+	return "Calculate the rate or delta of counter fields over each period."
+}
+
+func (d *Derivative) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := d.cache[id]
+	if !ok {
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*derivative),
+		}
+		d.cache[id] = a
+	}
+	a.updated = true
+
+	t := in.Time()
+	for k, v := range in.Fields() {
+		if !d.includeField(k) {
+			continue
+		}
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+
+		f, ok := a.fields[k]
+		if !ok {
+			a.fields[k] = &derivative{last: fv, lastTime: t}
+			continue
+		}
+		if !t.After(f.lastTime) {
+			// Out of order or duplicate samples can't be used for a rate
+			continue
+		}
+
+		delta := fv - f.last
+		if delta < 0 {
+			if d.CounterMax > 0 && f.last <= d.CounterMax {
+				delta = d.CounterMax - f.last + fv
+			} else {
+				// The counter was reset, skip the interval
+				f.last, f.lastTime = fv, t
+				continue
+			}
+		}
+
+		f.delta += delta
+		f.elapsed += t.Sub(f.lastTime)
+		f.valid = true
+		f.last, f.lastTime = fv, t
+	}
+}
+
+func (d *Derivative) Push(acc telegraf.Accumulator) {
+	suffix := d.Suffix
+	if suffix == "" {
+		suffix = "_" + d.mode()
+	}
+
+	for _, a := range d.cache {
+		fields := make(map[string]interface{})
+		var last time.Time
+		for k, f := range a.fields {
+			if !f.valid {
+				continue
+			}
+			if d.mode() == "delta" {
+				fields[k+suffix] = f.delta
+			} else {
+				fields[k+suffix] = f.delta / f.elapsed.Seconds()
+			}
+			if f.lastTime.After(last) {
+				last = f.lastTime
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags, last)
+		}
+	}
+}
+
+// Reset clears the deltas of the period, keeping the last samples so the
+// next period's deltas start from them. Series that were not updated during
+// the period are forgotten.
+func (d *Derivative) Reset() {
+	for id, a := range d.cache {
+		if !a.updated {
+			delete(d.cache, id)
+			continue
+		}
+		a.updated = false
+		for _, f := range a.fields {
+			f.delta = 0
+			f.elapsed = 0
+			f.valid = false
+		}
+	}
+}
+
+func (d *Derivative) mode() string {
+	switch d.Mode {
+	case "rate", "delta":
+		return d.Mode
+	case "":
+		return "rate"
+	default:
+		d.Log.Warnf("Unknown mode '%s', using rate", d.Mode)
+		d.Mode = "rate"
+		return d.Mode
+	}
+}
+
+func (d *Derivative) includeField(name string) bool {
+	if len(d.Fields) == 0 {
+		return true
+	}
+	for _, f := range d.Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("derivative", func() telegraf.Aggregator {
+		return NewDerivative()
+	})
+}
@@ -0,0 +1,102 @@
+package derivative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Unix(1460000000, 0)
+var tags = map[string]string{"interface": "eth0"}
+
+func newMetric(offset time.Duration, fields map[string]interface{}) telegraf.Metric {
+	m, _ := telegraf.NewMetric("net", tags, fields, start.Add(offset))
+	return m
+}
+
+func TestDerivativeRate(t *testing.T) {
+	d := NewDerivative()
+	d.Log = testutil.Logger{}
+	d.Fields = []string{"bytes_recv"}
+	d.Add(newMetric(0, map[string]interface{}{
+		"bytes_recv": int64(100), "packets_recv": int64(1)}))
+	d.Add(newMetric(10*time.Second, map[string]interface{}{
+		"bytes_recv": int64(200), "packets_recv": int64(2)}))
+	d.Add(newMetric(20*time.Second, map[string]interface{}{
+		"bytes_recv": int64(500), "packets_recv": int64(3)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_recv_rate": float64(20)}, tags)
+	assert.Equal(t, start.Add(20*time.Second), acc.Metrics[0].Time)
+}
+
+// Test that the last sample of a period is used for the next period
+func TestDerivativeDeltaAcrossPeriods(t *testing.T) {
+	d := NewDerivative()
+	d.Log = testutil.Logger{}
+	d.Mode = "delta"
+	d.Add(newMetric(0, map[string]interface{}{"count": int64(1)}))
+	d.Add(newMetric(time.Second, map[string]interface{}{"count": int64(3)}))
+	d.Reset()
+	d.Add(newMetric(2*time.Second, map[string]interface{}{"count": int64(10)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"count_delta": float64(7)}, tags)
+}
+
+func TestDerivativeRollover(t *testing.T) {
+	d := NewDerivative()
+	d.Log = testutil.Logger{}
+	d.Mode = "delta"
+	d.CounterMax = 100
+	d.Add(newMetric(0, map[string]interface{}{"count": int64(90)}))
+	d.Add(newMetric(time.Second, map[string]interface{}{"count": int64(5)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"count_delta": float64(15)}, tags)
+}
+
+// Test that intervals with a counter reset are skipped
+func TestDerivativeCounterReset(t *testing.T) {
+	d := NewDerivative()
+	d.Log = testutil.Logger{}
+	d.Mode = "delta"
+	d.Suffix = "_diff"
+	d.Add(newMetric(0, map[string]interface{}{"count": int64(90)}))
+	d.Add(newMetric(time.Second, map[string]interface{}{"count": int64(5)}))
+
+	acc := testutil.Accumulator{}
+	d.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	d.Add(newMetric(2*time.Second, map[string]interface{}{"count": int64(8)}))
+	d.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"count_diff": float64(3)}, tags)
+}
+
+// Test that series are forgotten after a period without samples
+func TestDerivativeExpiry(t *testing.T) {
+	d := NewDerivative()
+	d.Log = testutil.Logger{}
+	d.Add(newMetric(0, map[string]interface{}{"count": int64(1)}))
+	d.Reset()
+	assert.Equal(t, 1, len(d.cache))
+	d.Reset()
+	assert.Equal(t, 0, len(d.cache))
+}
+
+func TestDerivativeConvertUnsigned(t *testing.T) {
+	v, ok := convert(uint64(1) << 63)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1<<63), v)
+}
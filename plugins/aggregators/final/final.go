@@ -0,0 +1,85 @@
+package final
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # The time that a series is not updated until considering it final.
+  series_timeout = "5m"
+`
+
+type Final struct {
+	SeriesTimeout internal.Duration
+
+	cache map[uint64]*lastValue
+
+	// now returns the current time, it is overridden in tests
+	now func() time.Time
+}
+
+// lastValue is the last metric of a series and when it was received
+type lastValue struct {
+	metric telegraf.Metric
+	seen   time.Time
+}
+
+func NewFinal() *Final {
+	return &Final{
+		SeriesTimeout: internal.Duration{Duration: 5 * time.Minute},
+		cache:         make(map[uint64]*lastValue),
+		now:           time.Now,
+	}
+}
+
+func (f *Final) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Final) Description() string {
+	return "Report the final metric of a series once it stops reporting."
+}
+
+func (f *Final) Add(in telegraf.Metric) {
+	f.cache[in.HashID()] = &lastValue{metric: in, seen: f.now()}
+}
+
+// Push emits the last metric of every series that was not updated within
+// the series timeout, and forgets these series.
+func (f *Final) Push(acc telegraf.Accumulator) {
+	now := f.now()
+	for id, last := range f.cache {
+		if now.Sub(last.seen) < f.SeriesTimeout.Duration {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		for k, v := range last.metric.Fields() {
+			fields[k+"_final"] = v
+		}
+		acc.AddFields(last.metric.Name(), fields, last.metric.Tags(),
+			last.metric.Time())
+		delete(f.cache, id)
+	}
+}
+
+// Reset keeps the last metrics, they are only removed when pushed
+func (f *Final) Reset() {
+}
+
+func init() {
+	aggregators.Add("final", func() telegraf.Aggregator {
+		return NewFinal()
+	})
+}
@@ -0,0 +1,46 @@
+package final
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinalSeriesTimeout(t *testing.T) {
+	now := time.Unix(1460000000, 0)
+	f := NewFinal()
+	f.now = func() time.Time { return now }
+
+	tags := map[string]string{"job": "backup"}
+	m1, _ := telegraf.NewMetric("job", tags,
+		map[string]interface{}{"progress": int64(50)}, now)
+	m2, _ := telegraf.NewMetric("job", tags,
+		map[string]interface{}{"progress": int64(100)}, now.Add(time.Minute))
+
+	f.Add(m1)
+	now = now.Add(time.Minute)
+	f.Add(m2)
+
+	// The series is still reporting
+	acc := testutil.Accumulator{}
+	now = now.Add(4 * time.Minute)
+	f.Push(&acc)
+	f.Reset()
+	assert.Equal(t, 0, len(acc.Metrics))
+
+	now = now.Add(time.Minute)
+	f.Push(&acc)
+	f.Reset()
+	acc.AssertContainsTaggedFields(t, "job",
+		map[string]interface{}{"progress_final": int64(100)}, tags)
+	assert.Equal(t, m2.Time(), acc.Metrics[0].Time)
+
+	// The final metric is only emitted once
+	acc = testutil.Accumulator{}
+	now = now.Add(10 * time.Minute)
+	f.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
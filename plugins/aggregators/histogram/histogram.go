@@ -0,0 +1,216 @@
+package histogram
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+// bucketTag is the tag holding the upper bound of each bucket
+const bucketTag = "le"
+
+// bucketInf is the value of the bucket tag for the last, unbounded bucket
+const bucketInf = "+Inf"
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # If true, the histogram counts are reset at the end of every period,
+  # otherwise they keep growing for as long as telegraf runs.
+  reset = false
+
+  # If true, each bucket counts all values less than or equal to its upper
+  # bound, like Prometheus histograms. If false, each bucket only counts the
+  # values between the previous bound and its own.
+  cumulative = true
+
+  # Example config that aggregates all fields of the metric.
+  # [[aggregators.histogram.config]]
+  #   # The set of buckets.
+  #   buckets = [0.0, 15.6, 34.5, 49.1, 71.5, 80.5, 94.5, 100.0]
+  #   # The name of metric.
+  #   measurement_name = "cpu"
+
+  # Example config that aggregates only specific fields of the metric.
+  # [[aggregators.histogram.config]]
+  #   # The set of buckets.
+  #   buckets = [0.0, 10.0, 20.0, 30.0, 40.0, 50.0, 60.0, 70.0, 80.0, 90.0, 100.0]
+  #   # The name of metric.
+  #   measurement_name = "diskio"
+  #   # The concrete fields of metric
+  #   fields = ["io_time", "read_time", "write_time"]
+`
+
+type HistogramAggregator struct {
+	Configs    []config `toml:"config"`
+	ResetAll   bool     `toml:"reset"`
+	Cumulative bool
+
+	buckets map[string]map[string][]float64
+	cache   map[uint64]metricHistogram
+}
+
+// config is the config of a single histogram
+type config struct {
+	Metric  string `toml:"measurement_name"`
+	Fields  []string
+	Buckets []float64
+}
+
+// metricHistogram holds the bucket counts of every field of a series
+type metricHistogram struct {
+	name   string
+	tags   map[string]string
+	fields map[string][]int64
+}
+
+func NewHistogramAggregator() *HistogramAggregator {
+	return &HistogramAggregator{
+		Cumulative: true,
+		cache:      make(map[uint64]metricHistogram),
+	}
+}
+
+func (h *HistogramAggregator) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HistogramAggregator) Description() string {
+	return "Create cumulative histograms of the values of metric fields."
+}
+
+func (h *HistogramAggregator) Add(in telegraf.Metric) {
+	if h.buckets == nil {
+		h.buckets = h.parseConfigs()
+	}
+
+	fieldBuckets, ok := h.buckets[in.Name()]
+	if !ok {
+		return
+	}
+
+	id := in.HashID()
+	agr, ok := h.cache[id]
+	if !ok {
+		agr = metricHistogram{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string][]int64),
+		}
+		h.cache[id] = agr
+	}
+
+	for field, value := range in.Fields() {
+		buckets, ok := fieldBuckets[field]
+		if !ok {
+			// an empty field name applies the buckets to all fields
+			buckets, ok = fieldBuckets[""]
+		}
+		if !ok {
+			continue
+		}
+
+		v, ok := convert(value)
+		if !ok {
+			continue
+		}
+
+		counts, ok := agr.fields[field]
+		if !ok {
+			// one more than the bounds, for the +Inf bucket
+			counts = make([]int64, len(buckets)+1)
+			agr.fields[field] = counts
+		}
+		counts[sort.SearchFloat64s(buckets, v)]++
+	}
+}
+
+func (h *HistogramAggregator) Push(acc telegraf.Accumulator) {
+	for _, agr := range h.cache {
+		for field, counts := range agr.fields {
+			buckets := h.buckets[agr.name][field]
+			if buckets == nil {
+				buckets = h.buckets[agr.name][""]
+			}
+
+			var count int64
+			for i, c := range counts {
+				if h.Cumulative {
+					count += c
+				} else {
+					count = c
+				}
+
+				tags := make(map[string]string)
+				for k, v := range agr.tags {
+					tags[k] = v
+				}
+				if i < len(buckets) {
+					tags[bucketTag] = strconv.FormatFloat(buckets[i], 'f', -1, 64)
+				} else {
+					tags[bucketTag] = bucketInf
+				}
+
+				acc.AddFields(agr.name,
+					map[string]interface{}{field + "_bucket": count}, tags)
+			}
+		}
+	}
+}
+
+// Reset clears the histogram counts if reset is configured, otherwise the
+// counts accumulate over all periods.
+func (h *HistogramAggregator) Reset() {
+	if h.ResetAll {
+		h.cache = make(map[uint64]metricHistogram)
+	}
+}
+
+// parseConfigs returns the sorted bucket bounds of every configured field,
+// by metric name and field name. The empty field name stands for all fields
+// of the metric.
+func (h *HistogramAggregator) parseConfigs() map[string]map[string][]float64 {
+	buckets := make(map[string]map[string][]float64)
+	for _, c := range h.Configs {
+		bounds := make([]float64, len(c.Buckets))
+		copy(bounds, c.Buckets)
+		sort.Float64s(bounds)
+
+		if _, ok := buckets[c.Metric]; !ok {
+			buckets[c.Metric] = make(map[string][]float64)
+		}
+		if len(c.Fields) == 0 {
+			buckets[c.Metric][""] = bounds
+		}
+		for _, field := range c.Fields {
+			buckets[c.Metric][field] = bounds
+		}
+	}
+	return buckets
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("histogram", func() telegraf.Aggregator {
+		return NewHistogramAggregator()
+	})
+}
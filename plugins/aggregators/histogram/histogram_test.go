@@ -0,0 +1,106 @@
+package histogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "localhost"}, fields, time.Now())
+	return m
+}
+
+func bucketTags(le string) map[string]string {
+	return map[string]string{"host": "localhost", bucketTag: le}
+}
+
+// Test that values are counted in cumulative buckets
+func TestHistogramCumulative(t *testing.T) {
+	h := NewHistogramAggregator()
+	h.Configs = []config{
+		{Metric: "cpu", Fields: []string{"usage"}, Buckets: []float64{50, 10}},
+	}
+	h.Add(newMetric(map[string]interface{}{"usage": float64(5), "other": 1.0}))
+	h.Add(newMetric(map[string]interface{}{"usage": int64(10)}))
+	h.Add(newMetric(map[string]interface{}{"usage": float64(20)}))
+	h.Add(newMetric(map[string]interface{}{"usage": float64(90)}))
+
+	acc := testutil.Accumulator{}
+	h.Push(&acc)
+
+	assert.Equal(t, 3, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(2)}, bucketTags("10"))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(3)}, bucketTags("50"))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(4)}, bucketTags("+Inf"))
+}
+
+// Test that non-cumulative buckets only count the values within their bounds
+func TestHistogramNonCumulative(t *testing.T) {
+	h := NewHistogramAggregator()
+	h.Cumulative = false
+	h.Configs = []config{
+		{Metric: "cpu", Buckets: []float64{10, 50}},
+	}
+	h.Add(newMetric(map[string]interface{}{"usage": float64(5)}))
+	h.Add(newMetric(map[string]interface{}{"usage": float64(20)}))
+	h.Add(newMetric(map[string]interface{}{"usage": float64(30)}))
+
+	acc := testutil.Accumulator{}
+	h.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(1)}, bucketTags("10"))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(2)}, bucketTags("50"))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(0)}, bucketTags("+Inf"))
+}
+
+// Test that counts are only cleared between periods if reset is set
+func TestHistogramReset(t *testing.T) {
+	h := NewHistogramAggregator()
+	h.Configs = []config{
+		{Metric: "cpu", Buckets: []float64{10}},
+	}
+	h.Add(newMetric(map[string]interface{}{"usage": float64(5)}))
+	h.Reset()
+	h.Add(newMetric(map[string]interface{}{"usage": float64(5)}))
+
+	acc := testutil.Accumulator{}
+	h.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_bucket": int64(2)}, bucketTags("10"))
+
+	h.ResetAll = true
+	h.Reset()
+	acc = testutil.Accumulator{}
+	h.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+// Test that metrics without a configured histogram are ignored
+func TestHistogramIgnoresOtherMetrics(t *testing.T) {
+	h := NewHistogramAggregator()
+	h.Configs = []config{
+		{Metric: "mem", Buckets: []float64{10}},
+	}
+	h.Add(newMetric(map[string]interface{}{"usage": float64(5)}))
+
+	acc := testutil.Accumulator{}
+	h.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestHistogramConvertUnsigned(t *testing.T) {
+	v, ok := convert(uint64(1) << 63)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1<<63), v)
+}
@@ -0,0 +1,89 @@
+package merge
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = true
+`
+
+type Merge struct {
+	cache map[seriesKey]*aggregate
+	// order is the order in which the merged metrics were first seen, so
+	// they are pushed in the order they arrived
+	order []seriesKey
+}
+
+// seriesKey identifies the metrics to merge, by series and timestamp
+type seriesKey struct {
+	id uint64
+	t  int64
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]interface{}
+	t      time.Time
+}
+
+func NewMerge() *Merge {
+	return &Merge{
+		cache: make(map[seriesKey]*aggregate),
+	}
+}
+
+func (m *Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Merge) Description() string {
+	return "Merge metrics into multifield metrics by series key"
+}
+
+func (m *Merge) Add(in telegraf.Metric) {
+	key := seriesKey{id: in.HashID(), t: in.UnixNano()}
+	a, ok := m.cache[key]
+	if !ok {
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]interface{}),
+			t:      in.Time(),
+		}
+		m.cache[key] = a
+		m.order = append(m.order, key)
+	}
+
+	// Fields of later metrics replace the fields of earlier ones
+	for k, v := range in.Fields() {
+		a.fields[k] = v
+	}
+}
+
+func (m *Merge) Push(acc telegraf.Accumulator) {
+	for _, key := range m.order {
+		a := m.cache[key]
+		acc.AddFields(a.name, a.fields, a.tags, a.t)
+	}
+}
+
+func (m *Merge) Reset() {
+	m.cache = make(map[seriesKey]*aggregate)
+	m.order = nil
+}
+
+func init() {
+	aggregators.Add("merge", func() telegraf.Aggregator {
+		return NewMerge()
+	})
+}
@@ -0,0 +1,65 @@
+package merge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(
+	tags map[string]string,
+	fields map[string]interface{},
+	t time.Time,
+) telegraf.Metric {
+	m, _ := telegraf.NewMetric("cpu", tags, fields, t)
+	return m
+}
+
+func TestMergeSameSeries(t *testing.T) {
+	now := time.Unix(1460000000, 0)
+	tags := map[string]string{"cpu": "cpu0"}
+
+	m := NewMerge()
+	m.Add(newMetric(tags, map[string]interface{}{"usage_idle": float64(42)}, now))
+	m.Add(newMetric(tags, map[string]interface{}{"usage_user": float64(10)}, now))
+
+	acc := testutil.Accumulator{}
+	m.Push(&acc)
+
+	assert.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{
+			"usage_idle": float64(42),
+			"usage_user": float64(10),
+		},
+		tags)
+	assert.Equal(t, now, acc.Metrics[0].Time)
+}
+
+// Test that metrics with different tags or timestamps are not merged
+func TestMergeDifferentSeries(t *testing.T) {
+	now := time.Unix(1460000000, 0)
+	cpu0 := map[string]string{"cpu": "cpu0"}
+	cpu1 := map[string]string{"cpu": "cpu1"}
+
+	m := NewMerge()
+	m.Add(newMetric(cpu0, map[string]interface{}{"usage_idle": float64(42)}, now))
+	m.Add(newMetric(cpu1, map[string]interface{}{"usage_idle": float64(43)}, now))
+	m.Add(newMetric(cpu0, map[string]interface{}{"usage_idle": float64(44)},
+		now.Add(time.Second)))
+
+	acc := testutil.Accumulator{}
+	m.Push(&acc)
+	assert.Equal(t, 3, len(acc.Metrics))
+	assert.Equal(t, float64(42), acc.Metrics[0].Fields["usage_idle"])
+	assert.Equal(t, float64(43), acc.Metrics[1].Fields["usage_idle"])
+	assert.Equal(t, float64(44), acc.Metrics[2].Fields["usage_idle"])
+
+	m.Reset()
+	acc = testutil.Accumulator{}
+	m.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
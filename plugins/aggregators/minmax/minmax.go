@@ -0,0 +1,110 @@
+package minmax
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+`
+
+type MinMax struct {
+	cache map[uint64]aggregate
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]minmax
+}
+
+type minmax struct {
+	min float64
+	max float64
+}
+
+func NewMinMax() *MinMax {
+	return &MinMax{
+		cache: make(map[uint64]aggregate),
+	}
+}
+
+func (m *MinMax) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *MinMax) Description() string {
+	return "Keep the aggregate min/max of each metric passing through."
+}
+
+func (m *MinMax) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := m.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]minmax),
+		}
+		m.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		mm, ok := a.fields[k]
+		if !ok {
+			a.fields[k] = minmax{min: fv, max: fv}
+			continue
+		}
+		if fv < mm.min {
+			mm.min = fv
+		}
+		if fv > mm.max {
+			mm.max = fv
+		}
+		a.fields[k] = mm
+	}
+}
+
+func (m *MinMax) Push(acc telegraf.Accumulator) {
+	for _, a := range m.cache {
+		fields := make(map[string]interface{})
+		for k, mm := range a.fields {
+			fields[k+"_min"] = mm.min
+			fields[k+"_max"] = mm.max
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (m *MinMax) Reset() {
+	m.cache = make(map[uint64]aggregate)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("minmax", func() telegraf.Aggregator {
+		return NewMinMax()
+	})
+}
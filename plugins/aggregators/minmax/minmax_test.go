@@ -0,0 +1,62 @@
+package minmax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var tags = map[string]string{"foo": "bar"}
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := telegraf.NewMetric("m1", tags, fields, time.Now())
+	return m
+}
+
+func TestMinMax(t *testing.T) {
+	mm := NewMinMax()
+	mm.Add(newMetric(map[string]interface{}{
+		"a": int64(1), "b": float64(-5), "c": "string"}))
+	mm.Add(newMetric(map[string]interface{}{
+		"a": int64(10), "b": float64(-10)}))
+	mm.Add(newMetric(map[string]interface{}{
+		"a": int64(5)}))
+
+	acc := testutil.Accumulator{}
+	mm.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{
+			"a_min": float64(1),
+			"a_max": float64(10),
+			"b_min": float64(-10),
+			"b_max": float64(-5),
+		},
+		tags)
+}
+
+func TestMinMaxReset(t *testing.T) {
+	mm := NewMinMax()
+	mm.Add(newMetric(map[string]interface{}{"a": int64(1)}))
+	mm.Reset()
+	mm.Add(newMetric(map[string]interface{}{"a": int64(5)}))
+
+	acc := testutil.Accumulator{}
+	mm.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1",
+		map[string]interface{}{"a_min": float64(5), "a_max": float64(5)},
+		tags)
+
+	mm.Reset()
+	acc = testutil.Accumulator{}
+	mm.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestMinMaxConvertUnsigned(t *testing.T) {
+	v, ok := convert(uint64(1) << 63)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1<<63), v)
+}
@@ -0,0 +1,160 @@
+package quantile
+
+import (
+	"math"
+	"sort"
+)
+
+// estimator estimates the quantiles of a stream of values
+type estimator interface {
+	Add(value float64)
+	Quantile(q float64) float64
+}
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// tdigest is a merging t-digest, estimating quantiles in bounded memory with
+// a higher accuracy close to the extreme quantiles. See
+// https://github.com/tdunning/t-digest/blob/master/docs/t-digest-paper/histo.pdf
+type tdigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min         float64
+	max         float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+func (t *tdigest) Add(value float64) {
+	t.buffer = append(t.buffer, centroid{mean: value, count: 1})
+	t.count++
+	t.min = math.Min(t.min, value)
+	t.max = math.Max(t.max, value)
+
+	if len(t.buffer) >= int(5*t.compression) {
+		t.merge()
+	}
+}
+
+// merge merges the buffered values into the centroids, making each centroid
+// as large as the scale function allows for its quantile.
+func (t *tdigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+	sort.Sort(byMean(all))
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	current := all[0]
+	// the quantile at the left edge of the current centroid
+	q0 := 0.0
+	kLimit := t.k(q0) + 1
+	for _, c := range all[1:] {
+		q := q0 + (current.count+c.count)/t.count
+		if t.k(q) <= kLimit {
+			current.mean += (c.mean - current.mean) * c.count /
+				(current.count + c.count)
+			current.count += c.count
+			continue
+		}
+		merged = append(merged, current)
+		q0 += current.count / t.count
+		kLimit = t.k(q0) + 1
+		current = c
+	}
+	merged = append(merged, current)
+
+	t.centroids = merged
+	t.buffer = nil
+}
+
+// k is the scale function of the digest, mapping a quantile onto the index
+// of the centroid it belongs to.
+func (t *tdigest) k(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*math.Min(q, 1)-1)
+}
+
+func (t *tdigest) Quantile(q float64) float64 {
+	t.merge()
+
+	switch {
+	case len(t.centroids) == 0:
+		return math.NaN()
+	case q <= 0:
+		return t.min
+	case q >= 1:
+		return t.max
+	case len(t.centroids) == 1:
+		return t.centroids[0].mean
+	}
+
+	// The values of a centroid are assumed to be spread evenly around its
+	// mean, so the mean sits at the middle of the centroid's rank range.
+	target := q * t.count
+	first := t.centroids[0]
+	if target < first.count/2 {
+		return t.min + (first.mean-t.min)*target/(first.count/2)
+	}
+
+	rank := first.count / 2
+	for i := 1; i < len(t.centroids); i++ {
+		prev, c := t.centroids[i-1], t.centroids[i]
+		next := rank + (prev.count+c.count)/2
+		if target < next {
+			return prev.mean + (c.mean-prev.mean)*(target-rank)/(next-rank)
+		}
+		rank = next
+	}
+
+	last := t.centroids[len(t.centroids)-1]
+	return last.mean + (t.max-last.mean)*(target-rank)/(last.count/2)
+}
+
+type byMean []centroid
+
+func (c byMean) Len() int           { return len(c) }
+func (c byMean) Less(i, j int) bool { return c[i].mean < c[j].mean }
+func (c byMean) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// exactR7 keeps all values and computes exact quantiles using the
+// interpolation of type 7 in Hyndman & Fan, the default of R and NumPy.
+type exactR7 struct {
+	values []float64
+	sorted bool
+}
+
+func (e *exactR7) Add(value float64) {
+	e.values = append(e.values, value)
+	e.sorted = false
+}
+
+func (e *exactR7) Quantile(q float64) float64 {
+	if len(e.values) == 0 {
+		return math.NaN()
+	}
+	if !e.sorted {
+		sort.Float64s(e.values)
+		e.sorted = true
+	}
+
+	h := float64(len(e.values)-1) * math.Max(0, math.Min(q, 1))
+	lo := math.Floor(h)
+	i := int(lo)
+	if i+1 >= len(e.values) {
+		return e.values[i]
+	}
+	return e.values[i] + (h-lo)*(e.values[i+1]-e.values[i])
+}
@@ -0,0 +1,193 @@
+package quantile
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # Quantiles to output in the range [0,1]
+  quantiles = [0.25, 0.5, 0.75]
+
+  # Type of aggregation algorithm
+  # Supported are:
+  #  "t-digest" -- approximation using centroids, can cope with large number of samples
+  #  "exact_R7" -- exact computation also used by Excel or NumPy (Hyndman & Fan 1996 R7)
+  # NOTE: Do not use "exact" algorithms with large number of samples
+  #       to not impair performance or memory consumption!
+  algorithm = "t-digest"
+
+  # Compression for approximation (t-digest). The value needs to be
+  # greater or equal to 1.0. Smaller values will result in more
+  # performance but less accuracy.
+  compression = 100.0
+`
+
+type Quantile struct {
+	Quantiles   []float64
+	Algorithm   string
+	Compression float64
+	Log         telegraf.Logger `toml:"-"`
+
+	newEstimator func() estimator
+	suffixes     []string
+	cache        map[uint64]aggregate
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]estimator
+}
+
+func NewQuantile() *Quantile {
+	return &Quantile{
+		Quantiles:   []float64{0.25, 0.5, 0.75},
+		Algorithm:   "t-digest",
+		Compression: 100,
+		cache:       make(map[uint64]aggregate),
+	}
+}
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]estimator),
+		}
+		q.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		e, ok := a.fields[k]
+		if !ok {
+			e = q.newEstimator()
+			a.fields[k] = e
+		}
+		e.Add(fv)
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	for _, a := range q.cache {
+		fields := make(map[string]interface{})
+		for k, e := range a.fields {
+			for i, quantile := range q.Quantiles {
+				fields[k+q.suffixes[i]] = e.Quantile(quantile)
+			}
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+// Init validates the configuration and chooses the estimator, falling back
+// to the defaults on invalid settings. Quantiles with the same field suffix
+// are an error.
+func (q *Quantile) Init() error {
+	switch q.Algorithm {
+	case "", "t-digest":
+	case "exact_R7":
+	default:
+		q.Log.Warnf("Unknown algorithm '%s', using t-digest",
+			q.Algorithm)
+		q.Algorithm = "t-digest"
+	}
+	if q.Compression < 1 {
+		q.Log.Warnf("Compression %f is less than 1, using 100",
+			q.Compression)
+		q.Compression = 100
+	}
+
+	if q.Algorithm == "exact_R7" {
+		q.newEstimator = func() estimator { return &exactR7{} }
+	} else {
+		compression := q.Compression
+		q.newEstimator = func() estimator { return newTDigest(compression) }
+	}
+
+	var quantiles []float64
+	q.suffixes = nil
+	seen := make(map[string]float64)
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			q.Log.Warnf("Quantile %f is not in [0,1], ignoring",
+				quantile)
+			continue
+		}
+		s := suffix(quantile)
+		if other, ok := seen[s]; ok {
+			return fmt.Errorf("quantiles %v and %v are the same field %s",
+				other, quantile, s)
+		}
+		seen[s] = quantile
+		quantiles = append(quantiles, quantile)
+		q.suffixes = append(q.suffixes, s)
+	}
+	q.Quantiles = quantiles
+	return nil
+}
+
+// suffix returns the field name suffix of a quantile, the percentile as
+// three digits followed by its fractional digits, if any, eg. "_050" for the
+// median and "_099.9" for the 0.999 quantile.
+func suffix(quantile float64) string {
+	// Round away the error of the float multiplication, ie 0.995*100
+	percentile := math.Floor(quantile*100*1e6+0.5) / 1e6
+	whole := math.Floor(percentile)
+	s := fmt.Sprintf("_%03d", int(whole))
+	if percentile == whole {
+		return s
+	}
+	fraction := strconv.FormatFloat(percentile-whole, 'f', 6, 64)
+	return s + "." + strings.TrimRight(fraction[2:], "0")
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
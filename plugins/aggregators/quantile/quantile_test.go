@@ -0,0 +1,105 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExactR7(t *testing.T) {
+	e := &exactR7{}
+	for _, v := range []float64{5, 1, 4, 2, 3} {
+		e.Add(v)
+	}
+
+	assert.Equal(t, float64(1), e.Quantile(0))
+	assert.Equal(t, float64(2), e.Quantile(0.25))
+	assert.Equal(t, float64(3), e.Quantile(0.5))
+	assert.Equal(t, float64(4.6), e.Quantile(0.9))
+	assert.Equal(t, float64(5), e.Quantile(1))
+}
+
+// Test that the t-digest approximates the quantiles of a large uniform sample
+func TestTDigestAccuracy(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	d := newTDigest(100)
+	for i := 0; i < 100000; i++ {
+		d.Add(r.Float64() * 1000)
+	}
+
+	assert.InDelta(t, 10, d.Quantile(0.01), 2)
+	assert.InDelta(t, 500, d.Quantile(0.5), 10)
+	assert.InDelta(t, 990, d.Quantile(0.99), 2)
+	assert.True(t, len(d.centroids) < 500)
+}
+
+func TestTDigestSmallSample(t *testing.T) {
+	d := newTDigest(100)
+	assert.True(t, math.IsNaN(d.Quantile(0.5)))
+
+	for _, v := range []float64{1, 2, 3} {
+		d.Add(v)
+	}
+	assert.Equal(t, float64(1), d.Quantile(0))
+	assert.Equal(t, float64(2), d.Quantile(0.5))
+	assert.Equal(t, float64(3), d.Quantile(1))
+}
+
+func TestQuantileAggregator(t *testing.T) {
+	q := NewQuantile()
+	q.Log = testutil.Logger{}
+	q.Algorithm = "exact_R7"
+	q.Quantiles = []float64{0.5, 0.99, 0.995, 1.5}
+	require.NoError(t, q.Init())
+
+	tags := map[string]string{"host": "localhost"}
+	for _, v := range []int64{1, 2, 3} {
+		m, _ := telegraf.NewMetric("latency", tags,
+			map[string]interface{}{"value": v, "text": "foo"}, time.Now())
+		q.Add(m)
+	}
+
+	acc := testutil.Accumulator{}
+	q.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "latency",
+		map[string]interface{}{
+			"value_050":   float64(2),
+			"value_099":   float64(2.98),
+			"value_099.5": float64(2.99),
+		},
+		tags)
+
+	q.Reset()
+	acc = testutil.Accumulator{}
+	q.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestQuantileConvertUnsigned(t *testing.T) {
+	v, ok := convert(uint64(1) << 63)
+	assert.True(t, ok)
+	assert.Equal(t, float64(1<<63), v)
+}
+
+func TestQuantileSuffix(t *testing.T) {
+	assert.Equal(t, "_000", suffix(0))
+	assert.Equal(t, "_050", suffix(0.5))
+	assert.Equal(t, "_099", suffix(0.99))
+	assert.Equal(t, "_099.5", suffix(0.995))
+	assert.Equal(t, "_099.9", suffix(0.999))
+	assert.Equal(t, "_002.5", suffix(0.025))
+	assert.Equal(t, "_100", suffix(1))
+}
+
+func TestQuantileDuplicates(t *testing.T) {
+	q := NewQuantile()
+	q.Log = testutil.Logger{}
+	q.Quantiles = []float64{0.5, 0.999, 0.9990000001}
+	assert.Error(t, q.Init())
+}
@@ -0,0 +1,20 @@
+package aggregators
+
+import "github.com/influxdata/telegraf"
+
+type Creator func() telegraf.Aggregator
+
+var Aggregators = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Aggregators[name] = creator
+}
+
+// Deprecations holds the deprecated aggregators, warned about when configured
+var Deprecations = map[string]telegraf.DeprecationInfo{}
+
+// AddDeprecated registers a deprecated aggregator
+func AddDeprecated(name string, creator Creator, info telegraf.DeprecationInfo) {
+	Add(name, creator)
+	Deprecations[name] = info
+}
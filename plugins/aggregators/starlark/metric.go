@@ -0,0 +1,232 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"go.starlark.net/starlark"
+)
+
+// Metric is the starlark representation of a telegraf metric. Unlike
+// telegraf metrics it is mutable, until it is frozen.
+type Metric struct {
+	name   string
+	tags   *starlark.Dict
+	fields *starlark.Dict
+	time   int64
+	frozen bool
+
+	// valueType is the value type of the telegraf metric converted, zero for
+	// metrics created by the script
+	valueType telegraf.ValueType
+}
+
+// newMetric converts a telegraf metric into a starlark Metric
+func newMetric(m telegraf.Metric) (*Metric, error) {
+	sm := &Metric{
+		name:   m.Name(),
+		tags:   new(starlark.Dict),
+		fields: new(starlark.Dict),
+		time:   m.UnixNano(),
+
+		valueType: m.Type(),
+	}
+	for k, v := range m.Tags() {
+		if err := sm.tags.SetKey(starlark.String(k), starlark.String(v)); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range m.Fields() {
+		sv, err := toStarlark(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", k, err)
+		}
+		if err := sm.fields.SetKey(starlark.String(k), sv); err != nil {
+			return nil, err
+		}
+	}
+	return sm, nil
+}
+
+// toMetric converts the starlark Metric back into a telegraf metric. Metrics
+// without a time get the current time.
+func (m *Metric) toMetric() (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for _, item := range m.tags.Items() {
+		k, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("tag key %s is not a string", item[0])
+		}
+		v, ok := starlark.AsString(item[1])
+		if !ok {
+			return nil, fmt.Errorf("tag %s: value %s is not a string", k, item[1])
+		}
+		tags[k] = v
+	}
+
+	fields := make(map[string]interface{})
+	for _, item := range m.fields.Items() {
+		k, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("field key %s is not a string", item[0])
+		}
+		v, err := fromStarlark(item[1])
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", k, err)
+		}
+		fields[k] = v
+	}
+
+	t := time.Now()
+	if m.time != 0 {
+		t = time.Unix(0, m.time)
+	}
+	if m.valueType != 0 {
+		return telegraf.NewTypedMetric(m.valueType, m.name, tags, fields, t)
+	}
+	return telegraf.NewMetric(m.name, tags, fields, t)
+}
+
+func (m *Metric) String() string {
+	return fmt.Sprintf("Metric(%q, tags=%s, fields=%s, time=%d)",
+		m.name, m.tags.String(), m.fields.String(), m.time)
+}
+
+func (m *Metric) Type() string {
+	return "Metric"
+}
+
+func (m *Metric) Freeze() {
+	m.frozen = true
+	m.tags.Freeze()
+	m.fields.Freeze()
+}
+
+func (m *Metric) Truth() starlark.Bool {
+	return true
+}
+
+func (m *Metric) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+func (m *Metric) AttrNames() []string {
+	return []string{"name", "tags", "fields", "time"}
+}
+
+func (m *Metric) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "name":
+		return starlark.String(m.name), nil
+	case "tags":
+		return m.tags, nil
+	case "fields":
+		return m.fields, nil
+	case "time":
+		return starlark.MakeInt64(m.time), nil
+	default:
+		// Returning nil, nil indicates "no such field or method"
+		return nil, nil
+	}
+}
+
+func (m *Metric) SetField(name string, value starlark.Value) error {
+	if m.frozen {
+		return errors.New("cannot modify frozen metric")
+	}
+
+	switch name {
+	case "name":
+		s, ok := starlark.AsString(value)
+		if !ok {
+			return fmt.Errorf("type error: name must be a string, not %s",
+				value.Type())
+		}
+		m.name = s
+	case "time":
+		t, ok := value.(starlark.Int)
+		if !ok {
+			return fmt.Errorf("type error: time must be an int, not %s",
+				value.Type())
+		}
+		ns, ok := t.Int64()
+		if !ok {
+			return errors.New("time is out of range")
+		}
+		m.time = ns
+	case "tags", "fields":
+		d, ok := value.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("type error: %s must be a dict, not %s",
+				name, value.Type())
+		}
+		copied := new(starlark.Dict)
+		for _, item := range d.Items() {
+			copied.SetKey(item[0], item[1])
+		}
+		if name == "tags" {
+			m.tags = copied
+		} else {
+			m.fields = copied
+		}
+	default:
+		return starlark.NoSuchAttrError(
+			fmt.Sprintf("cannot assign to field '%s'", name))
+	}
+	return nil
+}
+
+// deepcopy returns an unfrozen copy of the metric
+func (m *Metric) deepcopy() *Metric {
+	c := &Metric{
+		name:   m.name,
+		tags:   new(starlark.Dict),
+		fields: new(starlark.Dict),
+		time:   m.time,
+	}
+	for _, item := range m.tags.Items() {
+		c.tags.SetKey(item[0], item[1])
+	}
+	for _, item := range m.fields.Items() {
+		c.fields.SetKey(item[0], item[1])
+	}
+	return c
+}
+
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case float64:
+		return starlark.Float(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case string:
+		return starlark.String(v), nil
+	case bool:
+		return starlark.Bool(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, errors.New("int is out of range")
+		}
+		return i, nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bool:
+		return bool(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", v.Type())
+	}
+}
@@ -0,0 +1,240 @@
+package starlark
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"go.starlark.net/starlark"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # The Starlark source can be set as a string in this configuration file, or
+  # by referencing a file containing the script. Only one source or script
+  # should be set at once.
+  #
+  # Source of the Starlark script. It must define the functions add(metric),
+  # push() and reset(). Global variables are frozen once the script is loaded,
+  # the predeclared "state" dict keeps the state of the aggregation instead.
+  source = '''
+def add(metric):
+  state["last"] = metric
+
+def push():
+  return state.get("last")
+
+def reset():
+  state.clear()
+'''
+
+  # File containing a Starlark script.
+  # script = "/usr/local/bin/myscript.star"
+
+  # The constants of the Starlark script.
+  # [aggregators.starlark.constants]
+  #   max_size = 10
+  #   threshold = 0.75
+  #   default_name = "Julia"
+  #   debug_mode = true
+`
+
+type Starlark struct {
+	Source    string
+	Script    string
+	Constants map[string]interface{}
+	Log       telegraf.Logger `toml:"-"`
+
+	thread *starlark.Thread
+	state  *starlark.Dict
+	add    starlark.Value
+	push   starlark.Value
+	reset  starlark.Value
+}
+
+func NewStarlark() *Starlark {
+	return &Starlark{}
+}
+
+func (s *Starlark) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Starlark) Description() string {
+	return "Aggregate metrics using a Starlark script"
+}
+
+// Init compiles and runs the script, which must define the add, push and
+// reset functions.
+func (s *Starlark) Init() error {
+	return s.compile()
+}
+
+func (s *Starlark) Add(in telegraf.Metric) {
+	m, err := newMetric(in)
+	if err != nil {
+		s.Log.Errorf("Unable to convert metric %s: %s", in.Name(), err)
+		return
+	}
+	if _, err := s.call(s.add, m); err != nil {
+		s.Log.Errorf("Error in add: %s", err)
+	}
+}
+
+func (s *Starlark) Push(acc telegraf.Accumulator) {
+	rv, err := s.call(s.push)
+	if err != nil {
+		s.Log.Errorf("Error in push: %s", err)
+		return
+	}
+
+	var values []starlark.Value
+	switch rv := rv.(type) {
+	case starlark.NoneType:
+	case *Metric:
+		values = append(values, rv)
+	case *starlark.List:
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, rv.Index(i))
+		}
+	default:
+		s.Log.Errorf("Push must return a Metric, a list of "+
+			"Metrics or None, not %s", rv.Type())
+		return
+	}
+
+	for _, v := range values {
+		sm, ok := v.(*Metric)
+		if !ok {
+			s.Log.Errorf("Push returned %s instead of a Metric",
+				v.Type())
+			continue
+		}
+		m, err := sm.toMetric()
+		if err != nil {
+			s.Log.Errorf("Unable to convert metric %s: %s",
+				sm.name, err)
+			continue
+		}
+		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+}
+
+func (s *Starlark) Reset() {
+	if _, err := s.call(s.reset); err != nil {
+		s.Log.Errorf("Error in reset: %s", err)
+	}
+}
+
+func (s *Starlark) compile() error {
+	var src interface{}
+	filename := "aggregators.starlark"
+	switch {
+	case s.Source != "" && s.Script != "":
+		return fmt.Errorf("both source and script are set")
+	case s.Source != "":
+		src = s.Source
+	case s.Script != "":
+		b, err := ioutil.ReadFile(s.Script)
+		if err != nil {
+			return fmt.Errorf("unable to read script: %s", err)
+		}
+		src = b
+		filename = s.Script
+	default:
+		return fmt.Errorf("one of source or script must be set")
+	}
+
+	s.state = new(starlark.Dict)
+	predeclared := starlark.StringDict{
+		"Metric":   starlark.NewBuiltin("Metric", newMetricBuiltin),
+		"deepcopy": starlark.NewBuiltin("deepcopy", deepcopyBuiltin),
+		"state":    s.state,
+	}
+	for k, v := range s.Constants {
+		sv, err := toStarlark(v)
+		if err != nil {
+			return fmt.Errorf("constant %s: %s", k, err)
+		}
+		predeclared[k] = sv
+	}
+
+	s.thread = &starlark.Thread{
+		Print: func(_ *starlark.Thread, msg string) {
+			s.Log.Info(msg)
+		},
+	}
+	globals, err := starlark.ExecFile(s.thread, filename, src, predeclared)
+	if err != nil {
+		return err
+	}
+
+	for name, fn := range map[string]*starlark.Value{
+		"add":   &s.add,
+		"push":  &s.push,
+		"reset": &s.reset,
+	} {
+		v, ok := globals[name]
+		if !ok {
+			return fmt.Errorf("the script must define a %s function", name)
+		}
+		if _, ok := v.(*starlark.Function); !ok {
+			return fmt.Errorf("%s must be a function, not %s", name, v.Type())
+		}
+		*fn = v
+	}
+	return nil
+}
+
+func (s *Starlark) call(fn starlark.Value, args ...starlark.Value) (starlark.Value, error) {
+	rv, err := starlark.Call(s.thread, fn, starlark.Tuple(args), nil)
+	if err, ok := err.(*starlark.EvalError); ok {
+		return nil, fmt.Errorf("%s", err.Backtrace())
+	}
+	return rv, err
+}
+
+// newMetricBuiltin implements Metric(name), creating an empty metric
+func newMetricBuiltin(
+	_ *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var name starlark.String
+	if err := starlark.UnpackPositionalArgs("Metric", args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+	return &Metric{
+		name:   string(name),
+		tags:   new(starlark.Dict),
+		fields: new(starlark.Dict),
+	}, nil
+}
+
+// deepcopyBuiltin implements deepcopy(metric), returning a modifiable copy
+func deepcopyBuiltin(
+	_ *starlark.Thread,
+	_ *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var m *Metric
+	if err := starlark.UnpackPositionalArgs("deepcopy", args, kwargs, 1, &m); err != nil {
+		return nil, err
+	}
+	return m.deepcopy(), nil
+}
+
+func init() {
+	aggregators.Add("starlark", func() telegraf.Aggregator {
+		return NewStarlark()
+	})
+}
@@ -0,0 +1,105 @@
+package starlark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const weightedMean = `
+def add(metric):
+  w = metric.fields["weight"]
+  state["sum"] = state.get("sum", 0.0) + metric.fields["value"] * w
+  state["weight"] = state.get("weight", 0.0) + w
+  state["tags"] = metric.tags
+
+def push():
+  if not state.get("weight"):
+    return None
+  m = Metric("weighted")
+  m.tags = state["tags"]
+  m.tags["window"] = window
+  m.fields["mean"] = state["sum"] / state["weight"]
+  m.time = 1460000000000000000
+  return [m]
+
+def reset():
+  state.clear()
+`
+
+func testMetric(value, weight float64) telegraf.Metric {
+	m, _ := telegraf.NewMetric("request",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": value, "weight": weight},
+		time.Now())
+	return m
+}
+
+func TestStarlarkWeightedMean(t *testing.T) {
+	s := NewStarlark()
+	s.Log = testutil.Logger{}
+	s.Source = weightedMean
+	s.Constants = map[string]interface{}{"window": "1m"}
+	require.NoError(t, s.Init())
+
+	s.Add(testMetric(10, 1))
+	s.Add(testMetric(20, 3))
+
+	acc := testutil.Accumulator{}
+	s.Push(&acc)
+	s.Reset()
+
+	require.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "weighted",
+		map[string]interface{}{"mean": float64(17.5)},
+		map[string]string{"host": "localhost", "window": "1m"})
+	assert.Equal(t, time.Unix(0, 1460000000000000000), acc.Metrics[0].Time)
+
+	// The state was cleared by reset
+	acc = testutil.Accumulator{}
+	s.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
+
+func TestStarlarkDeepcopy(t *testing.T) {
+	s := NewStarlark()
+	s.Log = testutil.Logger{}
+	s.Source = `
+def add(metric):
+  state["last"] = deepcopy(metric)
+
+def push():
+  m = state["last"]
+  m.name = "last_" + m.name
+  return m
+
+def reset():
+  pass
+`
+	require.NoError(t, s.Init())
+	s.Add(testMetric(10, 1))
+
+	acc := testutil.Accumulator{}
+	s.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "last_request",
+		map[string]interface{}{"value": float64(10), "weight": float64(1)},
+		map[string]string{"host": "localhost"})
+}
+
+func TestStarlarkInvalidScripts(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"def add(metric):\n  pass\n",
+		"add = 1\npush = 2\nreset = 3\n",
+		"def add(metric) pass",
+	} {
+		s := NewStarlark()
+		s.Log = testutil.Logger{}
+		s.Source = src
+		assert.Error(t, s.Init(), src)
+	}
+}
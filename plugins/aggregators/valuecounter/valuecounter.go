@@ -0,0 +1,95 @@
+package valuecounter
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const sampleConfig = `
+  # General Aggregator Arguments:
+  # The period on which to flush & clear the aggregator.
+  period = "30s"
+  # If true, the original metric will be dropped by the
+  # aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  # The fields for which the values will be counted
+  fields = ["status"]
+`
+
+type ValueCounter struct {
+	Fields []string
+
+	cache map[uint64]aggregate
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	counts map[string]int64
+}
+
+func NewValueCounter() *ValueCounter {
+	return &ValueCounter{
+		cache: make(map[uint64]aggregate),
+	}
+}
+
+func (vc *ValueCounter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (vc *ValueCounter) Description() string {
+	return "Count the occurrence of values in fields."
+}
+
+func (vc *ValueCounter) Add(in telegraf.Metric) {
+	id := in.HashID()
+	a, ok := vc.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			counts: make(map[string]int64),
+		}
+		vc.cache[id] = a
+	}
+
+	fields := in.Fields()
+	for _, field := range vc.Fields {
+		v, ok := fields[field]
+		if !ok {
+			continue
+		}
+		// Counting floats would create a field for almost every value
+		if _, ok := v.(float64); ok {
+			continue
+		}
+		a.counts[fmt.Sprintf("%s_%v", field, v)]++
+	}
+}
+
+func (vc *ValueCounter) Push(acc telegraf.Accumulator) {
+	for _, a := range vc.cache {
+		if len(a.counts) == 0 {
+			continue
+		}
+		fields := make(map[string]interface{})
+		for k, count := range a.counts {
+			fields[k] = count
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (vc *ValueCounter) Reset() {
+	vc.cache = make(map[uint64]aggregate)
+}
+
+func init() {
+	aggregators.Add("valuecounter", func() telegraf.Aggregator {
+		return NewValueCounter()
+	})
+}
@@ -0,0 +1,66 @@
+package valuecounter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	m, _ := telegraf.NewMetric("access_log",
+		map[string]string{"host": "localhost"}, fields, time.Now())
+	return m
+}
+
+func TestValueCounter(t *testing.T) {
+	vc := NewValueCounter()
+	vc.Fields = []string{"status", "cached"}
+	vc.Add(newMetric(map[string]interface{}{
+		"status": int64(200), "cached": true, "bytes": int64(10)}))
+	vc.Add(newMetric(map[string]interface{}{
+		"status": int64(200), "cached": false, "bytes": int64(10)}))
+	vc.Add(newMetric(map[string]interface{}{
+		"status": int64(404), "bytes": int64(20)}))
+
+	acc := testutil.Accumulator{}
+	vc.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "access_log",
+		map[string]interface{}{
+			"status_200":   int64(2),
+			"status_404":   int64(1),
+			"cached_true":  int64(1),
+			"cached_false": int64(1),
+		},
+		map[string]string{"host": "localhost"})
+}
+
+func TestValueCounterReset(t *testing.T) {
+	vc := NewValueCounter()
+	vc.Fields = []string{"status"}
+	vc.Add(newMetric(map[string]interface{}{"status": "OK"}))
+	vc.Reset()
+	vc.Add(newMetric(map[string]interface{}{"status": "OK"}))
+
+	acc := testutil.Accumulator{}
+	vc.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "access_log",
+		map[string]interface{}{"status_OK": int64(1)},
+		map[string]string{"host": "localhost"})
+}
+
+// Test that series without any of the configured fields, or with only float
+// values for them, are not pushed
+func TestValueCounterNoFields(t *testing.T) {
+	vc := NewValueCounter()
+	vc.Fields = []string{"status"}
+	vc.Add(newMetric(map[string]interface{}{"bytes": int64(10)}))
+	vc.Add(newMetric(map[string]interface{}{"status": float64(1.5)}))
+
+	acc := testutil.Accumulator{}
+	vc.Push(&acc)
+	assert.Equal(t, 0, len(acc.Metrics))
+}
@@ -0,0 +1,324 @@
+// Package shim runs a telegraf input, processor or output plugin as a
+// standalone program, speaking influx line protocol over stdin and stdout, so
+// that it can be run by the execd input, processor or output of telegraf.
+//
+// This allows plugins to be built and versioned outside of the telegraf
+// repository:
+//
+//	func main() {
+//		s := shim.New()
+//		if err := s.LoadConfig("/etc/myplugin.conf"); err != nil {
+//			log.Fatal(err)
+//		}
+//		if err := s.Run(10 * time.Second); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package shim
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/logger"
+)
+
+// Shim runs a single plugin, added either with one of the Add methods or
+// loaded from a config file.
+type Shim struct {
+	Input     telegraf.Input
+	Processor telegraf.Processor
+	Output    telegraf.Output
+
+	stdin  io.Reader
+	stdout io.Writer
+
+	// writeLock serializes the metrics written to stdout, serialized in buf
+	writeLock sync.Mutex
+	buf       []byte
+}
+
+// New returns a Shim reading from stdin and writing to stdout.
+func New() *Shim {
+	return &Shim{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+	}
+}
+
+// AddInput sets the input plugin to run. On every poll interval, and every
+// time a line is read from stdin, the input is gathered and the metrics are
+// written to stdout.
+func (s *Shim) AddInput(input telegraf.Input) error {
+	if err := s.checkEmpty(); err != nil {
+		return err
+	}
+	logger.SetLoggerOnPlugin(input, logger.NewLogger("inputs", "shim", ""))
+	s.Input = input
+	return nil
+}
+
+// AddProcessor sets the processor plugin to run. The metrics read from stdin
+// are passed through the processor and written to stdout.
+func (s *Shim) AddProcessor(processor telegraf.Processor) error {
+	if err := s.checkEmpty(); err != nil {
+		return err
+	}
+	logger.SetLoggerOnPlugin(processor, logger.NewLogger("processors", "shim", ""))
+	s.Processor = processor
+	return nil
+}
+
+// AddOutput sets the output plugin to run. The metrics read from stdin are
+// written to the output.
+func (s *Shim) AddOutput(output telegraf.Output) error {
+	if err := s.checkEmpty(); err != nil {
+		return err
+	}
+	logger.SetLoggerOnPlugin(output, logger.NewLogger("outputs", "shim", ""))
+	s.Output = output
+	return nil
+}
+
+// LoadConfig loads the plugin from a telegraf config file, which must define
+// exactly one input, processor or output. The plugin must have been
+// registered by importing it, or by calling inputs.Add, processors.Add or
+// outputs.Add. Only the plugin's own options are used, the filters and other
+// options applied by telegraf are ignored.
+func (s *Shim) LoadConfig(path string) error {
+	c := config.NewConfig()
+	if err := c.LoadConfig(path); err != nil {
+		return err
+	}
+
+	if len(c.Inputs)+len(c.Processors)+len(c.Outputs) != 1 {
+		return fmt.Errorf("%s must define exactly one input, processor or "+
+			"output", path)
+	}
+	switch {
+	case len(c.Inputs) == 1:
+		s.Input = c.Inputs[0].Input
+	case len(c.Processors) == 1:
+		s.Processor = c.Processors[0].Processor
+	default:
+		s.Output = c.Outputs[0].Output
+	}
+	return nil
+}
+
+// Run runs the plugin until stdin is closed, after calling its Init method if
+// it is a telegraf.Initializer. Inputs are gathered every pollInterval, if it
+// is not zero, and every time a line is read from stdin.
+func (s *Shim) Run(pollInterval time.Duration) error {
+	for _, plugin := range []interface{}{s.Input, s.Processor, s.Output} {
+		if p, ok := plugin.(telegraf.Initializer); ok {
+			if err := p.Init(); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case s.Input != nil:
+		return s.runInput(pollInterval)
+	case s.Processor != nil:
+		return s.runProcessor()
+	case s.Output != nil:
+		return s.runOutput()
+	}
+	return errors.New("no plugin to run")
+}
+
+func (s *Shim) checkEmpty() error {
+	if s.Input != nil || s.Processor != nil || s.Output != nil {
+		return errors.New("a shim can only run one plugin")
+	}
+	return nil
+}
+
+func (s *Shim) runInput(pollInterval time.Duration) error {
+	if si, ok := s.Input.(telegraf.ServiceInput); ok {
+		if err := si.Start(); err != nil {
+			return err
+		}
+		defer si.Stop()
+	}
+
+	// Every line read from stdin signals a gather, stdin being closed stops
+	// the shim
+	gatherC := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(s.stdin)
+		for scanner.Scan() {
+			gatherC <- struct{}{}
+		}
+		close(gatherC)
+	}()
+
+	var tick <-chan time.Time
+	if pollInterval > 0 {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	acc := &accumulator{shim: s}
+	for {
+		select {
+		case _, ok := <-gatherC:
+			if !ok {
+				return nil
+			}
+		case <-tick:
+		}
+		if err := s.Input.Gather(acc); err != nil {
+			log.Printf("ERROR: gathering metrics: %s\n", err)
+		}
+	}
+}
+
+func (s *Shim) runProcessor() error {
+	sp, ok := s.Processor.(telegraf.ServiceProcessor)
+	if ok {
+		if err := sp.Start(); err != nil {
+			return err
+		}
+	}
+
+	err := s.readMetrics(func(metrics []telegraf.Metric) {
+		for _, m := range s.Processor.Apply(metrics...) {
+			s.writeMetric(m)
+		}
+	})
+
+	// Write the metrics the processor still holds once stopped
+	if ok {
+		sp.Stop()
+		for _, m := range sp.Apply() {
+			s.writeMetric(m)
+		}
+	}
+	return err
+}
+
+func (s *Shim) runOutput() error {
+	if err := s.Output.Connect(); err != nil {
+		return err
+	}
+	defer s.Output.Close()
+
+	return s.readMetrics(func(metrics []telegraf.Metric) {
+		if err := s.Output.Write(metrics); err != nil {
+			log.Printf("ERROR: writing metrics: %s\n", err)
+		}
+	})
+}
+
+// readMetrics calls fn with the metrics of every line read from stdin until
+// it is closed.
+func (s *Shim) readMetrics(fn func([]telegraf.Metric)) error {
+	scanner := bufio.NewScanner(s.stdin)
+	for scanner.Scan() {
+		metrics, err := telegraf.ParseMetrics(scanner.Bytes())
+		if err != nil {
+			log.Printf("ERROR: parsing metrics: %s\n", err)
+		}
+		if len(metrics) > 0 {
+			fn(metrics)
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Shim) writeMetric(m telegraf.Metric) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	s.buf = m.AppendTo(s.buf[:0])
+	s.buf = append(s.buf, '\n')
+	if _, err := s.stdout.Write(s.buf); err != nil {
+		log.Printf("ERROR: writing metric to stdout: %s\n", err)
+	}
+}
+
+// accumulator writes the metrics added by an input to stdout.
+type accumulator struct {
+	shim  *Shim
+	debug bool
+}
+
+func (a *accumulator) Add(
+	measurement string,
+	value interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	fields := map[string]interface{}{"value": value}
+	a.AddFields(measurement, fields, tags, t...)
+}
+
+func (a *accumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (a *accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (a *accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (a *accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	valueType telegraf.ValueType,
+	t ...time.Time,
+) {
+	timestamp := time.Now()
+	if len(t) > 0 {
+		timestamp = t[0]
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+
+	m, err := telegraf.NewTypedMetric(valueType, measurement, tags, fields,
+		timestamp)
+	if err != nil {
+		log.Printf("ERROR: adding metric %s: %s\n", measurement, err)
+		return
+	}
+	a.shim.writeMetric(m)
+}
+
+func (a *accumulator) Debug() bool {
+	return a.debug
+}
+
+func (a *accumulator) SetDebug(debug bool) {
+	a.debug = debug
+}
@@ -0,0 +1,121 @@
+package shim
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testInput struct {
+	gathers int
+}
+
+func (i *testInput) SampleConfig() string { return "" }
+func (i *testInput) Description() string  { return "" }
+func (i *testInput) Gather(acc telegraf.Accumulator) error {
+	i.gathers++
+	acc.AddFields("test", map[string]interface{}{"gathers": i.gathers},
+		map[string]string{"tag": "value"}, time.Unix(1453831884, 0))
+	return nil
+}
+
+type testProcessor struct{}
+
+func (p *testProcessor) SampleConfig() string { return "" }
+func (p *testProcessor) Description() string  { return "" }
+func (p *testProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	var out []telegraf.Metric
+	for _, m := range in {
+		tags := m.Tags()
+		tags["processed"] = "true"
+		pm, _ := telegraf.NewMetric(m.Name(), tags, m.Fields(), m.Time())
+		out = append(out, pm)
+	}
+	return out
+}
+
+type testOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (o *testOutput) Connect() error       { return nil }
+func (o *testOutput) Close() error         { return nil }
+func (o *testOutput) SampleConfig() string { return "" }
+func (o *testOutput) Description() string  { return "" }
+func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func TestShimInput(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	s := New()
+	s.stdin = stdinR
+	s.stdout = stdoutW
+	require.NoError(t, s.AddInput(&testInput{}))
+
+	done := make(chan error)
+	go func() {
+		done <- s.Run(0)
+	}()
+
+	// Every line on stdin triggers a gather
+	stdout := bufio.NewReader(stdoutR)
+	for i := 1; i <= 2; i++ {
+		_, err := stdinW.Write([]byte("\n"))
+		require.NoError(t, err)
+		line, err := stdout.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, "test,tag=value gathers=")
+		assert.True(t, strings.HasSuffix(line, " 1453831884000000000\n"))
+	}
+
+	stdinW.Close()
+	assert.NoError(t, <-done)
+}
+
+func TestShimProcessor(t *testing.T) {
+	stdoutR, stdoutW := io.Pipe()
+
+	s := New()
+	s.stdin = strings.NewReader("cpu,host=a usage=1i 1453831884000000000\n")
+	s.stdout = stdoutW
+	require.NoError(t, s.AddProcessor(&testProcessor{}))
+
+	done := make(chan error)
+	go func() {
+		done <- s.Run(0)
+	}()
+
+	line, err := bufio.NewReader(stdoutR).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t,
+		"cpu,host=a,processed=true usage=1i 1453831884000000000\n", line)
+	assert.NoError(t, <-done)
+}
+
+func TestShimOutput(t *testing.T) {
+	o := &testOutput{}
+	s := New()
+	s.stdin = strings.NewReader("cpu,host=a usage=1i 1453831884000000000\n" +
+		"cpu,host=b usage=2i 1453831884000000000\n")
+	require.NoError(t, s.AddOutput(o))
+	require.NoError(t, s.Run(0))
+
+	require.Len(t, o.metrics, 2)
+	assert.Equal(t, map[string]string{"host": "b"}, o.metrics[1].Tags())
+}
+
+func TestShimOnlyOnePlugin(t *testing.T) {
+	s := New()
+	require.NoError(t, s.AddOutput(&testOutput{}))
+	assert.Error(t, s.AddInput(&testInput{}))
+}
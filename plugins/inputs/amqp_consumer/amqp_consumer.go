@@ -0,0 +1,311 @@
+package amqp_consumer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/streadway/amqp"
+)
+
+type AMQPConsumer struct {
+	// AMQP broker to consume from
+	URL string
+	// Exchange the queue is bound to, and its type
+	Exchange     string
+	ExchangeType string `toml:"exchange_type"`
+	// Queue consumed, and the routing key binding it to the exchange
+	Queue      string
+	BindingKey string `toml:"binding_key"`
+	// Messages delivered and not yet acknowledged, 50 if zero
+	PrefetchCount int `toml:"prefetch_count"`
+	// Metrics buffered between collection intervals, 100000 if zero
+	MetricBuffer int `toml:"metric_buffer"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Options of the parser, see parsers.Config
+	DataFormat     string   `toml:"data_format"`
+	TagKeys        []string `toml:"tag_keys"`
+	CSVColumnNames []string `toml:"csv_column_names"`
+	CSVDelimiter   string   `toml:"csv_delimiter"`
+	XMLPath        string   `toml:"xml_path"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	conn   *amqp.Connection
+	parser telegraf.Parser
+	// channel for all incoming parsed AMQP metrics
+	metricC chan telegraf.Metric
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var sampleConfig = `
+  # AMQP url
+  url = "amqp://localhost:5672/influxdb"
+  # Exchange the queue is bound to, declared if missing, and its type
+  exchange = "telegraf"
+  exchange_type = "topic"
+  # Queue consumed, declared if missing, and the routing key binding it to
+  # the exchange
+  queue = "telegraf"
+  binding_key = "#"
+
+  # Messages delivered and not yet acknowledged. The messages are
+  # acknowledged once parsed and buffered until the next collection.
+  prefetch_count = 50
+  # Maximum number of metrics to buffer between collection intervals, the
+  # messages being left unacknowledged while the buffer is full
+  metric_buffer = 100000
+
+  # Use ssl
+  #ssl_ca = "/etc/telegraf/ca.pem"
+  #ssl_cert = "/etc/telegraf/cert.pem"
+  #ssl_key = "/etc/telegraf/key.pem"
+  #insecure_skip_verify = false
+
+  # Data format of the message bodies: influx, json, csv, prometheus or xml
+  data_format = "influx"
+  # Keys tagging the metrics with their value instead of being fields
+  # tag_keys = []
+`
+
+func (a *AMQPConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AMQPConsumer) Description() string {
+	return "Read metrics from an AMQP queue"
+}
+
+func (a *AMQPConsumer) Start() error {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:     a.DataFormat,
+		MetricName:     "amqp_consumer",
+		TagKeys:        a.TagKeys,
+		CSVColumnNames: a.CSVColumnNames,
+		CSVDelimiter:   a.CSVDelimiter,
+		XMLPath:        a.XMLPath,
+	})
+	if err != nil {
+		return err
+	}
+	a.parser = parser
+
+	if a.PrefetchCount == 0 {
+		a.PrefetchCount = 50
+	}
+	if a.MetricBuffer == 0 {
+		a.MetricBuffer = 100000
+	}
+	a.metricC = make(chan telegraf.Metric, a.MetricBuffer)
+	a.done = make(chan struct{})
+
+	msgs, err := a.connect()
+	if err != nil {
+		return err
+	}
+	a.wg.Add(1)
+	go a.process(msgs)
+	a.Log.Infof("Started the AMQP consumer service, url: %s, queue: %s",
+		a.URL, a.Queue)
+	return nil
+}
+
+// connect declares and binds the queue, and consumes it
+func (a *AMQPConsumer) connect() (<-chan amqp.Delivery, error) {
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              a.SSLCA,
+		SSLCert:            a.SSLCert,
+		SSLKey:             a.SSLKey,
+		InsecureSkipVerify: a.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var conn *amqp.Connection
+	if tlsConfig != nil {
+		conn, err = amqp.DialTLS(a.URL, tlsConfig)
+	} else {
+		conn, err = amqp.Dial(a.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := a.consume(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	a.Lock()
+	defer a.Unlock()
+	// Connections opened while stopping are not closed by Stop
+	select {
+	case <-a.done:
+		conn.Close()
+		return nil, errors.New("consumer stopped")
+	default:
+	}
+	a.conn = conn
+	return msgs, nil
+}
+
+func (a *AMQPConsumer) consume(conn *amqp.Connection) (<-chan amqp.Delivery, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open a channel: %s", err)
+	}
+
+	exchangeType := a.ExchangeType
+	if exchangeType == "" {
+		exchangeType = "topic"
+	}
+	err = channel.ExchangeDeclare(
+		a.Exchange,   // name
+		exchangeType, // type
+		true,         // durable
+		false,        // delete when unused
+		false,        // internal
+		false,        // no-wait
+		nil,          // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to declare an exchange: %s", err)
+	}
+
+	queue, err := channel.QueueDeclare(
+		a.Queue, // name
+		true,    // durable
+		false,   // delete when unused
+		false,   // exclusive
+		false,   // no-wait
+		nil,     // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to declare a queue: %s", err)
+	}
+
+	bindingKey := a.BindingKey
+	if bindingKey == "" {
+		bindingKey = "#"
+	}
+	err = channel.QueueBind(queue.Name, bindingKey, a.Exchange, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to bind the queue: %s", err)
+	}
+
+	// Only the prefetch count messages are delivered before being
+	// acknowledged
+	if err := channel.Qos(a.PrefetchCount, 0, false); err != nil {
+		return nil, fmt.Errorf("Failed to set the prefetch count: %s", err)
+	}
+
+	msgs, err := channel.Consume(
+		queue.Name, // queue
+		"",         // consumer
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to consume the queue: %s", err)
+	}
+	return msgs, nil
+}
+
+// process handles the messages delivered, reconnecting when the connection
+// is lost until the consumer is stopped
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery) {
+	defer a.wg.Done()
+	for {
+		for msg := range msgs {
+			if !a.onMessage(msg) {
+				return
+			}
+		}
+
+		a.Log.Info("Connection closed, trying to reconnect")
+		for {
+			select {
+			case <-a.done:
+				return
+			case <-time.After(10 * time.Second):
+			}
+			var err error
+			if msgs, err = a.connect(); err == nil {
+				break
+			}
+			a.Log.Error(err)
+		}
+	}
+}
+
+// onMessage parses a message, acknowledging it once its metrics are
+// buffered. It returns false when the consumer is stopped before.
+func (a *AMQPConsumer) onMessage(msg amqp.Delivery) bool {
+	metrics, err := a.parser.Parse(msg.Body)
+	if err != nil {
+		a.Log.Errorf("Could not parse AMQP message: %s, error: %s",
+			string(msg.Body), err)
+		// Invalid messages are rejected, and not redelivered
+		if len(metrics) == 0 {
+			if err := msg.Reject(false); err != nil {
+				a.Log.Errorf("Could not reject AMQP message: %s", err)
+			}
+			return true
+		}
+	}
+
+	for _, metric := range metrics {
+		select {
+		case a.metricC <- metric:
+		case <-a.done:
+			// The message is redelivered to the next consumer
+			return false
+		}
+	}
+	if err := msg.Ack(false); err != nil {
+		a.Log.Errorf("Could not acknowledge AMQP message: %s", err)
+	}
+	return true
+}
+
+func (a *AMQPConsumer) Stop() {
+	a.Lock()
+	close(a.done)
+	conn := a.conn
+	a.Unlock()
+	if err := conn.Close(); err != nil && err != amqp.ErrClosed {
+		a.Log.Errorf("Error closing AMQP connection: %s", err)
+	}
+	a.wg.Wait()
+}
+
+func (a *AMQPConsumer) Gather(acc telegraf.Accumulator) error {
+	nmetrics := len(a.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-a.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("amqp_consumer", func() telegraf.Input {
+		return &AMQPConsumer{}
+	})
+}
@@ -0,0 +1,103 @@
+package amqp_consumer
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/streadway/amqp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testMsg      = "cpu_load_short,host=server01 value=23422.0 1422568543702900257"
+	invalidMsg   = "cpu_load_short,host=server01 1422568543702900257"
+	metricBuffer = 5
+)
+
+// acknowledger records the acknowledgements of the deliveries
+type acknowledger struct {
+	acked    []uint64
+	rejected []uint64
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return nil
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	a.rejected = append(a.rejected, tag)
+	return nil
+}
+
+func newTestAMQPConsumer() *AMQPConsumer {
+	return &AMQPConsumer{
+		URL:     "amqp://localhost:5672/influxdb",
+		Log:     testutil.Logger{},
+		parser:  &influx.Influx{},
+		done:    make(chan struct{}),
+		metricC: make(chan telegraf.Metric, metricBuffer),
+	}
+}
+
+// Test that the messages are acknowledged once parsed and buffered
+func TestOnMessage(t *testing.T) {
+	a := newTestAMQPConsumer()
+	ack := &acknowledger{}
+
+	assert.True(t, a.onMessage(amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  1,
+		Body:         []byte(testMsg),
+	}))
+	assert.Equal(t, []uint64{1}, ack.acked)
+
+	acc := testutil.Accumulator{}
+	a.Gather(&acc)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422)},
+		map[string]string{"host": "server01"})
+}
+
+// Test that the invalid messages are rejected
+func TestOnMessageInvalid(t *testing.T) {
+	a := newTestAMQPConsumer()
+	ack := &acknowledger{}
+
+	assert.True(t, a.onMessage(amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  2,
+		Body:         []byte(invalidMsg),
+	}))
+	assert.Empty(t, ack.acked)
+	assert.Equal(t, []uint64{2}, ack.rejected)
+	assert.Equal(t, 0, len(a.metricC))
+}
+
+// Test that the messages are not acknowledged while the buffer is full
+func TestOnMessageBufferFull(t *testing.T) {
+	a := newTestAMQPConsumer()
+	ack := &acknowledger{}
+	for i := 0; i < metricBuffer; i++ {
+		assert.True(t, a.onMessage(amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  uint64(i),
+			Body:         []byte(testMsg),
+		}))
+	}
+
+	close(a.done)
+	assert.False(t, a.onMessage(amqp.Delivery{
+		Acknowledger: ack,
+		DeliveryTag:  metricBuffer,
+		Body:         []byte(testMsg),
+	}))
+	assert.Len(t, ack.acked, metricBuffer)
+}
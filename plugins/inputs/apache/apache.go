@@ -12,16 +12,44 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type Apache struct {
 	Urls []string
+
+	// Options of the HTTP client, see httpconfig.Config
+	Username           string
+	Password           string
+	ResponseTimeout    internal.Duration `toml:"response_timeout"`
+	SSLCA              string            `toml:"ssl_ca"`
+	SSLCert            string            `toml:"ssl_cert"`
+	SSLKey             string            `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	client *http.Client
 }
 
 var sampleConfig = `
   # An array of Apache status URI to gather stats.
   urls = ["http://localhost/server-status?auto"]
+  # Servers listening on a unix socket are queried with a "unix://" URL, ie
+  # "unix:///var/run/apache.sock/server-status?auto"
+
+  # Credentials for basic HTTP authentication
+  # username = "myuser"
+  # password = "mypassword"
+
+  # Timeout of the requests, 5s by default
+  # response_timeout = "5s"
+
+  # TLS options of the https urls
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
 `
 
 func (n *Apache) SampleConfig() string {
@@ -32,8 +60,34 @@ func (n *Apache) Description() string {
 	return "Read Apache status information (mod_status)"
 }
 
+// Init creates the HTTP client of the plugin from its options
+func (n *Apache) Init() error {
+	timeout := n.ResponseTimeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	c := httpconfig.Config{
+		Timeout:  timeout,
+		Username: n.Username,
+		Password: n.Password,
+		TLS: internal.TLSOptions{
+			SSLCA:              n.SSLCA,
+			SSLCert:            n.SSLCert,
+			SSLKey:             n.SSLKey,
+			InsecureSkipVerify: n.InsecureSkipVerify,
+		},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	n.client = client
+	return nil
+}
+
 func (n *Apache) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, u := range n.Urls {
@@ -45,7 +99,11 @@ func (n *Apache) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(addr *url.URL) {
 			defer wg.Done()
-			outerr = n.gatherUrl(addr, acc)
+			if err := n.gatherUrl(addr, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}(addr)
 	}
 
@@ -54,14 +112,8 @@ func (n *Apache) Gather(acc telegraf.Accumulator) error {
 	return outerr
 }
 
-var tr = &http.Transport{
-	ResponseHeaderTimeout: time.Duration(3 * time.Second),
-}
-
-var client = &http.Client{Transport: tr}
-
 func (n *Apache) gatherUrl(addr *url.URL, acc telegraf.Accumulator) error {
-	resp, err := client.Get(addr.String())
+	resp, err := n.client.Get(addr.String())
 	if err != nil {
 		return fmt.Errorf("error making HTTP request to %s: %s", addr.String(), err)
 	}
@@ -149,6 +201,10 @@ func (n *Apache) gatherScores(data string) map[string]interface{} {
 
 // Get tag(s) for the apache plugin
 func getTags(addr *url.URL) map[string]string {
+	if addr.Scheme == "unix" {
+		socket, _, _ := internal.SplitUnixSocketPath(addr.Path)
+		return map[string]string{"server": socket, "port": ""}
+	}
 	h := addr.Host
 	host, port, err := net.SplitHostPort(h)
 	if err != nil {
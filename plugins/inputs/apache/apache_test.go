@@ -38,6 +38,7 @@ func TestHTTPApache(t *testing.T) {
 	a := Apache{
 		Urls: []string{ts.URL},
 	}
+	require.NoError(t, a.Init())
 
 	var acc testutil.Accumulator
 	err := a.Gather(&acc)
@@ -71,3 +72,39 @@ func TestHTTPApache(t *testing.T) {
 	}
 	acc.AssertContainsFields(t, "apache", fields)
 }
+
+func TestHTTPApacheAuthAndTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "telegraf" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "BusyWorkers: 270\nIdleWorkers: 630\n")
+	}))
+	defer ts.Close()
+
+	a := Apache{
+		Urls:               []string{ts.URL},
+		Username:           "telegraf",
+		Password:           "secret",
+		InsecureSkipVerify: true,
+	}
+	require.NoError(t, a.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, a.Gather(&acc))
+	acc.AssertContainsFields(t, "apache", map[string]interface{}{
+		"BusyWorkers": float64(270),
+		"IdleWorkers": float64(630),
+	})
+
+	a = Apache{
+		Urls:               []string{ts.URL},
+		Username:           "telegraf",
+		Password:           "wrong",
+		InsecureSkipVerify: true,
+	}
+	require.NoError(t, a.Init())
+	require.Error(t, a.Gather(&acc))
+}
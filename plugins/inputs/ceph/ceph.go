@@ -0,0 +1,432 @@
+package ceph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs the ceph command with the arguments, killing it after the
+// timeout, and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) ([]byte, error)
+
+// Ceph reads the perf counters of the daemons of the host from their admin
+// sockets, and the status of the cluster with the ceph command
+type Ceph struct {
+	CephBinary             string `toml:"ceph_binary"`
+	OsdPrefix              string `toml:"osd_prefix"`
+	MonPrefix              string `toml:"mon_prefix"`
+	MdsPrefix              string `toml:"mds_prefix"`
+	SocketDir              string `toml:"socket_dir"`
+	SocketSuffix           string `toml:"socket_suffix"`
+	CephUser               string `toml:"ceph_user"`
+	CephConfig             string `toml:"ceph_config"`
+	GatherAdminSocketStats bool   `toml:"gather_admin_socket_stats"`
+	GatherClusterStats     bool   `toml:"gather_cluster_stats"`
+	Timeout                internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of the ceph command
+  ceph_binary = "/usr/bin/ceph"
+
+  # Gather the perf counters of the daemons of the host from their admin
+  # sockets, in the socket directory, named by their prefix, their id and
+  # their suffix, ie ceph-osd.0.asok
+  gather_admin_socket_stats = true
+  socket_dir = "/var/run/ceph"
+  osd_prefix = "ceph-osd"
+  mon_prefix = "ceph-mon"
+  mds_prefix = "ceph-mds"
+  socket_suffix = "asok"
+
+  # Gather the status of the cluster, its usage, its pools and the latency
+  # of its OSDs, as the ceph user with the ceph config. They are reported by
+  # every host, it should be enabled on a single host, ie a monitor.
+  gather_cluster_stats = false
+  ceph_user = "client.admin"
+  ceph_config = "/etc/ceph/ceph.conf"
+
+  # Timeout of the ceph commands
+  # timeout = "10s"
+`
+
+func (c *Ceph) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Ceph) Description() string {
+	return "Collect the perf counters of the ceph daemons and the status of the cluster"
+}
+
+func (c *Ceph) Gather(acc telegraf.Accumulator) error {
+	if c.runner == nil {
+		c.runner = runCeph
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = 10 * time.Second
+	}
+
+	var errorStrings []string
+	if c.GatherAdminSocketStats {
+		if err := c.gatherAdminSocketStats(acc); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if c.GatherClusterStats {
+		for _, stats := range []struct {
+			command []string
+			gather  func(out []byte, acc telegraf.Accumulator) error
+		}{
+			{[]string{"status"}, gatherStatus},
+			{[]string{"df"}, gatherDf},
+			{[]string{"osd", "pool", "stats"}, gatherPoolStats},
+			{[]string{"osd", "perf"}, gatherOsdPerf},
+		} {
+			out, err := c.runCluster(stats.command...)
+			if err == nil {
+				err = stats.gather(out, acc)
+			}
+			if err != nil {
+				errorStrings = append(errorStrings, fmt.Sprintf("ceph %s: %s",
+					strings.Join(stats.command, " "), err))
+			}
+		}
+	}
+
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// daemon is the admin socket of a daemon of the host
+type daemon struct {
+	socket string
+	typ    string
+	id     string
+}
+
+// findSockets returns the admin sockets of the osd, mon and mds daemons in the
+// socket directory
+func (c *Ceph) findSockets() ([]daemon, error) {
+	files, err := ioutil.ReadDir(c.SocketDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading the socket directory: %s", err)
+	}
+	var daemons []daemon
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, "."+c.SocketSuffix) {
+			continue
+		}
+		for typ, prefix := range map[string]string{
+			"osd": c.OsdPrefix,
+			"mon": c.MonPrefix,
+			"mds": c.MdsPrefix,
+		} {
+			if !strings.HasPrefix(name, prefix+".") {
+				continue
+			}
+			daemons = append(daemons, daemon{
+				socket: filepath.Join(c.SocketDir, name),
+				typ:    typ,
+				id: strings.TrimSuffix(strings.TrimPrefix(name, prefix+"."),
+					"."+c.SocketSuffix),
+			})
+		}
+	}
+	return daemons, nil
+}
+
+// gatherAdminSocketStats adds the perf counters of the daemons, a point per
+// collection of counters, the counters of their latencies being flattened
+// into their average count and sum, ie op_latency_avgcount
+func (c *Ceph) gatherAdminSocketStats(acc telegraf.Accumulator) error {
+	daemons, err := c.findSockets()
+	if err != nil {
+		return err
+	}
+
+	var errorStrings []string
+	for _, d := range daemons {
+		out, err := c.runner(c.Timeout.Duration, c.CephBinary,
+			"--admin-daemon", d.socket, "perf", "dump")
+		if err != nil {
+			errorStrings = append(errorStrings, fmt.Sprintf(
+				"perf dump of %s: %s: %s", d.socket, err,
+				strings.TrimSpace(string(out))))
+			continue
+		}
+		var collections map[string]interface{}
+		if err := json.Unmarshal(out, &collections); err != nil {
+			errorStrings = append(errorStrings, fmt.Sprintf(
+				"parsing the perf dump of %s: %s", d.socket, err))
+			continue
+		}
+
+		now := time.Now()
+		for collection, counters := range collections {
+			f := internal.JSONFlattener{}
+			if err := f.FlattenJSON("", counters); err != nil {
+				errorStrings = append(errorStrings, err.Error())
+				continue
+			}
+			if len(f.Fields) == 0 {
+				continue
+			}
+			acc.AddFields("ceph", f.Fields, map[string]string{
+				"type":       d.typ,
+				"id":         d.id,
+				"collection": collection,
+			}, now)
+		}
+	}
+
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// runCluster runs the ceph command on the cluster, as the ceph user with the
+// ceph config, and returns its json output
+func (c *Ceph) runCluster(command ...string) ([]byte, error) {
+	args := []string{"--conf", c.CephConfig, "--name", c.CephUser}
+	args = append(args, command...)
+	args = append(args, "--format", "json")
+	out, err := c.runner(c.Timeout.Duration, c.CephBinary, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
+
+// flatten returns the numeric values of v as fields
+func flatten(v interface{}) (map[string]interface{}, error) {
+	f := internal.JSONFlattener{}
+	if err := f.FlattenJSON("", v); err != nil {
+		return nil, err
+	}
+	return f.Fields, nil
+}
+
+// gatherStatus adds the health of the cluster, and the state of its OSD map
+// and its placement groups, of ceph status
+func gatherStatus(out []byte, acc telegraf.Accumulator) error {
+	var status struct {
+		Health struct {
+			Status        string `json:"status"`
+			OverallStatus string `json:"overall_status"`
+		} `json:"health"`
+		OSDMap map[string]interface{} `json:"osdmap"`
+		PGMap  map[string]interface{} `json:"pgmap"`
+	}
+	if err := json.Unmarshal(out, &status); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	health := map[string]interface{}{
+		"status": status.Health.Status,
+	}
+	if status.Health.OverallStatus != "" {
+		health["overall_status"] = status.Health.OverallStatus
+	}
+	acc.AddFields("ceph_health", health, map[string]string{}, now)
+
+	// The OSD map is nested in an osdmap object before Nautilus
+	osdmap := status.OSDMap
+	if nested, ok := osdmap["osdmap"].(map[string]interface{}); ok {
+		osdmap = nested
+	}
+	fields, err := flatten(osdmap)
+	if err != nil {
+		return err
+	}
+	acc.AddFields("ceph_osdmap", fields, map[string]string{}, now)
+
+	// The placement groups by state are points of ceph_pgmap_state
+	states, _ := status.PGMap["pgs_by_state"].([]interface{})
+	delete(status.PGMap, "pgs_by_state")
+	for _, s := range states {
+		state, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := state["state_name"].(string)
+		acc.AddFields("ceph_pgmap_state", map[string]interface{}{
+			"count": state["count"],
+		}, map[string]string{"state": name}, now)
+	}
+	if fields, err = flatten(status.PGMap); err != nil {
+		return err
+	}
+	acc.AddFields("ceph_pgmap", fields, map[string]string{}, now)
+	return nil
+}
+
+// gatherDf adds the usage of the cluster and of its pools of ceph df
+func gatherDf(out []byte, acc telegraf.Accumulator) error {
+	var df struct {
+		Stats map[string]interface{} `json:"stats"`
+		Pools []struct {
+			Name  string                 `json:"name"`
+			Stats map[string]interface{} `json:"stats"`
+		} `json:"pools"`
+	}
+	if err := json.Unmarshal(out, &df); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fields, err := flatten(df.Stats)
+	if err != nil {
+		return err
+	}
+	acc.AddFields("ceph_usage", fields, map[string]string{}, now)
+	for _, pool := range df.Pools {
+		fields, err := flatten(pool.Stats)
+		if err != nil {
+			return err
+		}
+		acc.AddFields("ceph_pool_usage", fields,
+			map[string]string{"name": pool.Name}, now)
+	}
+	return nil
+}
+
+// gatherPoolStats adds the client and recovery rates of the pools of ceph osd
+// pool stats, which omits the rates of the idle pools
+func gatherPoolStats(out []byte, acc telegraf.Accumulator) error {
+	var pools []struct {
+		Name         string                 `json:"pool_name"`
+		RecoveryRate map[string]interface{} `json:"recovery_rate"`
+		ClientIORate map[string]interface{} `json:"client_io_rate"`
+	}
+	if err := json.Unmarshal(out, &pools); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, pool := range pools {
+		fields := map[string]interface{}{
+			"read_bytes_sec":             float64(0),
+			"write_bytes_sec":            float64(0),
+			"read_op_per_sec":            float64(0),
+			"write_op_per_sec":           float64(0),
+			"recovering_objects_per_sec": float64(0),
+			"recovering_bytes_per_sec":   float64(0),
+			"recovering_keys_per_sec":    float64(0),
+		}
+		for _, rates := range []map[string]interface{}{
+			pool.RecoveryRate,
+			pool.ClientIORate,
+		} {
+			rateFields, err := flatten(rates)
+			if err != nil {
+				return err
+			}
+			for k, v := range rateFields {
+				fields[k] = v
+			}
+		}
+		acc.AddFields("ceph_pool_stats", fields,
+			map[string]string{"name": pool.Name}, now)
+	}
+	return nil
+}
+
+// osdPerfInfo is the latency of an OSD of ceph osd perf
+type osdPerfInfo struct {
+	ID        int `json:"id"`
+	PerfStats struct {
+		CommitLatencyMs float64 `json:"commit_latency_ms"`
+		ApplyLatencyMs  float64 `json:"apply_latency_ms"`
+	} `json:"perf_stats"`
+}
+
+// gatherOsdPerf adds the commit and apply latencies of the OSDs of ceph osd
+// perf, listed in osdstats since Nautilus
+func gatherOsdPerf(out []byte, acc telegraf.Accumulator) error {
+	var perf struct {
+		OSDPerfInfos []osdPerfInfo `json:"osd_perf_infos"`
+		OSDStats     struct {
+			OSDPerfInfos []osdPerfInfo `json:"osd_perf_infos"`
+		} `json:"osdstats"`
+	}
+	if err := json.Unmarshal(out, &perf); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, infos := range [][]osdPerfInfo{
+		perf.OSDPerfInfos,
+		perf.OSDStats.OSDPerfInfos,
+	} {
+		for _, info := range infos {
+			acc.AddFields("ceph_osd_perf", map[string]interface{}{
+				"commit_latency_ms": info.PerfStats.CommitLatencyMs,
+				"apply_latency_ms":  info.PerfStats.ApplyLatencyMs,
+			}, map[string]string{"id": strconv.Itoa(info.ID)}, now)
+		}
+	}
+	return nil
+}
+
+// runCeph runs the ceph command, killing it after the timeout
+func runCeph(timeout time.Duration, path string, args ...string) ([]byte, error) {
+	var out, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return stderr.Bytes(), err
+		}
+		return out.Bytes(), nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return stderr.Bytes(), fmt.Errorf("ceph timed out after %s", timeout)
+	}
+}
+
+func init() {
+	inputs.Add("ceph", func() telegraf.Input {
+		return &Ceph{
+			CephBinary:             "/usr/bin/ceph",
+			OsdPrefix:              "ceph-osd",
+			MonPrefix:              "ceph-mon",
+			MdsPrefix:              "ceph-mds",
+			SocketDir:              "/var/run/ceph",
+			SocketSuffix:           "asok",
+			CephUser:               "client.admin",
+			CephConfig:             "/etc/ceph/ceph.conf",
+			GatherAdminSocketStats: true,
+			GatherClusterStats:     false,
+			Timeout:                internal.Duration{Duration: 10 * time.Second},
+			runner:                 runCeph,
+		}
+	})
+}
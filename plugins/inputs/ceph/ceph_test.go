@@ -0,0 +1,227 @@
+package ceph
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const osdPerfDump = `{
+  "filestore": {
+    "journal_queue_ops": 0,
+    "journal_latency": {"avgcount": 12, "sum": 0.6}
+  },
+  "osd": {
+    "op": 1024,
+    "op_r": 512,
+    "op_latency": {"avgcount": 1024, "sum": 20.48}
+  }
+}`
+
+const monPerfDump = `{"mon": {"num_sessions": 5, "session_add": 8}}`
+
+const clusterStatus = `{
+  "health": {"status": "HEALTH_WARN", "overall_status": "HEALTH_WARN"},
+  "osdmap": {"osdmap": {"epoch": 21, "num_osds": 3, "num_up_osds": 2,
+    "num_in_osds": 3, "full": false, "nearfull": false}},
+  "pgmap": {
+    "pgs_by_state": [
+      {"state_name": "active+clean", "count": 60},
+      {"state_name": "active+undersized+degraded", "count": 4}
+    ],
+    "num_pgs": 64, "num_pools": 1, "num_objects": 1200,
+    "data_bytes": 1048576, "bytes_used": 3145728, "bytes_avail": 1073741824,
+    "bytes_total": 1076887552, "read_bytes_sec": 4096, "write_op_per_sec": 12
+  }
+}`
+
+const clusterDf = `{
+  "stats": {"total_bytes": 1076887552, "total_used_bytes": 3145728,
+    "total_avail_bytes": 1073741824},
+  "pools": [{"name": "rbd", "id": 0,
+    "stats": {"kb_used": 1024, "bytes_used": 1048576, "objects": 1200,
+      "max_avail": 357913941}}]
+}`
+
+const clusterPoolStats = `[
+  {"pool_name": "rbd", "pool_id": 0, "recovery": {},
+    "recovery_rate": {"recovering_objects_per_sec": 3},
+    "client_io_rate": {"read_bytes_sec": 4096, "write_op_per_sec": 12}},
+  {"pool_name": "idle", "pool_id": 1, "recovery": {}, "recovery_rate": {},
+    "client_io_rate": {}}
+]`
+
+const clusterOsdPerf = `{"osdstats": {"osd_perf_infos": [
+  {"id": 0, "perf_stats": {"commit_latency_ms": 2, "apply_latency_ms": 3}},
+  {"id": 1, "perf_stats": {"commit_latency_ms": 5, "apply_latency_ms": 7}}
+]}}`
+
+// mockCeph answers the commands with the outputs of the daemons of dir and of
+// the cluster
+func mockCeph(dir string) Runner {
+	return func(timeout time.Duration, path string, args ...string) ([]byte, error) {
+		if args[0] == "--admin-daemon" {
+			switch args[1] {
+			case filepath.Join(dir, "ceph-osd.0.asok"):
+				return []byte(osdPerfDump), nil
+			case filepath.Join(dir, "ceph-mon.node1.asok"):
+				return []byte(monPerfDump), nil
+			}
+			return []byte("admin_socket: exception getting command descriptions"),
+				errors.New("exit status 22")
+		}
+		switch strings.Join(args[4:len(args)-2], " ") {
+		case "status":
+			return []byte(clusterStatus), nil
+		case "df":
+			return []byte(clusterDf), nil
+		case "osd pool stats":
+			return []byte(clusterPoolStats), nil
+		case "osd perf":
+			return []byte(clusterOsdPerf), nil
+		}
+		return nil, errors.New("unknown command")
+	}
+}
+
+func newCeph(dir string) *Ceph {
+	return &Ceph{
+		CephBinary:   "ceph",
+		OsdPrefix:    "ceph-osd",
+		MonPrefix:    "ceph-mon",
+		MdsPrefix:    "ceph-mds",
+		SocketDir:    dir,
+		SocketSuffix: "asok",
+		CephUser:     "client.admin",
+		CephConfig:   "/etc/ceph/ceph.conf",
+		runner:       mockCeph(dir),
+	}
+}
+
+func TestGatherAdminSocketStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ceph")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"ceph-osd.0.asok", "ceph-mon.node1.asok",
+		"ceph-client.admin.asok", "ceph-osd.1.log"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	c := newCeph(dir)
+	c.GatherAdminSocketStats = true
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "ceph", map[string]interface{}{
+		"op":                  float64(1024),
+		"op_r":                float64(512),
+		"op_latency_avgcount": float64(1024),
+		"op_latency_sum":      20.48,
+	}, map[string]string{"type": "osd", "id": "0", "collection": "osd"})
+	acc.AssertContainsTaggedFields(t, "ceph", map[string]interface{}{
+		"journal_queue_ops":        float64(0),
+		"journal_latency_avgcount": float64(12),
+		"journal_latency_sum":      0.6,
+	}, map[string]string{"type": "osd", "id": "0", "collection": "filestore"})
+	acc.AssertContainsTaggedFields(t, "ceph", map[string]interface{}{
+		"num_sessions": float64(5),
+		"session_add":  float64(8),
+	}, map[string]string{"type": "mon", "id": "node1", "collection": "mon"})
+	assert.Equal(t, 3, len(acc.Metrics))
+
+	// The errors of the daemons are reported, the others being gathered
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, "ceph-mds.a.asok"), nil, 0644))
+	acc = testutil.Accumulator{}
+	err = c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ceph-mds.a.asok")
+	assert.Equal(t, 3, len(acc.Metrics))
+}
+
+func TestGatherClusterStats(t *testing.T) {
+	c := newCeph("")
+	c.GatherClusterStats = true
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	empty := map[string]string{}
+	acc.AssertContainsTaggedFields(t, "ceph_health", map[string]interface{}{
+		"status":         "HEALTH_WARN",
+		"overall_status": "HEALTH_WARN",
+	}, empty)
+	acc.AssertContainsTaggedFields(t, "ceph_osdmap", map[string]interface{}{
+		"epoch":       float64(21),
+		"num_osds":    float64(3),
+		"num_up_osds": float64(2),
+		"num_in_osds": float64(3),
+	}, empty)
+	acc.AssertContainsTaggedFields(t, "ceph_pgmap", map[string]interface{}{
+		"num_pgs":          float64(64),
+		"num_pools":        float64(1),
+		"num_objects":      float64(1200),
+		"data_bytes":       float64(1048576),
+		"bytes_used":       float64(3145728),
+		"bytes_avail":      float64(1073741824),
+		"bytes_total":      float64(1076887552),
+		"read_bytes_sec":   float64(4096),
+		"write_op_per_sec": float64(12),
+	}, empty)
+	acc.AssertContainsTaggedFields(t, "ceph_pgmap_state", map[string]interface{}{
+		"count": float64(4),
+	}, map[string]string{"state": "active+undersized+degraded"})
+	acc.AssertContainsTaggedFields(t, "ceph_usage", map[string]interface{}{
+		"total_bytes":       float64(1076887552),
+		"total_used_bytes":  float64(3145728),
+		"total_avail_bytes": float64(1073741824),
+	}, empty)
+	acc.AssertContainsTaggedFields(t, "ceph_pool_usage", map[string]interface{}{
+		"kb_used":    float64(1024),
+		"bytes_used": float64(1048576),
+		"objects":    float64(1200),
+		"max_avail":  float64(357913941),
+	}, map[string]string{"name": "rbd"})
+	acc.AssertContainsTaggedFields(t, "ceph_pool_stats", map[string]interface{}{
+		"read_bytes_sec":             float64(4096),
+		"write_bytes_sec":            float64(0),
+		"read_op_per_sec":            float64(0),
+		"write_op_per_sec":           float64(12),
+		"recovering_objects_per_sec": float64(3),
+		"recovering_bytes_per_sec":   float64(0),
+		"recovering_keys_per_sec":    float64(0),
+	}, map[string]string{"name": "rbd"})
+	acc.AssertContainsTaggedFields(t, "ceph_pool_stats", map[string]interface{}{
+		"read_bytes_sec":             float64(0),
+		"write_bytes_sec":            float64(0),
+		"read_op_per_sec":            float64(0),
+		"write_op_per_sec":           float64(0),
+		"recovering_objects_per_sec": float64(0),
+		"recovering_bytes_per_sec":   float64(0),
+		"recovering_keys_per_sec":    float64(0),
+	}, map[string]string{"name": "idle"})
+	acc.AssertContainsTaggedFields(t, "ceph_osd_perf", map[string]interface{}{
+		"commit_latency_ms": float64(5),
+		"apply_latency_ms":  float64(7),
+	}, map[string]string{"id": "1"})
+}
+
+func TestGatherClusterStatsError(t *testing.T) {
+	c := newCeph("")
+	c.GatherClusterStats = true
+	c.runner = func(timeout time.Duration, path string, args ...string) ([]byte, error) {
+		return []byte("error connecting to the cluster"), errors.New("exit status 1")
+	}
+	var acc testutil.Accumulator
+	err := c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ceph osd pool stats: exit status 1: "+
+		"error connecting to the cluster")
+}
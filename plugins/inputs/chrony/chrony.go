@@ -0,0 +1,294 @@
+package chrony
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs chronyc with the arguments, killing it after the timeout, and
+// returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Chrony reads the tracking and the sources of chronyd with chronyc
+type Chrony struct {
+	Binary    string
+	DNSLookup bool `toml:"dns_lookup"`
+	Sources   bool
+	Timeout   internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of chronyc
+  binary = "/usr/bin/chronyc"
+
+  # Resolve the addresses of the reference and sources into hostnames,
+  # which can be slow when the DNS is
+  dns_lookup = false
+
+  # Gather the sources, a point per source with its offset, jitter and reach
+  sources = true
+
+  # Timeout of chronyc
+  # timeout = "5s"
+`
+
+func (c *Chrony) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Chrony) Description() string {
+	return "Gather the offset of the clock and of the NTP sources with chronyc"
+}
+
+// trackingFields are the fields of the lines of chronyc tracking, the
+// leading number of their value
+var trackingFields = map[string]string{
+	"Stratum":         "stratum",
+	"System time":     "system_time",
+	"Last offset":     "last_offset",
+	"RMS offset":      "rms_offset",
+	"Frequency":       "frequency",
+	"Residual freq":   "residual_freq",
+	"Skew":            "skew",
+	"Root delay":      "root_delay",
+	"Root dispersion": "root_dispersion",
+	"Update interval": "update_interval",
+}
+
+// sourceModes and sourceStates are the modes and states of the sources,
+// the first and second characters of chronyc sources
+var sourceModes = map[string]string{
+	"^": "server",
+	"=": "peer",
+	"#": "refclock",
+}
+
+var sourceStates = map[string]string{
+	"*": "sync",
+	"+": "combined",
+	"-": "not_combined",
+	"?": "unreachable",
+	"x": "falseticker",
+	"~": "variable",
+}
+
+func (c *Chrony) Gather(acc telegraf.Accumulator) error {
+	if c.runner == nil {
+		c.runner = runChronyc
+	}
+	if c.Binary == "" {
+		c.Binary = "/usr/bin/chronyc"
+	}
+	if c.Timeout.Duration == 0 {
+		c.Timeout.Duration = 5 * time.Second
+	}
+
+	out, err := c.chronyc("tracking")
+	if err != nil {
+		return err
+	}
+	if err := parseTracking(out, acc); err != nil {
+		return err
+	}
+	if !c.Sources {
+		return nil
+	}
+
+	out, err = c.chronyc("-c", "sourcestats")
+	if err != nil {
+		return err
+	}
+	jitters := parseSourcestats(out)
+	out, err = c.chronyc("-c", "sources")
+	if err != nil {
+		return err
+	}
+	return parseSources(out, jitters, acc)
+}
+
+// chronyc runs chronyc with the arguments, resolving the addresses only if
+// dns_lookup is set
+func (c *Chrony) chronyc(args ...string) (string, error) {
+	if !c.DNSLookup {
+		args = append([]string{"-n"}, args...)
+	}
+	out, err := c.runner(c.Timeout.Duration, c.Binary, args...)
+	if err != nil {
+		return "", fmt.Errorf("running chronyc %s: %s: %s",
+			args[len(args)-1], err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// parseTracking adds the point of chronyc tracking, listed as
+//
+//	Reference ID    : 0A000001 (10.0.0.1)
+//	Stratum         : 3
+//	System time     : 0.000020390 seconds slow of NTP time
+//	Frequency       : 16.001 ppm slow
+//	Leap status     : Normal
+//
+// The system time and the frequency are negative when the clock is slow.
+func parseTracking(out string, acc telegraf.Accumulator) error {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		words := strings.Fields(value)
+		if len(words) == 0 {
+			continue
+		}
+		switch key {
+		case "Reference ID":
+			tags["reference_id"] = words[0]
+		case "Leap status":
+			tags["leap_status"] = strings.ToLower(strings.Join(words, "_"))
+		}
+
+		name, ok := trackingFields[key]
+		if !ok {
+			continue
+		}
+		if name == "stratum" {
+			if v, err := strconv.ParseInt(words[0], 10, 64); err == nil {
+				fields[name] = v
+			}
+			continue
+		}
+		v, err := strconv.ParseFloat(words[0], 64)
+		if err != nil {
+			continue
+		}
+		if strings.Contains(value, " slow") {
+			v = -v
+		}
+		fields[name] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no tracking in the output of chronyc: %s",
+			strings.TrimSpace(out))
+	}
+	acc.AddFields("chrony", fields, tags, time.Now())
+	return nil
+}
+
+// parseSourcestats returns the standard deviations of the offsets of the
+// sources, by name, from chronyc -c sourcestats, whose columns are the name,
+// the number of samples and of runs, the span, the frequency and its skew,
+// the offset and its standard deviation
+func parseSourcestats(out string) map[string]float64 {
+	jitters := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), ",")
+		if len(cols) != 8 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(cols[7], 64); err == nil {
+			jitters[cols[0]] = v
+		}
+	}
+	return jitters
+}
+
+// parseSources adds a point per source of chronyc -c sources, whose columns
+// are the mode, the state, the name, the stratum, the poll interval as a
+// power of 2, the reach in octal, the seconds since the last sample, its
+// offset adjusted and measured, and the error margin of the measure
+func parseSources(out string, jitters map[string]float64,
+	acc telegraf.Accumulator) error {
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), ",")
+		if len(cols) != 10 {
+			continue
+		}
+		tags := map[string]string{"source": cols[2]}
+		if mode, ok := sourceModes[cols[0]]; ok {
+			tags["mode"] = mode
+		}
+		if state, ok := sourceStates[cols[1]]; ok {
+			tags["state"] = state
+		}
+
+		fields := make(map[string]interface{})
+		for name, col := range map[string]int{
+			"stratum": 3,
+			"poll":    4,
+			"reach":   5,
+			"last_rx": 6,
+		} {
+			if v, err := strconv.ParseInt(cols[col], 10, 64); err == nil {
+				fields[name] = v
+			}
+		}
+		for name, col := range map[string]int{
+			"offset":          7,
+			"measured_offset": 8,
+			"error":           9,
+		} {
+			if v, err := strconv.ParseFloat(cols[col], 64); err == nil {
+				fields[name] = v
+			}
+		}
+		if jitter, ok := jitters[cols[2]]; ok {
+			fields["jitter"] = jitter
+		}
+		acc.AddFields("chrony_sources", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+// runChronyc runs chronyc, killing it after the timeout
+func runChronyc(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("chronyc timed out after %s", timeout)
+	}
+}
+
+func init() {
+	inputs.Add("chrony", func() telegraf.Input {
+		return &Chrony{
+			Binary:  "/usr/bin/chronyc",
+			Sources: true,
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			runner:  runChronyc,
+		}
+	})
+}
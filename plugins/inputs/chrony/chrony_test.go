@@ -0,0 +1,136 @@
+package chrony
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const trackingOutput = `Reference ID    : 0A000001 (10.0.0.1)
+Stratum         : 3
+Ref time (UTC)  : Thu Jan 21 10:27:54 2016
+System time     : 0.000020390 seconds slow of NTP time
+Last offset     : +0.000012651 seconds
+RMS offset      : 0.000025577 seconds
+Frequency       : 16.001 ppm slow
+Residual freq   : -0.000 ppm
+Skew            : 0.006 ppm
+Root delay      : 0.001655 seconds
+Root dispersion : 0.003307 seconds
+Update interval : 507.2 seconds
+Leap status     : Not synchronised
+`
+
+const sourcesOutput = `^,*,10.0.0.1,2,9,377,35,-0.000012345,-0.000015000,0.002345000
+^,?,10.0.0.2,0,6,0,-,0.000000000,0.000000000,0.000000000
+`
+
+const sourcestatsOutput = `10.0.0.1,25,13,207m,-0.001,0.012,-0.000011000,0.000045000
+`
+
+func runner(calls *[]string) Runner {
+	return func(timeout time.Duration, path string, args ...string) (string, error) {
+		*calls = append(*calls, path+" "+strings.Join(args, " "))
+		switch args[len(args)-1] {
+		case "tracking":
+			return trackingOutput, nil
+		case "sources":
+			return sourcesOutput, nil
+		case "sourcestats":
+			return sourcestatsOutput, nil
+		}
+		return "", errors.New("exit status 1")
+	}
+}
+
+func TestGather(t *testing.T) {
+	var calls []string
+	c := &Chrony{
+		Binary:  "chronyc",
+		Sources: true,
+		runner:  runner(&calls),
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	assert.Equal(t, []string{
+		"chronyc -n tracking",
+		"chronyc -n -c sourcestats",
+		"chronyc -n -c sources",
+	}, calls)
+	assert.Equal(t, 3, len(acc.Metrics))
+	// The system time and the frequency are negative when slow
+	acc.AssertContainsTaggedFields(t, "chrony", map[string]interface{}{
+		"stratum":         int64(3),
+		"system_time":     -0.000020390,
+		"last_offset":     0.000012651,
+		"rms_offset":      0.000025577,
+		"frequency":       -16.001,
+		"residual_freq":   -0.0,
+		"skew":            0.006,
+		"root_delay":      0.001655,
+		"root_dispersion": 0.003307,
+		"update_interval": 507.2,
+	}, map[string]string{
+		"reference_id": "0A000001",
+		"leap_status":  "not_synchronised",
+	})
+	acc.AssertContainsTaggedFields(t, "chrony_sources", map[string]interface{}{
+		"stratum":         int64(2),
+		"poll":            int64(9),
+		"reach":           int64(377),
+		"last_rx":         int64(35),
+		"offset":          -0.000012345,
+		"measured_offset": -0.000015,
+		"error":           0.002345,
+		"jitter":          0.000045,
+	}, map[string]string{
+		"source": "10.0.0.1",
+		"mode":   "server",
+		"state":  "sync",
+	})
+	// The sources never reached have no last sample, nor statistics
+	acc.AssertContainsTaggedFields(t, "chrony_sources", map[string]interface{}{
+		"stratum":         int64(0),
+		"poll":            int64(6),
+		"reach":           int64(0),
+		"offset":          0.0,
+		"measured_offset": 0.0,
+		"error":           0.0,
+	}, map[string]string{
+		"source": "10.0.0.2",
+		"mode":   "server",
+		"state":  "unreachable",
+	})
+}
+
+func TestGatherTrackingOnly(t *testing.T) {
+	var calls []string
+	c := &Chrony{
+		Binary:    "chronyc",
+		DNSLookup: true,
+		runner:    runner(&calls),
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	assert.Equal(t, []string{"chronyc tracking"}, calls)
+	assert.Equal(t, 1, len(acc.Metrics))
+}
+
+func TestGatherError(t *testing.T) {
+	c := &Chrony{
+		Binary: "chronyc",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			return "506 Cannot talk to daemon", errors.New("exit status 1")
+		},
+	}
+	var acc testutil.Accumulator
+	err := c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Cannot talk to daemon")
+}
@@ -0,0 +1,184 @@
+// +build linux
+
+package conntrack
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Conntrack reads the number of connections tracked by netfilter, and the
+// statistics of the tracking per CPU
+type Conntrack struct {
+	HostProc string `toml:"host_proc"`
+	Files    []string
+	Collect  []string
+}
+
+var defaultFiles = []string{
+	"ip_conntrack_count",
+	"ip_conntrack_max",
+	"nf_conntrack_count",
+	"nf_conntrack_max",
+}
+
+var sampleConfig = `
+  # Path of /proc, ie /hostfs/proc when telegraf runs in a container with
+  # the /proc of the host mounted
+  # host_proc = "/proc"
+
+  # Files of the sysctls of netfilter read, in /proc/sys/net/netfilter, or
+  # /proc/sys/net/ipv4/netfilter with the older kernels, the ip_ prefix
+  # being replaced by nf_
+  files = ["ip_conntrack_count", "ip_conntrack_max",
+           "nf_conntrack_count", "nf_conntrack_max"]
+
+  # Statistics of the tracking read from /proc/net/stat/nf_conntrack, "all"
+  # for their sum over the CPUs, "percpu" for a point per CPU
+  # collect = ["all", "percpu"]
+`
+
+func (c *Conntrack) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Conntrack) Description() string {
+	return "Gather the connections tracked by netfilter"
+}
+
+func (c *Conntrack) Gather(acc telegraf.Accumulator) error {
+	if c.HostProc == "" {
+		c.HostProc = "/proc"
+	}
+	var all, percpu bool
+	for _, collect := range c.Collect {
+		switch collect {
+		case "all":
+			all = true
+		case "percpu":
+			percpu = true
+		default:
+			return fmt.Errorf("invalid collect %q, expected all or percpu",
+				collect)
+		}
+	}
+	files := c.Files
+	if len(files) == 0 {
+		files = defaultFiles
+	}
+	dirs := []string{
+		filepath.Join(c.HostProc, "sys", "net", "ipv4", "netfilter"),
+		filepath.Join(c.HostProc, "sys", "net", "netfilter"),
+	}
+
+	fields := make(map[string]interface{})
+	for _, dir := range dirs {
+		for _, file := range files {
+			data, err := ioutil.ReadFile(filepath.Join(dir, file))
+			if os.IsNotExist(err) {
+				// The files of the other kernels, or of the module not
+				// loaded
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			v, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10,
+				64)
+			if err != nil {
+				return fmt.Errorf("parsing %s: %s", file, err)
+			}
+			fields[strings.Replace(file, "ip_", "nf_", 1)] = v
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("conntrack files %s not found in %s, the "+
+			"nf_conntrack module may not be loaded", strings.Join(files, ", "),
+			strings.Join(dirs, " or "))
+	}
+	now := time.Now()
+	acc.AddFields("conntrack", fields, nil, now)
+
+	if all || percpu {
+		return c.gatherStats(all, percpu, now, acc)
+	}
+	return nil
+}
+
+// gatherStats adds the statistics of /proc/net/stat/nf_conntrack, a line of
+// hex counters per CPU after the line of their names, listed as
+//
+//	entries  searched found new invalid ignore delete delete_list insert ...
+//	0000004a  00000000 00000000 00000000 00000005 00000004 00000000 ...
+//
+// summed over the CPUs if all is set, the entries being global
+func (c *Conntrack) gatherStats(all bool, percpu bool, now time.Time,
+	acc telegraf.Accumulator) error {
+	data, err := ioutil.ReadFile(filepath.Join(c.HostProc, "net", "stat",
+		"nf_conntrack"))
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	total := make(map[string]interface{})
+	cpu := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		cols := strings.Fields(scanner.Text())
+		if names == nil {
+			names = cols
+			continue
+		}
+		if len(cols) != len(names) {
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(cols))
+		for i, col := range cols {
+			v, err := strconv.ParseUint(col, 16, 64)
+			if err != nil {
+				return fmt.Errorf("parsing the %s of cpu%d: %s", names[i],
+					cpu, err)
+			}
+			fields[names[i]] = v
+			if names[i] == "entries" {
+				total[names[i]] = v
+				continue
+			}
+			sum, _ := total[names[i]].(uint64)
+			total[names[i]] = sum + v
+		}
+		if percpu {
+			acc.AddFields("conntrack", fields,
+				map[string]string{"cpu": fmt.Sprintf("cpu%d", cpu)}, now)
+		}
+		cpu++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if all && len(total) > 0 {
+		acc.AddFields("conntrack", total, map[string]string{"cpu": "all"}, now)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("conntrack", func() telegraf.Input {
+		return &Conntrack{
+			HostProc: "/proc",
+			Files:    defaultFiles,
+		}
+	})
+}
@@ -0,0 +1,122 @@
+// +build linux
+
+package conntrack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statNfConntrack = `entries  searched found new invalid ignore delete delete_list insert insert_failed drop early_drop icmp_error  expect_new expect_create expect_delete search_restart
+0000004a  00000000 00000000 00000000 00000005 00000004 00000000 00000000 00000000 00000000 00000000 00000000 00000000  00000000 00000000 00000000 0000000a
+0000004a  00000000 00000000 00000000 00000002 000000ff 00000000 00000000 00000000 00000001 00000000 00000000 00000000  00000000 00000000 00000000 00000001
+`
+
+// writeProc writes the files of the proc filesystem, by path
+func writeProc(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "conntrack")
+	require.NoError(t, err)
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestGather(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"sys/net/netfilter/nf_conntrack_count": "74\n",
+		"sys/net/netfilter/nf_conntrack_max":   "262144\n",
+		"net/stat/nf_conntrack":                statNfConntrack,
+	})
+	defer os.RemoveAll(dir)
+
+	c := &Conntrack{HostProc: dir, Files: defaultFiles}
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	assert.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsFields(t, "conntrack", map[string]interface{}{
+		"nf_conntrack_count": int64(74),
+		"nf_conntrack_max":   int64(262144),
+	})
+}
+
+func TestGatherIPConntrack(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"sys/net/ipv4/netfilter/ip_conntrack_count": "12\n",
+		"sys/net/ipv4/netfilter/ip_conntrack_max":   "65536\n",
+	})
+	defer os.RemoveAll(dir)
+
+	// The ip_ files of the older kernels are named as the nf_ files
+	c := &Conntrack{HostProc: dir}
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	acc.AssertContainsFields(t, "conntrack", map[string]interface{}{
+		"nf_conntrack_count": int64(12),
+		"nf_conntrack_max":   int64(65536),
+	})
+}
+
+func TestGatherStats(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"sys/net/netfilter/nf_conntrack_count": "74\n",
+		"net/stat/nf_conntrack":                statNfConntrack,
+	})
+	defer os.RemoveAll(dir)
+
+	c := &Conntrack{HostProc: dir, Collect: []string{"all", "percpu"}}
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	assert.Equal(t, 4, len(acc.Metrics))
+	stats := func(invalid, ignore, insertFailed, searchRestart uint64) map[string]interface{} {
+		fields := make(map[string]interface{})
+		for _, name := range []string{"searched", "found", "new", "delete",
+			"delete_list", "insert", "drop", "early_drop", "icmp_error",
+			"expect_new", "expect_create", "expect_delete"} {
+			fields[name] = uint64(0)
+		}
+		fields["entries"] = uint64(74)
+		fields["invalid"] = invalid
+		fields["ignore"] = ignore
+		fields["insert_failed"] = insertFailed
+		fields["search_restart"] = searchRestart
+		return fields
+	}
+	acc.AssertContainsTaggedFields(t, "conntrack", stats(5, 4, 0, 10),
+		map[string]string{"cpu": "cpu0"})
+	acc.AssertContainsTaggedFields(t, "conntrack", stats(2, 255, 1, 1),
+		map[string]string{"cpu": "cpu1"})
+	// The entries are not summed, being global
+	acc.AssertContainsTaggedFields(t, "conntrack", stats(7, 259, 1, 11),
+		map[string]string{"cpu": "all"})
+}
+
+func TestGatherErrors(t *testing.T) {
+	dir := writeProc(t, map[string]string{
+		"sys/net/netfilter/nf_conntrack_count": "74\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var acc testutil.Accumulator
+	err := (&Conntrack{HostProc: "/nonexistent"}).Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not be loaded")
+
+	err = (&Conntrack{HostProc: dir, Collect: []string{"cpu"}}).Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid collect")
+
+	// The statistics are missing
+	err = (&Conntrack{HostProc: dir, Collect: []string{"all"}}).Gather(&acc)
+	assert.Error(t, err)
+}
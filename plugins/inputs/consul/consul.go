@@ -0,0 +1,281 @@
+package consul
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Consul reads the health checks of the services and nodes of a Consul
+// datacenter, and optionally the metrics of the Consul agent
+type Consul struct {
+	Address            string
+	Scheme             string
+	Token              string
+	Datacenter         string
+	TagDelimiter       string `toml:"tag_delimiter"`
+	GatherAgentMetrics bool   `toml:"gather_agent_metrics"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	Username           string
+	Password           string
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  # Address of the Consul agent, and its scheme, http or https
+  address = "localhost:8500"
+  # scheme = "http"
+
+  # ACL token of the requests
+  # token = ""
+
+  # Datacenter of the checks, the datacenter of the agent by default
+  # datacenter = ""
+
+  # Separator of the key and the value of the service tags added as tags,
+  # ie "env:prod" adds an env tag. The service tags are not added if empty.
+  # tag_delimiter = ":"
+
+  # Gather the metrics of the agent, in measurements named by the metrics
+  # gather_agent_metrics = false
+
+  # Timeout of the requests, 5s by default
+  # timeout = "5s"
+
+  # Credentials of the requests
+  # username = ""
+  # password = ""
+
+  # TLS options of the https scheme
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+`
+
+func (c *Consul) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Consul) Description() string {
+	return "Gather the health checks of the services and nodes of Consul"
+}
+
+// Init creates the HTTP client of the plugin, sending the ACL token in the
+// X-Consul-Token header
+func (c *Consul) Init() error {
+	if c.Address == "" {
+		c.Address = "localhost:8500"
+	}
+	if c.Scheme == "" {
+		c.Scheme = "http"
+	}
+	if c.Scheme != "http" && c.Scheme != "https" {
+		return fmt.Errorf("unknown scheme %q, must be http or https", c.Scheme)
+	}
+
+	config := httpconfig.Config{
+		Timeout:  c.Timeout.Duration,
+		Username: c.Username,
+		Password: c.Password,
+		TLS: internal.TLSOptions{
+			SSLCA:              c.SSLCA,
+			SSLCert:            c.SSLCert,
+			SSLKey:             c.SSLKey,
+			InsecureSkipVerify: c.InsecureSkipVerify,
+		},
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if c.Token != "" {
+		config.Headers = map[string]string{"X-Consul-Token": c.Token}
+	}
+	client, err := config.CreateClient()
+	if err != nil {
+		return err
+	}
+	c.client = client
+	return nil
+}
+
+func (c *Consul) Gather(acc telegraf.Accumulator) error {
+	var errorStrings []string
+	if err := c.gatherHealthChecks(acc); err != nil {
+		errorStrings = append(errorStrings, err.Error())
+	}
+	if c.GatherAgentMetrics {
+		if err := c.gatherAgentMetrics(acc); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// get decodes the json response of the path of the Consul HTTP API
+func (c *Consul) get(path string, v interface{}) error {
+	u := url.URL{Scheme: c.Scheme, Host: c.Address, Path: path}
+	if c.Datacenter != "" {
+		u.RawQuery = url.Values{"dc": {c.Datacenter}}.Encode()
+	}
+	resp, err := c.client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u.String(),
+			resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding the response of %s: %s",
+			u.String(), err)
+	}
+	return nil
+}
+
+// healthCheck is a check of /v1/health/state/any, of a service, or of the
+// node if its service is empty
+type healthCheck struct {
+	Node        string
+	CheckID     string
+	Name        string
+	Status      string
+	ServiceID   string
+	ServiceName string
+	ServiceTags []string
+}
+
+// gatherHealthChecks adds a point per health check of the datacenter, its
+// status, passing, warning or critical, as a string and as counts
+func (c *Consul) gatherHealthChecks(acc telegraf.Accumulator) error {
+	var checks []healthCheck
+	if err := c.get("/v1/health/state/any", &checks); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, check := range checks {
+		tags := map[string]string{
+			"node":     check.Node,
+			"check_id": check.CheckID,
+		}
+		if check.ServiceName != "" {
+			tags["service_name"] = check.ServiceName
+		}
+		if c.TagDelimiter != "" {
+			for _, tag := range check.ServiceTags {
+				parts := strings.SplitN(tag, c.TagDelimiter, 2)
+				if len(parts) == 2 && parts[0] != "" {
+					tags[parts[0]] = parts[1]
+				}
+			}
+		}
+
+		fields := map[string]interface{}{
+			"check_name": check.Name,
+			"status":     check.Status,
+			"passing":    0,
+			"warning":    0,
+			"critical":   0,
+		}
+		if check.ServiceID != "" {
+			fields["service_id"] = check.ServiceID
+		}
+		switch check.Status {
+		case "passing", "warning", "critical":
+			fields[check.Status] = 1
+		}
+		acc.AddFields("consul_health_checks", fields, tags, now)
+	}
+	return nil
+}
+
+// agentMetrics are the metrics of the agent of /v1/agent/metrics, its
+// counters and samples being aggregated over the last interval of the agent
+type agentMetrics struct {
+	Gauges []struct {
+		Name   string
+		Value  float64
+		Labels map[string]string
+	}
+	Counters []aggregate
+	Samples  []aggregate
+}
+
+type aggregate struct {
+	Name   string
+	Count  int64
+	Sum    float64
+	Min    float64
+	Max    float64
+	Mean   float64
+	Stddev float64
+	Labels map[string]string
+}
+
+// gatherAgentMetrics adds a point per metric of the agent, named by the
+// metric, ie consul.runtime.alloc_bytes, and tagged with its labels
+func (c *Consul) gatherAgentMetrics(acc telegraf.Accumulator) error {
+	var metrics agentMetrics
+	if err := c.get("/v1/agent/metrics", &metrics); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, g := range metrics.Gauges {
+		acc.AddFields(g.Name, map[string]interface{}{
+			"value": g.Value,
+		}, labels(g.Labels), now)
+	}
+	for _, aggregates := range [][]aggregate{
+		metrics.Counters,
+		metrics.Samples,
+	} {
+		for _, a := range aggregates {
+			acc.AddFields(a.Name, map[string]interface{}{
+				"count":  a.Count,
+				"sum":    a.Sum,
+				"min":    a.Min,
+				"max":    a.Max,
+				"mean":   a.Mean,
+				"stddev": a.Stddev,
+			}, labels(a.Labels), now)
+		}
+	}
+	return nil
+}
+
+// labels returns the labels of a metric as tags, the metrics without labels
+// having none
+func labels(l map[string]string) map[string]string {
+	if l == nil {
+		return map[string]string{}
+	}
+	return l
+}
+
+func init() {
+	inputs.Add("consul", func() telegraf.Input {
+		return &Consul{}
+	})
+}
@@ -0,0 +1,181 @@
+package consul
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const healthChecks = `[
+  {
+    "Node": "node1",
+    "CheckID": "serfHealth",
+    "Name": "Serf Health Status",
+    "Status": "passing",
+    "ServiceID": "",
+    "ServiceName": "",
+    "ServiceTags": []
+  },
+  {
+    "Node": "node1",
+    "CheckID": "service:redis",
+    "Name": "Service 'redis' check",
+    "Status": "critical",
+    "ServiceID": "redis",
+    "ServiceName": "redis",
+    "ServiceTags": ["env:prod", "primary"]
+  }
+]`
+
+const agentMetricsResponse = `{
+  "Timestamp": "2019-03-11 10:42:30 +0000 UTC",
+  "Gauges": [
+    {"Name": "consul.runtime.alloc_bytes", "Value": 7193152, "Labels": {}},
+    {"Name": "consul.autopilot.healthy", "Value": 1, "Labels": {"dc": "dc1"}}
+  ],
+  "Points": [],
+  "Counters": [
+    {"Name": "consul.rpc.request", "Count": 4, "Sum": 4, "Min": 1, "Max": 1,
+     "Mean": 1, "Stddev": 0, "Labels": {}}
+  ],
+  "Samples": [
+    {"Name": "consul.raft.commitTime", "Count": 2, "Sum": 3.5, "Min": 1.5,
+     "Max": 2, "Mean": 1.75, "Stddev": 0.35, "Labels": {}}
+  ]
+}`
+
+// consulServer returns a fake Consul agent, requiring the ACL token
+func consulServer(t *testing.T, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Consul-Token") != token {
+				http.Error(w, "ACL not found", http.StatusForbidden)
+				return
+			}
+			switch r.URL.Path {
+			case "/v1/health/state/any":
+				if dc := r.URL.Query().Get("dc"); dc != "" && dc != "dc1" {
+					http.Error(w, "No path to datacenter",
+						http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprint(w, healthChecks)
+			case "/v1/agent/metrics":
+				fmt.Fprint(w, agentMetricsResponse)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+}
+
+func TestGatherHealthChecks(t *testing.T) {
+	ts := consulServer(t, "secret")
+	defer ts.Close()
+
+	c := &Consul{
+		Address:      strings.TrimPrefix(ts.URL, "http://"),
+		Token:        "secret",
+		Datacenter:   "dc1",
+		TagDelimiter: ":",
+	}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "consul_health_checks",
+		map[string]interface{}{
+			"check_name": "Serf Health Status",
+			"status":     "passing",
+			"passing":    1,
+			"warning":    0,
+			"critical":   0,
+		}, map[string]string{
+			"node":     "node1",
+			"check_id": "serfHealth",
+		})
+	acc.AssertContainsTaggedFields(t, "consul_health_checks",
+		map[string]interface{}{
+			"check_name": "Service 'redis' check",
+			"service_id": "redis",
+			"status":     "critical",
+			"passing":    0,
+			"warning":    0,
+			"critical":   1,
+		}, map[string]string{
+			"node":         "node1",
+			"check_id":     "service:redis",
+			"service_name": "redis",
+			"env":          "prod",
+		})
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestGatherAgentMetrics(t *testing.T) {
+	ts := consulServer(t, "")
+	defer ts.Close()
+
+	c := &Consul{
+		Address:            strings.TrimPrefix(ts.URL, "http://"),
+		GatherAgentMetrics: true,
+	}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "consul.runtime.alloc_bytes",
+		map[string]interface{}{"value": float64(7193152)},
+		map[string]string{})
+	acc.AssertContainsTaggedFields(t, "consul.autopilot.healthy",
+		map[string]interface{}{"value": float64(1)},
+		map[string]string{"dc": "dc1"})
+	acc.AssertContainsTaggedFields(t, "consul.raft.commitTime",
+		map[string]interface{}{
+			"count":  int64(2),
+			"sum":    3.5,
+			"min":    1.5,
+			"max":    float64(2),
+			"mean":   1.75,
+			"stddev": 0.35,
+		}, map[string]string{})
+	assert.True(t, acc.HasMeasurement("consul.rpc.request"))
+}
+
+func TestGatherTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, healthChecks)
+		}))
+	defer ts.Close()
+
+	c := &Consul{
+		Address:            strings.TrimPrefix(ts.URL, "https://"),
+		Scheme:             "https",
+		InsecureSkipVerify: true,
+	}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestGatherErrors(t *testing.T) {
+	ts := consulServer(t, "secret")
+	defer ts.Close()
+
+	// The requests without the token are denied
+	c := &Consul{Address: strings.TrimPrefix(ts.URL, "http://")}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	err := c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+
+	c = &Consul{Address: "localhost:8500", Scheme: "ftp"}
+	assert.Error(t, c.Init())
+}
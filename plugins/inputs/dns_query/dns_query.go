@@ -0,0 +1,288 @@
+package dns_query
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// The results of the queries, in the result tag and the result_code field
+const (
+	Success = 0
+	Timeout = 1
+	Error   = 2
+)
+
+var resultNames = map[int]string{
+	Success: "success",
+	Timeout: "timeout",
+	Error:   "error",
+}
+
+// recordTypes are the types of the records queried, see RFC 1035 and 3596
+var recordTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"SOA":   6,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+	"SRV":   33,
+	"ANY":   255,
+}
+
+// rcodes are the names of the response codes, see RFC 1035 and 2136
+var rcodes = map[int]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// DNSQuery queries the records of domains from DNS servers
+type DNSQuery struct {
+	Servers    []string
+	Network    string
+	Domains    []string
+	RecordType string `toml:"record_type"`
+	Port       int
+	Timeout    internal.Duration
+}
+
+var sampleConfig = `
+  # Servers queried, their port is the port option
+  servers = ["8.8.8.8"]
+  # Network of the queries, "udp" or "tcp"
+  # network = "udp"
+  # Domains queried, the root by default
+  # domains = ["."]
+  # Type of the records queried: A, AAAA, ANY, CNAME, MX, NS, PTR, SOA, SRV
+  # or TXT, NS by default
+  # record_type = "NS"
+  # Port of the servers, 53 by default
+  # port = 53
+  # Timeout of the queries, 2s by default
+  # timeout = "2s"
+`
+
+func (d *DNSQuery) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *DNSQuery) Description() string {
+	return "Query the records of domains from DNS servers, and their query time"
+}
+
+// Init checks the options of the plugin and sets their defaults
+func (d *DNSQuery) Init() error {
+	if d.Network == "" {
+		d.Network = "udp"
+	}
+	if d.Network != "udp" && d.Network != "tcp" {
+		return fmt.Errorf("unknown network %q, must be udp or tcp", d.Network)
+	}
+	if len(d.Domains) == 0 {
+		d.Domains = []string{"."}
+	}
+	if d.RecordType == "" {
+		d.RecordType = "NS"
+	}
+	if _, ok := recordTypes[strings.ToUpper(d.RecordType)]; !ok {
+		return fmt.Errorf("unknown record_type %q", d.RecordType)
+	}
+	if d.Port == 0 {
+		d.Port = 53
+	}
+	if d.Timeout.Duration == 0 {
+		d.Timeout.Duration = 2 * time.Second
+	}
+	return nil
+}
+
+// Gather queries the domains from all the servers. The failures of the
+// queries are results, and not errors of the plugin.
+func (d *DNSQuery) Gather(acc telegraf.Accumulator) error {
+	qtype := recordTypes[strings.ToUpper(d.RecordType)]
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var outerr error
+
+	for _, server := range d.Servers {
+		for _, domain := range d.Domains {
+			wg.Add(1)
+			go func(server, domain string) {
+				defer wg.Done()
+				fields := make(map[string]interface{})
+				tags := map[string]string{
+					"server":      server,
+					"domain":      domain,
+					"record_type": strings.ToUpper(d.RecordType),
+				}
+				result, err := d.query(server, domain, qtype, fields, tags)
+				if err != nil {
+					errMu.Lock()
+					outerr = err
+					errMu.Unlock()
+					return
+				}
+				fields["result_code"] = result
+				tags["result"] = resultNames[result]
+				acc.AddFields("dns_query", fields, tags)
+			}(server, domain)
+		}
+	}
+
+	wg.Wait()
+	return outerr
+}
+
+// query sends the query of the records of the domain to the server, adding
+// its query time, response code and number of answers to the fields, and the
+// name of the response code to the tags. It returns the result of the query.
+func (d *DNSQuery) query(
+	server string,
+	domain string,
+	qtype uint16,
+	fields map[string]interface{},
+	tags map[string]string,
+) (int, error) {
+	id := uint16(rand.Intn(1 << 16))
+	msg, err := packQuery(id, domain, qtype)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	addr := net.JoinHostPort(server, strconv.Itoa(d.Port))
+	c, err := net.DialTimeout(d.Network, addr, d.Timeout.Duration)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Timeout, nil
+		}
+		return Error, nil
+	}
+	defer c.Close()
+	c.SetDeadline(start.Add(d.Timeout.Duration))
+
+	resp, err := exchange(c, d.Network, msg)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Timeout, nil
+		}
+		return Error, nil
+	}
+	h, err := unpackHeader(resp)
+	if err != nil || h.id != id {
+		return Error, nil
+	}
+	fields["query_time_ms"] = float64(time.Since(start)) / float64(time.Millisecond)
+	fields["rcode_value"] = h.rcode
+	fields["answers"] = int(h.ancount)
+	if name, ok := rcodes[h.rcode]; ok {
+		tags["rcode"] = name
+	} else {
+		tags["rcode"] = strconv.Itoa(h.rcode)
+	}
+	return Success, nil
+}
+
+// exchange sends the message on the connection and returns the response. TCP
+// messages are prefixed by their length, see RFC 1035 4.2.2.
+func exchange(c net.Conn, network string, msg []byte) ([]byte, error) {
+	if network == "tcp" {
+		l := make([]byte, 2)
+		binary.BigEndian.PutUint16(l, uint16(len(msg)))
+		if _, err := c.Write(append(l, msg...)); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(c, l); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(l))
+		_, err := io.ReadFull(c, resp)
+		return resp, err
+	}
+
+	if _, err := c.Write(msg); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, 65535)
+	n, err := c.Read(resp)
+	return resp[:n], err
+}
+
+// packQuery returns the message of a recursive query of the records of type
+// qtype of the domain, in the IN class, see RFC 1035 4.1.
+func packQuery(id uint16, domain string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 12+len(domain)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	// Recursion desired
+	binary.BigEndian.PutUint16(msg[2:], 1<<8)
+	// One question
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	for _, label := range strings.Split(strings.Trim(domain, "."), ".") {
+		if label == "" {
+			continue
+		}
+		if len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain %q: label %q is longer "+
+				"than 63 characters", domain, label)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = append(msg, byte(qtype>>8), byte(qtype), 0, 1)
+	return msg, nil
+}
+
+// header is the header of a DNS message, see RFC 1035 4.1.1
+type header struct {
+	id      uint16
+	rcode   int
+	ancount uint16
+}
+
+// unpackHeader returns the header of a response
+func unpackHeader(msg []byte) (header, error) {
+	var h header
+	if len(msg) < 12 {
+		return h, errors.New("DNS message shorter than its header")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&(1<<15) == 0 {
+		return h, errors.New("DNS message is not a response")
+	}
+	h.id = binary.BigEndian.Uint16(msg[0:])
+	h.rcode = int(flags & 0xf)
+	h.ancount = binary.BigEndian.Uint16(msg[6:])
+	return h, nil
+}
+
+func init() {
+	inputs.Add("dns_query", func() telegraf.Input {
+		return &DNSQuery{}
+	})
+}
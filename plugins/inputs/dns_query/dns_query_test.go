@@ -0,0 +1,157 @@
+package dns_query
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answer returns the response to the query, with two answers to the queries
+// of example.com, and NXDOMAIN otherwise. The answers themselves are not
+// added, only their count.
+func answer(query []byte) []byte {
+	resp := append([]byte{}, query...)
+	flags := binary.BigEndian.Uint16(resp[2:]) | 1<<15
+	want, _ := packQuery(0, "example.com", recordTypes["A"])
+	if string(query[12:]) == string(want[12:]) {
+		binary.BigEndian.PutUint16(resp[6:], 2)
+	} else {
+		flags |= 3
+	}
+	binary.BigEndian.PutUint16(resp[2:], flags)
+	return resp
+}
+
+func udpServer(t *testing.T) net.PacketConn {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := c.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			c.WriteTo(answer(buf[:n]), addr)
+		}
+	}()
+	return c
+}
+
+func tcpServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			l := make([]byte, 2)
+			if _, err := io.ReadFull(c, l); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(l))
+				if _, err := io.ReadFull(c, query); err == nil {
+					resp := answer(query)
+					binary.BigEndian.PutUint16(l, uint16(len(resp)))
+					c.Write(append(l, resp...))
+				}
+			}
+			c.Close()
+		}
+	}()
+	return l
+}
+
+func TestDNSQuery(t *testing.T) {
+	udp := udpServer(t)
+	defer udp.Close()
+	tcp := tcpServer(t)
+	defer tcp.Close()
+
+	for _, tt := range []struct {
+		network string
+		addr    net.Addr
+	}{
+		{"udp", udp.LocalAddr()},
+		{"tcp", tcp.Addr()},
+	} {
+		host, port, err := net.SplitHostPort(tt.addr.String())
+		require.NoError(t, err)
+		d := &DNSQuery{
+			Servers:    []string{host},
+			Network:    tt.network,
+			Domains:    []string{"example.com", "missing.example.com"},
+			RecordType: "a",
+		}
+		require.NoError(t, d.Init())
+		d.Port, err = net.LookupPort(tt.network, port)
+		require.NoError(t, err)
+
+		var acc testutil.Accumulator
+		require.NoError(t, d.Gather(&acc))
+		require.Len(t, acc.Metrics, 2)
+		for _, m := range acc.Metrics {
+			assert.Equal(t, "success", m.Tags["result"])
+			assert.Equal(t, "A", m.Tags["record_type"])
+			assert.Equal(t, Success, m.Fields["result_code"])
+			assert.IsType(t, float64(0), m.Fields["query_time_ms"])
+			switch m.Tags["domain"] {
+			case "example.com":
+				assert.Equal(t, "NOERROR", m.Tags["rcode"])
+				assert.Equal(t, 0, m.Fields["rcode_value"])
+				assert.Equal(t, 2, m.Fields["answers"])
+			case "missing.example.com":
+				assert.Equal(t, "NXDOMAIN", m.Tags["rcode"])
+				assert.Equal(t, 3, m.Fields["rcode_value"])
+				assert.Equal(t, 0, m.Fields["answers"])
+			default:
+				t.Errorf("unexpected domain %q", m.Tags["domain"])
+			}
+		}
+	}
+}
+
+func TestDNSQueryTimeout(t *testing.T) {
+	// The server never answers
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer c.Close()
+	host, port, err := net.SplitHostPort(c.LocalAddr().String())
+	require.NoError(t, err)
+
+	d := &DNSQuery{
+		Servers: []string{host},
+		Timeout: internal.Duration{Duration: 50 * time.Millisecond},
+	}
+	require.NoError(t, d.Init())
+	d.Port, err = net.LookupPort("udp", port)
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	require.NoError(t, d.Gather(&acc))
+	m, ok := acc.Get("dns_query")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		"server":      host,
+		"domain":      ".",
+		"record_type": "NS",
+		"result":      "timeout",
+	}, m.Tags)
+	assert.Equal(t, map[string]interface{}{"result_code": Timeout}, m.Fields)
+}
+
+func TestDNSQueryInvalidConfig(t *testing.T) {
+	for _, d := range []*DNSQuery{
+		{Network: "icmp"},
+		{RecordType: "MD"},
+	} {
+		assert.Error(t, d.Init())
+	}
+}
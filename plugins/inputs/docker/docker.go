@@ -2,20 +2,33 @@ package system
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 
 	"github.com/fsouza/go-dockerclient"
 )
 
 type Docker struct {
-	Endpoint       string
-	ContainerNames []string
+	Endpoint string
+	// TLS certificate, key and CA of a TCP endpoint
+	SSLCert string `toml:"ssl_cert"`
+	SSLKey  string `toml:"ssl_key"`
+	SSLCA   string `toml:"ssl_ca"`
+
+	// Globs of the names of the containers collected and ignored
+	ContainerNameInclude []string `toml:"container_name_include"`
+	ContainerNameExclude []string `toml:"container_name_exclude"`
+	// Globs of the container labels added as tags and ignored
+	LabelInclude []string `toml:"label_include"`
+	LabelExclude []string `toml:"label_exclude"`
+
+	ContainerNames []string        `deprecated:"0.10.2;0.12.0;use 'container_name_include' instead" migrate:"ContainerNameInclude"`
+	Log            telegraf.Logger `toml:"-"`
 
 	client *docker.Client
 }
@@ -25,8 +38,19 @@ var sampleConfig = `
   #   To use TCP, set endpoint = "tcp://[ip]:[port]"
   #   To use environment variables (ie, docker-machine), set endpoint = "ENV"
   endpoint = "unix:///var/run/docker.sock"
-  # Only collect metrics for these containers, collect all if empty
-  container_names = []
+  # TLS certificate, key and CA of a TCP endpoint with TLS enabled
+  # ssl_cert = "/etc/telegraf/docker/cert.pem"
+  # ssl_key = "/etc/telegraf/docker/key.pem"
+  # ssl_ca = "/etc/telegraf/docker/ca.pem"
+
+  # Only collect metrics for the containers matching these globs, collect all
+  # if empty, and ignore those matching the excluded globs
+  container_name_include = []
+  # container_name_exclude = ["telegraf*"]
+
+  # Container labels added as tags, all if empty, except the excluded ones
+  # label_include = []
+  # label_exclude = ["com.docker.compose.*"]
 `
 
 func (d *Docker) Description() string {
@@ -49,6 +73,12 @@ func (d *Docker) Gather(acc telegraf.Accumulator) error {
 			if err != nil {
 				return err
 			}
+		} else if d.SSLCert != "" || d.SSLKey != "" || d.SSLCA != "" {
+			c, err = docker.NewTLSClient(d.Endpoint, d.SSLCert, d.SSLKey,
+				d.SSLCA)
+			if err != nil {
+				return err
+			}
 		} else {
 			c, err = docker.NewClient(d.Endpoint)
 			if err != nil {
@@ -96,10 +126,8 @@ func (d *Docker) gatherContainer(
 		"cont_name":  cname,
 		"cont_image": container.Image,
 	}
-	if len(d.ContainerNames) > 0 {
-		if !sliceContains(cname, d.ContainerNames) {
-			return nil
-		}
+	if !d.containerPass(cname) {
+		return nil
 	}
 
 	statChan := make(chan *docker.Stats)
@@ -115,7 +143,7 @@ func (d *Docker) gatherContainer(
 	go func() {
 		err := d.client.Stats(statOpts)
 		if err != nil {
-			log.Printf("Error getting docker stats: %s\n", err.Error())
+			d.Log.Errorf("Error getting docker stats: %s", err)
 		}
 	}()
 
@@ -128,7 +156,9 @@ func (d *Docker) gatherContainer(
 
 	// Add labels to tags
 	for k, v := range container.Labels {
-		tags[k] = v
+		if d.labelPass(k) {
+			tags[k] = v
+		}
 	}
 
 	gatherContainerStats(stat, acc, tags)
@@ -302,9 +332,27 @@ func copyTags(in map[string]string) map[string]string {
 	return out
 }
 
-func sliceContains(in string, sl []string) bool {
-	for _, str := range sl {
-		if str == in {
+// containerPass returns true if the container is collected, its name
+// matching the included globs, if any, but none of the excluded globs
+func (d *Docker) containerPass(name string) bool {
+	return globsPass(name, d.ContainerNameInclude, d.ContainerNameExclude)
+}
+
+// labelPass returns true if the container label is added as a tag
+func (d *Docker) labelPass(label string) bool {
+	return globsPass(label, d.LabelInclude, d.LabelExclude)
+}
+
+func globsPass(s string, include []string, exclude []string) bool {
+	if len(include) > 0 && !globsMatch(s, include) {
+		return false
+	}
+	return !globsMatch(s, exclude)
+}
+
+func globsMatch(s string, globs []string) bool {
+	for _, glob := range globs {
+		if internal.Glob(glob, s) {
 			return true
 		}
 	}
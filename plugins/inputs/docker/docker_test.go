@@ -5,10 +5,33 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/fsouza/go-dockerclient"
 )
 
+func TestDockerFilters(t *testing.T) {
+	d := &Docker{}
+	assert.True(t, d.containerPass("redis"))
+	assert.True(t, d.labelPass("maintainer"))
+
+	d = &Docker{
+		ContainerNameInclude: []string{"redis*", "nginx"},
+		ContainerNameExclude: []string{"*-test"},
+		LabelExclude:         []string{"com.docker.compose.*"},
+	}
+	assert.True(t, d.containerPass("redis-cache"))
+	assert.True(t, d.containerPass("nginx"))
+	assert.False(t, d.containerPass("nginx-proxy"))
+	assert.False(t, d.containerPass("redis-test"))
+	assert.True(t, d.labelPass("maintainer"))
+	assert.False(t, d.labelPass("com.docker.compose.project"))
+
+	d = &Docker{LabelInclude: []string{"app"}}
+	assert.True(t, d.labelPass("app"))
+	assert.False(t, d.labelPass("maintainer"))
+}
+
 func TestDockerGatherContainerStats(t *testing.T) {
 	var acc testutil.Accumulator
 	stats := testStats()
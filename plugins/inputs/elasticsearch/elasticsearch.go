@@ -11,12 +11,14 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 const statsPath = "/_nodes/stats"
 const statsPathLocal = "/_nodes/_local/stats"
 const healthPath = "/_cluster/health"
+const indicesStatsPath = "/_stats"
 
 type node struct {
 	Host       string            `json:"host"`
@@ -47,6 +49,16 @@ type clusterHealth struct {
 	Indices             map[string]indexHealth `json:"indices"`
 }
 
+type indicesStats struct {
+	All     indexStats            `json:"_all"`
+	Indices map[string]indexStats `json:"indices"`
+}
+
+type indexStats struct {
+	Primaries interface{} `json:"primaries"`
+	Total     interface{} `json:"total"`
+}
+
 type indexHealth struct {
 	Status              string `json:"status"`
 	NumberOfShards      int    `json:"number_of_shards"`
@@ -68,20 +80,74 @@ const sampleConfig = `
 
   # set cluster_health to true when you want to also obtain cluster level stats
   cluster_health = false
+
+  # set indices_stats to true when you want to also obtain the stats of the
+  # indices_include indices, all indices if empty
+  indices_stats = false
+  indices_include = []
+
+  # The cluster health and the stats of the indices are cluster level stats,
+  # gathered from every server: list a single server per cluster to gather
+  # them once
+
+  # HTTP basic auth and timeout of the requests
+  # username = ""
+  # password = ""
+  # timeout = "5s"
+
+  # TLS options of the https servers
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
 `
 
 // Elasticsearch is a plugin to read stats from one or many Elasticsearch
 // servers.
 type Elasticsearch struct {
-	Local         bool
-	Servers       []string
-	ClusterHealth bool
-	client        *http.Client
+	Local          bool
+	Servers        []string
+	ClusterHealth  bool
+	IndicesStats   bool     `toml:"indices_stats"`
+	IndicesInclude []string `toml:"indices_include"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	Username           string
+	Password           string
+	Timeout            internal.Duration
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	client *http.Client
 }
 
 // NewElasticsearch return a new instance of Elasticsearch
 func NewElasticsearch() *Elasticsearch {
-	return &Elasticsearch{client: http.DefaultClient}
+	return &Elasticsearch{client: &http.Client{}}
+}
+
+// Init creates the HTTP client of the plugin from its options
+func (e *Elasticsearch) Init() error {
+	c := httpconfig.Config{
+		Timeout:  e.Timeout.Duration,
+		Username: e.Username,
+		Password: e.Password,
+		TLS: internal.TLSOptions{
+			SSLCA:              e.SSLCA,
+			SSLCert:            e.SSLCert,
+			SSLKey:             e.SSLKey,
+			InsecureSkipVerify: e.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	e.client = client
+	return nil
 }
 
 // SampleConfig returns sample configuration for this plugin.
@@ -115,7 +181,22 @@ func (e *Elasticsearch) Gather(acc telegraf.Accumulator) error {
 				return
 			}
 			if e.ClusterHealth {
-				e.gatherClusterStats(fmt.Sprintf("%s/_cluster/health?level=indices", s), acc)
+				url := s + healthPath + "?level=indices"
+				if err := e.gatherClusterStats(url, acc); err != nil {
+					errChan <- err
+					return
+				}
+			}
+			if e.IndicesStats {
+				url := s + indicesStatsPath
+				if len(e.IndicesInclude) > 0 {
+					url = s + "/" + strings.Join(e.IndicesInclude, ",") +
+						indicesStatsPath
+				}
+				if err := e.gatherIndicesStats(url, acc); err != nil {
+					errChan <- err
+					return
+				}
 			}
 		}(serv, acc)
 	}
@@ -224,6 +305,32 @@ func (e *Elasticsearch) gatherClusterStats(url string, acc telegraf.Accumulator)
 	return nil
 }
 
+// gatherIndicesStats adds the stats of the primary shards and of all the
+// shards of each index, and of all indices as the _all index_name
+func (e *Elasticsearch) gatherIndicesStats(url string, acc telegraf.Accumulator) error {
+	stats := &indicesStats{}
+	if err := e.gatherData(url, stats); err != nil {
+		return err
+	}
+	now := time.Now()
+	indices := map[string]indexStats{"_all": stats.All}
+	for name, index := range stats.Indices {
+		indices[name] = index
+	}
+	for name, index := range indices {
+		f := internal.JSONFlattener{}
+		if err := f.FlattenJSON("primaries", index.Primaries); err != nil {
+			return err
+		}
+		if err := f.FlattenJSON("total", index.Total); err != nil {
+			return err
+		}
+		acc.AddFields("elasticsearch_indices_stats", f.Fields,
+			map[string]string{"index_name": name}, now)
+	}
+	return nil
+}
+
 func (e *Elasticsearch) gatherData(url string, v interface{}) error {
 	r, err := e.client.Get(url)
 	if err != nil {
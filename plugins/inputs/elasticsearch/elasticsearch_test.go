@@ -84,3 +84,19 @@ func TestGatherClusterStats(t *testing.T) {
 		v2IndexExpected,
 		map[string]string{"index": "v2"})
 }
+
+func TestGatherIndicesStats(t *testing.T) {
+	es := NewElasticsearch()
+	es.Servers = []string{"http://example.com:9200"}
+	es.IndicesStats = true
+	es.IndicesInclude = []string{"twitter"}
+	es.client.Transport = newTransportMock(http.StatusOK, indicesStatsResponse)
+
+	var acc testutil.Accumulator
+	require.NoError(t, es.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "elasticsearch_indices_stats",
+		twitterIndexStatsExpected,
+		map[string]string{"index_name": "twitter"})
+	require.True(t, acc.HasMeasurement("elasticsearch_indices_stats"))
+}
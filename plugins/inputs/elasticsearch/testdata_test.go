@@ -71,6 +71,67 @@ var v2IndexExpected = map[string]interface{}{
 	"unassigned_shards":     20,
 }
 
+const indicesStatsResponse = `
+{
+   "_shards": {
+      "total": 10,
+      "successful": 5,
+      "failed": 0
+   },
+   "_all": {
+      "primaries": {
+         "docs": {
+            "count": 3,
+            "deleted": 0
+         },
+         "store": {
+            "size_in_bytes": 9214
+         }
+      },
+      "total": {
+         "docs": {
+            "count": 3,
+            "deleted": 0
+         },
+         "store": {
+            "size_in_bytes": 9214
+         }
+      }
+   },
+   "indices": {
+      "twitter": {
+         "primaries": {
+            "docs": {
+               "count": 3,
+               "deleted": 0
+            },
+            "store": {
+               "size_in_bytes": 9214
+            }
+         },
+         "total": {
+            "docs": {
+               "count": 6,
+               "deleted": 0
+            },
+            "store": {
+               "size_in_bytes": 18428
+            }
+         }
+      }
+   }
+}
+`
+
+var twitterIndexStatsExpected = map[string]interface{}{
+	"primaries_docs_count":          float64(3),
+	"primaries_docs_deleted":        float64(0),
+	"primaries_store_size_in_bytes": float64(9214),
+	"total_docs_count":              float64(6),
+	"total_docs_deleted":            float64(0),
+	"total_store_size_in_bytes":     float64(18428),
+}
+
 const statsResponse = `
 {
   "cluster_name": "es-testcluster",
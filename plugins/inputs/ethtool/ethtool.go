@@ -0,0 +1,130 @@
+// +build linux
+
+package ethtool
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Command reads the interfaces, and the drivers and statistics of their
+// NICs. It is replaced by a mock in the tests.
+type Command interface {
+	Interfaces() ([]net.Interface, error)
+	DriverName(intf string) (string, error)
+	Stats(intf string) (map[string]uint64, error)
+}
+
+// Ethtool reads the statistics of the drivers of the NICs, as ethtool -S
+type Ethtool struct {
+	InterfaceInclude []string `toml:"interface_include"`
+	InterfaceExclude []string `toml:"interface_exclude"`
+
+	command Command
+}
+
+var sampleConfig = `
+  # Interfaces gathered, with * globs, ie "eth*". All the interfaces are
+  # gathered if empty, but the loopback.
+  # interface_include = ["eth0"]
+
+  # Interfaces not gathered, with * globs, ie "veth*"
+  # interface_exclude = ["docker0"]
+`
+
+func (e *Ethtool) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Ethtool) Description() string {
+	return "Gather the statistics of the drivers of the NICs, as ethtool -S"
+}
+
+func (e *Ethtool) Gather(acc telegraf.Accumulator) error {
+	if e.command == nil {
+		e.command = ioctlCommand{}
+	}
+	interfaces, err := e.command.Interfaces()
+	if err != nil {
+		return err
+	}
+
+	var errorStrings []string
+	for _, intf := range interfaces {
+		if !e.selected(intf) {
+			continue
+		}
+		driver, err := e.command.DriverName(intf.Name)
+		if err == syscall.EOPNOTSUPP {
+			// The virtual interfaces without driver statistics
+			continue
+		}
+		if err != nil {
+			errorStrings = append(errorStrings,
+				fmt.Sprintf("reading the driver of %s: %s", intf.Name, err))
+			continue
+		}
+		stats, err := e.command.Stats(intf.Name)
+		if err == syscall.EOPNOTSUPP {
+			continue
+		}
+		if err != nil {
+			errorStrings = append(errorStrings,
+				fmt.Sprintf("reading the statistics of %s: %s", intf.Name,
+					err))
+			continue
+		}
+		if len(stats) == 0 {
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(stats))
+		for name, value := range stats {
+			fields[name] = value
+		}
+		tags := map[string]string{
+			"interface": intf.Name,
+			"driver":    driver,
+		}
+		acc.AddFields("ethtool", fields, tags, time.Now())
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// selected returns whether the interface is included and not excluded, the
+// loopback only being gathered if included
+func (e *Ethtool) selected(intf net.Interface) bool {
+	for _, glob := range e.InterfaceExclude {
+		if internal.Glob(glob, intf.Name) {
+			return false
+		}
+	}
+	if len(e.InterfaceInclude) == 0 {
+		return intf.Flags&net.FlagLoopback == 0
+	}
+	for _, glob := range e.InterfaceInclude {
+		if internal.Glob(glob, intf.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	inputs.Add("ethtool", func() telegraf.Input {
+		return &Ethtool{
+			command: ioctlCommand{},
+		}
+	})
+}
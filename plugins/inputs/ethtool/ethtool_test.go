@@ -0,0 +1,126 @@
+// +build linux
+
+package ethtool
+
+import (
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCommand is a Command returning the drivers and statistics of the
+// interfaces, the interfaces without driver not supporting ethtool
+type mockCommand struct {
+	interfaces []net.Interface
+	drivers    map[string]string
+	stats      map[string]map[string]uint64
+}
+
+func (c *mockCommand) Interfaces() ([]net.Interface, error) {
+	return c.interfaces, nil
+}
+
+func (c *mockCommand) DriverName(intf string) (string, error) {
+	driver, ok := c.drivers[intf]
+	if !ok {
+		return "", syscall.EOPNOTSUPP
+	}
+	return driver, nil
+}
+
+func (c *mockCommand) Stats(intf string) (map[string]uint64, error) {
+	stats, ok := c.stats[intf]
+	if !ok {
+		return nil, errors.New("no such device")
+	}
+	return stats, nil
+}
+
+func newMockCommand() *mockCommand {
+	return &mockCommand{
+		interfaces: []net.Interface{
+			{Name: "lo", Flags: net.FlagUp | net.FlagLoopback},
+			{Name: "eth0", Flags: net.FlagUp},
+			{Name: "eth1", Flags: net.FlagUp},
+			{Name: "docker0", Flags: net.FlagUp},
+		},
+		drivers: map[string]string{
+			"lo":   "loopback",
+			"eth0": "igb",
+			"eth1": "ixgbe",
+		},
+		stats: map[string]map[string]uint64{
+			"lo": {"rx_packets": 1},
+			"eth0": {
+				"rx_packets":          1422,
+				"rx_fifo_errors":      3,
+				"rx_queue_0_packets":  1000,
+				"rx_queue_1_packets":  422,
+				"rx_missed_errors":    0,
+				"tx_restart_queue":    2,
+				"rx_no_buffer_count":  5,
+				"tx_timeout_count":    0,
+				"os2bmc_rx_by_bmc":    0,
+				"rx_long_byte_count":  123456789,
+				"tx_dma_out_of_sync":  0,
+				"rx_hwtstamp_cleared": 0,
+			},
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	e := &Ethtool{command: newMockCommand()}
+	var acc testutil.Accumulator
+	err := e.Gather(&acc)
+	// The statistics of eth1 cannot be read, docker0 having no driver
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "eth1")
+
+	assert.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "ethtool", map[string]interface{}{
+		"rx_packets":          uint64(1422),
+		"rx_fifo_errors":      uint64(3),
+		"rx_queue_0_packets":  uint64(1000),
+		"rx_queue_1_packets":  uint64(422),
+		"rx_missed_errors":    uint64(0),
+		"tx_restart_queue":    uint64(2),
+		"rx_no_buffer_count":  uint64(5),
+		"tx_timeout_count":    uint64(0),
+		"os2bmc_rx_by_bmc":    uint64(0),
+		"rx_long_byte_count":  uint64(123456789),
+		"tx_dma_out_of_sync":  uint64(0),
+		"rx_hwtstamp_cleared": uint64(0),
+	}, map[string]string{"interface": "eth0", "driver": "igb"})
+}
+
+func TestGatherIncludeExclude(t *testing.T) {
+	e := &Ethtool{
+		InterfaceInclude: []string{"eth*", "lo"},
+		InterfaceExclude: []string{"eth1"},
+		command:          newMockCommand(),
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, e.Gather(&acc))
+
+	// The loopback is gathered once included
+	assert.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "ethtool",
+		map[string]interface{}{"rx_packets": uint64(1)},
+		map[string]string{"interface": "lo", "driver": "loopback"})
+}
+
+func TestIoctlCommand(t *testing.T) {
+	// The loopback has no driver statistics, the ioctl being refused
+	_, err := ioctlCommand{}.DriverName("lo")
+	if err != nil {
+		assert.Equal(t, syscall.EOPNOTSUPP, err)
+	}
+	_, err = ioctlCommand{}.Stats("nonexistent0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+// +build linux
+
+package ethtool
+
+import (
+	"net"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// The ethtool ioctl and its commands, see linux/ethtool.h
+const (
+	siocEthtool    = 0x8946
+	ethtoolDrvinfo = 0x03
+	ethtoolStrings = 0x1b
+	ethtoolStats   = 0x1d
+
+	ethSSStats   = 1
+	ethStringLen = 32
+)
+
+// ifreq is the request of the ethtool ioctl, the name of the interface and
+// the buffer of the command, padded to the size of struct ifreq
+type ifreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data uintptr
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// drvinfo is struct ethtool_drvinfo
+type drvinfo struct {
+	cmd         uint32
+	driver      [32]byte
+	version     [32]byte
+	fwVersion   [32]byte
+	busInfo     [32]byte
+	eromVersion [32]byte
+	reserved2   [12]byte
+	nPrivFlags  uint32
+	nStats      uint32
+	testinfoLen uint32
+	eedumpLen   uint32
+	regdumpLen  uint32
+}
+
+// ioctlCommand reads the statistics of the NICs with the ethtool ioctls
+type ioctlCommand struct{}
+
+func (c ioctlCommand) Interfaces() ([]net.Interface, error) {
+	return net.Interfaces()
+}
+
+// ioctl runs the ethtool command of the buffer on the interface
+func ioctl(intf string, data unsafe.Pointer) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req ifreq
+	copy(req.name[:syscall.IFNAMSIZ-1], intf)
+	req.data = uintptr(data)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd),
+		siocEthtool, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// cString returns the string of a NUL terminated buffer
+func cString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+func (c ioctlCommand) drvinfo(intf string) (*drvinfo, error) {
+	info := &drvinfo{cmd: ethtoolDrvinfo}
+	if err := ioctl(intf, unsafe.Pointer(info)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c ioctlCommand) DriverName(intf string) (string, error) {
+	info, err := c.drvinfo(intf)
+	if err != nil {
+		return "", err
+	}
+	return cString(info.driver[:]), nil
+}
+
+// Stats returns the statistics of the driver, their names being read with
+// ETHTOOL_GSTRINGS and their values with ETHTOOL_GSTATS, in the same order
+func (c ioctlCommand) Stats(intf string) (map[string]uint64, error) {
+	info, err := c.drvinfo(intf)
+	if err != nil {
+		return nil, err
+	}
+	n := int(info.nStats)
+	if n == 0 {
+		return nil, nil
+	}
+
+	// struct ethtool_gstrings, the command, string set and length being
+	// followed by the strings
+	strs := make([]byte, 12+n*ethStringLen)
+	*(*uint32)(unsafe.Pointer(&strs[0])) = ethtoolStrings
+	*(*uint32)(unsafe.Pointer(&strs[4])) = ethSSStats
+	*(*uint32)(unsafe.Pointer(&strs[8])) = uint32(n)
+	if err := ioctl(intf, unsafe.Pointer(&strs[0])); err != nil {
+		return nil, err
+	}
+
+	// struct ethtool_stats, the command and number of statistics being
+	// followed by their values
+	values := make([]uint64, 1+n)
+	*(*uint32)(unsafe.Pointer(&values[0])) = ethtoolStats
+	*(*uint32)(unsafe.Pointer(uintptr(unsafe.Pointer(&values[0])) + 4)) =
+		uint32(n)
+	if err := ioctl(intf, unsafe.Pointer(&values[0])); err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]uint64, n)
+	for i := 0; i < n; i++ {
+		name := cString(strs[12+i*ethStringLen : 12+(i+1)*ethStringLen])
+		// Some drivers indent or space their names
+		name = strings.Replace(strings.TrimSpace(name), " ", "_", -1)
+		if name != "" {
+			stats[name] = values[1+i]
+		}
+	}
+	return stats, nil
+}
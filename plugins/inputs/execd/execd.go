@@ -0,0 +1,147 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  # Program to run as daemon, the first element is the executable and the
+  # remaining elements are its arguments.
+  # The program must write metrics to stdout in influx line protocol, one
+  # metric per line. Anything written to stderr is logged.
+  command = ["/usr/bin/mycollector", "--foo=bar"]
+
+  # Define how the program is signaled on every interval:
+  #   "none"  : the program writes metrics on its own schedule
+  #   "STDIN" : a newline is written to the program's stdin
+  signal = "none"
+
+  # Delay before the program is restarted after an unexpected exit
+  restart_delay = "10s"
+`
+
+type Execd struct {
+	Command      []string
+	Signal       string
+	RestartDelay internal.Duration
+	Log          telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	process *process.Process
+	metrics []telegraf.Metric
+
+	// Statistics reported by the internal input
+	linesRead   selfstat.Stat
+	parseErrors selfstat.Stat
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		Signal:       "none",
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run an external program as a daemon and read metrics from its stdout"
+}
+
+// Start launches the external program and begins reading the metrics it
+// writes to stdout. The program is restarted if it exits before Stop is called.
+func (e *Execd) Start() error {
+	switch strings.ToLower(e.Signal) {
+	case "", "none", "stdin":
+	default:
+		return fmt.Errorf("execd: unknown signal %q, must be none or STDIN",
+			e.Signal)
+	}
+
+	tags := map[string]string{"command": strings.Join(e.Command, " ")}
+	e.linesRead = selfstat.Register("execd", "lines_read", tags)
+	e.parseErrors = selfstat.Register("execd", "parse_errors", tags)
+
+	p, err := process.New(e.Command, e.Log)
+	if err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	p.RestartDelay = e.RestartDelay.Duration
+	p.ReadStdout = e.readMetrics
+	e.process = p
+
+	if err := p.Start(); err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	return nil
+}
+
+// Stop closes the program's stdin and waits for it to exit, killing it if it
+// does not exit in a timely manner.
+func (e *Execd) Stop() {
+	if e.process != nil {
+		e.process.Stop()
+		selfstat.Unregister(e.linesRead)
+		selfstat.Unregister(e.parseErrors)
+	}
+}
+
+// Gather signals the program, if configured, and adds the metrics written by
+// the program since the last call.
+func (e *Execd) Gather(acc telegraf.Accumulator) error {
+	if strings.ToLower(e.Signal) == "stdin" {
+		if _, err := io.WriteString(e.process, "\n"); err != nil {
+			return fmt.Errorf("execd: unable to signal %s: %s",
+				e.Command[0], err)
+		}
+	}
+
+	e.Lock()
+	metrics := e.metrics
+	e.metrics = nil
+	e.Unlock()
+
+	for _, m := range metrics {
+		acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	}
+	return nil
+}
+
+func (e *Execd) readMetrics(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.linesRead.Incr(1)
+		metrics, err := telegraf.ParseMetrics(scanner.Bytes())
+		if err != nil {
+			e.parseErrors.Incr(1)
+			e.Log.Errorf("Unable to parse output of %s: %s",
+				e.Command[0], err)
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+
+		e.Lock()
+		e.metrics = append(e.metrics, metrics...)
+		e.Unlock()
+	}
+}
+
+func init() {
+	inputs.Add("execd", func() telegraf.Input {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,74 @@
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForMetrics gathers until at least n metrics have been accumulated.
+func waitForMetrics(t *testing.T, e *Execd, acc *testutil.Accumulator, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(acc.Metrics) < n && time.Now().Before(deadline) {
+		require.NoError(t, e.Gather(acc))
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecdSignalNone(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"sh", "-c",
+		"echo 'cpu,host=localhost usage=42i 1453831884000000000'; cat"}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	var acc testutil.Accumulator
+	waitForMetrics(t, e, &acc, 1)
+
+	require.Len(t, acc.Metrics, 1)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage": int64(42)},
+		map[string]string{"host": "localhost"})
+}
+
+func TestExecdSignalStdin(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Signal = "STDIN"
+	e.Command = []string{"sh", "-c",
+		"while read line; do echo 'cpu usage=42i'; done"}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	var acc testutil.Accumulator
+	waitForMetrics(t, e, &acc, 1)
+
+	require.NotEmpty(t, acc.Metrics)
+	assert.True(t, acc.HasIntField("cpu", "usage"))
+}
+
+func TestExecdUnknownSignal(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Signal = "SIGUSR1"
+	e.Command = []string{"cat"}
+	assert.Error(t, e.Start())
+}
+
+func TestExecdParseErrors(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"sh", "-c", "echo 'not a metric'; echo 'cpu usage=42i'; cat"}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	var acc testutil.Accumulator
+	waitForMetrics(t, e, &acc, 1)
+
+	assert.Equal(t, int64(2), e.linesRead.Get())
+	assert.Equal(t, int64(1), e.parseErrors.Get())
+}
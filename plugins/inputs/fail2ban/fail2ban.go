@@ -0,0 +1,192 @@
+package fail2ban
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs fail2ban-client with the arguments, killing it after the
+// timeout, and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Fail2ban reads the failures and bans of the jails of fail2ban with
+// fail2ban-client
+type Fail2ban struct {
+	Binary  string
+	UseSudo bool `toml:"use_sudo"`
+	Timeout internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of fail2ban-client
+  binary = "/usr/bin/fail2ban-client"
+
+  # Run fail2ban-client with sudo, which must be allowed without password,
+  # the socket of fail2ban being only writable by root
+  # use_sudo = false
+
+  # Timeout of fail2ban-client
+  # timeout = "5s"
+`
+
+func (f *Fail2ban) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *Fail2ban) Description() string {
+	return "Gather the failures and bans of the jails of fail2ban"
+}
+
+// statusFields are the fields of the lines of the status of a jail
+var statusFields = map[string]string{
+	"Currently failed": "failed",
+	"Total failed":     "total_failed",
+	"Currently banned": "banned",
+	"Total banned":     "total_banned",
+}
+
+func (f *Fail2ban) Gather(acc telegraf.Accumulator) error {
+	if f.runner == nil {
+		f.runner = runFail2banClient
+	}
+	if f.Binary == "" {
+		f.Binary = "/usr/bin/fail2ban-client"
+	}
+	if f.Timeout.Duration == 0 {
+		f.Timeout.Duration = 5 * time.Second
+	}
+
+	out, err := f.run("status")
+	if err != nil {
+		return fmt.Errorf("listing the jails: %s", err)
+	}
+	var errorStrings []string
+	for _, jail := range parseJails(out) {
+		out, err := f.run("status", jail)
+		if err != nil {
+			errorStrings = append(errorStrings,
+				fmt.Sprintf("reading the status of %s: %s", jail, err))
+			continue
+		}
+		fields := parseStatus(out)
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("fail2ban", fields, map[string]string{"jail": jail},
+			time.Now())
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// run runs fail2ban-client, with sudo if use_sudo is set
+func (f *Fail2ban) run(args ...string) (string, error) {
+	path := f.Binary
+	if f.UseSudo {
+		path, args = "sudo", append([]string{"-n", f.Binary}, args...)
+	}
+	out, err := f.runner(f.Timeout.Duration, path, args...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// keyValue returns the key and value of a line of a status, listed as
+//
+//	|  |- Currently failed:	0
+//
+// and whether the line has one
+func keyValue(line string) (string, string, bool) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	key := strings.TrimLeft(parts[0], " |`-")
+	return key, strings.TrimSpace(parts[1]), true
+}
+
+// parseJails returns the jails of the status of fail2ban
+func parseJails(out string) []string {
+	var jails []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, v, ok := keyValue(scanner.Text())
+		if !ok || key != "Jail list" {
+			continue
+		}
+		for _, jail := range strings.Split(v, ",") {
+			if jail = strings.TrimSpace(jail); jail != "" {
+				jails = append(jails, jail)
+			}
+		}
+	}
+	return jails
+}
+
+// parseStatus returns the fields of the status of a jail
+func parseStatus(out string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, v, ok := keyValue(scanner.Text())
+		if !ok {
+			continue
+		}
+		name, ok := statusFields[key]
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			fields[name] = n
+		}
+	}
+	return fields
+}
+
+// runFail2banClient runs fail2ban-client, killing it after the timeout
+func runFail2banClient(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("fail2ban-client timed out after %s",
+			timeout)
+	}
+}
+
+func init() {
+	inputs.Add("fail2ban", func() telegraf.Input {
+		return &Fail2ban{
+			Binary:  "/usr/bin/fail2ban-client",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			runner:  runFail2banClient,
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package fail2ban
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statusOutput = "Status\n" +
+	"|- Number of jail:\t2\n" +
+	"`- Jail list:\tsshd, postfix\n"
+
+const sshdOutput = "Status for the jail: sshd\n" +
+	"|- Filter\n" +
+	"|  |- Currently failed:\t3\n" +
+	"|  |- Total failed:\t257\n" +
+	"|  `- File list:\t/var/log/auth.log\n" +
+	"`- Actions\n" +
+	"   |- Currently banned:\t2\n" +
+	"   |- Total banned:\t31\n" +
+	"   `- Banned IP list:\t192.168.0.1 192.168.0.2\n"
+
+func TestGather(t *testing.T) {
+	var calls []string
+	f := &Fail2ban{
+		Binary:  "fail2ban-client",
+		UseSudo: true,
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			switch args[len(args)-1] {
+			case "status":
+				return statusOutput, nil
+			case "sshd":
+				return sshdOutput, nil
+			}
+			return "Sorry but the jail 'postfix' does not exist",
+				errors.New("exit status 255")
+		},
+	}
+	var acc testutil.Accumulator
+	err := f.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "postfix")
+
+	assert.Equal(t, []string{
+		"sudo -n fail2ban-client status",
+		"sudo -n fail2ban-client status sshd",
+		"sudo -n fail2ban-client status postfix",
+	}, calls)
+	assert.Equal(t, 1, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "fail2ban", map[string]interface{}{
+		"failed":       int64(3),
+		"total_failed": int64(257),
+		"banned":       int64(2),
+		"total_banned": int64(31),
+	}, map[string]string{"jail": "sshd"})
+}
+
+func TestGatherNoJails(t *testing.T) {
+	f := &Fail2ban{
+		Binary: "fail2ban-client",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			return "Status\n|- Number of jail:\t0\n`- Jail list:\t\n", nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, f.Gather(&acc))
+	assert.Equal(t, 0, len(acc.Metrics))
+}
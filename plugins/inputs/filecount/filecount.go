@@ -0,0 +1,172 @@
+package filecount
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// FileCount counts the files of directories, and their total size
+type FileCount struct {
+	Directories []string
+	Name        string
+	Recursive   bool
+	RegularOnly bool `toml:"regular_only"`
+	Size        internal.Size
+	MTime       internal.Duration `toml:"mtime"`
+
+	globs []*globpath.GlobPath
+}
+
+var sampleConfig = `
+  # Directories to gather stats about, with glob patterns:
+  #   /var/spool/**    -> all the directories under /var/spool
+  #   /var/spool/*/new -> the new directories of the spools
+  directories = ["/var/spool/postfix/deferred"]
+
+  # Only count the files with a name matching this pattern
+  name = "*"
+
+  # Count the files in the subdirectories
+  recursive = true
+
+  # Only count the regular files, and not the directories or symlinks
+  regular_only = true
+
+  # Only count the files of at least this size, or at most this size if
+  # negative, ie "10MB" or "-1KB"
+  size = 0
+
+  # Only count the files older than this duration, or newer than this
+  # duration if negative, ie "1h" or "-30m"
+  mtime = "0s"
+`
+
+func (f *FileCount) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FileCount) Description() string {
+	return "Count files in a directory"
+}
+
+// Init compiles the glob patterns of the directories and checks the name
+func (f *FileCount) Init() error {
+	if f.Name == "" {
+		f.Name = "*"
+	}
+	if _, err := filepath.Match(f.Name, ""); err != nil {
+		return fmt.Errorf("invalid name %q: %s", f.Name, err)
+	}
+	f.globs = nil
+	for _, dir := range f.Directories {
+		g, err := globpath.Compile(dir)
+		if err != nil {
+			return fmt.Errorf("invalid directory %q: %s", dir, err)
+		}
+		f.globs = append(f.globs, g)
+	}
+	return nil
+}
+
+// Gather adds a point per directory matched
+func (f *FileCount) Gather(acc telegraf.Accumulator) error {
+	var errorStrings []string
+	now := time.Now()
+	for _, g := range f.globs {
+		for _, dir := range g.Match() {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := f.count(dir, now, acc); err != nil {
+				errorStrings = append(errorStrings, err.Error())
+			}
+		}
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// count adds the number of files of the directory matching the filters, and
+// their total size
+func (f *FileCount) count(dir string, now time.Time, acc telegraf.Accumulator) error {
+	var count, size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// The files removed while walking the directory are skipped
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if f.match(info, now) {
+			count++
+			size += info.Size()
+		}
+		if info.IsDir() && !f.Recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	acc.AddFields("filecount",
+		map[string]interface{}{
+			"count":      count,
+			"size_bytes": size,
+		},
+		map[string]string{"directory": dir})
+	return nil
+}
+
+// match returns whether the file matches the name, type, size and
+// modification time filters
+func (f *FileCount) match(info os.FileInfo, now time.Time) bool {
+	if ok, _ := filepath.Match(f.Name, info.Name()); !ok {
+		return false
+	}
+	if f.RegularOnly && !info.Mode().IsRegular() {
+		return false
+	}
+
+	if f.Size.Size > 0 && info.Size() < f.Size.Size {
+		return false
+	}
+	if f.Size.Size < 0 && info.Size() > -f.Size.Size {
+		return false
+	}
+
+	age := now.Sub(info.ModTime())
+	if f.MTime.Duration > 0 && age < f.MTime.Duration {
+		return false
+	}
+	if f.MTime.Duration < 0 && age > -f.MTime.Duration {
+		return false
+	}
+	return true
+}
+
+func init() {
+	inputs.Add("filecount", func() telegraf.Input {
+		return &FileCount{
+			Name:        "*",
+			Recursive:   true,
+			RegularOnly: true,
+		}
+	})
+}
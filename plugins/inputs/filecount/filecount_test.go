@@ -0,0 +1,109 @@
+package filecount
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spool creates a spool directory, with a file of 10 bytes modified an hour
+// ago, two files of 100 bytes and one of 1000 bytes in a subdirectory
+func spool(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "filecount")
+	require.NoError(t, err)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	for file, size := range map[string]int{
+		"old.msg":   10,
+		"a.msg":     100,
+		"b.tmp":     100,
+		"sub/c.msg": 1000,
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, file),
+			make([]byte, size), 0644))
+	}
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "old.msg"), old, old))
+	return dir
+}
+
+// count returns the count and size_bytes fields of the directory
+func count(t *testing.T, f *FileCount, dir string) (interface{}, interface{}) {
+	f.Directories = []string{dir}
+	require.NoError(t, f.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, f.Gather(&acc))
+	m, ok := acc.Get("filecount")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"directory": dir}, m.Tags)
+	return m.Fields["count"], m.Fields["size_bytes"]
+}
+
+func TestCount(t *testing.T) {
+	dir := spool(t)
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		f     FileCount
+		count int64
+		size  int64
+	}{
+		{FileCount{Recursive: true, RegularOnly: true}, 4, 1210},
+		{FileCount{RegularOnly: true}, 3, 210},
+		// The sub directory is counted, with its size
+		{FileCount{}, 4, -1},
+		{FileCount{Name: "*.msg", Recursive: true, RegularOnly: true}, 3, 1110},
+		{FileCount{
+			Recursive:   true,
+			RegularOnly: true,
+			Size:        internal.Size{Size: 100},
+		}, 3, 1200},
+		{FileCount{
+			Recursive:   true,
+			RegularOnly: true,
+			Size:        internal.Size{Size: -100},
+		}, 3, 210},
+		{FileCount{
+			Recursive:   true,
+			RegularOnly: true,
+			MTime:       internal.Duration{Duration: 30 * time.Minute},
+		}, 1, 10},
+		{FileCount{
+			Recursive:   true,
+			RegularOnly: true,
+			MTime:       internal.Duration{Duration: -30 * time.Minute},
+		}, 3, 1200},
+	} {
+		c, size := count(t, &tt.f, dir)
+		assert.Equal(t, tt.count, c)
+		if tt.size >= 0 {
+			assert.Equal(t, tt.size, size)
+		}
+	}
+}
+
+func TestCountGlobs(t *testing.T) {
+	dir := spool(t)
+	defer os.RemoveAll(dir)
+
+	f := &FileCount{
+		Directories: []string{filepath.Join(dir, "*")},
+		Recursive:   true,
+		RegularOnly: true,
+	}
+	require.NoError(t, f.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, f.Gather(&acc))
+	// Only the directories matched are counted
+	acc.AssertContainsTaggedFields(t, "filecount", map[string]interface{}{
+		"count":      int64(1),
+		"size_bytes": int64(1000),
+	}, map[string]string{"directory": filepath.Join(dir, "sub")})
+	assert.Equal(t, 1, len(acc.Metrics))
+}
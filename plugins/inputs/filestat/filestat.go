@@ -0,0 +1,131 @@
+package filestat
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// FileStat reports the existence, size and modification time of files
+type FileStat struct {
+	Files []string
+	Md5   bool
+
+	globs []*globpath.GlobPath
+}
+
+var sampleConfig = `
+  # Files to gather stats about, with glob patterns:
+  #   /var/log/**.log     -> recursively find all .log files in /var/log
+  #   /var/log/*/*.log    -> find all .log files with a parent dir in /var/log
+  #   /var/log/apache.log -> only the apache log file
+  files = ["/var/log/**.log"]
+
+  # Whether to compute the md5 checksum of the files
+  md5 = false
+`
+
+func (f *FileStat) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FileStat) Description() string {
+	return "Read stats about given file(s)"
+}
+
+// Init compiles the glob patterns of the files
+func (f *FileStat) Init() error {
+	f.globs = nil
+	for _, file := range f.Files {
+		g, err := globpath.Compile(file)
+		if err != nil {
+			return fmt.Errorf("invalid file %q: %s", file, err)
+		}
+		f.globs = append(f.globs, g)
+	}
+	return nil
+}
+
+// Gather adds a point per file matched. The files without wildcards are
+// reported as missing when they do not exist.
+func (f *FileStat) Gather(acc telegraf.Accumulator) error {
+	var errorStrings []string
+	for i, g := range f.globs {
+		files := g.Match()
+		if len(files) == 0 && !g.HasMeta() {
+			acc.AddFields("filestat",
+				map[string]interface{}{"exists": int64(0)},
+				map[string]string{"file": f.Files[i]})
+			continue
+		}
+
+		for _, file := range files {
+			if err := f.gatherFile(file, acc); err != nil {
+				errorStrings = append(errorStrings, err.Error())
+			}
+		}
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// gatherFile adds the stats of the file
+func (f *FileStat) gatherFile(file string, acc telegraf.Accumulator) error {
+	tags := map[string]string{"file": file}
+	info, err := os.Stat(file)
+	if os.IsNotExist(err) {
+		// The file was removed since it was matched
+		acc.AddFields("filestat",
+			map[string]interface{}{"exists": int64(0)}, tags)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"exists":            int64(1),
+		"size_bytes":        info.Size(),
+		"modification_time": info.ModTime().UnixNano(),
+	}
+	if f.Md5 && info.Mode().IsRegular() {
+		sum, err := md5Sum(file)
+		if err != nil {
+			return err
+		}
+		fields["md5_sum"] = sum
+	}
+	acc.AddFields("filestat", fields, tags)
+	return nil
+}
+
+// md5Sum returns the hex md5 checksum of the contents of the file
+func md5Sum(file string) (string, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func init() {
+	inputs.Add("filestat", func() telegraf.Input {
+		return &FileStat{}
+	})
+}
@@ -0,0 +1,48 @@
+package filestat
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestat")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(log, []byte("hello\n"), 0644))
+	missing := filepath.Join(dir, "missing.log")
+
+	f := &FileStat{
+		Files: []string{log, missing, filepath.Join(dir, "*.gz")},
+		Md5:   true,
+	}
+	require.NoError(t, f.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, f.Gather(&acc))
+
+	info, err := os.Stat(log)
+	require.NoError(t, err)
+	acc.AssertContainsTaggedFields(t, "filestat", map[string]interface{}{
+		"exists":            int64(1),
+		"size_bytes":        int64(6),
+		"modification_time": info.ModTime().UnixNano(),
+		"md5_sum":           "b1946ac92492d2347c6235b4d2611184",
+	}, map[string]string{"file": log})
+	acc.AssertContainsTaggedFields(t, "filestat", map[string]interface{}{
+		"exists": int64(0),
+	}, map[string]string{"file": missing})
+	// The patterns matching no files have no points
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestInitInvalidPattern(t *testing.T) {
+	f := &FileStat{Files: []string{"/var/log/[.log"}}
+	assert.Error(t, f.Init())
+}
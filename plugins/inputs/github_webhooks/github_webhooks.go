@@ -1,15 +1,16 @@
 package github_webhooks
 
 import (
+	"crypto/tls"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -19,6 +20,18 @@ func init() {
 
 type GithubWebhooks struct {
 	ServiceAddress string
+	// TLS certificate and key of the listener, or their PKCS#12 bundle, the
+	// password of an encrypted key or bundle, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSPKCS12         string   `toml:"tls_pkcs12"`
+	TLSKeyPassword    string   `toml:"tls_key_password"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+	// Period at which the certificate files are checked, the certificate
+	// being reloaded once they are modified
+	TLSReloadInterval internal.Duration `toml:"tls_reload_interval"`
+	Log               telegraf.Logger   `toml:"-"`
 	// Lock for the struct
 	sync.Mutex
 	// Events buffer to store events between Gather calls
@@ -33,6 +46,20 @@ func (gh *GithubWebhooks) SampleConfig() string {
 	return `
   # Address and port to host Webhook listener on
   service_address = ":1618"
+
+  # Optional TLS configuration, only accepting the clients presenting a
+  # certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # Certificate and key bundled as PKCS#12, instead of tls_cert and tls_key
+  # tls_pkcs12 = "/etc/telegraf/server.p12"
+  # Password of an encrypted key or PKCS#12 bundle, which can be read from a
+  # secret store, ie "@{vault:webhooks_key_password}"
+  # tls_key_password = ""
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+  # Reload the certificate once its files are modified, ie rotated short-lived
+  # certificates, checking them at this interval. Disabled if empty.
+  # tls_reload_interval = "1m"
 `
 }
 
@@ -45,30 +72,53 @@ func (gh *GithubWebhooks) Gather(acc telegraf.Accumulator) error {
 	gh.Lock()
 	defer gh.Unlock()
 	for _, event := range gh.events {
-		p := event.NewMetric()
+		p, err := event.NewMetric()
+		if err != nil {
+			gh.Log.Errorf("%s", err)
+			continue
+		}
 		acc.AddFields("github_webhooks", p.Fields(), p.Tags(), p.Time())
 	}
 	gh.events = make([]Event, 0)
 	return nil
 }
 
-func (gh *GithubWebhooks) Listen() {
+func (gh *GithubWebhooks) Listen(listener net.Listener) {
 	r := mux.NewRouter()
 	r.HandleFunc("/", gh.eventHandler).Methods("POST")
-	err := http.ListenAndServe(fmt.Sprintf("%s", gh.ServiceAddress), r)
+	err := http.Serve(listener, r)
 	if err != nil {
-		log.Printf("Error starting server: %v", err)
+		gh.Log.Errorf("Error starting server: %v", err)
 	}
 }
 
 func (gh *GithubWebhooks) Start() error {
-	go gh.Listen()
-	log.Printf("Started the github_webhooks service on %s\n", gh.ServiceAddress)
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        gh.TLSCert,
+		TLSKey:         gh.TLSKey,
+		TLSPKCS12:      gh.TLSPKCS12,
+		TLSKeyPassword: gh.TLSKeyPassword,
+		AllowedCACerts: gh.TLSAllowedCACerts,
+		ReloadInterval: gh.TLSReloadInterval.Duration,
+	})
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", gh.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	go gh.Listen(listener)
+	gh.Log.Infof("Started the github_webhooks service on %s", gh.ServiceAddress)
 	return nil
 }
 
 func (gh *GithubWebhooks) Stop() {
-	log.Println("Stopping the ghWebhooks service")
+	gh.Log.Info("Stopping the ghWebhooks service")
 }
 
 // Handles the / route
@@ -78,6 +128,7 @@ func (gh *GithubWebhooks) eventHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 	}
+	gh.Log.Debugf("New %v event received", eventType)
 	e, err := NewEvent(data, eventType)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -286,7 +337,6 @@ func (e *newEventError) Error() string {
 }
 
 func NewEvent(r []byte, t string) (Event, error) {
-	log.Printf("New %v event recieved", t)
 	switch t {
 	case "commit_comment":
 		return newCommitComment(r)
@@ -2,7 +2,6 @@ package github_webhooks
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -11,7 +10,7 @@ import (
 const meas = "github_webhooks"
 
 type Event interface {
-	NewMetric() telegraf.Metric
+	NewMetric() (telegraf.Metric, error)
 }
 
 type Repository struct {
@@ -90,7 +89,7 @@ type CommitCommentEvent struct {
 	Sender     Sender        `json:"sender"`
 }
 
-func (s CommitCommentEvent) NewMetric() telegraf.Metric {
+func (s CommitCommentEvent) NewMetric() (telegraf.Metric, error) {
 	event := "commit_comment"
 	t := map[string]string{
 		"event":      event,
@@ -108,9 +107,9 @@ func (s CommitCommentEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type CreateEvent struct {
@@ -120,7 +119,7 @@ type CreateEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s CreateEvent) NewMetric() telegraf.Metric {
+func (s CreateEvent) NewMetric() (telegraf.Metric, error) {
 	event := "create"
 	t := map[string]string{
 		"event":      event,
@@ -138,9 +137,9 @@ func (s CreateEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeleteEvent struct {
@@ -150,7 +149,7 @@ type DeleteEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s DeleteEvent) NewMetric() telegraf.Metric {
+func (s DeleteEvent) NewMetric() (telegraf.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -168,9 +167,9 @@ func (s DeleteEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeploymentEvent struct {
@@ -179,7 +178,7 @@ type DeploymentEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s DeploymentEvent) NewMetric() telegraf.Metric {
+func (s DeploymentEvent) NewMetric() (telegraf.Metric, error) {
 	event := "deployment"
 	t := map[string]string{
 		"event":      event,
@@ -199,9 +198,9 @@ func (s DeploymentEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type DeploymentStatusEvent struct {
@@ -211,7 +210,7 @@ type DeploymentStatusEvent struct {
 	Sender           Sender           `json:"sender"`
 }
 
-func (s DeploymentStatusEvent) NewMetric() telegraf.Metric {
+func (s DeploymentStatusEvent) NewMetric() (telegraf.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -233,9 +232,9 @@ func (s DeploymentStatusEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type ForkEvent struct {
@@ -244,7 +243,7 @@ type ForkEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s ForkEvent) NewMetric() telegraf.Metric {
+func (s ForkEvent) NewMetric() (telegraf.Metric, error) {
 	event := "fork"
 	t := map[string]string{
 		"event":      event,
@@ -261,9 +260,9 @@ func (s ForkEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type GollumEvent struct {
@@ -273,7 +272,7 @@ type GollumEvent struct {
 }
 
 // REVIEW: Going to be lazy and not deal with the pages.
-func (s GollumEvent) NewMetric() telegraf.Metric {
+func (s GollumEvent) NewMetric() (telegraf.Metric, error) {
 	event := "gollum"
 	t := map[string]string{
 		"event":      event,
@@ -289,9 +288,9 @@ func (s GollumEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type IssueCommentEvent struct {
@@ -301,7 +300,7 @@ type IssueCommentEvent struct {
 	Sender     Sender       `json:"sender"`
 }
 
-func (s IssueCommentEvent) NewMetric() telegraf.Metric {
+func (s IssueCommentEvent) NewMetric() (telegraf.Metric, error) {
 	event := "issue_comment"
 	t := map[string]string{
 		"event":      event,
@@ -321,9 +320,9 @@ func (s IssueCommentEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type IssuesEvent struct {
@@ -333,7 +332,7 @@ type IssuesEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s IssuesEvent) NewMetric() telegraf.Metric {
+func (s IssuesEvent) NewMetric() (telegraf.Metric, error) {
 	event := "issue"
 	t := map[string]string{
 		"event":      event,
@@ -353,9 +352,9 @@ func (s IssuesEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type MemberEvent struct {
@@ -364,7 +363,7 @@ type MemberEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s MemberEvent) NewMetric() telegraf.Metric {
+func (s MemberEvent) NewMetric() (telegraf.Metric, error) {
 	event := "member"
 	t := map[string]string{
 		"event":      event,
@@ -382,9 +381,9 @@ func (s MemberEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type MembershipEvent struct {
@@ -394,7 +393,7 @@ type MembershipEvent struct {
 	Team   Team   `json:"team"`
 }
 
-func (s MembershipEvent) NewMetric() telegraf.Metric {
+func (s MembershipEvent) NewMetric() (telegraf.Metric, error) {
 	event := "membership"
 	t := map[string]string{
 		"event":  event,
@@ -408,9 +407,9 @@ func (s MembershipEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PageBuildEvent struct {
@@ -418,7 +417,7 @@ type PageBuildEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PageBuildEvent) NewMetric() telegraf.Metric {
+func (s PageBuildEvent) NewMetric() (telegraf.Metric, error) {
 	event := "page_build"
 	t := map[string]string{
 		"event":      event,
@@ -434,9 +433,9 @@ func (s PageBuildEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PublicEvent struct {
@@ -444,7 +443,7 @@ type PublicEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PublicEvent) NewMetric() telegraf.Metric {
+func (s PublicEvent) NewMetric() (telegraf.Metric, error) {
 	event := "public"
 	t := map[string]string{
 		"event":      event,
@@ -460,9 +459,9 @@ func (s PublicEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PullRequestEvent struct {
@@ -472,7 +471,7 @@ type PullRequestEvent struct {
 	Sender      Sender      `json:"sender"`
 }
 
-func (s PullRequestEvent) NewMetric() telegraf.Metric {
+func (s PullRequestEvent) NewMetric() (telegraf.Metric, error) {
 	event := "pull_request"
 	t := map[string]string{
 		"event":      event,
@@ -497,9 +496,9 @@ func (s PullRequestEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PullRequestReviewCommentEvent struct {
@@ -509,7 +508,7 @@ type PullRequestReviewCommentEvent struct {
 	Sender      Sender                   `json:"sender"`
 }
 
-func (s PullRequestReviewCommentEvent) NewMetric() telegraf.Metric {
+func (s PullRequestReviewCommentEvent) NewMetric() (telegraf.Metric, error) {
 	event := "pull_request_review_comment"
 	t := map[string]string{
 		"event":      event,
@@ -535,9 +534,9 @@ func (s PullRequestReviewCommentEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type PushEvent struct {
@@ -548,7 +547,7 @@ type PushEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s PushEvent) NewMetric() telegraf.Metric {
+func (s PushEvent) NewMetric() (telegraf.Metric, error) {
 	event := "push"
 	t := map[string]string{
 		"event":      event,
@@ -567,9 +566,9 @@ func (s PushEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type ReleaseEvent struct {
@@ -578,7 +577,7 @@ type ReleaseEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s ReleaseEvent) NewMetric() telegraf.Metric {
+func (s ReleaseEvent) NewMetric() (telegraf.Metric, error) {
 	event := "release"
 	t := map[string]string{
 		"event":      event,
@@ -595,9 +594,9 @@ func (s ReleaseEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type RepositoryEvent struct {
@@ -605,7 +604,7 @@ type RepositoryEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s RepositoryEvent) NewMetric() telegraf.Metric {
+func (s RepositoryEvent) NewMetric() (telegraf.Metric, error) {
 	event := "repository"
 	t := map[string]string{
 		"event":      event,
@@ -621,9 +620,9 @@ func (s RepositoryEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type StatusEvent struct {
@@ -633,7 +632,7 @@ type StatusEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s StatusEvent) NewMetric() telegraf.Metric {
+func (s StatusEvent) NewMetric() (telegraf.Metric, error) {
 	event := "status"
 	t := map[string]string{
 		"event":      event,
@@ -651,9 +650,9 @@ func (s StatusEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type TeamAddEvent struct {
@@ -662,7 +661,7 @@ type TeamAddEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s TeamAddEvent) NewMetric() telegraf.Metric {
+func (s TeamAddEvent) NewMetric() (telegraf.Metric, error) {
 	event := "team_add"
 	t := map[string]string{
 		"event":      event,
@@ -679,9 +678,9 @@ func (s TeamAddEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
 
 type WatchEvent struct {
@@ -689,7 +688,7 @@ type WatchEvent struct {
 	Sender     Sender     `json:"sender"`
 }
 
-func (s WatchEvent) NewMetric() telegraf.Metric {
+func (s WatchEvent) NewMetric() (telegraf.Metric, error) {
 	event := "delete"
 	t := map[string]string{
 		"event":      event,
@@ -705,7 +704,7 @@ func (s WatchEvent) NewMetric() telegraf.Metric {
 	}
 	m, err := telegraf.NewMetric(meas, t, f, time.Now())
 	if err != nil {
-		log.Fatalf("Failed to create %v event", event)
+		return nil, fmt.Errorf("failed to create %v event: %s", event, err)
 	}
-	return m
+	return m, nil
 }
@@ -5,10 +5,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
 )
 
 func TestCommitCommentEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := CommitCommentEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "commit_comment")
@@ -21,6 +24,7 @@ func TestCommitCommentEvent(t *testing.T) {
 
 func TestDeleteEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := DeleteEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "delete")
@@ -33,6 +37,7 @@ func TestDeleteEvent(t *testing.T) {
 
 func TestDeploymentEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := DeploymentEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "deployment")
@@ -45,6 +50,7 @@ func TestDeploymentEvent(t *testing.T) {
 
 func TestDeploymentStatusEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := DeploymentStatusEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "deployment_status")
@@ -57,6 +63,7 @@ func TestDeploymentStatusEvent(t *testing.T) {
 
 func TestForkEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := ForkEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "fork")
@@ -69,6 +76,7 @@ func TestForkEvent(t *testing.T) {
 
 func TestGollumEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := GollumEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "gollum")
@@ -81,6 +89,7 @@ func TestGollumEvent(t *testing.T) {
 
 func TestIssueCommentEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := IssueCommentEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "issue_comment")
@@ -93,6 +102,7 @@ func TestIssueCommentEvent(t *testing.T) {
 
 func TestIssuesEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := IssuesEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "issues")
@@ -105,6 +115,7 @@ func TestIssuesEvent(t *testing.T) {
 
 func TestMemberEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := MemberEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "member")
@@ -117,6 +128,7 @@ func TestMemberEvent(t *testing.T) {
 
 func TestMembershipEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := MembershipEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "membership")
@@ -129,6 +141,7 @@ func TestMembershipEvent(t *testing.T) {
 
 func TestPageBuildEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := PageBuildEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "page_build")
@@ -141,6 +154,7 @@ func TestPageBuildEvent(t *testing.T) {
 
 func TestPublicEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := PublicEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "public")
@@ -153,6 +167,7 @@ func TestPublicEvent(t *testing.T) {
 
 func TestPullRequestReviewCommentEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := PullRequestReviewCommentEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "pull_request_review_comment")
@@ -165,6 +180,7 @@ func TestPullRequestReviewCommentEvent(t *testing.T) {
 
 func TestPushEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := PushEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "push")
@@ -177,6 +193,7 @@ func TestPushEvent(t *testing.T) {
 
 func TestReleaseEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := ReleaseEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "release")
@@ -189,6 +206,7 @@ func TestReleaseEvent(t *testing.T) {
 
 func TestRepositoryEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := RepositoryEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "repository")
@@ -201,6 +219,7 @@ func TestRepositoryEvent(t *testing.T) {
 
 func TestStatusEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 
 	jsonString := StatusEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
@@ -214,6 +233,7 @@ func TestStatusEvent(t *testing.T) {
 
 func TestTeamAddEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := TeamAddEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "team_add")
@@ -226,6 +246,7 @@ func TestTeamAddEvent(t *testing.T) {
 
 func TestWatchEvent(t *testing.T) {
 	gh := NewGithubWebhooks()
+	gh.Log = testutil.Logger{}
 	jsonString := WatchEventJSON()
 	req, _ := http.NewRequest("POST", "/", strings.NewReader(jsonString))
 	req.Header.Add("X-Github-Event", "watch")
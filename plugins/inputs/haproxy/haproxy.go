@@ -4,11 +4,14 @@ import (
 	"encoding/csv"
 	"fmt"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -91,8 +94,10 @@ var sampleConfig = `
   #
   # If no servers are specified, then default to 127.0.0.1:1936
   servers = ["http://myhaproxy.com:1936", "http://anotherhaproxy.com:1936"]
-  # Or you can also use local socket(not work yet)
-  # servers = ["socket://run/haproxy/admin.sock"]
+  # Or you can also use the unix socket of a stats page, ie
+  # servers = ["unix:///var/run/haproxy/stats.sock"]
+  # Or the admin socket of haproxy, its "stats socket", given by its path
+  # servers = ["/var/run/haproxy/admin.sock"]
 `
 
 func (r *haproxy) SampleConfig() string {
@@ -112,13 +117,24 @@ func (g *haproxy) Gather(acc telegraf.Accumulator) error {
 
 	var wg sync.WaitGroup
 
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, serv := range g.Servers {
 		wg.Add(1)
 		go func(serv string) {
 			defer wg.Done()
-			outerr = g.gatherServer(serv, acc)
+			var err error
+			if strings.HasPrefix(serv, "/") {
+				err = g.gatherServerSocket(serv, acc)
+			} else {
+				err = g.gatherServer(serv, acc)
+			}
+			if err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}(serv)
 	}
 
@@ -129,8 +145,11 @@ func (g *haproxy) Gather(acc telegraf.Accumulator) error {
 
 func (g *haproxy) gatherServer(addr string, acc telegraf.Accumulator) error {
 	if g.client == nil {
-
-		client := &http.Client{}
+		client, err := internal.NewHTTPClient(0,
+			internal.HTTPProxyOptions{UseSystemProxy: true})
+		if err != nil {
+			return err
+		}
 		g.client = client
 	}
 
@@ -154,15 +173,41 @@ func (g *haproxy) gatherServer(addr string, acc telegraf.Accumulator) error {
 		return fmt.Errorf("Unable to get valid stat result from '%s': %s", addr, err)
 	}
 
-	return importCsvResult(res.Body, acc, u.Host)
+	host := u.Host
+	if u.Scheme == "unix" {
+		host, _, _ = internal.SplitUnixSocketPath(u.Path)
+	}
+	return importCsvResult(res.Body, acc, host)
+}
+
+// gatherServerSocket reads the stats of the admin socket of haproxy, in the
+// CSV format of the stats page
+func (g *haproxy) gatherServerSocket(socket string, acc telegraf.Accumulator) error {
+	c, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to haproxy socket '%s': %s", socket, err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := c.Write([]byte("show stat\n")); err != nil {
+		return fmt.Errorf("Unable to write to haproxy socket '%s': %s", socket, err)
+	}
+	return importCsvResult(c, acc, socket)
 }
 
 func importCsvResult(r io.Reader, acc telegraf.Accumulator, host string) error {
 	csv := csv.NewReader(r)
+	// The number of columns depends on the version of haproxy
+	csv.FieldsPerRecord = -1
 	result, err := csv.ReadAll()
 	now := time.Now()
 
 	for _, row := range result {
+		// The header of the columns is commented
+		if len(row) <= HF_SVNAME || strings.HasPrefix(row[HF_PXNAME], "#") {
+			continue
+		}
 		fields := make(map[string]interface{})
 		tags := map[string]string{
 			"server": host,
@@ -251,6 +296,16 @@ func importCsvResult(r io.Reader, acc telegraf.Accumulator, host string) error {
 				if err == nil {
 					fields["backup_servers"] = ival
 				}
+			case HF_CHKFAIL:
+				ival, err := strconv.ParseUint(v, 10, 64)
+				if err == nil {
+					fields["chkfail"] = ival
+				}
+			case HF_CHKDOWN:
+				ival, err := strconv.ParseUint(v, 10, 64)
+				if err == nil {
+					fields["chkdown"] = ival
+				}
 			case HF_DOWNTIME:
 				ival, err := strconv.ParseUint(v, 10, 64)
 				if err == nil {
@@ -306,6 +361,11 @@ func importCsvResult(r io.Reader, acc telegraf.Accumulator, host string) error {
 				if err == nil {
 					fields["http_response.5xx"] = ival
 				}
+			case HF_HRSP_OTHER:
+				ival, err := strconv.ParseUint(v, 10, 64)
+				if err == nil {
+					fields["http_response.other"] = ival
+				}
 			case HF_REQ_RATE:
 				ival, err := strconv.ParseUint(v, 10, 64)
 				if err == nil {
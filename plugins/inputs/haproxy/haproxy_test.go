@@ -1,7 +1,12 @@
 package haproxy
 
 import (
+	"bufio"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -48,36 +53,39 @@ func TestHaproxyGeneratesMetricsWithAuthentication(t *testing.T) {
 	}
 
 	fields := map[string]interface{}{
-		"active_servers":    uint64(1),
-		"backup_servers":    uint64(0),
-		"bin":               uint64(510913516),
-		"bout":              uint64(2193856571),
-		"check_duration":    uint64(10),
-		"cli_abort":         uint64(73),
-		"ctime":             uint64(2),
-		"downtime":          uint64(0),
-		"dresp":             uint64(0),
-		"econ":              uint64(0),
-		"eresp":             uint64(1),
-		"http_response.1xx": uint64(0),
-		"http_response.2xx": uint64(119534),
-		"http_response.3xx": uint64(48051),
-		"http_response.4xx": uint64(2345),
-		"http_response.5xx": uint64(1056),
-		"lbtot":             uint64(171013),
-		"qcur":              uint64(0),
-		"qmax":              uint64(0),
-		"qtime":             uint64(0),
-		"rate":              uint64(3),
-		"rate_max":          uint64(12),
-		"rtime":             uint64(312),
-		"scur":              uint64(1),
-		"smax":              uint64(32),
-		"srv_abort":         uint64(1),
-		"stot":              uint64(171014),
-		"ttime":             uint64(2341),
-		"wredis":            uint64(0),
-		"wretr":             uint64(1),
+		"active_servers":      uint64(1),
+		"backup_servers":      uint64(0),
+		"bin":                 uint64(510913516),
+		"bout":                uint64(2193856571),
+		"check_duration":      uint64(10),
+		"chkdown":             uint64(0),
+		"chkfail":             uint64(1),
+		"cli_abort":           uint64(73),
+		"ctime":               uint64(2),
+		"downtime":            uint64(0),
+		"dresp":               uint64(0),
+		"econ":                uint64(0),
+		"eresp":               uint64(1),
+		"http_response.1xx":   uint64(0),
+		"http_response.2xx":   uint64(119534),
+		"http_response.3xx":   uint64(48051),
+		"http_response.4xx":   uint64(2345),
+		"http_response.5xx":   uint64(1056),
+		"http_response.other": uint64(0),
+		"lbtot":               uint64(171013),
+		"qcur":                uint64(0),
+		"qmax":                uint64(0),
+		"qtime":               uint64(0),
+		"rate":                uint64(3),
+		"rate_max":            uint64(12),
+		"rtime":               uint64(312),
+		"scur":                uint64(1),
+		"smax":                uint64(32),
+		"srv_abort":           uint64(1),
+		"stot":                uint64(171014),
+		"ttime":               uint64(2341),
+		"wredis":              uint64(0),
+		"wretr":               uint64(1),
 	}
 	acc.AssertContainsTaggedFields(t, "haproxy", fields, tags)
 
@@ -112,42 +120,115 @@ func TestHaproxyGeneratesMetricsWithoutAuthentication(t *testing.T) {
 	}
 
 	fields := map[string]interface{}{
-		"active_servers":    uint64(1),
-		"backup_servers":    uint64(0),
-		"bin":               uint64(510913516),
-		"bout":              uint64(2193856571),
-		"check_duration":    uint64(10),
-		"cli_abort":         uint64(73),
-		"ctime":             uint64(2),
-		"downtime":          uint64(0),
-		"dresp":             uint64(0),
-		"econ":              uint64(0),
-		"eresp":             uint64(1),
-		"http_response.1xx": uint64(0),
-		"http_response.2xx": uint64(119534),
-		"http_response.3xx": uint64(48051),
-		"http_response.4xx": uint64(2345),
-		"http_response.5xx": uint64(1056),
-		"lbtot":             uint64(171013),
-		"qcur":              uint64(0),
-		"qmax":              uint64(0),
-		"qtime":             uint64(0),
-		"rate":              uint64(3),
-		"rate_max":          uint64(12),
-		"rtime":             uint64(312),
-		"scur":              uint64(1),
-		"smax":              uint64(32),
-		"srv_abort":         uint64(1),
-		"stot":              uint64(171014),
-		"ttime":             uint64(2341),
-		"wredis":            uint64(0),
-		"wretr":             uint64(1),
+		"active_servers":      uint64(1),
+		"backup_servers":      uint64(0),
+		"bin":                 uint64(510913516),
+		"bout":                uint64(2193856571),
+		"check_duration":      uint64(10),
+		"chkdown":             uint64(0),
+		"chkfail":             uint64(1),
+		"cli_abort":           uint64(73),
+		"ctime":               uint64(2),
+		"downtime":            uint64(0),
+		"dresp":               uint64(0),
+		"econ":                uint64(0),
+		"eresp":               uint64(1),
+		"http_response.1xx":   uint64(0),
+		"http_response.2xx":   uint64(119534),
+		"http_response.3xx":   uint64(48051),
+		"http_response.4xx":   uint64(2345),
+		"http_response.5xx":   uint64(1056),
+		"http_response.other": uint64(0),
+		"lbtot":               uint64(171013),
+		"qcur":                uint64(0),
+		"qmax":                uint64(0),
+		"qtime":               uint64(0),
+		"rate":                uint64(3),
+		"rate_max":            uint64(12),
+		"rtime":               uint64(312),
+		"scur":                uint64(1),
+		"smax":                uint64(32),
+		"srv_abort":           uint64(1),
+		"stot":                uint64(171014),
+		"ttime":               uint64(2341),
+		"wredis":              uint64(0),
+		"wretr":               uint64(1),
 	}
 	acc.AssertContainsTaggedFields(t, "haproxy", fields, tags)
 }
 
-//When not passing server config, we default to localhost
-//We just want to make sure we did request stat from localhost
+func TestHaproxyGeneratesMetricsUsingSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "haproxy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "stats.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, csvOutputSample)
+	}))
+
+	r := &haproxy{
+		Servers: []string{"unix://" + socket},
+	}
+
+	var acc testutil.Accumulator
+
+	err = r.Gather(&acc)
+	require.NoError(t, err)
+
+	// The socket is the server of the metrics
+	require.NotEmpty(t, acc.Metrics)
+	for _, m := range acc.Metrics {
+		assert.Equal(t, socket, m.Tags["server"])
+	}
+}
+
+func TestHaproxyGeneratesMetricsUsingAdminSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "haproxy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "admin.sock")
+
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		line, err := bufio.NewReader(c).ReadString('\n')
+		if err != nil || line != "show stat\n" {
+			return
+		}
+		fmt.Fprint(c, "# pxname,svname,qcur,qmax,scur,smax,slim,stot,\n")
+		fmt.Fprint(c, csvOutputSample)
+	}()
+
+	r := &haproxy{
+		Servers: []string{socket},
+	}
+
+	var acc testutil.Accumulator
+
+	err = r.Gather(&acc)
+	require.NoError(t, err)
+
+	// The header is skipped, all the rows are from the sample
+	require.NotEmpty(t, acc.Metrics)
+	for _, m := range acc.Metrics {
+		assert.Equal(t, socket, m.Tags["server"])
+		assert.NotEqual(t, "# pxname", m.Tags["proxy"])
+	}
+	assert.True(t, acc.HasUIntField("haproxy", "chkfail"))
+}
+
+// When not passing server config, we default to localhost
+// We just want to make sure we did request stat from localhost
 func TestHaproxyDefaultGetFromLocalhost(t *testing.T) {
 	r := &haproxy{}
 
@@ -0,0 +1,151 @@
+package hddtemp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// HDDTemp reads the temperatures of the disks from the hddtemp daemon
+type HDDTemp struct {
+	Address string
+	Devices []string
+	Timeout internal.Duration
+}
+
+var sampleConfig = `
+  # Address of the hddtemp daemon, localhost:7634 by default
+  # address = "localhost:7634"
+
+  # Only collect the selected disks, named as their device without /dev/,
+  # * selecting all the disks
+  # devices = ["sda", "sdb"]
+
+  # Timeout of the query, 5s by default
+  # timeout = "5s"
+`
+
+func (h *HDDTemp) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HDDTemp) Description() string {
+	return "Monitor the temperature of the disks, read from the hddtemp daemon"
+}
+
+// disk is the temperature of a disk reported by hddtemp, the temperature
+// being replaced by a status when it is not known, ie SLP for the sleeping
+// disks or NA for the disks without a sensor, and reported as 0
+type disk struct {
+	device      string
+	model       string
+	temperature int
+	unit        string
+	status      string
+}
+
+func (h *HDDTemp) Gather(acc telegraf.Accumulator) error {
+	address := h.Address
+	if address == "" {
+		address = "localhost:7634"
+	}
+	timeout := h.Timeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	// The daemon writes the temperatures and closes the connection
+	b, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return err
+	}
+
+	disks, err := parse(string(b))
+	if err != nil {
+		return err
+	}
+	for _, d := range disks {
+		if !h.selected(d.device) {
+			continue
+		}
+		acc.AddFields("hddtemp", map[string]interface{}{
+			"temperature": d.temperature,
+		}, map[string]string{
+			"device": d.device,
+			"model":  d.model,
+			"unit":   d.unit,
+			"status": d.status,
+		})
+	}
+	return nil
+}
+
+// selected returns whether the device is selected by the devices option, all
+// being selected if it is empty
+func (h *HDDTemp) selected(device string) bool {
+	if len(h.Devices) == 0 {
+		return true
+	}
+	for _, d := range h.Devices {
+		if d == "*" || d == device {
+			return true
+		}
+	}
+	return false
+}
+
+// parse parses the response of the hddtemp daemon, the device, model,
+// temperature and unit of each disk separated by |, ie
+//
+//	|/dev/sda|ST3500418AS|40|C||/dev/sdb|WDC WD10EARS|SLP|*|
+func parse(response string) ([]disk, error) {
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(response, "|") || !strings.HasSuffix(response, "|") {
+		return nil, errors.New("invalid hddtemp response: " + response)
+	}
+
+	var disks []disk
+	for _, entry := range strings.Split(
+		response[1:len(response)-1], "||") {
+		parts := strings.Split(entry, "|")
+		if len(parts) != 4 {
+			return nil, errors.New("invalid hddtemp response: " + response)
+		}
+		d := disk{
+			device: filepath.Base(parts[0]),
+			model:  parts[1],
+			unit:   parts[3],
+			status: "ok",
+		}
+		temperature, err := strconv.Atoi(parts[2])
+		if err != nil {
+			d.status = parts[2]
+		}
+		d.temperature = temperature
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+func init() {
+	inputs.Add("hddtemp", func() telegraf.Input {
+		return &HDDTemp{}
+	})
+}
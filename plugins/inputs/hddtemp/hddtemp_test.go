@@ -0,0 +1,70 @@
+package hddtemp
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hddtempServer answers the connections of l with the response, as the
+// hddtemp daemon
+func hddtempServer(l net.Listener, response string) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		fmt.Fprint(c, response)
+		c.Close()
+	}
+}
+
+func TestHDDTemp(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go hddtempServer(l,
+		"|/dev/sda|ST3500418AS|40|C||/dev/sdb|WDC WD10EARS|SLP|*|")
+
+	h := &HDDTemp{Address: l.Addr().String()}
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "hddtemp", map[string]interface{}{
+		"temperature": 40,
+	}, map[string]string{
+		"device": "sda",
+		"model":  "ST3500418AS",
+		"unit":   "C",
+		"status": "ok",
+	})
+	acc.AssertContainsTaggedFields(t, "hddtemp", map[string]interface{}{
+		"temperature": 0,
+	}, map[string]string{
+		"device": "sdb",
+		"model":  "WDC WD10EARS",
+		"unit":   "*",
+		"status": "SLP",
+	})
+
+	h.Devices = []string{"sdb"}
+	acc = testutil.Accumulator{}
+	require.NoError(t, h.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, "sdb", acc.Metrics[0].Tags["device"])
+}
+
+func TestHDDTempInvalidResponse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go hddtempServer(l, "|/dev/sda|ST3500418AS|40|")
+
+	h := &HDDTemp{Address: l.Addr().String()}
+	var acc testutil.Accumulator
+	assert.Error(t, h.Gather(&acc))
+}
@@ -0,0 +1,221 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type HTTP struct {
+	URLs   []string `toml:"urls"`
+	Method string
+	Body   string
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	KeepAlive          internal.Duration `toml:"keep_alive"`
+	MaxIdleConns       int               `toml:"max_idle_conns"`
+	Headers            map[string]string
+	Username           string
+	Password           string
+	BearerToken        string   `toml:"bearer_token"`
+	OAuth2ClientID     string   `toml:"oauth2_client_id"`
+	OAuth2ClientSecret string   `toml:"oauth2_client_secret"`
+	OAuth2TokenURL     string   `toml:"oauth2_token_url"`
+	OAuth2Scopes       []string `toml:"oauth2_scopes"`
+	SSLCA              string   `toml:"ssl_ca"`
+	SSLCert            string   `toml:"ssl_cert"`
+	SSLKey             string   `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Options of the parser, see parsers.Config
+	DataFormat     string   `toml:"data_format"`
+	TagKeys        []string `toml:"tag_keys"`
+	CSVColumnNames []string `toml:"csv_column_names"`
+	CSVDelimiter   string   `toml:"csv_delimiter"`
+	XMLPath        string   `toml:"xml_path"`
+
+	client *http.Client
+	parser telegraf.Parser
+}
+
+var sampleConfig = `
+  # URLs to request
+  urls = ["http://localhost/metrics"]
+
+  # HTTP method, and body of the requests
+  # method = "GET"
+  # body = ""
+
+  # Timeout of the requests, and period of the TCP keep-alives
+  # timeout = "5s"
+  # keep_alive = "30s"
+  # Idle connections kept open per url, -1 to close the connections after
+  # every request
+  # max_idle_conns = 2
+
+  # Credentials of the requests, either basic auth, a bearer token, or an
+  # OAuth2 client requesting the bearer tokens from oauth2_token_url
+  # username = ""
+  # password = ""
+  # bearer_token = ""
+  # oauth2_client_id = ""
+  # oauth2_client_secret = ""
+  # oauth2_token_url = ""
+  # oauth2_scopes = []
+
+  # TLS options of the https urls
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Data format of the responses: influx, json, csv, prometheus or xml
+  data_format = "json"
+  # Keys tagging the metrics with their value instead of being fields: top
+  # level keys of json, columns of csv, attributes or child elements of xml
+  # tag_keys = []
+  # Names of the csv columns, read from the first row if empty, and their
+  # delimiter
+  # csv_column_names = []
+  # csv_delimiter = ","
+  # Path of the xml elements parsed as metrics, the root element if empty
+  # xml_path = "/stats/server"
+
+  # Headers added to the requests
+  # [inputs.http.headers]
+  #   Content-Type = "application/json"
+`
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTP) Description() string {
+	return "Read metrics in one of the data formats from one or more HTTP endpoints"
+}
+
+// Init creates the HTTP client and parser of the plugin.
+func (h *HTTP) Init() error {
+	c := httpconfig.Config{
+		Timeout:      h.Timeout.Duration,
+		KeepAlive:    h.KeepAlive.Duration,
+		MaxIdleConns: h.MaxIdleConns,
+		Headers:      h.Headers,
+		Username:     h.Username,
+		Password:     h.Password,
+		BearerToken:  h.BearerToken,
+		OAuth2: httpconfig.OAuth2Config{
+			ClientID:     h.OAuth2ClientID,
+			ClientSecret: h.OAuth2ClientSecret,
+			TokenURL:     h.OAuth2TokenURL,
+			Scopes:       h.OAuth2Scopes,
+		},
+		TLS: internal.TLSOptions{
+			SSLCA:              h.SSLCA,
+			SSLCert:            h.SSLCert,
+			SSLKey:             h.SSLKey,
+			InsecureSkipVerify: h.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:     h.DataFormat,
+		MetricName:     "http",
+		TagKeys:        h.TagKeys,
+		CSVColumnNames: h.CSVColumnNames,
+		CSVDelimiter:   h.CSVDelimiter,
+		XMLPath:        h.XMLPath,
+	})
+	if err != nil {
+		return err
+	}
+	h.client = client
+	h.parser = parser
+	return nil
+}
+
+// Gathers the metrics of all urls.
+func (h *HTTP) Gather(acc telegraf.Accumulator) error {
+	var wg sync.WaitGroup
+
+	errorChannel := make(chan error, len(h.URLs))
+
+	for _, u := range h.URLs {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			if err := h.gatherURL(acc, u); err != nil {
+				errorChannel <- err
+			}
+		}(u)
+	}
+
+	wg.Wait()
+	close(errorChannel)
+
+	errorStrings := []string{}
+	for err := range errorChannel {
+		errorStrings = append(errorStrings, err.Error())
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// gatherURL requests the url and adds the metrics of the response, tagged
+// with the url
+func (h *HTTP) gatherURL(acc telegraf.Accumulator, u string) error {
+	method := h.Method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequest(method, u, strings.NewReader(h.Body))
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making HTTP request to %s: %s", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading the response of %s: %s", u, err)
+	}
+
+	metrics, err := h.parser.Parse(body)
+	for _, m := range metrics {
+		tags := m.Tags()
+		tags["url"] = u
+		acc.AddFields(m.Name(), m.Fields(), tags, m.Time())
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing the response of %s: %s", u, err)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("http", func() telegraf.Input {
+		return &HTTP{}
+	})
+}
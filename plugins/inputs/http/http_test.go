@@ -0,0 +1,66 @@
+package http
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != "POST" || string(body) != `{"query": "stats"}` ||
+			r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"role": "master", "uptime": 10, "mem": {"used": 1.5}}`)
+	}))
+	defer ts.Close()
+
+	h := &HTTP{
+		URLs:        []string{ts.URL},
+		Method:      "POST",
+		Body:        `{"query": "stats"}`,
+		BearerToken: "secret",
+		DataFormat:  "json",
+		TagKeys:     []string{"role"},
+	}
+	require.NoError(t, h.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "http",
+		map[string]interface{}{"uptime": float64(10), "mem_used": 1.5},
+		map[string]string{"role": "master", "url": ts.URL})
+
+	h.BearerToken = "wrong"
+	require.NoError(t, h.Init())
+	assert.Error(t, h.Gather(&acc))
+}
+
+func TestHTTPInflux(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "cpu,host=a usage=0.5 1455312810012459582")
+	}))
+	defer ts.Close()
+
+	h := &HTTP{URLs: []string{ts.URL}}
+	require.NoError(t, h.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage": 0.5},
+		map[string]string{"host": "a", "url": ts.URL})
+}
+
+func TestHTTPInvalidDataFormat(t *testing.T) {
+	h := &HTTP{URLs: []string{"http://localhost"}, DataFormat: "yaml"}
+	assert.Error(t, h.Init())
+}
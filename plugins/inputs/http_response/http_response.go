@@ -0,0 +1,231 @@
+package http_response
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// The results of the requests, in the result tag and the result_code field
+const (
+	Success                = 0
+	ResponseStringMismatch = 1
+	ConnectionFailed       = 2
+	Timeout                = 3
+)
+
+var resultNames = map[int]string{
+	Success:                "success",
+	ResponseStringMismatch: "response_string_mismatch",
+	ConnectionFailed:       "connection_failed",
+	Timeout:                "timeout",
+}
+
+// errRedirect stops the redirects when they are not followed
+var errRedirect = errors.New("redirect not followed")
+
+// HTTPResponse checks an HTTP endpoint, its response time, status code and
+// body, and the expiry of its certificate
+type HTTPResponse struct {
+	Address             string
+	Method              string
+	Body                string
+	FollowRedirects     bool   `toml:"follow_redirects"`
+	ResponseStringMatch string `toml:"response_string_match"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	ResponseTimeout    internal.Duration `toml:"response_timeout"`
+	Headers            map[string]string
+	Username           string
+	Password           string
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	client *http.Client
+	match  *regexp.Regexp
+}
+
+var sampleConfig = `
+  # Server address, with its scheme
+  address = "http://github.com"
+  # HTTP method, and body of the requests
+  # method = "GET"
+  # body = ""
+  # Whether the redirects are followed, or their response checked
+  # follow_redirects = false
+
+  # Regular expression matched against the body of the response
+  # response_string_match = "ok"
+
+  # Timeout of the requests, 5s by default
+  # response_timeout = "5s"
+
+  # Credentials for basic HTTP authentication
+  # username = ""
+  # password = ""
+
+  # TLS options of an https address
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Headers added to the requests
+  # [inputs.http_response.headers]
+  #   Host = "github.com"
+`
+
+func (h *HTTPResponse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTPResponse) Description() string {
+	return "Check HTTP endpoints, their response time, status code and body"
+}
+
+// Init creates the HTTP client of the plugin and compiles its
+// response_string_match regular expression.
+func (h *HTTPResponse) Init() error {
+	u, err := url.Parse(h.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %s", h.Address, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "unix" {
+		return fmt.Errorf("invalid address %q: the scheme must be http, "+
+			"https or unix", h.Address)
+	}
+	if h.ResponseStringMatch != "" {
+		match, err := regexp.Compile(h.ResponseStringMatch)
+		if err != nil {
+			return fmt.Errorf("invalid response_string_match %q: %s",
+				h.ResponseStringMatch, err)
+		}
+		h.match = match
+	}
+
+	c := httpconfig.Config{
+		Timeout:      h.ResponseTimeout.Duration,
+		MaxIdleConns: -1,
+		Headers:      h.Headers,
+		Username:     h.Username,
+		Password:     h.Password,
+		TLS: internal.TLSOptions{
+			SSLCA:              h.SSLCA,
+			SSLCert:            h.SSLCert,
+			SSLKey:             h.SSLKey,
+			InsecureSkipVerify: h.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	if !h.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return errRedirect
+		}
+	}
+	h.client = client
+	return nil
+}
+
+// Gather requests the address, adding the result of the request. The
+// failures of the requests are results, and not errors of the plugin.
+func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
+	method := h.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	fields := make(map[string]interface{})
+	result, err := h.request(method, fields)
+	if err != nil {
+		return err
+	}
+	fields["result_code"] = result
+	tags := map[string]string{
+		"server": h.Address,
+		"method": method,
+		"result": resultNames[result],
+	}
+	acc.AddFields("http_response", fields, tags)
+	return nil
+}
+
+// request sends the request and checks its response, adding the response
+// time, status code, match of the body and expiry of the certificate to the
+// fields. It returns the result of the request.
+func (h *HTTPResponse) request(
+	method string,
+	fields map[string]interface{},
+) (int, error) {
+	req, err := http.NewRequest(method, h.Address, strings.NewReader(h.Body))
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := h.client.Do(req)
+	// The response of a redirect not followed is returned with the error of
+	// CheckRedirect, its body closed
+	if e, ok := err.(*url.Error); ok && e.Err == errRedirect && resp != nil {
+		err = nil
+	}
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Timeout, nil
+		}
+		return ConnectionFailed, nil
+	}
+	defer resp.Body.Close()
+	fields["response_time"] = time.Since(start).Seconds()
+	fields["http_response_code"] = resp.StatusCode
+
+	// The expiry of the certificate is the first one of its chain
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiry := resp.TLS.PeerCertificates[0].NotAfter
+		for _, cert := range resp.TLS.PeerCertificates[1:] {
+			if cert.NotAfter.Before(expiry) {
+				expiry = cert.NotAfter
+			}
+		}
+		fields["cert_expiry"] = int64(expiry.Sub(time.Now()).Seconds())
+	}
+
+	if h.match == nil {
+		return Success, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return ConnectionFailed, nil
+	}
+	if h.match.Match(body) {
+		fields["response_string_match"] = 1
+		return Success, nil
+	}
+	fields["response_string_match"] = 0
+	return ResponseStringMismatch, nil
+}
+
+func init() {
+	inputs.Add("http_response", func() telegraf.Input {
+		return &HTTPResponse{}
+	})
+}
@@ -0,0 +1,144 @@
+package http_response
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func handler(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/redirect":
+		http.Redirect(w, r, "/good", http.StatusMovedPermanently)
+	case "/good":
+		if r.Header.Get("X-Check") != "telegraf" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		fmt.Fprint(w, "hit the good page!")
+	case "/post":
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		fmt.Fprint(w, "posted")
+	case "/slow":
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// gather initializes h and returns its http_response metric
+func gather(t *testing.T, h *HTTPResponse) *testutil.Metric {
+	h.Headers = map[string]string{"X-Check": "telegraf"}
+	require.NoError(t, h.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+	m, ok := acc.Get("http_response")
+	require.True(t, ok)
+	return m
+}
+
+func TestHTTPResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	m := gather(t, &HTTPResponse{
+		Address:             ts.URL + "/good",
+		ResponseStringMatch: "hit the good page",
+	})
+	assert.Equal(t, map[string]string{
+		"server": ts.URL + "/good",
+		"method": "GET",
+		"result": "success",
+	}, m.Tags)
+	assert.Equal(t, 200, m.Fields["http_response_code"])
+	assert.Equal(t, 1, m.Fields["response_string_match"])
+	assert.Equal(t, Success, m.Fields["result_code"])
+	assert.IsType(t, float64(0), m.Fields["response_time"])
+	assert.NotContains(t, m.Fields, "cert_expiry")
+
+	m = gather(t, &HTTPResponse{
+		Address:             ts.URL + "/good",
+		ResponseStringMatch: "bad page",
+	})
+	assert.Equal(t, "response_string_mismatch", m.Tags["result"])
+	assert.Equal(t, 0, m.Fields["response_string_match"])
+
+	m = gather(t, &HTTPResponse{
+		Address: ts.URL + "/post",
+		Method:  "POST",
+		Body:    "{}",
+	})
+	assert.Equal(t, "POST", m.Tags["method"])
+	assert.Equal(t, 200, m.Fields["http_response_code"])
+}
+
+func TestHTTPResponseRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	m := gather(t, &HTTPResponse{Address: ts.URL + "/redirect"})
+	assert.Equal(t, "success", m.Tags["result"])
+	assert.Equal(t, 301, m.Fields["http_response_code"])
+
+	m = gather(t, &HTTPResponse{
+		Address:         ts.URL + "/redirect",
+		FollowRedirects: true,
+	})
+	assert.Equal(t, 200, m.Fields["http_response_code"])
+}
+
+func TestHTTPResponseFailures(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	m := gather(t, &HTTPResponse{
+		Address:         ts.URL + "/slow",
+		ResponseTimeout: internal.Duration{Duration: 50 * time.Millisecond},
+	})
+	assert.Equal(t, "timeout", m.Tags["result"])
+	assert.Equal(t, Timeout, m.Fields["result_code"])
+	assert.NotContains(t, m.Fields, "response_time")
+
+	// The port of a closed listener is refusing the connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l.Close()
+	m = gather(t, &HTTPResponse{Address: "http://" + l.Addr().String()})
+	assert.Equal(t, "connection_failed", m.Tags["result"])
+	assert.Equal(t, ConnectionFailed, m.Fields["result_code"])
+}
+
+func TestHTTPResponseCertExpiry(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	// The certificate of httptest is not trusted
+	m := gather(t, &HTTPResponse{Address: ts.URL + "/good"})
+	assert.Equal(t, "connection_failed", m.Tags["result"])
+
+	m = gather(t, &HTTPResponse{
+		Address:            ts.URL + "/good",
+		InsecureSkipVerify: true,
+	})
+	assert.Equal(t, "success", m.Tags["result"])
+	expiry, ok := m.Fields["cert_expiry"].(int64)
+	require.True(t, ok)
+	assert.True(t, expiry > 0)
+}
+
+func TestHTTPResponseInvalidConfig(t *testing.T) {
+	for _, h := range []*HTTPResponse{
+		{Address: "localhost:80"},
+		{Address: "ftp://localhost"},
+		{Address: "http://localhost", ResponseStringMatch: "("},
+	} {
+		assert.Error(t, h.Init())
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -23,7 +24,20 @@ type HttpJson struct {
 	TagKeys    []string
 	Parameters map[string]string
 	Headers    map[string]string
-	client     HTTPClient
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	KeepAlive          internal.Duration `toml:"keep_alive"`
+	MaxIdleConns       int               `toml:"max_idle_conns"`
+	Username           string
+	Password           string
+	BearerToken        string   `toml:"bearer_token"`
+	OAuth2ClientID     string   `toml:"oauth2_client_id"`
+	OAuth2ClientSecret string   `toml:"oauth2_client_secret"`
+	OAuth2TokenURL     string   `toml:"oauth2_token_url"`
+	OAuth2Scopes       []string `toml:"oauth2_scopes"`
+
+	client HTTPClient
 }
 
 type HTTPClient interface {
@@ -73,6 +87,23 @@ var sampleConfig = `
     event_type = "cpu_spike"
     threshold = "0.75"
 
+  # Timeout of the requests, and period of the TCP keep-alives
+  # timeout = "5s"
+  # keep_alive = "30s"
+  # Idle connections kept open per server, -1 to close the connections after
+  # every request
+  # max_idle_conns = 2
+
+  # Credentials of the requests, either basic auth, a bearer token, or an
+  # OAuth2 client requesting the bearer tokens from oauth2_token_url
+  # username = ""
+  # password = ""
+  # bearer_token = ""
+  # oauth2_client_id = ""
+  # oauth2_client_secret = ""
+  # oauth2_token_url = ""
+  # oauth2_scopes = []
+
   # HTTP Header parameters (all values must be strings)
   # [inputs.httpjson.headers]
   #   X-Auth-Token = "my-xauth-token"
@@ -88,6 +119,32 @@ func (h *HttpJson) Description() string {
 	return "Read flattened metrics from one or more JSON HTTP endpoints"
 }
 
+// Init creates the HTTP client of the plugin.
+func (h *HttpJson) Init() error {
+	c := httpconfig.Config{
+		Timeout:      h.Timeout.Duration,
+		KeepAlive:    h.KeepAlive.Duration,
+		MaxIdleConns: h.MaxIdleConns,
+		Headers:      h.Headers,
+		Username:     h.Username,
+		Password:     h.Password,
+		BearerToken:  h.BearerToken,
+		OAuth2: httpconfig.OAuth2Config{
+			ClientID:     h.OAuth2ClientID,
+			ClientSecret: h.OAuth2ClientSecret,
+			TokenURL:     h.OAuth2TokenURL,
+			Scopes:       h.OAuth2Scopes,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	h.client = RealHTTPClient{client: client}
+	return nil
+}
+
 // Gathers data for all servers.
 func (h *HttpJson) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
@@ -199,15 +256,6 @@ func (h *HttpJson) sendRequest(serverURL string) (string, float64, error) {
 		return "", -1, err
 	}
 
-	// Add header parameters
-	for k, v := range h.Headers {
-		if strings.ToLower(k) == "host" {
-			req.Host = v
-		} else {
-			req.Header.Add(k, v)
-		}
-	}
-
 	start := time.Now()
 	resp, err := h.client.MakeRequest(req)
 	if err != nil {
@@ -238,6 +286,6 @@ func (h *HttpJson) sendRequest(serverURL string) (string, float64, error) {
 
 func init() {
 	inputs.Add("httpjson", func() telegraf.Input {
-		return &HttpJson{client: RealHTTPClient{client: &http.Client{}}}
+		return &HttpJson{}
 	})
 }
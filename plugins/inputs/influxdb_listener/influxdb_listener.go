@@ -0,0 +1,324 @@
+package influxdb_listener
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// version is the version of InfluxDB reported to the clients, which check it
+// to pick the API they use
+const version = "1.0.0"
+
+// precisions are the precisions of the timestamps of the writes, with the
+// precision they are parsed with
+var precisions = map[string]string{
+	"":   "n",
+	"n":  "n",
+	"ns": "n",
+	"u":  "u",
+	"us": "u",
+	"ms": "ms",
+	"s":  "s",
+	"m":  "m",
+	"h":  "h",
+}
+
+// InfluxDBListener accepts the writes of the InfluxDB HTTP API, the points
+// of the /write requests being added as metrics
+type InfluxDBListener struct {
+	ServiceAddress string            `toml:"service_address"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MaxBodySize    internal.Size     `toml:"max_body_size"`
+	MetricBuffer   int               `toml:"metric_buffer"`
+	// DatabaseTag is the tag set to the database of the writes, the
+	// database is not kept if empty
+	DatabaseTag string `toml:"database_tag"`
+
+	// Credentials of the clients, with basic auth or the u and p parameters
+	BasicUsername string `toml:"basic_username"`
+	BasicPassword string `toml:"basic_password"`
+
+	// TLS certificate and key of the listener, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	metricC  chan telegraf.Metric
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Address and port to listen on
+  service_address = ":8186"
+
+  # Timeouts of reading the requests and writing the responses
+  # read_timeout = "10s"
+  # write_timeout = "10s"
+
+  # Maximum size of the body of the writes, larger writes being refused
+  # with HTTP status 413
+  # max_body_size = "32MiB"
+
+  # Maximum number of metrics to buffer between collection intervals, the
+  # writes being refused with HTTP status 503 when it is full
+  metric_buffer = 100000
+
+  # Tag set to the database of the writes, the db parameter of the
+  # requests, not kept if empty
+  # database_tag = ""
+
+  # Credentials of the clients, with basic auth or the u and p parameters
+  # basic_username = ""
+  # basic_password = ""
+
+  # TLS certificate and key of the listener, only accepting the clients
+  # presenting a certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+`
+
+func (l *InfluxDBListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *InfluxDBListener) Description() string {
+	return "Accept the writes of the InfluxDB HTTP API"
+}
+
+func (l *InfluxDBListener) Start() error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.ReadTimeout.Duration == 0 {
+		l.ReadTimeout.Duration = 10 * time.Second
+	}
+	if l.WriteTimeout.Duration == 0 {
+		l.WriteTimeout.Duration = 10 * time.Second
+	}
+	if l.MaxBodySize.Size == 0 {
+		l.MaxBodySize.Size = 32 * 1024 * 1024
+	}
+	if l.MetricBuffer == 0 {
+		l.MetricBuffer = 100000
+	}
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        l.TLSCert,
+		TLSKey:         l.TLSKey,
+		AllowedCACerts: l.TLSAllowedCACerts,
+	})
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", l.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	l.listener = listener
+	l.metricC = make(chan telegraf.Metric, l.MetricBuffer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/write", l.authenticate(l.handleWrite))
+	mux.HandleFunc("/query", l.authenticate(l.handleQuery))
+	mux.HandleFunc("/ping", l.handlePing)
+	l.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  l.ReadTimeout.Duration,
+		WriteTimeout: l.WriteTimeout.Duration,
+	}
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		// Serve returns an error once the listener is closed by Stop
+		l.server.Serve(listener)
+	}()
+	l.Log.Infof("Started the influxdb_listener service on %s",
+		l.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address listened on
+func (l *InfluxDBListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *InfluxDBListener) Stop() {
+	l.Lock()
+	defer l.Unlock()
+	l.listener.Close()
+	l.wg.Wait()
+	l.Log.Info("Stopped the influxdb_listener service")
+}
+
+func (l *InfluxDBListener) Gather(acc telegraf.Accumulator) error {
+	l.Lock()
+	defer l.Unlock()
+	nmetrics := len(l.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-l.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+// authenticate checks the credentials of the requests if basic_username or
+// basic_password are set, given with basic auth or the u and p parameters
+func (l *InfluxDBListener) authenticate(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if l.BasicUsername == "" && l.BasicPassword == "" {
+			h(w, r)
+			return
+		}
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			username = r.URL.Query().Get("u")
+			password = r.URL.Query().Get("p")
+		}
+		if subtle.ConstantTimeCompare([]byte(username),
+			[]byte(l.BasicUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password),
+				[]byte(l.BasicPassword)) != 1 {
+			writeError(w, http.StatusUnauthorized, "authorization failed")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// writeError responds with the error in the json of InfluxDB
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Influxdb-Version", version)
+	w.Header().Set("X-Influxdb-Error", msg)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// handlePing responds to the health checks of the clients
+func (l *InfluxDBListener) handlePing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Influxdb-Version", version)
+	if r.URL.Query().Get("verbose") != "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"version": version})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleQuery responds with empty results to the queries, the clients
+// creating their database before writing
+func (l *InfluxDBListener) handleQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Influxdb-Version", version)
+	fmt.Fprint(w, `{"results":[]}`)
+}
+
+// handleWrite queues the points of the write for the next Gather, the
+// points parsed being kept if some lines are not valid
+func (l *InfluxDBListener) handleWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	params := r.URL.Query()
+	precision, ok := precisions[params.Get("precision")]
+	if !ok {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("invalid precision %q", params.Get("precision")))
+		return
+	}
+	if r.ContentLength > l.MaxBodySize.Size {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			"request body too large")
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+	// The size is checked after decompression
+	buf, err := ioutil.ReadAll(io.LimitReader(body, l.MaxBodySize.Size+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if int64(len(buf)) > l.MaxBodySize.Size {
+		writeError(w, http.StatusRequestEntityTooLarge,
+			"request body too large")
+		return
+	}
+
+	points, parseErr := models.ParsePointsWithPrecision(buf, time.Now(),
+		precision)
+	db := params.Get("db")
+	for _, point := range points {
+		tags := point.Tags()
+		if l.DatabaseTag != "" && db != "" {
+			tags[l.DatabaseTag] = db
+		}
+		m, err := telegraf.NewMetric(point.Name(), tags, point.Fields(),
+			point.Time())
+		if err != nil {
+			l.Log.Errorf("Could not create metric: %s", err)
+			continue
+		}
+		select {
+		case l.metricC <- m:
+		default:
+			l.Log.Warn("Buffer is full, refusing the write." +
+				" You may want to increase the metric_buffer setting")
+			writeError(w, http.StatusServiceUnavailable, "buffer full")
+			return
+		}
+	}
+	if parseErr != nil {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("partial write: %s", parseErr))
+		return
+	}
+	w.Header().Set("X-Influxdb-Version", version)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func init() {
+	inputs.Add("influxdb_listener", func() telegraf.Input {
+		return &InfluxDBListener{
+			ServiceAddress: ":8186",
+			MetricBuffer:   100000,
+		}
+	})
+}
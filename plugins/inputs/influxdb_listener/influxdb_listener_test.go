@@ -0,0 +1,166 @@
+package influxdb_listener
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lines = `cpu_load_short,host=server01 value=12.0 1422568543702900257
+cpu_load_short,host=server02 value=1.5,idle=98i 1422568543702900257
+`
+
+func newListener(t *testing.T, l *InfluxDBListener) (*InfluxDBListener, string) {
+	l.ServiceAddress = "127.0.0.1:0"
+	l.Log = testutil.Logger{}
+	require.NoError(t, l.Start())
+	return l, "http://" + l.Addr().String()
+}
+
+func TestWrite(t *testing.T) {
+	l, url := newListener(t, &InfluxDBListener{DatabaseTag: "database"})
+	defer l.Stop()
+
+	resp, err := http.Post(url+"/write?db=mydb", "", strings.NewReader(lines))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, version, resp.Header.Get("X-Influxdb-Version"))
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{"host": "server01", "database": "mydb"})
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": 1.5, "idle": int64(98)},
+		map[string]string{"host": "server02", "database": "mydb"})
+	assert.Equal(t, time.Unix(0, 1422568543702900257).UTC(),
+		acc.Metrics[0].Time)
+
+	// The timestamps are in seconds, gzipped
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("disk,path=/ used=10i 1422568543\n"))
+	gz.Close()
+	req, err := http.NewRequest("POST", url+"/write?precision=s", &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	acc = testutil.Accumulator{}
+	require.NoError(t, l.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	assert.Equal(t, map[string]string{"path": "/"}, acc.Metrics[0].Tags)
+	assert.Equal(t, time.Unix(1422568543, 0).UTC(),
+		acc.Metrics[0].Time)
+}
+
+func TestWriteErrors(t *testing.T) {
+	l, url := newListener(t, &InfluxDBListener{
+		MaxBodySize:  internal.Size{Size: 100},
+		MetricBuffer: 2,
+	})
+	defer l.Stop()
+
+	post := func(path string, body string) (int, string) {
+		resp, err := http.Post(url+path, "", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	// The valid lines of a partial write are kept
+	status, body := post("/write", "cpu value=1\ncpu value=\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "partial write")
+	var acc testutil.Accumulator
+	require.NoError(t, l.Gather(&acc))
+	assert.Len(t, acc.Metrics, 1)
+
+	status, _ = post("/write?precision=d", "cpu value=1\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = post("/write", strings.Repeat("cpu value=1\n", 10))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	status, body = post("/write", "cpu value=1\ncpu value=2\ncpu value=3\n")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, body, "buffer full")
+
+	resp, err := http.Get(url + "/write")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestPingQuery(t *testing.T) {
+	l, url := newListener(t, &InfluxDBListener{})
+	defer l.Stop()
+
+	resp, err := http.Get(url + "/ping")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, version, resp.Header.Get("X-Influxdb-Version"))
+
+	resp, err = http.Post(url+"/query?q=CREATE+DATABASE+mydb", "", nil)
+	require.NoError(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `{"results":[]}`, string(b))
+}
+
+func TestBasicAuth(t *testing.T) {
+	l, url := newListener(t, &InfluxDBListener{
+		BasicUsername: "telegraf",
+		BasicPassword: "secret",
+	})
+	defer l.Stop()
+
+	resp, err := http.Post(url+"/write", "", strings.NewReader(lines))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, err := http.NewRequest("POST", url+"/write", strings.NewReader(lines))
+	require.NoError(t, err)
+	req.SetBasicAuth("telegraf", "secret")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Post(url+"/write?u=telegraf&p=secret", "",
+		strings.NewReader(lines))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// The pings are not authenticated
+	resp, err = http.Get(url + "/ping")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	var acc testutil.Accumulator
+	require.NoError(t, l.Gather(&acc))
+	assert.Len(t, acc.Metrics, 4)
+}
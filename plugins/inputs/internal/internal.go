@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"runtime"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+type Self struct {
+	CollectMemstats bool `toml:"collect_memstats"`
+}
+
+func NewSelf() telegraf.Input {
+	return &Self{
+		CollectMemstats: true,
+	}
+}
+
+var sampleConfig = `
+  # If true, collect telegraf memory stats.
+  collect_memstats = true
+`
+
+func (s *Self) Description() string {
+	return "Collect statistics about itself"
+}
+
+func (s *Self) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Self) Gather(acc telegraf.Accumulator) error {
+	if s.CollectMemstats {
+		m := &runtime.MemStats{}
+		runtime.ReadMemStats(m)
+		fields := map[string]interface{}{
+			"alloc_bytes":         m.Alloc,      // bytes allocated and not yet freed
+			"total_alloc_bytes":   m.TotalAlloc, // bytes allocated (even if freed)
+			"sys_bytes":           m.Sys,        // bytes obtained from system (sum of XxxSys below)
+			"pointer_lookups":     m.Lookups,    // number of pointer lookups
+			"mallocs":             m.Mallocs,    // number of mallocs
+			"frees":               m.Frees,      // number of frees
+			"heap_alloc_bytes":    m.HeapAlloc,  // bytes allocated and not yet freed (same as Alloc above)
+			"heap_sys_bytes":      m.HeapSys,    // bytes obtained from system
+			"heap_idle_bytes":     m.HeapIdle,   // bytes in idle spans
+			"heap_in_use_bytes":   m.HeapInuse,  // bytes in non-idle span
+			"heap_released_bytes": m.HeapReleased,
+			"heap_objects":        m.HeapObjects,
+			"num_gc":              m.NumGC,
+			"num_goroutines":      runtime.NumGoroutine(),
+		}
+		acc.AddFields("internal_memstats", fields, map[string]string{})
+	}
+
+	runningInputs, runningOutputs := internal_models.Running()
+
+	var gathered, gatherErrors, gatherTimeouts int64
+	for _, ri := range runningInputs {
+		fields := map[string]interface{}{
+			"metrics_gathered":     ri.MetricsGathered(),
+			"gather_time_ns":       int64(ri.GatherTime()),
+			"errors":               ri.GatherErrors(),
+			"timeouts":             ri.GatherTimeouts(),
+			"panics":               ri.GatherPanics(),
+			"slow_gathers":         ri.SlowGathers(),
+			"consecutive_failures": ri.ConsecutiveFailures(),
+			"goroutines":           ri.Goroutines(),
+			"disabled":             ri.Disabled(),
+		}
+		tags := map[string]string{"input": ri.Name}
+		if ri.Config.Alias != "" {
+			tags["alias"] = ri.Config.Alias
+		}
+		acc.AddFields("internal_gather", fields, tags)
+		gathered += ri.MetricsGathered()
+		gatherErrors += ri.GatherErrors()
+		gatherTimeouts += ri.GatherTimeouts()
+	}
+
+	var written, dropped, writeErrors int64
+	for _, ro := range runningOutputs {
+		fields := map[string]interface{}{
+			"metrics_added":       ro.MetricsAdded(),
+			"metrics_written":     ro.MetricsWritten(),
+			"metrics_dropped":     ro.MetricsDropped(),
+			"buffer_size":         ro.BufferSize(),
+			"buffer_limit":        ro.BufferLimit(),
+			"write_time_ns":       int64(ro.WriteTime()),
+			"errors":              ro.WriteErrors(),
+			"rate_limit_delay_ns": int64(ro.RateLimitDelay()),
+		}
+		tags := map[string]string{"output": ro.Name}
+		if ro.Config.Alias != "" {
+			tags["alias"] = ro.Config.Alias
+		}
+		acc.AddFields("internal_write", fields, tags)
+		written += ro.MetricsWritten()
+		dropped += ro.MetricsDropped()
+		writeErrors += ro.WriteErrors()
+	}
+
+	fields := map[string]interface{}{
+		"metrics_gathered": gathered,
+		"metrics_written":  written,
+		"metrics_dropped":  dropped,
+		"gather_errors":    gatherErrors,
+		"gather_timeouts":  gatherTimeouts,
+		"write_errors":     writeErrors,
+	}
+	acc.AddFields("internal_agent", fields, map[string]string{})
+
+	// Statistics registered by the plugins
+	for _, m := range selfstat.Metrics() {
+		if m.Type() == telegraf.Counter {
+			acc.AddCounter(m.Name(), m.Fields(), m.Tags(), m.Time())
+		} else {
+			acc.AddGauge(m.Name(), m.Fields(), m.Tags(), m.Time())
+		}
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("internal", NewSelf)
+}
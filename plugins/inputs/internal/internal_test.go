@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutput struct{}
+
+func (o *testOutput) Connect() error                  { return nil }
+func (o *testOutput) Close() error                    { return nil }
+func (o *testOutput) Description() string             { return "" }
+func (o *testOutput) SampleConfig() string            { return "" }
+func (o *testOutput) Write(_ []telegraf.Metric) error { return nil }
+
+func TestGather(t *testing.T) {
+	ri := &internal_models.RunningInput{
+		Name:   "cpu",
+		Config: &internal_models.InputConfig{Name: "cpu", Alias: "system"},
+	}
+	ri.GatherComplete(3, 2*time.Millisecond, 0, nil)
+	ri.GatherComplete(0, time.Millisecond, 2, errors.New("failed"))
+	ri.GatherTimedOut()
+	ri.GatherPanicked()
+	ri.GatherSlow(true)
+	ri.GatherFailed(true)
+	ri.GatherFailed(true)
+
+	ro := internal_models.NewRunningOutput("influxdb", &testOutput{},
+		&internal_models.OutputConfig{Name: "influxdb"}, 0, 10)
+	ro.Quiet = true
+	m, _ := telegraf.NewMetric("cpu", nil,
+		map[string]interface{}{"value": 1}, time.Now())
+	ro.AddPoint(m)
+	ro.AddPoint(m)
+	require.NoError(t, ro.Write())
+	ro.AddPoint(m)
+
+	internal_models.SetRunning([]*internal_models.RunningInput{ri},
+		[]*internal_models.RunningOutput{ro})
+	defer internal_models.SetRunning(nil, nil)
+
+	s := &Self{}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	acc.AssertContainsTaggedFields(t, "internal_gather",
+		map[string]interface{}{
+			"metrics_gathered":     int64(3),
+			"gather_time_ns":       int64(time.Millisecond),
+			"errors":               int64(1),
+			"timeouts":             int64(1),
+			"panics":               int64(1),
+			"slow_gathers":         int64(1),
+			"consecutive_failures": int64(2),
+			"goroutines":           int64(2),
+			"disabled":             false,
+		},
+		map[string]string{"input": "cpu", "alias": "system"})
+
+	assert.True(t, acc.HasMeasurement("internal_write"))
+	assert.True(t, acc.HasIntField("internal_write", "write_time_ns"))
+	for _, p := range acc.Metrics {
+		if p.Measurement != "internal_write" {
+			continue
+		}
+		assert.Equal(t, map[string]string{"output": "influxdb"}, p.Tags)
+		assert.Equal(t, int64(3), p.Fields["metrics_added"])
+		assert.Equal(t, int64(2), p.Fields["metrics_written"])
+		assert.Equal(t, int64(0), p.Fields["metrics_dropped"])
+		assert.Equal(t, 1, p.Fields["buffer_size"])
+		assert.Equal(t, 10, p.Fields["buffer_limit"])
+		assert.Equal(t, int64(0), p.Fields["errors"])
+	}
+
+	acc.AssertContainsTaggedFields(t, "internal_agent",
+		map[string]interface{}{
+			"metrics_gathered": int64(3),
+			"metrics_written":  int64(2),
+			"metrics_dropped":  int64(0),
+			"gather_errors":    int64(1),
+			"gather_timeouts":  int64(1),
+			"write_errors":     int64(0),
+		},
+		map[string]string{})
+
+	assert.False(t, acc.HasMeasurement("internal_memstats"))
+}
+
+func TestGatherMemstats(t *testing.T) {
+	s := NewSelf()
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	assert.True(t, acc.HasMeasurement("internal_memstats"))
+	assert.True(t, acc.HasMeasurement("internal_agent"))
+}
+
+func TestGatherSelfstat(t *testing.T) {
+	tags := map[string]string{"address": ":8125"}
+	parseErrors := selfstat.Register("test", "parse_errors", tags)
+	defer selfstat.Unregister(parseErrors)
+	queue := selfstat.RegisterGauge("test", "queue_size", tags)
+	defer selfstat.Unregister(queue)
+	parseErrors.Incr(2)
+	queue.Set(5)
+
+	s := &Self{}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	for _, m := range acc.Metrics {
+		if m.Measurement != "internal_test" {
+			continue
+		}
+		assert.Equal(t, tags, m.Tags)
+		if m.Type == telegraf.Counter {
+			assert.Equal(t, map[string]interface{}{"parse_errors": int64(2)},
+				m.Fields)
+		} else {
+			assert.Equal(t, telegraf.Gauge, m.Type)
+			assert.Equal(t, map[string]interface{}{"queue_size": int64(5)},
+				m.Fields)
+		}
+	}
+	assert.True(t, acc.HasMeasurement("internal_test"))
+}
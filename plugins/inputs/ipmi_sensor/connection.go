@@ -0,0 +1,62 @@
+package ipmi_sensor
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// connectionRe matches the servers, [username[:password]@][protocol](address)
+var connectionRe = regexp.MustCompile(`^(?:([^:@]*)(?::(.*))?@)?(\w*)\((.+)\)$`)
+
+// Connection is a remote IPMI interface
+type Connection struct {
+	Hostname  string
+	Username  string
+	Password  string
+	Port      string
+	Interface string
+	Privilege string
+}
+
+// NewConnection parses a server of the config
+func NewConnection(server string, privilege string) (*Connection, error) {
+	m := connectionRe.FindStringSubmatch(server)
+	if m == nil {
+		return nil, fmt.Errorf("invalid server %q, must be "+
+			"[username[:password]@][protocol](address)", server)
+	}
+	conn := &Connection{
+		Username:  m[1],
+		Password:  m[2],
+		Interface: m[3],
+		Hostname:  m[4],
+		Privilege: privilege,
+	}
+	if conn.Interface == "" {
+		conn.Interface = "lan"
+	}
+	if host, port, err := net.SplitHostPort(conn.Hostname); err == nil {
+		conn.Hostname, conn.Port = host, port
+	}
+	return conn, nil
+}
+
+// options returns the options of ipmitool connecting to the interface
+func (c *Connection) options() []string {
+	options := []string{"-I", c.Interface, "-H", c.Hostname}
+	if c.Port != "" {
+		options = append(options, "-p", c.Port)
+	}
+	if c.Username != "" {
+		options = append(options, "-U", c.Username)
+	}
+	if c.Password != "" {
+		options = append(options, "-P", c.Password)
+	}
+	if c.Privilege != "" {
+		options = append(options, "-L", strings.ToUpper(c.Privilege))
+	}
+	return options
+}
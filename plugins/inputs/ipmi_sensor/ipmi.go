@@ -0,0 +1,196 @@
+package ipmi_sensor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs ipmitool with the arguments, killing it after the timeout, and
+// returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Ipmi reads the sensors of the local or remote IPMI interfaces with
+// ipmitool
+type Ipmi struct {
+	Path      string
+	Servers   []string
+	Privilege string
+	Timeout   internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of ipmitool, looked up in the PATH by default
+  # path = "/usr/bin/ipmitool"
+
+  # Remote IPMI interfaces, [username[:password]@][protocol](address), the
+  # protocol being lan by default, ie "USERID:PASSW0RD@lan(192.168.1.1)" or
+  # "root@lanplus(10.0.0.1:623)". The sensors of the local interface are
+  # read if no servers are set.
+  # servers = ["USERID:PASSW0RD@lan(192.168.1.1)"]
+
+  # Privilege level of the sessions with the remote interfaces, ie
+  # "USER", "OPERATOR" or "ADMINISTRATOR"
+  # privilege = "USER"
+
+  # Timeout of the ipmitool commands
+  timeout = "20s"
+`
+
+func (m *Ipmi) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Ipmi) Description() string {
+	return "Read metrics from the bare metal servers via IPMI"
+}
+
+func (m *Ipmi) Gather(acc telegraf.Accumulator) error {
+	if m.Path == "" {
+		path, err := exec.LookPath("ipmitool")
+		if err != nil {
+			return fmt.Errorf("ipmitool not found: %s", err)
+		}
+		m.Path = path
+	}
+	if m.runner == nil {
+		m.runner = runIpmitool
+	}
+	if m.Timeout.Duration == 0 {
+		m.Timeout.Duration = 20 * time.Second
+	}
+
+	if len(m.Servers) == 0 {
+		return m.gatherServer("", acc)
+	}
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errorStrings []string
+	for _, server := range m.Servers {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if err := m.gatherServer(server, acc); err != nil {
+				errMu.Lock()
+				errorStrings = append(errorStrings, err.Error())
+				errMu.Unlock()
+			}
+		}(server)
+	}
+	wg.Wait()
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// gatherServer adds the sensors of the server, the local interface if empty
+func (m *Ipmi) gatherServer(server string, acc telegraf.Accumulator) error {
+	var args []string
+	var hostname string
+	if server != "" {
+		conn, err := NewConnection(server, m.Privilege)
+		if err != nil {
+			return err
+		}
+		args = conn.options()
+		hostname = conn.Hostname
+	}
+	args = append(args, "sdr")
+
+	out, err := m.runner(m.Timeout.Duration, m.Path, args...)
+	if err != nil {
+		if hostname != "" {
+			return fmt.Errorf("reading the sensors of %s: %s: %s", hostname,
+				err, strings.TrimSpace(out))
+		}
+		return fmt.Errorf("reading the sensors: %s: %s", err,
+			strings.TrimSpace(out))
+	}
+
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		// Name | value unit | status
+		parts := strings.Split(scanner.Text(), "|")
+		if len(parts) != 3 {
+			continue
+		}
+		tags := map[string]string{"name": transform(parts[0])}
+		if hostname != "" {
+			tags["server"] = hostname
+		}
+		fields := map[string]interface{}{
+			"status": 0,
+		}
+		if strings.TrimSpace(parts[2]) == "ok" {
+			fields["status"] = 1
+		}
+
+		// The readings are a value and its unit, the discrete sensors a
+		// hexadecimal state, ie "0x01", and the sensors not read "no reading"
+		reading := strings.Fields(parts[1])
+		if len(reading) > 0 {
+			if value, err := strconv.ParseFloat(reading[0], 64); err == nil &&
+				!strings.HasPrefix(reading[0], "0x") {
+				fields["value"] = value
+				if len(reading) > 1 {
+					tags["unit"] = transform(strings.Join(reading[1:], " "))
+				}
+			}
+		}
+		acc.AddFields("ipmi_sensor", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+// transform returns the name or unit in lower case with underscores, ie
+// "degrees_c" for "degrees C"
+func transform(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// runIpmitool runs ipmitool, killing it after the timeout
+func runIpmitool(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("ipmitool timed out after %s", timeout)
+	}
+}
+
+func init() {
+	inputs.Add("ipmi_sensor", func() telegraf.Input {
+		return &Ipmi{
+			Timeout: internal.Duration{Duration: 20 * time.Second},
+			runner:  runIpmitool,
+		}
+	})
+}
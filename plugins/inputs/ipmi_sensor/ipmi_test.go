@@ -0,0 +1,124 @@
+package ipmi_sensor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sdrOutput = `Ambient Temp     | 20 degrees C      | ok
+Altitude         | 80 feet           | ok
+Avg Power        | 210 Watts         | ok
+Planar 3.3V      | 3.29 Volts        | ok
+Fan 1A Tach      | 4710 RPM          | ok
+Fan 2B Tach      | no reading        | ns
+PS 1 Status      | 0x01              | ok
+CPU 2 Temp       | 95 degrees C      | cr
+`
+
+func TestGather(t *testing.T) {
+	var calls [][]string
+	i := &Ipmi{
+		Path:      "ipmitool",
+		Servers:   []string{"USERID:PASSW0RD@lan(192.168.1.1)"},
+		Privilege: "user",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, args)
+			return sdrOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+
+	assert.Equal(t, [][]string{{"-I", "lan", "-H", "192.168.1.1", "-U",
+		"USERID", "-P", "PASSW0RD", "-L", "USER", "sdr"}}, calls)
+	assert.Equal(t, 8, len(acc.Metrics))
+	for _, tt := range []struct {
+		fields map[string]interface{}
+		tags   map[string]string
+	}{
+		{
+			map[string]interface{}{"value": float64(20), "status": 1},
+			map[string]string{"name": "ambient_temp", "unit": "degrees_c"},
+		},
+		{
+			map[string]interface{}{"value": 3.29, "status": 1},
+			map[string]string{"name": "planar_3.3v", "unit": "volts"},
+		},
+		{
+			map[string]interface{}{"value": float64(4710), "status": 1},
+			map[string]string{"name": "fan_1a_tach", "unit": "rpm"},
+		},
+		{
+			map[string]interface{}{"status": 0},
+			map[string]string{"name": "fan_2b_tach"},
+		},
+		{
+			map[string]interface{}{"status": 1},
+			map[string]string{"name": "ps_1_status"},
+		},
+		{
+			map[string]interface{}{"value": float64(95), "status": 0},
+			map[string]string{"name": "cpu_2_temp", "unit": "degrees_c"},
+		},
+	} {
+		tt.tags["server"] = "192.168.1.1"
+		acc.AssertContainsTaggedFields(t, "ipmi_sensor", tt.fields, tt.tags)
+	}
+}
+
+func TestGatherLocal(t *testing.T) {
+	var calls [][]string
+	i := &Ipmi{
+		Path: "ipmitool",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, args)
+			return "Ambient Temp     | 20 degrees C      | ok\n", nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, i.Gather(&acc))
+	assert.Equal(t, [][]string{{"sdr"}}, calls)
+	acc.AssertContainsTaggedFields(t, "ipmi_sensor",
+		map[string]interface{}{"value": float64(20), "status": 1},
+		map[string]string{"name": "ambient_temp", "unit": "degrees_c"})
+
+	i.runner = func(timeout time.Duration, path string, args ...string) (string, error) {
+		return "Could not open device\n", errors.New("exit status 1")
+	}
+	err := i.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not open device")
+}
+
+func TestNewConnection(t *testing.T) {
+	conn, err := NewConnection("root:pa:ss@lanplus(10.0.0.1:623)", "")
+	require.NoError(t, err)
+	assert.Equal(t, &Connection{
+		Hostname:  "10.0.0.1",
+		Port:      "623",
+		Username:  "root",
+		Password:  "pa:ss",
+		Interface: "lanplus",
+	}, conn)
+
+	conn, err = NewConnection("(bmc.local)", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-I", "lan", "-H", "bmc.local"}, conn.options())
+
+	_, err = NewConnection("192.168.1.1", "")
+	assert.Error(t, err)
+}
+
+func TestRunIpmitoolTimeout(t *testing.T) {
+	_, err := runIpmitool(10*time.Millisecond, "sleep", "1")
+	assert.Error(t, err)
+
+	out, err := runIpmitool(time.Second, "echo", "sdr")
+	require.NoError(t, err)
+	assert.Equal(t, "sdr\n", out)
+}
@@ -0,0 +1,196 @@
+// +build linux
+
+package iptables
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs iptables with the arguments, killing it after the timeout,
+// and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Iptables reads the counters of the rules of iptables, the rules being
+// identified by their comment
+type Iptables struct {
+	Binary  string
+	UseSudo bool `toml:"use_sudo"`
+	UseLock bool `toml:"use_lock"`
+	Table   string
+	Chains  []string
+	Timeout internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of iptables, or ip6tables for the rules of IPv6
+  binary = "/sbin/iptables"
+
+  # Run iptables with sudo, which must be allowed without password
+  # use_sudo = false
+
+  # Wait for the lock of iptables, which is held while the rules are
+  # modified, instead of failing
+  # use_lock = false
+
+  # Table and chains of the rules. Only the rules with a comment, ie added
+  # with -m comment --comment "ssh", are gathered, identified by it.
+  table = "filter"
+  chains = ["INPUT"]
+
+  # Timeout of iptables
+  # timeout = "5s"
+`
+
+func (ipt *Iptables) SampleConfig() string {
+	return sampleConfig
+}
+
+func (ipt *Iptables) Description() string {
+	return "Gather the packets and bytes counters of the rules of iptables"
+}
+
+// commentRe is the comment of a rule, at the end of the options of the lines
+// of iptables -L
+var commentRe = regexp.MustCompile(`/\* (.+?) \*/`)
+
+func (ipt *Iptables) Gather(acc telegraf.Accumulator) error {
+	if ipt.runner == nil {
+		ipt.runner = runIptables
+	}
+	if ipt.Binary == "" {
+		ipt.Binary = "/sbin/iptables"
+	}
+	if ipt.Table == "" {
+		ipt.Table = "filter"
+	}
+	if ipt.Timeout.Duration == 0 {
+		ipt.Timeout.Duration = 5 * time.Second
+	}
+
+	var errorStrings []string
+	for _, chain := range ipt.Chains {
+		out, err := ipt.chainList(chain)
+		if err != nil {
+			errorStrings = append(errorStrings,
+				fmt.Sprintf("listing the chain %s: %s", chain, err))
+			continue
+		}
+		if err := ipt.parseChain(chain, out, acc); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// chainList lists the rules of the chain with their exact counters
+func (ipt *Iptables) chainList(chain string) (string, error) {
+	path, args := ipt.Binary, []string{}
+	if ipt.UseSudo {
+		path, args = "sudo", []string{"-n", ipt.Binary}
+	}
+	if ipt.UseLock {
+		args = append(args, "-w")
+	}
+	args = append(args, "-t", ipt.Table, "-nvxL", chain)
+	out, err := ipt.runner(ipt.Timeout.Duration, path, args...)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// parseChain adds a point per rule of the chain with a comment, the rules
+// being listed as
+//
+//	Chain INPUT (policy ACCEPT 58 packets, 5096 bytes)
+//	    pkts      bytes target     prot opt in     out     source               destination
+//	     100     1024 ACCEPT     tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpt:22 /* ssh */
+func (ipt *Iptables) parseChain(chain string, out string,
+	acc telegraf.Accumulator) error {
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		cols := strings.Fields(line)
+		if len(cols) < 2 {
+			continue
+		}
+		// The headers of the chain and of the columns
+		pkts, err := strconv.ParseUint(cols[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		nbytes, err := strconv.ParseUint(cols[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("parsing the bytes of the chain %s: %s", chain,
+				err)
+		}
+		comment := commentRe.FindStringSubmatch(line)
+		if comment == nil {
+			continue
+		}
+
+		tags := map[string]string{
+			"table":  ipt.Table,
+			"chain":  chain,
+			"ruleid": comment[1],
+		}
+		fields := map[string]interface{}{
+			"pkts":  pkts,
+			"bytes": nbytes,
+		}
+		acc.AddFields("iptables", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+// runIptables runs iptables, killing it after the timeout
+func runIptables(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("iptables timed out after %s",
+			timeout)
+	}
+}
+
+func init() {
+	inputs.Add("iptables", func() telegraf.Input {
+		return &Iptables{
+			Binary:  "/sbin/iptables",
+			Table:   "filter",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			runner:  runIptables,
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// +build linux
+
+package iptables
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const inputChain = `Chain INPUT (policy ACCEPT 58 packets, 5096 bytes)
+    pkts      bytes target     prot opt in     out     source               destination
+     100     1024 ACCEPT     tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp dpt:22 /* ssh */
+      42     2520 DROP       tcp  --  eth0   *       10.0.0.0/8           0.0.0.0/0            tcp dpt:80 /* drop http from lan */
+ 4294967296 123456789012            all  --  *      *       0.0.0.0/0            0.0.0.0/0            /* accounting */
+       7      420 ACCEPT     icmp --  *      *       0.0.0.0/0            0.0.0.0/0
+`
+
+func TestGather(t *testing.T) {
+	var calls []string
+	ipt := &Iptables{
+		Binary:  "iptables",
+		UseSudo: true,
+		UseLock: true,
+		Chains:  []string{"INPUT", "MISSING"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			if args[len(args)-1] == "MISSING" {
+				return "iptables: No chain/target/match by that name.",
+					errors.New("exit status 1")
+			}
+			return inputChain, nil
+		},
+	}
+	var acc testutil.Accumulator
+	err := ipt.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No chain/target/match")
+
+	assert.Equal(t, []string{
+		"sudo -n iptables -w -t filter -nvxL INPUT",
+		"sudo -n iptables -w -t filter -nvxL MISSING",
+	}, calls)
+	// The rules without comment are not gathered
+	assert.Equal(t, 3, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "iptables", map[string]interface{}{
+		"pkts":  uint64(100),
+		"bytes": uint64(1024),
+	}, map[string]string{"table": "filter", "chain": "INPUT", "ruleid": "ssh"})
+	acc.AssertContainsTaggedFields(t, "iptables", map[string]interface{}{
+		"pkts":  uint64(42),
+		"bytes": uint64(2520),
+	}, map[string]string{
+		"table":  "filter",
+		"chain":  "INPUT",
+		"ruleid": "drop http from lan",
+	})
+	// The rules without target
+	acc.AssertContainsTaggedFields(t, "iptables", map[string]interface{}{
+		"pkts":  uint64(4294967296),
+		"bytes": uint64(123456789012),
+	}, map[string]string{
+		"table":  "filter",
+		"chain":  "INPUT",
+		"ruleid": "accounting",
+	})
+}
+
+func TestGatherTable(t *testing.T) {
+	var calls []string
+	ipt := &Iptables{
+		Binary: "ip6tables",
+		Table:  "mangle",
+		Chains: []string{"PREROUTING"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return "Chain PREROUTING (policy ACCEPT 0 packets, 0 bytes)\n" +
+				"    pkts      bytes target     prot opt in     out     source               destination\n" +
+				"       3      180 MARK       all      *      *       ::/0                 ::/0                 /* mark */ MARK set 0x1\n", nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, ipt.Gather(&acc))
+
+	assert.Equal(t, []string{"ip6tables -t mangle -nvxL PREROUTING"}, calls)
+	acc.AssertContainsTaggedFields(t, "iptables", map[string]interface{}{
+		"pkts":  uint64(3),
+		"bytes": uint64(180),
+	}, map[string]string{
+		"table":  "mangle",
+		"chain":  "PREROUTING",
+		"ruleid": "mark",
+	})
+}
@@ -0,0 +1,79 @@
+package jolokia2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// readRequest is a bulk read request of the Jolokia protocol, see
+// https://jolokia.org/reference/html/protocol.html#read
+type readRequest struct {
+	Type      string       `json:"type"`
+	Mbean     string       `json:"mbean"`
+	Attribute interface{}  `json:"attribute,omitempty"`
+	Path      string       `json:"path,omitempty"`
+	Target    *proxyTarget `json:"target,omitempty"`
+}
+
+// proxyTarget is the agent read by a Jolokia proxy
+type proxyTarget struct {
+	URL      string `json:"url"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// readResponse is the response of a read request, its request is the
+// request as understood by Jolokia
+type readResponse struct {
+	Status  int         `json:"status"`
+	Error   string      `json:"error"`
+	Value   interface{} `json:"value"`
+	Request struct {
+		Mbean     string       `json:"mbean"`
+		Attribute interface{}  `json:"attribute"`
+		Path      string       `json:"path"`
+		Target    *proxyTarget `json:"target"`
+	} `json:"request"`
+}
+
+// read sends the read requests to the Jolokia agent or proxy at url in one
+// bulk request, and returns their responses in the same order.
+func read(
+	client *http.Client,
+	url string,
+	requests []readRequest,
+) ([]readResponse, error) {
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+"/",
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+
+	var responses []readResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		return nil, fmt.Errorf("error decoding the response of %s: %s", url,
+			err)
+	}
+	if len(responses) != len(requests) {
+		return nil, fmt.Errorf("%s returned %d responses for %d requests", url,
+			len(responses), len(requests))
+	}
+	return responses, nil
+}
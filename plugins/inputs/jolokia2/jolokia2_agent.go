@@ -0,0 +1,176 @@
+package jolokia2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Jolokia2Agent reads the MBeans of the JVMs running a Jolokia agent
+type Jolokia2Agent struct {
+	URLs []string `toml:"urls"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	Username           string
+	Password           string
+	ResponseTimeout    internal.Duration `toml:"response_timeout"`
+	SSLCA              string            `toml:"ssl_ca"`
+	SSLCert            string            `toml:"ssl_cert"`
+	SSLKey             string            `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Default options of the metrics, see Metric
+	DefaultFieldPrefix    string `toml:"default_field_prefix"`
+	DefaultFieldSeparator string `toml:"default_field_separator"`
+	DefaultTagPrefix      string `toml:"default_tag_prefix"`
+
+	Metrics []Metric `toml:"metric"`
+
+	client *http.Client
+}
+
+var agentSampleConfig = `
+  # Urls of the Jolokia agents
+  urls = ["http://localhost:8778/jolokia"]
+
+  # Credentials of the agents
+  # username = ""
+  # password = ""
+
+  # Timeout of the requests, 5s by default
+  # response_timeout = "5s"
+
+  # TLS options of the https urls
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Defaults of the options of the metrics
+  # default_field_prefix = ""
+  # default_field_separator = "."
+  # default_tag_prefix = ""
+
+  # Metrics gathered, the attributes of the paths of the MBeans of the mbean
+  # pattern, all of them if no paths are set. The properties of the tag_keys
+  # of the object names of the MBeans are added as tags.
+  [[inputs.jolokia2_agent.metric]]
+    name = "jvm_memory"
+    mbean = "java.lang:type=Memory"
+    paths = ["HeapMemoryUsage", "NonHeapMemoryUsage"]
+
+  [[inputs.jolokia2_agent.metric]]
+    name = "jvm_garbage_collector"
+    mbean = "java.lang:type=GarbageCollector,*"
+    paths = ["CollectionTime", "CollectionCount"]
+    tag_keys = ["name"]
+`
+
+func (j *Jolokia2Agent) SampleConfig() string {
+	return agentSampleConfig
+}
+
+func (j *Jolokia2Agent) Description() string {
+	return "Read JMX metrics from one or more Jolokia agents"
+}
+
+// Init creates the HTTP client of the plugin and checks its metrics.
+func (j *Jolokia2Agent) Init() error {
+	client, err := createClient(j.Username, j.Password, j.ResponseTimeout,
+		internal.TLSOptions{
+			SSLCA:              j.SSLCA,
+			SSLCert:            j.SSLCert,
+			SSLKey:             j.SSLKey,
+			InsecureSkipVerify: j.InsecureSkipVerify,
+		})
+	if err != nil {
+		return err
+	}
+	for i := range j.Metrics {
+		err := j.Metrics[i].check(j.DefaultFieldPrefix,
+			j.DefaultFieldSeparator, j.DefaultTagPrefix)
+		if err != nil {
+			return err
+		}
+	}
+	j.client = client
+	return nil
+}
+
+// Gather reads the metrics of all the agents, tagged with the url of their
+// agent.
+func (j *Jolokia2Agent) Gather(acc telegraf.Accumulator) error {
+	if len(j.Metrics) == 0 {
+		return nil
+	}
+
+	requests := make([]readRequest, len(j.Metrics))
+	for i, m := range j.Metrics {
+		requests[i] = m.request()
+	}
+
+	var wg sync.WaitGroup
+	errorChannel := make(chan error, len(j.URLs))
+
+	for _, u := range j.URLs {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			responses, err := read(j.client, u, requests)
+			if err == nil {
+				err = addResponses(acc, j.Metrics, responses,
+					map[string]string{"jolokia_agent_url": u})
+			}
+			if err != nil {
+				errorChannel <- fmt.Errorf("jolokia agent %s: %s", u, err)
+			}
+		}(u)
+	}
+
+	wg.Wait()
+	close(errorChannel)
+
+	errorStrings := []string{}
+	for err := range errorChannel {
+		errorStrings = append(errorStrings, err.Error())
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// createClient returns the HTTP client of the Jolokia requests, with a
+// timeout of 5s by default
+func createClient(
+	username string,
+	password string,
+	timeout internal.Duration,
+	tls internal.TLSOptions,
+) (*http.Client, error) {
+	c := httpconfig.Config{
+		Timeout:  timeout.Duration,
+		Username: username,
+		Password: password,
+		TLS:      tls,
+		Proxy:    internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+	return c.CreateClient()
+}
+
+func init() {
+	inputs.Add("jolokia2_agent", func() telegraf.Input {
+		return &Jolokia2Agent{}
+	})
+}
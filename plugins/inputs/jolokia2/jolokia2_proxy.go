@@ -0,0 +1,175 @@
+package jolokia2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Jolokia2Proxy reads the MBeans of JVMs without a Jolokia agent through a
+// Jolokia proxy, reading them with JSR-160 remote JMX connections
+type Jolokia2Proxy struct {
+	URL string `toml:"url"`
+
+	// Options of the HTTP client of the proxy, see httpconfig.Config
+	Username           string
+	Password           string
+	ResponseTimeout    internal.Duration `toml:"response_timeout"`
+	SSLCA              string            `toml:"ssl_ca"`
+	SSLCert            string            `toml:"ssl_cert"`
+	SSLKey             string            `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Credentials of the targets without their own
+	DefaultTargetUsername string        `toml:"default_target_username"`
+	DefaultTargetPassword string        `toml:"default_target_password"`
+	Targets               []ProxyTarget `toml:"target"`
+
+	// Default options of the metrics, see Metric
+	DefaultFieldPrefix    string `toml:"default_field_prefix"`
+	DefaultFieldSeparator string `toml:"default_field_separator"`
+	DefaultTagPrefix      string `toml:"default_tag_prefix"`
+
+	Metrics []Metric `toml:"metric"`
+
+	client *http.Client
+}
+
+// ProxyTarget is a remote JMX url read by the proxy, and its credentials
+type ProxyTarget struct {
+	URL      string `toml:"url"`
+	Username string
+	Password string
+}
+
+var proxySampleConfig = `
+  # Url of the Jolokia proxy
+  url = "http://localhost:8080/jolokia"
+
+  # Credentials of the proxy
+  # username = ""
+  # password = ""
+
+  # Timeout of the requests, 5s by default
+  # response_timeout = "5s"
+
+  # TLS options of an https url
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Credentials of the targets without their own
+  # default_target_username = ""
+  # default_target_password = ""
+
+  # Remote JMX urls read by the proxy
+  [[inputs.jolokia2_proxy.target]]
+    url = "service:jmx:rmi:///jndi/rmi://kafka1:9999/jmxrmi"
+    # username = ""
+    # password = ""
+
+  # Defaults of the options of the metrics
+  # default_field_prefix = ""
+  # default_field_separator = "."
+  # default_tag_prefix = ""
+
+  # Metrics gathered from all the targets, see the jolokia2_agent input
+  [[inputs.jolokia2_proxy.metric]]
+    name = "kafka_topics"
+    mbean = "kafka.server:type=BrokerTopicMetrics,name=*"
+    paths = ["Count", "OneMinuteRate"]
+    tag_keys = ["name"]
+`
+
+func (j *Jolokia2Proxy) SampleConfig() string {
+	return proxySampleConfig
+}
+
+func (j *Jolokia2Proxy) Description() string {
+	return "Read JMX metrics from one or more JVMs through a Jolokia proxy"
+}
+
+// Init creates the HTTP client of the plugin and checks its metrics.
+func (j *Jolokia2Proxy) Init() error {
+	client, err := createClient(j.Username, j.Password, j.ResponseTimeout,
+		internal.TLSOptions{
+			SSLCA:              j.SSLCA,
+			SSLCert:            j.SSLCert,
+			SSLKey:             j.SSLKey,
+			InsecureSkipVerify: j.InsecureSkipVerify,
+		})
+	if err != nil {
+		return err
+	}
+	for i := range j.Metrics {
+		err := j.Metrics[i].check(j.DefaultFieldPrefix,
+			j.DefaultFieldSeparator, j.DefaultTagPrefix)
+		if err != nil {
+			return err
+		}
+	}
+	j.client = client
+	return nil
+}
+
+// Gather reads the metrics of all the targets in one request to the proxy,
+// tagged with the urls of the proxy and of their target.
+func (j *Jolokia2Proxy) Gather(acc telegraf.Accumulator) error {
+	if len(j.Metrics) == 0 || len(j.Targets) == 0 {
+		return nil
+	}
+
+	var requests []readRequest
+	for _, t := range j.Targets {
+		target := &proxyTarget{
+			URL:      t.URL,
+			User:     t.Username,
+			Password: t.Password,
+		}
+		if target.User == "" && target.Password == "" {
+			target.User = j.DefaultTargetUsername
+			target.Password = j.DefaultTargetPassword
+		}
+		for _, m := range j.Metrics {
+			r := m.request()
+			r.Target = target
+			requests = append(requests, r)
+		}
+	}
+
+	responses, err := read(j.client, j.URL, requests)
+	if err != nil {
+		return err
+	}
+
+	// The responses of each target follow the order of the metrics
+	var errs []string
+	n := len(j.Metrics)
+	for i, t := range j.Targets {
+		err := addResponses(acc, j.Metrics, responses[i*n:(i+1)*n],
+			map[string]string{
+				"jolokia_proxy_url": j.URL,
+				"jolokia_agent_url": t.URL,
+			})
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("jolokia target %s: %s", t.URL,
+				err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("jolokia2_proxy", func() telegraf.Input {
+		return &Jolokia2Proxy{}
+	})
+}
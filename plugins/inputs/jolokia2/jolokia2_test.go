@@ -0,0 +1,191 @@
+package jolokia2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jolokiaServer returns a fake Jolokia agent or proxy, answering the bulk
+// read requests with the attributes of their mbean in values. The value of a
+// single attribute read is the value of the attribute, as with Jolokia.
+func jolokiaServer(
+	t *testing.T,
+	values map[string]string,
+) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var requests []readRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&requests))
+			var responses []map[string]interface{}
+			for _, req := range requests {
+				var value interface{}
+				if err := json.Unmarshal([]byte(values[req.Mbean]), &value); err != nil {
+					responses = append(responses, map[string]interface{}{
+						"status": 404,
+						"error":  "javax.management.InstanceNotFoundException",
+					})
+					continue
+				}
+				attribute, ok := req.Attribute.(string)
+				if m := (&Metric{Mbean: req.Mbean}); ok && !m.isPattern() {
+					value = value.(map[string]interface{})[attribute]
+				}
+				responses = append(responses, map[string]interface{}{
+					"status": 200,
+					"value":  value,
+				})
+			}
+			require.NoError(t, json.NewEncoder(w).Encode(responses))
+		}))
+}
+
+var jolokiaValues = map[string]string{
+	"java.lang:type=Memory": `{
+		"HeapMemoryUsage": {"init": 100, "committed": 200, "max": 400, "used": 150},
+		"NonHeapMemoryUsage": {"init": 10, "committed": 20, "max": -1, "used": 15}
+	}`,
+	"java.lang:type=GarbageCollector,*": `{
+		"java.lang:name=G1 Young Generation,type=GarbageCollector": {
+			"CollectionTime": 30, "CollectionCount": 3
+		},
+		"java.lang:name=G1 Old Generation,type=GarbageCollector": {
+			"CollectionTime": 0, "CollectionCount": 0
+		}
+	}`,
+	"java.lang:type=Threading": `{"ThreadCount": 42, "DaemonThreadCount": 12}`,
+}
+
+var jolokiaMetrics = []Metric{
+	{
+		Name:  "jvm_memory",
+		Mbean: "java.lang:type=Memory",
+		Paths: []string{"HeapMemoryUsage/used", "NonHeapMemoryUsage"},
+	},
+	{
+		Name:    "jvm_garbage_collector",
+		Mbean:   "java.lang:type=GarbageCollector,*",
+		Paths:   []string{"CollectionTime", "CollectionCount"},
+		TagKeys: []string{"name"},
+	},
+	{
+		Name:      "jvm_threading",
+		Mbean:     "java.lang:type=Threading",
+		Paths:     []string{"ThreadCount"},
+		FieldName: "threads",
+	},
+	{
+		Name:  "kafka_topics",
+		Mbean: "kafka.server:type=BrokerTopicMetrics,name=*",
+	},
+}
+
+func TestJolokia2Agent(t *testing.T) {
+	ts := jolokiaServer(t, jolokiaValues)
+	defer ts.Close()
+
+	j := &Jolokia2Agent{
+		URLs:    []string{ts.URL},
+		Metrics: append([]Metric{}, jolokiaMetrics...),
+	}
+	require.NoError(t, j.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, j.Gather(&acc))
+
+	tags := map[string]string{"jolokia_agent_url": ts.URL}
+	acc.AssertContainsTaggedFields(t, "jvm_memory", map[string]interface{}{
+		"HeapMemoryUsage.used":         float64(150),
+		"NonHeapMemoryUsage.init":      float64(10),
+		"NonHeapMemoryUsage.committed": float64(20),
+		"NonHeapMemoryUsage.max":       float64(-1),
+		"NonHeapMemoryUsage.used":      float64(15),
+	}, tags)
+	acc.AssertContainsTaggedFields(t, "jvm_threading", map[string]interface{}{
+		"threads": float64(42),
+	}, tags)
+	acc.AssertContainsTaggedFields(t, "jvm_garbage_collector",
+		map[string]interface{}{
+			"CollectionTime":  float64(30),
+			"CollectionCount": float64(3),
+		}, map[string]string{
+			"jolokia_agent_url": ts.URL,
+			"name":              "G1 Young Generation",
+		})
+	acc.AssertContainsTaggedFields(t, "jvm_garbage_collector",
+		map[string]interface{}{
+			"CollectionTime":  float64(0),
+			"CollectionCount": float64(0),
+		}, map[string]string{
+			"jolokia_agent_url": ts.URL,
+			"name":              "G1 Old Generation",
+		})
+	// The MBeans not found are skipped
+	assert.False(t, acc.HasMeasurement("kafka_topics"))
+}
+
+func TestJolokia2Proxy(t *testing.T) {
+	ts := jolokiaServer(t, jolokiaValues)
+	defer ts.Close()
+
+	j := &Jolokia2Proxy{
+		URL: ts.URL,
+		Targets: []ProxyTarget{
+			{URL: "service:jmx:rmi:///jndi/rmi://host1:9999/jmxrmi"},
+			{URL: "service:jmx:rmi:///jndi/rmi://host2:9999/jmxrmi"},
+		},
+		DefaultFieldPrefix:    "jvm_",
+		DefaultFieldSeparator: "_",
+		Metrics: []Metric{{
+			Name:  "java_memory",
+			Mbean: "java.lang:type=Memory",
+			Paths: []string{"HeapMemoryUsage"},
+		}},
+	}
+	require.NoError(t, j.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, j.Gather(&acc))
+
+	for _, target := range j.Targets {
+		acc.AssertContainsTaggedFields(t, "java_memory", map[string]interface{}{
+			"jvm_HeapMemoryUsage_init":      float64(100),
+			"jvm_HeapMemoryUsage_committed": float64(200),
+			"jvm_HeapMemoryUsage_max":       float64(400),
+			"jvm_HeapMemoryUsage_used":      float64(150),
+		}, map[string]string{
+			"jolokia_proxy_url": ts.URL,
+			"jolokia_agent_url": target.URL,
+		})
+	}
+}
+
+func TestJolokia2Errors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `[{"status":500,"error":"java.lang.Exception"}]`)
+		}))
+	defer ts.Close()
+
+	j := &Jolokia2Agent{
+		URLs: []string{ts.URL, "http://127.0.0.1:1/jolokia"},
+		Metrics: []Metric{{
+			Name:  "jvm_memory",
+			Mbean: "java.lang:type=Memory",
+		}},
+	}
+	require.NoError(t, j.Init())
+	var acc testutil.Accumulator
+	err := j.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "java.lang.Exception")
+	assert.Contains(t, err.Error(), "127.0.0.1:1")
+
+	// The name and mbean of the metrics are required
+	j = &Jolokia2Agent{Metrics: []Metric{{Name: "jvm_memory"}}}
+	assert.Error(t, j.Init())
+}
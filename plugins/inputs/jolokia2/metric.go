@@ -0,0 +1,230 @@
+package jolokia2
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Metric selects the attributes of the MBeans gathered in a measurement
+type Metric struct {
+	// Name of the measurement
+	Name string
+	// Mbean is the object name of the MBeans, a pattern selecting several
+	// MBeans if it contains a wildcard, ie "java.lang:type=GarbageCollector,*"
+	Mbean string
+	// Paths are the attributes gathered, and their inner paths separated by
+	// slashes, ie "HeapMemoryUsage/used". All the attributes are gathered if
+	// none is set.
+	Paths []string
+	// FieldName is the name of the field of a single path, the path joined by
+	// the field separator by default
+	FieldName string `toml:"field_name"`
+	// FieldPrefix is prepended to the names of the fields, and FieldSeparator
+	// joins the attributes and keys of their values, "." by default
+	FieldPrefix    string `toml:"field_prefix"`
+	FieldSeparator string `toml:"field_separator"`
+	// TagKeys are the properties of the object names of the MBeans added as
+	// tags, their names prefixed by TagPrefix
+	TagPrefix string   `toml:"tag_prefix"`
+	TagKeys   []string `toml:"tag_keys"`
+}
+
+// check returns an error if a required option of the metric is missing, and
+// sets the default options not set
+func (m *Metric) check(prefix, separator, tagPrefix string) error {
+	if m.Name == "" || m.Mbean == "" {
+		return errors.New("name and mbean must be set for all the metrics")
+	}
+	if m.FieldPrefix == "" {
+		m.FieldPrefix = prefix
+	}
+	if m.FieldSeparator == "" {
+		m.FieldSeparator = separator
+	}
+	if m.FieldSeparator == "" {
+		m.FieldSeparator = "."
+	}
+	if m.TagPrefix == "" {
+		m.TagPrefix = tagPrefix
+	}
+	return nil
+}
+
+// attributes returns the attributes of the paths of the metric
+func (m *Metric) attributes() []string {
+	var attributes []string
+	seen := make(map[string]bool)
+	for _, path := range m.Paths {
+		attribute := strings.SplitN(path, "/", 2)[0]
+		if !seen[attribute] {
+			seen[attribute] = true
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes
+}
+
+// isPattern returns whether the mbean of the metric selects several MBeans
+func (m *Metric) isPattern() bool {
+	return strings.ContainsAny(m.Mbean, "*?")
+}
+
+// request returns the read request of the attributes of the metric. The inner
+// paths are selected from the values of the attributes, as Jolokia applies a
+// single path to all the attributes read.
+func (m *Metric) request() readRequest {
+	r := readRequest{Type: "read", Mbean: m.Mbean}
+	switch attributes := m.attributes(); len(attributes) {
+	case 0:
+	case 1:
+		r.Attribute = attributes[0]
+	default:
+		r.Attribute = attributes
+	}
+	return r
+}
+
+// add adds the points of the response of the request of the metric to the
+// accumulator, one per MBean read, with the tags given.
+func (m *Metric) add(
+	acc telegraf.Accumulator,
+	resp readResponse,
+	tags map[string]string,
+) {
+	values := map[string]interface{}{m.Mbean: resp.Value}
+	if m.isPattern() {
+		values, _ = resp.Value.(map[string]interface{})
+	}
+
+	for mbean, value := range values {
+		// The value of a single attribute read is the value of the attribute,
+		// and not a map of the attributes, except in the responses of patterns
+		if attributes := m.attributes(); len(attributes) == 1 &&
+			!m.isPattern() {
+			value = map[string]interface{}{attributes[0]: value}
+		}
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		fields := make(map[string]interface{})
+		if len(m.Paths) == 0 {
+			m.addFields(fields, "", attributes)
+		}
+		for _, path := range m.Paths {
+			keys := strings.Split(path, "/")
+			value, ok := lookup(attributes, keys)
+			if !ok {
+				continue
+			}
+			name := strings.Join(keys, m.FieldSeparator)
+			if m.FieldName != "" && len(m.Paths) == 1 {
+				name = m.FieldName
+			}
+			m.addFields(fields, name, value)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		mtags := make(map[string]string, len(tags)+len(m.TagKeys))
+		for k, v := range tags {
+			mtags[k] = v
+		}
+		properties := mbeanProperties(mbean)
+		for _, key := range m.TagKeys {
+			if v, ok := properties[key]; ok {
+				mtags[m.TagPrefix+key] = v
+			}
+		}
+		acc.AddFields(m.Name, fields, mtags)
+	}
+}
+
+// addFields adds the value to the fields, the values of maps flattened, their
+// keys joined to the name by the field separator
+func (m *Metric) addFields(
+	fields map[string]interface{},
+	name string,
+	value interface{},
+) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, inner := range v {
+			if name != "" {
+				k = name + m.FieldSeparator + k
+			}
+			m.addFields(fields, k, inner)
+		}
+	case float64, string, bool:
+		if name == "" {
+			name = "value"
+		}
+		fields[m.FieldPrefix+name] = v
+	}
+}
+
+// lookup returns the value at the path of keys in the attributes
+func lookup(attributes map[string]interface{}, keys []string) (interface{}, bool) {
+	var value interface{} = attributes
+	for _, key := range keys {
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = values[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// mbeanProperties returns the key properties of the object name of an MBean,
+// ie type=GarbageCollector and name=G1 Young Generation for
+// "java.lang:type=GarbageCollector,name=G1 Young Generation"
+func mbeanProperties(mbean string) map[string]string {
+	properties := make(map[string]string)
+	i := strings.Index(mbean, ":")
+	if i < 0 {
+		return properties
+	}
+	for _, property := range strings.Split(mbean[i+1:], ",") {
+		kv := strings.SplitN(property, "=", 2)
+		if len(kv) == 2 {
+			properties[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return properties
+}
+
+// addResponses adds the points of the responses of the requests of the
+// metrics, in the same order, with the tags given. It returns the errors of
+// the failed requests, except the ones of MBeans not found, which are not
+// registered on every server.
+func addResponses(
+	acc telegraf.Accumulator,
+	metrics []Metric,
+	responses []readResponse,
+	tags map[string]string,
+) error {
+	var errs []string
+	for i, resp := range responses {
+		switch resp.Status {
+		case http.StatusOK:
+			metrics[i].add(acc, resp, tags)
+		case http.StatusNotFound:
+		default:
+			errs = append(errs, fmt.Sprintf("error reading %s: %s",
+				metrics[i].Mbean, resp.Error))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package kafka_consumer
 
 import (
-	"log"
 	"strings"
 	"sync"
 
@@ -19,6 +18,7 @@ type Kafka struct {
 	Consumer       *consumergroup.ConsumerGroup
 	PointBuffer    int
 	Offset         string
+	Log            telegraf.Logger `toml:"-"`
 
 	sync.Mutex
 
@@ -68,7 +68,7 @@ func (k *Kafka) Start() error {
 	case "newest":
 		config.Offsets.Initial = sarama.OffsetNewest
 	default:
-		log.Printf("WARNING: Kafka consumer invalid offset '%s', using 'oldest'\n",
+		k.Log.Warnf("Invalid offset '%s', using 'oldest'",
 			k.Offset)
 		config.Offsets.Initial = sarama.OffsetOldest
 	}
@@ -97,7 +97,7 @@ func (k *Kafka) Start() error {
 
 	// Start the kafka message reader
 	go k.parser()
-	log.Printf("Started the kafka consumer service, peers: %v, topics: %v\n",
+	k.Log.Infof("Started the kafka consumer service, peers: %v, topics: %v",
 		k.ZookeeperPeers, k.Topics)
 	return nil
 }
@@ -110,11 +110,11 @@ func (k *Kafka) parser() {
 		case <-k.done:
 			return
 		case err := <-k.errs:
-			log.Printf("Kafka Consumer Error: %s\n", err.Error())
+			k.Log.Errorf("Consumer error: %s", err)
 		case msg := <-k.in:
 			metrics, err := telegraf.ParseMetrics(msg.Value)
 			if err != nil {
-				log.Printf("Could not parse kafka message: %s, error: %s",
+				k.Log.Errorf("Could not parse kafka message: %s, error: %s",
 					string(msg.Value), err.Error())
 			}
 
@@ -123,7 +123,7 @@ func (k *Kafka) parser() {
 				case k.metricC <- metric:
 					continue
 				default:
-					log.Printf("Kafka Consumer buffer is full, dropping a metric." +
+					k.Log.Warn("Buffer is full, dropping a metric." +
 						" You may want to increase the point_buffer setting")
 				}
 			}
@@ -144,7 +144,7 @@ func (k *Kafka) Stop() {
 	defer k.Unlock()
 	close(k.done)
 	if err := k.Consumer.Close(); err != nil {
-		log.Printf("Error closing kafka consumer: %s\n", err.Error())
+		k.Log.Errorf("Error closing kafka consumer: %s", err)
 	}
 }
 
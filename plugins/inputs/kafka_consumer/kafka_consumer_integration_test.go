@@ -39,6 +39,7 @@ func TestReadsMetricsFromKafka(t *testing.T) {
 		ZookeeperPeers: zkPeers,
 		PointBuffer:    100000,
 		Offset:         "oldest",
+		Log:            testutil.Logger{},
 	}
 	if err := k.Start(); err != nil {
 		t.Fatal(err.Error())
@@ -25,6 +25,7 @@ func NewTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		ZookeeperPeers:  []string{"localhost:2181"},
 		PointBuffer:     pointBuffer,
 		Offset:          "oldest",
+		Log:             testutil.Logger{},
 		in:              in,
 		doNotCommitMsgs: true,
 		errs:            make(chan *sarama.ConsumerError, pointBuffer),
@@ -0,0 +1,373 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Kubernetes reads the resource usage of the node, pods, containers and
+// volumes of a kubelet from its summary API
+type Kubernetes struct {
+	URL string
+
+	// Token of the requests, either read from the bearer_token file, on every
+	// request as the service account tokens are rotated, or given as is
+	BearerToken       string `toml:"bearer_token"`
+	BearerTokenString string `toml:"bearer_token_string"`
+
+	// Labels of the pods added as tags, with * globs
+	LabelInclude []string `toml:"label_include"`
+	LabelExclude []string `toml:"label_exclude"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	client *http.Client
+}
+
+var sampleConfig = `
+  # URL of the kubelet, ie the read-only port, or "https://${NODE_IP}:10250"
+  # for the authenticated port
+  url = "http://127.0.0.1:10255"
+
+  # Token of the requests, read from a file, ie the token of the service
+  # account of the pod telegraf runs in, or given as is
+  # bearer_token = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+  # bearer_token_string = ""
+
+  # Labels of the pods added as tags of their points, with * globs, none by
+  # default, ie ["app", "app.kubernetes.io/*"]
+  # label_include = []
+  # label_exclude = []
+
+  # Timeout of the requests, 5s by default
+  # timeout = "5s"
+
+  # TLS options of the https url
+  # ssl_ca = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+`
+
+func (k *Kubernetes) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Kubernetes) Description() string {
+	return "Read the resource usage of the node, pods and containers of a kubelet"
+}
+
+// Init creates the HTTP client of the plugin
+func (k *Kubernetes) Init() error {
+	if k.URL == "" {
+		return fmt.Errorf("no kubelet url")
+	}
+	k.URL = strings.TrimSuffix(k.URL, "/")
+
+	config := httpconfig.Config{
+		Timeout: k.Timeout.Duration,
+		TLS: internal.TLSOptions{
+			SSLCA:              k.SSLCA,
+			SSLCert:            k.SSLCert,
+			SSLKey:             k.SSLKey,
+			InsecureSkipVerify: k.InsecureSkipVerify,
+		},
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	client, err := config.CreateClient()
+	if err != nil {
+		return err
+	}
+	k.client = client
+	return nil
+}
+
+func (k *Kubernetes) Gather(acc telegraf.Accumulator) error {
+	var summary summary
+	if err := k.get("/stats/summary", &summary); err != nil {
+		return err
+	}
+
+	// The labels of the pods are listed only if some are added as tags
+	var labels map[string]map[string]string
+	if len(k.LabelInclude) > 0 {
+		var pods podList
+		if err := k.get("/pods", &pods); err != nil {
+			return err
+		}
+		labels = make(map[string]map[string]string)
+		for _, pod := range pods.Items {
+			tags := make(map[string]string)
+			for name, value := range pod.Metadata.Labels {
+				if k.includeLabel(name) {
+					tags[name] = value
+				}
+			}
+			labels[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = tags
+		}
+	}
+
+	k.addNode(acc, summary.Node)
+	for _, pod := range summary.Pods {
+		k.addPod(acc, summary.Node.NodeName, pod,
+			labels[pod.PodRef.Namespace+"/"+pod.PodRef.Name])
+	}
+	return nil
+}
+
+// includeLabel returns whether the label is added as a tag
+func (k *Kubernetes) includeLabel(name string) bool {
+	for _, pattern := range k.LabelExclude {
+		if internal.Glob(pattern, name) {
+			return false
+		}
+	}
+	for _, pattern := range k.LabelInclude {
+		if internal.Glob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// get decodes the json response of the path of the kubelet
+func (k *Kubernetes) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", k.URL+path, nil)
+	if err != nil {
+		return err
+	}
+	token := k.BearerTokenString
+	if k.BearerToken != "" {
+		b, err := ioutil.ReadFile(k.BearerToken)
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding the response of %s: %s", req.URL,
+			err)
+	}
+	return nil
+}
+
+// summary is the part of the response of /stats/summary of the kubelet
+// gathered
+type summary struct {
+	Node nodeStats  `json:"node"`
+	Pods []podStats `json:"pods"`
+}
+
+type nodeStats struct {
+	NodeName string       `json:"nodeName"`
+	CPU      cpuStats     `json:"cpu"`
+	Memory   memoryStats  `json:"memory"`
+	Network  networkStats `json:"network"`
+	Fs       fsStats      `json:"fs"`
+	Runtime  struct {
+		ImageFs fsStats `json:"imageFs"`
+	} `json:"runtime"`
+}
+
+type podStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	Containers []containerStats `json:"containers"`
+	Network    networkStats     `json:"network"`
+	Volumes    []volumeStats    `json:"volume"`
+}
+
+type containerStats struct {
+	Name   string      `json:"name"`
+	CPU    cpuStats    `json:"cpu"`
+	Memory memoryStats `json:"memory"`
+	Rootfs fsStats     `json:"rootfs"`
+	Logs   fsStats     `json:"logs"`
+}
+
+type cpuStats struct {
+	Time                 time.Time `json:"time"`
+	UsageNanoCores       int64     `json:"usageNanoCores"`
+	UsageCoreNanoSeconds int64     `json:"usageCoreNanoSeconds"`
+}
+
+type memoryStats struct {
+	Time            time.Time `json:"time"`
+	AvailableBytes  int64     `json:"availableBytes"`
+	UsageBytes      int64     `json:"usageBytes"`
+	WorkingSetBytes int64     `json:"workingSetBytes"`
+	RSSBytes        int64     `json:"rssBytes"`
+	PageFaults      int64     `json:"pageFaults"`
+	MajorPageFaults int64     `json:"majorPageFaults"`
+}
+
+type networkStats struct {
+	Time     time.Time `json:"time"`
+	RxBytes  int64     `json:"rxBytes"`
+	RxErrors int64     `json:"rxErrors"`
+	TxBytes  int64     `json:"txBytes"`
+	TxErrors int64     `json:"txErrors"`
+}
+
+type fsStats struct {
+	AvailableBytes int64 `json:"availableBytes"`
+	CapacityBytes  int64 `json:"capacityBytes"`
+	UsedBytes      int64 `json:"usedBytes"`
+}
+
+type volumeStats struct {
+	Name           string `json:"name"`
+	AvailableBytes int64  `json:"availableBytes"`
+	CapacityBytes  int64  `json:"capacityBytes"`
+	UsedBytes      int64  `json:"usedBytes"`
+}
+
+// podList is the part of the response of /pods of the kubelet used for the
+// labels of the pods
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// timestamp returns the time of the stats, now if missing
+func timestamp(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// addNode adds the kubernetes_node point of the node
+func (k *Kubernetes) addNode(acc telegraf.Accumulator, node nodeStats) {
+	fields := map[string]interface{}{
+		"cpu_usage_nanocores":              node.CPU.UsageNanoCores,
+		"cpu_usage_core_nanoseconds":       node.CPU.UsageCoreNanoSeconds,
+		"memory_available_bytes":           node.Memory.AvailableBytes,
+		"memory_usage_bytes":               node.Memory.UsageBytes,
+		"memory_working_set_bytes":         node.Memory.WorkingSetBytes,
+		"memory_rss_bytes":                 node.Memory.RSSBytes,
+		"memory_page_faults":               node.Memory.PageFaults,
+		"memory_major_page_faults":         node.Memory.MajorPageFaults,
+		"network_rx_bytes":                 node.Network.RxBytes,
+		"network_rx_errors":                node.Network.RxErrors,
+		"network_tx_bytes":                 node.Network.TxBytes,
+		"network_tx_errors":                node.Network.TxErrors,
+		"fs_available_bytes":               node.Fs.AvailableBytes,
+		"fs_capacity_bytes":                node.Fs.CapacityBytes,
+		"fs_used_bytes":                    node.Fs.UsedBytes,
+		"runtime_image_fs_available_bytes": node.Runtime.ImageFs.AvailableBytes,
+		"runtime_image_fs_capacity_bytes":  node.Runtime.ImageFs.CapacityBytes,
+		"runtime_image_fs_used_bytes":      node.Runtime.ImageFs.UsedBytes,
+	}
+	tags := map[string]string{"node_name": node.NodeName}
+	acc.AddFields("kubernetes_node", fields, tags, timestamp(node.CPU.Time))
+}
+
+// addPod adds the kubernetes_pod_container points of the containers of the
+// pod, and its kubernetes_pod_volume and kubernetes_pod_network points, the
+// labels being added to the tags of the points
+func (k *Kubernetes) addPod(
+	acc telegraf.Accumulator,
+	nodeName string,
+	pod podStats,
+	labels map[string]string,
+) {
+	podTags := func() map[string]string {
+		tags := map[string]string{
+			"node_name": nodeName,
+			"namespace": pod.PodRef.Namespace,
+			"pod_name":  pod.PodRef.Name,
+		}
+		for name, value := range labels {
+			if _, ok := tags[name]; !ok {
+				tags[name] = value
+			}
+		}
+		return tags
+	}
+
+	for _, c := range pod.Containers {
+		fields := map[string]interface{}{
+			"cpu_usage_nanocores":        c.CPU.UsageNanoCores,
+			"cpu_usage_core_nanoseconds": c.CPU.UsageCoreNanoSeconds,
+			"memory_usage_bytes":         c.Memory.UsageBytes,
+			"memory_working_set_bytes":   c.Memory.WorkingSetBytes,
+			"memory_rss_bytes":           c.Memory.RSSBytes,
+			"memory_page_faults":         c.Memory.PageFaults,
+			"memory_major_page_faults":   c.Memory.MajorPageFaults,
+			"rootfs_available_bytes":     c.Rootfs.AvailableBytes,
+			"rootfs_capacity_bytes":      c.Rootfs.CapacityBytes,
+			"rootfs_used_bytes":          c.Rootfs.UsedBytes,
+			"logsfs_available_bytes":     c.Logs.AvailableBytes,
+			"logsfs_capacity_bytes":      c.Logs.CapacityBytes,
+			"logsfs_used_bytes":          c.Logs.UsedBytes,
+		}
+		tags := podTags()
+		tags["container_name"] = c.Name
+		acc.AddFields("kubernetes_pod_container", fields, tags,
+			timestamp(c.CPU.Time))
+	}
+
+	for _, v := range pod.Volumes {
+		fields := map[string]interface{}{
+			"available_bytes": v.AvailableBytes,
+			"capacity_bytes":  v.CapacityBytes,
+			"used_bytes":      v.UsedBytes,
+		}
+		tags := podTags()
+		tags["volume_name"] = v.Name
+		acc.AddFields("kubernetes_pod_volume", fields, tags, time.Now())
+	}
+
+	fields := map[string]interface{}{
+		"rx_bytes":  pod.Network.RxBytes,
+		"rx_errors": pod.Network.RxErrors,
+		"tx_bytes":  pod.Network.TxBytes,
+		"tx_errors": pod.Network.TxErrors,
+	}
+	acc.AddFields("kubernetes_pod_network", fields, podTags(),
+		timestamp(pod.Network.Time))
+}
+
+func init() {
+	inputs.Add("kubernetes", func() telegraf.Input {
+		return &Kubernetes{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const summaryResponse = `{
+  "node": {
+    "nodeName": "node1",
+    "cpu": {
+      "time": "2018-09-28T14:23:22Z",
+      "usageNanoCores": 56652446,
+      "usageCoreNanoSeconds": 101437561712262
+    },
+    "memory": {
+      "time": "2018-09-28T14:23:22Z",
+      "availableBytes": 6512510976,
+      "usageBytes": 9847607296,
+      "workingSetBytes": 1363390464,
+      "rssBytes": 470895616,
+      "pageFaults": 351742,
+      "majorPageFaults": 1765
+    },
+    "network": {
+      "time": "2018-09-28T14:23:22Z",
+      "rxBytes": 20959624,
+      "rxErrors": 0,
+      "txBytes": 11345968,
+      "txErrors": 0
+    },
+    "fs": {
+      "availableBytes": 84379979776,
+      "capacityBytes": 105553100800,
+      "usedBytes": 16754286592
+    },
+    "runtime": {
+      "imageFs": {
+        "availableBytes": 84379979776,
+        "capacityBytes": 105553100800,
+        "usedBytes": 5809947838
+      }
+    }
+  },
+  "pods": [
+    {
+      "podRef": {"name": "web-7d4b", "namespace": "prod", "uid": "c3c1"},
+      "containers": [
+        {
+          "name": "nginx",
+          "cpu": {
+            "time": "2018-09-28T14:23:20Z",
+            "usageNanoCores": 7126,
+            "usageCoreNanoSeconds": 5380926
+          },
+          "memory": {
+            "time": "2018-09-28T14:23:20Z",
+            "usageBytes": 20557824,
+            "workingSetBytes": 2498560,
+            "rssBytes": 1257472,
+            "pageFaults": 2412,
+            "majorPageFaults": 7
+          },
+          "rootfs": {
+            "availableBytes": 84379979776,
+            "capacityBytes": 105553100800,
+            "usedBytes": 57344
+          },
+          "logs": {
+            "availableBytes": 84379979776,
+            "capacityBytes": 105553100800,
+            "usedBytes": 24576
+          }
+        }
+      ],
+      "network": {
+        "time": "2018-09-28T14:23:20Z",
+        "rxBytes": 2436,
+        "rxErrors": 0,
+        "txBytes": 1024,
+        "txErrors": 1
+      },
+      "volume": [
+        {
+          "name": "default-token-xvtqp",
+          "availableBytes": 4152770560,
+          "capacityBytes": 4152782848,
+          "usedBytes": 12288
+        }
+      ]
+    }
+  ]
+}`
+
+const podsResponse = `{
+  "kind": "PodList",
+  "items": [
+    {
+      "metadata": {
+        "name": "web-7d4b",
+        "namespace": "prod",
+        "labels": {"app": "web", "pod-template-hash": "7d4b", "tier": "front"}
+      }
+    }
+  ]
+}`
+
+func TestGather(t *testing.T) {
+	var authorization string
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			authorization = r.Header.Get("Authorization")
+			switch r.URL.Path {
+			case "/stats/summary":
+				fmt.Fprint(w, summaryResponse)
+			case "/pods":
+				fmt.Fprint(w, podsResponse)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer ts.Close()
+
+	k := &Kubernetes{
+		URL:               ts.URL + "/",
+		BearerTokenString: "secret",
+		LabelInclude:      []string{"app", "t*"},
+		LabelExclude:      []string{"tier"},
+	}
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Gather(&acc))
+	assert.Equal(t, "Bearer secret", authorization)
+	assert.Equal(t, 2, requests)
+
+	acc.AssertContainsTaggedFields(t, "kubernetes_node",
+		map[string]interface{}{
+			"cpu_usage_nanocores":              int64(56652446),
+			"cpu_usage_core_nanoseconds":       int64(101437561712262),
+			"memory_available_bytes":           int64(6512510976),
+			"memory_usage_bytes":               int64(9847607296),
+			"memory_working_set_bytes":         int64(1363390464),
+			"memory_rss_bytes":                 int64(470895616),
+			"memory_page_faults":               int64(351742),
+			"memory_major_page_faults":         int64(1765),
+			"network_rx_bytes":                 int64(20959624),
+			"network_rx_errors":                int64(0),
+			"network_tx_bytes":                 int64(11345968),
+			"network_tx_errors":                int64(0),
+			"fs_available_bytes":               int64(84379979776),
+			"fs_capacity_bytes":                int64(105553100800),
+			"fs_used_bytes":                    int64(16754286592),
+			"runtime_image_fs_available_bytes": int64(84379979776),
+			"runtime_image_fs_capacity_bytes":  int64(105553100800),
+			"runtime_image_fs_used_bytes":      int64(5809947838),
+		},
+		map[string]string{"node_name": "node1"})
+
+	podTags := func(tags map[string]string) map[string]string {
+		tags["node_name"] = "node1"
+		tags["namespace"] = "prod"
+		tags["pod_name"] = "web-7d4b"
+		tags["app"] = "web"
+		return tags
+	}
+	acc.AssertContainsTaggedFields(t, "kubernetes_pod_container",
+		map[string]interface{}{
+			"cpu_usage_nanocores":        int64(7126),
+			"cpu_usage_core_nanoseconds": int64(5380926),
+			"memory_usage_bytes":         int64(20557824),
+			"memory_working_set_bytes":   int64(2498560),
+			"memory_rss_bytes":           int64(1257472),
+			"memory_page_faults":         int64(2412),
+			"memory_major_page_faults":   int64(7),
+			"rootfs_available_bytes":     int64(84379979776),
+			"rootfs_capacity_bytes":      int64(105553100800),
+			"rootfs_used_bytes":          int64(57344),
+			"logsfs_available_bytes":     int64(84379979776),
+			"logsfs_capacity_bytes":      int64(105553100800),
+			"logsfs_used_bytes":          int64(24576),
+		},
+		podTags(map[string]string{"container_name": "nginx"}))
+	acc.AssertContainsTaggedFields(t, "kubernetes_pod_volume",
+		map[string]interface{}{
+			"available_bytes": int64(4152770560),
+			"capacity_bytes":  int64(4152782848),
+			"used_bytes":      int64(12288),
+		},
+		podTags(map[string]string{"volume_name": "default-token-xvtqp"}))
+	acc.AssertContainsTaggedFields(t, "kubernetes_pod_network",
+		map[string]interface{}{
+			"rx_bytes":  int64(2436),
+			"rx_errors": int64(0),
+			"tx_bytes":  int64(1024),
+			"tx_errors": int64(1),
+		},
+		podTags(map[string]string{}))
+	assert.Equal(t, 4, len(acc.Metrics))
+
+	for _, p := range acc.Metrics {
+		if p.Measurement == "kubernetes_pod_container" {
+			assert.Equal(t,
+				time.Date(2018, 9, 28, 14, 23, 20, 0, time.UTC), p.Time.UTC())
+		}
+	}
+}
+
+func TestGatherBearerTokenFile(t *testing.T) {
+	var authorization string
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			paths = append(paths, r.URL.Path)
+			fmt.Fprint(w, `{"node": {"nodeName": "node1"}}`)
+		}))
+	defer ts.Close()
+
+	f, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("rotated\n")
+	f.Close()
+
+	k := &Kubernetes{URL: ts.URL, BearerToken: f.Name()}
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, k.Gather(&acc))
+	assert.Equal(t, "Bearer rotated", authorization)
+	// The pods are not listed without labels added as tags
+	assert.Equal(t, []string{"/stats/summary"}, paths)
+	assert.True(t, acc.HasMeasurement("kubernetes_node"))
+}
+
+func TestGatherError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+	defer ts.Close()
+
+	k := &Kubernetes{URL: ts.URL}
+	require.NoError(t, k.Init())
+	var acc testutil.Accumulator
+	err := k.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	assert.Error(t, (&Kubernetes{}).Init())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,6 +17,8 @@ import (
 type Memcached struct {
 	Servers     []string
 	UnixSockets []string
+	// Gather the stats of the slab classes, from stats slabs and stats items
+	GatherSlabs bool `toml:"gather_slabs"`
 }
 
 var sampleConfig = `
@@ -25,6 +28,10 @@ var sampleConfig = `
   # If no servers are specified, then localhost is used as the host.
   servers = ["localhost:11211"]
   # unix_sockets = ["/var/run/memcached.sock"]
+
+  # Gather the stats of each slab class, from stats slabs and stats items, in
+  # the memcached_slabs measurement tagged with the slab class
+  gather_slabs = false
 `
 
 var defaultTimeout = 5 * time.Second
@@ -52,13 +59,27 @@ var sendMetrics = []string{
 	"decr_misses",
 	"cas_hits",
 	"cas_misses",
+	"cas_badval",
+	"touch_hits",
+	"touch_misses",
+	"cmd_flush",
+	"cmd_touch",
 	"evictions",
+	"reclaimed",
+	"expired_unfetched",
+	"evicted_unfetched",
 	"bytes_read",
 	"bytes_written",
 	"threads",
 	"conn_yields",
 }
 
+// The stats of stats slabs which are not per slab class
+var sendSlabsMetrics = []string{
+	"active_slabs",
+	"total_malloced",
+}
+
 // SampleConfig returns sample configuration message
 func (m *Memcached) SampleConfig() string {
 	return sampleConfig
@@ -96,8 +117,9 @@ func (m *Memcached) gatherServer(
 	acc telegraf.Accumulator,
 ) error {
 	var conn net.Conn
+	var err error
 	if unix {
-		conn, err := net.DialTimeout("unix", address, defaultTimeout)
+		conn, err = net.DialTimeout("unix", address, defaultTimeout)
 		if err != nil {
 			return err
 		}
@@ -121,15 +143,7 @@ func (m *Memcached) gatherServer(
 	// Read and write buffer
 	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
 
-	// Send command
-	if _, err := fmt.Fprint(rw, "stats\r\n"); err != nil {
-		return err
-	}
-	if err := rw.Flush(); err != nil {
-		return err
-	}
-
-	values, err := parseResponse(rw.Reader)
+	values, err := stats(rw, "stats")
 	if err != nil {
 		return err
 	}
@@ -141,18 +155,104 @@ func (m *Memcached) gatherServer(
 	fields := make(map[string]interface{})
 	for _, key := range sendMetrics {
 		if value, ok := values[key]; ok {
-			// Mostly it is the number
-			if iValue, errParse := strconv.ParseInt(value, 10, 64); errParse == nil {
-				fields[key] = iValue
-			} else {
-				fields[key] = value
+			fields[key] = fieldValue(value)
+		}
+	}
+
+	// Ratios of the gets hitting and missing the cache, 0 without gets
+	hits, _ := strconv.ParseInt(values["get_hits"], 10, 64)
+	misses, _ := strconv.ParseInt(values["get_misses"], 10, 64)
+	fields["get_hit_ratio"] = float64(0)
+	fields["get_miss_ratio"] = float64(0)
+	if hits+misses > 0 {
+		fields["get_hit_ratio"] = float64(hits) / float64(hits+misses)
+		fields["get_miss_ratio"] = float64(misses) / float64(hits+misses)
+	}
+
+	if m.GatherSlabs {
+		slabs, err := stats(rw, "stats slabs")
+		if err != nil {
+			return err
+		}
+		items, err := stats(rw, "stats items")
+		if err != nil {
+			return err
+		}
+		for _, key := range sendSlabsMetrics {
+			if value, ok := slabs[key]; ok {
+				fields[key] = fieldValue(value)
 			}
 		}
+		gatherSlabs(slabs, items, acc, tags)
 	}
+
 	acc.AddFields("memcached", fields, tags)
 	return nil
 }
 
+// stats sends a stats command and returns the values of the response
+func stats(rw *bufio.ReadWriter, command string) (map[string]string, error) {
+	if _, err := fmt.Fprint(rw, command+"\r\n"); err != nil {
+		return nil, err
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	return parseResponse(rw.Reader)
+}
+
+// gatherSlabs adds a memcached_slabs metric per slab class, tagged with the
+// class id, from the "<class>:<stat>" values of stats slabs and the
+// "items:<class>:<stat>" values of stats items. The number of items of the
+// class is the items field.
+func gatherSlabs(
+	slabs map[string]string,
+	items map[string]string,
+	acc telegraf.Accumulator,
+	tags map[string]string,
+) {
+	classes := make(map[string]map[string]interface{})
+	add := func(class string, key string, value string) {
+		if classes[class] == nil {
+			classes[class] = make(map[string]interface{})
+		}
+		classes[class][key] = fieldValue(value)
+	}
+
+	for key, value := range slabs {
+		parts := strings.SplitN(key, ":", 2)
+		if len(parts) == 2 {
+			add(parts[0], parts[1], value)
+		}
+	}
+	for key, value := range items {
+		parts := strings.SplitN(key, ":", 3)
+		if len(parts) != 3 || parts[0] != "items" {
+			continue
+		}
+		if parts[2] == "number" {
+			parts[2] = "items"
+		}
+		add(parts[1], parts[2], value)
+	}
+
+	for class, fields := range classes {
+		slabTags := map[string]string{"slab": class}
+		for k, v := range tags {
+			slabTags[k] = v
+		}
+		acc.AddFields("memcached_slabs", fields, slabTags)
+	}
+}
+
+// fieldValue returns the value of a stat, mostly a number
+func fieldValue(value string) interface{} {
+	if iValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return iValue
+	}
+	return value
+}
+
 func parseResponse(r *bufio.Reader) (map[string]string, error) {
 	values := make(map[string]string)
 
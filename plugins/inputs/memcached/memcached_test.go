@@ -2,6 +2,10 @@ package memcached
 
 import (
 	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -108,6 +112,76 @@ func TestMemcachedParseMetrics(t *testing.T) {
 	}
 }
 
+func TestMemcachedGatherSlabs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memcached")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	sock := filepath.Join(dir, "memcached.sock")
+
+	// Serves the responses of the stats commands over a unix socket
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		responses := map[string]string{
+			"stats":       "STAT get_hits 3\r\nSTAT get_misses 1\r\nSTAT evictions 2\r\nEND\r\n",
+			"stats slabs": memcachedSlabs,
+			"stats items": memcachedItems,
+		}
+		rdr := bufio.NewReader(c)
+		for {
+			line, err := rdr.ReadString('\n')
+			if err != nil {
+				return
+			}
+			c.Write([]byte(responses[strings.TrimSpace(line)]))
+		}
+	}()
+
+	m := &Memcached{
+		UnixSockets: []string{sock},
+		GatherSlabs: true,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+
+	tags := map[string]string{"server": sock}
+	acc.AssertContainsTaggedFields(t, "memcached", map[string]interface{}{
+		"get_hits":       int64(3),
+		"get_misses":     int64(1),
+		"evictions":      int64(2),
+		"get_hit_ratio":  float64(0.75),
+		"get_miss_ratio": float64(0.25),
+		"active_slabs":   int64(1),
+		"total_malloced": int64(1048512),
+	}, tags)
+	acc.AssertContainsTaggedFields(t, "memcached_slabs", map[string]interface{}{
+		"chunk_size":  int64(96),
+		"used_chunks": int64(2),
+		"get_hits":    int64(3),
+		"items":       int64(2),
+		"age":         int64(36),
+		"evicted":     int64(1),
+	}, map[string]string{"server": sock, "slab": "1"})
+}
+
+var memcachedSlabs = "STAT 1:chunk_size 96\r\n" +
+	"STAT 1:used_chunks 2\r\n" +
+	"STAT 1:get_hits 3\r\n" +
+	"STAT active_slabs 1\r\n" +
+	"STAT total_malloced 1048512\r\n" +
+	"END\r\n"
+
+var memcachedItems = "STAT items:1:number 2\r\n" +
+	"STAT items:1:age 36\r\n" +
+	"STAT items:1:evicted 1\r\n" +
+	"END\r\n"
+
 var memcachedStats = `STAT pid 23235
 STAT uptime 194
 STAT time 1449174679
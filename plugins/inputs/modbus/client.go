@@ -0,0 +1,219 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The function codes of the reads
+const (
+	readCoils            = 0x01
+	readDiscreteInputs   = 0x02
+	readHoldingRegisters = 0x03
+	readInputRegisters   = 0x04
+)
+
+// exceptions are the exception codes of the responses
+var exceptions = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0a: "gateway path unavailable",
+	0x0b: "gateway target device failed to respond",
+}
+
+// transport sends the request PDUs to the slave, the function code followed
+// by its data, and returns the data of the response PDUs, without function
+// code
+type transport interface {
+	send(slaveID byte, pdu []byte) ([]byte, error)
+	Close() error
+}
+
+// checkResponse returns the data of the response PDU to the function,
+// or the error of its exception
+func checkResponse(function byte, pdu []byte) ([]byte, error) {
+	if len(pdu) < 2 {
+		return nil, errors.New("short response")
+	}
+	if pdu[0] == function|0x80 {
+		if msg, ok := exceptions[pdu[1]]; ok {
+			return nil, fmt.Errorf("exception %d: %s", pdu[1], msg)
+		}
+		return nil, fmt.Errorf("exception %d", pdu[1])
+	}
+	if pdu[0] != function {
+		return nil, fmt.Errorf("response to function %d, expected %d", pdu[0],
+			function)
+	}
+	return pdu[1:], nil
+}
+
+// tcpTransport is Modbus TCP, the PDUs being prefixed by the MBAP header of
+// the transaction id, protocol id 0, length and unit id
+type tcpTransport struct {
+	conn        net.Conn
+	timeout     time.Duration
+	transaction uint32
+}
+
+func (t *tcpTransport) send(slaveID byte, pdu []byte) ([]byte, error) {
+	id := uint16(atomic.AddUint32(&t.transaction, 1))
+	adu := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(adu[0:], id)
+	binary.BigEndian.PutUint16(adu[4:], uint16(1+len(pdu)))
+	adu[6] = slaveID
+	copy(adu[7:], pdu)
+
+	t.conn.SetDeadline(time.Now().Add(t.timeout))
+	if _, err := t.conn.Write(adu); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(t.conn, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 254 {
+		return nil, fmt.Errorf("invalid length %d of the response", length)
+	}
+	resp := make([]byte, length-1)
+	if _, err := io.ReadFull(t.conn, resp); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint16(header[0:]) != id {
+		return nil, fmt.Errorf("response to transaction %d, expected %d",
+			binary.BigEndian.Uint16(header[0:]), id)
+	}
+	if header[6] != slaveID {
+		return nil, fmt.Errorf("response of slave %d, expected %d", header[6],
+			slaveID)
+	}
+	return resp, nil
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
+
+// rtuTransport is Modbus RTU, over a serial port or a TCP connection, the
+// PDUs being prefixed by the slave id and followed by their CRC
+type rtuTransport struct {
+	conn io.ReadWriteCloser
+	// deadline sets the timeout of the request on the connections
+	// supporting it, the serial ports timing out on their own
+	deadline func() error
+}
+
+// crc16 is the CRC-16/MODBUS of the frame
+func crc16(data []byte) uint16 {
+	crc := uint16(0xffff)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xa001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+func (t *rtuTransport) send(slaveID byte, pdu []byte) ([]byte, error) {
+	adu := make([]byte, 1+len(pdu)+2)
+	adu[0] = slaveID
+	copy(adu[1:], pdu)
+	binary.LittleEndian.PutUint16(adu[len(adu)-2:], crc16(adu[:len(adu)-2]))
+
+	if t.deadline != nil {
+		if err := t.deadline(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := t.conn.Write(adu); err != nil {
+		return nil, err
+	}
+
+	// The length of the response depends on its function, the exceptions
+	// having a code, and the reads a byte count
+	frame := make([]byte, 3, 256)
+	if _, err := io.ReadFull(t.conn, frame); err != nil {
+		return nil, err
+	}
+	remaining := 2
+	if frame[1]&0x80 == 0 {
+		remaining += int(frame[2])
+	}
+	frame = frame[:3+remaining]
+	if _, err := io.ReadFull(t.conn, frame[3:]); err != nil {
+		return nil, err
+	}
+	n := len(frame) - 2
+	if crc16(frame[:n]) != binary.LittleEndian.Uint16(frame[n:]) {
+		return nil, errors.New("invalid CRC of the response")
+	}
+	if frame[0] != slaveID {
+		return nil, fmt.Errorf("response of slave %d, expected %d", frame[0],
+			slaveID)
+	}
+	return frame[1:n], nil
+}
+
+func (t *rtuTransport) Close() error {
+	return t.conn.Close()
+}
+
+// connect opens the transport of the controller, tcp://host:port with the
+// TCP or RTUoverTCP transmission modes, or file:///dev/ttyUSB0 for a serial
+// port in RTU
+func (m *Modbus) connect() (transport, error) {
+	u, err := url.Parse(m.Controller)
+	if err != nil {
+		return nil, err
+	}
+	mode := strings.ToUpper(m.TransmissionMode)
+	switch u.Scheme {
+	case "tcp":
+		conn, err := net.DialTimeout("tcp", u.Host, m.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		switch mode {
+		case "", "TCP":
+			return &tcpTransport{conn: conn, timeout: m.Timeout.Duration}, nil
+		case "RTUOVERTCP":
+			return &rtuTransport{conn: conn, deadline: func() error {
+				return conn.SetDeadline(time.Now().Add(m.Timeout.Duration))
+			}}, nil
+		}
+		conn.Close()
+	case "file":
+		switch mode {
+		case "", "RTU":
+			port, err := openSerial(u.Path, m.BaudRate, m.DataBits, m.Parity,
+				m.StopBits, m.Timeout.Duration)
+			if err != nil {
+				return nil, err
+			}
+			return &rtuTransport{conn: port}, nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid controller %q, expected tcp:// or "+
+			"file://", m.Controller)
+	}
+	return nil, fmt.Errorf("invalid transmission_mode %q for %s",
+		m.TransmissionMode, m.Controller)
+}
@@ -0,0 +1,388 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Modbus reads the coils, discrete inputs and registers of a slave, over
+// Modbus TCP or RTU
+type Modbus struct {
+	Name             string
+	Controller       string
+	TransmissionMode string `toml:"transmission_mode"`
+	SlaveID          int    `toml:"slave_id"`
+	Timeout          internal.Duration
+
+	// Settings of the serial ports
+	BaudRate int `toml:"baud_rate"`
+	DataBits int `toml:"data_bits"`
+	Parity   string
+	StopBits int `toml:"stop_bits"`
+
+	Coils            []Field
+	DiscreteInputs   []Field `toml:"discrete_inputs"`
+	HoldingRegisters []Field `toml:"holding_registers"`
+	InputRegisters   []Field `toml:"input_registers"`
+}
+
+// Field is a field read from the coil or discrete input at its address, or
+// from the registers at its addresses, in the order given
+type Field struct {
+	Name    string
+	Address []uint16
+	// DataType is the type of the value of the registers, INT16, UINT16,
+	// INT32, UINT32, INT64, UINT64, FLOAT32 or FLOAT64
+	DataType string `toml:"data_type"`
+	// ByteOrder is the order of the bytes of the value in the registers,
+	// A being its most significant byte
+	ByteOrder string `toml:"byte_order"`
+	// Scale multiplies the value, the field being a float if it is set
+	Scale float64
+}
+
+var sampleConfig = `
+  # Name of the device, added as the name tag
+  name = "device"
+
+  # Address of the slave, tcp://host:port for Modbus TCP, or the path of
+  # the serial port for Modbus RTU
+  controller = "tcp://localhost:502"
+  # controller = "file:///dev/ttyUSB0"
+
+  # Transmission mode, TCP or RTUoverTCP with tcp:// controllers, RTU with
+  # serial ports
+  # transmission_mode = "TCP"
+
+  # Unit identifier of the slave
+  slave_id = 1
+
+  # Timeout of the connection and of the requests
+  # timeout = "1s"
+
+  # Settings of the serial ports, Linux only
+  # baud_rate = 9600
+  # data_bits = 8
+  # parity = "N"
+  # stop_bits = 1
+
+  # The fields, a coil, discrete input or register address and its name.
+  # The fields of the registers may span several registers, read in the
+  # order of their addresses. The type of their value is INT16, UINT16,
+  # INT32, UINT32, INT64, UINT64, FLOAT32 or FLOAT64, and their byte order
+  # in the registers, big endian by default, is given by the letters of the
+  # value's bytes, A being the most significant: AB or BA for 16 bit values,
+  # ABCD, DCBA, BADC or CDAB for 32 bit values. The values multiplied by
+  # scale are floats.
+  [[inputs.modbus.coils]]
+    name = "motor_running"
+    address = [0]
+
+  # [[inputs.modbus.discrete_inputs]]
+  #   name = "door_open"
+  #   address = [0]
+
+  [[inputs.modbus.holding_registers]]
+    name = "voltage"
+    address = [0]
+    data_type = "UINT16"
+    scale = 0.1
+
+  # [[inputs.modbus.input_registers]]
+  #   name = "energy"
+  #   address = [4, 5]
+  #   data_type = "FLOAT32"
+  #   byte_order = "CDAB"
+`
+
+func (m *Modbus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Modbus) Description() string {
+	return "Read the coils, discrete inputs and registers of Modbus slaves"
+}
+
+// The maximum quantities of the reads
+const (
+	maxBits      = 2000
+	maxRegisters = 125
+)
+
+// dataTypes are the data types of the registers, with their size in bytes
+var dataTypes = map[string]int{
+	"INT16":   2,
+	"UINT16":  2,
+	"INT32":   4,
+	"UINT32":  4,
+	"INT64":   8,
+	"UINT64":  8,
+	"FLOAT32": 4,
+	"FLOAT64": 8,
+}
+
+// registerType are the reads of the fields of the coils, discrete inputs
+// and registers, their type being the type tag of their point
+type registerType struct {
+	name     string
+	function byte
+	fields   []Field
+}
+
+func (m *Modbus) registerTypes() []registerType {
+	return []registerType{
+		{"coil", readCoils, m.Coils},
+		{"discrete_input", readDiscreteInputs, m.DiscreteInputs},
+		{"holding_register", readHoldingRegisters, m.HoldingRegisters},
+		{"input_register", readInputRegisters, m.InputRegisters},
+	}
+}
+
+// validate checks the fields, setting their default data type and byte
+// order
+func (m *Modbus) validate() error {
+	if m.SlaveID < 0 || m.SlaveID > 255 {
+		return fmt.Errorf("invalid slave_id %d", m.SlaveID)
+	}
+	for _, rt := range m.registerTypes() {
+		for i := range rt.fields {
+			f := &rt.fields[i]
+			if f.Name == "" {
+				return fmt.Errorf("%s without name", rt.name)
+			}
+			if rt.function == readCoils || rt.function == readDiscreteInputs {
+				if len(f.Address) != 1 {
+					return fmt.Errorf("%s %s: expected one address", rt.name,
+						f.Name)
+				}
+				continue
+			}
+
+			if f.DataType == "" {
+				f.DataType = "UINT16"
+			}
+			f.DataType = strings.ToUpper(f.DataType)
+			size, ok := dataTypes[f.DataType]
+			if !ok {
+				return fmt.Errorf("%s %s: invalid data_type %q", rt.name, f.Name,
+					f.DataType)
+			}
+			if len(f.Address)*2 != size {
+				return fmt.Errorf("%s %s: %d addresses for %s, expected %d",
+					rt.name, f.Name, len(f.Address), f.DataType, size/2)
+			}
+			if f.ByteOrder == "" {
+				f.ByteOrder = "ABCDEFGH"[:size]
+			}
+			f.ByteOrder = strings.ToUpper(f.ByteOrder)
+			if !validByteOrder(f.ByteOrder, size) {
+				return fmt.Errorf("%s %s: invalid byte_order %q for %s", rt.name,
+					f.Name, f.ByteOrder, f.DataType)
+			}
+		}
+	}
+	return nil
+}
+
+// validByteOrder checks that the byte order is a permutation of the size
+// first letters
+func validByteOrder(order string, size int) bool {
+	if len(order) != size {
+		return false
+	}
+	seen := make(map[byte]bool)
+	for i := 0; i < len(order); i++ {
+		b := order[i]
+		if b < 'A' || int(b-'A') >= size || seen[b] {
+			return false
+		}
+		seen[b] = true
+	}
+	return true
+}
+
+type addresses []uint16
+
+func (a addresses) Len() int           { return len(a) }
+func (a addresses) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a addresses) Less(i, j int) bool { return a[i] < a[j] }
+
+// requests returns the ranges of consecutive addresses of the fields,
+// as their first address and quantity, of at most max addresses each
+func requests(fields []Field, max int) [][2]uint16 {
+	seen := make(map[uint16]bool)
+	var addrs addresses
+	for _, f := range fields {
+		for _, a := range f.Address {
+			if !seen[a] {
+				seen[a] = true
+				addrs = append(addrs, a)
+			}
+		}
+	}
+	sort.Sort(addrs)
+
+	var reqs [][2]uint16
+	for _, a := range addrs {
+		n := len(reqs) - 1
+		if n >= 0 && int(reqs[n][0])+int(reqs[n][1]) == int(a) &&
+			int(reqs[n][1]) < max {
+			reqs[n][1]++
+			continue
+		}
+		reqs = append(reqs, [2]uint16{a, 1})
+	}
+	return reqs
+}
+
+// read reads the registers of the fields, returning the 2 bytes of each
+// address, or a byte of 0 or 1 for the coils and discrete inputs
+func read(t transport, slaveID byte, function byte,
+	fields []Field) (map[uint16][]byte, error) {
+	bits := function == readCoils || function == readDiscreteInputs
+	max := maxRegisters
+	if bits {
+		max = maxBits
+	}
+
+	values := make(map[uint16][]byte)
+	for _, req := range requests(fields, max) {
+		pdu := make([]byte, 5)
+		pdu[0] = function
+		binary.BigEndian.PutUint16(pdu[1:], req[0])
+		binary.BigEndian.PutUint16(pdu[3:], req[1])
+		resp, err := t.send(slaveID, pdu)
+		if err != nil {
+			return nil, err
+		}
+		data, err := checkResponse(function, resp)
+		if err != nil {
+			return nil, fmt.Errorf("reading %d at %d: %s", req[1], req[0], err)
+		}
+
+		count := int(req[1]) * 2
+		if bits {
+			count = (int(req[1]) + 7) / 8
+		}
+		if len(data) != 1+count || int(data[0]) != count {
+			return nil, fmt.Errorf("reading %d at %d: %d bytes, expected %d",
+				req[1], req[0], len(data)-1, count)
+		}
+		data = data[1:]
+		for i := 0; i < int(req[1]); i++ {
+			addr := req[0] + uint16(i)
+			if bits {
+				values[addr] = []byte{data[i/8] >> uint(i%8) & 1}
+			} else {
+				values[addr] = data[2*i : 2*i+2]
+			}
+		}
+	}
+	return values, nil
+}
+
+// convert returns the value of the bytes of the registers of the field
+func convert(f Field, b []byte) interface{} {
+	// The bytes of the value, most significant first
+	v := make([]byte, 8)
+	offset := 8 - len(b)
+	for i := 0; i < len(b); i++ {
+		v[offset+int(f.ByteOrder[i]-'A')] = b[i]
+	}
+	raw := binary.BigEndian.Uint64(v)
+
+	var value interface{}
+	switch f.DataType {
+	case "INT16":
+		value = int64(int16(raw))
+	case "INT32":
+		value = int64(int32(raw))
+	case "INT64":
+		value = int64(raw)
+	case "UINT16", "UINT32", "UINT64":
+		value = raw
+	case "FLOAT32":
+		value = float64(math.Float32frombits(uint32(raw)))
+	case "FLOAT64":
+		value = math.Float64frombits(raw)
+	}
+	if f.Scale == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case int64:
+		return float64(v) * f.Scale
+	case uint64:
+		return float64(v) * f.Scale
+	case float64:
+		return v * f.Scale
+	}
+	return value
+}
+
+func (m *Modbus) Gather(acc telegraf.Accumulator) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if m.Timeout.Duration == 0 {
+		m.Timeout.Duration = time.Second
+	}
+	t, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	for _, rt := range m.registerTypes() {
+		if len(rt.fields) == 0 {
+			continue
+		}
+		values, err := read(t, byte(m.SlaveID), rt.function, rt.fields)
+		if err != nil {
+			return fmt.Errorf("%s: %s", rt.name, err)
+		}
+
+		fields := make(map[string]interface{})
+		for _, f := range rt.fields {
+			if rt.function == readCoils || rt.function == readDiscreteInputs {
+				fields[f.Name] = int64(values[f.Address[0]][0])
+				continue
+			}
+			var b []byte
+			for _, addr := range f.Address {
+				b = append(b, values[addr]...)
+			}
+			fields[f.Name] = convert(f, b)
+		}
+		tags := map[string]string{
+			"type":     rt.name,
+			"slave_id": strconv.Itoa(m.SlaveID),
+		}
+		if m.Name != "" {
+			tags["name"] = m.Name
+		}
+		acc.AddFields("modbus", fields, tags)
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("modbus", func() telegraf.Input {
+		return &Modbus{
+			SlaveID:  1,
+			BaudRate: 9600,
+			DataBits: 8,
+			Parity:   "N",
+			StopBits: 1,
+		}
+	})
+}
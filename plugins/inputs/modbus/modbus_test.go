@@ -0,0 +1,236 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slave is a Modbus slave serving the reads of its coils and registers,
+// over Modbus TCP or RTU over TCP
+type slave struct {
+	listener  net.Listener
+	rtu       bool
+	coils     map[uint16]bool
+	registers map[uint16]uint16
+}
+
+func newSlave(t *testing.T, rtu bool) *slave {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &slave{
+		listener:  listener,
+		rtu:       rtu,
+		coils:     make(map[uint16]bool),
+		registers: make(map[uint16]uint16),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *slave) controller() string {
+	return "tcp://" + s.listener.Addr().String()
+}
+
+// respond returns the response PDU to the read
+func (s *slave) respond(pdu []byte) []byte {
+	function := pdu[0]
+	addr := binary.BigEndian.Uint16(pdu[1:])
+	quantity := binary.BigEndian.Uint16(pdu[3:])
+	if addr >= 1000 {
+		return []byte{function | 0x80, 0x02}
+	}
+	switch function {
+	case readCoils, readDiscreteInputs:
+		data := make([]byte, (quantity+7)/8)
+		for i := uint16(0); i < quantity; i++ {
+			if s.coils[addr+i] {
+				data[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append([]byte{function, byte(len(data))}, data...)
+	}
+	data := make([]byte, 2*quantity)
+	for i := uint16(0); i < quantity; i++ {
+		binary.BigEndian.PutUint16(data[2*i:], s.registers[addr+i])
+	}
+	return append([]byte{function, byte(len(data))}, data...)
+}
+
+func (s *slave) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		if s.rtu {
+			req := make([]byte, 8)
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			resp := append([]byte{req[0]}, s.respond(req[1:6])...)
+			crc := make([]byte, 2)
+			binary.LittleEndian.PutUint16(crc, crc16(resp))
+			conn.Write(append(resp, crc...))
+			continue
+		}
+		req := make([]byte, 12)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		pdu := s.respond(req[7:])
+		header := make([]byte, 7)
+		copy(header, req[:4])
+		binary.BigEndian.PutUint16(header[4:], uint16(1+len(pdu)))
+		header[6] = req[6]
+		conn.Write(append(header, pdu...))
+	}
+}
+
+func TestCRC16(t *testing.T) {
+	// Read of the holding registers 0 and 1 of the slave 1
+	assert.Equal(t, uint16(0x0bc4),
+		crc16([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x02}))
+}
+
+func TestGather(t *testing.T) {
+	for _, rtu := range []bool{false, true} {
+		s := newSlave(t, rtu)
+		defer s.listener.Close()
+		s.coils[1] = true
+		s.coils[12] = true
+		s.registers[0] = 2305
+		s.registers[1] = 0xfffe
+		// 3.14 in FLOAT32 with its words swapped
+		s.registers[4] = 0xf5c3
+		s.registers[5] = 0x4048
+		s.registers[10] = 0x0102
+		s.registers[11] = 0x0304
+		s.registers[12] = 0x0506
+		s.registers[13] = 0x0708
+
+		m := &Modbus{
+			Name:       "meter",
+			Controller: s.controller(),
+			SlaveID:    3,
+			Coils: []Field{
+				{Name: "running", Address: []uint16{1}},
+				{Name: "alarm", Address: []uint16{2}},
+				{Name: "door", Address: []uint16{12}},
+			},
+			HoldingRegisters: []Field{
+				{Name: "voltage", Address: []uint16{0}, Scale: 0.1},
+				{Name: "offset", Address: []uint16{1}, DataType: "int16"},
+				{Name: "swapped", Address: []uint16{1}, ByteOrder: "BA"},
+				{Name: "energy", Address: []uint16{4, 5}, DataType: "FLOAT32",
+					ByteOrder: "CDAB"},
+				{Name: "counter", Address: []uint16{10, 11, 12, 13},
+					DataType: "UINT64"},
+				{Name: "low", Address: []uint16{11, 10}, DataType: "UINT32"},
+			},
+		}
+		if rtu {
+			m.TransmissionMode = "RTUoverTCP"
+		}
+
+		var acc testutil.Accumulator
+		require.NoError(t, m.Gather(&acc))
+		require.Len(t, acc.Metrics, 2)
+		acc.AssertContainsTaggedFields(t, "modbus",
+			map[string]interface{}{
+				"running": int64(1),
+				"alarm":   int64(0),
+				"door":    int64(1),
+			},
+			map[string]string{"name": "meter", "slave_id": "3", "type": "coil"})
+		acc.AssertContainsTaggedFields(t, "modbus",
+			map[string]interface{}{
+				"voltage": 230.5,
+				"offset":  int64(-2),
+				"swapped": uint64(0xfeff),
+				"energy":  float64(float32(3.14)),
+				"counter": uint64(0x0102030405060708),
+				"low":     uint64(0x03040102),
+			},
+			map[string]string{"name": "meter", "slave_id": "3",
+				"type": "holding_register"})
+	}
+}
+
+func TestRequests(t *testing.T) {
+	fields := []Field{
+		{Address: []uint16{5, 6}},
+		{Address: []uint16{0}},
+		{Address: []uint16{1}},
+		{Address: []uint16{6}},
+		{Address: []uint16{2, 3}},
+		{Address: []uint16{9}},
+	}
+	assert.Equal(t, [][2]uint16{{0, 4}, {5, 2}, {9, 1}}, requests(fields, 125))
+	assert.Equal(t, [][2]uint16{{0, 3}, {3, 1}, {5, 2}, {9, 1}},
+		requests(fields, 3))
+}
+
+func TestGatherErrors(t *testing.T) {
+	s := newSlave(t, false)
+	defer s.listener.Close()
+
+	m := &Modbus{
+		Controller: s.controller(),
+		InputRegisters: []Field{
+			{Name: "missing", Address: []uint16{1000}},
+		},
+	}
+	var acc testutil.Accumulator
+	err := m.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "illegal data address")
+
+	for _, m := range []*Modbus{
+		{Controller: "udp://localhost:502"},
+		{Controller: s.controller(), TransmissionMode: "RTU"},
+		{Controller: s.controller(), SlaveID: 256},
+		{Controller: s.controller(),
+			Coils: []Field{{Name: "c", Address: []uint16{1, 2}}}},
+		{Controller: s.controller(),
+			InputRegisters: []Field{{Name: "r", Address: []uint16{1},
+				DataType: "INT32"}}},
+		{Controller: s.controller(),
+			InputRegisters: []Field{{Name: "r", Address: []uint16{1},
+				DataType: "BCD"}}},
+		{Controller: s.controller(),
+			InputRegisters: []Field{{Name: "r", Address: []uint16{1, 2},
+				DataType: "INT32", ByteOrder: "ABCC"}}},
+	} {
+		m.Timeout = internal.Duration{Duration: time.Second}
+		assert.Error(t, m.Gather(&acc), m.Controller)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	table, err := toml.Parse([]byte("[[inputs.modbus]]" + sampleConfig))
+	require.NoError(t, err)
+	inputs := table.Fields["inputs"].(*ast.Table)
+	var m Modbus
+	require.NoError(t, toml.UnmarshalTable(
+		inputs.Fields["modbus"].([]*ast.Table)[0], &m))
+	assert.Equal(t, "tcp://localhost:502", m.Controller)
+	require.Len(t, m.HoldingRegisters, 1)
+	assert.Equal(t, Field{Name: "voltage", Address: []uint16{0},
+		DataType: "UINT16", Scale: 0.1}, m.HoldingRegisters[0])
+	require.NoError(t, m.validate())
+}
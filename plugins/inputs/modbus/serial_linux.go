@@ -0,0 +1,88 @@
+// +build linux
+
+package modbus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// baudRates are the termios speeds of the baud rates
+var baudRates = map[int]uint32{
+	1200:   syscall.B1200,
+	2400:   syscall.B2400,
+	4800:   syscall.B4800,
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+}
+
+// dataBits are the termios character sizes of the data bits
+var dataBits = map[int]uint32{
+	5: syscall.CS5,
+	6: syscall.CS6,
+	7: syscall.CS7,
+	8: syscall.CS8,
+}
+
+// openSerial opens the serial port in raw mode, the reads returning what was
+// received after the timeout, in tenths of seconds
+func openSerial(path string, baudRate int, bits int, parity string,
+	stopBits int, timeout time.Duration) (io.ReadWriteCloser, error) {
+	speed, ok := baudRates[baudRate]
+	if !ok {
+		return nil, fmt.Errorf("invalid baud_rate %d", baudRate)
+	}
+	size, ok := dataBits[bits]
+	if !ok {
+		return nil, fmt.Errorf("invalid data_bits %d", bits)
+	}
+	cflag := speed | size | syscall.CREAD | syscall.CLOCAL
+	switch parity {
+	case "N":
+	case "E":
+		cflag |= syscall.PARENB
+	case "O":
+		cflag |= syscall.PARENB | syscall.PARODD
+	default:
+		return nil, fmt.Errorf("invalid parity %q, expected N, E or O", parity)
+	}
+	switch stopBits {
+	case 1:
+	case 2:
+		cflag |= syscall.CSTOPB
+	default:
+		return nil, fmt.Errorf("invalid stop_bits %d", stopBits)
+	}
+	vtime := timeout / (100 * time.Millisecond)
+	if vtime < 1 {
+		vtime = 1
+	} else if vtime > 255 {
+		vtime = 255
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	t := syscall.Termios{
+		Cflag:  cflag,
+		Ispeed: speed,
+		Ospeed: speed,
+	}
+	t.Cc[syscall.VMIN] = 0
+	t.Cc[syscall.VTIME] = uint8(vtime)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+	if errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("configuring %s: %s", path, errno)
+	}
+	return f, nil
+}
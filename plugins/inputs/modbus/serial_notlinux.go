@@ -0,0 +1,15 @@
+// +build !linux
+
+package modbus
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// openSerial fails, the serial ports being only supported on Linux
+func openSerial(path string, baudRate int, bits int, parity string,
+	stopBits int, timeout time.Duration) (io.ReadWriteCloser, error) {
+	return nil, errors.New("serial ports are only supported on Linux")
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"gopkg.in/mgo.v2"
 )
@@ -17,7 +18,17 @@ import (
 type MongoDB struct {
 	Servers []string
 	Ssl     Ssl
-	mongos  map[string]*Server
+
+	GatherDbStats  bool     `toml:"gather_db_stats"`
+	GatherColStats bool     `toml:"gather_col_stats"`
+	ColStatsDbs    []string `toml:"col_stats_dbs"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	mongos map[string]*Server
 }
 
 type Ssl struct {
@@ -32,6 +43,19 @@ var sampleConfig = `
   #
   # If no servers are specified, then 127.0.0.1 is used as the host and 27107 as the port.
   servers = ["127.0.0.1:27017"]
+
+  # Gather the stats of each database in the mongodb_db_stats measurement
+  gather_db_stats = false
+  # Gather the stats of each collection in the mongodb_col_stats measurement,
+  # of the col_stats_dbs databases, all databases if empty
+  gather_col_stats = false
+  col_stats_dbs = []
+
+  # Use ssl
+  #ssl_ca = "/etc/telegraf/ca.pem"
+  #ssl_cert = "/etc/telegraf/cert.pem"
+  #ssl_key = "/etc/telegraf/key.pem"
+  #insecure_skip_verify = false
 `
 
 func (m *MongoDB) SampleConfig() string {
@@ -54,6 +78,7 @@ func (m *MongoDB) Gather(acc telegraf.Accumulator) error {
 
 	var wg sync.WaitGroup
 
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, serv := range m.Servers {
@@ -68,10 +93,15 @@ func (m *MongoDB) Gather(acc telegraf.Accumulator) error {
 				u.Path = ""
 			}
 		}
+		server := m.getMongoServer(u)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			outerr = m.gatherServer(m.getMongoServer(u), acc)
+			if err := m.gatherServer(server, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}()
 	}
 
@@ -83,7 +113,10 @@ func (m *MongoDB) Gather(acc telegraf.Accumulator) error {
 func (m *MongoDB) getMongoServer(url *url.URL) *Server {
 	if _, ok := m.mongos[url.Host]; !ok {
 		m.mongos[url.Host] = &Server{
-			Url: url,
+			Url:            url,
+			GatherDbStats:  m.GatherDbStats,
+			GatherColStats: m.GatherColStats,
+			ColStatsDbs:    m.ColStatsDbs,
 		}
 	}
 	return m.mongos[url.Host]
@@ -105,8 +138,17 @@ func (m *MongoDB) gatherServer(server *Server, acc telegraf.Accumulator) error {
 		dialInfo.Direct = true
 		dialInfo.Timeout = time.Duration(10) * time.Second
 
-		if m.Ssl.Enabled {
-			tlsConfig := &tls.Config{}
+		tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+			SSLCA:              m.SSLCA,
+			SSLCert:            m.SSLCert,
+			SSLKey:             m.SSLKey,
+			InsecureSkipVerify: m.InsecureSkipVerify,
+		})
+		if err != nil {
+			return err
+		}
+		if tlsConfig == nil && m.Ssl.Enabled {
+			tlsConfig = &tls.Config{}
 			if len(m.Ssl.CaCerts) > 0 {
 				roots := x509.NewCertPool()
 				for _, caCert := range m.Ssl.CaCerts {
@@ -119,6 +161,8 @@ func (m *MongoDB) gatherServer(server *Server, acc telegraf.Accumulator) error {
 			} else {
 				tlsConfig.InsecureSkipVerify = true
 			}
+		}
+		if tlsConfig != nil {
 			dialInfo.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
 				conn, err := tls.Dial("tcp", addr.String(), tlsConfig)
 				if err != nil {
@@ -123,6 +123,7 @@ func TestStateTag(t *testing.T) {
 		"repl_deletes_per_sec":  int64(0),
 		"repl_getmores_per_sec": int64(0),
 		"repl_inserts_per_sec":  int64(0),
+		"repl_lag":              int64(0),
 		"repl_queries_per_sec":  int64(0),
 		"repl_updates_per_sec":  int64(0),
 		"resident_megabytes":    int64(0),
@@ -131,3 +132,31 @@ func TestStateTag(t *testing.T) {
 	}
 	acc.AssertContainsTaggedFields(t, "mongodb", fields, stateTags)
 }
+
+func TestAddReplLag(t *testing.T) {
+	optime := time.Now()
+	status := ServerStatus{
+		Mem:  &MemStats{Supported: false},
+		Repl: &ReplStatus{IsMaster: false, Secondary: true},
+		ReplSetStatus: &ReplSetStatus{
+			Members: []ReplSetMember{
+				{Name: "mongo1:27017", State: 1, OptimeDate: optime},
+				{Name: "mongo2:27017", State: 2,
+					OptimeDate: optime.Add(-5 * time.Second), Self: true},
+				{Name: "mongo3:27017", State: 2, OptimeDate: optime},
+			},
+		},
+	}
+	d := NewMongodbData(NewStatLine(status, status, "mongo2:27017", true, 1),
+		map[string]string{})
+
+	var acc testutil.Accumulator
+
+	d.AddDefaultStats()
+	d.flush(&acc)
+
+	point, ok := acc.Get("mongodb")
+	assert.True(t, ok)
+	assert.Equal(t, "SEC", point.Tags["state"])
+	assert.Equal(t, int64(5), point.Fields["repl_lag"])
+}
@@ -13,6 +13,33 @@ type Server struct {
 	Url        *url.URL
 	Session    *mgo.Session
 	lastResult *ServerStatus
+
+	// Gather the stats of the databases, and of the collections of
+	// ColStatsDbs, all databases if empty
+	GatherDbStats  bool
+	GatherColStats bool
+	ColStatsDbs    []string
+}
+
+// DbStats are the stats of a database returned by dbStats
+type DbStats struct {
+	Collections int64   `bson:"collections"`
+	Objects     int64   `bson:"objects"`
+	AvgObjSize  float64 `bson:"avgObjSize"`
+	DataSize    int64   `bson:"dataSize"`
+	StorageSize int64   `bson:"storageSize"`
+	NumExtents  int64   `bson:"numExtents"`
+	Indexes     int64   `bson:"indexes"`
+	IndexSize   int64   `bson:"indexSize"`
+}
+
+// ColStats are the stats of a collection returned by collStats
+type ColStats struct {
+	Count          int64   `bson:"count"`
+	Size           int64   `bson:"size"`
+	AvgObjSize     float64 `bson:"avgObjSize"`
+	StorageSize    int64   `bson:"storageSize"`
+	TotalIndexSize int64   `bson:"totalIndexSize"`
 }
 
 func (s *Server) getDefaultTags() map[string]string {
@@ -33,6 +60,15 @@ func (s *Server) gatherData(acc telegraf.Accumulator) error {
 		s.lastResult = result
 	}()
 
+	// The members of a replica set report their lag behind the primary
+	if result.Repl != nil {
+		status := &ReplSetStatus{}
+		err := s.Session.DB("admin").Run(bson.D{{"replSetGetStatus", 1}}, status)
+		if err == nil {
+			result.ReplSetStatus = status
+		}
+	}
+
 	result.SampleTime = time.Now()
 	if s.lastResult != nil && result != nil {
 		duration := result.SampleTime.Sub(s.lastResult.SampleTime)
@@ -47,5 +83,78 @@ func (s *Server) gatherData(acc telegraf.Accumulator) error {
 		data.AddDefaultStats()
 		data.flush(acc)
 	}
+
+	if s.GatherDbStats || s.GatherColStats {
+		return s.gatherDbStats(acc)
+	}
+	return nil
+}
+
+// gatherDbStats adds the stats of the databases, and of their collections if
+// gathered, tagged with the name of the database and collection
+func (s *Server) gatherDbStats(acc telegraf.Accumulator) error {
+	names, err := s.Session.DatabaseNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		db := s.Session.DB(name)
+		tags := s.getDefaultTags()
+		tags["db_name"] = name
+
+		if s.GatherDbStats {
+			stats := &DbStats{}
+			if err := db.Run(bson.D{{"dbStats", 1}}, stats); err != nil {
+				return err
+			}
+			acc.AddFields("mongodb_db_stats", map[string]interface{}{
+				"collections":  stats.Collections,
+				"objects":      stats.Objects,
+				"avg_obj_size": stats.AvgObjSize,
+				"data_size":    stats.DataSize,
+				"storage_size": stats.StorageSize,
+				"num_extents":  stats.NumExtents,
+				"indexes":      stats.Indexes,
+				"index_size":   stats.IndexSize,
+			}, tags)
+		}
+
+		if !s.GatherColStats || !s.gatherColStatsOf(name) {
+			continue
+		}
+		collections, err := db.CollectionNames()
+		if err != nil {
+			return err
+		}
+		for _, collection := range collections {
+			stats := &ColStats{}
+			err := db.Run(bson.D{{"collStats", collection}}, stats)
+			if err != nil {
+				return err
+			}
+			colTags := s.getDefaultTags()
+			colTags["db_name"] = name
+			colTags["collection"] = collection
+			acc.AddFields("mongodb_col_stats", map[string]interface{}{
+				"count":            stats.Count,
+				"size":             stats.Size,
+				"avg_obj_size":     stats.AvgObjSize,
+				"storage_size":     stats.StorageSize,
+				"total_index_size": stats.TotalIndexSize,
+			}, colTags)
+		}
+	}
 	return nil
 }
+
+func (s *Server) gatherColStatsOf(name string) bool {
+	if len(s.ColStatsDbs) == 0 {
+		return true
+	}
+	for _, db := range s.ColStatsDbs {
+		if db == name {
+			return true
+		}
+	}
+	return false
+}
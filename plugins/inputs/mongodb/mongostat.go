@@ -54,8 +54,31 @@ type ServerStatus struct {
 	ShardCursorType    map[string]interface{} `bson:"shardCursorType"`
 	StorageEngine      map[string]string      `bson:"storageEngine"`
 	WiredTiger         *WiredTiger            `bson:"wiredTiger"`
+	// BEGIN code modification
+	// ReplSetStatus is the result of replSetGetStatus, for the members of a
+	// replica set
+	ReplSetStatus *ReplSetStatus `bson:"-"`
+	// END code modification
+}
+
+// BEGIN code modification
+// ReplSetStatus stores the status of the members of a replica set.
+type ReplSetStatus struct {
+	Members []ReplSetMember `bson:"members"`
+	MyState int64           `bson:"myState"`
+}
+
+// ReplSetMember stores the status of a member of a replica set.
+type ReplSetMember struct {
+	Name       string    `bson:"name"`
+	State      int64     `bson:"state"`
+	StateStr   string    `bson:"stateStr"`
+	OptimeDate time.Time `bson:"optimeDate"`
+	Self       bool      `bson:"self"`
 }
 
+// END code modification
+
 // WiredTiger stores information related to the WiredTiger storage engine.
 type WiredTiger struct {
 	Transaction TransactionStats       `bson:"transaction"`
@@ -351,6 +374,8 @@ type StatLine struct {
 	NumConnections                                        int64
 	ReplSetName                                           string
 	NodeType                                              string
+	// Seconds the node is behind the primary of its replica set
+	ReplLag int64
 }
 
 func parseLocks(stat ServerStatus) map[string]LockUsage {
@@ -396,6 +421,35 @@ func diff(newVal, oldVal, sampleTime int64) int64 {
 }
 
 // NewStatLine constructs a StatLine object from two ServerStatus objects.
+// BEGIN code modification
+// replLag returns the seconds between the last operation applied by the
+// primary and by the member the status was read from, 0 without status or
+// primary.
+func replLag(status *ReplSetStatus) int64 {
+	if status == nil {
+		return 0
+	}
+	var primary, self *ReplSetMember
+	for i, member := range status.Members {
+		if member.State == 1 {
+			primary = &status.Members[i]
+		}
+		if member.Self {
+			self = &status.Members[i]
+		}
+	}
+	if primary == nil || self == nil {
+		return 0
+	}
+	lag := int64(primary.OptimeDate.Sub(self.OptimeDate).Seconds())
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+// END code modification
+
 func NewStatLine(oldStat, newStat ServerStatus, key string, all bool, sampleSecs int64) *StatLine {
 	returnVal := &StatLine{
 		Key:       key,
@@ -473,6 +527,7 @@ func NewStatLine(oldStat, newStat ServerStatus, key string, all bool, sampleSecs
 		} else {
 			returnVal.NodeType = "UNK"
 		}
+		returnVal.ReplLag = replLag(newStat.ReplSetStatus)
 		// END code modification
 	} else if returnVal.IsMongos {
 		returnVal.NodeType = "RTR"
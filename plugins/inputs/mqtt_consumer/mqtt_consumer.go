@@ -0,0 +1,310 @@
+package mqtt_consumer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+
+	paho "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+type MQTTConsumer struct {
+	Servers []string
+	Topics  []string
+	// Names of the tags taken from the segments of the topics, "_" skipping
+	// a segment
+	TopicTags []string `toml:"topic_tags"`
+	QoS       int      `toml:"qos"`
+	// PersistentSession keeps the subscriptions and the messages of QoS 1
+	// and 2 on the broker while disconnected, requires a client_id
+	PersistentSession bool   `toml:"persistent_session"`
+	ClientID          string `toml:"client_id"`
+	Username          string
+	Password          string
+	MetricBuffer      int `toml:"metric_buffer"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Options of the parser, see parsers.Config
+	DataFormat     string   `toml:"data_format"`
+	TagKeys        []string `toml:"tag_keys"`
+	CSVColumnNames []string `toml:"csv_column_names"`
+	CSVDelimiter   string   `toml:"csv_delimiter"`
+	XMLPath        string   `toml:"xml_path"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	client *paho.Client
+	parser telegraf.Parser
+	// channel for all incoming MQTT messages
+	in chan paho.Message
+	// channel for all incoming parsed MQTT metrics
+	metricC chan telegraf.Metric
+	done    chan struct{}
+}
+
+var sampleConfig = `
+  servers = ["localhost:1883"]
+  # Topics to subscribe to, with the + and # wildcards
+  topics = [
+    "telegraf/host01/cpu",
+    "telegraf/+/mem",
+    "sensors/#",
+  ]
+  # Names of the tags taken from the segments of the topics, "_" skipping a
+  # segment, ie site and sensor for sensors/<site>/<sensor>
+  # topic_tags = ["_", "site", "sensor"]
+
+  # QoS of the subscriptions, 0, 1 or 2
+  qos = 0
+
+  # Keep the subscriptions and the messages of QoS 1 and 2 on the broker
+  # while telegraf is disconnected, requires a client_id
+  persistent_session = false
+  # client_id = "telegraf"
+
+  # username and password to connect to the MQTT server
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+
+  # TLS options, connecting with ssl:// when set
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 100000
+
+  # Data format of the payloads: influx, json, csv, prometheus or xml
+  data_format = "influx"
+  # Keys tagging the metrics with their value instead of being fields
+  # tag_keys = []
+`
+
+func (m *MQTTConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *MQTTConsumer) Description() string {
+	return "Read metrics from MQTT topic(s)"
+}
+
+func (m *MQTTConsumer) Start() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.QoS < 0 || m.QoS > 2 {
+		return fmt.Errorf("invalid qos %d, must be 0, 1 or 2", m.QoS)
+	}
+	if m.PersistentSession && m.ClientID == "" {
+		return fmt.Errorf("persistent_session requires a client_id")
+	}
+
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:     m.DataFormat,
+		MetricName:     "mqtt_consumer",
+		TagKeys:        m.TagKeys,
+		CSVColumnNames: m.CSVColumnNames,
+		CSVDelimiter:   m.CSVDelimiter,
+		XMLPath:        m.XMLPath,
+	})
+	if err != nil {
+		return err
+	}
+	m.parser = parser
+
+	opts, err := m.createOpts()
+	if err != nil {
+		return err
+	}
+
+	if m.MetricBuffer == 0 {
+		m.MetricBuffer = 100000
+	}
+	m.in = make(chan paho.Message, 1000)
+	m.metricC = make(chan telegraf.Metric, m.MetricBuffer)
+	m.done = make(chan struct{})
+
+	m.client = paho.NewClient(opts)
+	if token := m.client.Connect(); token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+
+	go m.receiver()
+	m.Log.Infof("Started the MQTT consumer service, servers: %v, topics: %v",
+		m.Servers, m.Topics)
+	return nil
+}
+
+// onConnect subscribes to the topics on every connection, the subscriptions
+// being lost on reconnections without a persistent session
+func (m *MQTTConsumer) onConnect(c *paho.Client) {
+	for _, topic := range m.Topics {
+		token := c.Subscribe(topic, byte(m.QoS), m.recvMessage)
+		if token.Wait() && token.Error() != nil {
+			m.Log.Errorf("Could not subscribe to topic %s: %s", topic,
+				token.Error())
+		}
+	}
+}
+
+func (m *MQTTConsumer) onConnectionLost(c *paho.Client, err error) {
+	m.Log.Warnf("Connection to the MQTT server lost, reconnecting: %s", err)
+}
+
+// recvMessage is the handler of the messages of the subscriptions
+func (m *MQTTConsumer) recvMessage(_ *paho.Client, msg paho.Message) {
+	select {
+	case m.in <- msg:
+	case <-m.done:
+	}
+}
+
+// receiver() reads all incoming messages from the consumer, and parses them
+// into metrics.
+func (m *MQTTConsumer) receiver() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case msg := <-m.in:
+			metrics, err := m.parser.Parse(msg.Payload())
+			if err != nil {
+				m.Log.Errorf("Could not parse MQTT message: %s, error: %s",
+					string(msg.Payload()), err)
+			}
+
+			for _, metric := range metrics {
+				metric, err = m.tagTopic(metric, msg.Topic())
+				if err != nil {
+					m.Log.Errorf("Could not tag metric: %s", err)
+					continue
+				}
+				select {
+				case m.metricC <- metric:
+				default:
+					m.Log.Warn("Buffer is full, dropping a metric." +
+						" You may want to increase the metric_buffer setting")
+				}
+			}
+		}
+	}
+}
+
+// tagTopic returns the metric tagged with its topic, and with the segments of
+// the topic named by topic_tags
+func (m *MQTTConsumer) tagTopic(
+	metric telegraf.Metric,
+	topic string,
+) (telegraf.Metric, error) {
+	tags := metric.Tags()
+	tags["topic"] = topic
+	segments := strings.Split(topic, "/")
+	for i, name := range m.TopicTags {
+		if i >= len(segments) {
+			break
+		}
+		if name != "_" && name != "" {
+			tags[name] = segments[i]
+		}
+	}
+	return telegraf.NewTypedMetric(metric.Type(), metric.Name(), tags,
+		metric.Fields(), metric.Time())
+}
+
+func (m *MQTTConsumer) Stop() {
+	m.Lock()
+	defer m.Unlock()
+	close(m.done)
+	// The subscriptions of a persistent session are kept for the next start
+	if !m.PersistentSession {
+		if token := m.client.Unsubscribe(m.Topics...); token.Wait() &&
+			token.Error() != nil {
+			m.Log.Errorf("Error unsubscribing: %s", token.Error())
+		}
+	}
+	m.client.Disconnect(200)
+}
+
+func (m *MQTTConsumer) Gather(acc telegraf.Accumulator) error {
+	m.Lock()
+	defer m.Unlock()
+	nmetrics := len(m.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-m.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func (m *MQTTConsumer) createOpts() (*paho.ClientOptions, error) {
+	opts := paho.NewClientOptions()
+
+	if m.ClientID == "" {
+		opts.SetClientID(getRandomClientId())
+	} else {
+		opts.SetClientID(m.ClientID)
+	}
+	opts.SetCleanSession(!m.PersistentSession)
+
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              m.SSLCA,
+		SSLCert:            m.SSLCert,
+		SSLKey:             m.SSLKey,
+		InsecureSkipVerify: m.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	scheme := "tcp"
+	if tlsConfig != nil {
+		scheme = "ssl"
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if m.Username != "" {
+		opts.SetUsername(m.Username)
+	}
+	if m.Password != "" {
+		opts.SetPassword(m.Password)
+	}
+
+	if len(m.Servers) == 0 {
+		return nil, fmt.Errorf("no MQTT servers set")
+	}
+	for _, host := range m.Servers {
+		opts.AddBroker(fmt.Sprintf("%s://%s", scheme, host))
+	}
+	opts.SetAutoReconnect(true)
+	opts.SetOnConnectHandler(m.onConnect)
+	opts.SetConnectionLostHandler(m.onConnectionLost)
+	return opts, nil
+}
+
+// getRandomClientId returns a client id for the sessions which are not
+// persistent
+func getRandomClientId() string {
+	const alphanum = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var bytes = make([]byte, 8)
+	rand.Read(bytes)
+	for i, b := range bytes {
+		bytes[i] = alphanum[b%byte(len(alphanum))]
+	}
+	return "telegraf-" + string(bytes)
+}
+
+func init() {
+	inputs.Add("mqtt_consumer", func() telegraf.Input {
+		return &MQTTConsumer{}
+	})
+}
@@ -0,0 +1,108 @@
+package mqtt_consumer
+
+import (
+	"testing"
+	"time"
+
+	paho "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testMsg      = "cpu_load_short,host=server01 value=23422.0 1422568543702900257"
+	invalidMsg   = "cpu_load_short,host=server01 1422568543702900257"
+	metricBuffer = 5
+)
+
+func newTestMQTTConsumer() (*MQTTConsumer, chan paho.Message) {
+	in := make(chan paho.Message, metricBuffer)
+	m := &MQTTConsumer{
+		Servers:      []string{"localhost:1883"},
+		MetricBuffer: metricBuffer,
+		Log:          testutil.Logger{},
+		parser:       &influx.Influx{},
+		in:           in,
+		done:         make(chan struct{}),
+		metricC:      make(chan telegraf.Metric, metricBuffer),
+	}
+	return m, in
+}
+
+// Test that the receiver parses MQTT messages into metrics
+func TestRunParser(t *testing.T) {
+	m, in := newTestMQTTConsumer()
+	defer close(m.done)
+
+	go m.receiver()
+	in <- mqttMsg("telegraf/server01/cpu", testMsg)
+	time.Sleep(time.Millisecond)
+
+	assert.Equal(t, 1, len(m.metricC))
+}
+
+// Test that the receiver ignores invalid messages
+func TestRunParserInvalidMsg(t *testing.T) {
+	m, in := newTestMQTTConsumer()
+	defer close(m.done)
+
+	go m.receiver()
+	in <- mqttMsg("telegraf/server01/cpu", invalidMsg)
+	time.Sleep(time.Millisecond)
+
+	assert.Equal(t, 0, len(m.metricC))
+}
+
+// Test that the metrics are tagged with their topic and its segments
+func TestRunParserTopicTags(t *testing.T) {
+	m, in := newTestMQTTConsumer()
+	m.TopicTags = []string{"_", "site", "sensor", "unused"}
+	defer close(m.done)
+
+	go m.receiver()
+	in <- mqttMsg("sensors/paris/temp1", testMsg)
+	time.Sleep(time.Millisecond)
+
+	acc := testutil.Accumulator{}
+	m.Gather(&acc)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422.0)},
+		map[string]string{
+			"host":   "server01",
+			"topic":  "sensors/paris/temp1",
+			"site":   "paris",
+			"sensor": "temp1",
+		})
+}
+
+func TestStartInvalid(t *testing.T) {
+	for _, m := range []*MQTTConsumer{
+		{Servers: []string{"localhost:1883"}, QoS: 3},
+		{Servers: []string{"localhost:1883"}, PersistentSession: true},
+		{Servers: []string{"localhost:1883"}, DataFormat: "yaml"},
+		{},
+	} {
+		m.Log = testutil.Logger{}
+		assert.Error(t, m.Start())
+	}
+}
+
+func mqttMsg(topic, payload string) paho.Message {
+	return &message{topic: topic, payload: []byte(payload)}
+}
+
+// message is an MQTT message received from a subscription
+type message struct {
+	topic   string
+	payload []byte
+}
+
+func (m *message) Duplicate() bool   { return false }
+func (m *message) Qos() byte         { return 0 }
+func (m *message) Retained() bool    { return false }
+func (m *message) Topic() string     { return m.topic }
+func (m *message) MessageID() uint16 { return 0 }
+func (m *message) Payload() []byte   { return m.payload }
@@ -2,16 +2,33 @@ package mysql
 
 import (
 	"database/sql"
+	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type Mysql struct {
 	Servers []string
+
+	GatherGlobalVariables bool `toml:"gather_global_variables"`
+	GatherSlaveStatus     bool `toml:"gather_slave_status"`
+	GatherInnoDBMetrics   bool `toml:"gather_innodb_metrics"`
+	GatherTableIOWaits    bool `toml:"gather_table_io_waits"`
+	GatherIndexIOWaits    bool `toml:"gather_index_io_waits"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// name of the TLS config registered in the driver, empty without TLS
+	tlsName string
 }
 
 var sampleConfig = `
@@ -24,6 +41,25 @@ var sampleConfig = `
   #
   # If no servers are specified, then localhost is used as the host.
   servers = ["tcp(127.0.0.1:3306)/"]
+
+  # Gather SHOW GLOBAL VARIABLES in the mysql_variables measurement
+  gather_global_variables = false
+  # Gather the numeric columns of SHOW SLAVE STATUS, as the slave_ fields of
+  # the mysql measurement
+  gather_slave_status = false
+  # Gather the enabled counters of information_schema.INNODB_METRICS in the
+  # mysql_innodb measurement
+  gather_innodb_metrics = false
+  # Gather the IO waits per table and per index of the performance_schema, in
+  # the mysql_table_io_waits and mysql_index_io_waits measurements
+  gather_table_io_waits = false
+  gather_index_io_waits = false
+
+  # Use ssl, for the servers without a tls parameter
+  #ssl_ca = "/etc/telegraf/ca.pem"
+  #ssl_cert = "/etc/telegraf/cert.pem"
+  #ssl_key = "/etc/telegraf/key.pem"
+  #insecure_skip_verify = false
 `
 
 func (m *Mysql) SampleConfig() string {
@@ -36,6 +72,35 @@ func (m *Mysql) Description() string {
 
 var localhost = ""
 
+// tlsConfigs counts the TLS configs registered in the driver, each plugin
+// registering its own
+var tlsConfigs struct {
+	sync.Mutex
+	n int
+}
+
+func (m *Mysql) Init() error {
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              m.SSLCA,
+		SSLCert:            m.SSLCert,
+		SSLKey:             m.SSLKey,
+		InsecureSkipVerify: m.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		tlsConfigs.Lock()
+		tlsConfigs.n++
+		m.tlsName = fmt.Sprintf("telegraf%d", tlsConfigs.n)
+		tlsConfigs.Unlock()
+		if err := mysql.RegisterTLSConfig(m.tlsName, tlsConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *Mysql) Gather(acc telegraf.Accumulator) error {
 	if len(m.Servers) == 0 {
 		// if we can't get stats in this case, thats fine, don't report
@@ -122,6 +187,10 @@ func (m *Mysql) gatherServer(serv string, acc telegraf.Accumulator) error {
 		serv = ""
 	}
 
+	if m.tlsName != "" {
+		serv = dsnWithTLS(serv, m.tlsName)
+	}
+
 	db, err := sql.Open("mysql", serv)
 	if err != nil {
 		return err
@@ -133,6 +202,7 @@ func (m *Mysql) gatherServer(serv string, acc telegraf.Accumulator) error {
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	var servtag string
 	servtag, err = parseDSN(serv)
@@ -181,9 +251,22 @@ func (m *Mysql) gatherServer(serv string, acc telegraf.Accumulator) error {
 			fields["slow_queries"] = i
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if m.GatherSlaveStatus {
+		if err := gatherSlaveStatus(db, fields); err != nil {
+			return err
+		}
+	}
 	acc.AddFields("mysql", fields, tags)
 
 	conn_rows, err := db.Query("SELECT user, sum(1) FROM INFORMATION_SCHEMA.PROCESSLIST GROUP BY user")
+	if err != nil {
+		return err
+	}
+	defer conn_rows.Close()
 
 	for conn_rows.Next() {
 		var user string
@@ -197,16 +280,256 @@ func (m *Mysql) gatherServer(serv string, acc telegraf.Accumulator) error {
 		tags := map[string]string{"server": servtag, "user": user}
 		fields := make(map[string]interface{})
 
-		if err != nil {
-			return err
-		}
 		fields["connections"] = connections
 		acc.AddFields("mysql_users", fields, tags)
 	}
 
+	if m.GatherGlobalVariables {
+		if err := gatherGlobalVariables(db, acc, servtag); err != nil {
+			return err
+		}
+	}
+	if m.GatherInnoDBMetrics {
+		if err := gatherInnoDBMetrics(db, acc, servtag); err != nil {
+			return err
+		}
+	}
+	if m.GatherTableIOWaits {
+		if err := gatherTableIOWaits(db, acc, servtag); err != nil {
+			return err
+		}
+	}
+	if m.GatherIndexIOWaits {
+		if err := gatherIndexIOWaits(db, acc, servtag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dsnWithTLS returns the DSN connecting with the registered TLS config name,
+// unless it sets its own tls parameter
+func dsnWithTLS(dsn string, name string) string {
+	i := strings.LastIndex(dsn, "/")
+	if i < 0 {
+		dsn += "/"
+		i = len(dsn) - 1
+	}
+	params := dsn[i:]
+	if strings.Contains(params, "?tls=") || strings.Contains(params, "&tls=") {
+		return dsn
+	}
+	if strings.Contains(params, "?") {
+		return dsn + "&tls=" + name
+	}
+	return dsn + "?tls=" + name
+}
+
+// parseValue returns the number of a value of the server: an int64 or a
+// float64, 1 for ON and YES and 0 for OFF and NO. It returns false if the
+// value is not a number.
+func parseValue(value sql.RawBytes) (interface{}, bool) {
+	s := string(value)
+	switch strings.ToUpper(s) {
+	case "ON", "YES":
+		return int64(1), true
+	case "OFF", "NO":
+		return int64(0), true
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// gatherSlaveStatus adds the numeric columns of SHOW SLAVE STATUS to the
+// fields, lowercased and prefixed with slave_. Servers which are not slaves
+// return no row.
+func gatherSlaveStatus(db *sql.DB, fields map[string]interface{}) error {
+	rows, err := db.Query("SHOW SLAVE STATUS")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, column := range columns {
+			if value, ok := parseValue(values[i]); ok {
+				fields["slave_"+strings.ToLower(column)] = value
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// gatherGlobalVariables adds the variables of SHOW GLOBAL VARIABLES, the
+// values which are not numbers being added as strings
+func gatherGlobalVariables(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	servtag string,
+) error {
+	rows, err := db.Query(`SHOW /*!50002 GLOBAL */ VARIABLES`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	fields := make(map[string]interface{})
+	for rows.Next() {
+		var name string
+		var val sql.RawBytes
+		if err := rows.Scan(&name, &val); err != nil {
+			return err
+		}
+		if value, ok := parseValue(val); ok {
+			fields[name] = value
+		} else if len(val) > 0 {
+			fields[name] = string(val)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	acc.AddFields("mysql_variables", fields,
+		map[string]string{"server": servtag})
+	return nil
+}
+
+// gatherInnoDBMetrics adds the enabled counters of INNODB_METRICS
+func gatherInnoDBMetrics(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	servtag string,
+) error {
+	rows, err := db.Query(`SELECT NAME, COUNT
+		FROM information_schema.INNODB_METRICS WHERE status = 'enabled'`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	fields := make(map[string]interface{})
+	for rows.Next() {
+		var name string
+		var count int64
+		if err := rows.Scan(&name, &count); err != nil {
+			return err
+		}
+		fields[name] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	acc.AddFields("mysql_innodb", fields,
+		map[string]string{"server": servtag})
 	return nil
 }
 
+// The counts and times, in picoseconds, of the IO waits of the
+// performance_schema summaries by table and by index
+const ioWaitsColumns = `COUNT_FETCH, COUNT_INSERT, COUNT_UPDATE,
+	COUNT_DELETE, SUM_TIMER_FETCH, SUM_TIMER_INSERT, SUM_TIMER_UPDATE,
+	SUM_TIMER_DELETE`
+
+var ioWaitsFields = []string{"count_fetch", "count_insert", "count_update",
+	"count_delete", "time_fetch", "time_insert", "time_update", "time_delete"}
+
+// gatherTableIOWaits adds the IO waits per table, tagged with its schema and
+// name
+func gatherTableIOWaits(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	servtag string,
+) error {
+	rows, err := db.Query(`SELECT OBJECT_SCHEMA, OBJECT_NAME, ` +
+		ioWaitsColumns + `
+		FROM performance_schema.table_io_waits_summary_by_table
+		WHERE OBJECT_SCHEMA NOT IN ('mysql', 'performance_schema')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, name string
+		values := make([]int64, len(ioWaitsFields))
+		dest := []interface{}{&schema, &name}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fields := make(map[string]interface{})
+		for i, field := range ioWaitsFields {
+			fields[field] = values[i]
+		}
+		acc.AddFields("mysql_table_io_waits", fields, map[string]string{
+			"server": servtag,
+			"schema": schema,
+			"name":   name,
+		})
+	}
+	return rows.Err()
+}
+
+// gatherIndexIOWaits adds the IO waits per index, tagged with the schema and
+// name of its table and its name, NONE for the waits not using an index
+func gatherIndexIOWaits(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	servtag string,
+) error {
+	rows, err := db.Query(`SELECT OBJECT_SCHEMA, OBJECT_NAME,
+		IFNULL(INDEX_NAME, 'NONE'), ` + ioWaitsColumns + `
+		FROM performance_schema.table_io_waits_summary_by_index_usage
+		WHERE OBJECT_SCHEMA NOT IN ('mysql', 'performance_schema')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, name, index string
+		values := make([]int64, len(ioWaitsFields))
+		dest := []interface{}{&schema, &name, &index}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fields := make(map[string]interface{})
+		for i, field := range ioWaitsFields {
+			fields[field] = values[i]
+		}
+		acc.AddFields("mysql_index_io_waits", fields, map[string]string{
+			"server": servtag,
+			"schema": schema,
+			"name":   name,
+			"index":  index,
+		})
+	}
+	return rows.Err()
+}
+
 func init() {
 	inputs.Add("mysql", func() telegraf.Input {
 		return &Mysql{}
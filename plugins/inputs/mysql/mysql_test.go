@@ -17,6 +17,7 @@ func TestMysqlDefaultsToLocal(t *testing.T) {
 	m := &Mysql{
 		Servers: []string{fmt.Sprintf("root@tcp(%s:3306)/", testutil.GetLocalHost())},
 	}
+	require.NoError(t, m.Init())
 
 	var acc testutil.Accumulator
 
@@ -84,3 +85,42 @@ func TestMysqlParseDSN(t *testing.T) {
 		}
 	}
 }
+
+func TestMysqlDSNWithTLS(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"", "/?tls=custom"},
+		{"tcp(192.168.1.1:3306)/", "tcp(192.168.1.1:3306)/?tls=custom"},
+		{"root:passwd@tcp(localhost:3036)/dbname?allowOldPasswords=1",
+			"root:passwd@tcp(localhost:3036)/dbname?allowOldPasswords=1&tls=custom"},
+		{"root@tcp(127.0.0.1:3306)/?tls=false",
+			"root@tcp(127.0.0.1:3306)/?tls=false"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.output, dsnWithTLS(test.input, "custom"))
+	}
+}
+
+func TestMysqlParseValue(t *testing.T) {
+	tests := []struct {
+		input  string
+		output interface{}
+		ok     bool
+	}{
+		{"ON", int64(1), true},
+		{"Yes", int64(1), true},
+		{"OFF", int64(0), true},
+		{"12", int64(12), true},
+		{"0.5", float64(0.5), true},
+		{"/var/lib/mysql/", nil, false},
+	}
+
+	for _, test := range tests {
+		value, ok := parseValue([]byte(test.input))
+		assert.Equal(t, test.ok, ok, test.input)
+		assert.Equal(t, test.output, value, test.input)
+	}
+}
@@ -0,0 +1,267 @@
+package nats_consumer
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/nats-io/nats"
+)
+
+type NatsConsumer struct {
+	Servers  []string
+	Subjects []string
+	// Queue group of the subscriptions, the messages of a subject being
+	// delivered to a single member of the group
+	QueueGroup string `toml:"queue_group"`
+	Username   string
+	Password   string
+
+	// Limits of the messages received and not yet processed, past which the
+	// subscriptions are slow consumers and drop messages, 65536 messages and
+	// 64MB if zero
+	PendingMessageLimit int `toml:"pending_message_limit"`
+	PendingBytesLimit   int `toml:"pending_bytes_limit"`
+	MetricBuffer        int `toml:"metric_buffer"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Options of the parser, see parsers.Config
+	DataFormat     string   `toml:"data_format"`
+	TagKeys        []string `toml:"tag_keys"`
+	CSVColumnNames []string `toml:"csv_column_names"`
+	CSVDelimiter   string   `toml:"csv_delimiter"`
+	XMLPath        string   `toml:"xml_path"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	conn   *nats.Conn
+	subs   []*nats.Subscription
+	parser telegraf.Parser
+	// channel for all incoming NATS messages
+	in chan *nats.Msg
+	// channel for all incoming parsed NATS metrics
+	metricC chan telegraf.Metric
+	done    chan struct{}
+}
+
+var sampleConfig = `
+  servers = ["nats://localhost:4222"]
+  # Subjects to subscribe to, with the * and > wildcards
+  subjects = ["telegraf"]
+  # Queue group of the subscriptions, sharing the messages between the
+  # telegraf instances of the group
+  queue_group = "telegraf_consumers"
+
+  # username and password to connect to the NATS servers
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+
+  # TLS options
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Messages and bytes received and not yet processed per subscription, past
+  # which messages are dropped
+  pending_message_limit = 65536
+  pending_bytes_limit = 67108864
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 100000
+
+  # Data format of the messages: influx, json, csv, prometheus or xml
+  data_format = "influx"
+  # Keys tagging the metrics with their value instead of being fields
+  # tag_keys = []
+`
+
+func (n *NatsConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NatsConsumer) Description() string {
+	return "Read metrics from NATS subject(s)"
+}
+
+func (n *NatsConsumer) Start() error {
+	n.Lock()
+	defer n.Unlock()
+
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:     n.DataFormat,
+		MetricName:     "nats_consumer",
+		TagKeys:        n.TagKeys,
+		CSVColumnNames: n.CSVColumnNames,
+		CSVDelimiter:   n.CSVDelimiter,
+		XMLPath:        n.XMLPath,
+	})
+	if err != nil {
+		return err
+	}
+	n.parser = parser
+
+	opts, err := n.createOpts()
+	if err != nil {
+		return err
+	}
+	n.conn, err = opts.Connect()
+	if err != nil {
+		return err
+	}
+
+	if n.MetricBuffer == 0 {
+		n.MetricBuffer = 100000
+	}
+	n.in = make(chan *nats.Msg, 1000)
+	n.metricC = make(chan telegraf.Metric, n.MetricBuffer)
+	n.done = make(chan struct{})
+
+	for _, subject := range n.Subjects {
+		sub, err := n.conn.QueueSubscribe(subject, n.QueueGroup, n.recvMessage)
+		if err != nil {
+			n.conn.Close()
+			return fmt.Errorf("could not subscribe to subject %s: %s",
+				subject, err)
+		}
+		msgLimit, bytesLimit := n.PendingMessageLimit, n.PendingBytesLimit
+		if msgLimit == 0 {
+			msgLimit = 65536
+		}
+		if bytesLimit == 0 {
+			bytesLimit = 64 * 1024 * 1024
+		}
+		if err := sub.SetPendingLimits(msgLimit, bytesLimit); err != nil {
+			n.conn.Close()
+			return err
+		}
+		n.subs = append(n.subs, sub)
+	}
+
+	go n.receiver()
+	n.Log.Infof("Started the NATS consumer service, servers: %v, "+
+		"subjects: %v, queue group: %s", n.Servers, n.Subjects, n.QueueGroup)
+	return nil
+}
+
+// recvMessage is the handler of the messages of the subscriptions
+func (n *NatsConsumer) recvMessage(msg *nats.Msg) {
+	select {
+	case n.in <- msg:
+	case <-n.done:
+	}
+}
+
+// onAsyncError logs the errors of the subscriptions, ie slow consumers
+func (n *NatsConsumer) onAsyncError(_ *nats.Conn, sub *nats.Subscription, err error) {
+	if sub != nil {
+		n.Log.Errorf("Error on subject %s: %s", sub.Subject, err)
+	} else {
+		n.Log.Errorf("Error: %s", err)
+	}
+}
+
+// receiver() reads all incoming messages from the consumer, and parses them
+// into metrics.
+func (n *NatsConsumer) receiver() {
+	for {
+		select {
+		case <-n.done:
+			return
+		case msg := <-n.in:
+			metrics, err := n.parser.Parse(msg.Data)
+			if err != nil {
+				n.Log.Errorf("Could not parse NATS message: %s, error: %s",
+					string(msg.Data), err)
+			}
+
+			for _, metric := range metrics {
+				select {
+				case n.metricC <- metric:
+				default:
+					n.Log.Warn("Buffer is full, dropping a metric." +
+						" You may want to increase the metric_buffer setting")
+				}
+			}
+		}
+	}
+}
+
+func (n *NatsConsumer) Stop() {
+	n.Lock()
+	defer n.Unlock()
+	close(n.done)
+	for _, sub := range n.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			n.Log.Errorf("Error unsubscribing from subject %s: %s",
+				sub.Subject, err)
+		}
+	}
+	n.subs = nil
+	n.conn.Close()
+}
+
+func (n *NatsConsumer) Gather(acc telegraf.Accumulator) error {
+	n.Lock()
+	defer n.Unlock()
+	nmetrics := len(n.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-n.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func (n *NatsConsumer) createOpts() (nats.Options, error) {
+	opts := nats.DefaultOptions
+	if len(n.Servers) == 0 {
+		return opts, fmt.Errorf("no NATS servers set")
+	}
+	// The credentials are given by the URLs of the servers
+	for _, server := range n.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return opts, fmt.Errorf("invalid NATS server %q: %s", server, err)
+		}
+		if n.Username != "" {
+			u.User = url.UserPassword(n.Username, n.Password)
+		}
+		opts.Servers = append(opts.Servers, u.String())
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              n.SSLCA,
+		SSLCert:            n.SSLCert,
+		SSLKey:             n.SSLKey,
+		InsecureSkipVerify: n.InsecureSkipVerify,
+	})
+	if err != nil {
+		return opts, err
+	}
+	if tlsConfig != nil {
+		opts.Secure = true
+		opts.TLSConfig = tlsConfig
+	}
+
+	opts.Name = "telegraf"
+	// Reconnect forever, the subscriptions are renewed by the client
+	opts.MaxReconnect = -1
+	opts.AsyncErrorCB = n.onAsyncError
+	return opts, nil
+}
+
+func init() {
+	inputs.Add("nats_consumer", func() telegraf.Input {
+		return &NatsConsumer{}
+	})
+}
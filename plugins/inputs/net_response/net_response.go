@@ -0,0 +1,183 @@
+package net_response
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// The results of the checks, in the result tag and the result_code field
+const (
+	Success          = 0
+	Timeout          = 1
+	ConnectionFailed = 2
+	ReadFailed       = 3
+	StringMismatch   = 4
+)
+
+var resultNames = map[int]string{
+	Success:          "success",
+	Timeout:          "timeout",
+	ConnectionFailed: "connection_failed",
+	ReadFailed:       "read_failed",
+	StringMismatch:   "string_mismatch",
+}
+
+// NetResponse checks a TCP or UDP service, connecting to its address and
+// optionally matching the response to a payload sent
+type NetResponse struct {
+	Address     string
+	Protocol    string
+	Timeout     internal.Duration
+	ReadTimeout internal.Duration `toml:"read_timeout"`
+	Send        string
+	Expect      string
+
+	expect *regexp.Regexp
+}
+
+var sampleConfig = `
+  # Protocol, "tcp" or "udp"
+  protocol = "tcp"
+  # Server address, host:port
+  address = "localhost:80"
+
+  # Timeout of the connection, 1s by default
+  # timeout = "1s"
+  # Timeout of the response, once connected, 1s by default
+  # read_timeout = "1s"
+
+  # Payload sent once connected, required with udp
+  # send = "ssh"
+  # Regular expression matched against the first line of the response
+  # expect = "ssh"
+`
+
+func (n *NetResponse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NetResponse) Description() string {
+	return "Check TCP or UDP services, their response time and response"
+}
+
+// Init checks the options of the plugin and compiles its expect regular
+// expression
+func (n *NetResponse) Init() error {
+	if n.Protocol == "" {
+		n.Protocol = "tcp"
+	}
+	if n.Protocol != "tcp" && n.Protocol != "udp" {
+		return fmt.Errorf("unknown protocol %q, must be tcp or udp", n.Protocol)
+	}
+	if _, _, err := net.SplitHostPort(n.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %s", n.Address, err)
+	}
+	if n.Protocol == "udp" && n.Send == "" {
+		return errors.New("send must be set with the udp protocol")
+	}
+	if n.Timeout.Duration == 0 {
+		n.Timeout.Duration = time.Second
+	}
+	if n.ReadTimeout.Duration == 0 {
+		n.ReadTimeout.Duration = time.Second
+	}
+	if n.Expect != "" {
+		expect, err := regexp.Compile(n.Expect)
+		if err != nil {
+			return fmt.Errorf("invalid expect %q: %s", n.Expect, err)
+		}
+		n.expect = expect
+	}
+	return nil
+}
+
+// Gather checks the service, adding its result and response time. The
+// failures of the checks are results, and not errors of the plugin.
+func (n *NetResponse) Gather(acc telegraf.Accumulator) error {
+	host, port, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	result := n.check(fields)
+	fields["result_code"] = result
+	tags := map[string]string{
+		"server":   host,
+		"port":     port,
+		"protocol": n.Protocol,
+		"result":   resultNames[result],
+	}
+	acc.AddFields("net_response", fields, tags)
+	return nil
+}
+
+// check connects to the service, sends the payload and reads the response,
+// adding the response time and whether the response matched to the fields.
+// It returns the result of the check.
+func (n *NetResponse) check(fields map[string]interface{}) int {
+	start := time.Now()
+	c, err := net.DialTimeout(n.Protocol, n.Address, n.Timeout.Duration)
+	if err != nil {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Timeout
+		}
+		return ConnectionFailed
+	}
+	defer c.Close()
+
+	if n.Send != "" {
+		if _, err := c.Write([]byte(n.Send)); err != nil {
+			return ConnectionFailed
+		}
+	}
+	// The services are up once connected, unless they are expected to
+	// respond. UDP services are only known to be up once they responded.
+	if n.Protocol == "tcp" && n.expect == nil {
+		fields["response_time"] = time.Since(start).Seconds()
+		return Success
+	}
+
+	// The response of TCP services is their first line, the one of UDP
+	// services their first datagram
+	c.SetReadDeadline(time.Now().Add(n.ReadTimeout.Duration))
+	var line string
+	if n.Protocol == "tcp" {
+		line, err = bufio.NewReader(c).ReadString('\n')
+	} else {
+		buf := make([]byte, 65536)
+		var l int
+		l, err = c.Read(buf)
+		line = string(buf[:l])
+	}
+	if err != nil && line == "" {
+		if e, ok := err.(net.Error); ok && e.Timeout() {
+			return Timeout
+		}
+		return ReadFailed
+	}
+	fields["response_time"] = time.Since(start).Seconds()
+
+	if n.expect != nil {
+		found := n.expect.MatchString(line)
+		fields["string_found"] = found
+		if !found {
+			return StringMismatch
+		}
+	}
+	return Success
+}
+
+func init() {
+	inputs.Add("net_response", func() telegraf.Input {
+		return &NetResponse{}
+	})
+}
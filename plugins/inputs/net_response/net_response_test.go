@@ -0,0 +1,143 @@
+package net_response
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpServer accepts the connections of l, greeting them with an ssh banner
+func tcpServer(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		fmt.Fprint(c, "SSH-2.0-OpenSSH_6.9\r\n")
+		c.Close()
+	}
+}
+
+func TestNetResponseTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go tcpServer(l)
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		expect string
+		result string
+		code   int
+		found  interface{}
+	}{
+		{"", "success", Success, nil},
+		{"^SSH-2\\.0", "success", Success, true},
+		{"^HTTP/1\\.1", "string_mismatch", StringMismatch, false},
+	} {
+		n := &NetResponse{
+			Address: l.Addr().String(),
+			Expect:  tt.expect,
+		}
+		require.NoError(t, n.Init())
+		var acc testutil.Accumulator
+		require.NoError(t, n.Gather(&acc))
+
+		m, ok := acc.Get("net_response")
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{
+			"server":   host,
+			"port":     port,
+			"protocol": "tcp",
+			"result":   tt.result,
+		}, m.Tags)
+		assert.Equal(t, tt.code, m.Fields["result_code"])
+		assert.Equal(t, tt.found, m.Fields["string_found"])
+		assert.True(t, acc.HasFloatField("net_response", "response_time"))
+	}
+}
+
+func TestNetResponseTCPConnectionFailed(t *testing.T) {
+	// The port of a closed listener is refusing the connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	n := &NetResponse{Address: addr}
+	require.NoError(t, n.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+
+	m, ok := acc.Get("net_response")
+	require.True(t, ok)
+	assert.Equal(t, "connection_failed", m.Tags["result"])
+	assert.Equal(t, ConnectionFailed, m.Fields["result_code"])
+	assert.NotContains(t, m.Fields, "response_time")
+}
+
+func TestNetResponseUDP(t *testing.T) {
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer c.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := c.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// Only the pings are answered
+			if string(buf[:n]) == "ping" {
+				c.WriteTo([]byte("pong"), addr)
+			}
+		}
+	}()
+
+	n := &NetResponse{
+		Protocol:    "udp",
+		Address:     c.LocalAddr().String(),
+		Send:        "ping",
+		Expect:      "pong",
+		ReadTimeout: internal.Duration{Duration: 100 * time.Millisecond},
+	}
+	require.NoError(t, n.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	m, ok := acc.Get("net_response")
+	require.True(t, ok)
+	assert.Equal(t, "success", m.Tags["result"])
+	assert.Equal(t, true, m.Fields["string_found"])
+
+	n = &NetResponse{
+		Protocol:    "udp",
+		Address:     c.LocalAddr().String(),
+		Send:        "hello",
+		ReadTimeout: internal.Duration{Duration: 100 * time.Millisecond},
+	}
+	require.NoError(t, n.Init())
+	acc = testutil.Accumulator{}
+	require.NoError(t, n.Gather(&acc))
+	m, ok = acc.Get("net_response")
+	require.True(t, ok)
+	assert.Equal(t, "timeout", m.Tags["result"])
+	assert.Equal(t, Timeout, m.Fields["result_code"])
+}
+
+func TestNetResponseInvalidConfig(t *testing.T) {
+	for _, n := range []*NetResponse{
+		{Address: "localhost"},
+		{Address: "localhost:22", Protocol: "icmp"},
+		{Address: "localhost:53", Protocol: "udp"},
+		{Address: "localhost:22", Expect: "("},
+	} {
+		assert.Error(t, n.Init())
+	}
+}
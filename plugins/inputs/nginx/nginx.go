@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -22,6 +23,12 @@ type Nginx struct {
 var sampleConfig = `
   # An array of Nginx stub_status URI to gather stats.
   urls = ["http://localhost/status"]
+  # Servers listening on a unix socket are queried with a "unix://" URL, ie
+  # "unix:///var/run/nginx.sock/status"
+  #
+  # The JSON status of nginx Plus, ie "http://localhost/status", is
+  # recognized by its content type, and adds the nginx_server_zone and
+  # nginx_upstream_peer measurements.
 `
 
 func (n *Nginx) SampleConfig() string {
@@ -34,6 +41,7 @@ func (n *Nginx) Description() string {
 
 func (n *Nginx) Gather(acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, u := range n.Urls {
@@ -45,7 +53,11 @@ func (n *Nginx) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(addr *url.URL) {
 			defer wg.Done()
-			outerr = n.gatherUrl(addr, acc)
+			if err := n.gatherUrl(addr, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}(addr)
 	}
 
@@ -54,9 +66,9 @@ func (n *Nginx) Gather(acc telegraf.Accumulator) error {
 	return outerr
 }
 
-var tr = &http.Transport{
+var tr = internal.RegisterUnixSockets(&http.Transport{
 	ResponseHeaderTimeout: time.Duration(3 * time.Second),
-}
+})
 
 var client = &http.Client{Transport: tr}
 
@@ -69,6 +81,9 @@ func (n *Nginx) gatherUrl(addr *url.URL, acc telegraf.Accumulator) error {
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("%s returned HTTP status %s", addr.String(), resp.Status)
 	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return gatherPlusStatus(resp.Body, getTags(addr), acc)
+	}
 	r := bufio.NewReader(resp.Body)
 
 	// Active connections
@@ -144,6 +159,10 @@ func (n *Nginx) gatherUrl(addr *url.URL, acc telegraf.Accumulator) error {
 
 // Get tag(s) for the nginx plugin
 func getTags(addr *url.URL) map[string]string {
+	if addr.Scheme == "unix" {
+		socket, _, _ := internal.SplitUnixSocketPath(addr.Path)
+		return map[string]string{"server": socket, "port": ""}
+	}
 	h := addr.Host
 	host, port, err := net.SplitHostPort(h)
 	if err != nil {
@@ -0,0 +1,132 @@
+package nginx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/influxdata/telegraf"
+)
+
+// plusStatus is the part of the JSON status of nginx Plus gathered
+type plusStatus struct {
+	Connections struct {
+		Accepted uint64 `json:"accepted"`
+		Dropped  uint64 `json:"dropped"`
+		Active   uint64 `json:"active"`
+		Idle     uint64 `json:"idle"`
+	} `json:"connections"`
+	Requests struct {
+		Total   uint64 `json:"total"`
+		Current uint64 `json:"current"`
+	} `json:"requests"`
+	ServerZones map[string]struct {
+		Processing uint64        `json:"processing"`
+		Requests   uint64        `json:"requests"`
+		Responses  plusResponses `json:"responses"`
+		Discarded  uint64        `json:"discarded"`
+		Received   uint64        `json:"received"`
+		Sent       uint64        `json:"sent"`
+	} `json:"server_zones"`
+	Upstreams map[string]struct {
+		Peers []struct {
+			Server    string        `json:"server"`
+			State     string        `json:"state"`
+			Backup    bool          `json:"backup"`
+			Weight    uint64        `json:"weight"`
+			Active    uint64        `json:"active"`
+			Requests  uint64        `json:"requests"`
+			Responses plusResponses `json:"responses"`
+			Sent      uint64        `json:"sent"`
+			Received  uint64        `json:"received"`
+			Fails     uint64        `json:"fails"`
+			Unavail   uint64        `json:"unavail"`
+		} `json:"peers"`
+	} `json:"upstreams"`
+}
+
+type plusResponses struct {
+	Responses1xx uint64 `json:"1xx"`
+	Responses2xx uint64 `json:"2xx"`
+	Responses3xx uint64 `json:"3xx"`
+	Responses4xx uint64 `json:"4xx"`
+	Responses5xx uint64 `json:"5xx"`
+	Total        uint64 `json:"total"`
+}
+
+func (r plusResponses) addFields(fields map[string]interface{}) {
+	fields["responses_1xx"] = r.Responses1xx
+	fields["responses_2xx"] = r.Responses2xx
+	fields["responses_3xx"] = r.Responses3xx
+	fields["responses_4xx"] = r.Responses4xx
+	fields["responses_5xx"] = r.Responses5xx
+	fields["responses_total"] = r.Total
+}
+
+// gatherPlusStatus parses the JSON status of nginx Plus. The connections and
+// requests are added to the nginx measurement like those of the stub status,
+// the idle connections being the waiting ones, along with the server zones
+// and upstream peers.
+func gatherPlusStatus(
+	r io.Reader,
+	tags map[string]string,
+	acc telegraf.Accumulator,
+) error {
+	var status plusStatus
+	if err := json.NewDecoder(r).Decode(&status); err != nil {
+		return fmt.Errorf("error decoding nginx Plus status: %s", err)
+	}
+
+	fields := map[string]interface{}{
+		"active":   status.Connections.Active,
+		"accepts":  status.Connections.Accepted,
+		"handled":  status.Connections.Accepted - status.Connections.Dropped,
+		"dropped":  status.Connections.Dropped,
+		"waiting":  status.Connections.Idle,
+		"requests": status.Requests.Total,
+		"current":  status.Requests.Current,
+	}
+	acc.AddFields("nginx", fields, tags)
+
+	for name, zone := range status.ServerZones {
+		zoneTags := map[string]string{"zone": name}
+		for k, v := range tags {
+			zoneTags[k] = v
+		}
+		fields := map[string]interface{}{
+			"processing": zone.Processing,
+			"requests":   zone.Requests,
+			"discarded":  zone.Discarded,
+			"received":   zone.Received,
+			"sent":       zone.Sent,
+		}
+		zone.Responses.addFields(fields)
+		acc.AddFields("nginx_server_zone", fields, zoneTags)
+	}
+
+	for name, upstream := range status.Upstreams {
+		for _, peer := range upstream.Peers {
+			peerTags := map[string]string{
+				"upstream":        name,
+				"upstream_server": peer.Server,
+			}
+			for k, v := range tags {
+				peerTags[k] = v
+			}
+			fields := map[string]interface{}{
+				"state":    peer.State,
+				"backup":   peer.Backup,
+				"weight":   peer.Weight,
+				"active":   peer.Active,
+				"requests": peer.Requests,
+				"sent":     peer.Sent,
+				"received": peer.Received,
+				"fails":    peer.Fails,
+				"unavail":  peer.Unavail,
+			}
+			peer.Responses.addFields(fields)
+			acc.AddFields("nginx_upstream_peer", fields, peerTags)
+		}
+	}
+	return nil
+}
@@ -83,3 +83,129 @@ func TestNginxGeneratesMetrics(t *testing.T) {
 	tags := map[string]string{"server": host, "port": port}
 	acc.AssertContainsTaggedFields(t, "nginx", fields, tags)
 }
+
+const samplePlusResponse = `
+{
+  "version": 6,
+  "nginx_version": "1.9.9",
+  "connections": {
+    "accepted": 1234,
+    "dropped": 4,
+    "active": 12,
+    "idle": 30
+  },
+  "requests": {
+    "total": 5678,
+    "current": 8
+  },
+  "server_zones": {
+    "site1": {
+      "processing": 2,
+      "requests": 736,
+      "responses": {
+        "1xx": 0,
+        "2xx": 700,
+        "3xx": 20,
+        "4xx": 15,
+        "5xx": 1,
+        "total": 736
+      },
+      "discarded": 0,
+      "received": 105240,
+      "sent": 2210488
+    }
+  },
+  "upstreams": {
+    "backend": {
+      "peers": [
+        {
+          "id": 0,
+          "server": "10.0.0.1:8080",
+          "backup": false,
+          "weight": 1,
+          "state": "up",
+          "active": 1,
+          "requests": 367,
+          "responses": {
+            "1xx": 0,
+            "2xx": 360,
+            "3xx": 0,
+            "4xx": 7,
+            "5xx": 0,
+            "total": 367
+          },
+          "sent": 80210,
+          "received": 1105244,
+          "fails": 0,
+          "unavail": 0
+        }
+      ]
+    }
+  }
+}
+`
+
+func TestNginxPlusGeneratesMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, samplePlusResponse)
+	}))
+	defer ts.Close()
+
+	n := &Nginx{
+		Urls: []string{ts.URL + "/status"},
+	}
+
+	var acc testutil.Accumulator
+
+	require.NoError(t, n.Gather(&acc))
+
+	addr, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	tags := getTags(addr)
+	acc.AssertContainsTaggedFields(t, "nginx", map[string]interface{}{
+		"active":   uint64(12),
+		"accepts":  uint64(1234),
+		"handled":  uint64(1230),
+		"dropped":  uint64(4),
+		"waiting":  uint64(30),
+		"requests": uint64(5678),
+		"current":  uint64(8),
+	}, tags)
+
+	tags["zone"] = "site1"
+	acc.AssertContainsTaggedFields(t, "nginx_server_zone", map[string]interface{}{
+		"processing":      uint64(2),
+		"requests":        uint64(736),
+		"discarded":       uint64(0),
+		"received":        uint64(105240),
+		"sent":            uint64(2210488),
+		"responses_1xx":   uint64(0),
+		"responses_2xx":   uint64(700),
+		"responses_3xx":   uint64(20),
+		"responses_4xx":   uint64(15),
+		"responses_5xx":   uint64(1),
+		"responses_total": uint64(736),
+	}, tags)
+
+	delete(tags, "zone")
+	tags["upstream"] = "backend"
+	tags["upstream_server"] = "10.0.0.1:8080"
+	acc.AssertContainsTaggedFields(t, "nginx_upstream_peer", map[string]interface{}{
+		"state":           "up",
+		"backup":          false,
+		"weight":          uint64(1),
+		"active":          uint64(1),
+		"requests":        uint64(367),
+		"sent":            uint64(80210),
+		"received":        uint64(1105244),
+		"fails":           uint64(0),
+		"unavail":         uint64(0),
+		"responses_1xx":   uint64(0),
+		"responses_2xx":   uint64(360),
+		"responses_3xx":   uint64(0),
+		"responses_4xx":   uint64(7),
+		"responses_5xx":   uint64(0),
+		"responses_total": uint64(367),
+	}, tags)
+}
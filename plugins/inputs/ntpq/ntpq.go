@@ -0,0 +1,184 @@
+package ntpq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs ntpq with the arguments, killing it after the timeout, and
+// returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Ntpq reads the peers of ntpd with ntpq -p
+type Ntpq struct {
+	Binary    string
+	DNSLookup bool `toml:"dns_lookup"`
+	Timeout   internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of ntpq
+  binary = "/usr/bin/ntpq"
+
+  # Resolve the addresses of the peers into hostnames, which can be slow
+  # when the DNS is
+  dns_lookup = false
+
+  # Timeout of ntpq
+  # timeout = "5s"
+`
+
+func (n *Ntpq) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *Ntpq) Description() string {
+	return "Gather the offsets, jitters and reach of the NTP peers with ntpq"
+}
+
+// intColumns and floatColumns are the indexes of the columns of the fields in
+// the lines of ntpq -p. The reach is kept as printed, in octal.
+var intColumns = map[string]int{
+	"stratum": 2,
+	"when":    4,
+	"poll":    5,
+	"reach":   6,
+}
+
+var floatColumns = map[string]int{
+	"delay":  7,
+	"offset": 8,
+	"jitter": 9,
+}
+
+// whenUnits are the units of the when column, printed in minutes, hours or
+// days once larger than 2048 seconds
+var whenUnits = map[byte]int64{
+	'm': 60,
+	'h': 60 * 60,
+	'd': 24 * 60 * 60,
+}
+
+func (n *Ntpq) Gather(acc telegraf.Accumulator) error {
+	if n.runner == nil {
+		n.runner = runNtpq
+	}
+	if n.Binary == "" {
+		n.Binary = "/usr/bin/ntpq"
+	}
+	if n.Timeout.Duration == 0 {
+		n.Timeout.Duration = 5 * time.Second
+	}
+
+	args := []string{"-p"}
+	if !n.DNSLookup {
+		args = append(args, "-n")
+	}
+	out, err := n.runner(n.Timeout.Duration, n.Binary, args...)
+	if err != nil {
+		return fmt.Errorf("reading the peers: %s: %s", err,
+			strings.TrimSpace(out))
+	}
+	return parsePeers(out, acc)
+}
+
+// parsePeers adds a point per peer of the output of ntpq -p, listed as
+//
+//	     remote           refid      st t when poll reach   delay   offset  jitter
+//	==============================================================================
+//	*10.0.0.1        193.190.230.65   2 u   38   64  377    0.873   -0.125   0.051
+//
+// the first character being the state of the peer, ie * for the peer
+// synchronized to
+func parsePeers(out string, acc telegraf.Accumulator) error {
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	header := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header {
+			header = !strings.HasPrefix(line, "===")
+			continue
+		}
+		if len(line) < 2 {
+			continue
+		}
+		cols := strings.Fields(line[1:])
+		if len(cols) != 10 {
+			continue
+		}
+
+		tags := map[string]string{
+			"remote": cols[0],
+			"refid":  cols[1],
+			"type":   cols[3],
+		}
+		if line[0] != ' ' {
+			tags["state_prefix"] = string(line[0])
+		}
+		fields := make(map[string]interface{})
+		for name, col := range intColumns {
+			value := cols[col]
+			var unit int64 = 1
+			if name == "when" && len(value) > 1 {
+				if u, ok := whenUnits[value[len(value)-1]]; ok {
+					value, unit = value[:len(value)-1], u
+				}
+			}
+			// The peers never reached have no when, printed as -
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[name] = v * unit
+			}
+		}
+		for name, col := range floatColumns {
+			if v, err := strconv.ParseFloat(cols[col], 64); err == nil {
+				fields[name] = v
+			}
+		}
+		acc.AddFields("ntpq", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+// runNtpq runs ntpq, killing it after the timeout
+func runNtpq(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("ntpq timed out after %s", timeout)
+	}
+}
+
+func init() {
+	inputs.Add("ntpq", func() telegraf.Input {
+		return &Ntpq{
+			Binary:  "/usr/bin/ntpq",
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			runner:  runNtpq,
+		}
+	})
+}
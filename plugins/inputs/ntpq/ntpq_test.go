@@ -0,0 +1,94 @@
+package ntpq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ntpqOutput = `     remote           refid      st t when poll reach   delay   offset  jitter
+==============================================================================
+*10.0.0.1        193.190.230.65   2 u   38   64  377    0.873   -0.125   0.051
++10.0.0.2        .GPS.            1 u   12m 1024  377   12.524    1.502   0.318
+ 10.0.0.3        .INIT.          16 u    - 1024    0    0.000    0.000   0.000
+`
+
+func TestGather(t *testing.T) {
+	var calls []string
+	n := &Ntpq{
+		Binary: "ntpq",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return ntpqOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+
+	assert.Equal(t, []string{"ntpq -p -n"}, calls)
+	assert.Equal(t, 3, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "ntpq", map[string]interface{}{
+		"stratum": int64(2),
+		"when":    int64(38),
+		"poll":    int64(64),
+		"reach":   int64(377),
+		"delay":   0.873,
+		"offset":  -0.125,
+		"jitter":  0.051,
+	}, map[string]string{
+		"remote":       "10.0.0.1",
+		"refid":        "193.190.230.65",
+		"type":         "u",
+		"state_prefix": "*",
+	})
+	// The when in minutes is converted to seconds
+	acc.AssertContainsTaggedFields(t, "ntpq", map[string]interface{}{
+		"stratum": int64(1),
+		"when":    int64(720),
+		"poll":    int64(1024),
+		"reach":   int64(377),
+		"delay":   12.524,
+		"offset":  1.502,
+		"jitter":  0.318,
+	}, map[string]string{
+		"remote":       "10.0.0.2",
+		"refid":        ".GPS.",
+		"type":         "u",
+		"state_prefix": "+",
+	})
+	// The peers never reached have no when, nor state
+	acc.AssertContainsTaggedFields(t, "ntpq", map[string]interface{}{
+		"stratum": int64(16),
+		"poll":    int64(1024),
+		"reach":   int64(0),
+		"delay":   0.0,
+		"offset":  0.0,
+		"jitter":  0.0,
+	}, map[string]string{
+		"remote": "10.0.0.3",
+		"refid":  ".INIT.",
+		"type":   "u",
+	})
+}
+
+func TestGatherDNSLookup(t *testing.T) {
+	var calls []string
+	n := &Ntpq{
+		Binary:    "ntpq",
+		DNSLookup: true,
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return "ntpq: read: Connection refused", errors.New("exit status 1")
+		},
+	}
+	var acc testutil.Accumulator
+	err := n.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Connection refused")
+	assert.Equal(t, []string{"ntpq -p"}, calls)
+}
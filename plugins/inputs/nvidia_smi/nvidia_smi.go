@@ -0,0 +1,213 @@
+package nvidia_smi
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs nvidia-smi with the arguments, killing it after the timeout,
+// and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) ([]byte, error)
+
+// NvidiaSMI reads the metrics of the NVIDIA GPUs with nvidia-smi
+type NvidiaSMI struct {
+	Path    string
+	Timeout internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of nvidia-smi, looked up in the PATH by default
+  # path = "/usr/bin/nvidia-smi"
+
+  # Timeout of nvidia-smi
+  # timeout = "5s"
+`
+
+func (n *NvidiaSMI) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NvidiaSMI) Description() string {
+	return "Read the metrics of the NVIDIA GPUs with nvidia-smi"
+}
+
+// smiLog is the report of nvidia-smi -q -x, the values being followed by
+// their unit, ie "42 MiB", or N/A when they are not supported
+type smiLog struct {
+	DriverVersion string `xml:"driver_version"`
+	GPUs          []struct {
+		ProductName      string `xml:"product_name"`
+		UUID             string `xml:"uuid"`
+		MinorNumber      string `xml:"minor_number"`
+		ComputeMode      string `xml:"compute_mode"`
+		PerformanceState string `xml:"performance_state"`
+		FanSpeed         string `xml:"fan_speed"`
+		Memory           struct {
+			Total string `xml:"total"`
+			Used  string `xml:"used"`
+			Free  string `xml:"free"`
+		} `xml:"fb_memory_usage"`
+		Utilization struct {
+			GPU     string `xml:"gpu_util"`
+			Memory  string `xml:"memory_util"`
+			Encoder string `xml:"encoder_util"`
+			Decoder string `xml:"decoder_util"`
+		} `xml:"utilization"`
+		Temperature struct {
+			GPU string `xml:"gpu_temp"`
+		} `xml:"temperature"`
+		Power struct {
+			Draw  string `xml:"power_draw"`
+			Limit string `xml:"power_limit"`
+		} `xml:"power_readings"`
+		Clocks struct {
+			Graphics string `xml:"graphics_clock"`
+			SM       string `xml:"sm_clock"`
+			Memory   string `xml:"mem_clock"`
+			Video    string `xml:"video_clock"`
+		} `xml:"clocks"`
+		Processes []struct {
+			PID        string `xml:"pid"`
+			Type       string `xml:"type"`
+			Name       string `xml:"process_name"`
+			UsedMemory string `xml:"used_memory"`
+		} `xml:"processes>process_info"`
+	} `xml:"gpu"`
+}
+
+func (n *NvidiaSMI) Gather(acc telegraf.Accumulator) error {
+	if n.Path == "" {
+		path, err := exec.LookPath("nvidia-smi")
+		if err != nil {
+			return fmt.Errorf("nvidia-smi not found: %s", err)
+		}
+		n.Path = path
+	}
+	if n.runner == nil {
+		n.runner = runNvidiaSMI
+	}
+	if n.Timeout.Duration == 0 {
+		n.Timeout.Duration = 5 * time.Second
+	}
+
+	out, err := n.runner(n.Timeout.Duration, n.Path, "-q", "-x")
+	if err != nil {
+		return fmt.Errorf("running nvidia-smi: %s: %s", err,
+			strings.TrimSpace(string(out)))
+	}
+	var log smiLog
+	if err := xml.Unmarshal(out, &log); err != nil {
+		return fmt.Errorf("parsing the output of nvidia-smi: %s", err)
+	}
+
+	now := time.Now()
+	for _, gpu := range log.GPUs {
+		tags := map[string]string{
+			"index": gpu.MinorNumber,
+			"uuid":  gpu.UUID,
+			"name":  gpu.ProductName,
+		}
+		if gpu.PerformanceState != "" {
+			tags["pstate"] = gpu.PerformanceState
+		}
+		if gpu.ComputeMode != "" {
+			tags["compute_mode"] = gpu.ComputeMode
+		}
+
+		fields := map[string]interface{}{}
+		if log.DriverVersion != "" {
+			fields["driver_version"] = log.DriverVersion
+		}
+		addInt(fields, "fan_speed", gpu.FanSpeed)
+		addInt(fields, "memory_total", gpu.Memory.Total)
+		addInt(fields, "memory_used", gpu.Memory.Used)
+		addInt(fields, "memory_free", gpu.Memory.Free)
+		addInt(fields, "utilization_gpu", gpu.Utilization.GPU)
+		addInt(fields, "utilization_memory", gpu.Utilization.Memory)
+		addInt(fields, "utilization_encoder", gpu.Utilization.Encoder)
+		addInt(fields, "utilization_decoder", gpu.Utilization.Decoder)
+		addInt(fields, "temperature_gpu", gpu.Temperature.GPU)
+		addFloat(fields, "power_draw", gpu.Power.Draw)
+		addFloat(fields, "power_limit", gpu.Power.Limit)
+		addInt(fields, "clocks_current_graphics", gpu.Clocks.Graphics)
+		addInt(fields, "clocks_current_sm", gpu.Clocks.SM)
+		addInt(fields, "clocks_current_memory", gpu.Clocks.Memory)
+		addInt(fields, "clocks_current_video", gpu.Clocks.Video)
+		acc.AddFields("nvidia_smi", fields, tags, now)
+
+		for _, p := range gpu.Processes {
+			fields := map[string]interface{}{}
+			addInt(fields, "used_memory", p.UsedMemory)
+			acc.AddFields("nvidia_smi_process", fields, map[string]string{
+				"index":        gpu.MinorNumber,
+				"uuid":         gpu.UUID,
+				"pid":          p.PID,
+				"process_name": p.Name,
+				"type":         p.Type,
+			}, now)
+		}
+	}
+	return nil
+}
+
+// addInt adds the integer value, followed by its unit, to the fields, unless
+// it is not supported by the GPU
+func addInt(fields map[string]interface{}, name string, value string) {
+	if v, err := strconv.ParseInt(strings.Fields(value + " ")[0], 10, 64); err == nil {
+		fields[name] = v
+	}
+}
+
+// addFloat adds the floating point value, followed by its unit, to the
+// fields, unless it is not supported by the GPU
+func addFloat(fields map[string]interface{}, name string, value string) {
+	if v, err := strconv.ParseFloat(strings.Fields(value + " ")[0], 64); err == nil {
+		fields[name] = v
+	}
+}
+
+// runNvidiaSMI runs nvidia-smi, killing it after the timeout. Its errors are
+// only returned when it fails, not to be parsed with its report.
+func runNvidiaSMI(timeout time.Duration, path string, args ...string) ([]byte, error) {
+	var out, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return append(out.Bytes(), stderr.Bytes()...), err
+		}
+		return out.Bytes(), nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return append(out.Bytes(), stderr.Bytes()...), fmt.Errorf("nvidia-smi timed out after %s", timeout)
+	}
+}
+
+func init() {
+	inputs.Add("nvidia_smi", func() telegraf.Input {
+		return &NvidiaSMI{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+			runner:  runNvidiaSMI,
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package nvidia_smi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const smiOutput = `
+	<?xml version="1.0" ?>
+	<!DOCTYPE nvidia_smi_log SYSTEM "nvsmi_device_v10.dtd">
+	<nvidia_smi_log>
+		<timestamp>Mon Mar 11 10:42:31 2019</timestamp>
+		<driver_version>418.43</driver_version>
+		<cuda_version>10.1</cuda_version>
+		<attached_gpus>2</attached_gpus>
+		<gpu id="00000000:01:00.0">
+			<product_name>GeForce GTX 1070 Ti</product_name>
+			<uuid>GPU-f9ba66fc-a7f5-94c5-da19-019ef2f9c665</uuid>
+			<minor_number>0</minor_number>
+			<compute_mode>Default</compute_mode>
+			<fan_speed>100 %</fan_speed>
+			<performance_state>P2</performance_state>
+			<fb_memory_usage>
+				<total>8119 MiB</total>
+				<used>2014 MiB</used>
+				<free>6105 MiB</free>
+			</fb_memory_usage>
+			<utilization>
+				<gpu_util>87 %</gpu_util>
+				<memory_util>42 %</memory_util>
+				<encoder_util>0 %</encoder_util>
+				<decoder_util>0 %</decoder_util>
+			</utilization>
+			<temperature>
+				<gpu_temp>73 C</gpu_temp>
+				<gpu_temp_max_threshold>99 C</gpu_temp_max_threshold>
+			</temperature>
+			<power_readings>
+				<power_state>P2</power_state>
+				<power_draw>152.34 W</power_draw>
+				<power_limit>180.00 W</power_limit>
+			</power_readings>
+			<clocks>
+				<graphics_clock>1860 MHz</graphics_clock>
+				<sm_clock>1860 MHz</sm_clock>
+				<mem_clock>3802 MHz</mem_clock>
+				<video_clock>1670 MHz</video_clock>
+			</clocks>
+			<processes>
+				<process_info>
+					<pid>2318</pid>
+					<type>C</type>
+					<process_name>python3</process_name>
+					<used_memory>2003 MiB</used_memory>
+				</process_info>
+			</processes>
+		</gpu>
+		<gpu id="00000000:02:00.0">
+			<product_name>Tesla K80</product_name>
+			<uuid>GPU-d5ac4d2a-6b3e-1a9c-4b1f-7c2e9b0f1d3a</uuid>
+			<minor_number>1</minor_number>
+			<compute_mode>Default</compute_mode>
+			<fan_speed>N/A</fan_speed>
+			<performance_state>P8</performance_state>
+			<fb_memory_usage>
+				<total>11441 MiB</total>
+				<used>0 MiB</used>
+				<free>11441 MiB</free>
+			</fb_memory_usage>
+			<utilization>
+				<gpu_util>0 %</gpu_util>
+				<memory_util>0 %</memory_util>
+				<encoder_util>N/A</encoder_util>
+				<decoder_util>N/A</decoder_util>
+			</utilization>
+			<temperature>
+				<gpu_temp>31 C</gpu_temp>
+			</temperature>
+			<power_readings>
+				<power_draw>26.12 W</power_draw>
+				<power_limit>149.00 W</power_limit>
+			</power_readings>
+			<clocks>
+				<graphics_clock>324 MHz</graphics_clock>
+				<sm_clock>324 MHz</sm_clock>
+				<mem_clock>324 MHz</mem_clock>
+				<video_clock>405 MHz</video_clock>
+			</clocks>
+			<processes>
+			</processes>
+		</gpu>
+	</nvidia_smi_log>
+`
+
+func TestGather(t *testing.T) {
+	var args []string
+	n := &NvidiaSMI{
+		Path: "nvidia-smi",
+		runner: func(timeout time.Duration, path string, a ...string) ([]byte, error) {
+			args = a
+			return []byte(smiOutput), nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+
+	assert.Equal(t, []string{"-q", "-x"}, args)
+	acc.AssertContainsTaggedFields(t, "nvidia_smi", map[string]interface{}{
+		"driver_version":          "418.43",
+		"fan_speed":               int64(100),
+		"memory_total":            int64(8119),
+		"memory_used":             int64(2014),
+		"memory_free":             int64(6105),
+		"utilization_gpu":         int64(87),
+		"utilization_memory":      int64(42),
+		"utilization_encoder":     int64(0),
+		"utilization_decoder":     int64(0),
+		"temperature_gpu":         int64(73),
+		"power_draw":              152.34,
+		"power_limit":             float64(180),
+		"clocks_current_graphics": int64(1860),
+		"clocks_current_sm":       int64(1860),
+		"clocks_current_memory":   int64(3802),
+		"clocks_current_video":    int64(1670),
+	}, map[string]string{
+		"index":        "0",
+		"uuid":         "GPU-f9ba66fc-a7f5-94c5-da19-019ef2f9c665",
+		"name":         "GeForce GTX 1070 Ti",
+		"pstate":       "P2",
+		"compute_mode": "Default",
+	})
+	acc.AssertContainsTaggedFields(t, "nvidia_smi_process",
+		map[string]interface{}{
+			"used_memory": int64(2003),
+		}, map[string]string{
+			"index":        "0",
+			"uuid":         "GPU-f9ba66fc-a7f5-94c5-da19-019ef2f9c665",
+			"pid":          "2318",
+			"process_name": "python3",
+			"type":         "C",
+		})
+
+	// The values not supported are skipped
+	m := acc.Metrics[2]
+	assert.Equal(t, "1", m.Tags["index"])
+	assert.NotContains(t, m.Fields, "fan_speed")
+	assert.NotContains(t, m.Fields, "utilization_encoder")
+	assert.Equal(t, 26.12, m.Fields["power_draw"])
+	assert.Equal(t, 3, len(acc.Metrics))
+}
+
+func TestGatherError(t *testing.T) {
+	n := &NvidiaSMI{
+		Path: "nvidia-smi",
+		runner: func(timeout time.Duration, path string, a ...string) ([]byte, error) {
+			return []byte("NVIDIA-SMI has failed because it couldn't " +
+				"communicate with the NVIDIA driver.\n"), errors.New("exit status 9")
+		},
+	}
+	var acc testutil.Accumulator
+	err := n.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NVIDIA driver")
+
+	n.runner = func(timeout time.Duration, path string, a ...string) ([]byte, error) {
+		return []byte("<nvidia_smi_log>"), nil
+	}
+	assert.Error(t, n.Gather(&acc))
+}
@@ -0,0 +1,549 @@
+package opcua
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// The security policies supported
+const (
+	policyNone           = "http://opcfoundation.org/UA/SecurityPolicy#None"
+	policyBasic256Sha256 = "http://opcfoundation.org/UA/SecurityPolicy#Basic256Sha256"
+)
+
+// The algorithms of the signatures of the sessions and of the encryption of
+// the passwords with Basic256Sha256
+const (
+	algorithmRsaSha256 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algorithmRsaOaep   = "http://www.w3.org/2001/04/xmlenc#rsa-oaep"
+)
+
+const (
+	// bufferSize is the size of the chunks received, announced to the
+	// remote
+	bufferSize = 65536
+	// maxMessageSize is the maximum size of the messages received
+	maxMessageSize = 16 * 1024 * 1024
+	// nonceSize is the size of the nonces of Basic256Sha256
+	nonceSize = 32
+)
+
+// symmetricKeys are the keys of the messages of a side of the channel,
+// derived from the nonces exchanged when it is opened
+type symmetricKeys struct {
+	signing    []byte
+	encrypting []byte
+	iv         []byte
+}
+
+// pSHA256 is the P_SHA256 pseudo random function of TLS
+func pSHA256(secret, seed []byte, length int) []byte {
+	var out []byte
+	a := seed
+	for len(out) < length {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(a)
+		a = mac.Sum(nil)
+		mac = hmac.New(sha256.New, secret)
+		mac.Write(a)
+		mac.Write(seed)
+		out = mac.Sum(out)
+	}
+	return out[:length]
+}
+
+func deriveKeys(secret, seed []byte) *symmetricKeys {
+	b := pSHA256(secret, seed, 32+32+aes.BlockSize)
+	return &symmetricKeys{
+		signing:    b[:32],
+		encrypting: b[32:64],
+		iv:         b[64:],
+	}
+}
+
+func nonce() ([]byte, error) {
+	b := make([]byte, nonceSize)
+	_, err := io.ReadFull(rand.Reader, b)
+	return b, err
+}
+
+func sign(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	sum := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+}
+
+func verify(key *rsa.PublicKey, data []byte, sig []byte) error {
+	sum := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig)
+}
+
+// encryptOAEP encrypts the data in blocks with RSA-OAEP, with SHA1
+func encryptOAEP(key *rsa.PublicKey, data []byte) ([]byte, error) {
+	block := (key.N.BitLen()+7)/8 - 2*sha1.Size - 2
+	var out []byte
+	for len(data) > 0 {
+		n := block
+		if n > len(data) {
+			n = len(data)
+		}
+		b, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, key, data[:n], nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+		data = data[n:]
+	}
+	return out, nil
+}
+
+func decryptOAEP(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	block := (key.N.BitLen() + 7) / 8
+	if len(data)%block != 0 {
+		return nil, errors.New("invalid size of the encrypted message")
+	}
+	var out []byte
+	for i := 0; i < len(data); i += block {
+		b, err := rsa.DecryptOAEP(sha1.New(), nil, key, data[i:i+block], nil)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b...)
+	}
+	return out, nil
+}
+
+// publicKey returns the RSA key of the certificate, the first of a chain
+func publicKey(cert []byte) (*rsa.PublicKey, error) {
+	certs, err := x509.ParseCertificates(cert)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate")
+	}
+	key, ok := certs[0].PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("certificate without RSA key")
+	}
+	return key, nil
+}
+
+// firstCertificate returns the first certificate of the chain
+func firstCertificate(chain []byte) []byte {
+	certs, err := x509.ParseCertificates(chain)
+	if err != nil || len(certs) == 0 {
+		return chain
+	}
+	return certs[0].Raw
+}
+
+// secureChannel is an OPC UA secure conversation on a TCP connection, the
+// messages being signed, and encrypted, with the keys of the side sending
+// them. The same channel is used by the clients and by the servers of the
+// tests.
+type secureChannel struct {
+	conn   net.Conn
+	policy string
+	mode   int32
+
+	localCert  []byte
+	localKey   *rsa.PrivateKey
+	remoteCert []byte
+	remoteKey  *rsa.PublicKey
+
+	channelID  uint32
+	tokenID    uint32
+	localNonce []byte
+	localKeys  *symmetricKeys
+	remoteKeys *symmetricKeys
+
+	sequence uint32
+	// sendSize is the size of the chunks sent, the size of the receive
+	// buffer of the remote
+	sendSize int
+}
+
+func (c *secureChannel) secure() bool {
+	return c.policy == policyBasic256Sha256
+}
+
+// deriveKeys sets the keys of the messages from the nonce of the remote
+func (c *secureChannel) deriveKeys(remoteNonce []byte) {
+	c.localKeys = deriveKeys(remoteNonce, c.localNonce)
+	c.remoteKeys = deriveKeys(c.localNonce, remoteNonce)
+}
+
+func (c *secureChannel) writeChunk(typ string, chunk byte, body []byte) error {
+	b := make([]byte, 8, 8+len(body))
+	copy(b, typ)
+	b[3] = chunk
+	binary.LittleEndian.PutUint32(b[4:], uint32(8+len(body)))
+	_, err := c.conn.Write(append(b, body...))
+	return err
+}
+
+// readChunk returns the type of the chunk read, whether it is final, and
+// its bytes, header included
+func (c *secureChannel) readChunk() (string, byte, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return "", 0, nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < 8 || size > bufferSize {
+		return "", 0, nil, fmt.Errorf("invalid size %d of the chunk", size)
+	}
+	raw := make([]byte, size)
+	copy(raw, header)
+	if _, err := io.ReadFull(c.conn, raw[8:]); err != nil {
+		return "", 0, nil, err
+	}
+	return string(header[:3]), header[3], raw, nil
+}
+
+// errorMessage returns the error of the ERR messages and aborted chunks
+func errorMessage(body []byte) error {
+	d := &decoder{b: body}
+	status := StatusCode(d.uint32())
+	reason := d.string()
+	if d.err != nil {
+		return d.err
+	}
+	if reason == "" {
+		return status
+	}
+	return fmt.Errorf("%s: %s", status, reason)
+}
+
+// hello exchanges the sizes of the buffers with the server of the endpoint
+func (c *secureChannel) hello(endpoint string) error {
+	e := &encoder{}
+	e.uint32(0)
+	e.uint32(bufferSize)
+	e.uint32(bufferSize)
+	e.uint32(maxMessageSize)
+	e.uint32(0)
+	e.string(endpoint)
+	if err := c.writeChunk("HEL", 'F', e.buf); err != nil {
+		return err
+	}
+	typ, _, raw, err := c.readChunk()
+	if err != nil {
+		return err
+	}
+	switch typ {
+	case "ERR":
+		return errorMessage(raw[8:])
+	case "ACK":
+	default:
+		return fmt.Errorf("unexpected %s message", typ)
+	}
+	d := &decoder{b: raw[8:]}
+	d.uint32()
+	receiveSize := int(d.uint32())
+	if d.err != nil {
+		return d.err
+	}
+	c.sendSize = bufferSize
+	if receiveSize < c.sendSize {
+		c.sendSize = receiveSize
+	}
+	if c.sendSize < 8192 {
+		return fmt.Errorf("invalid receive buffer size %d", receiveSize)
+	}
+	return nil
+}
+
+// send sends the message, the OPN messages with the asymmetric algorithms
+// of the policy, the others with the symmetric ones, in chunks
+func (c *secureChannel) send(typ string, requestID uint32, body []byte) error {
+	if typ == "OPN" {
+		return c.sendAsymmetric(requestID, body)
+	}
+
+	maxBody := c.sendSize - 24
+	if c.secure() && c.mode != modeNone {
+		maxBody -= sha256.Size
+	}
+	if c.secure() && c.mode == modeSignAndEncrypt {
+		maxBody -= maxBody%aes.BlockSize + aes.BlockSize
+	}
+	for {
+		part := body
+		chunk := byte('F')
+		if len(part) > maxBody {
+			part = body[:maxBody]
+			chunk = 'C'
+		}
+		body = body[len(part):]
+		if err := c.sendSymmetric(typ, chunk, requestID, part); err != nil {
+			return err
+		}
+		if chunk == 'F' {
+			return nil
+		}
+	}
+}
+
+func (c *secureChannel) sendAsymmetric(requestID uint32, body []byte) error {
+	e := &encoder{buf: []byte("OPNF\x00\x00\x00\x00")}
+	e.uint32(c.channelID)
+	e.string(c.policy)
+	if c.secure() {
+		thumbprint := sha1.Sum(c.remoteCert)
+		e.bytes(c.localCert)
+		e.bytes(thumbprint[:])
+	} else {
+		e.bytes(nil)
+		e.bytes(nil)
+	}
+	headerSize := len(e.buf)
+	c.sequence++
+	e.uint32(c.sequence)
+	e.uint32(requestID)
+	e.buf = append(e.buf, body...)
+	if !c.secure() {
+		binary.LittleEndian.PutUint32(e.buf[4:], uint32(len(e.buf)))
+		_, err := c.conn.Write(e.buf)
+		return err
+	}
+
+	// The padding completes the blocks of the encryption, its size being
+	// on 2 bytes with the keys larger than 2048 bits
+	keySize := (c.remoteKey.N.BitLen() + 7) / 8
+	plainBlock := keySize - 2*sha1.Size - 2
+	sigSize := (c.localKey.N.BitLen() + 7) / 8
+	paddingSize := 1
+	if keySize > 256 {
+		paddingSize = 2
+	}
+	n := len(e.buf) - headerSize + paddingSize + sigSize
+	padding := (plainBlock - n%plainBlock) % plainBlock
+	for i := 0; i <= padding; i++ {
+		e.uint8(byte(padding))
+	}
+	if paddingSize == 2 {
+		e.uint8(byte(padding >> 8))
+	}
+	size := headerSize + (len(e.buf)-headerSize+sigSize)/plainBlock*keySize
+	binary.LittleEndian.PutUint32(e.buf[4:], uint32(size))
+
+	sig, err := sign(c.localKey, e.buf)
+	if err != nil {
+		return err
+	}
+	plain := append(append([]byte{}, e.buf[headerSize:]...), sig...)
+	encrypted, err := encryptOAEP(c.remoteKey, plain)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(append(e.buf[:headerSize], encrypted...))
+	return err
+}
+
+func (c *secureChannel) sendSymmetric(typ string, chunk byte, requestID uint32,
+	body []byte) error {
+	e := &encoder{buf: []byte(typ + string(chunk) + "\x00\x00\x00\x00")}
+	e.uint32(c.channelID)
+	e.uint32(c.tokenID)
+	c.sequence++
+	e.uint32(c.sequence)
+	e.uint32(requestID)
+	e.buf = append(e.buf, body...)
+	if !c.secure() || c.mode == modeNone {
+		binary.LittleEndian.PutUint32(e.buf[4:], uint32(len(e.buf)))
+		_, err := c.conn.Write(e.buf)
+		return err
+	}
+
+	encrypt := c.mode == modeSignAndEncrypt
+	if encrypt {
+		n := len(e.buf) - 16 + 1 + sha256.Size
+		padding := (aes.BlockSize - n%aes.BlockSize) % aes.BlockSize
+		for i := 0; i <= padding; i++ {
+			e.uint8(byte(padding))
+		}
+	}
+	binary.LittleEndian.PutUint32(e.buf[4:], uint32(len(e.buf)+sha256.Size))
+	mac := hmac.New(sha256.New, c.localKeys.signing)
+	mac.Write(e.buf)
+	b := mac.Sum(e.buf)
+	if encrypt {
+		block, err := aes.NewCipher(c.localKeys.encrypting)
+		if err != nil {
+			return err
+		}
+		cipher.NewCBCEncrypter(block, c.localKeys.iv).CryptBlocks(b[16:],
+			b[16:])
+	}
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// receive returns the type, request id and body of the next message, its
+// chunks reassembled
+func (c *secureChannel) receive() (string, uint32, []byte, error) {
+	var msg []byte
+	for {
+		typ, chunk, raw, err := c.readChunk()
+		if err != nil {
+			return "", 0, nil, err
+		}
+		var requestID uint32
+		var body []byte
+		switch typ {
+		case "ERR":
+			return "", 0, nil, errorMessage(raw[8:])
+		case "OPN":
+			requestID, body, err = c.openAsymmetric(raw)
+		case "MSG", "CLO":
+			requestID, body, err = c.openSymmetric(raw)
+		default:
+			err = fmt.Errorf("unexpected %s message", typ)
+		}
+		if err != nil {
+			return "", 0, nil, err
+		}
+		switch chunk {
+		case 'A':
+			return "", 0, nil, errorMessage(body)
+		case 'C':
+			msg = append(msg, body...)
+			if len(msg) > maxMessageSize {
+				return "", 0, nil, errors.New("message too large")
+			}
+		default:
+			return typ, requestID, append(msg, body...), nil
+		}
+	}
+}
+
+// sequenceHeader returns the request id of the chunk and its body
+func sequenceHeader(b []byte) (uint32, []byte, error) {
+	if len(b) < 8 {
+		return 0, nil, errShort
+	}
+	return binary.LittleEndian.Uint32(b[4:]), b[8:], nil
+}
+
+// openAsymmetric decrypts and verifies the OPN chunks, the certificate of
+// the remote being learnt from the first one received by the servers
+func (c *secureChannel) openAsymmetric(raw []byte) (uint32, []byte, error) {
+	d := &decoder{b: raw[8:]}
+	d.uint32()
+	policy := d.string()
+	cert := d.bytes()
+	d.bytes()
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	if c.policy == "" {
+		c.policy = policy
+	}
+	if policy != c.policy {
+		return 0, nil, fmt.Errorf("unexpected security policy %s", policy)
+	}
+	if !c.secure() {
+		return sequenceHeader(d.b)
+	}
+
+	if c.remoteCert == nil {
+		key, err := publicKey(cert)
+		if err != nil {
+			return 0, nil, err
+		}
+		c.remoteCert = cert
+		c.remoteKey = key
+	} else if !bytes.Equal(cert, c.remoteCert) {
+		return 0, nil, errors.New("unexpected certificate of the remote")
+	}
+	headerSize := len(raw) - len(d.b)
+	plain, err := decryptOAEP(c.localKey, d.b)
+	if err != nil {
+		return 0, nil, err
+	}
+	sigSize := (c.remoteKey.N.BitLen() + 7) / 8
+	if len(plain) < sigSize+1 {
+		return 0, nil, errShort
+	}
+	signed := append(append([]byte{}, raw[:headerSize]...),
+		plain[:len(plain)-sigSize]...)
+	if err := verify(c.remoteKey, signed,
+		plain[len(plain)-sigSize:]); err != nil {
+		return 0, nil, errors.New("invalid signature of the message")
+	}
+
+	p := plain[:len(plain)-sigSize]
+	var padding int
+	if (c.localKey.N.BitLen()+7)/8 > 256 {
+		if len(p) < 2 {
+			return 0, nil, errShort
+		}
+		padding = int(p[len(p)-1])<<8 | int(p[len(p)-2]) + 2
+	} else {
+		padding = int(p[len(p)-1]) + 1
+	}
+	if padding > len(p) {
+		return 0, nil, errors.New("invalid padding of the message")
+	}
+	return sequenceHeader(p[:len(p)-padding])
+}
+
+// openSymmetric verifies and decrypts the MSG and CLO chunks
+func (c *secureChannel) openSymmetric(raw []byte) (uint32, []byte, error) {
+	if len(raw) < 16 {
+		return 0, nil, errShort
+	}
+	if id := binary.LittleEndian.Uint32(raw[8:]); id != c.channelID {
+		return 0, nil, fmt.Errorf("unexpected secure channel %d", id)
+	}
+	if id := binary.LittleEndian.Uint32(raw[12:]); id != c.tokenID {
+		return 0, nil, fmt.Errorf("unexpected security token %d", id)
+	}
+	if !c.secure() || c.mode == modeNone {
+		return sequenceHeader(raw[16:])
+	}
+
+	encrypted := c.mode == modeSignAndEncrypt
+	if encrypted {
+		if (len(raw)-16)%aes.BlockSize != 0 {
+			return 0, nil, errors.New("invalid size of the encrypted message")
+		}
+		block, err := aes.NewCipher(c.remoteKeys.encrypting)
+		if err != nil {
+			return 0, nil, err
+		}
+		cipher.NewCBCDecrypter(block, c.remoteKeys.iv).CryptBlocks(raw[16:],
+			raw[16:])
+	}
+	if len(raw) < 16+sha256.Size {
+		return 0, nil, errShort
+	}
+	n := len(raw) - sha256.Size
+	mac := hmac.New(sha256.New, c.remoteKeys.signing)
+	mac.Write(raw[:n])
+	if !hmac.Equal(mac.Sum(nil), raw[n:]) {
+		return 0, nil, errors.New("invalid signature of the message")
+	}
+	p := raw[16:n]
+	if encrypted {
+		if len(p) == 0 || int(p[len(p)-1])+1 > len(p) {
+			return 0, nil, errors.New("invalid padding of the message")
+		}
+		p = p[:len(p)-int(p[len(p)-1])-1]
+	}
+	return sequenceHeader(p)
+}
@@ -0,0 +1,451 @@
+package opcua
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// errAborted is returned once the client is aborted
+var errAborted = errors.New("client aborted")
+
+// client is a session on an OPC UA server, on a secure channel
+type client struct {
+	endpoint string
+	policy   string
+	mode     int32
+
+	// Certificate and key of the client, the certificate of the server
+	// being checked against serverCert if set
+	applicationURI string
+	cert           []byte
+	key            *rsa.PrivateKey
+	serverCert     []byte
+
+	// tokenType is the type of the user identity token, anonymous or the
+	// username and password
+	tokenType int32
+	username  string
+	password  string
+
+	connectTimeout time.Duration
+	requestTimeout time.Duration
+
+	channel   *secureChannel
+	requestID uint32
+	authToken NodeID
+	// expires is when the security token of the channel is to be renewed,
+	// the channel and the session being opened again
+	expires time.Time
+
+	// conn is the connection of the channel, closed by abort
+	mu      sync.Mutex
+	conn    net.Conn
+	aborted bool
+}
+
+// abort closes the connection, interrupting the requests in progress
+func (c *client) abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.aborted = true
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// dial opens a secure channel to the endpoint
+func (c *client) dial(policy string, mode int32, serverCert []byte) error {
+	u, err := url.Parse(c.endpoint)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "opc.tcp" {
+		return fmt.Errorf("invalid endpoint %q, expected opc.tcp://", c.endpoint)
+	}
+	host := u.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "4840")
+	}
+	conn, err := net.DialTimeout("tcp", host, c.connectTimeout)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.aborted {
+		c.mu.Unlock()
+		conn.Close()
+		return errAborted
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	c.channel = &secureChannel{
+		conn:       conn,
+		policy:     policy,
+		mode:       mode,
+		localCert:  c.cert,
+		localKey:   c.key,
+		remoteCert: serverCert,
+	}
+	if c.channel.secure() {
+		if c.channel.remoteKey, err = publicKey(serverCert); err != nil {
+			c.closeChannel()
+			return err
+		}
+	}
+	conn.SetDeadline(time.Now().Add(c.connectTimeout))
+	if err := c.channel.hello(c.endpoint); err != nil {
+		c.closeChannel()
+		return err
+	}
+
+	req := &openSecureChannelRequest{
+		SecurityMode:      mode,
+		RequestedLifetime: uint32(time.Hour / time.Millisecond),
+	}
+	if c.channel.secure() {
+		if c.channel.localNonce, err = nonce(); err != nil {
+			c.closeChannel()
+			return err
+		}
+		req.ClientNonce = c.channel.localNonce
+	}
+	var resp openSecureChannelResponse
+	if err := c.call(req, &resp, c.connectTimeout); err != nil {
+		c.closeChannel()
+		return err
+	}
+	c.channel.channelID = resp.SecurityToken.ChannelID
+	c.channel.tokenID = resp.SecurityToken.TokenID
+	if c.channel.secure() {
+		if len(resp.ServerNonce) != nonceSize {
+			c.closeChannel()
+			return errors.New("invalid nonce of the server")
+		}
+		c.channel.deriveKeys(resp.ServerNonce)
+	}
+	c.expires = time.Now().Add(time.Duration(resp.SecurityToken.RevisedLifetime) *
+		time.Millisecond * 3 / 4)
+	return nil
+}
+
+// closeChannel closes the secure channel, and its connection
+func (c *client) closeChannel() {
+	if c.channel == nil {
+		return
+	}
+	if c.channel.channelID != 0 {
+		c.call(&closeSecureChannelRequest{}, nil, c.requestTimeout)
+	}
+	c.channel.conn.Close()
+	c.channel = nil
+}
+
+// call sends the request and decodes its response into resp, the service
+// faults and the bad service results being returned as errors
+func (c *client) call(req interface{}, resp interface{},
+	timeout time.Duration) error {
+	header := reflect.ValueOf(req).Elem().Field(0).Addr().Interface().(*requestHeader)
+	c.requestID++
+	header.AuthenticationToken = c.authToken
+	header.Timestamp = time.Now()
+	header.RequestHandle = c.requestID
+	header.TimeoutHint = uint32(timeout / time.Millisecond)
+	b, err := encodeMessage(req)
+	if err != nil {
+		return err
+	}
+
+	typ := "MSG"
+	switch req.(type) {
+	case *openSecureChannelRequest:
+		typ = "OPN"
+	case *closeSecureChannelRequest:
+		typ = "CLO"
+	}
+	c.channel.conn.SetDeadline(time.Now().Add(timeout))
+	if err := c.channel.send(typ, c.requestID, b); err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+
+	for {
+		_, requestID, body, err := c.channel.receive()
+		if err != nil {
+			return err
+		}
+		// The responses of the requests timed out are skipped
+		if requestID != c.requestID {
+			continue
+		}
+		msg, err := decodeMessage(body)
+		if err != nil {
+			return err
+		}
+		if fault, ok := msg.(*serviceFault); ok {
+			return fault.Header.ServiceResult
+		}
+		v := reflect.ValueOf(msg)
+		if v.Type() != reflect.TypeOf(resp) {
+			return fmt.Errorf("unexpected response %s", v.Elem().Type().Name())
+		}
+		reflect.ValueOf(resp).Elem().Set(v.Elem())
+		result := v.Elem().Field(0).Interface().(responseHeader).ServiceResult
+		if result.Quality() == "Bad" {
+			return result
+		}
+		return nil
+	}
+}
+
+// connect opens the session, on the endpoint of the server with the
+// security policy and mode of the client, its certificate and the policies
+// of the user identity tokens being discovered on an unsecured channel
+func (c *client) connect() error {
+	if err := c.dial(policyNone, modeNone, nil); err != nil {
+		return err
+	}
+	var endpoints getEndpointsResponse
+	err := c.call(&getEndpointsRequest{EndpointURL: c.endpoint}, &endpoints,
+		c.requestTimeout)
+	c.closeChannel()
+	if err != nil {
+		return fmt.Errorf("getting the endpoints: %s", err)
+	}
+
+	var endpoint *endpointDescription
+	for i, e := range endpoints.Endpoints {
+		if e.SecurityPolicyURI == c.policy && e.SecurityMode == c.mode {
+			endpoint = &endpoints.Endpoints[i]
+			break
+		}
+	}
+	if endpoint == nil {
+		return fmt.Errorf("no endpoint with the security policy %s and mode %d",
+			c.policy, c.mode)
+	}
+	var token *userTokenPolicy
+	for i, t := range endpoint.UserIdentityTokens {
+		if t.TokenType == c.tokenType {
+			token = &endpoint.UserIdentityTokens[i]
+			break
+		}
+	}
+	if token == nil {
+		return errors.New("authentication method not supported by the endpoint")
+	}
+	serverCert := firstCertificate(endpoint.ServerCertificate)
+	if c.serverCert != nil && !bytes.Equal(serverCert, c.serverCert) {
+		return errors.New("certificate of the server not matching " +
+			"server_certificate")
+	}
+
+	if err := c.dial(c.policy, c.mode, serverCert); err != nil {
+		return err
+	}
+	if err := c.createSession(serverCert, token); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+// createSession creates and activates the session
+func (c *client) createSession(serverCert []byte, token *userTokenPolicy) error {
+	req := &createSessionRequest{
+		ClientDescription: applicationDescription{
+			ApplicationURI:  c.applicationURI,
+			ProductURI:      "urn:influxdata:telegraf",
+			ApplicationName: LocalizedText{Text: "Telegraf"},
+			ApplicationType: 1,
+		},
+		EndpointURL:             c.endpoint,
+		SessionName:             "telegraf",
+		RequestedSessionTimeout: float64(time.Hour / time.Millisecond),
+	}
+	secure := c.channel.secure()
+	if secure {
+		n, err := nonce()
+		if err != nil {
+			return err
+		}
+		req.ClientNonce = n
+		req.ClientCertificate = c.cert
+	}
+	var session createSessionResponse
+	if err := c.call(req, &session, c.requestTimeout); err != nil {
+		return fmt.Errorf("creating the session: %s", err)
+	}
+	if secure {
+		if err := verify(c.channel.remoteKey,
+			append(append([]byte{}, c.cert...), req.ClientNonce...),
+			session.ServerSignature.Signature); err != nil {
+			return errors.New("invalid signature of the server")
+		}
+	}
+	c.authToken = session.AuthenticationToken
+
+	activate := &activateSessionRequest{}
+	if secure {
+		sig, err := sign(c.key, append(append([]byte{},
+			session.ServerCertificate...), session.ServerNonce...))
+		if err != nil {
+			return err
+		}
+		activate.ClientSignature = signatureData{
+			Algorithm: algorithmRsaSha256,
+			Signature: sig,
+		}
+	}
+	switch token.TokenType {
+	case tokenAnonymous:
+		activate.UserIdentityToken.Value = &anonymousIdentityToken{
+			PolicyID: token.PolicyID,
+		}
+	case tokenUserName:
+		identity := &userNameIdentityToken{
+			PolicyID: token.PolicyID,
+			UserName: c.username,
+			Password: []byte(c.password),
+		}
+		// The password is encrypted with the security policy of the token,
+		// or of the channel if the token has none
+		policy := token.SecurityPolicyURI
+		if policy == "" {
+			policy = c.policy
+		}
+		switch policy {
+		case policyNone:
+		case policyBasic256Sha256:
+			key, err := publicKey(serverCert)
+			if err != nil {
+				return err
+			}
+			e := &encoder{}
+			e.uint32(uint32(len(c.password) + len(session.ServerNonce)))
+			e.buf = append(e.buf, c.password...)
+			e.buf = append(e.buf, session.ServerNonce...)
+			if identity.Password, err = encryptOAEP(key, e.buf); err != nil {
+				return err
+			}
+			identity.EncryptionAlgorithm = algorithmRsaOaep
+		default:
+			return fmt.Errorf("security policy %s of the user token not "+
+				"supported", policy)
+		}
+		activate.UserIdentityToken.Value = identity
+	}
+	var resp activateSessionResponse
+	if err := c.call(activate, &resp, c.requestTimeout); err != nil {
+		return fmt.Errorf("activating the session: %s", err)
+	}
+	return nil
+}
+
+// close closes the session and the channel
+func (c *client) close() {
+	if c.channel == nil {
+		return
+	}
+	if c.authToken != (NodeID{}) {
+		c.call(&closeSessionRequest{DeleteSubscriptions: true},
+			&closeSessionResponse{}, c.requestTimeout)
+		c.authToken = NodeID{}
+	}
+	c.closeChannel()
+}
+
+// read reads the values of the nodes
+func (c *client) read(ids []NodeID) ([]DataValue, error) {
+	req := &readRequest{TimestampsToReturn: timestampsBoth}
+	for _, id := range ids {
+		req.NodesToRead = append(req.NodesToRead, readValueID{
+			NodeID:      id,
+			AttributeID: attributeValue,
+		})
+	}
+	var resp readResponse
+	if err := c.call(req, &resp, c.requestTimeout); err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(ids) {
+		return nil, fmt.Errorf("%d values read, expected %d",
+			len(resp.Results), len(ids))
+	}
+	return resp.Results, nil
+}
+
+// subscribe creates a subscription monitoring the nodes, their index being
+// the client handle of their notifications, and returns the interval of
+// its keep alives and the results of the monitoring of the nodes
+func (c *client) subscribe(ids []NodeID,
+	interval time.Duration) (time.Duration, []StatusCode, error) {
+	ms := float64(interval / time.Millisecond)
+	var sub createSubscriptionResponse
+	err := c.call(&createSubscriptionRequest{
+		RequestedPublishingInterval: ms,
+		RequestedLifetimeCount:      60,
+		RequestedMaxKeepAliveCount:  10,
+		PublishingEnabled:           true,
+	}, &sub, c.requestTimeout)
+	if err != nil {
+		return 0, nil, fmt.Errorf("creating the subscription: %s", err)
+	}
+
+	req := &createMonitoredItemsRequest{
+		SubscriptionID:     sub.SubscriptionID,
+		TimestampsToReturn: timestampsBoth,
+	}
+	for i, id := range ids {
+		req.ItemsToCreate = append(req.ItemsToCreate, monitoredItemCreateRequest{
+			ItemToMonitor: readValueID{
+				NodeID:      id,
+				AttributeID: attributeValue,
+			},
+			MonitoringMode: monitoringReporting,
+			RequestedParameters: monitoringParameters{
+				ClientHandle:     uint32(i),
+				SamplingInterval: ms,
+				QueueSize:        1,
+				DiscardOldest:    true,
+			},
+		})
+	}
+	var items createMonitoredItemsResponse
+	if err := c.call(req, &items, c.requestTimeout); err != nil {
+		return 0, nil, fmt.Errorf("monitoring the nodes: %s", err)
+	}
+	if len(items.Results) != len(ids) {
+		return 0, nil, fmt.Errorf("%d nodes monitored, expected %d",
+			len(items.Results), len(ids))
+	}
+	results := make([]StatusCode, len(ids))
+	for i, r := range items.Results {
+		results[i] = r.StatusCode
+	}
+	keepAlive := time.Duration(sub.RevisedPublishingInterval*
+		float64(sub.RevisedMaxKeepAliveCount)) * time.Millisecond
+	return keepAlive, results, nil
+}
+
+// publish acknowledges the notifications received and waits for the next
+// ones, or a keep alive
+func (c *client) publish(acks []subscriptionAcknowledgement,
+	timeout time.Duration) (*publishResponse, error) {
+	var resp publishResponse
+	err := c.call(&publishRequest{SubscriptionAcknowledgements: acks}, &resp,
+		timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,978 @@
+package opcua
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The OPC UA binary encoding of the messages, little endian, the strings and
+// arrays being prefixed by their length, -1 if null. The structures are
+// encoded field by field, in order.
+
+// StatusCode is the result of an operation, its two most significant bits
+// being its severity
+type StatusCode uint32
+
+// statusCodes are the names of the status codes commonly returned
+var statusCodes = map[StatusCode]string{
+	0x00000000: "Good",
+	0x80010000: "BadUnexpectedError",
+	0x80020000: "BadInternalError",
+	0x80050000: "BadCommunicationError",
+	0x80060000: "BadEncodingError",
+	0x80070000: "BadDecodingError",
+	0x800A0000: "BadTimeout",
+	0x800B0000: "BadServiceUnsupported",
+	0x800D0000: "BadServerNotConnected",
+	0x800E0000: "BadServerHalted",
+	0x80100000: "BadTooManyOperations",
+	0x80120000: "BadCertificateInvalid",
+	0x80130000: "BadSecurityChecksFailed",
+	0x801A0000: "BadCertificateUntrusted",
+	0x801F0000: "BadUserAccessDenied",
+	0x80200000: "BadIdentityTokenInvalid",
+	0x80210000: "BadIdentityTokenRejected",
+	0x80220000: "BadSecureChannelIdInvalid",
+	0x80250000: "BadSessionIdInvalid",
+	0x80260000: "BadSessionClosed",
+	0x80270000: "BadSessionNotActivated",
+	0x80280000: "BadSubscriptionIdInvalid",
+	0x80320000: "BadWaitingForInitialData",
+	0x80330000: "BadNodeIdInvalid",
+	0x80340000: "BadNodeIdUnknown",
+	0x80350000: "BadAttributeIdInvalid",
+	0x803A0000: "BadNotReadable",
+	0x80550000: "BadSecurityPolicyRejected",
+	0x80560000: "BadTooManySessions",
+	0x80790000: "BadNoSubscription",
+	0x80830000: "BadTcpEndpointUrlInvalid",
+	0x80860000: "BadSecureChannelClosed",
+}
+
+func (s StatusCode) Error() string {
+	if name, ok := statusCodes[s]; ok {
+		return fmt.Sprintf("%s (0x%08X)", name, uint32(s))
+	}
+	return fmt.Sprintf("0x%08X", uint32(s))
+}
+
+// Quality returns the severity of the status code, Good, Uncertain or Bad
+func (s StatusCode) Quality() string {
+	switch s >> 30 {
+	case 0:
+		return "Good"
+	case 1:
+		return "Uncertain"
+	}
+	return "Bad"
+}
+
+// Guid is a globally unique identifier
+type Guid struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+func (g Guid) String() string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X", g.Data1, g.Data2, g.Data3,
+		g.Data4[:2], g.Data4[2:])
+}
+
+func parseGuid(s string) (Guid, error) {
+	var g Guid
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(b) != 16 || len(s) != 36 {
+		return g, fmt.Errorf("invalid guid %q", s)
+	}
+	g.Data1 = binary.BigEndian.Uint32(b[0:])
+	g.Data2 = binary.BigEndian.Uint16(b[4:])
+	g.Data3 = binary.BigEndian.Uint16(b[6:])
+	copy(g.Data4[:], b[8:])
+	return g, nil
+}
+
+// NodeID is the id of a node in its namespace, numeric, a string, a guid or
+// opaque bytes
+type NodeID struct {
+	Namespace uint16
+	// Type is the type of the identifier, i, s, g or b
+	Type    byte
+	Numeric uint32
+	// Value is the identifier of the string, guid and opaque node ids, the
+	// opaque bytes being kept as is
+	Value string
+}
+
+// ParseNodeID parses the node ids in their string format, such as
+// ns=2;s=Temperature, i=2258, ns=1;g=72962B91-FA75-4AE6-8D28-B404DC7DAF63
+// or ns=1;b=M/RbKBsRVkePCePcx24oRA==
+func ParseNodeID(s string) (NodeID, error) {
+	id := NodeID{}
+	rest := s
+	if strings.HasPrefix(rest, "ns=") {
+		i := strings.Index(rest, ";")
+		if i < 0 {
+			return id, fmt.Errorf("invalid node id %q", s)
+		}
+		ns, err := strconv.ParseUint(rest[3:i], 10, 16)
+		if err != nil {
+			return id, fmt.Errorf("invalid namespace of node id %q", s)
+		}
+		id.Namespace = uint16(ns)
+		rest = rest[i+1:]
+	}
+	if len(rest) < 2 || rest[1] != '=' {
+		return id, fmt.Errorf("invalid node id %q", s)
+	}
+	id.Type = rest[0]
+	value := rest[2:]
+	switch id.Type {
+	case 'i':
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return id, fmt.Errorf("invalid numeric node id %q", s)
+		}
+		id.Numeric = uint32(n)
+	case 's':
+		id.Value = value
+	case 'g':
+		g, err := parseGuid(value)
+		if err != nil {
+			return id, err
+		}
+		id.Value = g.String()
+	case 'b':
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return id, fmt.Errorf("invalid opaque node id %q", s)
+		}
+		id.Value = string(b)
+	default:
+		return id, fmt.Errorf("invalid type of node id %q", s)
+	}
+	return id, nil
+}
+
+func (id NodeID) String() string {
+	var s string
+	switch id.Type {
+	case 's', 'g':
+		s = string(id.Type) + "=" + id.Value
+	case 'b':
+		s = "b=" + base64.StdEncoding.EncodeToString([]byte(id.Value))
+	default:
+		s = "i=" + strconv.FormatUint(uint64(id.Numeric), 10)
+	}
+	if id.Namespace != 0 {
+		s = "ns=" + strconv.Itoa(int(id.Namespace)) + ";" + s
+	}
+	return s
+}
+
+// numericID is the node id of ns 0
+func numericID(id uint32) NodeID {
+	return NodeID{Type: 'i', Numeric: id}
+}
+
+// LocalizedText is a text in a locale
+type LocalizedText struct {
+	Locale string
+	Text   string
+}
+
+// QualifiedName is a name in a namespace
+type QualifiedName struct {
+	NamespaceIndex uint16
+	Name           string
+}
+
+// ExtensionObject is a structure of the type of its encoding id, decoded if
+// the type is known, its bytes otherwise
+type ExtensionObject struct {
+	TypeID NodeID
+	Value  interface{}
+}
+
+// DataValue is the value of an attribute, with its status and timestamps
+type DataValue struct {
+	Value           interface{}
+	Status          StatusCode
+	SourceTimestamp time.Time
+	ServerTimestamp time.Time
+}
+
+// DiagnosticInfo are the diagnostics of the operations, never requested, and
+// skipped when decoded
+type DiagnosticInfo struct{}
+
+// The builtin types of the variants
+const (
+	typeBoolean       = 1
+	typeSByte         = 2
+	typeByte          = 3
+	typeInt16         = 4
+	typeUInt16        = 5
+	typeInt32         = 6
+	typeUInt32        = 7
+	typeInt64         = 8
+	typeUInt64        = 9
+	typeFloat         = 10
+	typeDouble        = 11
+	typeString        = 12
+	typeDateTime      = 13
+	typeGuid          = 14
+	typeByteString    = 15
+	typeXMLElement    = 16
+	typeNodeID        = 17
+	typeExpandedNode  = 18
+	typeStatusCode    = 19
+	typeQualifiedName = 20
+	typeLocalizedText = 21
+	typeExtension     = 22
+	typeDataValue     = 23
+	typeVariant       = 24
+	typeDiagnostic    = 25
+)
+
+// epoch is the origin of the DateTime, the number of 100 nanosecond
+// intervals since January 1, 1601 UTC
+var epoch = time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// encodings are the encoding ids of the structures of the messages and of
+// the extension objects, and types the structures of the encoding ids
+var (
+	encodings = make(map[reflect.Type]uint32)
+	types     = make(map[uint32]reflect.Type)
+)
+
+func register(id uint32, v interface{}) {
+	t := reflect.TypeOf(v)
+	encodings[t] = id
+	types[id] = t
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	nodeIDType        = reflect.TypeOf(NodeID{})
+	guidType          = reflect.TypeOf(Guid{})
+	localizedTextType = reflect.TypeOf(LocalizedText{})
+	extensionType     = reflect.TypeOf(ExtensionObject{})
+	dataValueType     = reflect.TypeOf(DataValue{})
+	diagnosticType    = reflect.TypeOf(DiagnosticInfo{})
+	bytesType         = reflect.TypeOf([]byte(nil))
+)
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.buf = append(e.buf, byte(v), byte(v>>8))
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = append(e.buf, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.uint32(uint32(v))
+	e.uint32(uint32(v >> 32))
+}
+
+func (e *encoder) bytes(b []byte) {
+	if b == nil {
+		e.uint32(math.MaxUint32)
+		return
+	}
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// string encodes the empty strings as null
+func (e *encoder) string(s string) {
+	if s == "" {
+		e.bytes(nil)
+		return
+	}
+	e.bytes([]byte(s))
+}
+
+func (e *encoder) dateTime(t time.Time) {
+	if t.IsZero() {
+		e.uint64(0)
+		return
+	}
+	e.uint64(uint64((t.Unix()-epoch)*1e7 + int64(t.Nanosecond()/100)))
+}
+
+func (e *encoder) guid(g Guid) {
+	e.uint32(g.Data1)
+	e.uint16(g.Data2)
+	e.uint16(g.Data3)
+	e.buf = append(e.buf, g.Data4[:]...)
+}
+
+func (e *encoder) nodeID(id NodeID) {
+	switch id.Type {
+	case 's':
+		e.uint8(0x03)
+		e.uint16(id.Namespace)
+		e.string(id.Value)
+	case 'g':
+		g, _ := parseGuid(id.Value)
+		e.uint8(0x04)
+		e.uint16(id.Namespace)
+		e.guid(g)
+	case 'b':
+		e.uint8(0x05)
+		e.uint16(id.Namespace)
+		e.bytes([]byte(id.Value))
+	default:
+		switch {
+		case id.Namespace == 0 && id.Numeric <= math.MaxUint8:
+			e.uint8(0x00)
+			e.uint8(uint8(id.Numeric))
+		case id.Namespace <= math.MaxUint8 && id.Numeric <= math.MaxUint16:
+			e.uint8(0x01)
+			e.uint8(uint8(id.Namespace))
+			e.uint16(uint16(id.Numeric))
+		default:
+			e.uint8(0x02)
+			e.uint16(id.Namespace)
+			e.uint32(id.Numeric)
+		}
+	}
+}
+
+func (e *encoder) localizedText(t LocalizedText) {
+	var mask uint8
+	if t.Locale != "" {
+		mask |= 0x01
+	}
+	if t.Text != "" {
+		mask |= 0x02
+	}
+	e.uint8(mask)
+	if t.Locale != "" {
+		e.string(t.Locale)
+	}
+	if t.Text != "" {
+		e.string(t.Text)
+	}
+}
+
+func (e *encoder) extensionObject(o ExtensionObject) error {
+	if o.Value == nil {
+		e.nodeID(o.TypeID)
+		e.uint8(0x00)
+		return nil
+	}
+	if b, ok := o.Value.([]byte); ok {
+		e.nodeID(o.TypeID)
+		e.uint8(0x01)
+		e.bytes(b)
+		return nil
+	}
+	v := reflect.Indirect(reflect.ValueOf(o.Value))
+	id, ok := encodings[v.Type()]
+	if !ok {
+		return fmt.Errorf("no encoding of %s", v.Type())
+	}
+	body := &encoder{}
+	if err := body.value(v); err != nil {
+		return err
+	}
+	e.nodeID(numericID(id))
+	e.uint8(0x01)
+	e.bytes(body.buf)
+	return nil
+}
+
+// variant encodes the scalar values
+func (e *encoder) variant(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.uint8(0)
+	case bool:
+		e.uint8(typeBoolean)
+		if v {
+			e.uint8(1)
+		} else {
+			e.uint8(0)
+		}
+	case int8:
+		e.uint8(typeSByte)
+		e.uint8(uint8(v))
+	case uint8:
+		e.uint8(typeByte)
+		e.uint8(v)
+	case int16:
+		e.uint8(typeInt16)
+		e.uint16(uint16(v))
+	case uint16:
+		e.uint8(typeUInt16)
+		e.uint16(v)
+	case int32:
+		e.uint8(typeInt32)
+		e.uint32(uint32(v))
+	case uint32:
+		e.uint8(typeUInt32)
+		e.uint32(v)
+	case int64:
+		e.uint8(typeInt64)
+		e.uint64(uint64(v))
+	case uint64:
+		e.uint8(typeUInt64)
+		e.uint64(v)
+	case float32:
+		e.uint8(typeFloat)
+		e.uint32(math.Float32bits(v))
+	case float64:
+		e.uint8(typeDouble)
+		e.uint64(math.Float64bits(v))
+	case string:
+		e.uint8(typeString)
+		e.string(v)
+	case time.Time:
+		e.uint8(typeDateTime)
+		e.dateTime(v)
+	case Guid:
+		e.uint8(typeGuid)
+		e.guid(v)
+	case []byte:
+		e.uint8(typeByteString)
+		e.bytes(v)
+	case NodeID:
+		e.uint8(typeNodeID)
+		e.nodeID(v)
+	case StatusCode:
+		e.uint8(typeStatusCode)
+		e.uint32(uint32(v))
+	case LocalizedText:
+		e.uint8(typeLocalizedText)
+		e.localizedText(v)
+	default:
+		return fmt.Errorf("no variant of %T", v)
+	}
+	return nil
+}
+
+func (e *encoder) dataValue(d DataValue) error {
+	var mask uint8
+	if d.Value != nil {
+		mask |= 0x01
+	}
+	if d.Status != 0 {
+		mask |= 0x02
+	}
+	if !d.SourceTimestamp.IsZero() {
+		mask |= 0x04
+	}
+	if !d.ServerTimestamp.IsZero() {
+		mask |= 0x08
+	}
+	e.uint8(mask)
+	if d.Value != nil {
+		if err := e.variant(d.Value); err != nil {
+			return err
+		}
+	}
+	if d.Status != 0 {
+		e.uint32(uint32(d.Status))
+	}
+	if !d.SourceTimestamp.IsZero() {
+		e.dateTime(d.SourceTimestamp)
+	}
+	if !d.ServerTimestamp.IsZero() {
+		e.dateTime(d.ServerTimestamp)
+	}
+	return nil
+}
+
+func (e *encoder) value(v reflect.Value) error {
+	switch v.Type() {
+	case timeType:
+		e.dateTime(v.Interface().(time.Time))
+		return nil
+	case nodeIDType:
+		e.nodeID(v.Interface().(NodeID))
+		return nil
+	case guidType:
+		e.guid(v.Interface().(Guid))
+		return nil
+	case localizedTextType:
+		e.localizedText(v.Interface().(LocalizedText))
+		return nil
+	case extensionType:
+		return e.extensionObject(v.Interface().(ExtensionObject))
+	case dataValueType:
+		return e.dataValue(v.Interface().(DataValue))
+	case diagnosticType:
+		e.uint8(0)
+		return nil
+	case bytesType:
+		e.bytes(v.Bytes())
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.uint8(1)
+		} else {
+			e.uint8(0)
+		}
+	case reflect.Int8:
+		e.uint8(uint8(v.Int()))
+	case reflect.Uint8:
+		e.uint8(uint8(v.Uint()))
+	case reflect.Int16:
+		e.uint16(uint16(v.Int()))
+	case reflect.Uint16:
+		e.uint16(uint16(v.Uint()))
+	case reflect.Int32:
+		e.uint32(uint32(v.Int()))
+	case reflect.Uint32:
+		e.uint32(uint32(v.Uint()))
+	case reflect.Int64:
+		e.uint64(uint64(v.Int()))
+	case reflect.Uint64:
+		e.uint64(v.Uint())
+	case reflect.Float32:
+		e.uint32(math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.uint64(math.Float64bits(v.Float()))
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.uint32(math.MaxUint32)
+			return nil
+		}
+		e.uint32(uint32(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.value(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if err := e.value(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		return e.value(v.Elem())
+	default:
+		return fmt.Errorf("no encoding of %s", v.Type())
+	}
+	return nil
+}
+
+// encodeMessage encodes the message prefixed by the node id of its encoding
+func encodeMessage(msg interface{}) ([]byte, error) {
+	v := reflect.Indirect(reflect.ValueOf(msg))
+	id, ok := encodings[v.Type()]
+	if !ok {
+		return nil, fmt.Errorf("no encoding of %s", v.Type())
+	}
+	e := &encoder{}
+	e.nodeID(numericID(id))
+	if err := e.value(v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// decoder decodes the bytes, its error being set once they are short
+type decoder struct {
+	b   []byte
+	err error
+}
+
+var errShort = errors.New("short message")
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.b) {
+		d.err = errShort
+		d.b = nil
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) bytes() []byte {
+	n := int32(d.uint32())
+	if n == -1 || d.err != nil {
+		return nil
+	}
+	b := d.next(int(n))
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) dateTime() time.Time {
+	ticks := int64(d.uint64())
+	if ticks <= 0 || ticks == math.MaxInt64 {
+		return time.Time{}
+	}
+	return time.Unix(epoch+ticks/1e7, ticks%1e7*100).UTC()
+}
+
+func (d *decoder) guid() Guid {
+	var g Guid
+	g.Data1 = d.uint32()
+	g.Data2 = d.uint16()
+	g.Data3 = d.uint16()
+	copy(g.Data4[:], d.next(8))
+	return g
+}
+
+// nodeID decodes the node ids, and the expanded node ids, whose namespace
+// uri and server index are skipped
+func (d *decoder) nodeID() NodeID {
+	mask := d.uint8()
+	var id NodeID
+	switch mask & 0x0f {
+	case 0x00:
+		id = NodeID{Type: 'i', Numeric: uint32(d.uint8())}
+	case 0x01:
+		id = NodeID{Type: 'i', Namespace: uint16(d.uint8())}
+		id.Numeric = uint32(d.uint16())
+	case 0x02:
+		id = NodeID{Type: 'i', Namespace: d.uint16(), Numeric: d.uint32()}
+	case 0x03:
+		id = NodeID{Type: 's', Namespace: d.uint16(), Value: d.string()}
+	case 0x04:
+		id = NodeID{Type: 'g', Namespace: d.uint16(), Value: d.guid().String()}
+	case 0x05:
+		id = NodeID{Type: 'b', Namespace: d.uint16(), Value: d.string()}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("invalid node id encoding 0x%02x", mask)
+		}
+	}
+	if mask&0x80 != 0 {
+		d.string()
+	}
+	if mask&0x40 != 0 {
+		d.uint32()
+	}
+	return id
+}
+
+func (d *decoder) localizedText() LocalizedText {
+	var t LocalizedText
+	mask := d.uint8()
+	if mask&0x01 != 0 {
+		t.Locale = d.string()
+	}
+	if mask&0x02 != 0 {
+		t.Text = d.string()
+	}
+	return t
+}
+
+func (d *decoder) extensionObject() ExtensionObject {
+	o := ExtensionObject{TypeID: d.nodeID()}
+	if d.uint8()&0x03 == 0 {
+		return o
+	}
+	body := d.bytes()
+	if d.err != nil {
+		return o
+	}
+	t, ok := types[o.TypeID.Numeric]
+	if !ok || o.TypeID.Type != 'i' || o.TypeID.Namespace != 0 {
+		o.Value = body
+		return o
+	}
+	v := reflect.New(t)
+	bd := &decoder{b: body}
+	bd.value(v.Elem())
+	if bd.err != nil {
+		d.err = bd.err
+		return o
+	}
+	o.Value = v.Interface()
+	return o
+}
+
+func (d *decoder) diagnosticInfo() {
+	mask := d.uint8()
+	for _, bit := range []uint8{0x01, 0x02, 0x04, 0x08} {
+		if mask&bit != 0 {
+			d.uint32()
+		}
+	}
+	if mask&0x10 != 0 {
+		d.string()
+	}
+	if mask&0x20 != 0 {
+		d.uint32()
+	}
+	if mask&0x40 != 0 {
+		d.diagnosticInfo()
+	}
+}
+
+func (d *decoder) dataValue() DataValue {
+	var v DataValue
+	mask := d.uint8()
+	if mask&0x01 != 0 {
+		v.Value = d.variant()
+	}
+	if mask&0x02 != 0 {
+		v.Status = StatusCode(d.uint32())
+	}
+	if mask&0x04 != 0 {
+		v.SourceTimestamp = d.dateTime()
+	}
+	if mask&0x10 != 0 {
+		d.uint16()
+	}
+	if mask&0x08 != 0 {
+		v.ServerTimestamp = d.dateTime()
+	}
+	if mask&0x20 != 0 {
+		d.uint16()
+	}
+	return v
+}
+
+// variant decodes the value of the variant, the arrays as []interface{}
+// and the multi-dimensional arrays flattened
+func (d *decoder) variant() interface{} {
+	mask := d.uint8()
+	typ := mask & 0x3f
+	if mask&0x80 == 0 {
+		return d.builtin(typ)
+	}
+	n := int32(d.uint32())
+	if n < 0 {
+		return nil
+	}
+	values := make([]interface{}, 0, n)
+	for i := int32(0); i < n && d.err == nil; i++ {
+		values = append(values, d.builtin(typ))
+	}
+	if mask&0x40 != 0 {
+		dims := int32(d.uint32())
+		for i := int32(0); i < dims && d.err == nil; i++ {
+			d.uint32()
+		}
+	}
+	return values
+}
+
+func (d *decoder) builtin(typ uint8) interface{} {
+	switch typ {
+	case 0:
+		return nil
+	case typeBoolean:
+		return d.uint8() != 0
+	case typeSByte:
+		return int8(d.uint8())
+	case typeByte:
+		return d.uint8()
+	case typeInt16:
+		return int16(d.uint16())
+	case typeUInt16:
+		return d.uint16()
+	case typeInt32:
+		return int32(d.uint32())
+	case typeUInt32:
+		return d.uint32()
+	case typeInt64:
+		return int64(d.uint64())
+	case typeUInt64:
+		return d.uint64()
+	case typeFloat:
+		return math.Float32frombits(d.uint32())
+	case typeDouble:
+		return math.Float64frombits(d.uint64())
+	case typeString, typeXMLElement:
+		return d.string()
+	case typeDateTime:
+		return d.dateTime()
+	case typeGuid:
+		return d.guid()
+	case typeByteString:
+		return d.bytes()
+	case typeNodeID, typeExpandedNode:
+		return d.nodeID()
+	case typeStatusCode:
+		return StatusCode(d.uint32())
+	case typeQualifiedName:
+		return QualifiedName{NamespaceIndex: d.uint16(), Name: d.string()}
+	case typeLocalizedText:
+		return d.localizedText()
+	case typeExtension:
+		return d.extensionObject()
+	case typeDataValue:
+		return d.dataValue()
+	case typeVariant:
+		return d.variant()
+	case typeDiagnostic:
+		d.diagnosticInfo()
+		return nil
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("invalid variant type %d", typ)
+	}
+	return nil
+}
+
+func (d *decoder) value(v reflect.Value) {
+	if d.err != nil {
+		return
+	}
+	switch v.Type() {
+	case timeType:
+		v.Set(reflect.ValueOf(d.dateTime()))
+		return
+	case nodeIDType:
+		v.Set(reflect.ValueOf(d.nodeID()))
+		return
+	case guidType:
+		v.Set(reflect.ValueOf(d.guid()))
+		return
+	case localizedTextType:
+		v.Set(reflect.ValueOf(d.localizedText()))
+		return
+	case extensionType:
+		v.Set(reflect.ValueOf(d.extensionObject()))
+		return
+	case dataValueType:
+		v.Set(reflect.ValueOf(d.dataValue()))
+		return
+	case diagnosticType:
+		d.diagnosticInfo()
+		return
+	case bytesType:
+		v.SetBytes(d.bytes())
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(d.uint8() != 0)
+	case reflect.Int8:
+		v.SetInt(int64(int8(d.uint8())))
+	case reflect.Uint8:
+		v.SetUint(uint64(d.uint8()))
+	case reflect.Int16:
+		v.SetInt(int64(int16(d.uint16())))
+	case reflect.Uint16:
+		v.SetUint(uint64(d.uint16()))
+	case reflect.Int32:
+		v.SetInt(int64(int32(d.uint32())))
+	case reflect.Uint32:
+		v.SetUint(uint64(d.uint32()))
+	case reflect.Int64:
+		v.SetInt(int64(d.uint64()))
+	case reflect.Uint64:
+		v.SetUint(d.uint64())
+	case reflect.Float32:
+		v.SetFloat(float64(math.Float32frombits(d.uint32())))
+	case reflect.Float64:
+		v.SetFloat(math.Float64frombits(d.uint64()))
+	case reflect.String:
+		v.SetString(d.string())
+	case reflect.Slice:
+		n := int32(d.uint32())
+		if n < 0 || d.err != nil {
+			return
+		}
+		// The length is checked against the bytes left, each element
+		// being encoded in a byte at least
+		if int(n) > len(d.b) {
+			d.err = errShort
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), int(n), int(n))
+		for i := 0; i < int(n); i++ {
+			d.value(s.Index(i))
+		}
+		v.Set(s)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			d.value(v.Field(i))
+		}
+	default:
+		d.err = fmt.Errorf("no decoding of %s", v.Type())
+	}
+}
+
+// decodeMessage decodes the message of the node id of its encoding,
+// returning a pointer to its structure
+func decodeMessage(b []byte) (interface{}, error) {
+	d := &decoder{b: b}
+	id := d.nodeID()
+	if d.err != nil {
+		return nil, d.err
+	}
+	t, ok := types[id.Numeric]
+	if !ok || id.Type != 'i' || id.Namespace != 0 {
+		return nil, fmt.Errorf("unknown message %s", id)
+	}
+	v := reflect.New(t)
+	d.value(v.Elem())
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding %s: %s", t.Name(), d.err)
+	}
+	return v.Interface(), nil
+}
@@ -0,0 +1,333 @@
+package opcua
+
+import "time"
+
+// The structures of the services, as encoded, their first field being the
+// header of the requests or responses
+
+type requestHeader struct {
+	AuthenticationToken NodeID
+	Timestamp           time.Time
+	RequestHandle       uint32
+	ReturnDiagnostics   uint32
+	AuditEntryID        string
+	TimeoutHint         uint32
+	AdditionalHeader    ExtensionObject
+}
+
+type responseHeader struct {
+	Timestamp          time.Time
+	RequestHandle      uint32
+	ServiceResult      StatusCode
+	ServiceDiagnostics DiagnosticInfo
+	StringTable        []string
+	AdditionalHeader   ExtensionObject
+}
+
+type serviceFault struct {
+	Header responseHeader
+}
+
+// The security modes of the channels
+const (
+	modeNone           = 1
+	modeSign           = 2
+	modeSignAndEncrypt = 3
+)
+
+type openSecureChannelRequest struct {
+	Header                requestHeader
+	ClientProtocolVersion uint32
+	// RequestType is 0 to issue a token, 1 to renew it
+	RequestType       int32
+	SecurityMode      int32
+	ClientNonce       []byte
+	RequestedLifetime uint32
+}
+
+type channelSecurityToken struct {
+	ChannelID       uint32
+	TokenID         uint32
+	CreatedAt       time.Time
+	RevisedLifetime uint32
+}
+
+type openSecureChannelResponse struct {
+	Header                responseHeader
+	ServerProtocolVersion uint32
+	SecurityToken         channelSecurityToken
+	ServerNonce           []byte
+}
+
+type closeSecureChannelRequest struct {
+	Header requestHeader
+}
+
+type applicationDescription struct {
+	ApplicationURI      string
+	ProductURI          string
+	ApplicationName     LocalizedText
+	ApplicationType     int32
+	GatewayServerURI    string
+	DiscoveryProfileURI string
+	DiscoveryURLs       []string
+}
+
+// The types of the user identity tokens
+const (
+	tokenAnonymous = 0
+	tokenUserName  = 1
+)
+
+type userTokenPolicy struct {
+	PolicyID          string
+	TokenType         int32
+	IssuedTokenType   string
+	IssuerEndpointURL string
+	SecurityPolicyURI string
+}
+
+type endpointDescription struct {
+	EndpointURL         string
+	Server              applicationDescription
+	ServerCertificate   []byte
+	SecurityMode        int32
+	SecurityPolicyURI   string
+	UserIdentityTokens  []userTokenPolicy
+	TransportProfileURI string
+	SecurityLevel       uint8
+}
+
+type getEndpointsRequest struct {
+	Header      requestHeader
+	EndpointURL string
+	LocaleIDs   []string
+	ProfileURIs []string
+}
+
+type getEndpointsResponse struct {
+	Header    responseHeader
+	Endpoints []endpointDescription
+}
+
+type signedSoftwareCertificate struct {
+	CertificateData []byte
+	Signature       []byte
+}
+
+type signatureData struct {
+	Algorithm string
+	Signature []byte
+}
+
+type createSessionRequest struct {
+	Header                  requestHeader
+	ClientDescription       applicationDescription
+	ServerURI               string
+	EndpointURL             string
+	SessionName             string
+	ClientNonce             []byte
+	ClientCertificate       []byte
+	RequestedSessionTimeout float64
+	MaxResponseMessageSize  uint32
+}
+
+type createSessionResponse struct {
+	Header                     responseHeader
+	SessionID                  NodeID
+	AuthenticationToken        NodeID
+	RevisedSessionTimeout      float64
+	ServerNonce                []byte
+	ServerCertificate          []byte
+	ServerEndpoints            []endpointDescription
+	ServerSoftwareCertificates []signedSoftwareCertificate
+	ServerSignature            signatureData
+	MaxRequestMessageSize      uint32
+}
+
+type anonymousIdentityToken struct {
+	PolicyID string
+}
+
+type userNameIdentityToken struct {
+	PolicyID            string
+	UserName            string
+	Password            []byte
+	EncryptionAlgorithm string
+}
+
+type activateSessionRequest struct {
+	Header                     requestHeader
+	ClientSignature            signatureData
+	ClientSoftwareCertificates []signedSoftwareCertificate
+	LocaleIDs                  []string
+	UserIdentityToken          ExtensionObject
+	UserTokenSignature         signatureData
+}
+
+type activateSessionResponse struct {
+	Header          responseHeader
+	ServerNonce     []byte
+	Results         []StatusCode
+	DiagnosticInfos []DiagnosticInfo
+}
+
+type closeSessionRequest struct {
+	Header              requestHeader
+	DeleteSubscriptions bool
+}
+
+type closeSessionResponse struct {
+	Header responseHeader
+}
+
+// attributeValue is the id of the value attribute of the nodes
+const attributeValue = 13
+
+// timestampsBoth returns the source and server timestamps of the values
+const timestampsBoth = 2
+
+type readValueID struct {
+	NodeID       NodeID
+	AttributeID  uint32
+	IndexRange   string
+	DataEncoding QualifiedName
+}
+
+type readRequest struct {
+	Header             requestHeader
+	MaxAge             float64
+	TimestampsToReturn int32
+	NodesToRead        []readValueID
+}
+
+type readResponse struct {
+	Header          responseHeader
+	Results         []DataValue
+	DiagnosticInfos []DiagnosticInfo
+}
+
+type createSubscriptionRequest struct {
+	Header                      requestHeader
+	RequestedPublishingInterval float64
+	RequestedLifetimeCount      uint32
+	RequestedMaxKeepAliveCount  uint32
+	MaxNotificationsPerPublish  uint32
+	PublishingEnabled           bool
+	Priority                    uint8
+}
+
+type createSubscriptionResponse struct {
+	Header                    responseHeader
+	SubscriptionID            uint32
+	RevisedPublishingInterval float64
+	RevisedLifetimeCount      uint32
+	RevisedMaxKeepAliveCount  uint32
+}
+
+// monitoringReporting samples the items and reports their changes
+const monitoringReporting = 2
+
+type monitoringParameters struct {
+	ClientHandle     uint32
+	SamplingInterval float64
+	Filter           ExtensionObject
+	QueueSize        uint32
+	DiscardOldest    bool
+}
+
+type monitoredItemCreateRequest struct {
+	ItemToMonitor       readValueID
+	MonitoringMode      int32
+	RequestedParameters monitoringParameters
+}
+
+type createMonitoredItemsRequest struct {
+	Header             requestHeader
+	SubscriptionID     uint32
+	TimestampsToReturn int32
+	ItemsToCreate      []monitoredItemCreateRequest
+}
+
+type monitoredItemCreateResult struct {
+	StatusCode              StatusCode
+	MonitoredItemID         uint32
+	RevisedSamplingInterval float64
+	RevisedQueueSize        uint32
+	FilterResult            ExtensionObject
+}
+
+type createMonitoredItemsResponse struct {
+	Header          responseHeader
+	Results         []monitoredItemCreateResult
+	DiagnosticInfos []DiagnosticInfo
+}
+
+type subscriptionAcknowledgement struct {
+	SubscriptionID uint32
+	SequenceNumber uint32
+}
+
+type publishRequest struct {
+	Header                       requestHeader
+	SubscriptionAcknowledgements []subscriptionAcknowledgement
+}
+
+type notificationMessage struct {
+	SequenceNumber   uint32
+	PublishTime      time.Time
+	NotificationData []ExtensionObject
+}
+
+type publishResponse struct {
+	Header                   responseHeader
+	SubscriptionID           uint32
+	AvailableSequenceNumbers []uint32
+	MoreNotifications        bool
+	NotificationMessage      notificationMessage
+	Results                  []StatusCode
+	DiagnosticInfos          []DiagnosticInfo
+}
+
+type monitoredItemNotification struct {
+	ClientHandle uint32
+	Value        DataValue
+}
+
+type dataChangeNotification struct {
+	MonitoredItems  []monitoredItemNotification
+	DiagnosticInfos []DiagnosticInfo
+}
+
+type statusChangeNotification struct {
+	Status         StatusCode
+	DiagnosticInfo DiagnosticInfo
+}
+
+// The ids of the default binary encodings of the structures
+func init() {
+	register(321, anonymousIdentityToken{})
+	register(324, userNameIdentityToken{})
+	register(397, serviceFault{})
+	register(428, getEndpointsRequest{})
+	register(431, getEndpointsResponse{})
+	register(446, openSecureChannelRequest{})
+	register(449, openSecureChannelResponse{})
+	register(452, closeSecureChannelRequest{})
+	register(461, createSessionRequest{})
+	register(464, createSessionResponse{})
+	register(467, activateSessionRequest{})
+	register(470, activateSessionResponse{})
+	register(473, closeSessionRequest{})
+	register(476, closeSessionResponse{})
+	register(631, readRequest{})
+	register(634, readResponse{})
+	register(751, createMonitoredItemsRequest{})
+	register(754, createMonitoredItemsResponse{})
+	register(787, createSubscriptionRequest{})
+	register(790, createSubscriptionResponse{})
+	register(811, dataChangeNotification{})
+	register(820, statusChangeNotification{})
+	register(826, publishRequest{})
+	register(829, publishResponse{})
+}
@@ -0,0 +1,507 @@
+package opcua
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// OpcUA reads the values of the nodes of an OPC UA server, at each
+// interval, or subscribes to their changes
+type OpcUA struct {
+	Name     string
+	Endpoint string
+
+	SecurityPolicy string `toml:"security_policy"`
+	SecurityMode   string `toml:"security_mode"`
+	// Certificate and key of the client, required by the secure channels,
+	// and certificate expected of the server
+	Certificate       string
+	PrivateKey        string `toml:"private_key"`
+	ServerCertificate string `toml:"server_certificate"`
+
+	AuthMethod string `toml:"auth_method"`
+	Username   string
+	Password   string
+
+	ConnectTimeout internal.Duration `toml:"connect_timeout"`
+	RequestTimeout internal.Duration `toml:"request_timeout"`
+
+	// Timestamp is the time of the points, the time of the gather, or the
+	// timestamps of the values of the server or of their source
+	Timestamp string
+	// SubscriptionInterval subscribes to the changes of the nodes, sampled
+	// and published at the interval, instead of reading them
+	SubscriptionInterval internal.Duration `toml:"subscription_interval"`
+
+	Nodes []Node
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	nodeIDs []NodeID
+	client  *client
+	metricC chan telegraf.Metric
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Node is a node read, its value being the field Name of its point
+type Node struct {
+	Name   string
+	NodeID string `toml:"node_id"`
+	Tags   map[string]string
+}
+
+var sampleConfig = `
+  # Measurement of the points
+  name = "opcua"
+
+  # Endpoint of the server
+  endpoint = "opc.tcp://localhost:4840"
+
+  # Security policy, None or Basic256Sha256, and security mode, None, Sign
+  # or SignAndEncrypt, of the endpoint
+  security_policy = "None"
+  security_mode = "None"
+
+  # Certificate and RSA key of the client, PEM encoded, required with the
+  # Basic256Sha256 policy. Its application URI is the URI of its subject
+  # alternative names.
+  # certificate = "/etc/telegraf/opcua_cert.pem"
+  # private_key = "/etc/telegraf/opcua_key.pem"
+
+  # Certificate expected of the server, PEM encoded, any being accepted if
+  # not set
+  # server_certificate = "/etc/telegraf/opcua_server.pem"
+
+  # Authentication, Anonymous or UserName
+  auth_method = "Anonymous"
+  # username = ""
+  # password = ""
+
+  # Timeouts of the connection and of the requests
+  # connect_timeout = "10s"
+  # request_timeout = "5s"
+
+  # Time of the points, "gather", or the timestamps of the values, "server"
+  # or "source"
+  # timestamp = "gather"
+
+  # Subscribe to the changes of the nodes, sampled and published at the
+  # interval, instead of reading them at each interval
+  # subscription_interval = "1s"
+
+  # The nodes, a point per node, its value being the field of its name, with
+  # its quality, and its id and tags as tags
+  [[inputs.opcua.nodes]]
+    name = "temperature"
+    node_id = "ns=2;s=Line1.Temperature"
+    # [inputs.opcua.nodes.tags]
+    #   line = "1"
+`
+
+func (o *OpcUA) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpcUA) Description() string {
+	return "Read or subscribe to the values of the nodes of OPC UA servers"
+}
+
+var (
+	securityPolicies = map[string]string{
+		"None":           policyNone,
+		"Basic256Sha256": policyBasic256Sha256,
+	}
+	securityModes = map[string]int32{
+		"None":           modeNone,
+		"Sign":           modeSign,
+		"SignAndEncrypt": modeSignAndEncrypt,
+	}
+	authMethods = map[string]int32{
+		"Anonymous": tokenAnonymous,
+		"UserName":  tokenUserName,
+	}
+)
+
+// subjectAltName is the extension of the subject alternative names
+var subjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+// applicationURI returns the URI of the subject alternative names of the
+// certificate
+func applicationURI(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(subjectAltName) {
+			continue
+		}
+		var seq asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &seq); err != nil {
+			return ""
+		}
+		rest := seq.Bytes
+		for len(rest) > 0 {
+			var name asn1.RawValue
+			var err error
+			if rest, err = asn1.Unmarshal(rest, &name); err != nil {
+				return ""
+			}
+			// The URIs are the names of the context-specific tag 6
+			if name.Class == 2 && name.Tag == 6 {
+				return string(name.Bytes)
+			}
+		}
+	}
+	return ""
+}
+
+// newClient returns the client of the configuration
+func (o *OpcUA) newClient() (*client, error) {
+	policy, ok := securityPolicies[o.SecurityPolicy]
+	if !ok {
+		return nil, fmt.Errorf("invalid security_policy %q", o.SecurityPolicy)
+	}
+	mode, ok := securityModes[o.SecurityMode]
+	if !ok {
+		return nil, fmt.Errorf("invalid security_mode %q", o.SecurityMode)
+	}
+	if (policy == policyNone) != (mode == modeNone) {
+		return nil, errors.New("security_policy and security_mode must both " +
+			"be None or neither")
+	}
+	tokenType, ok := authMethods[o.AuthMethod]
+	if !ok {
+		return nil, fmt.Errorf("invalid auth_method %q", o.AuthMethod)
+	}
+
+	c := &client{
+		endpoint:       o.Endpoint,
+		policy:         policy,
+		mode:           mode,
+		applicationURI: "urn:influxdata:telegraf",
+		tokenType:      tokenType,
+		username:       o.Username,
+		password:       o.Password,
+		connectTimeout: o.ConnectTimeout.Duration,
+		requestTimeout: o.RequestTimeout.Duration,
+	}
+	if policy != policyNone {
+		if o.Certificate == "" || o.PrivateKey == "" {
+			return nil, errors.New("certificate and private_key are required " +
+				"by the security policy")
+		}
+		pair, err := tls.LoadX509KeyPair(o.Certificate, o.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an RSA key", o.PrivateKey)
+		}
+		cert, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		c.cert = cert.Raw
+		c.key = key
+		if uri := applicationURI(cert); uri != "" {
+			c.applicationURI = uri
+		}
+	}
+	if o.ServerCertificate != "" {
+		b, err := ioutil.ReadFile(o.ServerCertificate)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM certificate found in %s",
+				o.ServerCertificate)
+		}
+		c.serverCert = block.Bytes
+	}
+	return c, nil
+}
+
+func (o *OpcUA) Start() error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.Name == "" {
+		o.Name = "opcua"
+	}
+	if o.ConnectTimeout.Duration == 0 {
+		o.ConnectTimeout.Duration = 10 * time.Second
+	}
+	if o.RequestTimeout.Duration == 0 {
+		o.RequestTimeout.Duration = 5 * time.Second
+	}
+	switch o.Timestamp {
+	case "":
+		o.Timestamp = "gather"
+	case "gather", "server", "source":
+	default:
+		return fmt.Errorf("invalid timestamp %q", o.Timestamp)
+	}
+	o.nodeIDs = nil
+	for i, node := range o.Nodes {
+		id, err := ParseNodeID(node.NodeID)
+		if err != nil {
+			return err
+		}
+		if node.Name == "" {
+			o.Nodes[i].Name = id.String()
+		}
+		o.nodeIDs = append(o.nodeIDs, id)
+	}
+	if len(o.nodeIDs) == 0 {
+		return errors.New("no nodes configured")
+	}
+	c, err := o.newClient()
+	if err != nil {
+		return err
+	}
+	o.client = c
+
+	o.done = make(chan struct{})
+	if o.SubscriptionInterval.Duration > 0 {
+		o.metricC = make(chan telegraf.Metric, 10000)
+		o.wg.Add(1)
+		go o.subscribe()
+	}
+	return nil
+}
+
+// retryInterval is the interval of the attempts to subscribe again
+const retryInterval = 5 * time.Second
+
+// subscribe subscribes to the changes of the nodes until stopped, the
+// subscription being created again after the errors
+func (o *OpcUA) subscribe() {
+	defer o.wg.Done()
+	for {
+		o.Lock()
+		select {
+		case <-o.done:
+			o.Unlock()
+			return
+		default:
+		}
+		c, err := o.newClient()
+		if err == nil {
+			o.client = c
+		}
+		o.Unlock()
+
+		if err == nil {
+			err = o.runSubscription(c)
+		}
+		select {
+		case <-o.done:
+			return
+		default:
+		}
+		if err == nil {
+			continue
+		}
+		o.Log.Errorf("Subscription to %s failed: %s", o.Endpoint, err)
+		select {
+		case <-o.done:
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// runSubscription creates the subscription and queues the changes of the
+// nodes published, until the channel is to be renewed
+func (o *OpcUA) runSubscription(c *client) error {
+	defer c.close()
+	if err := c.connect(); err != nil {
+		return err
+	}
+	keepAlive, results, err := c.subscribe(o.nodeIDs,
+		o.SubscriptionInterval.Duration)
+	if err != nil {
+		return err
+	}
+	for i, status := range results {
+		if status.Quality() == "Bad" {
+			o.Log.Errorf("Could not monitor %s: %s", o.nodeIDs[i], status)
+		}
+	}
+
+	var acks []subscriptionAcknowledgement
+	for time.Now().Before(c.expires) {
+		resp, err := c.publish(acks, keepAlive+o.RequestTimeout.Duration)
+		if err != nil {
+			return err
+		}
+		acks = nil
+		msg := resp.NotificationMessage
+		// The keep alives have no notifications to acknowledge
+		if len(msg.NotificationData) > 0 {
+			acks = append(acks, subscriptionAcknowledgement{
+				SubscriptionID: resp.SubscriptionID,
+				SequenceNumber: msg.SequenceNumber,
+			})
+		}
+		now := time.Now()
+		for _, data := range msg.NotificationData {
+			switch n := data.Value.(type) {
+			case *dataChangeNotification:
+				for _, item := range n.MonitoredItems {
+					if int(item.ClientHandle) >= len(o.nodeIDs) {
+						continue
+					}
+					fields, tags, t := o.point(int(item.ClientHandle),
+						item.Value, now)
+					m, err := telegraf.NewMetric(o.Name, tags, fields, t)
+					if err != nil {
+						o.Log.Errorf("Could not create metric: %s", err)
+						continue
+					}
+					select {
+					case o.metricC <- m:
+					default:
+						o.Log.Warn("Buffer is full, dropping a metric")
+					}
+				}
+			case *statusChangeNotification:
+				return fmt.Errorf("subscription status changed to %s", n.Status)
+			}
+		}
+	}
+	return nil
+}
+
+// fieldValue returns the field of the scalar values, the arrays, opaque
+// bytes and structures being skipped
+func fieldValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case bool, string, int64, uint64, float64:
+		return v, true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case float32:
+		return float64(v), true
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true
+	case LocalizedText:
+		return v.Text, true
+	case StatusCode:
+		return int64(v), true
+	case Guid:
+		return v.String(), true
+	case NodeID:
+		return v.String(), true
+	}
+	return nil, false
+}
+
+// point returns the fields, tags and time of the value of the node, its
+// value being missing if its quality is bad
+func (o *OpcUA) point(i int, v DataValue,
+	now time.Time) (map[string]interface{}, map[string]string, time.Time) {
+	node := o.Nodes[i]
+	fields := map[string]interface{}{"quality": v.Status.Quality()}
+	if v.Status.Quality() != "Bad" {
+		if value, ok := fieldValue(v.Value); ok {
+			fields[node.Name] = value
+		}
+	}
+	tags := map[string]string{"id": o.nodeIDs[i].String()}
+	for k, v := range node.Tags {
+		tags[k] = v
+	}
+	t := now
+	switch {
+	case o.Timestamp == "server" && !v.ServerTimestamp.IsZero():
+		t = v.ServerTimestamp
+	case o.Timestamp == "source" && !v.SourceTimestamp.IsZero():
+		t = v.SourceTimestamp
+	}
+	return fields, tags, t
+}
+
+func (o *OpcUA) Gather(acc telegraf.Accumulator) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.SubscriptionInterval.Duration > 0 {
+		nmetrics := len(o.metricC)
+		for i := 0; i < nmetrics; i++ {
+			metric := <-o.metricC
+			acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+				metric.Time())
+		}
+		return nil
+	}
+
+	// The session is opened again on the errors, and before the token of
+	// the channel expires
+	if o.client.channel != nil && time.Now().After(o.client.expires) {
+		o.client.close()
+	}
+	if o.client.channel == nil {
+		if err := o.client.connect(); err != nil {
+			return err
+		}
+	}
+	values, err := o.client.read(o.nodeIDs)
+	if err != nil {
+		o.client.close()
+		return err
+	}
+	now := time.Now()
+	for i, v := range values {
+		fields, tags, t := o.point(i, v, now)
+		acc.AddFields(o.Name, fields, tags, t)
+	}
+	return nil
+}
+
+func (o *OpcUA) Stop() {
+	o.Lock()
+	close(o.done)
+	if o.SubscriptionInterval.Duration > 0 {
+		o.client.abort()
+	} else {
+		o.client.close()
+	}
+	o.Unlock()
+	o.wg.Wait()
+}
+
+func init() {
+	inputs.Add("opcua", func() telegraf.Input {
+		return &OpcUA{
+			Name:           "opcua",
+			SecurityPolicy: "None",
+			SecurityMode:   "None",
+			AuthMethod:     "Anonymous",
+			Timestamp:      "gather",
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package opcua
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNodeID(t *testing.T) {
+	for s, expected := range map[string]NodeID{
+		"i=2258":          {Type: 'i', Numeric: 2258},
+		"ns=2;s=Line1;T":  {Namespace: 2, Type: 's', Value: "Line1;T"},
+		"ns=70000;i=1":    {},
+		"ns=1;b=dG9rZW4=": {Namespace: 1, Type: 'b', Value: "token"},
+		"ns=3;g=72962b91-fa75-4ae6-8d28-b404dc7daf63": {Namespace: 3,
+			Type: 'g', Value: "72962B91-FA75-4AE6-8D28-B404DC7DAF63"},
+		"s":        {},
+		"ns=1;x=1": {},
+		"g=1234":   {},
+	} {
+		id, err := ParseNodeID(s)
+		if expected.Type == 0 {
+			assert.Error(t, err, s)
+			continue
+		}
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, id, s)
+		if expected.Type != 'g' {
+			assert.Equal(t, s, id.String())
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	ids := []NodeID{
+		{Type: 'i', Numeric: 85},
+		{Namespace: 2, Type: 'i', Numeric: 1000},
+		{Namespace: 300, Type: 'i', Numeric: 100000},
+		{Namespace: 2, Type: 's', Value: "Temperature"},
+		{Namespace: 3, Type: 'g', Value: "72962B91-FA75-4AE6-8D28-B404DC7DAF63"},
+		{Namespace: 1, Type: 'b', Value: "token"},
+	}
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 600, time.UTC)
+	resp := &readResponse{
+		Header: responseHeader{
+			Timestamp:        ts,
+			StringTable:      []string{"a"},
+			AdditionalHeader: ExtensionObject{TypeID: numericID(0)},
+		},
+	}
+	for _, v := range []interface{}{true, int8(-1), uint8(1), int16(-2),
+		uint16(2), int32(-3), uint32(3), int64(-4), uint64(4), float32(1.5),
+		2.5, "text", ts, []byte{1, 2}, StatusCode(0x80340000),
+		LocalizedText{Locale: "en", Text: "hello"}, ids[4]} {
+		resp.Results = append(resp.Results, DataValue{Value: v})
+	}
+	for _, id := range ids {
+		resp.Results = append(resp.Results, DataValue{Value: id})
+	}
+	resp.Results = append(resp.Results, DataValue{Status: 0x80340000,
+		SourceTimestamp: ts})
+
+	b, err := encodeMessage(resp)
+	require.NoError(t, err)
+	msg, err := decodeMessage(b)
+	require.NoError(t, err)
+	assert.Equal(t, resp, msg)
+
+	_, err = decodeMessage(b[:len(b)-1])
+	assert.Error(t, err)
+}
+
+func TestDecodeArray(t *testing.T) {
+	// A two dimensional array of Int32, flattened
+	b := []byte{0x80 | 0x40 | typeInt32, 4, 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0,
+		3, 0, 0, 0, 4, 0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0, 2, 0, 0, 0}
+	d := &decoder{b: b}
+	assert.Equal(t, []interface{}{int32(1), int32(2), int32(3), int32(4)},
+		d.variant())
+	require.NoError(t, d.err)
+	assert.Empty(t, d.b)
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, "BadNodeIdUnknown (0x80340000)",
+		StatusCode(0x80340000).Error())
+	assert.Equal(t, "0x40920000", StatusCode(0x40920000).Error())
+	assert.Equal(t, "Uncertain", StatusCode(0x40920000).Quality())
+	assert.Equal(t, "Good", StatusCode(0).Quality())
+}
+
+func newPlugin(s *testServer) *OpcUA {
+	s.values["ns=2;s=Temperature"] = 21.5
+	s.values["ns=2;i=1000"] = int32(-3)
+	s.values["ns=2;s=Name"] = LocalizedText{Text: "line 1"}
+	s.values["ns=2;s=Running"] = true
+	s.values["ns=2;s=Array"] = []byte{1, 2}
+	return &OpcUA{
+		Endpoint:       s.endpoint(),
+		SecurityPolicy: "None",
+		SecurityMode:   "None",
+		AuthMethod:     "Anonymous",
+		Nodes: []Node{
+			{Name: "temperature", NodeID: "ns=2;s=Temperature",
+				Tags: map[string]string{"line": "1"}},
+			{Name: "offset", NodeID: "ns=2;i=1000"},
+			{Name: "name", NodeID: "ns=2;s=Name"},
+			{NodeID: "ns=2;s=Running"},
+			{Name: "array", NodeID: "ns=2;s=Array"},
+			{Name: "missing", NodeID: "ns=2;s=Missing"},
+		},
+		Log: testutil.Logger{},
+	}
+}
+
+func assertPoints(t *testing.T, acc *testutil.Accumulator) {
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"temperature": 21.5, "quality": "Good"},
+		map[string]string{"id": "ns=2;s=Temperature", "line": "1"})
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"offset": int64(-3), "quality": "Good"},
+		map[string]string{"id": "ns=2;i=1000"})
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"name": "line 1", "quality": "Good"},
+		map[string]string{"id": "ns=2;s=Name"})
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"ns=2;s=Running": true, "quality": "Good"},
+		map[string]string{"id": "ns=2;s=Running"})
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"quality": "Good"},
+		map[string]string{"id": "ns=2;s=Array"})
+}
+
+func TestRead(t *testing.T) {
+	s := newTestServer(t)
+	defer s.listener.Close()
+	o := newPlugin(s)
+	o.Timestamp = "source"
+	require.NoError(t, o.Start())
+	defer o.Stop()
+
+	for i := 0; i < 2; i++ {
+		var acc testutil.Accumulator
+		require.NoError(t, o.Gather(&acc))
+		require.Len(t, acc.Metrics, 6)
+		assertPoints(t, &acc)
+		acc.AssertContainsTaggedFields(t, "opcua",
+			map[string]interface{}{"quality": "Bad"},
+			map[string]string{"id": "ns=2;s=Missing"})
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+			acc.Metrics[0].Time)
+	}
+}
+
+// writeCertificate writes the certificate and key of the client, and the
+// certificate of the server, in PEM files
+func writeCertificate(t *testing.T, dir string, o *OpcUA, s *testServer) {
+	cert, key := newCertificate(t, "urn:test:telegraf")
+	o.Certificate = filepath.Join(dir, "cert.pem")
+	o.PrivateKey = filepath.Join(dir, "key.pem")
+	o.ServerCertificate = filepath.Join(dir, "server.pem")
+	require.NoError(t, ioutil.WriteFile(o.Certificate,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}),
+		0600))
+	require.NoError(t, ioutil.WriteFile(o.PrivateKey,
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600))
+	require.NoError(t, ioutil.WriteFile(o.ServerCertificate,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.cert}),
+		0600))
+}
+
+func TestReadSecure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "opcua")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestServer(t)
+	defer s.listener.Close()
+	s.username = "telegraf"
+	s.password = "secret"
+	// The messages of the many nodes are sent in several chunks
+	for i := 0; i < 2000; i++ {
+		s.values[fmt.Sprintf("ns=2;s=Line.Sensor%04d", i)] = strings.Repeat("x", 50)
+	}
+
+	for _, mode := range []string{"Sign", "SignAndEncrypt"} {
+		o := newPlugin(s)
+		o.SecurityPolicy = "Basic256Sha256"
+		o.SecurityMode = mode
+		o.AuthMethod = "UserName"
+		o.Username = "telegraf"
+		o.Password = "secret"
+		for i := 0; i < 2000; i++ {
+			o.Nodes = append(o.Nodes, Node{
+				Name:   "value",
+				NodeID: fmt.Sprintf("ns=2;s=Line.Sensor%04d", i),
+			})
+		}
+		writeCertificate(t, dir, o, s)
+		require.NoError(t, o.Start())
+		assert.Equal(t, "urn:test:telegraf", o.client.applicationURI)
+
+		var acc testutil.Accumulator
+		require.NoError(t, o.Gather(&acc), mode)
+		require.Len(t, acc.Metrics, 2006)
+		assertPoints(t, &acc)
+		acc.AssertContainsTaggedFields(t, "opcua",
+			map[string]interface{}{"value": strings.Repeat("x", 50),
+				"quality": "Good"},
+			map[string]string{"id": "ns=2;s=Line.Sensor1999"})
+		o.Stop()
+	}
+
+	// The password is encrypted on the unsecured channels
+	o := newPlugin(s)
+	o.AuthMethod = "UserName"
+	o.Username = "telegraf"
+	o.Password = "secret"
+	require.NoError(t, o.Start())
+	var acc testutil.Accumulator
+	require.NoError(t, o.Gather(&acc))
+	o.Stop()
+
+	o.Password = "wrong"
+	require.NoError(t, o.Start())
+	err = o.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BadIdentityTokenRejected")
+	o.Stop()
+
+	// The anonymous users are refused
+	o = newPlugin(s)
+	require.NoError(t, o.Start())
+	assert.Error(t, o.Gather(&acc))
+	o.Stop()
+
+	// The certificate of the server does not match
+	o = newPlugin(s)
+	o.SecurityPolicy = "Basic256Sha256"
+	o.SecurityMode = "Sign"
+	writeCertificate(t, dir, o, s)
+	require.NoError(t, ioutil.WriteFile(o.ServerCertificate,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+			Bytes: []byte("other")}), 0600))
+	require.NoError(t, o.Start())
+	err = o.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server_certificate")
+	o.Stop()
+}
+
+func TestSubscribe(t *testing.T) {
+	s := newTestServer(t)
+	defer s.listener.Close()
+	o := newPlugin(s)
+	o.SubscriptionInterval = internal.Duration{Duration: 10 * time.Millisecond}
+	require.NoError(t, o.Start())
+	defer o.Stop()
+
+	var acc testutil.Accumulator
+	for i := 0; i < 200 && len(acc.Metrics) < 10; i++ {
+		require.NoError(t, o.Gather(&acc))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.True(t, len(acc.Metrics) >= 10)
+	assertPoints(t, &acc)
+}
+
+func TestStartErrors(t *testing.T) {
+	nodes := []Node{{NodeID: "i=2258"}}
+	for _, o := range []*OpcUA{
+		{Nodes: []Node{{NodeID: "ns=2;x=1"}}},
+		{},
+		{Nodes: nodes, SecurityPolicy: "Basic256", SecurityMode: "Sign"},
+		{Nodes: nodes, SecurityPolicy: "None", SecurityMode: "Sign"},
+		{Nodes: nodes, SecurityPolicy: "Basic256Sha256", SecurityMode: "Sign"},
+		{Nodes: nodes, AuthMethod: "Certificate"},
+		{Nodes: nodes, Timestamp: "now"},
+	} {
+		if o.SecurityPolicy == "" {
+			o.SecurityPolicy = "None"
+			o.SecurityMode = "None"
+		}
+		if o.AuthMethod == "" {
+			o.AuthMethod = "Anonymous"
+		}
+		assert.Error(t, o.Start())
+	}
+}
+
+func TestConfig(t *testing.T) {
+	config := strings.Replace(sampleConfig, "# [inputs.opcua.nodes.tags]",
+		"[inputs.opcua.nodes.tags]", 1)
+	config = strings.Replace(config, `#   line = "1"`, `  line = "1"`, 1)
+	table, err := toml.Parse([]byte("[[inputs.opcua]]" + config))
+	require.NoError(t, err)
+	inputs := table.Fields["inputs"].(*ast.Table)
+	var o OpcUA
+	require.NoError(t, toml.UnmarshalTable(
+		inputs.Fields["opcua"].([]*ast.Table)[0], &o))
+	assert.Equal(t, "opc.tcp://localhost:4840", o.Endpoint)
+	assert.Equal(t, []Node{{Name: "temperature",
+		NodeID: "ns=2;s=Line1.Temperature",
+		Tags:   map[string]string{"line": "1"}}}, o.Nodes)
+}
@@ -0,0 +1,338 @@
+package opcua
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testServer is an OPC UA server of the tests, serving the values of its
+// nodes, read or subscribed
+type testServer struct {
+	listener net.Listener
+	cert     []byte
+	key      *rsa.PrivateKey
+
+	// username and password of the users, anonymous if not set
+	username string
+	password string
+
+	sync.Mutex
+	values map[string]interface{}
+}
+
+// newCertificate returns a self-signed certificate and its key, with the
+// application URI in its subject alternative names
+func newCertificate(t *testing.T, uri string) ([]byte, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	name, err := asn1.Marshal(asn1.RawValue{
+		Class: 2,
+		Tag:   6,
+		Bytes: []byte(uri),
+	})
+	require.NoError(t, err)
+	san, err := asn1.Marshal(asn1.RawValue{
+		Tag:        16,
+		IsCompound: true,
+		Bytes:      name,
+	})
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: uri},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: subjectAltName, Value: san},
+		},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	cert, key := newCertificate(t, "urn:test:server")
+	s := &testServer{
+		listener: listener,
+		cert:     cert,
+		key:      key,
+		values:   make(map[string]interface{}),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) endpoint() string {
+	return "opc.tcp://" + s.listener.Addr().String()
+}
+
+func (s *testServer) endpoints() []endpointDescription {
+	tokens := []userTokenPolicy{
+		{PolicyID: "anonymous", TokenType: tokenAnonymous},
+		{PolicyID: "username", TokenType: tokenUserName},
+	}
+	var endpoints []endpointDescription
+	for _, e := range []struct {
+		policy string
+		mode   int32
+	}{
+		{policyNone, modeNone},
+		{policyBasic256Sha256, modeSign},
+		{policyBasic256Sha256, modeSignAndEncrypt},
+	} {
+		endpoints = append(endpoints, endpointDescription{
+			EndpointURL:        s.endpoint(),
+			ServerCertificate:  s.cert,
+			SecurityMode:       e.mode,
+			SecurityPolicyURI:  e.policy,
+			UserIdentityTokens: tokens,
+		})
+	}
+	// The passwords are encrypted on the unsecured channels
+	endpoints[0].UserIdentityTokens = []userTokenPolicy{
+		tokens[0],
+		{PolicyID: "username", TokenType: tokenUserName,
+			SecurityPolicyURI: policyBasic256Sha256},
+	}
+	return endpoints
+}
+
+func (s *testServer) value(id NodeID) DataValue {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.values[id.String()]
+	if !ok {
+		return DataValue{Status: 0x80340000}
+	}
+	now := time.Now()
+	return DataValue{
+		Value:           v,
+		SourceTimestamp: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		ServerTimestamp: now,
+	}
+}
+
+// authenticate checks the signature of the client and its identity token
+func (s *testServer) authenticate(ch *secureChannel, nonce []byte,
+	req *activateSessionRequest) StatusCode {
+	if ch.secure() {
+		if verify(ch.remoteKey, append(append([]byte{}, s.cert...), nonce...),
+			req.ClientSignature.Signature) != nil {
+			return 0x80130000
+		}
+	}
+	switch token := req.UserIdentityToken.Value.(type) {
+	case *anonymousIdentityToken:
+		if s.username != "" || token.PolicyID != "anonymous" {
+			return 0x80210000
+		}
+	case *userNameIdentityToken:
+		password := token.Password
+		if token.EncryptionAlgorithm == algorithmRsaOaep {
+			plain, err := decryptOAEP(s.key, password)
+			if err != nil || len(plain) < 4 {
+				return 0x80200000
+			}
+			n := int(binary.LittleEndian.Uint32(plain))
+			if n != len(plain)-4 || !bytes.HasSuffix(plain, nonce) {
+				return 0x80200000
+			}
+			password = plain[4 : len(plain)-len(nonce)]
+		} else if !ch.secure() {
+			return 0x80200000
+		}
+		if token.UserName != s.username || string(password) != s.password {
+			return 0x80210000
+		}
+	default:
+		return 0x80200000
+	}
+	return 0
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	ch := &secureChannel{conn: conn, localCert: s.cert, localKey: s.key}
+	typ, _, raw, err := ch.readChunk()
+	if err != nil || typ != "HEL" {
+		return
+	}
+	d := &decoder{b: raw[8:]}
+	d.uint32()
+	ch.sendSize = int(d.uint32())
+	ack := &encoder{}
+	ack.uint32(0)
+	ack.uint32(bufferSize)
+	ack.uint32(bufferSize)
+	ack.uint32(0)
+	ack.uint32(0)
+	if ch.writeChunk("ACK", 'F', ack.buf) != nil {
+		return
+	}
+
+	authToken := NodeID{Namespace: 1, Type: 'i', Numeric: 42}
+	var serverNonce []byte
+	var activated bool
+	var monitored []monitoredItemCreateRequest
+	var sequence uint32
+	for {
+		typ, requestID, body, err := ch.receive()
+		if err != nil || typ == "CLO" {
+			return
+		}
+		msg, err := decodeMessage(body)
+		if err != nil {
+			return
+		}
+		header := sessionHeader(msg)
+		if header != nil && activated && header.AuthenticationToken != authToken {
+			return
+		}
+
+		var resp interface{}
+		switch req := msg.(type) {
+		case *openSecureChannelRequest:
+			ch.mode = req.SecurityMode
+			ch.channelID = 1
+			ch.tokenID = 1
+			r := &openSecureChannelResponse{
+				SecurityToken: channelSecurityToken{
+					ChannelID:       1,
+					TokenID:         1,
+					RevisedLifetime: req.RequestedLifetime,
+				},
+			}
+			if ch.secure() {
+				ch.localNonce, _ = nonce()
+				r.ServerNonce = ch.localNonce
+				ch.deriveKeys(req.ClientNonce)
+			}
+			resp = r
+		case *getEndpointsRequest:
+			resp = &getEndpointsResponse{Endpoints: s.endpoints()}
+		case *createSessionRequest:
+			serverNonce, _ = nonce()
+			r := &createSessionResponse{
+				SessionID:           NodeID{Namespace: 1, Type: 'i', Numeric: 1},
+				AuthenticationToken: authToken,
+				ServerNonce:         serverNonce,
+				ServerCertificate:   s.cert,
+			}
+			if ch.secure() {
+				sig, _ := sign(s.key, append(append([]byte{},
+					req.ClientCertificate...), req.ClientNonce...))
+				r.ServerSignature = signatureData{
+					Algorithm: algorithmRsaSha256,
+					Signature: sig,
+				}
+			}
+			resp = r
+		case *activateSessionRequest:
+			if status := s.authenticate(ch, serverNonce, req); status != 0 {
+				resp = &serviceFault{Header: responseHeader{ServiceResult: status}}
+				break
+			}
+			activated = true
+			resp = &activateSessionResponse{}
+		case *readRequest:
+			r := &readResponse{}
+			for _, node := range req.NodesToRead {
+				r.Results = append(r.Results, s.value(node.NodeID))
+			}
+			resp = r
+		case *createSubscriptionRequest:
+			resp = &createSubscriptionResponse{
+				SubscriptionID:            1,
+				RevisedPublishingInterval: req.RequestedPublishingInterval,
+				RevisedMaxKeepAliveCount:  req.RequestedMaxKeepAliveCount,
+			}
+		case *createMonitoredItemsRequest:
+			monitored = req.ItemsToCreate
+			r := &createMonitoredItemsResponse{}
+			for _, item := range monitored {
+				r.Results = append(r.Results, monitoredItemCreateResult{
+					StatusCode: s.value(item.ItemToMonitor.NodeID).Status,
+				})
+			}
+			resp = r
+		case *publishRequest:
+			time.Sleep(10 * time.Millisecond)
+			sequence++
+			n := &dataChangeNotification{}
+			for _, item := range monitored {
+				v := s.value(item.ItemToMonitor.NodeID)
+				if v.Status != 0 {
+					continue
+				}
+				n.MonitoredItems = append(n.MonitoredItems,
+					monitoredItemNotification{
+						ClientHandle: item.RequestedParameters.ClientHandle,
+						Value:        v,
+					})
+			}
+			resp = &publishResponse{
+				SubscriptionID: 1,
+				NotificationMessage: notificationMessage{
+					SequenceNumber:   sequence,
+					PublishTime:      time.Now(),
+					NotificationData: []ExtensionObject{{Value: n}},
+				},
+			}
+		case *closeSessionRequest:
+			resp = &closeSessionResponse{}
+		default:
+			resp = &serviceFault{Header: responseHeader{ServiceResult: 0x800B0000}}
+		}
+
+		b, err := encodeMessage(resp)
+		if err != nil {
+			return
+		}
+		typ = "MSG"
+		if _, ok := msg.(*openSecureChannelRequest); ok {
+			typ = "OPN"
+		}
+		if ch.send(typ, requestID, b) != nil {
+			return
+		}
+	}
+}
+
+// sessionHeader returns the header of the requests of the sessions
+func sessionHeader(msg interface{}) *requestHeader {
+	switch req := msg.(type) {
+	case *readRequest:
+		return &req.Header
+	case *createSubscriptionRequest:
+		return &req.Header
+	case *createMonitoredItemsRequest:
+		return &req.Header
+	case *publishRequest:
+		return &req.Header
+	}
+	return nil
+}
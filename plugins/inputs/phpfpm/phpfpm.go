@@ -5,14 +5,18 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -37,6 +41,9 @@ type poolStat map[string]metric
 type phpfpm struct {
 	Urls []string
 
+	// Timeout of the status requests, 5s by default
+	Timeout internal.Duration
+
 	client *http.Client
 }
 
@@ -62,6 +69,9 @@ var sampleConfig = `
   # urls = ["http://192.168.1.20/status", "/tmp/fpm.sock"]
   # If no servers are specified, then default to http://127.0.0.1/status
   urls = ["http://localhost/status"]
+
+  # Timeout of the status requests, 5s by default
+  # timeout = "5s"
 `
 
 func (r *phpfpm) SampleConfig() string {
@@ -72,6 +82,27 @@ func (r *phpfpm) Description() string {
 	return "Read metrics of phpfpm, via HTTP status page or socket"
 }
 
+// Init creates the HTTP client of the status pages
+func (g *phpfpm) Init() error {
+	c := httpconfig.Config{
+		Timeout: g.timeout(),
+		Proxy:   internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	g.client = client
+	return nil
+}
+
+func (g *phpfpm) timeout() time.Duration {
+	if g.Timeout.Duration == 0 {
+		return 5 * time.Second
+	}
+	return g.Timeout.Duration
+}
+
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (g *phpfpm) Gather(acc telegraf.Accumulator) error {
@@ -81,13 +112,18 @@ func (g *phpfpm) Gather(acc telegraf.Accumulator) error {
 
 	var wg sync.WaitGroup
 
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, serv := range g.Urls {
 		wg.Add(1)
 		go func(serv string) {
 			defer wg.Done()
-			outerr = g.gatherServer(serv, acc)
+			if err := g.gatherServer(serv, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}(serv)
 	}
 
@@ -98,11 +134,6 @@ func (g *phpfpm) Gather(acc telegraf.Accumulator) error {
 
 // Request status page to get stat raw data and import it
 func (g *phpfpm) gatherServer(addr string, acc telegraf.Accumulator) error {
-	if g.client == nil {
-		client := &http.Client{}
-		g.client = client
-	}
-
 	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
 		return g.gatherHttp(addr, acc)
 	}
@@ -118,10 +149,22 @@ func (g *phpfpm) gatherServer(addr string, acc telegraf.Accumulator) error {
 		if err != nil {
 			return fmt.Errorf("Unable parse server address '%s': %s", addr, err)
 		}
-		socketAddr := strings.Split(u.Host, ":")
-		fcgiIp := socketAddr[0]
-		fcgiPort, _ := strconv.Atoi(socketAddr[1])
-		fcgi, _ = NewClient(fcgiIp, fcgiPort)
+		fcgiIp, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			return fmt.Errorf("Unable parse server address '%s': %s", addr, err)
+		}
+		fcgiPort, err := strconv.Atoi(port)
+		if err != nil {
+			return fmt.Errorf("Unable parse server address '%s': %s", addr, err)
+		}
+		fcgi, err = NewClient(fcgiIp, fcgiPort)
+		if err != nil {
+			return fmt.Errorf("Unable to connect to phpfpm '%s': %s", addr, err)
+		}
+		statusPath = strings.Trim(u.Path, "/")
+		if statusPath == "" {
+			statusPath = "status"
+		}
 	} else {
 		socketAddr := strings.Split(addr, ":")
 		if len(socketAddr) >= 2 {
@@ -135,7 +178,14 @@ func (g *phpfpm) gatherServer(addr string, acc telegraf.Accumulator) error {
 		if _, err := os.Stat(socketPath); os.IsNotExist(err) {
 			return fmt.Errorf("Socket doesn't exist  '%s': %s", socketPath, err)
 		}
-		fcgi, _ = NewClient("unix", socketPath)
+		var err error
+		fcgi, err = NewClient("unix", socketPath)
+		if err != nil {
+			return fmt.Errorf("Unable to connect to phpfpm '%s': %s", socketPath, err)
+		}
+	}
+	if c, ok := fcgi.rwc.(net.Conn); ok {
+		c.SetDeadline(time.Now().Add(g.timeout()))
 	}
 	return g.gatherFcgi(fcgi, statusPath, acc)
 }
@@ -169,15 +219,19 @@ func (g *phpfpm) gatherHttp(addr string, acc telegraf.Accumulator) error {
 
 	req, err := http.NewRequest("GET", fmt.Sprintf("%s://%s%s", u.Scheme,
 		u.Host, u.Path), nil)
+	if err != nil {
+		return err
+	}
 	res, err := g.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("Unable to connect to phpfpm status page '%s': %v",
 			addr, err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != 200 {
-		return fmt.Errorf("Unable to get valid stat result from '%s': %v",
-			addr, err)
+		return fmt.Errorf("Unable to get valid stat result from '%s': %s",
+			addr, res.Status)
 	}
 
 	importMetric(res.Body, acc)
@@ -294,6 +294,9 @@ func NewClient(h string, args ...interface{}) (fcgi *conn, err error) {
 	default:
 		err = errors.New("fcgi: we only accept int (port) or string (socket) params.")
 	}
+	if err != nil {
+		return
+	}
 	fcgi = &conn{
 		rwc: con,
 	}
@@ -32,6 +32,7 @@ func TestPhpFpmGeneratesMetrics_From_Http(t *testing.T) {
 	r := &phpfpm{
 		Urls: []string{ts.URL},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -73,6 +74,7 @@ func TestPhpFpmGeneratesMetrics_From_Fcgi(t *testing.T) {
 	r := &phpfpm{
 		Urls: []string{"fcgi://" + tcp.Addr().String() + "/status"},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 	err = r.Gather(&acc)
@@ -116,6 +118,7 @@ func TestPhpFpmGeneratesMetrics_From_Socket(t *testing.T) {
 	r := &phpfpm{
 		Urls: []string{tcp.Addr().String()},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -160,6 +163,7 @@ func TestPhpFpmGeneratesMetrics_From_Socket_Custom_Status_Path(t *testing.T) {
 	r := &phpfpm{
 		Urls: []string{tcp.Addr().String() + ":custom-status-path"},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -190,6 +194,7 @@ func TestPhpFpmGeneratesMetrics_From_Socket_Custom_Status_Path(t *testing.T) {
 //We just want to make sure we did request stat from localhost
 func TestPhpFpmDefaultGetFromLocalhost(t *testing.T) {
 	r := &phpfpm{}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -202,6 +207,7 @@ func TestPhpFpmGeneratesMetrics_Throw_Error_When_Fpm_Status_Is_Not_Responding(t
 	r := &phpfpm{
 		Urls: []string{"http://aninvalidone"},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -210,10 +216,30 @@ func TestPhpFpmGeneratesMetrics_Throw_Error_When_Fpm_Status_Is_Not_Responding(t
 	assert.Contains(t, err.Error(), `Unable to connect to phpfpm status page 'http://aninvalidone': Get http://aninvalidone: dial tcp: lookup aninvalidone`)
 }
 
+func TestPhpFpmGeneratesMetrics_Throw_Error_When_Fcgi_Is_Not_Responding(t *testing.T) {
+	// The port of a closed listener is refusing the connections
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := tcp.Addr().String()
+	tcp.Close()
+
+	r := &phpfpm{
+		Urls: []string{"fcgi://" + addr + "/status", "fcgi://127.0.0.1/status"},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+
+	err = r.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fcgi://")
+}
+
 func TestPhpFpmGeneratesMetrics_Throw_Error_When_Socket_Path_Is_Invalid(t *testing.T) {
 	r := &phpfpm{
 		Urls: []string{"/tmp/invalid.sock"},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
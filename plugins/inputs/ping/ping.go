@@ -4,6 +4,7 @@ package ping
 
 import (
 	"errors"
+	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -31,6 +32,17 @@ type Ping struct {
 	// Interface to send ping from (ping -I <INTERFACE>)
 	Interface string
 
+	// Time limit of all the pings, in seconds. 0 means no limit
+	// (ping -w <DEADLINE>)
+	Deadline int
+
+	// Ping the IPv6 address of the urls (ping -6)
+	IPv6 bool `toml:"ipv6"`
+
+	// Method pinging the urls, "exec" to run the ping command, or "native"
+	// to send the ICMP requests from telegraf
+	Method string
+
 	// URLs to ping
 	Urls []string
 
@@ -56,6 +68,17 @@ var sampleConfig = `
   timeout = 0.0
   # interface to send ping from (ping -I <INTERFACE>)
   interface = ""
+  # time limit of all the pings of a url, in s. 0 == no limit
+  # (ping -w <DEADLINE>)
+  # deadline = 0
+  # ping the IPv6 addresses of the urls (ping -6)
+  # ipv6 = false
+
+  # "exec" runs the ping command, "native" sends the ICMP echo requests from
+  # telegraf, with raw sockets if telegraf can open them, or unprivileged
+  # ICMP sockets, see the net.ipv4.ping_group_range sysctl on Linux.
+  # Timeout is the time waited for each reply with native, 1s by default.
+  # method = "exec"
 `
 
 func (_ *Ping) SampleConfig() string {
@@ -72,29 +95,43 @@ func (p *Ping) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(url string, acc telegraf.Accumulator) {
 			defer wg.Done()
-			args := p.args(url)
-			out, err := p.pingHost(args...)
-			if err != nil {
-				// Combine go err + stderr output
-				errorChannel <- errors.New(
-					strings.TrimSpace(out) + ", " + err.Error())
-			}
 			tags := map[string]string{"url": url}
-			trans, rec, avg, err := processPingOutput(out)
-			if err != nil {
-				// fatal error
-				errorChannel <- err
-				return
+			var stats pingStats
+			var err error
+			if p.Method == "native" {
+				stats, err = p.nativePing(url)
+				if err != nil {
+					errorChannel <- err
+					return
+				}
+			} else {
+				args := p.args(url)
+				out, err := p.pingHost(args...)
+				if err != nil {
+					// Combine go err + stderr output
+					errorChannel <- errors.New(
+						strings.TrimSpace(out) + ", " + err.Error())
+				}
+				stats, err = processPingOutput(out)
+				if err != nil {
+					// fatal error
+					errorChannel <- err
+					return
+				}
 			}
 			// Calculate packet loss percentage
-			loss := float64(trans-rec) / float64(trans) * 100.0
+			loss := float64(stats.trans-stats.recv) / float64(stats.trans) * 100.0
 			fields := map[string]interface{}{
-				"packets_transmitted": trans,
-				"packets_received":    rec,
+				"packets_transmitted": stats.trans,
+				"packets_received":    stats.recv,
 				"percent_packet_loss": loss,
 			}
-			if avg > 0 {
-				fields["average_response_ms"] = avg
+			if stats.recv > 0 {
+				fields["minimum_response_ms"] = stats.min
+				fields["average_response_ms"] = stats.avg
+				fields["maximum_response_ms"] = stats.max
+				fields["standard_deviation_ms"] = stats.stddev
+				fields["ttl"] = stats.ttl
 			}
 			acc.AddFields("ping", fields, tags)
 		}(url, acc)
@@ -138,10 +175,27 @@ func (p *Ping) args(url string) []string {
 	if p.Interface != "" {
 		args = append(args, "-I", p.Interface)
 	}
+	if p.Deadline > 0 {
+		args = append(args, "-w", strconv.Itoa(p.Deadline))
+	}
+	if p.IPv6 {
+		args = append(args, "-6")
+	}
 	args = append(args, url)
 	return args
 }
 
+// pingStats are the statistics of the pings of a url
+type pingStats struct {
+	// Transmitted and received packets
+	trans, recv int
+	// TTL of the replies
+	ttl int
+	// Minimum, average, maximum and standard deviation of the response
+	// times, in ms
+	min, avg, max, stddev float64
+}
+
 // processPingOutput takes in a string output from the ping command, like:
 //
 //     PING www.google.com (173.194.115.84): 56 data bytes
@@ -152,10 +206,10 @@ func (p *Ping) args(url string) []string {
 //     2 packets transmitted, 2 packets received, 0.0% packet loss
 //     round-trip min/avg/max/stddev = 34.843/43.508/52.172/8.664 ms
 //
-// It returns (<transmitted packets>, <received packets>, <average response>)
-func processPingOutput(out string) (int, int, float64, error) {
-	var trans, recv int
-	var avg float64
+// It returns the statistics of the pings, the response times of the
+// min/avg/max line, and the TTL of the first reply
+func processPingOutput(out string) (pingStats, error) {
+	var stats pingStats
 	// Set this error to nil if we find a 'transmitted' line
 	err := errors.New("Fatal error processing ping output")
 	lines := strings.Split(out, "\n")
@@ -163,26 +217,40 @@ func processPingOutput(out string) (int, int, float64, error) {
 		if strings.Contains(line, "transmitted") &&
 			strings.Contains(line, "received") {
 			err = nil
-			stats := strings.Split(line, ", ")
+			fields := strings.Split(line, ", ")
 			// Transmitted packets
-			trans, err = strconv.Atoi(strings.Split(stats[0], " ")[0])
+			stats.trans, err = strconv.Atoi(strings.Split(fields[0], " ")[0])
 			if err != nil {
-				return trans, recv, avg, err
+				return stats, err
 			}
 			// Received packets
-			recv, err = strconv.Atoi(strings.Split(stats[1], " ")[0])
+			stats.recv, err = strconv.Atoi(strings.Split(fields[1], " ")[0])
 			if err != nil {
-				return trans, recv, avg, err
+				return stats, err
 			}
 		} else if strings.Contains(line, "min/avg/max") {
-			stats := strings.Split(line, " = ")[1]
-			avg, err = strconv.ParseFloat(strings.Split(stats, "/")[1], 64)
-			if err != nil {
-				return trans, recv, avg, err
+			fields := strings.Split(strings.Split(line, " = ")[1], "/")
+			if len(fields) < 4 {
+				return stats, fmt.Errorf("unexpected ping statistics: %q",
+					line)
+			}
+			for i, v := range []*float64{
+				&stats.min, &stats.avg, &stats.max, &stats.stddev,
+			} {
+				// The last value is followed by its unit
+				s := strings.Fields(fields[i])[0]
+				if *v, err = strconv.ParseFloat(s, 64); err != nil {
+					return stats, err
+				}
+			}
+		} else if i := strings.Index(line, "ttl="); i >= 0 && stats.ttl == 0 {
+			ttl := strings.Fields(line[i+len("ttl="):])
+			if len(ttl) > 0 {
+				stats.ttl, _ = strconv.Atoi(ttl[0])
 			}
 		}
 	}
-	return trans, recv, avg, err
+	return stats, err
 }
 
 func init() {
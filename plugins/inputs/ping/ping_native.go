@@ -0,0 +1,185 @@
+// +build !windows
+
+package ping
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// The IANA protocol numbers of ICMP and ICMPv6, to parse the replies
+const (
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+)
+
+// nativePing pings the host with ICMP echo requests sent by telegraf, rather
+// than by the ping command. It uses raw ICMP sockets when telegraf has the
+// privileges to open them, or unprivileged datagram ICMP sockets otherwise,
+// allowed by the net.ipv4.ping_group_range sysctl on Linux.
+func (p *Ping) nativePing(host string) (pingStats, error) {
+	var stats pingStats
+
+	network, listen, rawNetwork, proto := "ip4", "udp4", "ip4:icmp", protocolICMP
+	var echoType icmp.Type = ipv4.ICMPTypeEcho
+	if p.IPv6 {
+		network, listen, rawNetwork, proto = "ip6", "udp6", "ip6:ipv6-icmp",
+			protocolIPv6ICMP
+		echoType = ipv6.ICMPTypeEchoRequest
+	}
+	dst, err := net.ResolveIPAddr(network, host)
+	if err != nil {
+		return stats, err
+	}
+
+	privileged := true
+	c, err := icmp.ListenPacket(rawNetwork, "")
+	if err != nil {
+		privileged = false
+		if c, err = icmp.ListenPacket(listen, ""); err != nil {
+			return stats, fmt.Errorf("could not open an ICMP socket: %s", err)
+		}
+	}
+	defer c.Close()
+	var addr net.Addr = dst
+	if !privileged {
+		addr = &net.UDPAddr{IP: dst.IP, Zone: dst.Zone}
+	}
+
+	// The TTL of the replies is read from their control messages
+	if p.IPv6 {
+		c.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	} else {
+		c.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	}
+
+	count := p.Count
+	if count < 1 {
+		count = 1
+	}
+	interval := time.Second
+	if p.PingInterval > 0 {
+		interval = time.Duration(p.PingInterval * float64(time.Second))
+	}
+	timeout := time.Second
+	if p.Timeout > 0 {
+		timeout = time.Duration(p.Timeout * float64(time.Second))
+	}
+	var deadline time.Time
+	if p.Deadline > 0 {
+		deadline = time.Now().Add(time.Duration(p.Deadline) * time.Second)
+	}
+
+	// The datagram sockets set the id of the requests themselves, the replies
+	// are matched by their sequence number
+	id := os.Getpid() & 0xffff
+	var rtts []float64
+	for seq := 0; seq < count; seq++ {
+		if seq > 0 {
+			time.Sleep(interval)
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+
+		req, err := (&icmp.Message{
+			Type: echoType,
+			Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("telegraf")},
+		}).Marshal(nil)
+		if err != nil {
+			return stats, err
+		}
+		start := time.Now()
+		if _, err := c.WriteTo(req, addr); err != nil {
+			return stats, err
+		}
+		stats.trans++
+
+		wait := start.Add(timeout)
+		if !deadline.IsZero() && deadline.Before(wait) {
+			wait = deadline
+		}
+		c.SetReadDeadline(wait)
+		received, ttl, err := readReply(c, p.IPv6, proto, seq, id, privileged)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				continue
+			}
+			return stats, err
+		}
+		stats.recv++
+		stats.ttl = ttl
+		rtts = append(rtts,
+			float64(received.Sub(start))/float64(time.Millisecond))
+	}
+
+	if len(rtts) > 0 {
+		stats.min, stats.max = math.MaxFloat64, 0
+		var sum, sumSquares float64
+		for _, rtt := range rtts {
+			stats.min = math.Min(stats.min, rtt)
+			stats.max = math.Max(stats.max, rtt)
+			sum += rtt
+			sumSquares += rtt * rtt
+		}
+		n := float64(len(rtts))
+		stats.avg = sum / n
+		stats.stddev = math.Sqrt(math.Max(sumSquares/n-stats.avg*stats.avg, 0))
+	}
+	return stats, nil
+}
+
+// readReply reads the echo reply of the request seq, skipping the other ICMP
+// messages received, and returns the time it was received and its TTL.
+func readReply(
+	c *icmp.PacketConn,
+	isIPv6 bool,
+	proto int,
+	seq int,
+	id int,
+	privileged bool,
+) (time.Time, int, error) {
+	b := make([]byte, 1500)
+	for {
+		var n, ttl int
+		var err error
+		if isIPv6 {
+			var cm *ipv6.ControlMessage
+			n, cm, _, err = c.IPv6PacketConn().ReadFrom(b)
+			if cm != nil {
+				ttl = cm.HopLimit
+			}
+		} else {
+			var cm *ipv4.ControlMessage
+			n, cm, _, err = c.IPv4PacketConn().ReadFrom(b)
+			if cm != nil {
+				ttl = cm.TTL
+			}
+		}
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		received := time.Now()
+
+		m, err := icmp.ParseMessage(proto, b[:n])
+		if err != nil {
+			continue
+		}
+		if m.Type != ipv4.ICMPTypeEchoReply &&
+			m.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := m.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+			continue
+		}
+		return received, ttl, nil
+	}
+}
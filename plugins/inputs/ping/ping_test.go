@@ -6,10 +6,12 @@ import (
 	"errors"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // BSD/Darwin ping output
@@ -47,23 +49,31 @@ ping: -i interval too short: Operation not permitted
 
 // Test that ping command output is processed properly
 func TestProcessPingOutput(t *testing.T) {
-	trans, rec, avg, err := processPingOutput(bsdPingOutput)
+	stats, err := processPingOutput(bsdPingOutput)
 	assert.NoError(t, err)
-	assert.Equal(t, 5, trans, "5 packets were transmitted")
-	assert.Equal(t, 5, rec, "5 packets were transmitted")
-	assert.InDelta(t, 20.224, avg, 0.001)
-
-	trans, rec, avg, err = processPingOutput(linuxPingOutput)
+	assert.Equal(t, 5, stats.trans, "5 packets were transmitted")
+	assert.Equal(t, 5, stats.recv, "5 packets were transmitted")
+	assert.InDelta(t, 20.224, stats.avg, 0.001)
+	assert.InDelta(t, 15.087, stats.min, 0.001)
+	assert.InDelta(t, 27.263, stats.max, 0.001)
+	assert.InDelta(t, 4.076, stats.stddev, 0.001)
+	assert.Equal(t, 55, stats.ttl)
+
+	stats, err = processPingOutput(linuxPingOutput)
 	assert.NoError(t, err)
-	assert.Equal(t, 5, trans, "5 packets were transmitted")
-	assert.Equal(t, 5, rec, "5 packets were transmitted")
-	assert.InDelta(t, 43.628, avg, 0.001)
+	assert.Equal(t, 5, stats.trans, "5 packets were transmitted")
+	assert.Equal(t, 5, stats.recv, "5 packets were transmitted")
+	assert.InDelta(t, 43.628, stats.avg, 0.001)
+	assert.InDelta(t, 35.225, stats.min, 0.001)
+	assert.InDelta(t, 51.806, stats.max, 0.001)
+	assert.InDelta(t, 5.325, stats.stddev, 0.001)
+	assert.Equal(t, 63, stats.ttl)
 }
 
 // Test that processPingOutput returns an error when 'ping' fails to run, such
 // as when an invalid argument is provided
 func TestErrorProcessPingOutput(t *testing.T) {
-	_, _, _, err := processPingOutput(fatalPingOutput)
+	_, err := processPingOutput(fatalPingOutput)
 	assert.Error(t, err, "Error was expected from processPingOutput")
 }
 
@@ -106,6 +116,16 @@ func TestArgs(t *testing.T) {
 	sort.Strings(expected)
 	assert.True(t, reflect.DeepEqual(expected, actual),
 		"Expected: %s Actual: %s", expected, actual)
+
+	p.Deadline = 10
+	p.IPv6 = true
+	actual = p.args("www.google.com")
+	expected = []string{"-c", "2", "-I", "eth0", "-t", "12.0", "-i", "1.2",
+		"-w", "10", "-6", "www.google.com"}
+	sort.Strings(actual)
+	sort.Strings(expected)
+	assert.True(t, reflect.DeepEqual(expected, actual),
+		"Expected: %s Actual: %s", expected, actual)
 }
 
 func mockHostPinger(args ...string) (string, error) {
@@ -123,10 +143,14 @@ func TestPingGather(t *testing.T) {
 	p.Gather(&acc)
 	tags := map[string]string{"url": "www.google.com"}
 	fields := map[string]interface{}{
-		"packets_transmitted": 5,
-		"packets_received":    5,
-		"percent_packet_loss": 0.0,
-		"average_response_ms": 43.628,
+		"packets_transmitted":   5,
+		"packets_received":      5,
+		"percent_packet_loss":   0.0,
+		"minimum_response_ms":   35.225,
+		"average_response_ms":   43.628,
+		"maximum_response_ms":   51.806,
+		"standard_deviation_ms": 5.325,
+		"ttl":                   63,
 	}
 	acc.AssertContainsTaggedFields(t, "ping", fields, tags)
 
@@ -160,10 +184,14 @@ func TestLossyPingGather(t *testing.T) {
 	p.Gather(&acc)
 	tags := map[string]string{"url": "www.google.com"}
 	fields := map[string]interface{}{
-		"packets_transmitted": 5,
-		"packets_received":    3,
-		"percent_packet_loss": 40.0,
-		"average_response_ms": 44.033,
+		"packets_transmitted":   5,
+		"packets_received":      3,
+		"percent_packet_loss":   40.0,
+		"minimum_response_ms":   35.225,
+		"average_response_ms":   44.033,
+		"maximum_response_ms":   51.806,
+		"standard_deviation_ms": 5.325,
+		"ttl":                   63,
 	}
 	acc.AssertContainsTaggedFields(t, "ping", fields, tags)
 }
@@ -221,3 +249,27 @@ func TestFatalPingGather(t *testing.T) {
 	assert.False(t, acc.HasMeasurement("average_response_ms"),
 		"Fatal ping should not have packet measurements")
 }
+
+// Test that the native method pings the loopback, when telegraf may open ICMP
+// sockets
+func TestNativePingGather(t *testing.T) {
+	var acc testutil.Accumulator
+	p := Ping{
+		Urls:         []string{"127.0.0.1"},
+		Count:        2,
+		PingInterval: 0.1,
+		Method:       "native",
+	}
+
+	err := p.Gather(&acc)
+	if err != nil && strings.Contains(err.Error(), "could not open an ICMP socket") {
+		t.Skip("Skipping native ping, ICMP sockets are not permitted")
+	}
+	assert.NoError(t, err)
+	m, ok := acc.Get("ping")
+	require.True(t, ok)
+	assert.Equal(t, 2, m.Fields["packets_transmitted"])
+	assert.Equal(t, 2, m.Fields["packets_received"])
+	assert.True(t, acc.HasFloatField("ping", "average_response_ms"))
+	assert.Contains(t, m.Fields, "ttl")
+}
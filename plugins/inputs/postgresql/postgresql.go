@@ -5,17 +5,34 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
 
 	_ "github.com/lib/pq"
 )
 
 type Postgresql struct {
-	Address        string
+	Address string
+	// Addresses of the other servers gathered
+	Addresses      []string
 	Databases      []string
 	OrderedColumns []string
+
+	GatherBgwriter    bool `toml:"gather_bgwriter"`
+	GatherReplication bool `toml:"gather_replication"`
+	Query             []Query
+}
+
+// Query is a custom query run on every server, adding a metric per row of its
+// result to the measurement, postgresql if empty. The tag columns tag the metrics, the other columns are their fields,
+// except the NULL and timestamp values.
+type Query struct {
+	SQLQuery    string   `toml:"sqlquery"`
+	Measurement string   `toml:"measurement"`
+	TagColumns  []string `toml:"tag_columns"`
 }
 
 var ignoredColumns = map[string]bool{"datid": true, "datname": true, "stats_reset": true}
@@ -34,10 +51,26 @@ var sampleConfig = `
   # to grab metrics for.
   #
   address = "host=localhost user=postgres sslmode=disable"
+  # Addresses of other servers to gather
+  # addresses = ["host=replica user=postgres sslmode=disable"]
 
   # A list of databases to pull metrics about. If not specified, metrics for all
   # databases are gathered.
   # databases = ["app_production", "testing"]
+
+  # Gather pg_stat_bgwriter in the postgresql_bgwriter measurement
+  gather_bgwriter = true
+  # Gather the replication lag in the postgresql_replication measurement: the
+  # replay_lag of the standbys, in seconds, and the lag_bytes of each standby
+  # of the primaries, PostgreSQL 9.2+
+  gather_replication = false
+
+  # Custom queries, adding a metric per row of the result to the measurement.
+  # The tag_columns tag the metrics, the other columns are their fields.
+  # [[inputs.postgresql.query]]
+  #   sqlquery = "SELECT datname, count(*) AS connections FROM pg_stat_activity GROUP BY datname"
+  #   measurement = "postgresql_connections"
+  #   tag_columns = ["datname"]
 `
 
 func (p *Postgresql) SampleConfig() string {
@@ -55,13 +88,29 @@ func (p *Postgresql) IgnoredColumns() map[string]bool {
 var localhost = "host=localhost sslmode=disable"
 
 func (p *Postgresql) Gather(acc telegraf.Accumulator) error {
-	var query string
-
-	if p.Address == "" || p.Address == "localhost" {
+	if len(p.Addresses) == 0 && (p.Address == "" || p.Address == "localhost") {
 		p.Address = localhost
 	}
 
-	db, err := sql.Open("postgres", p.Address)
+	addresses := p.Addresses
+	if p.Address != "" {
+		addresses = append([]string{p.Address}, addresses...)
+	}
+	for _, address := range addresses {
+		if err := p.gatherServer(address, acc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Postgresql) gatherServer(
+	address string,
+	acc telegraf.Accumulator,
+) error {
+	var query string
+
+	db, err := sql.Open("postgres", address)
 	if err != nil {
 		return err
 	}
@@ -89,20 +138,186 @@ func (p *Postgresql) Gather(acc telegraf.Accumulator) error {
 	}
 
 	for rows.Next() {
-		err = p.accRow(rows, acc)
+		err = p.accRow(address, rows, acc)
 		if err != nil {
 			return err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tags := map[string]string{"server": address}
+	if p.GatherBgwriter {
+		err := gatherQuery(db, acc, tags, Query{
+			SQLQuery:    `SELECT * FROM pg_stat_bgwriter`,
+			Measurement: "postgresql_bgwriter",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if p.GatherReplication {
+		if err := gatherReplication(db, acc, tags); err != nil {
+			return err
+		}
+	}
+	for _, q := range p.Query {
+		if err := gatherQuery(db, acc, tags, q); err != nil {
+			return fmt.Errorf("error running query %q: %s", q.SQLQuery, err)
+		}
+	}
+	return nil
+}
+
+// gatherReplication adds the replication lag: the seconds since the last
+// transaction replayed by a standby, and the bytes of WAL not yet replayed by
+// each standby of a primary, tagged with its application_name and
+// client_addr
+func gatherReplication(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	tags map[string]string,
+) error {
+	var recovery bool
+	var lag sql.NullFloat64
+	err := db.QueryRow(`SELECT pg_is_in_recovery(),
+		EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())`).Scan(
+		&recovery, &lag)
+	if err != nil {
+		return err
+	}
+	if recovery {
+		// No transaction was replayed yet
+		if lag.Valid {
+			acc.AddFields("postgresql_replication",
+				map[string]interface{}{"replay_lag": lag.Float64}, tags)
+		}
+		return nil
+	}
+
+	rows, err := db.Query(`SELECT application_name,
+		COALESCE(client_addr::text, ''),
+		pg_xlog_location_diff(pg_current_xlog_location(), replay_location)
+		FROM pg_stat_replication`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, addr string
+		var lagBytes sql.NullFloat64
+		if err := rows.Scan(&name, &addr, &lagBytes); err != nil {
+			return err
+		}
+		if !lagBytes.Valid {
+			continue
+		}
+		standbyTags := map[string]string{
+			"application_name": name,
+			"client_addr":      addr,
+		}
+		for k, v := range tags {
+			standbyTags[k] = v
+		}
+		acc.AddFields("postgresql_replication",
+			map[string]interface{}{"lag_bytes": int64(lagBytes.Float64)},
+			standbyTags)
+	}
+	return rows.Err()
+}
 
+// gatherQuery runs a query, adding a metric per row of its result
+func gatherQuery(
+	db *sql.DB,
+	acc telegraf.Accumulator,
+	tags map[string]string,
+	q Query,
+) error {
+	rows, err := db.Query(q.SQLQuery)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	measurement := q.Measurement
+	if measurement == "" {
+		measurement = "postgresql"
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		rowTags, fields := queryRow(q, columns, values)
+		for k, v := range tags {
+			rowTags[k] = v
+		}
+		if len(fields) > 0 {
+			acc.AddFields(measurement, fields, rowTags)
+		}
+	}
 	return rows.Err()
 }
 
+// queryRow returns the tags and fields of a row of the result of a query. The
+// text values are fields of the type they parse as.
+func queryRow(
+	q Query,
+	columns []string,
+	values []interface{},
+) (map[string]string, map[string]interface{}) {
+	tags := make(map[string]string)
+	fields := make(map[string]interface{})
+	for i, column := range columns {
+		value := values[i]
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		isTag := false
+		for _, tag := range q.TagColumns {
+			if tag == column {
+				isTag = true
+			}
+		}
+
+		switch v := value.(type) {
+		case nil, time.Time:
+		case string:
+			if isTag {
+				tags[column] = v
+			} else {
+				fields[column] = parsers.ParseValue(v)
+			}
+		default:
+			if isTag {
+				tags[column] = fmt.Sprint(v)
+			} else {
+				fields[column] = v
+			}
+		}
+	}
+	return tags, fields
+}
+
 type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func (p *Postgresql) accRow(row scanner, acc telegraf.Accumulator) error {
+func (p *Postgresql) accRow(
+	address string,
+	row scanner,
+	acc telegraf.Accumulator,
+) error {
 	var columnVars []interface{}
 	var dbname bytes.Buffer
 
@@ -131,7 +346,7 @@ func (p *Postgresql) accRow(row scanner, acc telegraf.Accumulator) error {
 		dbname.WriteString(string(dbnameChars[i]))
 	}
 
-	tags := map[string]string{"server": p.Address, "db": dbname.String()}
+	tags := map[string]string{"server": address, "db": dbname.String()}
 
 	fields := make(map[string]interface{})
 	for col, val := range columnMap {
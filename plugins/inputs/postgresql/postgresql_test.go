@@ -3,6 +3,7 @@ package postgresql
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -144,3 +145,26 @@ func TestPostgresqlIgnoresUnwantedColumns(t *testing.T) {
 		assert.False(t, acc.HasMeasurement(col))
 	}
 }
+
+func TestPostgresqlQueryRow(t *testing.T) {
+	q := Query{
+		SQLQuery:    "SELECT * FROM connections",
+		Measurement: "postgresql_connections",
+		TagColumns:  []string{"datname", "state"},
+	}
+	columns := []string{"datname", "state", "connections", "ratio", "waiting",
+		"since", "missing"}
+	values := []interface{}{[]byte("postgres"), int64(1), int64(3),
+		[]byte("0.5"), true, time.Now(), nil}
+
+	tags, fields := queryRow(q, columns, values)
+	assert.Equal(t, map[string]string{
+		"datname": "postgres",
+		"state":   "1",
+	}, tags)
+	assert.Equal(t, map[string]interface{}{
+		"connections": int64(3),
+		"ratio":       float64(0.5),
+		"waiting":     true,
+	}, fields)
+}
@@ -3,7 +3,6 @@ package procstat
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -15,10 +14,16 @@ import (
 )
 
 type Procstat struct {
-	PidFile string `toml:"pid_file"`
-	Exe     string
-	Pattern string
-	Prefix  string
+	PidFile     string `toml:"pid_file"`
+	Exe         string
+	Pattern     string
+	User        string
+	SystemdUnit string `toml:"systemd_unit"`
+	Prefix      string
+	// IncludeChildren adds the stats of the children of the processes,
+	// aggregated in the children_ fields
+	IncludeChildren bool            `toml:"include_children"`
+	Log             telegraf.Logger `toml:"-"`
 
 	pidmap map[int32]*process.Process
 }
@@ -30,16 +35,25 @@ func NewProcstat() *Procstat {
 }
 
 var sampleConfig = `
-  # Must specify one of: pid_file, exe, or pattern
+  # Must specify one of: pid_file, exe, pattern, user or systemd_unit
   # PID file to monitor process
   pid_file = "/var/run/nginx.pid"
   # executable name (ie, pgrep <exe>)
   # exe = "nginx"
   # pattern as argument for pgrep (ie, pgrep -f <pattern>)
   # pattern = "nginx"
+  # user as argument for pgrep (ie, pgrep -u <user>), alone or with exe or
+  # pattern
+  # user = "nginx"
+  # main process of a systemd unit (ie, systemctl show <unit> -p MainPID)
+  # systemd_unit = "nginx.service"
 
   # Field name prefix
   prefix = ""
+
+  # Add the stats of the children of the processes, aggregated in the
+  # children_ fields
+  # include_children = false
 `
 
 func (_ *Procstat) SampleConfig() string {
@@ -53,12 +67,16 @@ func (_ *Procstat) Description() string {
 func (p *Procstat) Gather(acc telegraf.Accumulator) error {
 	err := p.createProcesses()
 	if err != nil {
-		log.Printf("Error: procstat getting process, exe: [%s] pidfile: [%s] pattern: [%s] %s",
+		p.Log.Errorf("Getting process, exe: [%s] pidfile: [%s] pattern: [%s] %s",
 			p.Exe, p.PidFile, p.Pattern, err.Error())
 	} else {
 		for _, proc := range p.pidmap {
-			p := NewSpecProcessor(p.Prefix, acc, proc)
-			p.pushMetrics()
+			sp := NewSpecProcessor(p.Prefix, acc, proc)
+			for k, v := range p.selectorTags() {
+				sp.tags[k] = v
+			}
+			sp.IncludeChildren = p.IncludeChildren
+			sp.pushMetrics()
 		}
 	}
 
@@ -74,17 +92,21 @@ func (p *Procstat) createProcesses() error {
 		errstring += err.Error() + " "
 	}
 
+	// The processes are kept from one gather to the next, for their cpu
+	// usage, until they exit
+	pidmap := make(map[int32]*process.Process, len(pids))
 	for _, pid := range pids {
-		_, ok := p.pidmap[pid]
+		proc, ok := p.pidmap[pid]
 		if !ok {
-			proc, err := process.NewProcess(pid)
-			if err == nil {
-				p.pidmap[pid] = proc
-			} else {
+			proc, err = process.NewProcess(pid)
+			if err != nil {
 				errstring += err.Error() + " "
+				continue
 			}
 		}
+		pidmap[pid] = proc
 	}
+	p.pidmap = pidmap
 
 	if errstring != "" {
 		outerr = fmt.Errorf("%s", errstring)
@@ -99,17 +121,47 @@ func (p *Procstat) getAllPids() ([]int32, error) {
 
 	if p.PidFile != "" {
 		pids, err = pidsFromFile(p.PidFile)
-	} else if p.Exe != "" {
-		pids, err = pidsFromExe(p.Exe)
-	} else if p.Pattern != "" {
-		pids, err = pidsFromPattern(p.Pattern)
+	} else if p.SystemdUnit != "" {
+		pids, err = pidsFromSystemdUnit(p.SystemdUnit)
+	} else if p.Exe != "" || p.Pattern != "" || p.User != "" {
+		var args []string
+		if p.User != "" {
+			args = append(args, "-u", p.User)
+		}
+		if p.Pattern != "" {
+			args = append(args, "-f", p.Pattern)
+		} else if p.Exe != "" {
+			args = append(args, p.Exe)
+		}
+		pids, err = pidsFromPgrep(args...)
 	} else {
-		err = fmt.Errorf("Either exe, pid_file or pattern has to be specified")
+		err = fmt.Errorf("Either exe, pid_file, pattern, user or systemd_unit " +
+			"has to be specified")
 	}
 
 	return pids, err
 }
 
+// selectorTags returns the tags of the option selecting the processes
+func (p *Procstat) selectorTags() map[string]string {
+	tags := make(map[string]string)
+	if p.PidFile != "" {
+		tags["pidfile"] = p.PidFile
+	} else if p.SystemdUnit != "" {
+		tags["systemd_unit"] = p.SystemdUnit
+	} else {
+		if p.User != "" {
+			tags["user"] = p.User
+		}
+		if p.Pattern != "" {
+			tags["pattern"] = p.Pattern
+		} else if p.Exe != "" {
+			tags["exe"] = p.Exe
+		}
+	}
+	return tags
+}
+
 func pidsFromFile(file string) ([]int32, error) {
 	var out []int32
 	var outerr error
@@ -127,38 +179,36 @@ func pidsFromFile(file string) ([]int32, error) {
 	return out, outerr
 }
 
-func pidsFromExe(exe string) ([]int32, error) {
-	var out []int32
-	var outerr error
-	bin, err := exec.LookPath("pgrep")
+// pidsFromSystemdUnit returns the pid of the main process of the unit
+func pidsFromSystemdUnit(unit string) ([]int32, error) {
+	bin, err := exec.LookPath("systemctl")
 	if err != nil {
-		return out, fmt.Errorf("Couldn't find pgrep binary: %s", err)
+		return nil, fmt.Errorf("Couldn't find systemctl binary: %s", err)
 	}
-	pgrep, err := exec.Command(bin, exe).Output()
+	out, err := exec.Command(bin, "show", unit, "-p", "MainPID").Output()
 	if err != nil {
-		return out, fmt.Errorf("Failed to execute %s. Error: '%s'", bin, err)
-	} else {
-		pids := strings.Fields(string(pgrep))
-		for _, pid := range pids {
-			ipid, err := strconv.Atoi(pid)
-			if err == nil {
-				out = append(out, int32(ipid))
-			} else {
-				outerr = err
-			}
-		}
+		return nil, fmt.Errorf("Failed to execute %s. Error: '%s'", bin, err)
 	}
-	return out, outerr
+	// The output is MainPID=<pid>, 0 if the unit is not running
+	value := strings.TrimPrefix(strings.TrimSpace(string(out)), "MainPID=")
+	pid, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("Unexpected output of %s: '%s'", bin, out)
+	}
+	if pid == 0 {
+		return nil, fmt.Errorf("Unit '%s' is not running", unit)
+	}
+	return []int32{int32(pid)}, nil
 }
 
-func pidsFromPattern(pattern string) ([]int32, error) {
+func pidsFromPgrep(args ...string) ([]int32, error) {
 	var out []int32
 	var outerr error
 	bin, err := exec.LookPath("pgrep")
 	if err != nil {
 		return out, fmt.Errorf("Couldn't find pgrep binary: %s", err)
 	}
-	pgrep, err := exec.Command(bin, "-f", pattern).Output()
+	pgrep, err := exec.Command(bin, args...).Output()
 	if err != nil {
 		return out, fmt.Errorf("Failed to execute %s. Error: '%s'", bin, err)
 	} else {
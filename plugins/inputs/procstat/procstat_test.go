@@ -3,6 +3,7 @@ package procstat
 import (
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"strconv"
 	"testing"
 
@@ -24,9 +25,52 @@ func TestGather(t *testing.T) {
 	p := Procstat{
 		PidFile: file.Name(),
 		Prefix:  "foo",
+		Log:     testutil.Logger{},
 		pidmap:  make(map[int32]*process.Process),
 	}
 	p.Gather(&acc)
 	assert.True(t, acc.HasFloatField("procstat", "foo_cpu_time_user"))
 	assert.True(t, acc.HasUIntField("procstat", "foo_memory_vms"))
 }
+
+func TestGatherChildren(t *testing.T) {
+	// The test process is the parent of a sleep
+	cmd := exec.Command("sleep", "10")
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	var acc testutil.Accumulator
+	pid := os.Getpid()
+	file, err := ioutil.TempFile(os.TempDir(), "telegraf")
+	require.NoError(t, err)
+	file.Write([]byte(strconv.Itoa(pid)))
+	file.Close()
+	defer os.Remove(file.Name())
+	p := Procstat{
+		PidFile:         file.Name(),
+		IncludeChildren: true,
+		Log:             testutil.Logger{},
+		pidmap:          make(map[int32]*process.Process),
+	}
+	p.Gather(&acc)
+
+	m, ok := acc.Get("procstat")
+	require.True(t, ok)
+	assert.Equal(t, file.Name(), m.Tags["pidfile"])
+	assert.Equal(t, strconv.Itoa(pid), m.Tags["pid"])
+	assert.Contains(t, m.Fields, "num_threads")
+	children, ok := m.Fields["children"].(int32)
+	require.True(t, ok)
+	assert.True(t, children >= 1)
+	assert.True(t, acc.HasUIntField("procstat", "children_memory_rss"))
+}
+
+func TestSelectorTags(t *testing.T) {
+	p := Procstat{User: "nginx", Exe: "nginx"}
+	assert.Equal(t, map[string]string{"user": "nginx", "exe": "nginx"},
+		p.selectorTags())
+
+	p = Procstat{SystemdUnit: "nginx.service"}
+	assert.Equal(t, map[string]string{"systemd_unit": "nginx.service"},
+		p.selectorTags())
+}
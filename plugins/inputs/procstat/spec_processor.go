@@ -11,10 +11,12 @@ import (
 
 type SpecProcessor struct {
 	Prefix string
-	tags   map[string]string
-	fields map[string]interface{}
-	acc    telegraf.Accumulator
-	proc   *process.Process
+	// IncludeChildren adds the stats of the children of the process
+	IncludeChildren bool
+	tags            map[string]string
+	fields          map[string]interface{}
+	acc             telegraf.Accumulator
+	proc            *process.Process
 }
 
 func (p *SpecProcessor) add(metric string, value interface{}) {
@@ -53,10 +55,14 @@ func NewSpecProcessor(
 
 func (p *SpecProcessor) pushMetrics() {
 	p.pushFDStats()
+	p.pushThreadStats()
 	p.pushCtxStats()
 	p.pushIOStats()
 	p.pushCPUStats()
 	p.pushMemoryStats()
+	if p.IncludeChildren {
+		p.pushChildrenStats()
+	}
 	p.flush()
 }
 
@@ -69,6 +75,15 @@ func (p *SpecProcessor) pushFDStats() error {
 	return nil
 }
 
+func (p *SpecProcessor) pushThreadStats() error {
+	threads, err := p.proc.NumThreads()
+	if err != nil {
+		return fmt.Errorf("NumThreads error: %s\n", err)
+	}
+	p.add("num_threads", threads)
+	return nil
+}
+
 func (p *SpecProcessor) pushCtxStats() error {
 	ctx, err := p.proc.NumCtxSwitches()
 	if err != nil {
@@ -87,7 +102,7 @@ func (p *SpecProcessor) pushIOStats() error {
 	p.add("read_count", io.ReadCount)
 	p.add("write_count", io.WriteCount)
 	p.add("read_bytes", io.ReadBytes)
-	p.add("write_bytes", io.WriteCount)
+	p.add("write_bytes", io.WriteBytes)
 	return nil
 }
 
@@ -129,3 +144,52 @@ func (p *SpecProcessor) pushMemoryStats() error {
 	p.add("memory_swap", mem.Swap)
 	return nil
 }
+
+// pushChildrenStats adds the number of descendants of the process, and the
+// sum of their memory, threads, file descriptors and cpu times
+func (p *SpecProcessor) pushChildrenStats() error {
+	var count, threads, fds int32
+	var rss, vms uint64
+	var user, system float64
+
+	children := descendants(p.proc)
+	for _, child := range children {
+		if mem, err := child.MemoryInfo(); err == nil {
+			rss += mem.RSS
+			vms += mem.VMS
+		}
+		if n, err := child.NumThreads(); err == nil {
+			threads += n
+		}
+		if n, err := child.NumFDs(); err == nil {
+			fds += n
+		}
+		if cpu, err := child.CPUTimes(); err == nil {
+			user += cpu.User
+			system += cpu.System
+		}
+		count++
+	}
+	p.add("children", count)
+	p.add("children_memory_rss", rss)
+	p.add("children_memory_vms", vms)
+	p.add("children_num_threads", threads)
+	p.add("children_num_fds", fds)
+	p.add("children_cpu_time_user", user)
+	p.add("children_cpu_time_system", system)
+	return nil
+}
+
+// descendants returns the children of the process, and their children
+func descendants(proc *process.Process) []*process.Process {
+	// The processes without children return an error
+	children, err := proc.Children()
+	if err != nil {
+		return nil
+	}
+	all := children
+	for _, child := range children {
+		all = append(all, descendants(child)...)
+	}
+	return all
+}
@@ -0,0 +1,145 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+)
+
+// The credentials of the service account of the pod telegraf runs in
+const (
+	serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCA    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// The annotations of the pods scraped
+const (
+	annotationScrape = "prometheus.io/scrape"
+	annotationScheme = "prometheus.io/scheme"
+	annotationPort   = "prometheus.io/port"
+	annotationPath   = "prometheus.io/path"
+)
+
+// podList is the part of the pod list of the Kubernetes API used to discover
+// the pods scraped
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// kubernetesClient lists the pods of the Kubernetes API, with the token of
+// the service account of telegraf if any
+type kubernetesClient struct {
+	apiURL    string
+	tokenFile string
+	client    *http.Client
+}
+
+// newKubernetesClient returns the client of the Kubernetes API at apiURL, the
+// API of the cluster telegraf runs in if empty.
+func newKubernetesClient(apiURL string) (*kubernetesClient, error) {
+	c := httpconfig.Config{
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	if apiURL == "" {
+		host := os.Getenv("KUBERNETES_SERVICE_HOST")
+		port := os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("kubernetes_api_url must be set when not " +
+				"running in a Kubernetes cluster")
+		}
+		apiURL = "https://" + net.JoinHostPort(host, port)
+		c.TLS.SSLCA = serviceAccountCA
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return nil, err
+	}
+	return &kubernetesClient{
+		apiURL:    strings.TrimSuffix(apiURL, "/"),
+		tokenFile: serviceAccountToken,
+		client:    client,
+	}, nil
+}
+
+// scrapeURLs returns the URLs of the running pods of the namespace, all
+// namespaces if empty, annotated with prometheus.io/scrape = "true", and the
+// namespace and name of their pod as tags. The scheme, port and path of the
+// URLs are set by the prometheus.io/scheme, prometheus.io/port and
+// prometheus.io/path annotations, "http", 9102 and "/metrics" by default.
+func (k *kubernetesClient) scrapeURLs(
+	namespace string,
+) (map[string]map[string]string, error) {
+	path := "/api/v1/pods"
+	if namespace != "" {
+		path = "/api/v1/namespaces/" + url.QueryEscape(namespace) + "/pods"
+	}
+	req, err := http.NewRequest("GET", k.apiURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The token is read on every request, as it is rotated
+	if token, err := ioutil.ReadFile(k.tokenFile); err == nil {
+		req.Header.Set("Authorization",
+			"Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the Kubernetes pods: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error listing the Kubernetes pods: %s "+
+			"returned HTTP status %s", req.URL, resp.Status)
+	}
+	var pods podList
+	if err := json.NewDecoder(resp.Body).Decode(&pods); err != nil {
+		return nil, fmt.Errorf("error decoding the Kubernetes pods: %s", err)
+	}
+
+	urls := make(map[string]map[string]string)
+	for _, pod := range pods.Items {
+		annotations := pod.Metadata.Annotations
+		if annotations[annotationScrape] != "true" ||
+			pod.Status.Phase != "Running" || pod.Status.PodIP == "" {
+			continue
+		}
+		u := url.URL{
+			Scheme: annotations[annotationScheme],
+			Host:   pod.Status.PodIP + ":" + annotations[annotationPort],
+			Path:   annotations[annotationPath],
+		}
+		if u.Scheme == "" {
+			u.Scheme = "http"
+		}
+		if annotations[annotationPort] == "" {
+			u.Host = pod.Status.PodIP + ":9102"
+		}
+		if u.Path == "" {
+			u.Path = "/metrics"
+		}
+		urls[u.String()] = map[string]string{
+			"namespace": pod.Metadata.Namespace,
+			"pod_name":  pod.Metadata.Name,
+		}
+	}
+	return urls, nil
+}
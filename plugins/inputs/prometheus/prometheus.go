@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
@@ -14,11 +16,76 @@ import (
 
 type Prometheus struct {
 	Urls []string
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	KeepAlive          internal.Duration `toml:"keep_alive"`
+	MaxIdleConns       int               `toml:"max_idle_conns"`
+	Headers            map[string]string
+	Username           string
+	Password           string
+	BearerToken        string   `toml:"bearer_token"`
+	OAuth2ClientID     string   `toml:"oauth2_client_id"`
+	OAuth2ClientSecret string   `toml:"oauth2_client_secret"`
+	OAuth2TokenURL     string   `toml:"oauth2_token_url"`
+	OAuth2Scopes       []string `toml:"oauth2_scopes"`
+
+	// TLS options of the https urls
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Scrape the pods of the Kubernetes API annotated with
+	// prometheus.io/scrape = "true", see kubernetesClient.scrapeURLs
+	MonitorKubernetesPods bool   `toml:"monitor_kubernetes_pods"`
+	KubernetesNamespace   string `toml:"kubernetes_namespace"`
+	KubernetesAPIURL      string `toml:"kubernetes_api_url"`
+
+	client     *http.Client
+	kubernetes *kubernetesClient
 }
 
 var sampleConfig = `
   # An array of urls to scrape metrics from.
   urls = ["http://localhost:9100/metrics"]
+
+  # Timeout of the requests, and period of the TCP keep-alives
+  # timeout = "5s"
+  # keep_alive = "30s"
+  # Idle connections kept open per url, -1 to close the connections after
+  # every scrape
+  # max_idle_conns = 2
+
+  # Credentials of the requests, either basic auth, a bearer token, or an
+  # OAuth2 client requesting the bearer tokens from oauth2_token_url
+  # username = ""
+  # password = ""
+  # bearer_token = ""
+  # oauth2_client_id = ""
+  # oauth2_client_secret = ""
+  # oauth2_token_url = ""
+  # oauth2_scopes = []
+
+  # TLS options of the https urls
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Scrape the running pods annotated with prometheus.io/scrape = "true",
+  # at the port and path of their prometheus.io/port and prometheus.io/path
+  # annotations, 9102 and /metrics by default. The pods are listed from the
+  # API of the cluster telegraf runs in, with its service account, unless
+  # kubernetes_api_url is set.
+  # monitor_kubernetes_pods = false
+  # Only scrape the pods of this namespace, all namespaces by default
+  # kubernetes_namespace = ""
+  # kubernetes_api_url = ""
+
+  # Headers added to the requests
+  # [inputs.prometheus.headers]
+  #   X-Scope-OrgID = "telegraf"
 `
 
 func (r *Prometheus) SampleConfig() string {
@@ -29,21 +96,78 @@ func (r *Prometheus) Description() string {
 	return "Read metrics from one or many prometheus clients"
 }
 
+// Init creates the HTTP client of the plugin, and the client of the
+// Kubernetes API if monitoring the pods.
+func (g *Prometheus) Init() error {
+	c := httpconfig.Config{
+		Timeout:      g.Timeout.Duration,
+		KeepAlive:    g.KeepAlive.Duration,
+		MaxIdleConns: g.MaxIdleConns,
+		Headers:      g.Headers,
+		Username:     g.Username,
+		Password:     g.Password,
+		BearerToken:  g.BearerToken,
+		OAuth2: httpconfig.OAuth2Config{
+			ClientID:     g.OAuth2ClientID,
+			ClientSecret: g.OAuth2ClientSecret,
+			TokenURL:     g.OAuth2TokenURL,
+			Scopes:       g.OAuth2Scopes,
+		},
+		TLS: internal.TLSOptions{
+			SSLCA:              g.SSLCA,
+			SSLCert:            g.SSLCert,
+			SSLKey:             g.SSLKey,
+			InsecureSkipVerify: g.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	if g.MonitorKubernetesPods {
+		g.kubernetes, err = newKubernetesClient(g.KubernetesAPIURL)
+		if err != nil {
+			return err
+		}
+	}
+	g.client = client
+	return nil
+}
+
 var ErrProtocolError = errors.New("prometheus protocol error")
 
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (g *Prometheus) Gather(acc telegraf.Accumulator) error {
+	// The urls of the config have no extra tags, the urls of the pods are
+	// tagged with their namespace and name
+	urls := make(map[string]map[string]string)
+	for _, u := range g.Urls {
+		urls[u] = nil
+	}
+	if g.kubernetes != nil {
+		pods, err := g.kubernetes.scrapeURLs(g.KubernetesNamespace)
+		if err != nil {
+			return err
+		}
+		for u, tags := range pods {
+			urls[u] = tags
+		}
+	}
+
 	var wg sync.WaitGroup
 
 	var outerr error
 
-	for _, serv := range g.Urls {
+	for serv, tags := range urls {
 		wg.Add(1)
-		go func(serv string) {
+		go func(serv string, tags map[string]string) {
 			defer wg.Done()
-			outerr = g.gatherURL(serv, acc)
-		}(serv)
+			if err := g.gatherURL(serv, tags, acc); err != nil {
+				outerr = err
+			}
+		}(serv, tags)
 	}
 
 	wg.Wait()
@@ -51,8 +175,12 @@ func (g *Prometheus) Gather(acc telegraf.Accumulator) error {
 	return outerr
 }
 
-func (g *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
-	resp, err := http.Get(url)
+func (g *Prometheus) gatherURL(
+	url string,
+	extraTags map[string]string,
+	acc telegraf.Accumulator,
+) error {
+	resp, err := g.client.Get(url)
 	if err != nil {
 		return fmt.Errorf("error making HTTP request to %s: %s", url, err)
 	}
@@ -89,6 +217,9 @@ func (g *Prometheus) gatherURL(url string, acc telegraf.Accumulator) error {
 				}
 				tags[string(key)] = string(value)
 			}
+			for key, value := range extraTags {
+				tags[key] = value
+			}
 			acc.Add("prometheus_"+string(sample.Metric[model.MetricNameLabel]),
 				float64(sample.Value), tags)
 		}
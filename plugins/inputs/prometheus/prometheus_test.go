@@ -2,8 +2,10 @@ package prometheus
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
@@ -25,6 +27,29 @@ go_gc_duration_seconds_count 7
 go_goroutines 15
 `
 
+func TestPrometheusBearerToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Urls: []string{ts.URL},
+	}
+	require.NoError(t, p.Init())
+	var acc testutil.Accumulator
+	assert.Error(t, p.Gather(&acc))
+
+	p.BearerToken = "secret"
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Gather(&acc))
+	assert.True(t, acc.HasFloatField("prometheus_go_goroutines", "value"))
+}
+
 func TestPrometheusGeneratesMetrics(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, sampleTextFormat)
@@ -34,6 +59,7 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 	p := &Prometheus{
 		Urls: []string{ts.URL},
 	}
+	require.NoError(t, p.Init())
 
 	var acc testutil.Accumulator
 
@@ -53,3 +79,48 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 		assert.True(t, acc.HasFloatField(e.name, "value"))
 	}
 }
+
+func TestPrometheusKubernetesPods(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/default/pods" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"items": [
+			{"metadata": {"name": "web", "namespace": "default",
+			  "annotations": {"prometheus.io/scrape": "true",
+			    "prometheus.io/port": "%s"}},
+			 "status": {"phase": "Running", "podIP": "127.0.0.1"}},
+			{"metadata": {"name": "db", "namespace": "default"},
+			 "status": {"phase": "Running", "podIP": "127.0.0.2"}},
+			{"metadata": {"name": "job", "namespace": "default",
+			  "annotations": {"prometheus.io/scrape": "true"}},
+			 "status": {"phase": "Succeeded", "podIP": "127.0.0.3"}}
+		]}`, port)
+	}))
+	defer api.Close()
+
+	p := &Prometheus{
+		MonitorKubernetesPods: true,
+		KubernetesNamespace:   "default",
+		KubernetesAPIURL:      api.URL,
+	}
+	require.NoError(t, p.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "prometheus_go_goroutines",
+		map[string]interface{}{"value": float64(15)},
+		map[string]string{"namespace": "default", "pod_name": "web"})
+
+	p.KubernetesNamespace = "other"
+	assert.Error(t, p.Gather(&acc))
+}
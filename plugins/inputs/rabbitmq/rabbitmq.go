@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -22,6 +24,16 @@ type RabbitMQ struct {
 	Password string
 	Nodes    []string
 	Queues   []string
+	// Globs of the names of the queues gathered, all if empty, and of the
+	// queues not gathered
+	QueueNameInclude []string `toml:"queue_name_include"`
+	QueueNameExclude []string `toml:"queue_name_exclude"`
+
+	Timeout            internal.Duration
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
 
 	Client *http.Client
 }
@@ -47,6 +59,11 @@ type MessageStats struct {
 	PublishDetails    Details `json:"publish_details"`
 	Redeliver         int64
 	RedeliverDetails  Details `json:"redeliver_details"`
+	// Messages published to and routed by the exchanges
+	PublishIn         int64   `json:"publish_in"`
+	PublishInDetails  Details `json:"publish_in_details"`
+	PublishOut        int64   `json:"publish_out"`
+	PublishOutDetails Details `json:"publish_out_details"`
 }
 
 type ObjectTotals struct {
@@ -73,7 +90,7 @@ type Queue struct {
 	MessageStats        `json:"message_stats"`
 	Memory              int64
 	Consumers           int64
-	ConsumerUtilisation float64 `json:"consumer_utilisation"`
+	ConsumerUtilisation Utilisation `json:"consumer_utilisation"`
 	Name                string
 	Node                string
 	Vhost               string
@@ -81,6 +98,28 @@ type Queue struct {
 	AutoDelete          bool `json:"auto_delete"`
 }
 
+// Utilisation is the consumer utilisation of a queue, an empty string in the
+// responses when the queue has no consumers
+type Utilisation float64
+
+func (u *Utilisation) UnmarshalJSON(b []byte) error {
+	if string(b) == `""` {
+		*u = 0
+		return nil
+	}
+	return json.Unmarshal(b, (*float64)(u))
+}
+
+type Exchange struct {
+	Name         string
+	MessageStats `json:"message_stats"`
+	Type         string
+	Vhost        string
+	Internal     bool
+	Durable      bool
+	AutoDelete   bool `json:"auto_delete"`
+}
+
 type Node struct {
 	Name string
 
@@ -99,7 +138,8 @@ type Node struct {
 
 type gatherFunc func(r *RabbitMQ, acc telegraf.Accumulator, errChan chan error)
 
-var gatherFunctions = []gatherFunc{gatherOverview, gatherNodes, gatherQueues}
+var gatherFunctions = []gatherFunc{gatherOverview, gatherNodes, gatherQueues,
+	gatherExchanges}
 
 var sampleConfig = `
   url = "http://localhost:15672" # required
@@ -110,6 +150,17 @@ var sampleConfig = `
   # A list of nodes to pull metrics about. If not specified, metrics for
   # all nodes are gathered.
   # nodes = ["rabbit@node1", "rabbit@node2"]
+
+  # Only gather the queues matching these globs, all if empty, and ignore
+  # those matching the excluded globs, to limit the number of series
+  # queue_name_include = []
+  # queue_name_exclude = ["amq.gen-*"]
+
+  # timeout = "5s"
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
 `
 
 func (r *RabbitMQ) SampleConfig() string {
@@ -122,7 +173,20 @@ func (r *RabbitMQ) Description() string {
 
 func (r *RabbitMQ) Gather(acc telegraf.Accumulator) error {
 	if r.Client == nil {
-		r.Client = &http.Client{}
+		c := httpconfig.Config{
+			Timeout: r.Timeout.Duration,
+			TLS: internal.TLSOptions{
+				SSLCA:              r.SSLCA,
+				SSLCert:            r.SSLCert,
+				SSLKey:             r.SSLKey,
+				InsecureSkipVerify: r.InsecureSkipVerify,
+			},
+		}
+		client, err := c.CreateClient()
+		if err != nil {
+			return err
+		}
+		r.Client = client
 	}
 
 	var errChan = make(chan error, len(gatherFunctions))
@@ -168,9 +232,11 @@ func (r *RabbitMQ) requestJSON(u string, target interface{}) error {
 
 	defer resp.Body.Close()
 
-	json.NewDecoder(resp.Body).Decode(target)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
 
-	return nil
+	return json.NewDecoder(resp.Body).Decode(target)
 }
 
 func gatherOverview(r *RabbitMQ, acc telegraf.Accumulator, errChan chan error) {
@@ -273,7 +339,7 @@ func gatherQueues(r *RabbitMQ, acc telegraf.Accumulator, errChan chan error) {
 			map[string]interface{}{
 				// common information
 				"consumers":            queue.Consumers,
-				"consumer_utilisation": queue.ConsumerUtilisation,
+				"consumer_utilisation": float64(queue.ConsumerUtilisation),
 				"memory":               queue.Memory,
 				// messages information
 				"message_bytes":             queue.MessageBytes,
@@ -302,6 +368,46 @@ func gatherQueues(r *RabbitMQ, acc telegraf.Accumulator, errChan chan error) {
 	errChan <- nil
 }
 
+func gatherExchanges(r *RabbitMQ, acc telegraf.Accumulator, errChan chan error) {
+	// Gather information about exchanges
+	exchanges := make([]Exchange, 0)
+	err := r.requestJSON("/api/exchanges", &exchanges)
+	if err != nil {
+		errChan <- err
+		return
+	}
+
+	for _, exchange := range exchanges {
+		// The default exchange has no name
+		name := exchange.Name
+		if name == "" {
+			name = "amq.default"
+		}
+		tags := map[string]string{
+			"url":         r.URL,
+			"exchange":    name,
+			"type":        exchange.Type,
+			"vhost":       exchange.Vhost,
+			"internal":    strconv.FormatBool(exchange.Internal),
+			"durable":     strconv.FormatBool(exchange.Durable),
+			"auto_delete": strconv.FormatBool(exchange.AutoDelete),
+		}
+
+		acc.AddFields(
+			"rabbitmq_exchange",
+			map[string]interface{}{
+				"messages_publish_in":       exchange.MessageStats.PublishIn,
+				"messages_publish_in_rate":  exchange.MessageStats.PublishInDetails.Rate,
+				"messages_publish_out":      exchange.MessageStats.PublishOut,
+				"messages_publish_out_rate": exchange.MessageStats.PublishOutDetails.Rate,
+			},
+			tags,
+		)
+	}
+
+	errChan <- nil
+}
+
 func (r *RabbitMQ) shouldGatherNode(node Node) bool {
 	if len(r.Nodes) == 0 {
 		return true
@@ -316,7 +422,16 @@ func (r *RabbitMQ) shouldGatherNode(node Node) bool {
 	return false
 }
 
+// shouldGatherQueue returns true if the queue is one of the queues, if any,
+// matches the included globs, if any, and none of the excluded globs
 func (r *RabbitMQ) shouldGatherQueue(queue Queue) bool {
+	if len(r.QueueNameInclude) > 0 && !globsMatch(queue.Name, r.QueueNameInclude) {
+		return false
+	}
+	if globsMatch(queue.Name, r.QueueNameExclude) {
+		return false
+	}
+
 	if len(r.Queues) == 0 {
 		return true
 	}
@@ -330,6 +445,15 @@ func (r *RabbitMQ) shouldGatherQueue(queue Queue) bool {
 	return false
 }
 
+func globsMatch(s string, globs []string) bool {
+	for _, glob := range globs {
+		if internal.Glob(glob, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	inputs.Add("rabbitmq", func() telegraf.Input {
 		return &RabbitMQ{}
@@ -132,6 +132,39 @@ const sampleNodesResponse = `
     }
 ]
 `
+const sampleExchangesResponse = `
+[
+  {
+    "name": "",
+    "vhost": "/",
+    "type": "direct",
+    "durable": true,
+    "auto_delete": false,
+    "internal": false,
+    "arguments": {}
+  },
+  {
+    "message_stats": {
+      "publish_in": 5258,
+      "publish_in_details": {
+        "rate": 1.2
+      },
+      "publish_out": 5246,
+      "publish_out_details": {
+        "rate": 1.0
+      }
+    },
+    "name": "telegraf",
+    "vhost": "/",
+    "type": "topic",
+    "durable": true,
+    "auto_delete": false,
+    "internal": false,
+    "arguments": {}
+  }
+]
+`
+
 const sampleQueuesResponse = `
 [
   {
@@ -385,6 +418,8 @@ func TestRabbitMQGeneratesMetrics(t *testing.T) {
 			rsp = sampleNodesResponse
 		case "/api/queues":
 			rsp = sampleQueuesResponse
+		case "/api/exchanges":
+			rsp = sampleExchangesResponse
 		default:
 			panic("Cannot handle request")
 		}
@@ -441,4 +476,36 @@ func TestRabbitMQGeneratesMetrics(t *testing.T) {
 	}
 
 	assert.True(t, acc.HasMeasurement("rabbitmq_queue"))
+
+	acc.AssertContainsTaggedFields(t, "rabbitmq_exchange",
+		map[string]interface{}{
+			"messages_publish_in":       int64(5258),
+			"messages_publish_in_rate":  float64(1.2),
+			"messages_publish_out":      int64(5246),
+			"messages_publish_out_rate": float64(1.0),
+		},
+		map[string]string{
+			"url":         ts.URL,
+			"exchange":    "telegraf",
+			"type":        "topic",
+			"vhost":       "/",
+			"internal":    "false",
+			"durable":     "true",
+			"auto_delete": "false",
+		})
+}
+
+func TestRabbitMQQueueFilters(t *testing.T) {
+	r := &RabbitMQ{
+		QueueNameInclude: []string{"telegraf*", "collectd-*"},
+		QueueNameExclude: []string{"*-tmp"},
+	}
+	assert.True(t, r.shouldGatherQueue(Queue{Name: "telegraf"}))
+	assert.True(t, r.shouldGatherQueue(Queue{Name: "collectd-queue"}))
+	assert.False(t, r.shouldGatherQueue(Queue{Name: "telegraf-tmp"}))
+	assert.False(t, r.shouldGatherQueue(Queue{Name: "amq.gen-JzTY20BRgKO"}))
+
+	r.Queues = []string{"collectd-queue"}
+	assert.False(t, r.shouldGatherQueue(Queue{Name: "telegraf"}))
+	assert.True(t, r.shouldGatherQueue(Queue{Name: "collectd-queue"}))
 }
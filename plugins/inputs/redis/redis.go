@@ -2,20 +2,38 @@ package redis
 
 import (
 	"bufio"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/url"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type Redis struct {
 	Servers []string
+	// Entries of the slow log gathered per server, none if zero
+	SlowlogEntries int `toml:"slowlog_entries"`
+	// Gather CLUSTER INFO, for the servers in cluster mode
+	GatherClusterInfo bool `toml:"gather_cluster_info"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	tlsConfig *tls.Config
+	// ids of the last slow log entries gathered per server
+	sync.Mutex
+	slowlogIDs map[string]int64
 }
 
 var sampleConfig = `
@@ -26,8 +44,21 @@ var sampleConfig = `
   #    tcp://:password@192.168.99.100
   #
   # If no servers are specified, then localhost is used as the host.
-  # If no port is specified, 6379 is used
+  # If no port is specified, 6379 is used. The tls:// protocol connects
+  # over TLS, as do the servers when any ssl option is set.
   servers = ["tcp://localhost:6379"]
+
+  # Number of the latest entries of SLOWLOG GET gathered, each entry being
+  # gathered once, disabled if 0
+  slowlog_entries = 0
+  # Gather CLUSTER INFO, for the servers in cluster mode
+  gather_cluster_info = false
+
+  # Use ssl
+  #ssl_ca = "/etc/telegraf/ca.pem"
+  #ssl_cert = "/etc/telegraf/cert.pem"
+  #ssl_key = "/etc/telegraf/key.pem"
+  #insecure_skip_verify = false
 `
 
 func (r *Redis) SampleConfig() string {
@@ -39,42 +70,85 @@ func (r *Redis) Description() string {
 }
 
 var Tracking = map[string]string{
-	"uptime_in_seconds":           "uptime",
-	"connected_clients":           "clients",
-	"used_memory":                 "used_memory",
-	"used_memory_rss":             "used_memory_rss",
-	"used_memory_peak":            "used_memory_peak",
-	"used_memory_lua":             "used_memory_lua",
-	"rdb_changes_since_last_save": "rdb_changes_since_last_save",
-	"total_connections_received":  "total_connections_received",
-	"total_commands_processed":    "total_commands_processed",
-	"instantaneous_ops_per_sec":   "instantaneous_ops_per_sec",
-	"instantaneous_input_kbps":    "instantaneous_input_kbps",
-	"instantaneous_output_kbps":   "instantaneous_output_kbps",
-	"sync_full":                   "sync_full",
-	"sync_partial_ok":             "sync_partial_ok",
-	"sync_partial_err":            "sync_partial_err",
-	"expired_keys":                "expired_keys",
-	"evicted_keys":                "evicted_keys",
-	"keyspace_hits":               "keyspace_hits",
-	"keyspace_misses":             "keyspace_misses",
-	"pubsub_channels":             "pubsub_channels",
-	"pubsub_patterns":             "pubsub_patterns",
-	"latest_fork_usec":            "latest_fork_usec",
-	"connected_slaves":            "connected_slaves",
-	"master_repl_offset":          "master_repl_offset",
-	"repl_backlog_active":         "repl_backlog_active",
-	"repl_backlog_size":           "repl_backlog_size",
-	"repl_backlog_histlen":        "repl_backlog_histlen",
-	"mem_fragmentation_ratio":     "mem_fragmentation_ratio",
-	"used_cpu_sys":                "used_cpu_sys",
-	"used_cpu_user":               "used_cpu_user",
-	"used_cpu_sys_children":       "used_cpu_sys_children",
-	"used_cpu_user_children":      "used_cpu_user_children",
+	"uptime_in_seconds":              "uptime",
+	"connected_clients":              "clients",
+	"client_longest_output_list":     "client_longest_output_list",
+	"client_biggest_input_buf":       "client_biggest_input_buf",
+	"blocked_clients":                "blocked_clients",
+	"used_memory":                    "used_memory",
+	"used_memory_rss":                "used_memory_rss",
+	"used_memory_peak":               "used_memory_peak",
+	"used_memory_lua":                "used_memory_lua",
+	"total_system_memory":            "total_system_memory",
+	"maxmemory":                      "maxmemory",
+	"loading":                        "loading",
+	"rdb_changes_since_last_save":    "rdb_changes_since_last_save",
+	"rdb_bgsave_in_progress":         "rdb_bgsave_in_progress",
+	"rdb_last_save_time":             "rdb_last_save_time",
+	"rdb_last_bgsave_time_sec":       "rdb_last_bgsave_time_sec",
+	"rdb_current_bgsave_time_sec":    "rdb_current_bgsave_time_sec",
+	"aof_enabled":                    "aof_enabled",
+	"aof_rewrite_in_progress":        "aof_rewrite_in_progress",
+	"aof_rewrite_scheduled":          "aof_rewrite_scheduled",
+	"aof_last_rewrite_time_sec":      "aof_last_rewrite_time_sec",
+	"aof_current_rewrite_time_sec":   "aof_current_rewrite_time_sec",
+	"aof_current_size":               "aof_current_size",
+	"aof_base_size":                  "aof_base_size",
+	"aof_pending_bio_fsync":          "aof_pending_bio_fsync",
+	"aof_delayed_fsync":              "aof_delayed_fsync",
+	"total_connections_received":     "total_connections_received",
+	"total_commands_processed":       "total_commands_processed",
+	"instantaneous_ops_per_sec":      "instantaneous_ops_per_sec",
+	"instantaneous_input_kbps":       "instantaneous_input_kbps",
+	"instantaneous_output_kbps":      "instantaneous_output_kbps",
+	"rejected_connections":           "rejected_connections",
+	"total_net_input_bytes":          "total_net_input_bytes",
+	"total_net_output_bytes":         "total_net_output_bytes",
+	"sync_full":                      "sync_full",
+	"sync_partial_ok":                "sync_partial_ok",
+	"sync_partial_err":               "sync_partial_err",
+	"expired_keys":                   "expired_keys",
+	"evicted_keys":                   "evicted_keys",
+	"keyspace_hits":                  "keyspace_hits",
+	"keyspace_misses":                "keyspace_misses",
+	"pubsub_channels":                "pubsub_channels",
+	"pubsub_patterns":                "pubsub_patterns",
+	"latest_fork_usec":               "latest_fork_usec",
+	"connected_slaves":               "connected_slaves",
+	"master_repl_offset":             "master_repl_offset",
+	"master_last_io_seconds_ago":     "master_last_io_seconds_ago",
+	"master_sync_in_progress":        "master_sync_in_progress",
+	"master_link_down_since_seconds": "master_link_down_since_seconds",
+	"slave_repl_offset":              "slave_repl_offset",
+	"slave_priority":                 "slave_priority",
+	"repl_backlog_first_byte_offset": "repl_backlog_first_byte_offset",
+	"repl_backlog_active":            "repl_backlog_active",
+	"repl_backlog_size":              "repl_backlog_size",
+	"repl_backlog_histlen":           "repl_backlog_histlen",
+	"mem_fragmentation_ratio":        "mem_fragmentation_ratio",
+	"used_cpu_sys":                   "used_cpu_sys",
+	"used_cpu_user":                  "used_cpu_user",
+	"used_cpu_sys_children":          "used_cpu_sys_children",
+	"used_cpu_user_children":         "used_cpu_user_children",
 }
 
 var ErrProtocolError = errors.New("redis protocol error")
 
+func (r *Redis) Init() error {
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              r.SSLCA,
+		SSLCert:            r.SSLCert,
+		SSLKey:             r.SSLKey,
+		InsecureSkipVerify: r.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	r.tlsConfig = tlsConfig
+	r.slowlogIDs = make(map[string]int64)
+	return nil
+}
+
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (r *Redis) Gather(acc telegraf.Accumulator) error {
@@ -88,6 +162,7 @@ func (r *Redis) Gather(acc telegraf.Accumulator) error {
 
 	var wg sync.WaitGroup
 
+	var errMu sync.Mutex
 	var outerr error
 
 	for _, serv := range r.Servers {
@@ -103,7 +178,11 @@ func (r *Redis) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(serv string) {
 			defer wg.Done()
-			outerr = r.gatherServer(u, acc)
+			if err := r.gatherServer(u, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
 		}(serv)
 	}
 
@@ -114,38 +193,50 @@ func (r *Redis) Gather(acc telegraf.Accumulator) error {
 
 const defaultPort = "6379"
 
+// timeout of the connections and commands sent to a server
+const timeout = 5 * time.Second
+
 func (r *Redis) gatherServer(addr *url.URL, acc telegraf.Accumulator) error {
 	_, _, err := net.SplitHostPort(addr.Host)
 	if err != nil {
 		addr.Host = addr.Host + ":" + defaultPort
 	}
 
-	c, err := net.Dial("tcp", addr.Host)
+	var c net.Conn
+	dialer := &net.Dialer{Timeout: timeout}
+	if r.tlsConfig != nil || addr.Scheme == "tls" {
+		tlsConfig := r.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		c, err = tls.DialWithDialer(dialer, "tcp", addr.Host, tlsConfig)
+	} else {
+		c, err = dialer.Dial("tcp", addr.Host)
+	}
 	if err != nil {
 		return fmt.Errorf("Unable to connect to redis server '%s': %s", addr.Host, err)
 	}
 	defer c.Close()
+	c.SetDeadline(time.Now().Add(timeout))
+	rdr := bufio.NewReader(c)
 
 	if addr.User != nil {
 		pwd, set := addr.User.Password()
 		if set && pwd != "" {
-			c.Write([]byte(fmt.Sprintf("AUTH %s\r\n", pwd)))
-
-			rdr := bufio.NewReader(c)
-
-			line, err := rdr.ReadString('\n')
-			if err != nil {
+			if _, err := command(c, rdr, "AUTH", pwd); err != nil {
 				return err
 			}
-			if line[0] != '+' {
-				return fmt.Errorf("%s", strings.TrimSpace(line)[1:])
-			}
 		}
 	}
 
-	c.Write([]byte("INFO\r\n"))
-	c.Write([]byte("EOF\r\n"))
-	rdr := bufio.NewReader(c)
+	reply, err := command(c, rdr, "INFO")
+	if err != nil {
+		return err
+	}
+	info, ok := reply.(string)
+	if !ok {
+		return ErrProtocolError
+	}
 
 	// Setup tags for all redis metrics
 	host, port := "unknown", "unknown"
@@ -153,7 +244,106 @@ func (r *Redis) gatherServer(addr *url.URL, acc telegraf.Accumulator) error {
 	host, port, _ = net.SplitHostPort(addr.Host)
 	tags := map[string]string{"server": host, "port": port}
 
-	return gatherInfoOutput(rdr, acc, tags)
+	err = gatherInfoOutput(bufio.NewReader(strings.NewReader(info)), acc,
+		tags)
+	if err != nil {
+		return err
+	}
+
+	if r.SlowlogEntries > 0 {
+		reply, err := command(c, rdr, "SLOWLOG", "GET",
+			strconv.Itoa(r.SlowlogEntries))
+		if err != nil {
+			return err
+		}
+		err = r.gatherSlowlog(addr.Host, reply, acc,
+			map[string]string{"server": host, "port": port})
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.GatherClusterInfo {
+		reply, err := command(c, rdr, "CLUSTER", "INFO")
+		if err != nil {
+			return err
+		}
+		info, ok := reply.(string)
+		if !ok {
+			return ErrProtocolError
+		}
+		gatherClusterInfo(info, acc,
+			map[string]string{"server": host, "port": port})
+	}
+	return nil
+}
+
+// command sends a command to the server and returns its reply: a string for
+// the status and bulk replies, an int64 for the integer replies, a slice of
+// the replies for the arrays, and nil for the null replies. The error replies
+// are returned as errors.
+func command(w io.Writer, rdr *bufio.Reader, args ...string) (interface{}, error) {
+	req := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		req += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(w, req); err != nil {
+		return nil, err
+	}
+	return readReply(rdr)
+}
+
+func readReply(rdr *bufio.Reader) (interface{}, error) {
+	line, err := rdr.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) == 0 {
+		return nil, ErrProtocolError
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, ErrProtocolError
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ErrProtocolError
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rdr, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ErrProtocolError
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = readReply(rdr); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, ErrProtocolError
 }
 
 // gatherInfoOutput gathers
@@ -182,6 +372,10 @@ func gatherInfoOutput(
 		}
 
 		name := string(parts[0])
+		if name == "role" {
+			tags["replication_role"] = strings.TrimSpace(parts[1])
+			continue
+		}
 		metric, ok := Tracking[name]
 		if !ok {
 			kline := strings.TrimSpace(string(parts[1]))
@@ -223,7 +417,9 @@ func gatherInfoOutput(
 
 // Parse the special Keyspace line at end of redis stats
 // This is a special line that looks something like:
-//     db0:keys=2,expires=0,avg_ttl=0
+//
+//	db0:keys=2,expires=0,avg_ttl=0
+//
 // And there is one for each db on the redis instance
 func gatherKeyspaceLine(
 	name string,
@@ -246,6 +442,105 @@ func gatherKeyspaceLine(
 	}
 }
 
+// gatherSlowlog adds the entries of a SLOWLOG GET reply of the server which
+// were not gathered yet, each entry being timestamped with the time the
+// command was run and tagged with the command
+func (r *Redis) gatherSlowlog(
+	server string,
+	reply interface{},
+	acc telegraf.Accumulator,
+	tags map[string]string,
+) error {
+	entries, ok := reply.([]interface{})
+	if !ok {
+		return ErrProtocolError
+	}
+
+	r.Lock()
+	last, seen := r.slowlogIDs[server]
+	r.Unlock()
+
+	newest := int64(-1)
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) < 4 {
+			return ErrProtocolError
+		}
+		id, ok1 := entry[0].(int64)
+		timestamp, ok2 := entry[1].(int64)
+		duration, ok3 := entry[2].(int64)
+		args, ok4 := entry[3].([]interface{})
+		if !ok1 || !ok2 || !ok3 || !ok4 {
+			return ErrProtocolError
+		}
+		// The entries are the newest first, lower ids than the last one
+		// gathered mean that the server restarted
+		if newest < 0 {
+			newest = id
+			if id < last {
+				seen = false
+			}
+		}
+		if seen && id <= last {
+			break
+		}
+
+		t := map[string]string{"command": ""}
+		for k, v := range tags {
+			t[k] = v
+		}
+		if len(args) > 0 {
+			if cmd, ok := args[0].(string); ok {
+				t["command"] = strings.ToLower(cmd)
+			}
+		}
+		fields := map[string]interface{}{
+			"id":          id,
+			"duration_us": duration,
+		}
+		acc.AddFields("redis_slowlog", fields, t, time.Unix(timestamp, 0))
+	}
+
+	if newest >= 0 {
+		r.Lock()
+		r.slowlogIDs[server] = newest
+		r.Unlock()
+	}
+	return nil
+}
+
+// gatherClusterInfo parses the reply of CLUSTER INFO, the numeric fields of
+// which are added to the redis_cluster measurement. The cluster_state is
+// added as cluster_state_ok, 1 if the state is ok and 0 otherwise.
+func gatherClusterInfo(
+	info string,
+	acc telegraf.Accumulator,
+	tags map[string]string,
+) {
+	fields := make(map[string]interface{})
+	for _, line := range strings.Split(info, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) < 2 {
+			continue
+		}
+		name, val := parts[0], parts[1]
+		if name == "cluster_state" {
+			ok := uint64(0)
+			if val == "ok" {
+				ok = 1
+			}
+			fields["cluster_state_ok"] = ok
+			continue
+		}
+		if ival, err := strconv.ParseUint(val, 10, 64); err == nil {
+			fields[name] = ival
+		} else if fval, err := strconv.ParseFloat(val, 64); err == nil {
+			fields[name] = fval
+		}
+	}
+	acc.AddFields("redis_cluster", fields, tags)
+}
+
 func init() {
 	inputs.Add("redis", func() telegraf.Input {
 		return &Redis{}
@@ -3,10 +3,12 @@ package redis
 import (
 	"bufio"
 	"fmt"
+	"net"
 	"strings"
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,6 +22,7 @@ func TestRedisConnect(t *testing.T) {
 	r := &Redis{
 		Servers: []string{addr},
 	}
+	require.NoError(t, r.Init())
 
 	var acc testutil.Accumulator
 
@@ -36,49 +39,158 @@ func TestRedis_ParseMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	fields := map[string]interface{}{
-		"uptime":                      uint64(238),
-		"clients":                     uint64(1),
-		"used_memory":                 uint64(1003936),
-		"used_memory_rss":             uint64(811008),
-		"used_memory_peak":            uint64(1003936),
-		"used_memory_lua":             uint64(33792),
-		"rdb_changes_since_last_save": uint64(0),
-		"total_connections_received":  uint64(2),
-		"total_commands_processed":    uint64(1),
-		"instantaneous_ops_per_sec":   uint64(0),
-		"sync_full":                   uint64(0),
-		"sync_partial_ok":             uint64(0),
-		"sync_partial_err":            uint64(0),
-		"expired_keys":                uint64(0),
-		"evicted_keys":                uint64(0),
-		"keyspace_hits":               uint64(1),
-		"keyspace_misses":             uint64(1),
-		"pubsub_channels":             uint64(0),
-		"pubsub_patterns":             uint64(0),
-		"latest_fork_usec":            uint64(0),
-		"connected_slaves":            uint64(0),
-		"master_repl_offset":          uint64(0),
-		"repl_backlog_active":         uint64(0),
-		"repl_backlog_size":           uint64(1048576),
-		"repl_backlog_histlen":        uint64(0),
-		"mem_fragmentation_ratio":     float64(0.81),
-		"instantaneous_input_kbps":    float64(876.16),
-		"instantaneous_output_kbps":   float64(3010.23),
-		"used_cpu_sys":                float64(0.14),
-		"used_cpu_user":               float64(0.05),
-		"used_cpu_sys_children":       float64(0.00),
-		"used_cpu_user_children":      float64(0.00),
-		"keyspace_hitrate":            float64(0.50),
+		"uptime":                         uint64(238),
+		"clients":                        uint64(1),
+		"client_longest_output_list":     uint64(0),
+		"client_biggest_input_buf":       uint64(0),
+		"blocked_clients":                uint64(0),
+		"used_memory":                    uint64(1003936),
+		"used_memory_rss":                uint64(811008),
+		"used_memory_peak":               uint64(1003936),
+		"used_memory_lua":                uint64(33792),
+		"loading":                        uint64(0),
+		"rdb_changes_since_last_save":    uint64(0),
+		"rdb_bgsave_in_progress":         uint64(0),
+		"rdb_last_save_time":             uint64(1428427941),
+		"rdb_last_bgsave_time_sec":       float64(-1),
+		"rdb_current_bgsave_time_sec":    float64(-1),
+		"aof_enabled":                    uint64(0),
+		"aof_rewrite_in_progress":        uint64(0),
+		"aof_rewrite_scheduled":          uint64(0),
+		"aof_last_rewrite_time_sec":      float64(-1),
+		"aof_current_rewrite_time_sec":   float64(-1),
+		"rejected_connections":           uint64(0),
+		"total_connections_received":     uint64(2),
+		"total_commands_processed":       uint64(1),
+		"instantaneous_ops_per_sec":      uint64(0),
+		"sync_full":                      uint64(0),
+		"sync_partial_ok":                uint64(0),
+		"sync_partial_err":               uint64(0),
+		"expired_keys":                   uint64(0),
+		"evicted_keys":                   uint64(0),
+		"keyspace_hits":                  uint64(1),
+		"keyspace_misses":                uint64(1),
+		"pubsub_channels":                uint64(0),
+		"pubsub_patterns":                uint64(0),
+		"latest_fork_usec":               uint64(0),
+		"connected_slaves":               uint64(0),
+		"master_repl_offset":             uint64(0),
+		"repl_backlog_active":            uint64(0),
+		"repl_backlog_size":              uint64(1048576),
+		"repl_backlog_first_byte_offset": uint64(0),
+		"repl_backlog_histlen":           uint64(0),
+		"mem_fragmentation_ratio":        float64(0.81),
+		"instantaneous_input_kbps":       float64(876.16),
+		"instantaneous_output_kbps":      float64(3010.23),
+		"used_cpu_sys":                   float64(0.14),
+		"used_cpu_user":                  float64(0.05),
+		"used_cpu_sys_children":          float64(0.00),
+		"used_cpu_user_children":         float64(0.00),
+		"keyspace_hitrate":               float64(0.50),
 	}
 	keyspaceFields := map[string]interface{}{
 		"avg_ttl": uint64(0),
 		"expires": uint64(0),
 		"keys":    uint64(2),
 	}
+	assert.Equal(t, "master", tags["replication_role"])
 	acc.AssertContainsTaggedFields(t, "redis", fields, tags)
 	acc.AssertContainsTaggedFields(t, "redis_keyspace", keyspaceFields, tags)
 }
 
+// fakeServer serves the replies of the commands sent to a listener, by
+// command name
+func fakeServer(t *testing.T, replies map[string]string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				rdr := bufio.NewReader(c)
+				for {
+					req, err := readReply(rdr)
+					if err != nil {
+						return
+					}
+					args := req.([]interface{})
+					name := args[0].(string)
+					for _, arg := range args[1:] {
+						name += " " + arg.(string)
+					}
+					reply, ok := replies[name]
+					if !ok {
+						reply = "-ERR unknown command '" + name + "'\r\n"
+					}
+					c.Write([]byte(reply))
+				}
+			}(c)
+		}
+	}()
+	return l
+}
+
+// bulk returns a bulk reply of s
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func TestRedis_GatherServer(t *testing.T) {
+	slowlog := "*2\r\n" +
+		"*4\r\n:12\r\n:1428427950\r\n:15000\r\n*2\r\n" +
+		bulk("KEYS") + bulk("*") +
+		"*4\r\n:11\r\n:1428427940\r\n:12000\r\n*1\r\n" + bulk("FLUSHALL")
+	l := fakeServer(t, map[string]string{
+		"AUTH secret":    "+OK\r\n",
+		"INFO":           bulk(testOutput),
+		"SLOWLOG GET 10": slowlog,
+		"CLUSTER INFO": bulk("cluster_state:ok\r\ncluster_slots_assigned:16384\r\n" +
+			"cluster_known_nodes:6\r\n"),
+	})
+	defer l.Close()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+
+	r := &Redis{
+		Servers:           []string{"tcp://:secret@" + l.Addr().String()},
+		SlowlogEntries:    10,
+		GatherClusterInfo: true,
+	}
+	require.NoError(t, r.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, r.Gather(&acc))
+
+	assert.True(t, acc.HasUIntField("redis", "clients"))
+	tags := map[string]string{"server": host, "port": port}
+	acc.AssertContainsTaggedFields(t, "redis_cluster", map[string]interface{}{
+		"cluster_state_ok":       uint64(1),
+		"cluster_slots_assigned": uint64(16384),
+		"cluster_known_nodes":    uint64(6),
+	}, tags)
+	tags["command"] = "keys"
+	acc.AssertContainsTaggedFields(t, "redis_slowlog", map[string]interface{}{
+		"id":          int64(12),
+		"duration_us": int64(15000),
+	}, tags)
+	tags["command"] = "flushall"
+	acc.AssertContainsTaggedFields(t, "redis_slowlog", map[string]interface{}{
+		"id":          int64(11),
+		"duration_us": int64(12000),
+	}, tags)
+
+	// The entries are gathered once
+	acc = testutil.Accumulator{}
+	require.NoError(t, r.Gather(&acc))
+	assert.False(t, acc.HasMeasurement("redis_slowlog"))
+
+	// Wrong password
+	r.Servers = []string{"tcp://:wrong@" + l.Addr().String()}
+	assert.Error(t, r.Gather(&acc))
+}
+
 const testOutput = `# Server
 redis_version:2.8.9
 redis_git_sha1:00000000
@@ -9,3 +9,12 @@ var Inputs = map[string]Creator{}
 func Add(name string, creator Creator) {
 	Inputs[name] = creator
 }
+
+// Deprecations holds the deprecated inputs, warned about when configured
+var Deprecations = map[string]telegraf.DeprecationInfo{}
+
+// AddDeprecated registers a deprecated input
+func AddDeprecated(name string, creator Creator, info telegraf.DeprecationInfo) {
+	Add(name, creator)
+	Deprecations[name] = info
+}
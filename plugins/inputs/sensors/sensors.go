@@ -1,18 +1,34 @@
-// +build linux,sensors
+// +build linux
 
 package sensors
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
-
-	"github.com/md14454/gosensors"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
+// Sensors reads the sensors of the hardware monitoring chips, with the
+// sensors command of lm-sensors or from sysfs
 type Sensors struct {
 	Sensors []string
+	Method  string
+	Path    string
+	Timeout internal.Duration
+
+	// sysfs is the directory of the hwmon devices, replaced in the tests
+	sysfs string
 }
 
 func (_ *Sensors) Description() string {
@@ -30,58 +46,242 @@ var sensorsSampleConfig = `
   # A * as the feature name will return all features of the chip
   #
   # sensors = ["coretemp-isa-0000:Core 0", "coretemp-isa-0001:*"]
+
+  # Method reading the sensors, "sensors" running the sensors command of
+  # lm-sensors, or "sysfs" reading the hwmon devices of /sys/class/hwmon
+  # without lm-sensors, the chips being named by their driver
+  # method = "sensors"
+
+  # Path of the sensors command, looked up in the PATH by default
+  # path = "/usr/bin/sensors"
+
+  # Timeout of the sensors command
+  # timeout = "5s"
 `
 
 func (_ *Sensors) SampleConfig() string {
 	return sensorsSampleConfig
 }
 
+// reading is the value of a subfeature of a feature of a chip, ie the
+// temp1_input of the "Core 0" feature of the coretemp-isa-0000 chip
+type reading struct {
+	chip    string
+	feature string
+	field   string
+	value   float64
+}
+
 func (s *Sensors) Gather(acc telegraf.Accumulator) error {
-	gosensors.Init()
-	defer gosensors.Cleanup()
-
-	for _, chip := range gosensors.GetDetectedChips() {
-		for _, feature := range chip.GetFeatures() {
-			chipName := chip.String()
-			featureLabel := feature.GetLabel()
-
-			if len(s.Sensors) != 0 {
-				var found bool
-
-				for _, sensor := range s.Sensors {
-					parts := strings.SplitN(":", sensor, 2)
-
-					if parts[0] == chipName {
-						if parts[1] == "*" || parts[1] == featureLabel {
-							found = true
-							break
-						}
-					}
-				}
-
-				if !found {
-					continue
-				}
-			}
+	var readings []reading
+	var err error
+	switch s.Method {
+	case "", "sensors":
+		readings, err = s.readCommand()
+	case "sysfs":
+		readings, err = s.readSysfs()
+	default:
+		return fmt.Errorf("unknown method %q, must be sensors or sysfs",
+			s.Method)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The subfeatures of a feature are the fields of a point
+	type key struct{ chip, feature string }
+	var keys []key
+	points := make(map[key]map[string]interface{})
+	for _, r := range readings {
+		if !s.selected(r.chip, r.feature) {
+			continue
+		}
+		k := key{r.chip, r.feature}
+		if points[k] == nil {
+			keys = append(keys, k)
+			points[k] = make(map[string]interface{})
+		}
+		points[k][r.field] = r.value
+	}
+	for _, k := range keys {
+		acc.AddFields("sensors", points[k], map[string]string{
+			"chip":    k.chip,
+			"feature": strings.Replace(strings.ToLower(k.feature), " ", "_", -1),
+		})
+	}
+	return nil
+}
+
+// selected returns whether the feature of the chip is selected by the
+// sensors option, all being selected if it is empty
+func (s *Sensors) selected(chip string, feature string) bool {
+	if len(s.Sensors) == 0 {
+		return true
+	}
+	for _, sensor := range s.Sensors {
+		parts := strings.SplitN(sensor, ":", 2)
+		if parts[0] == chip &&
+			(len(parts) == 1 || parts[1] == "*" || parts[1] == feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCommand returns the readings of sensors -u
+func (s *Sensors) readCommand() ([]reading, error) {
+	path := s.Path
+	if path == "" {
+		var err error
+		if path, err = exec.LookPath("sensors"); err != nil {
+			return nil, fmt.Errorf("sensors not found, lm-sensors must be "+
+				"installed or the sysfs method used: %s", err)
+		}
+	}
+	timeout := s.Timeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(path, "-u")
+	cmd.Stdout = &out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("running sensors: %s", err)
+		}
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return nil, fmt.Errorf("sensors timed out after %s", timeout)
+	}
+	return parseSensorsOutput(out.String()), nil
+}
+
+// subfeatureRe matches the names of the subfeatures, ie temp1_input, their
+// type and number being the name of the sensor of the chip
+var subfeatureRe = regexp.MustCompile(`^([a-z]+)[0-9]*_(\w+)$`)
 
-			tags := map[string]string{
-				"chip":          chipName,
-				"adapter":       chip.AdapterName(),
-				"feature-name":  feature.Name,
-				"feature-label": featureLabel,
+// parseSensorsOutput parses the output of sensors -u, chips separated by an
+// empty line, their name followed by their adapter and features, which are
+// followed by their subfeatures indented:
+//
+//	coretemp-isa-0000
+//	Adapter: ISA adapter
+//	Core 0:
+//	  temp2_input: 57.000
+//	  temp2_max: 82.000
+func parseSensorsOutput(out string) []reading {
+	var readings []reading
+	var chip, feature string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			chip, feature = "", ""
+		case chip == "":
+			chip = strings.TrimSpace(line)
+		case strings.HasPrefix(line, "Adapter:"):
+		case !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":"):
+			feature = strings.TrimSuffix(line, ":")
+		case strings.HasPrefix(line, " ") && feature != "":
+			parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+			if len(parts) != 2 {
+				continue
 			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil {
+				continue
+			}
+			readings = append(readings, reading{
+				chip:    chip,
+				feature: feature,
+				field:   subfeatureRe.ReplaceAllString(parts[0], "${1}_${2}"),
+				value:   value,
+			})
+		}
+	}
+	return readings
+}
+
+// sysfsScales are the units of the values of the hwmon sysfs interface, in
+// the unit of lm-sensors: millidegrees, millivolts, milliamperes and
+// microwatts
+var sysfsScales = map[string]float64{
+	"temp":  1000,
+	"in":    1000,
+	"curr":  1000,
+	"power": 1000000,
+}
 
-			fieldName := chipName + ":" + featureLabel
+// sysfsFileRe matches the files of the hwmon sensors, ie temp1_input
+var sysfsFileRe = regexp.MustCompile(
+	`^(temp|fan|in|curr|power|humidity)([0-9]+)_(\w+)$`)
 
-			fields := map[string]interface{}{
-				fieldName: feature.GetValue(),
+// readSysfs returns the readings of the hwmon devices, the chips being named
+// by their driver and device, ie coretemp-hwmon1, and their features by their
+// label, or their sensor if they have none, ie temp1
+func (s *Sensors) readSysfs() ([]reading, error) {
+	root := s.sysfs
+	if root == "" {
+		root = "/sys/class/hwmon"
+	}
+	dirs, err := filepath.Glob(filepath.Join(root, "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []reading
+	for _, dir := range dirs {
+		name, err := ioutil.ReadFile(filepath.Join(dir, "name"))
+		if err != nil {
+			continue
+		}
+		chip := strings.TrimSpace(string(name)) + "-" + filepath.Base(dir)
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			m := sysfsFileRe.FindStringSubmatch(file.Name())
+			if m == nil || m[3] == "label" {
+				continue
+			}
+			b, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(string(b)), 64)
+			if err != nil {
+				continue
+			}
+			if scale, ok := sysfsScales[m[1]]; ok && m[3] != "alarm" &&
+				m[3] != "beep" && m[3] != "type" {
+				value /= scale
 			}
 
-			acc.AddFields("sensors", fields, tags)
+			feature := m[1] + m[2]
+			if label, err := ioutil.ReadFile(
+				filepath.Join(dir, feature+"_label")); err == nil {
+				feature = strings.TrimSpace(string(label))
+			}
+			readings = append(readings, reading{
+				chip:    chip,
+				feature: feature,
+				field:   m[1] + "_" + m[3],
+				value:   value,
+			})
 		}
 	}
-
-	return nil
+	return readings, nil
 }
 
 func init() {
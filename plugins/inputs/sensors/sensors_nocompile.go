@@ -1,3 +0,0 @@
-// +build !linux !sensors
-
-package sensors
@@ -0,0 +1,96 @@
+// +build linux
+
+package sensors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sensorsOutput = `coretemp-isa-0000
+Adapter: ISA adapter
+Physical id 0:
+  temp1_input: 61.000
+  temp1_max: 82.000
+  temp1_crit: 92.000
+  temp1_crit_alarm: 0.000
+Core 0:
+  temp2_input: 57.000
+  temp2_max: 82.000
+
+nct6775-isa-0290
+Adapter: ISA adapter
+Vcore:
+  in0_input: 0.880
+  in0_min: 0.000
+fan2:
+  fan2_input: 1054.000
+`
+
+func TestParseSensorsOutput(t *testing.T) {
+	readings := parseSensorsOutput(sensorsOutput)
+	require.Len(t, readings, 9)
+	assert.Equal(t, reading{"coretemp-isa-0000", "Physical id 0",
+		"temp_crit_alarm", 0}, readings[3])
+	assert.Equal(t, reading{"coretemp-isa-0000", "Core 0",
+		"temp_input", 57}, readings[4])
+	assert.Equal(t, reading{"nct6775-isa-0290", "fan2",
+		"fan_input", 1054}, readings[8])
+}
+
+func TestSensorsSysfs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sensors")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hwmon := filepath.Join(dir, "hwmon1")
+	require.NoError(t, os.Mkdir(hwmon, 0755))
+	for name, content := range map[string]string{
+		"name":        "coretemp\n",
+		"temp1_input": "61000\n",
+		"temp1_crit":  "92000\n",
+		"temp1_label": "Core 0\n",
+		"temp2_input": "57500\n",
+		"in0_input":   "880\n",
+		"fan1_input":  "1054\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(hwmon, name),
+			[]byte(content), 0644))
+	}
+
+	s := &Sensors{Method: "sysfs", sysfs: dir}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	chip := "coretemp-hwmon1"
+	acc.AssertContainsTaggedFields(t, "sensors", map[string]interface{}{
+		"temp_input": float64(61),
+		"temp_crit":  float64(92),
+	}, map[string]string{"chip": chip, "feature": "core_0"})
+	acc.AssertContainsTaggedFields(t, "sensors", map[string]interface{}{
+		"temp_input": float64(57.5),
+	}, map[string]string{"chip": chip, "feature": "temp2"})
+	acc.AssertContainsTaggedFields(t, "sensors", map[string]interface{}{
+		"in_input": float64(0.88),
+	}, map[string]string{"chip": chip, "feature": "in0"})
+	acc.AssertContainsTaggedFields(t, "sensors", map[string]interface{}{
+		"fan_input": float64(1054),
+	}, map[string]string{"chip": chip, "feature": "fan1"})
+
+	// Only the selected features are gathered
+	s.Sensors = []string{chip + ":Core 0"}
+	acc = testutil.Accumulator{}
+	require.NoError(t, s.Gather(&acc))
+	assert.Len(t, acc.Metrics, 1)
+}
+
+func TestSensorsInvalidMethod(t *testing.T) {
+	var acc testutil.Accumulator
+	assert.Error(t, (&Sensors{Method: "libsensors"}).Gather(&acc))
+}
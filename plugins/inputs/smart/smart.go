@@ -0,0 +1,444 @@
+package smart
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs the command with the arguments, killing it after the timeout,
+// and returns its output and exit status. It is replaced by a mock in the
+// tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, int, error)
+
+// Smart reads the S.M.A.R.T. attributes of the disks with smartctl, and of
+// the NVMe disks with nvme-cli when it is installed
+type Smart struct {
+	Path       string
+	PathNVMe   string `toml:"path_nvme"`
+	UseSudo    bool   `toml:"use_sudo"`
+	Nocheck    string
+	Devices    []string
+	Excludes   []string
+	Attributes []string
+	Timeout    internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of smartctl, looked up in the PATH by default
+  # path = "/usr/sbin/smartctl"
+
+  # Path of nvme-cli, looked up in the PATH by default. The NVMe disks are
+  # read with smartctl if it is not found.
+  # path_nvme = "/usr/sbin/nvme"
+
+  # Run the commands with sudo, which must be allowed without password
+  # use_sudo = false
+
+  # Skip the disks in this power mode or lower, not to spin them up, one of
+  # "never", "sleep", "standby" or "idle"
+  # nocheck = "standby"
+
+  # Disks read, with the options of smartctl for their device type, ie
+  # "/dev/sda -d sat". The disks are discovered by smartctl --scan by default.
+  # devices = ["/dev/sda -d sat", "/dev/nvme0"]
+
+  # Disks skipped when they are discovered
+  # excludes = ["/dev/sdc"]
+
+  # Attributes of the disks gathered in the smart_attribute measurement, by
+  # name, ie Reallocated_Sector_Ct, * gathering all the attributes. The
+  # main attributes are also fields of the smart_device measurement.
+  # attributes = ["Reallocated_Sector_Ct", "Wear_Leveling_Count"]
+
+  # Timeout of the commands
+  # timeout = "30s"
+`
+
+func (m *Smart) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Smart) Description() string {
+	return "Read the S.M.A.R.T. attributes of the disks with smartctl and nvme-cli"
+}
+
+func (m *Smart) Gather(acc telegraf.Accumulator) error {
+	if m.Path == "" {
+		path, err := exec.LookPath("smartctl")
+		if err != nil {
+			return fmt.Errorf("smartctl not found: %s", err)
+		}
+		m.Path = path
+	}
+	if m.PathNVMe == "" {
+		// nvme-cli is optional
+		m.PathNVMe, _ = exec.LookPath("nvme")
+	}
+	if m.runner == nil {
+		m.runner = runCommand
+	}
+	if m.Nocheck == "" {
+		m.Nocheck = "standby"
+	}
+	if m.Timeout.Duration == 0 {
+		m.Timeout.Duration = 30 * time.Second
+	}
+
+	devices := m.Devices
+	if len(devices) == 0 {
+		var err error
+		if devices, err = m.scan(); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var errorStrings []string
+	for _, device := range devices {
+		wg.Add(1)
+		go func(device string) {
+			defer wg.Done()
+			if err := m.gatherDevice(device, acc); err != nil {
+				errMu.Lock()
+				errorStrings = append(errorStrings, err.Error())
+				errMu.Unlock()
+			}
+		}(device)
+	}
+	wg.Wait()
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// run runs the command, with sudo if use_sudo is set
+func (m *Smart) run(path string, args ...string) (string, int, error) {
+	if m.UseSudo {
+		return m.runner(m.Timeout.Duration, "sudo",
+			append([]string{"-n", path}, args...)...)
+	}
+	return m.runner(m.Timeout.Duration, path, args...)
+}
+
+// scan returns the devices discovered by smartctl --scan, which are listed
+// with their device type, ie
+//
+//	/dev/sda -d scsi # /dev/sda, SCSI device
+func (m *Smart) scan() ([]string, error) {
+	out, _, err := m.run(m.Path, "--scan")
+	if err != nil {
+		return nil, fmt.Errorf("scanning the devices: %s: %s", err,
+			strings.TrimSpace(out))
+	}
+
+	var devices []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		device := strings.TrimSpace(strings.SplitN(scanner.Text(), "#", 2)[0])
+		if device == "" || m.excluded(device) {
+			continue
+		}
+		devices = append(devices, device)
+	}
+	return devices, scanner.Err()
+}
+
+// excluded returns whether the device is in the excludes
+func (m *Smart) excluded(device string) bool {
+	path := strings.Fields(device)[0]
+	for _, exclude := range m.Excludes {
+		if exclude == device || exclude == path {
+			return true
+		}
+	}
+	return false
+}
+
+// gatherDevice adds the smart_device point of the device, the path of the
+// device followed by the options of smartctl, and its smart_attribute points
+func (m *Smart) gatherDevice(device string, acc telegraf.Accumulator) error {
+	args := strings.Fields(device)
+	tags := map[string]string{
+		"device": strings.TrimPrefix(args[0], "/dev/"),
+	}
+	deviceType := ""
+	for i, arg := range args {
+		if arg == "-d" && i+1 < len(args) {
+			deviceType = args[i+1]
+			tags["device_type"] = deviceType
+		}
+	}
+
+	if m.PathNVMe != "" &&
+		(deviceType == "nvme" || strings.HasPrefix(tags["device"], "nvme")) {
+		return m.gatherNVMe(args[0], tags, acc)
+	}
+
+	out, status, err := m.run(m.Path, append([]string{"--info", "--health",
+		"--attributes", "--tolerance=verypermissive",
+		"--nocheck=" + m.Nocheck, "--format=brief"}, args...)...)
+	// The bits 0 and 1 of the exit status are the failures to parse the
+	// command line or to open the device, the others the errors found
+	if err != nil || status&3 != 0 {
+		if strings.Contains(out, "mode, exit(") {
+			// The device is in the power mode of nocheck
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("exit status %d", status)
+		}
+		return fmt.Errorf("reading %s: %s: %s", args[0], err,
+			strings.TrimSpace(out))
+	}
+
+	fields := map[string]interface{}{
+		"exit_status": status,
+	}
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if attr := attributeRe.FindStringSubmatch(line); attr != nil {
+			raw, ok := parseValue(attr[8])
+			if !ok {
+				continue
+			}
+			if field, ok := deviceFields[attr[2]]; ok {
+				fields[field] = raw
+			}
+			if !m.selected(attr[2]) {
+				continue
+			}
+			attrFields := map[string]interface{}{
+				"raw_value": raw,
+			}
+			for i, name := range []string{"value", "worst", "threshold"} {
+				if v, err := strconv.ParseInt(attr[4+i], 10, 64); err == nil {
+					attrFields[name] = v
+				}
+			}
+			attrTags := map[string]string{
+				"id":    attr[1],
+				"name":  attr[2],
+				"flags": attr[3],
+				"fail":  attr[7],
+			}
+			for k, v := range tags {
+				attrTags[k] = v
+			}
+			acc.AddFields("smart_attribute", attrFields, attrTags, now)
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		switch {
+		case key == "Device Model" || key == "Model Number" || key == "Product":
+			tags["model"] = value
+		case key == "Serial Number" || key == "Serial number":
+			tags["serial_no"] = value
+		case key == "User Capacity" || key == "Total NVM Capacity":
+			if v, ok := parseValue(value); ok {
+				tags["capacity"] = strconv.FormatInt(v, 10)
+			}
+		case strings.HasPrefix(key, "SMART overall-health"):
+			fields["health_ok"] = value == "PASSED"
+		case key == "SMART Health Status":
+			fields["health_ok"] = value == "OK"
+		case key == "Current Drive Temperature":
+			if v, ok := parseValue(value); ok {
+				fields["temp_c"] = v
+			}
+		default:
+			if field, ok := nvmeFields[key]; ok {
+				if v, ok := parseValue(value); ok {
+					fields[field] = v
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	acc.AddFields("smart_device", fields, tags, now)
+	return nil
+}
+
+// gatherNVMe adds the smart_device point of the NVMe device, read with
+// nvme smart-log, which is listed as
+//
+//	critical_warning                    : 0
+//	temperature                         : 38 C
+func (m *Smart) gatherNVMe(
+	path string,
+	tags map[string]string,
+	acc telegraf.Accumulator,
+) error {
+	out, status, err := m.run(m.PathNVMe, "smart-log", path)
+	if err != nil || status != 0 {
+		if err == nil {
+			err = fmt.Errorf("exit status %d", status)
+		}
+		return fmt.Errorf("reading %s: %s: %s", path, err,
+			strings.TrimSpace(out))
+	}
+
+	fields := map[string]interface{}{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		field, ok := nvmeCLIFields[strings.TrimSpace(parts[0])]
+		if !ok {
+			continue
+		}
+		if v, ok := parseValue(strings.TrimSpace(parts[1])); ok {
+			fields[field] = v
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if warning, ok := fields["critical_warning"]; ok {
+		fields["health_ok"] = warning == int64(0)
+	}
+	acc.AddFields("smart_device", fields, tags)
+	return nil
+}
+
+// selected returns whether the attribute is gathered in the smart_attribute
+// measurement
+func (m *Smart) selected(name string) bool {
+	for _, attribute := range m.Attributes {
+		if attribute == "*" || attribute == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeRe matches the attributes of the ATA disks printed by smartctl
+// --format=brief, ie
+//
+//	ID# ATTRIBUTE_NAME          FLAGS    VALUE WORST THRESH FAIL RAW_VALUE
+//	  5 Reallocated_Sector_Ct   PO--CK   100   100   036    -    0
+var attributeRe = regexp.MustCompile(
+	`^\s*([0-9]+)\s+(\S+)\s+([-P][-O][-S][-R][-C][-K])\s+([0-9]+)\s+([0-9]+)\s+([0-9-]+)\s+(\S+)\s+(.+)$`)
+
+// deviceFields are the ATA attributes in the fields of smart_device
+var deviceFields = map[string]string{
+	"Reallocated_Sector_Ct":   "reallocated_sectors",
+	"Current_Pending_Sector":  "pending_sectors",
+	"Offline_Uncorrectable":   "uncorrectable_sectors",
+	"Power_On_Hours":          "power_on_hours",
+	"Temperature_Celsius":     "temp_c",
+	"Airflow_Temperature_Cel": "temp_c",
+	"UDMA_CRC_Error_Count":    "udma_crc_errors",
+	"Wear_Leveling_Count":     "wear_leveling",
+	"Media_Wearout_Indicator": "wear_leveling",
+}
+
+// nvmeFields are the values of the NVMe log printed by smartctl in the
+// fields of smart_device
+var nvmeFields = map[string]string{
+	"Critical Warning":                "critical_warning",
+	"Temperature":                     "temp_c",
+	"Available Spare":                 "available_spare",
+	"Percentage Used":                 "percentage_used",
+	"Power On Hours":                  "power_on_hours",
+	"Unsafe Shutdowns":                "unsafe_shutdowns",
+	"Media and Data Integrity Errors": "media_errors",
+	"Error Information Log Entries":   "error_log_entries",
+}
+
+// nvmeCLIFields are the values of the NVMe log printed by nvme smart-log in
+// the fields of smart_device, named as with smartctl
+var nvmeCLIFields = map[string]string{
+	"critical_warning":    "critical_warning",
+	"temperature":         "temp_c",
+	"available_spare":     "available_spare",
+	"percentage_used":     "percentage_used",
+	"power_on_hours":      "power_on_hours",
+	"unsafe_shutdowns":    "unsafe_shutdowns",
+	"media_errors":        "media_errors",
+	"num_err_log_entries": "error_log_entries",
+}
+
+// parseValue parses the integer at the start of the value, ie 35 of
+// "35 (Min/Max 18/40)", 1000204886016 of "1,000,204,886,016 bytes [1.00 TB]",
+// 100 of "100%" or 0 of "0x00"
+func parseValue(value string) (int64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	s := strings.TrimSuffix(strings.Replace(fields[0], ",", "", -1), "%")
+	if strings.HasPrefix(s, "0x") {
+		v, err := strconv.ParseInt(s[2:], 16, 64)
+		return v, err == nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	return v, err == nil
+}
+
+// runCommand runs the command, killing it after the timeout, and returns its
+// output and exit status
+func runCommand(timeout time.Duration, path string, args ...string) (string, int, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", 0, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if e, ok := err.(*exec.ExitError); ok {
+			if s, ok := e.Sys().(syscall.WaitStatus); ok {
+				return out.String(), s.ExitStatus(), nil
+			}
+		}
+		return out.String(), 0, err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), 0, fmt.Errorf("%s timed out after %s", path,
+			timeout)
+	}
+}
+
+func init() {
+	inputs.Add("smart", func() telegraf.Input {
+		return &Smart{
+			Nocheck: "standby",
+			Timeout: internal.Duration{Duration: 30 * time.Second},
+			runner:  runCommand,
+		}
+	})
+}
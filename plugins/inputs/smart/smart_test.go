@@ -0,0 +1,219 @@
+package smart
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scanOutput = `/dev/sda -d sat # /dev/sda [SAT], ATA device
+/dev/sdb -d scsi # /dev/sdb, SCSI device
+/dev/nvme0 -d nvme # /dev/nvme0, NVMe device
+`
+
+const ataOutput = `smartctl 6.6 2016-05-31 r4324 [x86_64-linux-4.9.0] (local build)
+
+=== START OF INFORMATION SECTION ===
+Model Family:     Seagate Barracuda 7200.12
+Device Model:     ST3500418AS
+Serial Number:    6VM7QZ2L
+User Capacity:    500,107,862,016 bytes [500 GB]
+SMART support is: Enabled
+
+=== START OF READ SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART Attributes Data Structure revision number: 10
+Vendor Specific SMART Attributes with Thresholds:
+ID# ATTRIBUTE_NAME          FLAGS    VALUE WORST THRESH FAIL RAW_VALUE
+  1 Raw_Read_Error_Rate     POSR--   118   099   006    -    185937488
+  5 Reallocated_Sector_Ct   PO--CK   100   100   036    -    8
+  9 Power_On_Hours          -O--CK   071   071   000    -    25752
+194 Temperature_Celsius     -O---K   035   040   000    -    35 (0 18 0 0 0)
+199 UDMA_CRC_Error_Count    -OSRCK   200   200   ---    -    0
+                            ||||||_ K auto-keep
+                            |______ P prefailure warning
+`
+
+const nvmeSmartctlOutput = `=== START OF INFORMATION SECTION ===
+Model Number:                       Samsung SSD 960 EVO 250GB
+Serial Number:                      S3ESNX0J123456
+Total NVM Capacity:                 250,059,350,016 [250 GB]
+
+=== START OF SMART DATA SECTION ===
+SMART overall-health self-assessment test result: PASSED
+
+SMART/Health Information (NVMe Log 0x02)
+Critical Warning:                   0x00
+Temperature:                        38 Celsius
+Available Spare:                    100%
+Percentage Used:                    2%
+Power On Hours:                     1,234
+Unsafe Shutdowns:                   12
+Media and Data Integrity Errors:    0
+Error Information Log Entries:      7
+`
+
+const nvmeCLIOutput = `Smart Log for NVME device:nvme0 namespace-id:ffffffff
+critical_warning                    : 0
+temperature                         : 38 C
+available_spare                     : 100%
+available_spare_threshold           : 10%
+percentage_used                     : 2%
+data_units_read                     : 1,234,567
+power_on_hours                      : 1,234
+unsafe_shutdowns                    : 12
+media_errors                        : 3
+num_err_log_entries                 : 7
+`
+
+// mockRunner answers the commands, run with sudo or not, with the outputs of
+// the devices, the standby /dev/sdb being skipped
+func mockRunner(nvme string) (Runner, *[]string) {
+	var mu sync.Mutex
+	var calls []string
+	return func(timeout time.Duration, path string, args ...string) (string, int, error) {
+		mu.Lock()
+		calls = append(calls, path+" "+strings.Join(args, " "))
+		mu.Unlock()
+		if path == "sudo" {
+			args = args[2:]
+		}
+		switch {
+		case args[0] == "--scan":
+			return scanOutput, 0, nil
+		case args[0] == "smart-log":
+			return nvmeCLIOutput, 0, nil
+		}
+		switch args[len(args)-3] {
+		case "/dev/sda":
+			return ataOutput, 4, nil
+		case "/dev/sdb":
+			return "Device is in STANDBY mode, exit(2)\n", 2, nil
+		}
+		return nvme, 0, nil
+	}, &calls
+}
+
+func TestGather(t *testing.T) {
+	runner, calls := mockRunner(nvmeSmartctlOutput)
+	s := &Smart{
+		Path:       "smartctl",
+		Attributes: []string{"Reallocated_Sector_Ct"},
+		runner:     runner,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	assert.Contains(t, *calls, "smartctl --info --health --attributes "+
+		"--tolerance=verypermissive --nocheck=standby --format=brief "+
+		"/dev/sda -d sat")
+	acc.AssertContainsTaggedFields(t, "smart_device", map[string]interface{}{
+		"exit_status":         4,
+		"health_ok":           true,
+		"reallocated_sectors": int64(8),
+		"power_on_hours":      int64(25752),
+		"temp_c":              int64(35),
+		"udma_crc_errors":     int64(0),
+	}, map[string]string{
+		"device":      "sda",
+		"device_type": "sat",
+		"model":       "ST3500418AS",
+		"serial_no":   "6VM7QZ2L",
+		"capacity":    "500107862016",
+	})
+	acc.AssertContainsTaggedFields(t, "smart_attribute", map[string]interface{}{
+		"value":     int64(100),
+		"worst":     int64(100),
+		"threshold": int64(36),
+		"raw_value": int64(8),
+	}, map[string]string{
+		"device":      "sda",
+		"device_type": "sat",
+		"model":       "ST3500418AS",
+		"serial_no":   "6VM7QZ2L",
+		"capacity":    "500107862016",
+		"id":          "5",
+		"name":        "Reallocated_Sector_Ct",
+		"flags":       "PO--CK",
+		"fail":        "-",
+	})
+	acc.AssertContainsTaggedFields(t, "smart_device", map[string]interface{}{
+		"exit_status":       0,
+		"health_ok":         true,
+		"critical_warning":  int64(0),
+		"temp_c":            int64(38),
+		"available_spare":   int64(100),
+		"percentage_used":   int64(2),
+		"power_on_hours":    int64(1234),
+		"unsafe_shutdowns":  int64(12),
+		"media_errors":      int64(0),
+		"error_log_entries": int64(7),
+	}, map[string]string{
+		"device":      "nvme0",
+		"device_type": "nvme",
+		"model":       "Samsung SSD 960 EVO 250GB",
+		"serial_no":   "S3ESNX0J123456",
+		"capacity":    "250059350016",
+	})
+	// The standby disk is skipped and only one attribute is selected
+	assert.Equal(t, 3, len(acc.Metrics))
+}
+
+func TestGatherNVMeCLI(t *testing.T) {
+	runner, calls := mockRunner("")
+	s := &Smart{
+		Path:     "smartctl",
+		PathNVMe: "nvme",
+		UseSudo:  true,
+		Devices:  []string{"/dev/nvme0"},
+		runner:   runner,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	assert.Equal(t, []string{"sudo -n nvme smart-log /dev/nvme0"}, *calls)
+	acc.AssertContainsTaggedFields(t, "smart_device", map[string]interface{}{
+		"health_ok":         true,
+		"critical_warning":  int64(0),
+		"temp_c":            int64(38),
+		"available_spare":   int64(100),
+		"percentage_used":   int64(2),
+		"power_on_hours":    int64(1234),
+		"unsafe_shutdowns":  int64(12),
+		"media_errors":      int64(3),
+		"error_log_entries": int64(7),
+	}, map[string]string{"device": "nvme0"})
+}
+
+func TestGatherExcludes(t *testing.T) {
+	runner, calls := mockRunner(nvmeSmartctlOutput)
+	s := &Smart{
+		Path:     "smartctl",
+		Excludes: []string{"/dev/sda", "/dev/sdb"},
+		runner:   runner,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	assert.Equal(t, 2, len(*calls))
+	assert.Equal(t, 1, len(acc.Metrics))
+}
+
+func TestGatherError(t *testing.T) {
+	s := &Smart{
+		Path:    "smartctl",
+		Devices: []string{"/dev/sdz"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, int, error) {
+			return "Smartctl open device: /dev/sdz failed: No such device\n", 2, nil
+		},
+	}
+	var acc testutil.Accumulator
+	err := s.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such device")
+}
@@ -1,12 +1,13 @@
 package snmp
 
 import (
+	"fmt"
 	"io/ioutil"
-	"log"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -20,7 +21,9 @@ type Snmp struct {
 	Host              []Host
 	Get               []Data
 	Bulk              []Data
+	Table             []Table
 	SnmptranslateFile string
+	Log               telegraf.Logger `toml:"-"`
 }
 
 type Host struct {
@@ -32,13 +35,27 @@ type Host struct {
 	Timeout float64
 	// SNMP retries
 	Retries int
-	// Data to collect (list of Data names)
+	// SNMPv3 user and security level (noAuthNoPriv, authNoPriv or authPriv)
+	SecName  string `toml:"sec_name"`
+	SecLevel string `toml:"sec_level"`
+	// SNMPv3 authentication protocol (MD5 or SHA) and password
+	AuthProtocol string `toml:"auth_protocol"`
+	AuthPassword string `toml:"auth_password"`
+	// SNMPv3 privacy protocol (DES or AES) and password
+	PrivProtocol string `toml:"priv_protocol"`
+	PrivPassword string `toml:"priv_password"`
+	// SNMPv3 context
+	ContextName string `toml:"context_name"`
+	// Data to collect (list of Data and Table names)
 	Collect []string
 	// easy get oids
 	GetOids []string
 	// Oids
 	getOids  []Data
 	bulkOids []Data
+	tables   []Table
+
+	log telegraf.Logger
 }
 
 type Data struct {
@@ -57,6 +74,21 @@ type Data struct {
 	rawOid string
 }
 
+// Table is a table walked with GETBULK requests, SNMPv2c and v3, or GETNEXT
+// requests, SNMPv1. Each row is a metric tagged with its index.
+type Table struct {
+	Name string
+	// OID of the table or of its entry (could be numbers or name)
+	Oid string
+	// Columns tagging the rows with their value, ie ifDescr, instead of
+	// being fields
+	IndexTags []string `toml:"index_tags"`
+	//  SNMP getbulk max repetition
+	MaxRepetition uint8 `toml:"max_repetition"`
+	// OID (only number) (used for computation)
+	rawOid string
+}
+
 type Node struct {
 	id       string
 	name     string
@@ -83,7 +115,6 @@ var sampleConfig = `
     # SNMP community
     community = "public" # default public
     # SNMP version (1, 2 or 3)
-    # Version 3 not supported yet
     version = 2 # default 2
     # SNMP response timeout
     timeout = 2.0 # default 2.0
@@ -106,6 +137,21 @@ var sampleConfig = `
         ".1.3.6.1.2.1.1.3.0",
     ]
 
+  [[inputs.snmp.host]]
+    address = "192.168.2.4:161"
+    version = 3
+    # SNMPv3 user, and security level: noAuthNoPriv, authNoPriv or authPriv
+    sec_name = "telegraf"
+    sec_level = "authPriv"
+    # Authentication protocol, MD5 or SHA, and password
+    auth_protocol = "SHA"
+    auth_password = "secret"
+    # Privacy protocol, DES or AES, and password
+    priv_protocol = "AES"
+    priv_password = "secret"
+    # context_name = ""
+    collect = ["interfaces"]
+
   [[inputs.snmp.get]]
     name = "ifnumber"
     oid = "ifNumber"
@@ -129,6 +175,16 @@ var sampleConfig = `
     name = "ifoutoctets"
     max_repetition = 127
     oid = "ifOutOctets"
+
+  # Each row of a table is a metric, with the columns as fields, tagged with
+  # the instance (index) of the row. The columns are named from the
+  # snmptranslate file, or by their number.
+  [[inputs.snmp.table]]
+    name = "interfaces"
+    max_repetition = 127
+    oid = "ifTable"
+    # Columns tagging the rows with their value instead of being fields
+    index_tags = ["ifDescr"]
 `
 
 // SampleConfig returns sample configuration message
@@ -193,7 +249,7 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 	if s.SnmptranslateFile != "" && len(initNode.subnodes) == 0 {
 		data, err := ioutil.ReadFile(s.SnmptranslateFile)
 		if err != nil {
-			log.Printf("Reading SNMPtranslate file error: %s", err)
+			s.Log.Errorf("Reading SNMPtranslate file error: %s", err)
 			return err
 		} else {
 			for _, line := range strings.Split(string(data), "\n") {
@@ -208,7 +264,10 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 			}
 		}
 	}
-	// Fetching data
+	// Fetching data, the agents are polled concurrently
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var outerr error
 	for _, host := range s.Host {
 		// Set default args
 		if len(host.Address) == 0 {
@@ -272,16 +331,42 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 					host.bulkOids = append(host.bulkOids, oid)
 				}
 			}
+			// Get tables
+			for _, table := range s.Table {
+				if table.Name == oid_name {
+					if val, ok := NameToOid[table.Oid]; ok {
+						table.rawOid = "." + val
+					} else {
+						table.rawOid = "." + strings.TrimPrefix(table.Oid, ".")
+					}
+					host.tables = append(host.tables, table)
+				}
+			}
 		}
-		// Launch Get requests
-		if err := host.SNMPGet(acc); err != nil {
-			return err
-		}
-		if err := host.SNMPBulk(acc); err != nil {
-			return err
-		}
+		host.log = s.Log
+		wg.Add(1)
+		go func(host Host) {
+			defer wg.Done()
+			if err := host.gather(acc); err != nil {
+				errLock.Lock()
+				outerr = err
+				errLock.Unlock()
+			}
+		}(host)
 	}
-	return nil
+	wg.Wait()
+	return outerr
+}
+
+// gather launches the requests of the host
+func (h *Host) gather(acc telegraf.Accumulator) error {
+	if err := h.SNMPGet(acc); err != nil {
+		return err
+	}
+	if err := h.SNMPBulk(acc); err != nil {
+		return err
+	}
+	return h.SNMPTable(acc)
 }
 
 func (h *Host) SNMPGet(acc telegraf.Accumulator) error {
@@ -376,6 +461,143 @@ func (h *Host) SNMPBulk(acc telegraf.Accumulator) error {
 	return nil
 }
 
+// SNMPTable walks the tables of the host, adding a metric per row
+func (h *Host) SNMPTable(acc telegraf.Accumulator) error {
+	if len(h.tables) == 0 {
+		return nil
+	}
+	// Get snmp client
+	snmpClient, err := h.GetSNMPClient()
+	if err != nil {
+		return err
+	}
+	// Deconnection
+	defer snmpClient.Conn.Close()
+	for _, table := range h.tables {
+		var pdus []gosnmp.SnmpPDU
+		if snmpClient.Version == gosnmp.Version1 {
+			pdus, err = snmpClient.WalkAll(table.rawOid)
+		} else {
+			snmpClient.MaxRepetitions = int(table.MaxRepetition)
+			pdus, err = snmpClient.BulkWalkAll(table.rawOid)
+		}
+		if err != nil {
+			return err
+		}
+		host, _, _ := net.SplitHostPort(h.Address)
+		for _, row := range table.rows(pdus) {
+			if len(row.fields) == 0 {
+				continue
+			}
+			row.tags["host"] = host
+			acc.AddFields(table.Name, row.fields, row.tags)
+		}
+	}
+	return nil
+}
+
+// tableRow is a row of a table, the instance and index_tags columns as tags
+// and the other columns as fields
+type tableRow struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
+// rows groups the values walked by their row, in the order of the walk. The
+// OIDs walked are <entry>.<column>.<instance>, where the entry is the OID of
+// the table followed by 1 when the table OID is not the entry itself.
+func (t *Table) rows(pdus []gosnmp.SnmpPDU) []tableRow {
+	prefix := t.rawOid + "."
+	walked := 0
+	isTable := true
+	for _, pdu := range pdus {
+		if !strings.HasPrefix(pdu.Name, prefix) {
+			continue
+		}
+		ids := strings.Split(strings.TrimPrefix(pdu.Name, prefix), ".")
+		if len(ids) < 2 {
+			continue
+		}
+		walked++
+		if ids[0] != "1" || len(ids) < 3 {
+			isTable = false
+		}
+	}
+	// Every OID under a table starts with its entry, 1
+	entryOid := t.rawOid
+	if isTable && walked > 0 {
+		entryOid += ".1"
+	}
+
+	var rows []tableRow
+	byInstance := make(map[string]int)
+	for _, pdu := range pdus {
+		if !strings.HasPrefix(pdu.Name, prefix) {
+			continue
+		}
+		ids := strings.Split(strings.TrimPrefix(pdu.Name, entryOid+"."), ".")
+		if len(ids) < 2 {
+			continue
+		}
+		column, instance := ids[0], strings.Join(ids[1:], ".")
+		if name := oidName(entryOid + "." + column); name != "" {
+			column = name
+		}
+		n, ok := byInstance[instance]
+		if !ok {
+			n = len(rows)
+			byInstance[instance] = n
+			rows = append(rows, tableRow{
+				tags:   map[string]string{"instance": instance},
+				fields: make(map[string]interface{}),
+			})
+		}
+		if t.isIndexTag(column) {
+			rows[n].tags[column] = tagValue(pdu)
+			continue
+		}
+		switch pdu.Type {
+		case gosnmp.Boolean, gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32,
+			gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+			rows[n].fields[column] = pdu.Value
+		}
+	}
+	return rows
+}
+
+func (t *Table) isIndexTag(column string) bool {
+	for _, tag := range t.IndexTags {
+		if tag == column {
+			return true
+		}
+	}
+	return false
+}
+
+// tagValue returns the value of a column tagging the rows
+func tagValue(pdu gosnmp.SnmpPDU) string {
+	switch v := pdu.Value.(type) {
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// oidName returns the name of the OID in the snmptranslate file, empty if not
+// found
+func oidName(oid string) string {
+	node := initNode
+	for _, id := range strings.Split(strings.TrimPrefix(oid, "."), ".") {
+		subnode, ok := node.subnodes[id]
+		if !ok {
+			return ""
+		}
+		node = subnode
+	}
+	return node.name
+}
+
 func (h *Host) GetSNMPClient() (*gosnmp.GoSNMP, error) {
 	// Prepare Version
 	var version gosnmp.SnmpVersion
@@ -403,6 +625,11 @@ func (h *Host) GetSNMPClient() (*gosnmp.GoSNMP, error) {
 		Timeout:   time.Duration(h.Timeout) * time.Second,
 		Retries:   h.Retries,
 	}
+	if version == gosnmp.Version3 {
+		if err := h.setV3Security(snmpClient); err != nil {
+			return nil, err
+		}
+	}
 	// Connection
 	err2 := snmpClient.Connect()
 	if err2 != nil {
@@ -412,6 +639,54 @@ func (h *Host) GetSNMPClient() (*gosnmp.GoSNMP, error) {
 	return snmpClient, nil
 }
 
+// setV3Security sets the User Security Model credentials of the SNMPv3 client
+func (h *Host) setV3Security(snmpClient *gosnmp.GoSNMP) error {
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:               h.SecName,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+	switch strings.ToLower(h.SecLevel) {
+	case "", "noauthnopriv":
+		snmpClient.MsgFlags = gosnmp.NoAuthNoPriv
+	case "authnopriv":
+		snmpClient.MsgFlags = gosnmp.AuthNoPriv
+	case "authpriv":
+		snmpClient.MsgFlags = gosnmp.AuthPriv
+	default:
+		return fmt.Errorf("invalid sec_level %q, must be noAuthNoPriv, "+
+			"authNoPriv or authPriv", h.SecLevel)
+	}
+	if snmpClient.MsgFlags&gosnmp.AuthNoPriv != 0 {
+		switch strings.ToUpper(h.AuthProtocol) {
+		case "", "MD5":
+			params.AuthenticationProtocol = gosnmp.MD5
+		case "SHA":
+			params.AuthenticationProtocol = gosnmp.SHA
+		default:
+			return fmt.Errorf("invalid auth_protocol %q, must be MD5 or SHA",
+				h.AuthProtocol)
+		}
+		params.AuthenticationPassphrase = h.AuthPassword
+	}
+	if snmpClient.MsgFlags&gosnmp.AuthPriv == gosnmp.AuthPriv {
+		switch strings.ToUpper(h.PrivProtocol) {
+		case "", "DES":
+			params.PrivacyProtocol = gosnmp.DES
+		case "AES":
+			params.PrivacyProtocol = gosnmp.AES
+		default:
+			return fmt.Errorf("invalid priv_protocol %q, must be DES or AES",
+				h.PrivProtocol)
+		}
+		params.PrivacyPassphrase = h.PrivPassword
+	}
+	snmpClient.SecurityModel = gosnmp.UserSecurityModel
+	snmpClient.SecurityParameters = params
+	snmpClient.ContextName = h.ContextName
+	return nil
+}
+
 func (h *Host) HandleResponse(oids map[string]Data, result *gosnmp.SnmpPacket, acc telegraf.Accumulator) (string, error) {
 	var lastOid string
 	for _, variable := range result.Variables {
@@ -456,7 +731,7 @@ func (h *Host) HandleResponse(oids map[string]Data, result *gosnmp.SnmpPacket, a
 					acc.AddFields(field_name, fields, tags)
 				case gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
 					// Oid not found
-					log.Printf("[snmp input] Oid not found: %s", oid_key)
+					h.log.Infof("Oid not found: %s", oid_key)
 				default:
 					// delete other data
 				}
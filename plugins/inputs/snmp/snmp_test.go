@@ -4,8 +4,9 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/soniah/gosnmp"
 
-	//	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -19,6 +20,7 @@ func TestSNMPErrorGet1(t *testing.T) {
 		Collect: []string{"oid1"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "bad_oid.txt",
 		Host:              []Host{h},
 		Get:               []Data{get1},
@@ -39,6 +41,7 @@ func TestSNMPErrorGet2(t *testing.T) {
 		Collect: []string{"oid1"},
 	}
 	s := Snmp{
+		Log:  testutil.Logger{},
 		Host: []Host{h},
 		Get:  []Data{get1},
 	}
@@ -59,6 +62,7 @@ func TestSNMPErrorBulk(t *testing.T) {
 		Collect: []string{"oid1"},
 	}
 	s := Snmp{
+		Log:  testutil.Logger{},
 		Host: []Host{h},
 		Bulk: []Data{bulk1},
 	}
@@ -83,6 +87,7 @@ func TestSNMPGet1(t *testing.T) {
 		Collect:   []string{"oid1"},
 	}
 	s := Snmp{
+		Log:  testutil.Logger{},
 		Host: []Host{h},
 		Get:  []Data{get1},
 	}
@@ -117,6 +122,7 @@ func TestSNMPGet2(t *testing.T) {
 		Collect:   []string{"oid1"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Get:               []Data{get1},
@@ -154,6 +160,7 @@ func TestSNMPGet3(t *testing.T) {
 		Collect:   []string{"oid1"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Get:               []Data{get1},
@@ -193,6 +200,7 @@ func TestSNMPEasyGet4(t *testing.T) {
 		GetOids:   []string{"ifNumber"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Get:               []Data{get1},
@@ -243,6 +251,7 @@ func TestSNMPEasyGet5(t *testing.T) {
 		GetOids:   []string{".1.3.6.1.2.1.2.1.0"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Get:               []Data{get1},
@@ -286,6 +295,7 @@ func TestSNMPEasyGet6(t *testing.T) {
 		GetOids:   []string{"1.3.6.1.2.1.2.1.0"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 	}
@@ -322,6 +332,7 @@ func TestSNMPBulk1(t *testing.T) {
 		Collect:   []string{"oid1"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Bulk:              []Data{bulk1},
@@ -400,6 +411,7 @@ func dTestSNMPBulk2(t *testing.T) {
 		Collect:   []string{"oid1"},
 	}
 	s := Snmp{
+		Log:               testutil.Logger{},
 		SnmptranslateFile: "./testdata/oids.txt",
 		Host:              []Host{h},
 		Bulk:              []Data{bulk1},
@@ -457,3 +469,87 @@ func dTestSNMPBulk2(t *testing.T) {
 		},
 	)
 }
+
+func TestSNMPTableRows(t *testing.T) {
+	// Load the snmptranslate file
+	s := Snmp{
+		Log:               testutil.Logger{},
+		SnmptranslateFile: "./testdata/oids.txt",
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	pdus := []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.2.2.1.1.1", Type: gosnmp.Integer, Value: 1},
+		{Name: ".1.3.6.1.2.1.2.2.1.1.2", Type: gosnmp.Integer, Value: 2},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: gosnmp.OctetString,
+			Value: []byte("eth0")},
+		{Name: ".1.3.6.1.2.1.2.2.1.2.2", Type: gosnmp.OctetString,
+			Value: []byte("eth1")},
+		{Name: ".1.3.6.1.2.1.2.2.1.16.1", Type: gosnmp.Counter32,
+			Value: uint(543846)},
+		{Name: ".1.3.6.1.2.1.2.2.1.16.2", Type: gosnmp.Counter32,
+			Value: uint(26475179)},
+	}
+	expected := []tableRow{
+		{
+			tags: map[string]string{"instance": "1", "ifDescr": "eth0"},
+			fields: map[string]interface{}{
+				"ifIndex":     1,
+				"ifOutOctets": uint(543846),
+			},
+		},
+		{
+			tags: map[string]string{"instance": "2", "ifDescr": "eth1"},
+			fields: map[string]interface{}{
+				"ifIndex":     2,
+				"ifOutOctets": uint(26475179),
+			},
+		},
+	}
+
+	// The table and its entry give the same rows
+	for _, oid := range []string{".1.3.6.1.2.1.2.2", ".1.3.6.1.2.1.2.2.1"} {
+		table := Table{
+			Name:      "interfaces",
+			IndexTags: []string{"ifDescr"},
+			rawOid:    oid,
+		}
+		assert.Equal(t, expected, table.rows(pdus))
+	}
+}
+
+func TestSNMPv3Security(t *testing.T) {
+	h := Host{
+		SecName:      "telegraf",
+		SecLevel:     "authPriv",
+		AuthProtocol: "sha",
+		AuthPassword: "authsecret",
+		PrivProtocol: "AES",
+		PrivPassword: "privsecret",
+		ContextName:  "ctx",
+	}
+	snmpClient := &gosnmp.GoSNMP{}
+	require.NoError(t, h.setV3Security(snmpClient))
+	assert.Equal(t, gosnmp.AuthPriv, snmpClient.MsgFlags)
+	assert.Equal(t, gosnmp.UserSecurityModel, snmpClient.SecurityModel)
+	assert.Equal(t, "ctx", snmpClient.ContextName)
+	assert.Equal(t, &gosnmp.UsmSecurityParameters{
+		UserName:                 "telegraf",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "authsecret",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "privsecret",
+	}, snmpClient.SecurityParameters)
+
+	h.SecLevel = "authNoPriv"
+	require.NoError(t, h.setV3Security(snmpClient))
+	assert.Equal(t, gosnmp.AuthNoPriv, snmpClient.MsgFlags)
+	assert.Equal(t, gosnmp.NoPriv, snmpClient.SecurityParameters.(*gosnmp.UsmSecurityParameters).PrivacyProtocol)
+
+	h.SecLevel = "secret"
+	assert.Error(t, h.setV3Security(snmpClient))
+	h.SecLevel = "authPriv"
+	h.PrivProtocol = "3DES"
+	assert.Error(t, h.setV3Security(snmpClient))
+}
@@ -0,0 +1,438 @@
+package socket_listener
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// maxLineLength is the maximum length of the lines read from the stream
+// sockets, the one of the datagrams being the maximum size of a datagram
+const maxLineLength = 64 * 1024
+
+// SocketListener listens for data over UDP, TCP or unix sockets, parsing each
+// line of the stream sockets and each datagram of the packet sockets in a
+// data format
+type SocketListener struct {
+	ServiceAddress  string            `toml:"service_address"`
+	MaxConnections  int               `toml:"max_connections"`
+	ReadBufferSize  internal.Size     `toml:"read_buffer_size"`
+	ReadTimeout     internal.Duration `toml:"read_timeout"`
+	KeepAlivePeriod internal.Duration `toml:"keep_alive_period"`
+	SocketMode      string            `toml:"socket_mode"`
+	MetricBuffer    int               `toml:"metric_buffer"`
+
+	// TLS certificate and key of the listener, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	// Options of the parser, see parsers.Config
+	DataFormat             string   `toml:"data_format"`
+	TagKeys                []string `toml:"tag_keys"`
+	CSVColumnNames         []string `toml:"csv_column_names"`
+	CSVDelimiter           string   `toml:"csv_delimiter"`
+	XMLPath                string   `toml:"xml_path"`
+	GrokPatterns           []string `toml:"grok_patterns"`
+	GrokCustomPatterns     string   `toml:"grok_custom_patterns"`
+	GrokCustomPatternFiles []string `toml:"grok_custom_pattern_files"`
+	GrokTimezone           string   `toml:"grok_timezone"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	parser  telegraf.Parser
+	metricC chan telegraf.Metric
+	done    chan struct{}
+
+	// The packet connection or stream listener, and the stream connections,
+	// closed on Stop. wg waits for the goroutines reading them.
+	packetConn     net.PacketConn
+	streamListener net.Listener
+	connsLock      sync.Mutex
+	conns          map[net.Conn]struct{}
+	wg             sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Address to listen on, tcp://, udp://, unix:// or unixgram://, ie
+  # "tcp://:8094", "udp://127.0.0.1:8094" or "unix:///tmp/telegraf.sock".
+  # The data of the tcp:// and unix:// sockets is parsed line by line, the
+  # data of the udp:// and unixgram:// sockets datagram by datagram.
+  service_address = "tcp://:8094"
+
+  # Maximum number of connections of the stream sockets, unlimited if 0
+  # max_connections = 0
+
+  # Size of the receive buffer of the sockets, the default of the system if
+  # 0, ie "8MiB" for bursts of datagrams
+  # read_buffer_size = 0
+
+  # Close the connections idle for this duration, never if 0
+  # read_timeout = "0s"
+
+  # Period of the TCP keep-alives of the connections, the default of the
+  # system if 0
+  # keep_alive_period = "0s"
+
+  # Permissions of the unix sockets, in octal, ie "0660"
+  # socket_mode = ""
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 100000
+
+  # TLS certificate and key of the tcp:// listener, only accepting the
+  # clients presenting a certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  # Data format of the data: influx, json, logfmt, grok, csv or xml
+  data_format = "influx"
+`
+
+func (s *SocketListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SocketListener) Description() string {
+	return "Accept data in a data format over UDP, TCP or unix sockets"
+}
+
+func (s *SocketListener) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, err := url.Parse(s.ServiceAddress)
+	if err != nil {
+		return fmt.Errorf("invalid service_address %q: %s", s.ServiceAddress,
+			err)
+	}
+	var mode os.FileMode
+	if s.SocketMode != "" {
+		m, err := strconv.ParseUint(s.SocketMode, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid socket_mode %q", s.SocketMode)
+		}
+		mode = os.FileMode(m)
+	}
+	if s.MetricBuffer == 0 {
+		s.MetricBuffer = 100000
+	}
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        s.TLSCert,
+		TLSKey:         s.TLSKey,
+		AllowedCACerts: s.TLSAllowedCACerts,
+	})
+	if err != nil {
+		return err
+	}
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:             s.DataFormat,
+		MetricName:             "socket_listener",
+		TagKeys:                s.TagKeys,
+		CSVColumnNames:         s.CSVColumnNames,
+		CSVDelimiter:           s.CSVDelimiter,
+		XMLPath:                s.XMLPath,
+		GrokPatterns:           s.GrokPatterns,
+		GrokCustomPatterns:     s.GrokCustomPatterns,
+		GrokCustomPatternFiles: s.GrokCustomPatternFiles,
+		GrokTimezone:           s.GrokTimezone,
+	})
+	if err != nil {
+		return err
+	}
+	s.parser = parser
+
+	// The address of the unix sockets is their path
+	address := u.Host
+	if u.Scheme == "unix" || u.Scheme == "unixgram" {
+		address = u.Path
+		if u.Host != "" {
+			// A relative path, ie unix://telegraf.sock
+			address = u.Host + u.Path
+		}
+		// A socket left by a previous run prevents listening
+		if info, err := os.Lstat(address); err == nil &&
+			info.Mode()&os.ModeSocket != 0 {
+			os.Remove(address)
+		}
+	}
+	if tlsConfig != nil && u.Scheme != "tcp" && u.Scheme != "tcp4" &&
+		u.Scheme != "tcp6" {
+		return fmt.Errorf("the TLS options require a tcp service_address")
+	}
+
+	s.metricC = make(chan telegraf.Metric, s.MetricBuffer)
+	s.done = make(chan struct{})
+	switch u.Scheme {
+	case "udp", "udp4", "udp6", "unixgram":
+		if s.packetConn, err = net.ListenPacket(u.Scheme, address); err != nil {
+			return err
+		}
+		if s.ReadBufferSize.Size > 0 {
+			if conn, ok := s.packetConn.(interface {
+				SetReadBuffer(int) error
+			}); ok {
+				if err := conn.SetReadBuffer(int(s.ReadBufferSize.Size)); err != nil {
+					s.Log.Warnf("Could not set the read buffer size: %s", err)
+				}
+			}
+		}
+		s.wg.Add(1)
+		go s.packetListen()
+	case "tcp", "tcp4", "tcp6", "unix":
+		listener, err := net.Listen(u.Scheme, address)
+		if err != nil {
+			return err
+		}
+		// The options are set on the accepted connections before TLS
+		s.streamListener = &optionsListener{listener, s}
+		if tlsConfig != nil {
+			s.streamListener = tls.NewListener(s.streamListener, tlsConfig)
+		}
+		s.conns = make(map[net.Conn]struct{})
+		s.wg.Add(1)
+		go s.streamListen()
+	default:
+		return fmt.Errorf("unsupported service_address %q, must be tcp://, "+
+			"udp://, unix:// or unixgram://", s.ServiceAddress)
+	}
+	if mode != 0 && (u.Scheme == "unix" || u.Scheme == "unixgram") {
+		if err := os.Chmod(address, mode); err != nil {
+			s.stop()
+			return err
+		}
+	}
+	s.Log.Infof("Started the socket_listener service on %s", s.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address listened on
+func (s *SocketListener) Addr() net.Addr {
+	if s.packetConn != nil {
+		return s.packetConn.LocalAddr()
+	}
+	return s.streamListener.Addr()
+}
+
+// packetListen parses the datagrams until the listener is stopped
+func (s *SocketListener) packetListen() {
+	defer s.wg.Done()
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := s.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+		s.parse(buf[:n], addr)
+	}
+}
+
+// streamListen accepts the connections until the listener is stopped
+func (s *SocketListener) streamListen() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.streamListener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+
+		// A connection accepted while stopping is not closed by Stop
+		s.connsLock.Lock()
+		select {
+		case <-s.done:
+			s.connsLock.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections {
+			s.connsLock.Unlock()
+			s.Log.Warnf("Refusing connection from %s, the maximum of %d "+
+				"connections is reached", conn.RemoteAddr(), s.MaxConnections)
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.connsLock.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+// optionsListener sets the read buffer size and keep-alive period of the
+// connections it accepts
+type optionsListener struct {
+	net.Listener
+	s *SocketListener
+}
+
+func (l *optionsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.s.setOptions(conn)
+	return conn, nil
+}
+
+// setOptions sets the read buffer size and keep-alive period of the
+// connection
+func (s *SocketListener) setOptions(conn net.Conn) {
+	if s.ReadBufferSize.Size > 0 {
+		if c, ok := conn.(interface {
+			SetReadBuffer(int) error
+		}); ok {
+			if err := c.SetReadBuffer(int(s.ReadBufferSize.Size)); err != nil {
+				s.Log.Warnf("Could not set the read buffer size: %s", err)
+			}
+		}
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok && s.KeepAlivePeriod.Duration > 0 {
+		tcpConn.SetKeepAlive(true)
+		tcpConn.SetKeepAlivePeriod(s.KeepAlivePeriod.Duration)
+	}
+}
+
+// handleConn parses the lines of the connection
+func (s *SocketListener) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connsLock.Lock()
+		delete(s.conns, conn)
+		s.connsLock.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		if s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		line, err := readLine(r)
+		if len(line) > 0 {
+			s.parse(line, conn.RemoteAddr())
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				s.Log.Errorf("Reading from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readLine reads a line ended by a newline, or by the end of the connection
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		b, isPrefix, err := r.ReadLine()
+		line = append(line, b...)
+		if len(line) > maxLineLength {
+			return nil, fmt.Errorf("line longer than %d bytes", maxLineLength)
+		}
+		if err != nil || !isPrefix {
+			return line, err
+		}
+	}
+}
+
+// parse parses the data received from the address, and queues its metrics
+// for the next Gather
+func (s *SocketListener) parse(b []byte, addr net.Addr) {
+	metrics, err := s.parser.Parse(b)
+	if err != nil {
+		s.Log.Errorf("Could not parse data from %s: %q, error: %s", addr, b,
+			err)
+	}
+	for _, m := range metrics {
+		select {
+		case s.metricC <- m:
+		default:
+			s.Log.Warn("Buffer is full, dropping a metric." +
+				" You may want to increase the metric_buffer setting")
+		}
+	}
+}
+
+func (s *SocketListener) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	s.stop()
+	s.Log.Info("Stopped the socket_listener service")
+}
+
+// stop closes the listener and the connections, and waits for their
+// goroutines
+func (s *SocketListener) stop() {
+	close(s.done)
+	if s.packetConn != nil {
+		s.packetConn.Close()
+		// The unixgram sockets are not removed when closed
+		if addr, ok := s.packetConn.LocalAddr().(*net.UnixAddr); ok {
+			os.Remove(addr.Name)
+		}
+	}
+	if s.streamListener != nil {
+		s.streamListener.Close()
+		s.connsLock.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connsLock.Unlock()
+	}
+	s.wg.Wait()
+}
+
+func (s *SocketListener) Gather(acc telegraf.Accumulator) error {
+	s.Lock()
+	defer s.Unlock()
+	nmetrics := len(s.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-s.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("socket_listener", func() telegraf.Input {
+		return &SocketListener{
+			ServiceAddress: "tcp://:8094",
+			MetricBuffer:   100000,
+		}
+	})
+}
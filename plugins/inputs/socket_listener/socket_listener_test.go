@@ -0,0 +1,245 @@
+package socket_listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lines = "cpu,host=a value=1 1422568543702900257\ncpu,host=b value=2 1422568543702900257\n"
+
+// waitMetrics gathers the plugin until it received n metrics
+func waitMetrics(t *testing.T, s *SocketListener, n int) *testutil.Accumulator {
+	var acc testutil.Accumulator
+	for i := 0; i < 200 && len(acc.Metrics) < n; i++ {
+		require.NoError(t, s.Gather(&acc))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Metrics, n)
+	return &acc
+}
+
+func assertLines(t *testing.T, acc *testutil.Accumulator) {
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value": float64(1)},
+		map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"value": float64(2)},
+		map[string]string{"host": "b"})
+}
+
+func TestSocketListenerTCP(t *testing.T) {
+	s := &SocketListener{
+		ServiceAddress: "tcp://127.0.0.1:0",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	// A line split over writes
+	_, err = conn.Write([]byte(lines[:10]))
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = conn.Write([]byte(lines[10:]))
+	require.NoError(t, err)
+	conn.Close()
+
+	assertLines(t, waitMetrics(t, s, 2))
+}
+
+func TestSocketListenerUDP(t *testing.T) {
+	s := &SocketListener{
+		ServiceAddress: "udp://127.0.0.1:0",
+		ReadBufferSize: internal.Size{Size: 1024 * 1024},
+		DataFormat:     "logfmt",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("level=info took=12"))
+	require.NoError(t, err)
+
+	acc := waitMetrics(t, s, 1)
+	acc.AssertContainsFields(t, "socket_listener",
+		map[string]interface{}{"level": "info", "took": int64(12)})
+}
+
+func TestSocketListenerUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, network := range []string{"unix", "unixgram"} {
+		path := filepath.Join(dir, network+".sock")
+		// A socket left by a previous run is replaced, the unixgram sockets
+		// not being removed when closed
+		stale, err := net.ListenPacket("unixgram", path)
+		require.NoError(t, err)
+		stale.Close()
+
+		s := &SocketListener{
+			ServiceAddress: network + "://" + path,
+			SocketMode:     "0600",
+			Log:            testutil.Logger{},
+		}
+		require.NoError(t, s.Start())
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+		conn, err := net.Dial(network, path)
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(lines))
+		require.NoError(t, err)
+		conn.Close()
+
+		assertLines(t, waitMetrics(t, s, 2))
+		s.Stop()
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err), network)
+	}
+}
+
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "telegraf"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		0600))
+	return certFile, keyFile
+}
+
+func TestSocketListenerTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_listener")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	s := &SocketListener{
+		ServiceAddress:    "tcp://127.0.0.1:0",
+		TLSCert:           certFile,
+		TLSKey:            keyFile,
+		TLSAllowedCACerts: []string{certFile},
+		KeepAlivePeriod:   internal.Duration{Duration: time.Minute},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	pem, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+
+	// The clients without a certificate are refused
+	conn, err := tls.Dial("tcp", s.Addr().String(), &tls.Config{RootCAs: pool})
+	if err == nil {
+		conn.Write([]byte(lines))
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	assert.Error(t, err)
+
+	conn, err = tls.Dial("tcp", s.Addr().String(), &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{cert},
+	})
+	require.NoError(t, err)
+	_, err = conn.Write([]byte(lines))
+	require.NoError(t, err)
+	conn.Close()
+
+	assertLines(t, waitMetrics(t, s, 2))
+}
+
+func TestSocketListenerMaxConnections(t *testing.T) {
+	s := &SocketListener{
+		ServiceAddress: "tcp://127.0.0.1:0",
+		MaxConnections: 1,
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	first, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	_, err = first.Write([]byte("cpu value=1\n"))
+	require.NoError(t, err)
+	waitMetrics(t, s, 1)
+
+	// The second connection is closed by the listener
+	second, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, isTimeout(err))
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func TestSocketListenerInvalidConfig(t *testing.T) {
+	for _, s := range []*SocketListener{
+		{ServiceAddress: "http://127.0.0.1:0"},
+		{ServiceAddress: "tcp://127.0.0.1:0", SocketMode: "rw"},
+		{ServiceAddress: "tcp://127.0.0.1:0", DataFormat: "unknown"},
+		{ServiceAddress: "udp://127.0.0.1:0", TLSCert: "cert.pem"},
+	} {
+		s.Log = testutil.Logger{}
+		assert.Error(t, s.Start(), s.ServiceAddress)
+	}
+}
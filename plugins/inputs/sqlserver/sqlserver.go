@@ -2,18 +2,26 @@ package sqlserver
 
 import (
 	"database/sql"
-	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/plugins/inputs"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+
 	// go-mssqldb initialization
 	_ "github.com/zensqlmonitor/go-mssqldb"
 )
 
 // SQLServer struct
 type SQLServer struct {
-	Servers []string
+	Servers      []string
+	DatabaseType string   `toml:"database_type"`
+	IncludeQuery []string `toml:"include_query"`
+	ExcludeQuery []string `toml:"exclude_query"`
 }
 
 // Query struct
@@ -40,6 +48,15 @@ var sampleConfig = `
   # servers = [
   #  "Server=192.168.1.10;Port=1433;User Id=telegraf;Password=T$l$gr@f69*;app name=telegraf;log=1;",
   # ]
+
+  # Flavor of the instances, selecting the queries they support:
+  # "SQLServer", "AzureSQLDB" or "AzureSQLManagedInstance"
+  # database_type = "SQLServer"
+
+  # Queries run, all the queries of the database type by default, or
+  # excluded. See the README for the queries of each database type.
+  # include_query = []
+  # exclude_query = ["VolumeSpace"]
 `
 
 // SampleConfig return the sample configuration
@@ -56,43 +73,122 @@ type scanner interface {
 	Scan(dest ...interface{}) error
 }
 
-func initQueries() {
+// databaseQueries are the queries supported by each database type
+var databaseQueries = map[string]MapQuery{
+	"SQLServer": {
+		"PerformanceCounters":          {Script: sqlPerformanceCounters, ResultByRow: true},
+		"WaitStatsCategorized":         {Script: sqlWaitStatsCategorized, ResultByRow: false},
+		"CPUHistory":                   {Script: sqlCPUHistory, ResultByRow: false},
+		"DatabaseIO":                   {Script: sqlDatabaseIO, ResultByRow: false},
+		"DatabaseSize":                 {Script: sqlDatabaseSize, ResultByRow: false},
+		"DatabaseStats":                {Script: sqlDatabaseStats, ResultByRow: false},
+		"DatabaseProperties":           {Script: sqlDatabaseProperties, ResultByRow: false},
+		"MemoryClerk":                  {Script: sqlMemoryClerk, ResultByRow: false},
+		"VolumeSpace":                  {Script: sqlVolumeSpace, ResultByRow: false},
+		"PerformanceMetrics":           {Script: sqlPerformanceMetrics, ResultByRow: false},
+		"AvailabilityReplicaStates":    {Script: sqlAvailabilityReplicaStates, ResultByRow: false},
+		"AvailabilityDatabaseReplicas": {Script: sqlAvailabilityDatabaseReplicas, ResultByRow: false},
+	},
+	"AzureSQLManagedInstance": {
+		"PerformanceCounters":          {Script: sqlPerformanceCounters, ResultByRow: true},
+		"WaitStatsCategorized":         {Script: sqlWaitStatsCategorized, ResultByRow: false},
+		"DatabaseIO":                   {Script: sqlDatabaseIO, ResultByRow: false},
+		"DatabaseSize":                 {Script: sqlDatabaseSize, ResultByRow: false},
+		"DatabaseStats":                {Script: sqlDatabaseStats, ResultByRow: false},
+		"DatabaseProperties":           {Script: sqlDatabaseProperties, ResultByRow: false},
+		"MemoryClerk":                  {Script: sqlMemoryClerk, ResultByRow: false},
+		"AvailabilityReplicaStates":    {Script: sqlAvailabilityReplicaStates, ResultByRow: false},
+		"AvailabilityDatabaseReplicas": {Script: sqlAvailabilityDatabaseReplicas, ResultByRow: false},
+		"AzureMIResourceStats":         {Script: sqlAzureMIResourceStats, ResultByRow: false},
+	},
+	"AzureSQLDB": {
+		"PerformanceCounters":  {Script: sqlPerformanceCounters, ResultByRow: true},
+		"AzureDBResourceStats": {Script: sqlAzureDBResourceStats, ResultByRow: false},
+		"AzureDBWaitStats":     {Script: sqlAzureDBWaitStats, ResultByRow: false},
+		"AzureDBDatabaseIO":    {Script: sqlAzureDBDatabaseIO, ResultByRow: false},
+	},
+}
+
+// initQueries selects the queries of the database type, SQLServer by
+// default, included and not excluded
+func initQueries(s *SQLServer) error {
+	databaseType := s.DatabaseType
+	if databaseType == "" {
+		databaseType = "SQLServer"
+	}
+	supported, ok := databaseQueries[databaseType]
+	if !ok {
+		return fmt.Errorf("unknown database_type %q, must be SQLServer, "+
+			"AzureSQLDB or AzureSQLManagedInstance", databaseType)
+	}
+	for _, name := range append(s.IncludeQuery, s.ExcludeQuery...) {
+		if _, ok := supported[name]; !ok {
+			var names []string
+			for n := range supported {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown query %q of %s, must be one of %s",
+				name, databaseType, strings.Join(names, ", "))
+		}
+	}
+
 	queries = make(MapQuery)
-	queries["PerformanceCounters"] = Query{Script: sqlPerformanceCounters, ResultByRow: true}
-	queries["WaitStatsCategorized"] = Query{Script: sqlWaitStatsCategorized, ResultByRow: false}
-	queries["CPUHistory"] = Query{Script: sqlCPUHistory, ResultByRow: false}
-	queries["DatabaseIO"] = Query{Script: sqlDatabaseIO, ResultByRow: false}
-	queries["DatabaseSize"] = Query{Script: sqlDatabaseSize, ResultByRow: false}
-	queries["DatabaseStats"] = Query{Script: sqlDatabaseStats, ResultByRow: false}
-	queries["DatabaseProperties"] = Query{Script: sqlDatabaseProperties, ResultByRow: false}
-	queries["MemoryClerk"] = Query{Script: sqlMemoryClerk, ResultByRow: false}
-	queries["VolumeSpace"] = Query{Script: sqlVolumeSpace, ResultByRow: false}
-	queries["PerformanceMetrics"] = Query{Script: sqlPerformanceMetrics, ResultByRow: false}
+	for name, query := range supported {
+		if len(s.IncludeQuery) > 0 && !contains(s.IncludeQuery, name) {
+			continue
+		}
+		if contains(s.ExcludeQuery, name) {
+			continue
+		}
+		queries[name] = query
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Gather collect data from SQL Server
 func (s *SQLServer) Gather(acc telegraf.Accumulator) error {
-	initQueries()
+	if err := initQueries(s); err != nil {
+		return err
+	}
 
 	if len(s.Servers) == 0 {
 		s.Servers = append(s.Servers, defaultServer)
 	}
 
 	var wg sync.WaitGroup
-	var outerr error
+	var errMu sync.Mutex
+	var errorStrings []string
 
 	for _, serv := range s.Servers {
-		for _, query := range queries {
+		for name, query := range queries {
 			wg.Add(1)
-			go func(serv string, query Query) {
+			go func(serv string, name string, query Query) {
 				defer wg.Done()
-				outerr = s.gatherServer(serv, query, acc)
-			}(serv, query)
+				if err := s.gatherServer(serv, query, acc); err != nil {
+					errMu.Lock()
+					errorStrings = append(errorStrings,
+						fmt.Sprintf("query %s: %s", name, err))
+					errMu.Unlock()
+				}
+			}(serv, name, query)
 		}
 	}
 
 	wg.Wait()
-	return outerr
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
 }
 
 func (s *SQLServer) gatherServer(server string, query Query, acc telegraf.Accumulator) error {
@@ -1509,3 +1605,134 @@ PIVOT(SUM(used_percent) FOR volume IN (' + @ColumnName + ')) AS PVTTable'
 
 EXEC sp_executesql @DynamicPivotQuery;
 `
+
+const sqlAvailabilityReplicaStates string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+IF SERVERPROPERTY('IsHadrEnabled') = 1
+SELECT measurement = 'Availability replica state'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Availability group'
+, availability_group = ag.name
+, replica_server_name = ar.replica_server_name
+, availability_mode_desc = ar.availability_mode_desc
+, role_desc = ISNULL(ars.role_desc, 'RESOLVING')
+, synchronization_health_desc = ISNULL(ars.synchronization_health_desc, 'NOT_HEALTHY')
+, role = CAST(ISNULL(ars.role, 0) AS int)
+, is_local = CAST(ISNULL(ars.is_local, 0) AS int)
+, connected_state = CAST(ISNULL(ars.connected_state, 0) AS int)
+, operational_state = CAST(ISNULL(ars.operational_state, 0) AS int)
+, synchronization_health = CAST(ISNULL(ars.synchronization_health, 0) AS int)
+FROM sys.availability_replicas AS ar
+INNER JOIN sys.availability_groups AS ag ON ag.group_id = ar.group_id
+LEFT JOIN sys.dm_hadr_availability_replica_states AS ars ON ars.replica_id = ar.replica_id;
+`
+
+const sqlAvailabilityDatabaseReplicas string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+IF SERVERPROPERTY('IsHadrEnabled') = 1
+SELECT measurement = 'Availability database replica state'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Availability group'
+, availability_group = ag.name
+, replica_server_name = ar.replica_server_name
+, database_name = DB_NAME(drs.database_id)
+, synchronization_state_desc = drs.synchronization_state_desc
+, synchronization_health_desc = drs.synchronization_health_desc
+, is_primary_replica = CAST(drs.is_primary_replica AS int)
+, is_suspended = CAST(drs.is_suspended AS int)
+, synchronization_state = CAST(drs.synchronization_state AS int)
+, synchronization_health = CAST(drs.synchronization_health AS int)
+, log_send_queue_size_kb = CAST(ISNULL(drs.log_send_queue_size, 0) AS bigint)
+, log_send_rate_kb = CAST(ISNULL(drs.log_send_rate, 0) AS bigint)
+, redo_queue_size_kb = CAST(ISNULL(drs.redo_queue_size, 0) AS bigint)
+, redo_rate_kb = CAST(ISNULL(drs.redo_rate, 0) AS bigint)
+FROM sys.dm_hadr_database_replica_states AS drs
+INNER JOIN sys.availability_replicas AS ar ON ar.replica_id = drs.replica_id
+INNER JOIN sys.availability_groups AS ag ON ag.group_id = drs.group_id;
+`
+
+const sqlAzureDBResourceStats string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+SELECT TOP(1) measurement = 'Azure SQL DB resource stats'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Azure SQL DB resource stats'
+, database_name = DB_NAME()
+, avg_cpu_percent = CAST(avg_cpu_percent AS float)
+, avg_data_io_percent = CAST(avg_data_io_percent AS float)
+, avg_log_write_percent = CAST(avg_log_write_percent AS float)
+, avg_memory_usage_percent = CAST(avg_memory_usage_percent AS float)
+, xtp_storage_percent = CAST(ISNULL(xtp_storage_percent, 0) AS float)
+, max_worker_percent = CAST(max_worker_percent AS float)
+, max_session_percent = CAST(max_session_percent AS float)
+, dtu_limit = CAST(ISNULL(dtu_limit, 0) AS int)
+FROM sys.dm_db_resource_stats
+ORDER BY end_time DESC;
+`
+
+const sqlAzureDBWaitStats string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+SELECT measurement = 'Azure SQL DB wait stats'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Wait stats'
+, database_name = DB_NAME()
+, wait_type = wait_type
+, wait_time_ms = wait_time_ms
+, resource_wait_ms = wait_time_ms - signal_wait_time_ms
+, signal_wait_time_ms = signal_wait_time_ms
+, max_wait_time_ms = max_wait_time_ms
+, waiting_tasks_count = waiting_tasks_count
+FROM sys.dm_db_wait_stats
+WHERE wait_time_ms > 0
+AND wait_type NOT IN (
+	N'BROKER_EVENTHANDLER', N'BROKER_RECEIVE_WAITFOR', N'BROKER_TASK_STOP',
+	N'BROKER_TO_FLUSH', N'BROKER_TRANSMITTER', N'CHECKPOINT_QUEUE',
+	N'CLR_AUTO_EVENT', N'CLR_MANUAL_EVENT', N'DIRTY_PAGE_POLL',
+	N'DISPATCHER_QUEUE_SEMAPHORE', N'FT_IFTS_SCHEDULER_IDLE_WAIT',
+	N'HADR_FILESTREAM_IOMGR_IOCOMPLETION', N'LAZYWRITER_SLEEP',
+	N'LOGMGR_QUEUE', N'QDS_ASYNC_QUEUE',
+	N'QDS_PERSIST_TASK_MAIN_LOOP_SLEEP', N'REQUEST_FOR_DEADLOCK_SEARCH',
+	N'SLEEP_TASK', N'SP_SERVER_DIAGNOSTICS_SLEEP', N'SQLTRACE_BUFFER_FLUSH',
+	N'WAITFOR', N'XE_DISPATCHER_WAIT', N'XE_TIMER_EVENT');
+`
+
+const sqlAzureDBDatabaseIO string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+SELECT measurement = 'Azure SQL DB database IO'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Database IO'
+, database_name = DB_NAME()
+, logical_filename = df.name
+, file_type = df.type_desc
+, reads = vfs.num_of_reads
+, read_bytes = vfs.num_of_bytes_read
+, read_latency_ms = vfs.io_stall_read_ms
+, writes = vfs.num_of_writes
+, write_bytes = vfs.num_of_bytes_written
+, write_latency_ms = vfs.io_stall_write_ms
+FROM sys.dm_io_virtual_file_stats(NULL, NULL) AS vfs
+INNER JOIN sys.database_files AS df ON df.file_id = vfs.file_id;
+`
+
+const sqlAzureMIResourceStats string = `SET NOCOUNT ON;
+SET TRANSACTION ISOLATION LEVEL READ UNCOMMITTED;
+
+SELECT TOP(1) measurement = 'Azure SQL MI resource stats'
+, servername = REPLACE(@@SERVERNAME, '\', ':')
+, type = 'Azure SQL MI resource stats'
+, sku = sku
+, hardware_generation = hardware_generation
+, virtual_core_count = CAST(virtual_core_count AS int)
+, avg_cpu_percent = CAST(avg_cpu_percent AS float)
+, reserved_storage_mb = CAST(reserved_storage_mb AS bigint)
+, storage_space_used_mb = CAST(storage_space_used_mb AS float)
+, io_requests = CAST(io_requests AS bigint)
+, io_bytes_read = CAST(io_bytes_read AS bigint)
+, io_bytes_written = CAST(io_bytes_written AS bigint)
+FROM sys.server_resource_stats
+ORDER BY end_time DESC;
+`
@@ -79,6 +79,35 @@ func TestSqlServer_ParseMetrics(t *testing.T) {
 	}
 }
 
+func TestSqlServer_InitQueries(t *testing.T) {
+	require.NoError(t, initQueries(&SQLServer{}))
+	require.Contains(t, queries, "AvailabilityReplicaStates")
+	require.Contains(t, queries, "VolumeSpace")
+	require.NotContains(t, queries, "AzureDBResourceStats")
+
+	require.NoError(t, initQueries(&SQLServer{
+		DatabaseType: "AzureSQLDB",
+		ExcludeQuery: []string{"AzureDBWaitStats"},
+	}))
+	require.Contains(t, queries, "AzureDBResourceStats")
+	require.Contains(t, queries, "PerformanceCounters")
+	require.NotContains(t, queries, "AzureDBWaitStats")
+	require.NotContains(t, queries, "CPUHistory")
+
+	require.NoError(t, initQueries(&SQLServer{
+		DatabaseType: "AzureSQLManagedInstance",
+		IncludeQuery: []string{"AzureMIResourceStats", "MemoryClerk"},
+	}))
+	require.Len(t, queries, 2)
+
+	// The queries must be supported by the database type
+	require.Error(t, initQueries(&SQLServer{DatabaseType: "Oracle"}))
+	require.Error(t, initQueries(&SQLServer{
+		DatabaseType: "AzureSQLDB",
+		IncludeQuery: []string{"VolumeSpace"},
+	}))
+}
+
 const mockPerformanceMetrics = `measurement;servername;type;Point In Time Recovery;Available physical memory (bytes);Average pending disk IO;Average runnable tasks;Average tasks;Buffer pool rate (bytes/sec);Connection memory per connection (bytes);Memory grant pending;Page File Usage (%);Page lookup per batch request;Page split per batch request;Readahead per page read;Signal wait (%);Sql compilation per batch request;Sql recompilation per batch request;Total target memory ratio
 Performance metrics;WIN8-DEV;Performance metrics;0;6353158144;0;0;7;2773;415061;0;25;229371;130;10;18;188;52;14`
 
@@ -1,9 +1,9 @@
 package statsd
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"sort"
 	"strconv"
@@ -15,14 +15,17 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
 )
 
 const UDP_PACKET_SIZE int = 1500
 
-var dropwarn = "ERROR: Message queue full. Discarding line [%s] " +
-	"You may want to increase allowed_pending_messages in the config\n"
+var dropwarn = "Message queue full. Discarding line [%s] " +
+	"You may want to increase allowed_pending_messages in the config"
 
 type Statsd struct {
+	// Protocol of the listener, "udp" or "tcp"
+	Protocol string
 	// Address & Port to serve from
 	ServiceAddress string
 
@@ -45,12 +48,25 @@ type Statsd struct {
 	// for statsd UDP packets. This will default to 1500 bytes.
 	UDPPacketSize int `toml:"udp_packet_size"`
 
+	// ParseDataDogTags parses the tags of the datadog extension of the
+	// statsd protocol, ie "metric:1|c|#tag1:value,tag2"
+	ParseDataDogTags bool            `toml:"parse_data_dog_tags"`
+	Log              telegraf.Logger `toml:"-"`
+
 	sync.Mutex
 
 	// Channel for all incoming statsd packets
 	in   chan []byte
 	done chan struct{}
 
+	// The UDP connection or TCP listener, and the TCP connections, closed on
+	// Stop. wg waits for the goroutines reading them.
+	udpConn     *net.UDPConn
+	tcpListener net.Listener
+	connsLock   sync.Mutex
+	conns       map[net.Conn]struct{}
+	wg          sync.WaitGroup
+
 	// Cache gauges, counters & sets so they can be aggregated as they arrive
 	// gauges and counters map measurement/tags hash -> field name -> metrics
 	// sets and timings map measurement/tags hash -> metrics
@@ -59,6 +75,11 @@ type Statsd struct {
 	sets     map[string]cachedset
 	timings  map[string]cachedtimings
 
+	// Statistics reported by the internal input
+	packetsRecv    selfstat.Stat
+	packetsDropped selfstat.Stat
+	parseErrors    selfstat.Stat
+
 	// bucket -> influx templates
 	Templates []string
 }
@@ -123,7 +144,9 @@ func (_ *Statsd) Description() string {
 }
 
 const sampleConfig = `
-  # Address and port to host UDP listener on
+  # Protocol of the listener, "udp" or "tcp"
+  protocol = "udp"
+  # Address and port to host the listener on
   service_address = ":8125"
   # Delete gauges every interval (default=false)
   delete_gauges = false
@@ -155,6 +178,10 @@ const sampleConfig = `
   # UDP packet size for the server to listen for. This will depend on the size
   # of the packets that the client is sending, which is usually 1500 bytes.
   udp_packet_size = 1500
+
+  # Parse the tags of the datadog statsd extension, ie
+  # "users.online:1|c|#country:china,beta"
+  parse_data_dog_tags = false
 `
 
 func (_ *Statsd) SampleConfig() string {
@@ -220,44 +247,132 @@ func (s *Statsd) Start() error {
 	s.sets = make(map[string]cachedset)
 	s.timings = make(map[string]cachedtimings)
 
-	// Start the UDP listener
-	go s.udpListen()
+	// The TCP lines are counted as the UDP packets
+	unit := "udp_packets"
+	switch s.Protocol {
+	case "", "udp":
+		address, err := net.ResolveUDPAddr("udp", s.ServiceAddress)
+		if err != nil {
+			return err
+		}
+		if s.udpConn, err = net.ListenUDP("udp", address); err != nil {
+			return err
+		}
+		s.Log.Infof("Statsd UDP listener listening on: %s",
+			s.udpConn.LocalAddr().String())
+		s.wg.Add(1)
+		go s.udpListen()
+	case "tcp":
+		var err error
+		if s.tcpListener, err = net.Listen("tcp", s.ServiceAddress); err != nil {
+			return err
+		}
+		s.Log.Infof("Statsd TCP listener listening on: %s",
+			s.tcpListener.Addr().String())
+		s.conns = make(map[net.Conn]struct{})
+		unit = "tcp_lines"
+		s.wg.Add(1)
+		go s.tcpListen()
+	default:
+		return fmt.Errorf("unsupported protocol %q, must be udp or tcp",
+			s.Protocol)
+	}
+
+	tags := map[string]string{"address": s.ServiceAddress}
+	s.packetsRecv = selfstat.Register("statsd", unit+"_received", tags)
+	s.packetsDropped = selfstat.Register("statsd", unit+"_dropped", tags)
+	s.parseErrors = selfstat.Register("statsd", "parse_errors", tags)
+
 	// Start the line parser
 	go s.parser()
-	log.Printf("Started the statsd service on %s\n", s.ServiceAddress)
+	s.Log.Infof("Started the statsd service on %s", s.ServiceAddress)
 	return nil
 }
 
-// udpListen starts listening for udp packets on the configured port.
-func (s *Statsd) udpListen() error {
-	address, _ := net.ResolveUDPAddr("udp", s.ServiceAddress)
-	listener, err := net.ListenUDP("udp", address)
-	if err != nil {
-		log.Fatalf("ERROR: ListenUDP - %s", err)
+// udpListen reads the udp packets until the listener is stopped.
+func (s *Statsd) udpListen() {
+	defer s.wg.Done()
+	for {
+		buf := make([]byte, s.UDPPacketSize)
+		n, _, err := s.udpConn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+		s.queue(buf[:n])
 	}
-	defer listener.Close()
-	log.Println("Statsd listener listening on: ", listener.LocalAddr().String())
+}
 
+// tcpListen accepts the tcp connections until the listener is stopped, their
+// lines being read as the udp packets.
+func (s *Statsd) tcpListen() {
+	defer s.wg.Done()
 	for {
+		conn, err := s.tcpListener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+
+		// A connection accepted while stopping is not closed by Stop
+		s.connsLock.Lock()
 		select {
 		case <-s.done:
-			return nil
+			s.connsLock.Unlock()
+			conn.Close()
+			return
 		default:
-			buf := make([]byte, s.UDPPacketSize)
-			n, _, err := listener.ReadFromUDP(buf)
-			if err != nil {
-				log.Printf("ERROR: %s\n", err.Error())
-			}
+		}
+		s.conns[conn] = struct{}{}
+		s.connsLock.Unlock()
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
 
-			select {
-			case s.in <- buf[:n]:
-			default:
-				log.Printf(dropwarn, string(buf[:n]))
-			}
+func (s *Statsd) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connsLock.Lock()
+		delete(s.conns, conn)
+		s.connsLock.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		s.queue([]byte(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		select {
+		case <-s.done:
+		default:
+			s.Log.Errorf("Reading from %s: %s", conn.RemoteAddr(), err)
 		}
 	}
 }
 
+// queue queues a packet for the parser, dropping it if the queue is full
+func (s *Statsd) queue(packet []byte) {
+	s.packetsRecv.Incr(1)
+	select {
+	case s.in <- packet:
+	default:
+		s.packetsDropped.Incr(1)
+		s.Log.Errorf(dropwarn, string(packet))
+	}
+}
+
 // parser monitors the s.in channel, if there is a packet ready, it parses the
 // packet into statsd strings and then calls parseStatsdLine, which parses a
 // single statsd metric into a struct.
@@ -271,7 +386,9 @@ func (s *Statsd) parser() error {
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
 				if line != "" {
-					s.parseStatsdLine(line)
+					if err := s.parseStatsdLine(line); err != nil {
+						s.parseErrors.Incr(1)
+					}
 				}
 			}
 		}
@@ -284,10 +401,24 @@ func (s *Statsd) parseStatsdLine(line string) error {
 	s.Lock()
 	defer s.Unlock()
 
+	// The datadog tags, "|#tag1:value,tag2", may come in any position after
+	// the value, and are removed before parsing the rest of the line
+	var ddtags map[string]string
+	if s.ParseDataDogTags {
+		if i := strings.Index(line, "|#"); i >= 0 {
+			tagstr, rest := line[i+2:], ""
+			if j := strings.Index(tagstr, "|"); j >= 0 {
+				tagstr, rest = tagstr[:j], tagstr[j:]
+			}
+			ddtags = parseDataDogTags(tagstr)
+			line = line[:i] + rest
+		}
+	}
+
 	// Validate splitting the line on ":"
 	bits := strings.Split(line, ":")
 	if len(bits) < 2 {
-		log.Printf("Error: splitting ':', Unable to parse metric: %s\n", line)
+		s.Log.Errorf("Splitting ':', Unable to parse metric: %s", line)
 		return errors.New("Error Parsing statsd line")
 	}
 
@@ -303,22 +434,22 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		// Validate splitting the bit on "|"
 		pipesplit := strings.Split(bit, "|")
 		if len(pipesplit) < 2 {
-			log.Printf("Error: splitting '|', Unable to parse metric: %s\n", line)
+			s.Log.Errorf("Splitting '|', Unable to parse metric: %s", line)
 			return errors.New("Error Parsing statsd line")
 		} else if len(pipesplit) > 2 {
 			sr := pipesplit[2]
-			errmsg := "Error: parsing sample rate, %s, it must be in format like: " +
-				"@0.1, @0.5, etc. Ignoring sample rate for line: %s\n"
+			errmsg := "Parsing sample rate, %s, it must be in format like: " +
+				"@0.1, @0.5, etc. Ignoring sample rate for line: %s"
 			if strings.Contains(sr, "@") && len(sr) > 1 {
 				samplerate, err := strconv.ParseFloat(sr[1:], 64)
 				if err != nil {
-					log.Printf(errmsg, err.Error(), line)
+					s.Log.Warnf(errmsg, err.Error(), line)
 				} else {
 					// sample rate successfully parsed
 					m.samplerate = samplerate
 				}
 			} else {
-				log.Printf(errmsg, "", line)
+				s.Log.Warnf(errmsg, "", line)
 			}
 		}
 
@@ -327,14 +458,14 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "c", "s", "ms", "h":
 			m.mtype = pipesplit[1]
 		default:
-			log.Printf("Error: Statsd Metric type %s unsupported", pipesplit[1])
+			s.Log.Errorf("Statsd Metric type %s unsupported", pipesplit[1])
 			return errors.New("Error Parsing statsd line")
 		}
 
 		// Parse the value
 		if strings.ContainsAny(pipesplit[0], "-+") {
 			if m.mtype != "g" {
-				log.Printf("Error: +- values are only supported for gauges: %s\n", line)
+				s.Log.Errorf("+- values are only supported for gauges: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.additive = true
@@ -344,7 +475,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		case "g", "ms", "h":
 			v, err := strconv.ParseFloat(pipesplit[0], 64)
 			if err != nil {
-				log.Printf("Error: parsing value to float64: %s\n", line)
+				s.Log.Errorf("Parsing value to float64: %s", line)
 				return errors.New("Error Parsing statsd line")
 			}
 			m.floatvalue = v
@@ -354,7 +485,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 			if err != nil {
 				v2, err2 := strconv.ParseFloat(pipesplit[0], 64)
 				if err2 != nil {
-					log.Printf("Error: parsing value to int64: %s\n", line)
+					s.Log.Errorf("Parsing value to int64: %s", line)
 					return errors.New("Error Parsing statsd line")
 				}
 				v = int64(v2)
@@ -368,6 +499,9 @@ func (s *Statsd) parseStatsdLine(line string) error {
 
 		// Parse the name & tags from bucket
 		m.name, m.field, m.tags = s.parseName(m.bucket)
+		for k, v := range ddtags {
+			m.tags[k] = v
+		}
 		// fields are not supported for timings, so if specified combine into
 		// the name
 		if (m.mtype == "ms" || m.mtype == "h") && m.field != "value" {
@@ -457,6 +591,23 @@ func parseKeyValue(keyvalue string) (string, string) {
 	return key, val
 }
 
+// parseDataDogTags parses datadog tags, ie "tag1:value,tag2", the tags
+// without a value being set to "true"
+func parseDataDogTags(tagstr string) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(tagstr, ",") {
+		if tag == "" {
+			continue
+		}
+		if i := strings.Index(tag, ":"); i >= 0 {
+			tags[tag[:i]] = tag[i+1:]
+		} else {
+			tags[tag] = "true"
+		}
+	}
+	return tags
+}
+
 // aggregate takes in a metric. It then
 // aggregates and caches the current value(s). It does not deal with the
 // Delete* options, because those are dealt with in the Gather function.
@@ -541,16 +692,36 @@ func (s *Statsd) aggregate(m metric) {
 }
 
 func (s *Statsd) Stop() {
+	s.Log.Info("Stopping the statsd service")
+	close(s.done)
+
+	// The listeners must be stopped before the channel of the packets is
+	// closed
+	if s.udpConn != nil {
+		s.udpConn.Close()
+	}
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+		s.connsLock.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connsLock.Unlock()
+	}
+	s.wg.Wait()
+
 	s.Lock()
 	defer s.Unlock()
-	log.Println("Stopping the statsd service")
-	close(s.done)
 	close(s.in)
+	selfstat.Unregister(s.packetsRecv)
+	selfstat.Unregister(s.packetsDropped)
+	selfstat.Unregister(s.parseErrors)
 }
 
 func init() {
 	inputs.Add("statsd", func() telegraf.Input {
 		return &Statsd{
+			Protocol:      "udp",
 			ConvertNames:  true,
 			UDPPacketSize: UDP_PACKET_SIZE,
 		}
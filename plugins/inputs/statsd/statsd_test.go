@@ -3,7 +3,9 @@ package statsd
 import (
 	"errors"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -11,6 +13,7 @@ import (
 // Invalid lines should return an error
 func TestParse_InvalidLines(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	invalid_lines := []string{
 		"i.dont.have.a.pipe:45g",
 		"i.dont.have.a.colon45|c",
@@ -35,6 +38,7 @@ func TestParse_InvalidLines(t *testing.T) {
 // Invalid sample rates should be ignored and not applied
 func TestParse_InvalidSampleRate(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	invalid_lines := []string{
 		"invalid.sample.rate:45|c|0.1",
 		"invalid.sample.rate.2:45|c|@foo",
@@ -87,6 +91,7 @@ func TestParse_InvalidSampleRate(t *testing.T) {
 // Names should be parsed like . -> _ and - -> __
 func TestParse_DefaultNameParsing(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	valid_lines := []string{
 		"valid:1|c",
 		"valid.foo-bar:11|c",
@@ -124,6 +129,7 @@ func TestParse_DefaultNameParsing(t *testing.T) {
 // Test that template name transformation works
 func TestParse_Template(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.Templates = []string{
 		"measurement.measurement.host.service",
 	}
@@ -166,6 +172,7 @@ func TestParse_Template(t *testing.T) {
 // Test that template filters properly
 func TestParse_TemplateFilter(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.Templates = []string{
 		"cpu.idle.* measurement.measurement.host",
 	}
@@ -208,6 +215,7 @@ func TestParse_TemplateFilter(t *testing.T) {
 // Test that most specific template is chosen
 func TestParse_TemplateSpecificity(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.Templates = []string{
 		"cpu.* measurement.foo.host",
 		"cpu.idle.* measurement.measurement.host",
@@ -246,6 +254,7 @@ func TestParse_TemplateSpecificity(t *testing.T) {
 // Test that most specific template is chosen
 func TestParse_TemplateFields(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.Templates = []string{
 		"* measurement.measurement.field",
 	}
@@ -360,6 +369,7 @@ func TestParse_Fields(t *testing.T) {
 // Test that tags within the bucket are parsed correctly
 func TestParse_Tags(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	tests := []struct {
 		bucket string
@@ -413,6 +423,7 @@ func TestParse_Tags(t *testing.T) {
 // Test that statsd buckets are parsed to measurement names properly
 func TestParseName(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	tests := []struct {
 		in_name  string
@@ -472,6 +483,7 @@ func TestParseName(t *testing.T) {
 // as different outputs
 func TestParse_MeasurementsWithSameName(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	// Test that counters work
 	valid_lines := []string{
@@ -530,6 +542,7 @@ func TestParse_MeasurementsWithMultipleValues(t *testing.T) {
 	}
 
 	s_single := NewStatsd()
+	s_single.Log = testutil.Logger{}
 	s_multiple := NewStatsd()
 
 	for _, line := range single_lines {
@@ -624,6 +637,7 @@ func TestParse_MeasurementsWithMultipleValues(t *testing.T) {
 // Valid lines should be parsed and their values should be cached
 func TestParse_ValidLines(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	valid_lines := []string{
 		"valid:45|c",
 		"valid:45|s",
@@ -643,6 +657,7 @@ func TestParse_ValidLines(t *testing.T) {
 // Tests low-level functionality of gauges
 func TestParse_Gauges(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	// Test that gauge +- values work
 	valid_lines := []string{
@@ -709,6 +724,7 @@ func TestParse_Gauges(t *testing.T) {
 // Tests low-level functionality of sets
 func TestParse_Sets(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	// Test that sets work
 	valid_lines := []string{
@@ -757,6 +773,7 @@ func TestParse_Sets(t *testing.T) {
 // Tests low-level functionality of counters
 func TestParse_Counters(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 
 	// Test that counters work
 	valid_lines := []string{
@@ -811,6 +828,7 @@ func TestParse_Counters(t *testing.T) {
 // Tests low-level functionality of timings
 func TestParse_Timings(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.Percentiles = []int{90}
 	acc := &testutil.Accumulator{}
 
@@ -847,6 +865,7 @@ func TestParse_Timings(t *testing.T) {
 
 func TestParse_Timings_Delete(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.DeleteTimings = true
 	fakeacc := &testutil.Accumulator{}
 	var err error
@@ -871,6 +890,7 @@ func TestParse_Timings_Delete(t *testing.T) {
 // Tests the delete_gauges option
 func TestParse_Gauges_Delete(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.DeleteGauges = true
 	fakeacc := &testutil.Accumulator{}
 	var err error
@@ -897,6 +917,7 @@ func TestParse_Gauges_Delete(t *testing.T) {
 // Tests the delete_sets option
 func TestParse_Sets_Delete(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.DeleteSets = true
 	fakeacc := &testutil.Accumulator{}
 	var err error
@@ -923,6 +944,7 @@ func TestParse_Sets_Delete(t *testing.T) {
 // Tests the delete_counters option
 func TestParse_Counters_Delete(t *testing.T) {
 	s := NewStatsd()
+	s.Log = testutil.Logger{}
 	s.DeleteCounters = true
 	fakeacc := &testutil.Accumulator{}
 	var err error
@@ -964,6 +986,93 @@ func TestParseKeyValue(t *testing.T) {
 	}
 }
 
+// Test that the datadog tags are parsed in any position after the value
+func TestParse_DataDogTags(t *testing.T) {
+	s := NewStatsd()
+	s.Log = testutil.Logger{}
+	s.ParseDataDogTags = true
+
+	lines := []string{
+		"my_counter:1|c|#host:localhost,environment:prod,endpoint:/:tenant?/oauth/ro",
+		"my_gauge:10.1|g|#live",
+		"my_timer:3|ms|@0.1|#host:localhost",
+		"my_set:1|s|#host:localhost|@0.5",
+	}
+	for _, line := range lines {
+		if err := s.parseStatsdLine(line); err != nil {
+			t.Errorf("Parsing line %s should not have resulted in an error\n",
+				line)
+		}
+	}
+
+	expected := map[string]map[string]string{
+		"my_counter": {
+			"host":        "localhost",
+			"environment": "prod",
+			"endpoint":    "/:tenant?/oauth/ro",
+			"metric_type": "counter",
+		},
+		"my_gauge": {"live": "true", "metric_type": "gauge"},
+		"my_timer": {"host": "localhost", "metric_type": "timing"},
+		"my_set":   {"host": "localhost", "metric_type": "set"},
+	}
+	actual := make(map[string]map[string]string)
+	for _, m := range s.counters {
+		actual[m.name] = m.tags
+	}
+	for _, m := range s.gauges {
+		actual[m.name] = m.tags
+	}
+	for _, m := range s.timings {
+		actual[m.name] = m.tags
+	}
+	for _, m := range s.sets {
+		actual[m.name] = m.tags
+	}
+	for name, tags := range expected {
+		if fmt.Sprint(actual[name]) != fmt.Sprint(tags) {
+			t.Errorf("Expected tags %v for %s, got %v", tags, name,
+				actual[name])
+		}
+	}
+
+	err := test_validate_counter("my_counter", 1, s.counters)
+	if err != nil {
+		t.Error(err.Error())
+	}
+}
+
+// Test that the lines received over tcp are parsed
+func TestTCPListener(t *testing.T) {
+	s := NewStatsd()
+	s.Log = testutil.Logger{}
+	s.Protocol = "tcp"
+	s.ServiceAddress = "127.0.0.1:0"
+	s.AllowedPendingMessages = 10
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	conn, err := net.Dial("tcp", s.tcpListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(conn, "tcp.counter:2|c\ntcp.counter:3|c\n")
+	conn.Close()
+
+	for i := 0; i < 100; i++ {
+		s.Lock()
+		err = test_validate_counter("tcp_counter", 5, s.counters)
+		s.Unlock()
+		if err == nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error(err.Error())
+}
+
 // Test utility functions
 
 func test_validate_set(
@@ -0,0 +1,312 @@
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp",
+	"cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6",
+	"local7",
+}
+
+// message is a syslog message of RFC 5424, or of RFC 3164 with a version 0,
+// the missing values, "-" in RFC 5424, being empty
+type message struct {
+	facility  int
+	severity  int
+	version   int
+	timestamp time.Time
+	hostname  string
+	appname   string
+	procid    string
+	msgid     string
+	// structuredData are the parameters of the SD elements, by their ID
+	structuredData map[string]map[string]string
+	message        string
+}
+
+// parseMessage parses a syslog message of RFC 5424, or of RFC 3164 if it has
+// no version. With bestEffort, the messages not valid are returned with
+// the parts parsed before the error, the rest of the message being their
+// message, and the error is only returned if even the priority is not valid.
+func parseMessage(b []byte, bestEffort bool) (*message, error) {
+	m := &message{}
+	p := &parser{b: b}
+	pri, err := p.priority()
+	if err != nil {
+		return nil, err
+	}
+	m.facility, m.severity = pri/8, pri%8
+
+	if version, ok := p.version(); ok {
+		m.version = version
+		err = p.rfc5424(m)
+	} else {
+		err = p.rfc3164(m)
+	}
+	if err != nil {
+		if !bestEffort {
+			return nil, err
+		}
+		m.message = string(p.rest())
+	}
+	return m, nil
+}
+
+// parser reads the parts of a message
+type parser struct {
+	b   []byte
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid syslog message at offset %d: %s", p.pos,
+		fmt.Sprintf(format, args...))
+}
+
+func (p *parser) rest() []byte {
+	return p.b[p.pos:]
+}
+
+// priority reads the PRI part, "<" facility * 8 + severity ">"
+func (p *parser) priority() (int, error) {
+	if len(p.b) < 3 || p.b[0] != '<' {
+		return 0, p.errorf("missing priority")
+	}
+	// The priority has at most 3 digits
+	n := len(p.b)
+	if n > 5 {
+		n = 5
+	}
+	end := bytes.IndexByte(p.b[:n], '>')
+	if end < 2 {
+		return 0, p.errorf("invalid priority")
+	}
+	pri, err := strconv.Atoi(string(p.b[1:end]))
+	if err != nil || pri > 191 {
+		return 0, p.errorf("invalid priority %q", p.b[1:end])
+	}
+	p.pos = end + 1
+	return pri, nil
+}
+
+// version reads the VERSION of RFC 5424, returning false if there is none
+func (p *parser) version() (int, bool) {
+	i := p.pos
+	for i < len(p.b) && i-p.pos < 2 && p.b[i] >= '0' && p.b[i] <= '9' {
+		i++
+	}
+	if i == p.pos || i >= len(p.b) || p.b[i] != ' ' || p.b[p.pos] == '0' {
+		return 0, false
+	}
+	version, _ := strconv.Atoi(string(p.b[p.pos:i]))
+	p.pos = i + 1
+	return version, true
+}
+
+// token reads the part until the next space, and the space
+func (p *parser) token(name string) (string, error) {
+	end := bytes.IndexByte(p.rest(), ' ')
+	if end == -1 {
+		return "", p.errorf("missing %s", name)
+	}
+	token := string(p.b[p.pos : p.pos+end])
+	if token == "" {
+		return "", p.errorf("empty %s", name)
+	}
+	p.pos += end + 1
+	if token == "-" {
+		return "", nil
+	}
+	return token, nil
+}
+
+// rfc5424 reads the parts of a message of RFC 5424 following its version
+func (p *parser) rfc5424(m *message) error {
+	ts, err := p.token("timestamp")
+	if err != nil {
+		return err
+	}
+	if ts != "" {
+		if m.timestamp, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			p.pos -= len(ts) + 1
+			return p.errorf("invalid timestamp %q", ts)
+		}
+	}
+	for _, part := range []struct {
+		name  string
+		value *string
+	}{
+		{"hostname", &m.hostname},
+		{"appname", &m.appname},
+		{"procid", &m.procid},
+		{"msgid", &m.msgid},
+	} {
+		if *part.value, err = p.token(part.name); err != nil {
+			return err
+		}
+	}
+
+	// The structured data is followed by the message, if any, without a
+	// trailing space otherwise
+	if err := p.structuredData(m); err != nil {
+		return err
+	}
+	if p.pos < len(p.b) {
+		if p.b[p.pos] != ' ' {
+			return p.errorf("missing space before the message")
+		}
+		p.pos++
+		m.message = strings.TrimPrefix(string(p.rest()), "\ufeff")
+		p.pos = len(p.b)
+	}
+	return nil
+}
+
+// structuredData reads the STRUCTURED-DATA, "-" or SD elements,
+// [id name="value" ...], with \", \\ and \] escaped in the values
+func (p *parser) structuredData(m *message) error {
+	if p.pos < len(p.b) && p.b[p.pos] == '-' {
+		p.pos++
+		return nil
+	}
+	if p.pos >= len(p.b) || p.b[p.pos] != '[' {
+		return p.errorf("missing structured data")
+	}
+
+	m.structuredData = make(map[string]map[string]string)
+	for p.pos < len(p.b) && p.b[p.pos] == '[' {
+		start := p.pos
+		p.pos++
+		id, err := p.sdName("SD-ID")
+		if err != nil {
+			p.pos = start
+			return err
+		}
+		params := make(map[string]string)
+		for p.pos < len(p.b) && p.b[p.pos] == ' ' {
+			p.pos++
+			name, err := p.sdName("SD-PARAM name")
+			if err != nil {
+				p.pos = start
+				return err
+			}
+			if !strings.HasPrefix(string(p.rest()), `="`) {
+				p.pos = start
+				return p.errorf("missing value of %s", name)
+			}
+			p.pos += 2
+			value, err := p.sdValue()
+			if err != nil {
+				p.pos = start
+				return err
+			}
+			params[name] = value
+		}
+		if p.pos >= len(p.b) || p.b[p.pos] != ']' {
+			p.pos = start
+			return p.errorf("unterminated structured data element")
+		}
+		p.pos++
+		m.structuredData[id] = params
+	}
+	return nil
+}
+
+// sdName reads a name of the structured data, up to 32 printable characters
+// except "=", " ", "]" and '"'
+func (p *parser) sdName(kind string) (string, error) {
+	start := p.pos
+	for p.pos < len(p.b) && p.b[p.pos] > ' ' && p.b[p.pos] < 127 &&
+		!strings.ContainsRune(`= ]"`, rune(p.b[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start || p.pos-start > 32 {
+		return "", p.errorf("invalid %s", kind)
+	}
+	return string(p.b[start:p.pos]), nil
+}
+
+// sdValue reads a value of the structured data and its closing quote
+func (p *parser) sdValue() (string, error) {
+	var value []byte
+	for p.pos < len(p.b) {
+		c := p.b[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return string(value), nil
+		case '\\':
+			if p.pos < len(p.b) && strings.ContainsRune(`"\]`,
+				rune(p.b[p.pos])) {
+				c = p.b[p.pos]
+				p.pos++
+			}
+		}
+		value = append(value, c)
+	}
+	return "", p.errorf("unterminated structured data value")
+}
+
+// rfc3164 reads the parts of a message of RFC 3164 following its priority,
+// "Jan _2 15:04:05 hostname tag[pid]: message". The timestamps have no year,
+// they are of the last twelve months.
+func (p *parser) rfc3164(m *message) error {
+	if len(p.rest()) < len(time.Stamp)+1 {
+		return p.errorf("missing timestamp")
+	}
+	ts := string(p.b[p.pos : p.pos+len(time.Stamp)])
+	t, err := time.ParseInLocation(time.Stamp, ts, time.Local)
+	if err != nil || p.b[p.pos+len(time.Stamp)] != ' ' {
+		return p.errorf("invalid timestamp %q", ts)
+	}
+	now := time.Now()
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.AddDate(0, 0, 1)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	m.timestamp = t
+	p.pos += len(time.Stamp) + 1
+
+	if m.hostname, err = p.token("hostname"); err != nil {
+		return err
+	}
+
+	// The tag is optional, the message starting at the first character not
+	// allowed in tags otherwise
+	rest := string(p.rest())
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' || strings.ContainsRune("_-./", r))
+	})
+	if end > 0 && end <= 48 {
+		tag := rest[:end]
+		rest = rest[end:]
+		if strings.HasPrefix(rest, "[") {
+			if i := strings.Index(rest, "]"); i > 0 {
+				m.procid = rest[1:i]
+				rest = rest[i+1:]
+			}
+		}
+		if strings.HasPrefix(rest, ":") {
+			m.appname = tag
+			rest = strings.TrimPrefix(rest[1:], " ")
+		} else {
+			m.procid = ""
+			rest = string(p.rest())
+		}
+	}
+	m.message = rest
+	p.pos = len(p.b)
+	return nil
+}
@@ -0,0 +1,90 @@
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	m, err := parseMessage([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high \"quoted\" \]"] An application event`), false)
+	require.NoError(t, err)
+	assert.Equal(t, &message{
+		facility:  20,
+		severity:  5,
+		version:   1,
+		timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+		hostname:  "mymachine.example.com",
+		appname:   "evntslog",
+		msgid:     "ID47",
+		structuredData: map[string]map[string]string{
+			"exampleSDID@32473": {
+				"iut":         "3",
+				"eventSource": "Application",
+				"eventID":     "1011",
+			},
+			"examplePriority@32473": {"class": `high "quoted" ]`},
+		},
+		message: "An application event",
+	}, m)
+
+	// Without structured data, nor message
+	m, err = parseMessage([]byte(`<34>1 - - su 42 - -`), false)
+	require.NoError(t, err)
+	assert.Equal(t, &message{
+		facility: 4,
+		severity: 2,
+		version:  1,
+		appname:  "su",
+		procid:   "42",
+	}, m)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	m, err := parseMessage([]byte(`<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed`), false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, m.facility)
+	assert.Equal(t, 2, m.severity)
+	assert.Equal(t, 0, m.version)
+	assert.Equal(t, "mymachine", m.hostname)
+	assert.Equal(t, "su", m.appname)
+	assert.Equal(t, "230", m.procid)
+	assert.Equal(t, "'su root' failed", m.message)
+	assert.Equal(t, time.October, m.timestamp.Month())
+	assert.Equal(t, 22, m.timestamp.Hour())
+	assert.False(t, m.timestamp.After(time.Now().AddDate(0, 0, 1)))
+
+	// Without tag
+	m, err = parseMessage([]byte(`<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!`), false)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.99", m.hostname)
+	assert.Equal(t, "", m.appname)
+	assert.Equal(t, "Use the BFG!", m.message)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, msg := range []string{
+		``,
+		`no priority`,
+		`<192>1 - - - - - -`,
+		`<34>1 yesterday host app - - -`,
+		`<34>1 - host app - - [id`,
+		`<34>1 - host app - - [id name=value]`,
+		`<34>Oct 11 22:14`,
+	} {
+		_, err := parseMessage([]byte(msg), false)
+		assert.Error(t, err, msg)
+	}
+
+	// The parts parsed are kept with best effort
+	m, err := parseMessage([]byte(`<34>1 - host app - - [id name=value] msg`), true)
+	require.NoError(t, err)
+	assert.Equal(t, "host", m.hostname)
+	assert.Equal(t, "app", m.appname)
+	assert.Equal(t, "[id name=value] msg", m.message)
+
+	_, err = parseMessage([]byte(`no priority`), true)
+	assert.Error(t, err)
+}
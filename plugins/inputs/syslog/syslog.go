@@ -0,0 +1,404 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// maxMessageLength is the maximum length of the messages read over TCP, the
+// one of UDP being the maximum size of the datagrams
+const maxMessageLength = 64 * 1024
+
+// Syslog listens for syslog messages over UDP, TCP or TLS
+type Syslog struct {
+	Server string
+	// Framing of the messages over TCP, "octet-counting", each message
+	// prefixed by its length, or "non-transparent", each message ended by a
+	// newline
+	Framing        string
+	BestEffort     bool              `toml:"best_effort"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	MaxConnections int               `toml:"max_connections"`
+	// SDParamSeparator joins the IDs of the structured data elements and the
+	// names of their parameters in the fields
+	SDParamSeparator string `toml:"sdparam_separator"`
+	MetricBuffer     int    `toml:"metric_buffer"`
+
+	// TLS certificate and key of the listener, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	metricC chan telegraf.Metric
+	done    chan struct{}
+
+	// The UDP connection or TCP listener, and the TCP connections, closed on
+	// Stop. wg waits for the goroutines reading them.
+	udpConn     net.PacketConn
+	tcpListener net.Listener
+	connsLock   sync.Mutex
+	conns       map[net.Conn]struct{}
+	wg          sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Address to listen on, tcp://, udp:// or with TLS tcp:// and the TLS
+  # options, ie "tcp://:6514" or "udp://:514"
+  server = "tcp://:6514"
+
+  # Framing of the messages over TCP, "octet-counting" (RFC 5425 and 6587),
+  # each message prefixed by its length, or "non-transparent", each message
+  # ended by a newline
+  # framing = "octet-counting"
+
+  # Keep the messages not valid, with the parts parsed
+  # best_effort = false
+
+  # Close the TCP connections idle for this duration, never if 0
+  # read_timeout = "0s"
+  # Maximum number of TCP connections, unlimited if 0
+  # max_connections = 0
+
+  # Separator of the ID of the structured data elements and the names of
+  # their parameters in the fields
+  # sdparam_separator = "_"
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 100000
+
+  # TLS certificate and key of the listener, only accepting the clients
+  # presenting a certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+`
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Accept syslog messages over UDP, TCP or TLS"
+}
+
+func (s *Syslog) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, err := url.Parse(s.Server)
+	if err != nil {
+		return fmt.Errorf("invalid server %q: %s", s.Server, err)
+	}
+	switch s.Framing {
+	case "":
+		s.Framing = "octet-counting"
+	case "octet-counting", "non-transparent":
+	default:
+		return fmt.Errorf("unknown framing %q, must be octet-counting or "+
+			"non-transparent", s.Framing)
+	}
+	if s.SDParamSeparator == "" {
+		s.SDParamSeparator = "_"
+	}
+	if s.MetricBuffer == 0 {
+		s.MetricBuffer = 100000
+	}
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        s.TLSCert,
+		TLSKey:         s.TLSKey,
+		AllowedCACerts: s.TLSAllowedCACerts,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.metricC = make(chan telegraf.Metric, s.MetricBuffer)
+	s.done = make(chan struct{})
+	switch u.Scheme {
+	case "udp", "udp4", "udp6":
+		if tlsConfig != nil {
+			return fmt.Errorf("the TLS options require a tcp server")
+		}
+		if s.udpConn, err = net.ListenPacket(u.Scheme, u.Host); err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.udpListen()
+	case "tcp", "tcp4", "tcp6":
+		if s.tcpListener, err = net.Listen(u.Scheme, u.Host); err != nil {
+			return err
+		}
+		if tlsConfig != nil {
+			s.tcpListener = tls.NewListener(s.tcpListener, tlsConfig)
+		}
+		s.conns = make(map[net.Conn]struct{})
+		s.wg.Add(1)
+		go s.tcpListen()
+	default:
+		return fmt.Errorf("unsupported server %q, must be tcp:// or udp://",
+			s.Server)
+	}
+	s.Log.Infof("Started the syslog service on %s", s.Server)
+	return nil
+}
+
+// Addr returns the address listened on
+func (s *Syslog) Addr() net.Addr {
+	if s.udpConn != nil {
+		return s.udpConn.LocalAddr()
+	}
+	return s.tcpListener.Addr()
+}
+
+// udpListen reads the datagrams, a message each, until the listener is
+// stopped
+func (s *Syslog) udpListen() {
+	defer s.wg.Done()
+	buf := make([]byte, 65536)
+	for {
+		n, addr, err := s.udpConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+		s.handleMessage(buf[:n], addr)
+	}
+}
+
+// tcpListen accepts the tcp connections until the listener is stopped
+func (s *Syslog) tcpListen() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.tcpListener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.Log.Error(err)
+				continue
+			}
+		}
+
+		// A connection accepted while stopping is not closed by Stop
+		s.connsLock.Lock()
+		select {
+		case <-s.done:
+			s.connsLock.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		if s.MaxConnections > 0 && len(s.conns) >= s.MaxConnections {
+			s.connsLock.Unlock()
+			s.Log.Warnf("Refusing connection from %s, the maximum of %d "+
+				"connections is reached", conn.RemoteAddr(), s.MaxConnections)
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.connsLock.Unlock()
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn reads the messages of the connection, in its framing
+func (s *Syslog) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connsLock.Lock()
+		delete(s.conns, conn)
+		s.connsLock.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		if s.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.ReadTimeout.Duration))
+		}
+		var msg []byte
+		var err error
+		if s.Framing == "octet-counting" {
+			msg, err = readOctetCounted(r)
+		} else {
+			msg, err = readNonTransparent(r)
+		}
+		if len(msg) > 0 {
+			s.handleMessage(msg, conn.RemoteAddr())
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			select {
+			case <-s.done:
+			default:
+				s.Log.Errorf("Reading from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readOctetCounted reads a message prefixed by its length and a space
+func readOctetCounted(r *bufio.Reader) ([]byte, error) {
+	prefix, err := r.ReadString(' ')
+	if err != nil {
+		if err == io.EOF && prefix != "" {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(prefix[:len(prefix)-1])
+	if err != nil || length < 1 || length > maxMessageLength {
+		return nil, fmt.Errorf("invalid message length %q", prefix)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// readNonTransparent reads a message ended by a newline, or by the end of
+// the connection
+func readNonTransparent(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		line, isPrefix, err := r.ReadLine()
+		msg = append(msg, line...)
+		if len(msg) > maxMessageLength {
+			return nil, fmt.Errorf("message longer than %d bytes",
+				maxMessageLength)
+		}
+		if err != nil || !isPrefix {
+			return msg, err
+		}
+	}
+}
+
+// handleMessage parses the message received from the address, and queues its
+// metric for the next Gather
+func (s *Syslog) handleMessage(b []byte, addr net.Addr) {
+	msg, err := parseMessage(b, s.BestEffort)
+	if err != nil {
+		s.Log.Errorf("Could not parse message from %s: %q, error: %s", addr,
+			b, err)
+		return
+	}
+
+	tags := map[string]string{
+		"severity": severityNames[msg.severity],
+		"facility": facilityNames[msg.facility],
+	}
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		tags["source"] = host
+	}
+	if msg.hostname != "" {
+		tags["hostname"] = msg.hostname
+	}
+	if msg.appname != "" {
+		tags["appname"] = msg.appname
+	}
+
+	fields := map[string]interface{}{
+		"version":       msg.version,
+		"severity_code": msg.severity,
+		"facility_code": msg.facility,
+	}
+	if !msg.timestamp.IsZero() {
+		fields["timestamp"] = msg.timestamp.UnixNano()
+	}
+	if msg.procid != "" {
+		fields["procid"] = msg.procid
+	}
+	if msg.msgid != "" {
+		fields["msgid"] = msg.msgid
+	}
+	if msg.message != "" {
+		fields["message"] = msg.message
+	}
+	for id, params := range msg.structuredData {
+		if len(params) == 0 {
+			fields[id] = true
+		}
+		for name, value := range params {
+			fields[id+s.SDParamSeparator+name] = value
+		}
+	}
+
+	m, err := telegraf.NewMetric("syslog", tags, fields, time.Now())
+	if err != nil {
+		s.Log.Errorf("Could not create metric: %s", err)
+		return
+	}
+	select {
+	case s.metricC <- m:
+	default:
+		s.Log.Warn("Buffer is full, dropping a metric." +
+			" You may want to increase the metric_buffer setting")
+	}
+}
+
+func (s *Syslog) Stop() {
+	close(s.done)
+	if s.udpConn != nil {
+		s.udpConn.Close()
+	}
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+		s.connsLock.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.connsLock.Unlock()
+	}
+	s.wg.Wait()
+	s.Log.Info("Stopped the syslog service")
+}
+
+func (s *Syslog) Gather(acc telegraf.Accumulator) error {
+	s.Lock()
+	defer s.Unlock()
+	nmetrics := len(s.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-s.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("syslog", func() telegraf.Input {
+		return &Syslog{
+			Server:  "tcp://:6514",
+			Framing: "octet-counting",
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package syslog
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rfc5424 = `<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 [exampleSDID@32473 iut="3"][origin] An application event`
+
+// waitMetrics gathers the plugin until it received n metrics
+func waitMetrics(t *testing.T, s *Syslog, n int) *testutil.Accumulator {
+	var acc testutil.Accumulator
+	for i := 0; i < 200 && len(acc.Metrics) < n; i++ {
+		require.NoError(t, s.Gather(&acc))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Metrics, n)
+	return &acc
+}
+
+func TestSyslogUDP(t *testing.T) {
+	s := &Syslog{Server: "udp://127.0.0.1:0", Log: testutil.Logger{}}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(rfc5424))
+	require.NoError(t, err)
+
+	acc := waitMetrics(t, s, 1)
+	acc.AssertContainsTaggedFields(t, "syslog", map[string]interface{}{
+		"version":               int64(1),
+		"severity_code":         int64(5),
+		"facility_code":         int64(20),
+		"timestamp":             int64(1065910455003000000),
+		"msgid":                 "ID47",
+		"message":               "An application event",
+		"exampleSDID@32473_iut": "3",
+		"origin":                true,
+	}, map[string]string{
+		"severity": "notice",
+		"facility": "local4",
+		"hostname": "mymachine",
+		"appname":  "evntslog",
+		"source":   "127.0.0.1",
+	})
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	for framing, payload := range map[string]string{
+		"octet-counting": fmt.Sprintf("%d %s%d %s", len(rfc5424), rfc5424,
+			len("<13>1 - - - - - - second"), "<13>1 - - - - - - second"),
+		"non-transparent": rfc5424 + "\n<13>1 - - - - - - second\n",
+	} {
+		s := &Syslog{
+			Server:  "tcp://127.0.0.1:0",
+			Framing: framing,
+			Log:     testutil.Logger{},
+		}
+		require.NoError(t, s.Start())
+
+		conn, err := net.Dial("tcp", s.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte(payload))
+		require.NoError(t, err)
+		conn.Close()
+
+		acc := waitMetrics(t, s, 2)
+		assert.Equal(t, "An application event", acc.Metrics[0].Fields["message"],
+			framing)
+		assert.Equal(t, "second", acc.Metrics[1].Fields["message"], framing)
+		s.Stop()
+	}
+}
+
+// writeCert writes a self-signed certificate and its key to dir, returning
+// their paths
+func writeCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "telegraf"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		0600))
+	return certFile, keyFile
+}
+
+func TestSyslogTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCert(t, dir)
+
+	s := &Syslog{
+		Server:  "tcp://127.0.0.1:0",
+		TLSCert: certFile,
+		TLSKey:  keyFile,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := tls.Dial("tcp", s.Addr().String(),
+		&tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(conn, "%d %s", len(rfc5424), rfc5424)
+	require.NoError(t, err)
+	conn.Close()
+
+	acc := waitMetrics(t, s, 1)
+	assert.Equal(t, "ID47", acc.Metrics[0].Fields["msgid"])
+}
+
+func TestSyslogInvalidConfig(t *testing.T) {
+	for _, s := range []*Syslog{
+		{Server: "http://:6514"},
+		{Server: "tcp://:6514", Framing: "netstring"},
+		{Server: "udp://:6514", TLSCert: "cert.pem", TLSKey: "key.pem"},
+	} {
+		s.Log = testutil.Logger{}
+		assert.Error(t, s.Start(), s.Server)
+	}
+}
@@ -10,8 +10,7 @@ import (
 type DiskStats struct {
 	ps PS
 
-	// Legacy support
-	Mountpoints []string
+	Mountpoints []string `deprecated:"0.10.0;;use 'mount_points' instead" migrate:"MountPoints"`
 
 	MountPoints []string
 }
@@ -31,11 +30,6 @@ func (_ *DiskStats) SampleConfig() string {
 }
 
 func (s *DiskStats) Gather(acc telegraf.Accumulator) error {
-	// Legacy support:
-	if len(s.Mountpoints) != 0 {
-		s.MountPoints = s.Mountpoints
-	}
-
 	disks, err := s.ps.DiskUsage(s.MountPoints)
 	if err != nil {
 		return fmt.Errorf("error getting disk usage info: %s", err)
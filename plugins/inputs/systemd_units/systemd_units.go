@@ -0,0 +1,236 @@
+package systemd_units
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs systemctl with the arguments, killing it after the timeout,
+// and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// SystemdUnits reads the states of the systemd units with systemctl
+type SystemdUnits struct {
+	Path     string
+	UnitType string `toml:"unittype"`
+	Pattern  []string
+	Timeout  internal.Duration
+
+	runner Runner
+}
+
+var sampleConfig = `
+  # Path of systemctl, looked up in the PATH by default
+  # path = "/bin/systemctl"
+
+  # Type of the units, ie service, socket, mount or timer
+  unittype = "service"
+
+  # Names of the units gathered, with * globs, ie "nginx.*" or "*@*". All
+  # the units of the type are gathered if empty.
+  # pattern = ["sshd.service", "nginx.*"]
+
+  # Timeout of systemctl
+  # timeout = "1s"
+`
+
+func (s *SystemdUnits) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SystemdUnits) Description() string {
+	return "Gather the load, active and sub states of the systemd units"
+}
+
+// The codes of the states, the load and active states of the units of
+// systemd, and the sub states of their types, numbered by type from the
+// lists of systemd's unit-def.c
+var loadCodes = map[string]int{
+	"loaded":      0,
+	"stub":        1,
+	"not-found":   2,
+	"bad-setting": 3,
+	"error":       4,
+	"merged":      5,
+	"masked":      6,
+}
+
+var activeCodes = map[string]int{
+	"active":       0,
+	"reloading":    1,
+	"inactive":     2,
+	"failed":       3,
+	"activating":   4,
+	"deactivating": 5,
+}
+
+var subCodes = map[string]int{
+	// service
+	"running":       0x0000,
+	"dead":          0x0001,
+	"start-pre":     0x0002,
+	"start":         0x0003,
+	"exited":        0x0004,
+	"reload":        0x0005,
+	"stop":          0x0006,
+	"stop-watchdog": 0x0007,
+	"stop-sigterm":  0x0008,
+	"stop-sigkill":  0x0009,
+	"stop-post":     0x000a,
+	"final-sigterm": 0x000b,
+	"failed":        0x000c,
+	"auto-restart":  0x000d,
+	// automount
+	"waiting": 0x0010,
+	// device
+	"tentative": 0x0020,
+	"plugged":   0x0021,
+	// mount
+	"mounting":           0x0030,
+	"mounting-done":      0x0031,
+	"mounted":            0x0032,
+	"remounting":         0x0033,
+	"unmounting":         0x0034,
+	"remounting-sigterm": 0x0035,
+	"remounting-sigkill": 0x0036,
+	"unmounting-sigterm": 0x0037,
+	"unmounting-sigkill": 0x0038,
+	// scope
+	"abandoned": 0x0050,
+	// slice
+	"active": 0x0060,
+	// socket
+	"start-chown":      0x0070,
+	"start-post":       0x0071,
+	"listening":        0x0072,
+	"stop-pre":         0x0073,
+	"stop-pre-sigterm": 0x0074,
+	"stop-pre-sigkill": 0x0075,
+	"final-sigkill":    0x0076,
+	// swap
+	"activating":           0x0080,
+	"activating-done":      0x0081,
+	"deactivating":         0x0082,
+	"deactivating-sigterm": 0x0083,
+	"deactivating-sigkill": 0x0084,
+	// timer
+	"elapsed": 0x00a0,
+}
+
+func (s *SystemdUnits) Gather(acc telegraf.Accumulator) error {
+	if s.Path == "" {
+		path, err := exec.LookPath("systemctl")
+		if err != nil {
+			return fmt.Errorf("systemctl not found: %s", err)
+		}
+		s.Path = path
+	}
+	if s.runner == nil {
+		s.runner = runSystemctl
+	}
+	if s.UnitType == "" {
+		s.UnitType = "service"
+	}
+	if s.Timeout.Duration == 0 {
+		s.Timeout.Duration = time.Second
+	}
+
+	out, err := s.runner(s.Timeout.Duration, s.Path, "list-units", "--all",
+		"--plain", "--no-legend", "--type="+s.UnitType)
+	if err != nil {
+		return fmt.Errorf("listing the units: %s: %s", err,
+			strings.TrimSpace(out))
+	}
+
+	// The units are listed as
+	//
+	//	sshd.service    loaded active running OpenSSH Daemon
+	//
+	// the failed units being prefixed by a bullet with the older systemctl
+	// ignoring --plain
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Fields(strings.TrimPrefix(
+			strings.TrimSpace(scanner.Text()), "●"))
+		if len(cols) < 4 || !s.selected(cols[0]) {
+			continue
+		}
+		tags := map[string]string{
+			"name":   cols[0],
+			"load":   cols[1],
+			"active": cols[2],
+			"sub":    cols[3],
+		}
+		// The states unknown to this version have no codes
+		fields := make(map[string]interface{})
+		if code, ok := loadCodes[cols[1]]; ok {
+			fields["load_code"] = code
+		}
+		if code, ok := activeCodes[cols[2]]; ok {
+			fields["active_code"] = code
+		}
+		if code, ok := subCodes[cols[3]]; ok {
+			fields["sub_code"] = code
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("systemd_units", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+// selected returns whether the unit matches one of the patterns
+func (s *SystemdUnits) selected(name string) bool {
+	if len(s.Pattern) == 0 {
+		return true
+	}
+	for _, glob := range s.Pattern {
+		if internal.Glob(glob, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runSystemctl runs systemctl, killing it after the timeout
+func runSystemctl(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("systemctl timed out after %s",
+			timeout)
+	}
+}
+
+func init() {
+	inputs.Add("systemd_units", func() telegraf.Input {
+		return &SystemdUnits{
+			UnitType: "service",
+			Timeout:  internal.Duration{Duration: time.Second},
+			runner:   runSystemctl,
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package systemd_units
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const listUnitsOutput = `sshd.service                loaded    active   running OpenSSH Daemon
+● nginx.service             loaded    failed   failed  A high performance web server
+getty@tty1.service          loaded    active   running Getty on tty1
+plymouth-start.service      not-found inactive dead    plymouth-start.service
+`
+
+func TestGather(t *testing.T) {
+	var calls []string
+	s := &SystemdUnits{
+		Path: "systemctl",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return listUnitsOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	assert.Equal(t, []string{
+		"systemctl list-units --all --plain --no-legend --type=service",
+	}, calls)
+	assert.Equal(t, 4, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "systemd_units", map[string]interface{}{
+		"load_code":   0,
+		"active_code": 0,
+		"sub_code":    0,
+	}, map[string]string{
+		"name":   "sshd.service",
+		"load":   "loaded",
+		"active": "active",
+		"sub":    "running",
+	})
+	acc.AssertContainsTaggedFields(t, "systemd_units", map[string]interface{}{
+		"load_code":   0,
+		"active_code": 3,
+		"sub_code":    0x000c,
+	}, map[string]string{
+		"name":   "nginx.service",
+		"load":   "loaded",
+		"active": "failed",
+		"sub":    "failed",
+	})
+	acc.AssertContainsTaggedFields(t, "systemd_units", map[string]interface{}{
+		"load_code":   2,
+		"active_code": 2,
+		"sub_code":    1,
+	}, map[string]string{
+		"name":   "plymouth-start.service",
+		"load":   "not-found",
+		"active": "inactive",
+		"sub":    "dead",
+	})
+}
+
+func TestGatherPattern(t *testing.T) {
+	var calls []string
+	s := &SystemdUnits{
+		Path:     "systemctl",
+		UnitType: "socket",
+		Pattern:  []string{"nginx.*", "getty@*"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return listUnitsOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+
+	assert.Equal(t, []string{
+		"systemctl list-units --all --plain --no-legend --type=socket",
+	}, calls)
+	assert.Equal(t, 2, len(acc.Metrics))
+	for _, m := range acc.Metrics {
+		assert.Contains(t, []string{"nginx.service", "getty@tty1.service"},
+			m.Tags["name"])
+	}
+}
+
+func TestGatherError(t *testing.T) {
+	s := &SystemdUnits{
+		Path: "systemctl",
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			return "System has not been booted with systemd",
+				errors.New("exit status 1")
+		},
+	}
+	var acc testutil.Accumulator
+	err := s.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not been booted")
+}
@@ -0,0 +1,384 @@
+package tail
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/globpath"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Tail follows files, like tail -F, parsing every line appended to them
+type Tail struct {
+	Files         []string
+	FromBeginning bool              `toml:"from_beginning"`
+	PollInterval  internal.Duration `toml:"poll_interval"`
+	MetricBuffer  int               `toml:"metric_buffer"`
+
+	// Options of the parser, see parsers.Config
+	DataFormat             string   `toml:"data_format"`
+	TagKeys                []string `toml:"tag_keys"`
+	CSVColumnNames         []string `toml:"csv_column_names"`
+	CSVDelimiter           string   `toml:"csv_delimiter"`
+	XMLPath                string   `toml:"xml_path"`
+	GrokPatterns           []string `toml:"grok_patterns"`
+	GrokCustomPatterns     string   `toml:"grok_custom_patterns"`
+	GrokCustomPatternFiles []string `toml:"grok_custom_pattern_files"`
+	GrokTimezone           string   `toml:"grok_timezone"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	globs   []*globpath.GlobPath
+	parser  telegraf.Parser
+	tailers map[string]*tailer
+	// offsets are the offsets of the lines read of the files, restored by
+	// SetState to resume reading where telegraf stopped
+	offsets map[string]int64
+	metricC chan telegraf.Metric
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Files to tail, with glob patterns:
+  #   /var/log/**.log     -> recursively find all .log files in /var/log
+  #   /var/log/*/*.log    -> find all .log files with a parent dir in /var/log
+  #   /var/log/apache.log -> only the apache log file
+  files = ["/var/log/apache/access.log"]
+
+  # Read the files from their beginning, rather than from their end, when
+  # telegraf starts. The files are read from the offset reached before the
+  # last stop when the agent has a statefile.
+  from_beginning = false
+
+  # Interval of the checks for new lines, new files, rotations and
+  # truncations
+  poll_interval = "250ms"
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 100000
+
+  # Data format of the lines: influx, json, logfmt, grok, csv or xml
+  data_format = "grok"
+  # Patterns of the lines of the grok data format
+  grok_patterns = ["%{COMBINED_LOG_FORMAT}"]
+  # Definitions of patterns used by the patterns, "NAME regexp" per line
+  # grok_custom_patterns = '''
+  # '''
+  # Files of definitions of patterns
+  # grok_custom_pattern_files = []
+  # Location of the timestamps without a timezone, UTC by default, or "Local"
+  # grok_timezone = "Local"
+  # Keys tagging the metrics with their value instead of being fields
+  # tag_keys = []
+`
+
+func (t *Tail) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Tail) Description() string {
+	return "Stream a log file, like the tail -f command"
+}
+
+// GetState returns the offsets of the lines read of the files
+func (t *Tail) GetState() interface{} {
+	t.Lock()
+	defer t.Unlock()
+	offsets := make(map[string]int64, len(t.offsets))
+	for path, offset := range t.offsets {
+		offsets[path] = offset
+	}
+	return offsets
+}
+
+// SetState restores the offsets of the lines read of the files
+func (t *Tail) SetState(state interface{}) error {
+	offsets, ok := state.(map[string]int64)
+	if !ok {
+		return fmt.Errorf("invalid state of type %T", state)
+	}
+	t.Lock()
+	defer t.Unlock()
+	t.offsets = offsets
+	return nil
+}
+
+func (t *Tail) Start() error {
+	t.Lock()
+	defer t.Unlock()
+
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:             t.DataFormat,
+		MetricName:             "tail",
+		TagKeys:                t.TagKeys,
+		CSVColumnNames:         t.CSVColumnNames,
+		CSVDelimiter:           t.CSVDelimiter,
+		XMLPath:                t.XMLPath,
+		GrokPatterns:           t.GrokPatterns,
+		GrokCustomPatterns:     t.GrokCustomPatterns,
+		GrokCustomPatternFiles: t.GrokCustomPatternFiles,
+		GrokTimezone:           t.GrokTimezone,
+	})
+	if err != nil {
+		return err
+	}
+	t.parser = parser
+
+	t.globs = nil
+	for _, file := range t.Files {
+		g, err := globpath.Compile(file)
+		if err != nil {
+			return fmt.Errorf("invalid file %q: %s", file, err)
+		}
+		t.globs = append(t.globs, g)
+	}
+
+	if t.PollInterval.Duration <= 0 {
+		t.PollInterval.Duration = 250 * time.Millisecond
+	}
+	if t.MetricBuffer == 0 {
+		t.MetricBuffer = 100000
+	}
+	if t.offsets == nil {
+		t.offsets = make(map[string]int64)
+	}
+	t.tailers = make(map[string]*tailer)
+	t.metricC = make(chan telegraf.Metric, t.MetricBuffer)
+	t.done = make(chan struct{})
+
+	// The files existing when telegraf starts are read from their end, the
+	// ones created later from their beginning
+	t.poll(t.FromBeginning)
+	t.wg.Add(1)
+	go t.run()
+	return nil
+}
+
+// run polls the files until the plugin is stopped
+func (t *Tail) run() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.PollInterval.Duration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.Lock()
+			t.poll(true)
+			t.Unlock()
+		}
+	}
+}
+
+// poll starts tailing the new files matched, and reads the new lines of the
+// files tailed. The new files are read from their beginning, or from their
+// end if fromBeginning is false, unless their offset is known.
+func (t *Tail) poll(fromBeginning bool) {
+	for _, g := range t.globs {
+		for _, path := range g.Match() {
+			if _, ok := t.tailers[path]; ok {
+				continue
+			}
+			if info, err := os.Stat(path); err != nil || info.IsDir() {
+				continue
+			}
+			offset, ok := t.offsets[path]
+			if !ok && !fromBeginning {
+				offset = -1
+			}
+			t.tailers[path] = &tailer{path: path, offset: offset}
+		}
+	}
+
+	for path, tail := range t.tailers {
+		err := tail.read(func(line string) { t.parseLine(path, line) })
+		if os.IsNotExist(err) {
+			// The file was removed and not recreated, yet
+			tail.close()
+			delete(t.tailers, path)
+			delete(t.offsets, path)
+			continue
+		}
+		if err != nil {
+			t.Log.Errorf("Could not tail %s: %s", path, err)
+			continue
+		}
+		t.offsets[path] = tail.offset
+	}
+}
+
+// parseLine parses the line read from the file of the path
+func (t *Tail) parseLine(path string, line string) {
+	metrics, err := t.parser.Parse([]byte(line))
+	if err != nil {
+		t.Log.Errorf("Could not parse line of %s: %q, error: %s", path, line,
+			err)
+	}
+	for _, metric := range metrics {
+		tags := metric.Tags()
+		tags["path"] = path
+		m, err := telegraf.NewTypedMetric(metric.Type(), metric.Name(), tags,
+			metric.Fields(), metric.Time())
+		if err != nil {
+			t.Log.Errorf("Could not tag metric: %s", err)
+			continue
+		}
+		select {
+		case t.metricC <- m:
+		default:
+			t.Log.Warn("Buffer is full, dropping a metric." +
+				" You may want to increase the metric_buffer setting")
+		}
+	}
+}
+
+func (t *Tail) Stop() {
+	close(t.done)
+	t.wg.Wait()
+
+	t.Lock()
+	defer t.Unlock()
+	for _, tail := range t.tailers {
+		tail.close()
+	}
+}
+
+func (t *Tail) Gather(acc telegraf.Accumulator) error {
+	t.Lock()
+	defer t.Unlock()
+	nmetrics := len(t.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-t.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+// tailer reads the lines appended to a file, following it when it is
+// rotated, ie renamed and replaced by a new file, or truncated
+type tailer struct {
+	path string
+	// offset is the offset of the end of the last line read, -1 to start at
+	// the end of the file
+	offset int64
+
+	file   *os.File
+	reader *bufio.Reader
+	// partial is the last line read, without its newline yet
+	partial string
+}
+
+// read calls fn with the complete lines appended to the file since the last
+// read
+func (t *tailer) read(fn func(line string)) error {
+	if t.file == nil {
+		if err := t.open(); err != nil {
+			return err
+		}
+	}
+	if err := t.readLines(fn); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return err
+	}
+	current, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+	switch {
+	case !os.SameFile(info, current):
+		// The file was rotated, the lines of the old file being read
+		// already. A line without its newline is lost.
+		t.close()
+		t.offset = 0
+		if err := t.open(); err != nil {
+			return err
+		}
+		return t.readLines(fn)
+	case info.Size() < t.offset+int64(len(t.partial)):
+		// The file was truncated
+		t.offset = 0
+		t.partial = ""
+		if _, err := t.file.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		t.reader.Reset(t.file)
+		return t.readLines(fn)
+	}
+	return nil
+}
+
+// open opens the file at the offset, or at its beginning if the file is
+// smaller, ie replaced since the offset was stored
+func (t *tailer) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if t.offset < 0 {
+		t.offset = info.Size()
+	} else if t.offset > info.Size() {
+		t.offset = 0
+	}
+	if _, err := file.Seek(t.offset, os.SEEK_SET); err != nil {
+		file.Close()
+		return err
+	}
+	t.file = file
+	t.reader = bufio.NewReader(file)
+	t.partial = ""
+	return nil
+}
+
+// readLines reads the file until its end, calling fn with the lines
+func (t *tailer) readLines(fn func(line string)) error {
+	for {
+		s, err := t.reader.ReadString('\n')
+		t.partial += s
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line := t.partial
+		t.offset += int64(len(line))
+		t.partial = ""
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			fn(line)
+		}
+	}
+}
+
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+func init() {
+	inputs.Add("tail", func() telegraf.Input {
+		return &Tail{}
+	})
+}
@@ -0,0 +1,159 @@
+package tail
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/config"
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendLines appends the lines to the file
+func appendLines(t *testing.T, path string, lines ...string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	for _, line := range lines {
+		_, err := fmt.Fprintln(f, line)
+		require.NoError(t, err)
+	}
+}
+
+// newTail returns a started tail of the files of dir, in logfmt
+func newTail(t *testing.T, dir string, fromBeginning bool) *Tail {
+	return &Tail{
+		Files:         []string{filepath.Join(dir, "*.log")},
+		FromBeginning: fromBeginning,
+		PollInterval:  internal.Duration{Duration: 10 * time.Millisecond},
+		DataFormat:    "logfmt",
+		Log:           testutil.Logger{},
+	}
+}
+
+// waitValues gathers the tail until it read n metrics, and returns the value
+// fields of the metrics of the path
+func waitValues(t *testing.T, tail *Tail, n int, path string) []interface{} {
+	var acc testutil.Accumulator
+	for i := 0; i < 200 && len(acc.Metrics) < n; i++ {
+		require.NoError(t, tail.Gather(&acc))
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, acc.Metrics, n)
+	var values []interface{}
+	for _, m := range acc.Metrics {
+		if m.Tags["path"] == path {
+			values = append(values, m.Fields["value"])
+		}
+	}
+	return values
+}
+
+func TestTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendLines(t, path, "value=1")
+
+	tail := newTail(t, dir, false)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+
+	// The lines existing before the start are skipped, the partial lines
+	// are read once complete
+	appendLines(t, path, "value=2", "not logfmt=\"")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	fmt.Fprint(f, "val")
+	f.Close()
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, path, "ue=3")
+	assert.Equal(t, []interface{}{int64(2), int64(3)},
+		waitValues(t, tail, 2, path))
+
+	// The new files are read from their beginning
+	other := filepath.Join(dir, "other.log")
+	appendLines(t, other, "value=4")
+	assert.Equal(t, []interface{}{int64(4)}, waitValues(t, tail, 1, other))
+}
+
+func TestTailRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendLines(t, path, "value=1")
+
+	tail := newTail(t, dir, true)
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+	assert.Equal(t, []interface{}{int64(1)}, waitValues(t, tail, 1, path))
+
+	// Rotated by renaming, the lines written to the old file before the new
+	// file is created are read
+	appendLines(t, path, "value=2")
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "app.log.1")))
+	appendLines(t, path, "value=3")
+	assert.Equal(t, []interface{}{int64(2), int64(3)},
+		waitValues(t, tail, 2, path))
+
+	// Truncated, and shorter than the offset reached
+	appendLines(t, path, "value=4")
+	assert.Equal(t, []interface{}{int64(4)}, waitValues(t, tail, 1, path))
+	require.NoError(t, os.Truncate(path, 0))
+	appendLines(t, path, "value=5")
+	assert.Equal(t, []interface{}{int64(5)}, waitValues(t, tail, 1, path))
+}
+
+func TestTailState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	appendLines(t, path, "value=1")
+
+	tail := newTail(t, dir, true)
+	require.NoError(t, tail.Start())
+	assert.Equal(t, []interface{}{int64(1)}, waitValues(t, tail, 1, path))
+	tail.Stop()
+	state := tail.GetState()
+	assert.Equal(t, map[string]int64{path: 8}, state)
+
+	// Restarted, the file is read from the offset reached before the stop
+	appendLines(t, path, "value=2")
+	tail = newTail(t, dir, true)
+	require.NoError(t, tail.SetState(state))
+	require.NoError(t, tail.Start())
+	defer tail.Stop()
+	assert.Equal(t, []interface{}{int64(2)}, waitValues(t, tail, 1, path))
+
+	assert.Error(t, tail.SetState("offsets"))
+}
+
+func TestSampleConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tail")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "telegraf.conf"))
+	require.NoError(t, err)
+	config.PrintFilteredSampleConfig(f, config.SampleConfigFilters{
+		Sections: []string{"inputs"},
+		Inputs:   []string{"tail"},
+	})
+	require.NoError(t, f.Close())
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig(f.Name()))
+	require.Len(t, c.Inputs, 1)
+	tail := c.Inputs[0].Input.(*Tail)
+	assert.Equal(t, []string{"%{COMBINED_LOG_FORMAT}"}, tail.GrokPatterns)
+}
@@ -0,0 +1,197 @@
+package varnish
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Runner runs varnishstat with the arguments, killing it after the timeout,
+// and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
+// Varnish reads the counters of the varnish instances with varnishstat
+type Varnish struct {
+	Binary        string
+	UseSudo       bool `toml:"use_sudo"`
+	Stats         []string
+	InstanceNames []string `toml:"instance_names"`
+	Timeout       internal.Duration
+
+	runner Runner
+}
+
+var defaultStats = []string{"MAIN.cache_hit", "MAIN.cache_miss", "MAIN.uptime"}
+
+var sampleConfig = `
+  # Path of varnishstat
+  binary = "/usr/bin/varnishstat"
+
+  # Run varnishstat with sudo, which must be allowed without password
+  # use_sudo = false
+
+  # Counters gathered, as printed by varnishstat -1, with * globs, ie
+  # "MAIN.*" or "VBE.*.req". The cache hits and misses and the uptime are
+  # gathered by default.
+  stats = ["MAIN.cache_hit", "MAIN.cache_miss", "MAIN.uptime"]
+
+  # Names of the varnish instances, their -n option, the default instance
+  # being read if empty
+  # instance_names = ["frontend"]
+
+  # Timeout of varnishstat
+  # timeout = "1s"
+`
+
+func (v *Varnish) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Varnish) Description() string {
+	return "Gather the counters of varnish with varnishstat"
+}
+
+func (v *Varnish) Gather(acc telegraf.Accumulator) error {
+	if v.runner == nil {
+		v.runner = runVarnishstat
+	}
+	if v.Binary == "" {
+		v.Binary = "/usr/bin/varnishstat"
+	}
+	if v.Timeout.Duration == 0 {
+		v.Timeout.Duration = time.Second
+	}
+
+	if len(v.InstanceNames) == 0 {
+		return v.gatherInstance("", acc)
+	}
+	var errorStrings []string
+	for _, instance := range v.InstanceNames {
+		if err := v.gatherInstance(instance, acc); err != nil {
+			errorStrings = append(errorStrings, err.Error())
+		}
+	}
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// gatherInstance adds the counters of the instance, the default instance if
+// empty, a point per section of counters, ie MAIN or VBE. The counters of
+// varnishstat -1 are listed as
+//
+//	MAIN.cache_hit            1209872        12.34 Cache hits
+func (v *Varnish) gatherInstance(instance string, acc telegraf.Accumulator) error {
+	path, args := v.Binary, []string{"-1"}
+	if instance != "" {
+		args = append(args, "-n", instance)
+	}
+	if v.UseSudo {
+		path, args = "sudo", append([]string{"-n", v.Binary}, args...)
+	}
+	out, err := v.runner(v.Timeout.Duration, path, args...)
+	if err != nil {
+		if instance != "" {
+			return fmt.Errorf("reading the counters of %s: %s: %s", instance,
+				err, strings.TrimSpace(out))
+		}
+		return fmt.Errorf("reading the counters: %s: %s", err,
+			strings.TrimSpace(out))
+	}
+
+	stats := v.Stats
+	if len(stats) == 0 {
+		stats = defaultStats
+	}
+
+	sections := make(map[string]map[string]interface{})
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Fields(scanner.Text())
+		if len(cols) < 2 || !selected(stats, cols[0]) {
+			continue
+		}
+		// The counters overflowing an int64, ie the unset gauges of 2^64-1,
+		// are skipped
+		value, err := strconv.ParseInt(cols[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		// The section is the prefix of the counter, the field its name
+		parts := strings.SplitN(cols[0], ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if sections[parts[0]] == nil {
+			sections[parts[0]] = make(map[string]interface{})
+		}
+		sections[parts[0]][parts[1]] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for section, fields := range sections {
+		tags := map[string]string{"section": section}
+		if instance != "" {
+			tags["instance"] = instance
+		}
+		acc.AddFields("varnish", fields, tags, now)
+	}
+	return nil
+}
+
+// selected returns whether the counter matches one of the stats globs
+func selected(stats []string, counter string) bool {
+	for _, glob := range stats {
+		if internal.Glob(glob, counter) {
+			return true
+		}
+	}
+	return false
+}
+
+// runVarnishstat runs varnishstat, killing it after the timeout
+func runVarnishstat(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("varnishstat timed out after %s",
+			timeout)
+	}
+}
+
+func init() {
+	inputs.Add("varnish", func() telegraf.Input {
+		return &Varnish{
+			Binary:  "/usr/bin/varnishstat",
+			Stats:   defaultStats,
+			Timeout: internal.Duration{Duration: time.Second},
+			runner:  runVarnishstat,
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package varnish
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const varnishstatOutput = `MGT.uptime                 326570         1.00 Management process uptime
+MGT.child_start                 1         0.00 Child process started
+MAIN.uptime                326571         1.00 Child process uptime
+MAIN.sess_conn            4286155        13.12 Sessions accepted
+MAIN.cache_hit           11807221        36.15 Cache hits
+MAIN.cache_miss           2004873         6.14 Cache misses
+MAIN.backend_conn          172846         0.53 Backend conn. success
+MAIN.backend_fail              12         0.00 Backend conn. failures
+MAIN.threads                  200          .   Total number of threads
+MAIN.n_lru_limited 18446744073709551615    .   Reached nuke_limit
+VBE.boot.default.req      1915236         5.86 Backend requests sent
+`
+
+func TestGather(t *testing.T) {
+	var calls []string
+	v := &Varnish{
+		Binary: "varnishstat",
+		Stats:  []string{"MAIN.cache_*", "MAIN.backend_*", "MAIN.threads", "MAIN.n_lru_limited", "VBE.*.req"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			return varnishstatOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, v.Gather(&acc))
+
+	assert.Equal(t, []string{"varnishstat -1"}, calls)
+	acc.AssertContainsTaggedFields(t, "varnish", map[string]interface{}{
+		"cache_hit":    int64(11807221),
+		"cache_miss":   int64(2004873),
+		"backend_conn": int64(172846),
+		"backend_fail": int64(12),
+		"threads":      int64(200),
+	}, map[string]string{"section": "MAIN"})
+	acc.AssertContainsTaggedFields(t, "varnish", map[string]interface{}{
+		"boot.default.req": int64(1915236),
+	}, map[string]string{"section": "VBE"})
+	assert.Equal(t, 2, len(acc.Metrics))
+}
+
+func TestGatherInstances(t *testing.T) {
+	var calls []string
+	v := &Varnish{
+		Binary:        "/usr/bin/varnishstat",
+		UseSudo:       true,
+		InstanceNames: []string{"frontend", "backend"},
+		runner: func(timeout time.Duration, path string, args ...string) (string, error) {
+			calls = append(calls, path+" "+strings.Join(args, " "))
+			if args[len(args)-1] == "backend" {
+				return "Could not open shared memory", errors.New("exit status 1")
+			}
+			return varnishstatOutput, nil
+		},
+	}
+	var acc testutil.Accumulator
+	err := v.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backend")
+
+	assert.Equal(t, []string{
+		"sudo -n /usr/bin/varnishstat -1 -n frontend",
+		"sudo -n /usr/bin/varnishstat -1 -n backend",
+	}, calls)
+	// The default stats are gathered
+	acc.AssertContainsTaggedFields(t, "varnish", map[string]interface{}{
+		"uptime":     int64(326571),
+		"cache_hit":  int64(11807221),
+		"cache_miss": int64(2004873),
+	}, map[string]string{"section": "MAIN", "instance": "frontend"})
+	assert.Equal(t, 1, len(acc.Metrics))
+}
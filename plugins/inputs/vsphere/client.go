@@ -0,0 +1,444 @@
+package vsphere
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// client is a client of the vSphere Web Services SOAP API (vim25) of a
+// vCenter, making the few requests of the plugin: the login, the inventory
+// retrieved with the property collector, and the performance manager queries
+type client struct {
+	url      string
+	username string
+	password string
+	http     *http.Client
+
+	mu       sync.Mutex
+	content  serviceContent
+	loggedIn bool
+}
+
+// moref is a managed object reference, ie <obj type="HostSystem">host-10</obj>
+type moref struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (m moref) String() string {
+	return fmt.Sprintf(`type="%s">%s`, escape(m.Type), escape(m.Value))
+}
+
+// serviceContent are the managed objects of the services of the vCenter
+type serviceContent struct {
+	RootFolder        moref `xml:"rootFolder"`
+	PropertyCollector moref `xml:"propertyCollector"`
+	ViewManager       moref `xml:"viewManager"`
+	SessionManager    moref `xml:"sessionManager"`
+	PerfManager       moref `xml:"perfManager"`
+}
+
+// soapFault is the fault of a failed request, its detail naming the fault,
+// ie NotAuthenticatedFault
+type soapFault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Detail struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"detail"`
+}
+
+func (f *soapFault) Error() string {
+	return f.String
+}
+
+func (f *soapFault) notAuthenticated() bool {
+	return strings.Contains(f.Detail.Inner, "NotAuthenticated")
+}
+
+// escape escapes the text of the XML requests
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func escapef(format string, a ...interface{}) string {
+	for i, v := range a {
+		if s, ok := v.(string); ok {
+			a[i] = escape(s)
+		}
+	}
+	return fmt.Sprintf(format, a...)
+}
+
+// call sends the request, the XML of a vim25 method, and decodes the body of
+// its response into resp
+func (c *client) call(request string, resp interface{}) error {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` +
+		`<soapenv:Envelope ` +
+		`xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" ` +
+		`xmlns:xsd="http://www.w3.org/2001/XMLSchema" ` +
+		`xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<soapenv:Body>`)
+	body.WriteString(request)
+	body.WriteString(`</soapenv:Body></soapenv:Envelope>`)
+
+	req, err := http.NewRequest("POST", c.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", "urn:vim25/6.0")
+	r, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Body struct {
+			Fault *soapFault `xml:"Fault"`
+			Inner []byte     `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	if err := xml.Unmarshal(b, &envelope); err != nil {
+		return fmt.Errorf("%s returned HTTP status %s: %s", c.url, r.Status,
+			err)
+	}
+	if envelope.Body.Fault != nil {
+		return envelope.Body.Fault
+	}
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", c.url, r.Status)
+	}
+	if resp == nil {
+		return nil
+	}
+	return xml.Unmarshal(envelope.Body.Inner, resp)
+}
+
+// login retrieves the service content of the vCenter and logs in, the
+// session cookie being kept by the cookie jar of the HTTP client
+func (c *client) login() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loggedIn {
+		return nil
+	}
+
+	var content struct {
+		Returnval serviceContent `xml:"returnval"`
+	}
+	err := c.call(`<RetrieveServiceContent xmlns="urn:vim25">`+
+		`<_this type="ServiceInstance">ServiceInstance</_this>`+
+		`</RetrieveServiceContent>`, &content)
+	if err != nil {
+		return err
+	}
+	c.content = content.Returnval
+
+	err = c.call(escapef(`<Login xmlns="urn:vim25">`+
+		`<_this type="SessionManager">%s</_this>`+
+		`<userName>%s</userName><password>%s</password></Login>`,
+		c.content.SessionManager.Value, c.username, c.password), nil)
+	if err != nil {
+		return fmt.Errorf("login: %s", err)
+	}
+	c.loggedIn = true
+	return nil
+}
+
+// do calls the method of the request built from the service content, logging
+// in again once if the session has expired
+func (c *client) do(request func(serviceContent) string, resp interface{}) error {
+	if err := c.login(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	content := c.content
+	c.mu.Unlock()
+
+	err := c.call(request(content), resp)
+	if f, ok := err.(*soapFault); ok && f.notAuthenticated() {
+		c.mu.Lock()
+		c.loggedIn = false
+		c.mu.Unlock()
+		if err := c.login(); err != nil {
+			return err
+		}
+		return c.call(request(content), resp)
+	}
+	return err
+}
+
+// property is a property of a managed object, its value being decoded by its
+// user
+type property struct {
+	Name string `xml:"name"`
+	Val  struct {
+		Text  string `xml:",chardata"`
+		Inner string `xml:",innerxml"`
+	} `xml:"val"`
+}
+
+// objectContent are the properties of a managed object
+type objectContent struct {
+	Obj     moref      `xml:"obj"`
+	PropSet []property `xml:"propSet"`
+}
+
+// prop returns the text of the value of the property, ie the name of the
+// object or the value of a reference to another object
+func (o objectContent) prop(name string) string {
+	for _, p := range o.PropSet {
+		if p.Name == name {
+			return p.Val.Text
+		}
+	}
+	return ""
+}
+
+// propXML returns the inner XML of the value of the property, for the
+// values decoded by their user
+func (o objectContent) propXML(name string) string {
+	for _, p := range o.PropSet {
+		if p.Name == name {
+			return p.Val.Inner
+		}
+	}
+	return ""
+}
+
+// retrieveResult is the result of RetrievePropertiesEx, continued with its
+// token
+type retrieveResult struct {
+	Returnval struct {
+		Token   string          `xml:"token"`
+		Objects []objectContent `xml:"objects"`
+	} `xml:"returnval"`
+}
+
+// retrieve returns the properties of the objects, retrieved by batches of
+// maxObjects, the object set being the XML of the objectSet of the spec
+func (c *client) retrieve(
+	typ string,
+	paths []string,
+	objectSet func(serviceContent) string,
+	maxObjects int,
+) ([]objectContent, error) {
+	var pathSet string
+	for _, path := range paths {
+		pathSet += escapef("<pathSet>%s</pathSet>", path)
+	}
+
+	var result retrieveResult
+	err := c.do(func(content serviceContent) string {
+		return escapef(`<RetrievePropertiesEx xmlns="urn:vim25">`+
+			`<_this type="PropertyCollector">%s</_this><specSet>`+
+			`<propSet><type>%s</type>`, content.PropertyCollector.Value, typ) +
+			pathSet + `</propSet>` + objectSet(content) + `</specSet>` +
+			fmt.Sprintf(`<options><maxObjects>%d</maxObjects></options>`+
+				`</RetrievePropertiesEx>`, maxObjects)
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := result.Returnval.Objects
+	for token := result.Returnval.Token; token != ""; {
+		var next retrieveResult
+		err := c.do(func(content serviceContent) string {
+			return escapef(`<ContinueRetrievePropertiesEx xmlns="urn:vim25">`+
+				`<_this type="PropertyCollector">%s</_this>`+
+				`<token>%s</token></ContinueRetrievePropertiesEx>`,
+				content.PropertyCollector.Value, token)
+		}, &next)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, next.Returnval.Objects...)
+		token = next.Returnval.Token
+	}
+	return objects, nil
+}
+
+// inventory returns the properties of the objects of the type in the
+// inventory, ie HostSystem, found with a container view of the root folder
+func (c *client) inventory(
+	typ string,
+	paths []string,
+	maxObjects int,
+) ([]objectContent, error) {
+	var view struct {
+		Returnval moref `xml:"returnval"`
+	}
+	err := c.do(func(content serviceContent) string {
+		return escapef(`<CreateContainerView xmlns="urn:vim25">`+
+			`<_this type="ViewManager">%s</_this>`+
+			`<container type="Folder">%s</container>`+
+			`<type>%s</type><recursive>true</recursive>`+
+			`</CreateContainerView>`, content.ViewManager.Value,
+			content.RootFolder.Value, typ)
+	}, &view)
+	if err != nil {
+		return nil, err
+	}
+	defer c.do(func(serviceContent) string {
+		return `<DestroyView xmlns="urn:vim25"><_this ` +
+			view.Returnval.String() + `</_this></DestroyView>`
+	}, nil)
+
+	return c.retrieve(typ, paths, func(serviceContent) string {
+		return `<objectSet><obj ` + view.Returnval.String() + `</obj>` +
+			`<skip>true</skip><selectSet xsi:type="TraversalSpec">` +
+			`<name>traverseEntities</name><type>ContainerView</type>` +
+			`<path>view</path><skip>false</skip></selectSet></objectSet>`
+	}, maxObjects)
+}
+
+// perfCounter is a counter of the performance manager, named by its group,
+// its name and its rollup, ie cpu.usage.average
+type perfCounter struct {
+	Key       int32  `xml:"key"`
+	Name      string `xml:"nameInfo>key"`
+	Group     string `xml:"groupInfo>key"`
+	Unit      string `xml:"unitInfo>key"`
+	Rollup    string `xml:"rollupType"`
+	StatsType string `xml:"statsType"`
+}
+
+func (p perfCounter) fullName() string {
+	return p.Group + "." + p.Name + "." + p.Rollup
+}
+
+// perfCounters returns the counters of the performance manager
+func (c *client) perfCounters() ([]perfCounter, error) {
+	objects, err := c.retrieve("PerformanceManager", []string{"perfCounter"},
+		func(content serviceContent) string {
+			return `<objectSet><obj ` + content.PerfManager.String() +
+				`</obj><skip>false</skip></objectSet>`
+		}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no performance counters")
+	}
+	var counters struct {
+		Infos []perfCounter `xml:"PerfCounterInfo"`
+	}
+	err = xml.Unmarshal([]byte("<val>"+objects[0].propXML("perfCounter")+
+		"</val>"), &counters)
+	return counters.Infos, err
+}
+
+// metricID is a counter of an entity, the instance being empty for the
+// aggregate of the entity, ie the cpu of a host, or its instance, ie a cpu
+// of the host, "*" querying all the instances
+type metricID struct {
+	CounterID int32  `xml:"counterId"`
+	Instance  string `xml:"instance"`
+}
+
+// availableMetrics returns the counters of the entity for the interval
+func (c *client) availableMetrics(entity moref, interval int) ([]metricID, error) {
+	var resp struct {
+		Returnval []metricID `xml:"returnval"`
+	}
+	err := c.do(func(content serviceContent) string {
+		return escapef(`<QueryAvailablePerfMetric xmlns="urn:vim25">`+
+			`<_this type="PerformanceManager">%s</_this>`,
+			content.PerfManager.Value) +
+			`<entity ` + entity.String() + `</entity>` +
+			fmt.Sprintf(`<intervalId>%d</intervalId>`, interval) +
+			`</QueryAvailablePerfMetric>`
+	}, &resp)
+	return resp.Returnval, err
+}
+
+// querySpec queries the metrics of an entity
+type querySpec struct {
+	entity  moref
+	metrics []metricID
+}
+
+// entityMetrics are the samples of the metrics of an entity in csv, the
+// sample info being the interval and timestamp of the samples, ie
+// "20,2019-03-11T10:42:20Z,20,2019-03-11T10:42:40Z", and the values
+// of a metric the values of the samples, ie "1234,1250"
+type entityMetrics struct {
+	Entity     moref  `xml:"entity"`
+	SampleInfo string `xml:"sampleInfoCSV"`
+	Values     []struct {
+		ID    metricID `xml:"id"`
+		Value string   `xml:"value"`
+	} `xml:"value"`
+}
+
+// queryPerf returns the samples of the metrics of the entities for the
+// interval, the last sample for the real-time interval of 20s, or the
+// samples since start for the historical intervals
+func (c *client) queryPerf(
+	specs []querySpec,
+	interval int,
+	start string,
+) ([]entityMetrics, error) {
+	var resp struct {
+		Returnval []entityMetrics `xml:"returnval"`
+	}
+	err := c.do(func(content serviceContent) string {
+		var b bytes.Buffer
+		b.WriteString(escapef(`<QueryPerf xmlns="urn:vim25">`+
+			`<_this type="PerformanceManager">%s</_this>`,
+			content.PerfManager.Value))
+		for _, spec := range specs {
+			b.WriteString(`<querySpec><entity ` + spec.entity.String() +
+				`</entity>`)
+			if start != "" {
+				b.WriteString(escapef(`<startTime>%s</startTime>`, start))
+			} else {
+				b.WriteString(`<maxSample>1</maxSample>`)
+			}
+			for _, m := range spec.metrics {
+				b.WriteString(escapef(`<metricId><counterId>%d</counterId>`+
+					`<instance>%s</instance></metricId>`, m.CounterID,
+					m.Instance))
+			}
+			fmt.Fprintf(&b, `<intervalId>%d</intervalId>`+
+				`<format>csv</format></querySpec>`, interval)
+		}
+		b.WriteString(`</QueryPerf>`)
+		return b.String()
+	}, &resp)
+	return resp.Returnval, err
+}
+
+// newClient returns a client of the SDK url of the vCenter, ie
+// https://vcenter.local/sdk
+func newClient(u *url.URL, username string, password string, httpClient *http.Client) *client {
+	if u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	sdk := *u
+	sdk.User = nil
+	return &client{
+		url:      sdk.String(),
+		username: username,
+		password: password,
+		http:     httpClient,
+	}
+}
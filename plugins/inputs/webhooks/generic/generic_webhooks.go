@@ -0,0 +1,83 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/json"
+)
+
+// GenericWebhook receives JSON objects, or arrays of objects, parsed as the
+// json data format
+type GenericWebhook struct {
+	Path            string
+	MeasurementName string   `toml:"measurement_name"`
+	TagKeys         []string `toml:"tag_keys"`
+	// Secret checking the hex HMAC-SHA256 of the body in the signature
+	// header, prefixed or not by "sha256="
+	Secret          string
+	SignatureHeader string `toml:"signature_header"`
+
+	parser *json.JSON
+	add    func(telegraf.Metric)
+	log    telegraf.Logger
+}
+
+func (g *GenericWebhook) Register(router *mux.Router,
+	add func(telegraf.Metric), log telegraf.Logger) {
+	if g.Path == "" {
+		g.Path = "/generic"
+	}
+	if g.MeasurementName == "" {
+		g.MeasurementName = "webhooks_generic"
+	}
+	if g.SignatureHeader == "" {
+		g.SignatureHeader = "X-Hub-Signature-256"
+	}
+	g.parser = &json.JSON{MetricName: g.MeasurementName, TagKeys: g.TagKeys}
+	g.add = add
+	g.log = log
+	router.HandleFunc(g.Path, g.eventHandler).Methods("POST")
+	log.Infof("Started the webhooks_generic on %s", g.Path)
+}
+
+func (g *GenericWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if g.Secret != "" && !g.checkSignature(data, r.Header) {
+		g.log.Error("Invalid signature of the generic event")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	metrics, err := g.parser.Parse(data)
+	if err != nil {
+		g.log.Debugf("Could not parse the generic event: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, m := range metrics {
+		g.add(m)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (g *GenericWebhook) checkSignature(data []byte, header http.Header) bool {
+	signature := strings.TrimPrefix(header.Get(g.SignatureHeader), "sha256=")
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(g.Secret))
+	mac.Write(data)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
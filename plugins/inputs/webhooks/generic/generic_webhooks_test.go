@@ -0,0 +1,76 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const body = `[{"host":"a","load":{"1m":0.5,"5m":0.25}},{"host":"b","up":1}]`
+
+func post(g *GenericWebhook, body string,
+	header http.Header) (int, []telegraf.Metric) {
+	var metrics []telegraf.Metric
+	router := mux.NewRouter()
+	g.Register(router, func(m telegraf.Metric) {
+		metrics = append(metrics, m)
+	}, testutil.Logger{})
+
+	req, _ := http.NewRequest("POST", "/generic", strings.NewReader(body))
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, metrics
+}
+
+func TestGenericEvents(t *testing.T) {
+	code, metrics := post(&GenericWebhook{TagKeys: []string{"host"}}, body, nil)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, metrics, 2) {
+		assert.Equal(t, "webhooks_generic", metrics[0].Name())
+		assert.Equal(t, map[string]string{"host": "a"}, metrics[0].Tags())
+		assert.Equal(t, map[string]interface{}{
+			"load_1m": 0.5,
+			"load_5m": 0.25,
+		}, metrics[0].Fields())
+		assert.Equal(t, map[string]string{"host": "b"}, metrics[1].Tags())
+	}
+
+	code, _ = post(&GenericWebhook{}, "[1]", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestGenericSignature(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	// The signature is accepted with or without the sha256= prefix
+	for _, value := range []string{signature, "sha256=" + signature} {
+		header := http.Header{}
+		header.Set("X-Signature", value)
+		code, metrics := post(&GenericWebhook{
+			Secret:          "secret",
+			SignatureHeader: "X-Signature",
+		}, body, header)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, metrics, 2)
+	}
+
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", "sha256="+signature)
+	code, metrics := post(&GenericWebhook{Secret: "other"}, body, header)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Len(t, metrics, 0)
+}
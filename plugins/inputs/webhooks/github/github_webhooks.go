@@ -0,0 +1,91 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs/github_webhooks"
+)
+
+// GithubWebhook receives the events of GitHub, as the github_webhooks input
+type GithubWebhook struct {
+	Path string
+	// Secret of the webhook, the signatures of the events being checked
+	// if set
+	Secret string
+
+	add func(telegraf.Metric)
+	log telegraf.Logger
+}
+
+func (gh *GithubWebhook) Register(router *mux.Router,
+	add func(telegraf.Metric), log telegraf.Logger) {
+	if gh.Path == "" {
+		gh.Path = "/github"
+	}
+	gh.add = add
+	gh.log = log
+	router.HandleFunc(gh.Path, gh.eventHandler).Methods("POST")
+	log.Infof("Started the webhooks_github on %s", gh.Path)
+}
+
+func (gh *GithubWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
+	eventType := r.Header.Get("X-Github-Event")
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if gh.Secret != "" && !checkSignature(gh.Secret, data, r.Header) {
+		gh.log.Errorf("Invalid signature of the %s event", eventType)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	e, err := github_webhooks.NewEvent(data, eventType)
+	if err != nil {
+		gh.log.Debugf("Could not handle the %q event: %s", eventType, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m, err := e.NewMetric()
+	if err != nil {
+		gh.log.Errorf("Could not handle the %q event: %s", eventType, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	gh.add(m)
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkSignature checks the HMAC of the body with the secret, SHA-256 in
+// X-Hub-Signature-256, or SHA-1 in X-Hub-Signature with the older setups
+func checkSignature(secret string, data []byte, header http.Header) bool {
+	var h func() hash.Hash
+	var prefix string
+	signature := header.Get("X-Hub-Signature-256")
+	if signature != "" {
+		h, prefix = sha256.New, "sha256="
+	} else {
+		signature = header.Get("X-Hub-Signature")
+		h, prefix = sha1.New, "sha1="
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(data)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
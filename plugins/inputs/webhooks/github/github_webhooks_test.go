@@ -0,0 +1,69 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs/github_webhooks"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func post(gh *GithubWebhook, event string, body string,
+	header http.Header) (int, []telegraf.Metric) {
+	var metrics []telegraf.Metric
+	router := mux.NewRouter()
+	gh.Register(router, func(m telegraf.Metric) {
+		metrics = append(metrics, m)
+	}, testutil.Logger{})
+
+	req, _ := http.NewRequest("POST", "/github", strings.NewReader(body))
+	for key := range header {
+		req.Header.Set(key, header.Get(key))
+	}
+	req.Header.Set("X-Github-Event", event)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, metrics
+}
+
+func TestGithubEvent(t *testing.T) {
+	code, metrics := post(&GithubWebhook{}, "push",
+		github_webhooks.PushEventJSON(), nil)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, metrics, 1) {
+		assert.Equal(t, "github_webhooks", metrics[0].Name())
+		assert.Equal(t, "push", metrics[0].Tags()["event"])
+	}
+
+	code, metrics = post(&GithubWebhook{}, "unknown", "{}", nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, metrics, 0)
+}
+
+func TestGithubSignature(t *testing.T) {
+	body := github_webhooks.PushEventJSON()
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	header := http.Header{}
+	header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	code, metrics := post(&GithubWebhook{Secret: "secret"}, "push", body, header)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, metrics, 1)
+
+	code, metrics = post(&GithubWebhook{Secret: "other"}, "push", body, header)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Len(t, metrics, 0)
+
+	// The events without a signature are refused
+	code, _ = post(&GithubWebhook{Secret: "secret"}, "push", body, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
@@ -0,0 +1,107 @@
+package mandrill
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+)
+
+// MandrillWebhook receives the events of Mandrill, the messages sent, opened,
+// bounced...
+type MandrillWebhook struct {
+	Path string
+	// Key of the webhook, and its URL as configured in Mandrill, the
+	// signatures of the events being checked if set
+	WebhookKey string `toml:"webhook_key"`
+	URL        string `toml:"url"`
+
+	add func(telegraf.Metric)
+	log telegraf.Logger
+}
+
+// event is an event of the mandrill_events of the requests
+type event struct {
+	ID        string `json:"_id"`
+	EventName string `json:"event"`
+	Timestamp int64  `json:"ts"`
+}
+
+func (md *MandrillWebhook) Register(router *mux.Router,
+	add func(telegraf.Metric), log telegraf.Logger) {
+	if md.Path == "" {
+		md.Path = "/mandrill"
+	}
+	md.add = add
+	md.log = log
+	// Mandrill checks the URL with a HEAD request when the webhook is added
+	router.HandleFunc(md.Path, md.returnOK).Methods("HEAD")
+	router.HandleFunc(md.Path, md.eventHandler).Methods("POST")
+	log.Infof("Started the webhooks_mandrill on %s", md.Path)
+}
+
+func (md *MandrillWebhook) returnOK(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (md *MandrillWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if md.WebhookKey != "" && !md.checkSignature(r) {
+		md.log.Error("Invalid signature of the mandrill events")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var events []event
+	err := json.Unmarshal([]byte(r.PostForm.Get("mandrill_events")), &events)
+	if err != nil {
+		md.log.Debugf("Could not parse the mandrill events: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, e := range events {
+		m, err := telegraf.NewMetric("mandrill_webhooks",
+			map[string]string{"event": e.EventName},
+			map[string]interface{}{"id": e.ID},
+			time.Unix(e.Timestamp, 0))
+		if err != nil {
+			md.log.Errorf("Could not create metric: %s", err)
+			continue
+		}
+		md.add(m)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkSignature checks X-Mandrill-Signature, the base64 HMAC-SHA1 with the
+// webhook key of the URL followed by the keys and values of the sorted
+// parameters
+func (md *MandrillWebhook) checkSignature(r *http.Request) bool {
+	keys := make([]string, 0, len(r.PostForm))
+	for key := range r.PostForm {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(md.WebhookKey))
+	mac.Write([]byte(md.URL))
+	for _, key := range keys {
+		mac.Write([]byte(key))
+		mac.Write([]byte(r.PostForm.Get(key)))
+	}
+	sig, err := base64.StdEncoding.DecodeString(
+		r.Header.Get("X-Mandrill-Signature"))
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sig, mac.Sum(nil))
+}
@@ -0,0 +1,80 @@
+package mandrill
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const events = `[{"event":"send","_id":"id1","ts":1384954004},` +
+	`{"event":"hard_bounce","_id":"id2","ts":1384954005}]`
+
+func request(md *MandrillWebhook, method string, form url.Values,
+	signature string) (int, []telegraf.Metric) {
+	var metrics []telegraf.Metric
+	router := mux.NewRouter()
+	md.Register(router, func(m telegraf.Metric) {
+		metrics = append(metrics, m)
+	}, testutil.Logger{})
+
+	req, _ := http.NewRequest(method, "/mandrill",
+		strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if signature != "" {
+		req.Header.Set("X-Mandrill-Signature", signature)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, metrics
+}
+
+func TestMandrillEvents(t *testing.T) {
+	code, _ := request(&MandrillWebhook{}, "HEAD", nil, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, metrics := request(&MandrillWebhook{}, "POST",
+		url.Values{"mandrill_events": {events}}, "")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, metrics, 2) {
+		assert.Equal(t, "mandrill_webhooks", metrics[0].Name())
+		assert.Equal(t, map[string]string{"event": "send"}, metrics[0].Tags())
+		assert.Equal(t, map[string]interface{}{"id": "id1"},
+			metrics[0].Fields())
+		assert.Equal(t, time.Unix(1384954004, 0), metrics[0].Time())
+		assert.Equal(t, "hard_bounce", metrics[1].Tags()["event"])
+	}
+
+	code, _ = request(&MandrillWebhook{}, "POST",
+		url.Values{"mandrill_events": {"{"}}, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestMandrillSignature(t *testing.T) {
+	md := &MandrillWebhook{
+		WebhookKey: "key",
+		URL:        "https://telegraf.example.com/mandrill",
+	}
+	mac := hmac.New(sha1.New, []byte("key"))
+	mac.Write([]byte(md.URL + "mandrill_events" + events))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	form := url.Values{"mandrill_events": {events}}
+	code, metrics := request(md, "POST", form, signature)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, metrics, 2)
+
+	code, metrics = request(md, "POST", form, "invalid")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Len(t, metrics, 0)
+}
@@ -0,0 +1,97 @@
+package rollbar
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+)
+
+// RollbarWebhook receives the events of Rollbar, the items, occurrences and
+// deploys of the projects
+type RollbarWebhook struct {
+	Path string
+
+	add func(telegraf.Metric)
+	log telegraf.Logger
+}
+
+// event is the body of the requests, the data depending on the event
+type event struct {
+	EventName string `json:"event_name"`
+	Data      struct {
+		Item struct {
+			ID             int    `json:"id"`
+			Environment    string `json:"environment"`
+			ProjectID      int    `json:"project_id"`
+			LastOccurrence struct {
+				Language string `json:"language"`
+				Level    string `json:"level"`
+			} `json:"last_occurrence"`
+		} `json:"item"`
+		Deploy struct {
+			ID          int    `json:"id"`
+			Environment string `json:"environment"`
+			ProjectID   int    `json:"project_id"`
+		} `json:"deploy"`
+	} `json:"data"`
+}
+
+func (rb *RollbarWebhook) Register(router *mux.Router,
+	add func(telegraf.Metric), log telegraf.Logger) {
+	if rb.Path == "" {
+		rb.Path = "/rollbar"
+	}
+	rb.add = add
+	rb.log = log
+	router.HandleFunc(rb.Path, rb.eventHandler).Methods("POST")
+	log.Infof("Started the webhooks_rollbar on %s", rb.Path)
+}
+
+func (rb *RollbarWebhook) eventHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var e event
+	if err := json.Unmarshal(data, &e); err != nil {
+		rb.log.Debugf("Could not parse the rollbar event: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	tags := map[string]string{"event": e.EventName}
+	fields := make(map[string]interface{})
+	switch e.EventName {
+	case "new_item", "occurrence", "reactivated_item", "resolved_item":
+		item := e.Data.Item
+		tags["environment"] = item.Environment
+		tags["project_id"] = strconv.Itoa(item.ProjectID)
+		tags["language"] = item.LastOccurrence.Language
+		tags["level"] = item.LastOccurrence.Level
+		fields["id"] = item.ID
+	case "deploy":
+		deploy := e.Data.Deploy
+		tags["environment"] = deploy.Environment
+		tags["project_id"] = strconv.Itoa(deploy.ProjectID)
+		fields["id"] = deploy.ID
+	default:
+		rb.log.Debugf("Unsupported rollbar event %q", e.EventName)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	m, err := telegraf.NewMetric("rollbar_webhooks", tags, fields, time.Now())
+	if err != nil {
+		rb.log.Errorf("Could not create metric: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rb.add(m)
+	w.WriteHeader(http.StatusOK)
+}
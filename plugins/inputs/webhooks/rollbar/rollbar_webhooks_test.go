@@ -0,0 +1,68 @@
+package rollbar
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func post(body string) (int, []telegraf.Metric) {
+	var metrics []telegraf.Metric
+	router := mux.NewRouter()
+	rb := &RollbarWebhook{}
+	rb.Register(router, func(m telegraf.Metric) {
+		metrics = append(metrics, m)
+	}, testutil.Logger{})
+
+	req, _ := http.NewRequest("POST", "/rollbar", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code, metrics
+}
+
+func TestRollbarNewItem(t *testing.T) {
+	code, metrics := post(`{"event_name":"new_item","data":{"item":{` +
+		`"id":272716944,"environment":"production","project_id":90,` +
+		`"last_occurrence":{"language":"python","level":"error"}}}}`)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, metrics, 1) {
+		assert.Equal(t, "rollbar_webhooks", metrics[0].Name())
+		assert.Equal(t, map[string]string{
+			"event":       "new_item",
+			"environment": "production",
+			"project_id":  "90",
+			"language":    "python",
+			"level":       "error",
+		}, metrics[0].Tags())
+		assert.Equal(t, map[string]interface{}{"id": int64(272716944)},
+			metrics[0].Fields())
+	}
+}
+
+func TestRollbarDeploy(t *testing.T) {
+	code, metrics := post(`{"event_name":"deploy","data":{"deploy":{` +
+		`"id":187585,"environment":"production","project_id":90}}}`)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, metrics, 1) {
+		assert.Equal(t, map[string]string{
+			"event":       "deploy",
+			"environment": "production",
+			"project_id":  "90",
+		}, metrics[0].Tags())
+		assert.Equal(t, map[string]interface{}{"id": int64(187585)},
+			metrics[0].Fields())
+	}
+}
+
+func TestRollbarInvalid(t *testing.T) {
+	code, _ := post(`{"event_name":"unknown"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(`{`)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
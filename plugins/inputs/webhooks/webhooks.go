@@ -0,0 +1,217 @@
+package webhooks
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/github"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/mandrill"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/rollbar"
+)
+
+// Webhook is the endpoint of a provider, registering its handlers on the
+// router, the metrics of the events being added with add
+type Webhook interface {
+	Register(router *mux.Router, add func(telegraf.Metric), log telegraf.Logger)
+}
+
+// Webhooks runs an HTTP server receiving the webhooks of providers, an
+// endpoint per provider configured
+type Webhooks struct {
+	ServiceAddress string            `toml:"service_address"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MetricBuffer   int               `toml:"metric_buffer"`
+
+	// TLS certificate and key of the listener, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	Github   *github.GithubWebhook
+	Mandrill *mandrill.MandrillWebhook
+	Rollbar  *rollbar.RollbarWebhook
+	Generic  *generic.GenericWebhook
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	metricC  chan telegraf.Metric
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+var sampleConfig = `
+  # Address and port to listen on
+  service_address = ":1619"
+
+  # Timeouts of reading the requests and writing the responses
+  # read_timeout = "10s"
+  # write_timeout = "10s"
+
+  # Maximum number of metrics to buffer between collection intervals
+  metric_buffer = 10000
+
+  # TLS certificate and key of the listener, only accepting the clients
+  # presenting a certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  # The endpoints of the providers, enabled by their section
+  [inputs.webhooks.github]
+    path = "/github"
+    # Secret of the webhook, checking the signatures of the events
+    # secret = ""
+
+  [inputs.webhooks.mandrill]
+    path = "/mandrill"
+    # Key of the webhook, and its URL as configured in Mandrill, checking
+    # the signatures of the events
+    # webhook_key = ""
+    # url = "https://telegraf.example.com:1619/mandrill"
+
+  [inputs.webhooks.rollbar]
+    path = "/rollbar"
+
+  # JSON objects, or arrays of objects, their numbers being fields
+  # [inputs.webhooks.generic]
+  #   path = "/generic"
+  #   measurement_name = "webhooks_generic"
+  #   # Keys of the strings added as tags
+  #   tag_keys = []
+  #   # Secret checking the hex HMAC-SHA256 of the body in the header,
+  #   # prefixed or not by "sha256="
+  #   # secret = ""
+  #   # signature_header = "X-Hub-Signature-256"
+`
+
+func (wb *Webhooks) SampleConfig() string {
+	return sampleConfig
+}
+
+func (wb *Webhooks) Description() string {
+	return "Receive the webhooks of GitHub, Mandrill, Rollbar or JSON events"
+}
+
+// webhooks returns the endpoints configured
+func (wb *Webhooks) webhooks() []Webhook {
+	var webhooks []Webhook
+	if wb.Github != nil {
+		webhooks = append(webhooks, wb.Github)
+	}
+	if wb.Mandrill != nil {
+		webhooks = append(webhooks, wb.Mandrill)
+	}
+	if wb.Rollbar != nil {
+		webhooks = append(webhooks, wb.Rollbar)
+	}
+	if wb.Generic != nil {
+		webhooks = append(webhooks, wb.Generic)
+	}
+	return webhooks
+}
+
+func (wb *Webhooks) Start() error {
+	wb.Lock()
+	defer wb.Unlock()
+
+	if wb.ReadTimeout.Duration == 0 {
+		wb.ReadTimeout.Duration = 10 * time.Second
+	}
+	if wb.WriteTimeout.Duration == 0 {
+		wb.WriteTimeout.Duration = 10 * time.Second
+	}
+	if wb.MetricBuffer == 0 {
+		wb.MetricBuffer = 10000
+	}
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        wb.TLSCert,
+		TLSKey:         wb.TLSKey,
+		AllowedCACerts: wb.TLSAllowedCACerts,
+	})
+	if err != nil {
+		return err
+	}
+
+	wb.metricC = make(chan telegraf.Metric, wb.MetricBuffer)
+	router := mux.NewRouter()
+	for _, webhook := range wb.webhooks() {
+		webhook.Register(router, wb.add, wb.Log)
+	}
+
+	listener, err := net.Listen("tcp", wb.ServiceAddress)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	wb.listener = listener
+	server := &http.Server{
+		Handler:      router,
+		ReadTimeout:  wb.ReadTimeout.Duration,
+		WriteTimeout: wb.WriteTimeout.Duration,
+	}
+	wb.wg.Add(1)
+	go func() {
+		defer wb.wg.Done()
+		// Serve returns an error once the listener is closed by Stop
+		server.Serve(listener)
+	}()
+	wb.Log.Infof("Started the webhooks service on %s", wb.ServiceAddress)
+	return nil
+}
+
+// Addr returns the address listened on
+func (wb *Webhooks) Addr() net.Addr {
+	return wb.listener.Addr()
+}
+
+// add queues the metric of an event for the next Gather
+func (wb *Webhooks) add(m telegraf.Metric) {
+	select {
+	case wb.metricC <- m:
+	default:
+		wb.Log.Warn("Buffer is full, dropping a metric." +
+			" You may want to increase the metric_buffer setting")
+	}
+}
+
+func (wb *Webhooks) Stop() {
+	wb.Lock()
+	defer wb.Unlock()
+	wb.listener.Close()
+	wb.wg.Wait()
+	wb.Log.Info("Stopped the webhooks service")
+}
+
+func (wb *Webhooks) Gather(acc telegraf.Accumulator) error {
+	wb.Lock()
+	defer wb.Unlock()
+	nmetrics := len(wb.metricC)
+	for i := 0; i < nmetrics; i++ {
+		metric := <-wb.metricC
+		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(),
+			metric.Time())
+	}
+	return nil
+}
+
+func init() {
+	inputs.Add("webhooks", func() telegraf.Input {
+		return &Webhooks{
+			ServiceAddress: ":1619",
+			MetricBuffer:   10000,
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/generic"
+	"github.com/influxdata/telegraf/plugins/inputs/webhooks/rollbar"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksConfig(t *testing.T) {
+	// The sections of the endpoints are the sub-tables of the input
+	table, err := toml.Parse([]byte("[inputs.webhooks]" + sampleConfig))
+	require.NoError(t, err)
+	inputs := table.Fields["inputs"].(*ast.Table)
+	wb := &Webhooks{}
+	require.NoError(t, toml.UnmarshalTable(
+		inputs.Fields["webhooks"].(*ast.Table), wb))
+
+	assert.Equal(t, ":1619", wb.ServiceAddress)
+	require.NotNil(t, wb.Github)
+	assert.Equal(t, "/github", wb.Github.Path)
+	require.NotNil(t, wb.Mandrill)
+	require.NotNil(t, wb.Rollbar)
+	// The endpoints are only enabled by their section
+	assert.Nil(t, wb.Generic)
+	assert.Len(t, wb.webhooks(), 3)
+}
+
+func TestWebhooks(t *testing.T) {
+	wb := &Webhooks{
+		ServiceAddress: "127.0.0.1:0",
+		Rollbar:        &rollbar.RollbarWebhook{},
+		Generic:        &generic.GenericWebhook{MeasurementName: "events"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, wb.Start())
+	defer wb.Stop()
+	url := "http://" + wb.Addr().String()
+
+	resp, err := http.Post(url+"/generic", "application/json",
+		strings.NewReader(`{"value":1}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The endpoints not configured are not found
+	resp, err = http.Post(url+"/github", "application/json",
+		strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var acc testutil.Accumulator
+	require.NoError(t, wb.Gather(&acc))
+	acc.AssertContainsFields(t, "events",
+		map[string]interface{}{"value": float64(1)})
+}
+
+func TestWebhooksBufferFull(t *testing.T) {
+	wb := &Webhooks{
+		ServiceAddress: "127.0.0.1:0",
+		MetricBuffer:   1,
+		Generic:        &generic.GenericWebhook{},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, wb.Start())
+	defer wb.Stop()
+
+	resp, err := http.Post("http://"+wb.Addr().String()+"/generic",
+		"application/json", strings.NewReader(`[{"a":1},{"a":2}]`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	var acc testutil.Accumulator
+	require.NoError(t, wb.Gather(&acc))
+	assert.Len(t, acc.Metrics, 1)
+}
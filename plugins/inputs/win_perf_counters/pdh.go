@@ -0,0 +1,79 @@
+// +build windows
+
+package win_perf_counters
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/lxn/win"
+)
+
+// PdhExpandWildCardPathW is not wrapped by lxn/win
+var (
+	libpdh                 = syscall.NewLazyDLL("pdh.dll")
+	procPdhExpandWildCards = libpdh.NewProc("PdhExpandWildCardPathW")
+)
+
+// expandWildCardPath returns the paths of the counters matching the path with
+// wildcards, with the localized names of the objects and counters.
+func expandWildCardPath(path string) ([]string, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first call returns the size of the list of paths
+	var size uint32
+	ret, _, _ := procPdhExpandWildCards.Call(0, uintptr(unsafe.Pointer(p)), 0,
+		uintptr(unsafe.Pointer(&size)), 0)
+	if uint32(ret) != win.PDH_MORE_DATA {
+		return nil, fmt.Errorf("could not expand %s: error 0x%x", path, ret)
+	}
+	buf := make([]uint16, size)
+	ret, _, _ = procPdhExpandWildCards.Call(0, uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)), 0)
+	if uint32(ret) != win.ERROR_SUCCESS {
+		return nil, fmt.Errorf("could not expand %s: error 0x%x", path, ret)
+	}
+
+	// The paths are separated by a null character, and end with two
+	var paths []string
+	start := 0
+	for i, c := range buf[:size] {
+		if c != 0 {
+			continue
+		}
+		if i > start {
+			paths = append(paths, syscall.UTF16ToString(buf[start:i]))
+		}
+		start = i + 1
+	}
+	return paths, nil
+}
+
+// expandCounters returns the localized names of the counters of the object,
+// without duplicates
+func expandCounters(objectName string, hasInstances bool) ([]string, error) {
+	query := "\\" + objectName + "\\*"
+	if hasInstances {
+		query = "\\" + objectName + "(*)\\*"
+	}
+	paths, err := expandWildCardPath(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var counters []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		counter := path[strings.LastIndex(path, "\\")+1:]
+		if !seen[counter] {
+			seen[counter] = true
+			counters = append(counters, counter)
+		}
+	}
+	return counters, nil
+}
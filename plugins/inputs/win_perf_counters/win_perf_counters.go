@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/lxn/win"
 )
@@ -64,6 +65,14 @@ var sampleConfig string = `
     ]
     Instances = ["------"] # Use 6 x - to remove the Instance bit from the query.
     Measurement = "win_mem"
+
+  [[inputs.win_perf_counters.object]]
+    # Example query of all the counters of the instances matching a pattern,
+    # the names of the counters being localized.
+    ObjectName = "Process"
+    Counters = ["*"]
+    Instances = ["sqlservr*", "w3wp*"]
+    Measurement = "win_proc"
 `
 
 // Valid queries end up in this map.
@@ -103,17 +112,20 @@ type item struct {
 	instance      string
 	measurement   string
 	include_total bool
+	// localized is whether the names of the query are localized, rather
+	// than in English
+	localized     bool
 	handle        win.PDH_HQUERY
 	counterHandle win.PDH_HCOUNTER
 }
 
 func (m *Win_PerfCounters) AddItem(metrics *itemList, query string, objectName string, counter string, instance string,
-	measurement string, include_total bool) {
+	measurement string, include_total bool, localized bool) {
 
 	var handle win.PDH_HQUERY
 	var counterHandle win.PDH_HCOUNTER
 	ret := win.PdhOpenQuery(0, 0, &handle)
-	if m.PreVistaSupport {
+	if m.PreVistaSupport || localized {
 		ret = win.PdhAddCounter(handle, query, 0, &counterHandle)
 	} else {
 		ret = win.PdhAddEnglishCounter(handle, query, 0, &counterHandle)
@@ -121,7 +133,7 @@ func (m *Win_PerfCounters) AddItem(metrics *itemList, query string, objectName s
 	_ = ret
 
 	temp := &item{query, objectName, counter, instance, measurement,
-		include_total, handle, counterHandle}
+		include_total, localized, handle, counterHandle}
 	index := len(gItemList)
 	gItemList[index] = temp
 
@@ -173,6 +185,40 @@ func (m *Win_PerfCounters) SampleConfig() string {
 	return sampleConfig
 }
 
+// validatePath returns the status of the path of a counter, ERROR_SUCCESS if
+// it is valid. The paths in English are validated by adding them to a query,
+// PdhValidatePath only accepting the localized names.
+func (m *Win_PerfCounters) validatePath(query string, localized bool) uint32 {
+	if m.PreVistaSupport || localized {
+		return win.PdhValidatePath(query)
+	}
+
+	var handle win.PDH_HQUERY
+	var counterHandle win.PDH_HCOUNTER
+	if ret := win.PdhOpenQuery(0, 0, &handle); ret != win.ERROR_SUCCESS {
+		return ret
+	}
+	defer win.PdhCloseQuery(handle)
+	return win.PdhAddEnglishCounter(handle, query, 0, &counterHandle)
+}
+
+// matchInstance returns whether the instance of a counter is selected by the
+// instance of the config, "------" for the objects without instances or a
+// pattern with "*" wildcards. The _Total instances are only matched by the
+// wildcards if includeTotal is set.
+func matchInstance(pattern string, instance string, includeTotal bool) bool {
+	switch {
+	case pattern == "------" || pattern == instance:
+		return true
+	case strings.Contains(pattern, "*"):
+		if !includeTotal && strings.Contains(instance, "_Total") {
+			return false
+		}
+		return internal.Glob(pattern, instance)
+	}
+	return includeTotal
+}
+
 func (m *Win_PerfCounters) ParseConfig(metrics *itemList) error {
 	var query string
 
@@ -180,24 +226,48 @@ func (m *Win_PerfCounters) ParseConfig(metrics *itemList) error {
 
 	if len(m.Object) > 0 {
 		for _, PerfObject := range m.Object {
-			for _, counter := range PerfObject.Counters {
-				for _, instance := range PerfObject.Instances {
-					objectname := PerfObject.ObjectName
+			objectname := PerfObject.ObjectName
+
+			// All the counters are selected by "*", with their localized
+			// names
+			counters := PerfObject.Counters
+			localized := false
+			if len(counters) == 1 && counters[0] == "*" {
+				hasInstances := len(PerfObject.Instances) != 1 ||
+					PerfObject.Instances[0] != "------"
+				var err error
+				counters, err = expandCounters(objectname, hasInstances)
+				if err != nil {
+					if PerfObject.FailOnMissing || PerfObject.WarnOnMissing {
+						return err
+					}
+					continue
+				}
+				localized = true
+			}
 
+			for _, counter := range counters {
+				for _, instance := range PerfObject.Instances {
+					// The instances matching patterns are filtered from
+					// all the instances
+					queryInstance := instance
+					if strings.Contains(instance, "*") {
+						queryInstance = "*"
+					}
 					if instance == "------" {
 						query = "\\" + objectname + "\\" + counter
 					} else {
-						query = "\\" + objectname + "(" + instance + ")\\" + counter
+						query = "\\" + objectname + "(" + queryInstance + ")\\" + counter
 					}
 
-					var exists uint32 = win.PdhValidatePath(query)
+					var exists uint32 = m.validatePath(query, localized)
 
 					if exists == win.ERROR_SUCCESS {
 						if m.PrintValid {
 							fmt.Printf("Valid: %s\n", query)
 						}
 						m.AddItem(metrics, query, objectname, counter, instance,
-							PerfObject.Measurement, PerfObject.IncludeTotal)
+							PerfObject.Measurement, PerfObject.IncludeTotal, localized)
 					} else {
 						if PerfObject.FailOnMissing || PerfObject.WarnOnMissing {
 							err := m.InvalidObject(exists, query, PerfObject, instance, counter)
@@ -275,22 +345,7 @@ func (m *Win_PerfCounters) Gather(acc telegraf.Accumulator) error {
 					c := filledBuf[i]
 					var s string = win.UTF16PtrToString(c.SzName)
 
-					var add bool
-
-					if metric.include_total {
-						// If IncludeTotal is set, include all.
-						add = true
-					} else if metric.instance == "*" && !strings.Contains(s, "_Total") {
-						// Catch if set to * and that it is not a '*_Total*' instance.
-						add = true
-					} else if metric.instance == s {
-						// Catch if we set it to total or some form of it
-						add = true
-					} else if metric.instance == "------" {
-						add = true
-					}
-
-					if add {
+					if matchInstance(metric.instance, s, metric.include_total) {
 						fields := make(map[string]interface{})
 						tags := make(map[string]string)
 						if s != "" {
@@ -525,3 +525,31 @@ func TestWinPerfcountersCollect2(t *testing.T) {
 	acc.AssertContainsTaggedFields(t, measurement, fields, tags)
 
 }
+
+func TestWinPerfcountersMatchInstance(t *testing.T) {
+	require.True(t, matchInstance("------", "", false))
+	require.True(t, matchInstance("_Total", "_Total", false))
+	require.True(t, matchInstance("*", "0", false))
+	require.False(t, matchInstance("*", "_Total", false))
+	require.True(t, matchInstance("*", "0,_Total", true))
+	require.True(t, matchInstance("w3wp*", "w3wp#1", false))
+	require.False(t, matchInstance("w3wp*", "sqlservr", false))
+	require.False(t, matchInstance("0", "1", false))
+}
+
+func TestWinPerfcountersExpandCounters(t *testing.T) {
+	counters, err := expandCounters("Memory", false)
+	require.NoError(t, err)
+	require.NotEmpty(t, counters)
+
+	var perfobjects = []perfobject{{
+		ObjectName:    "Processor",
+		Instances:     []string{"*"},
+		Counters:      []string{"*"},
+		FailOnMissing: true,
+	}}
+	metrics := itemList{}
+	m := Win_PerfCounters{TestName: "ExpandCounters", Object: perfobjects}
+	require.NoError(t, m.ParseConfig(&metrics))
+	require.NotEmpty(t, metrics.items)
+}
@@ -0,0 +1,109 @@
+// +build linux
+
+package wireless
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// Wireless reads the link quality and signal of the wireless interfaces
+// from /proc/net/wireless
+type Wireless struct {
+	HostProc string `toml:"host_proc"`
+}
+
+var sampleConfig = `
+  # Path of /proc, ie /hostfs/proc when telegraf runs in a container with
+  # the /proc of the host mounted
+  # host_proc = "/proc"
+`
+
+func (w *Wireless) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wireless) Description() string {
+	return "Gather the link quality and signal of the wireless interfaces"
+}
+
+// columns are the fields of the columns of /proc/net/wireless after the
+// interface, listed as
+//
+//	Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+//	 face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+//	 wlan0: 0000   60.  -50.  -256        0      0      0      0      0        0
+//
+// the status being in hex, and the quality values followed by a dot when
+// they were updated since the last read
+var columns = []string{
+	"status",
+	"link",
+	"level",
+	"noise",
+	"nwid",
+	"crypt",
+	"frag",
+	"retry",
+	"misc",
+	"missed_beacon",
+}
+
+func (w *Wireless) Gather(acc telegraf.Accumulator) error {
+	if w.HostProc == "" {
+		w.HostProc = "/proc"
+	}
+	data, err := ioutil.ReadFile(filepath.Join(w.HostProc, "net",
+		"wireless"))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		cols := strings.Fields(parts[1])
+		if len(cols) < len(columns) {
+			continue
+		}
+
+		fields := make(map[string]interface{}, len(columns))
+		for i, name := range columns {
+			value := strings.TrimRight(cols[i], ".")
+			var v int64
+			var err error
+			if name == "status" {
+				v, err = strconv.ParseInt(value, 16, 64)
+			} else {
+				v, err = strconv.ParseInt(value, 10, 64)
+			}
+			if err != nil {
+				return fmt.Errorf("parsing the %s of %s: %s", name,
+					strings.TrimSpace(parts[0]), err)
+			}
+			fields[name] = v
+		}
+		tags := map[string]string{"interface": strings.TrimSpace(parts[0])}
+		acc.AddFields("wireless", fields, tags, now)
+	}
+	return scanner.Err()
+}
+
+func init() {
+	inputs.Add("wireless", func() telegraf.Input {
+		return &Wireless{HostProc: "/proc"}
+	})
+}
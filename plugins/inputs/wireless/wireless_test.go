@@ -0,0 +1,77 @@
+// +build linux
+
+package wireless
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const procNetWireless = `Inter-| sta-|   Quality        |   Discarded packets               | Missed | WE
+ face | tus | link level noise |  nwid  crypt   frag  retry   misc | beacon | 22
+ wlan0: 0000   60.  -50.  -256        0      0      0     12      3        7
+  wlp2s0: 0001   35   -75   -256        1      2      0      0      0        0
+`
+
+func writeProc(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "wireless")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "net"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "net", "wireless"),
+		[]byte(content), 0644))
+	return dir
+}
+
+func TestGather(t *testing.T) {
+	dir := writeProc(t, procNetWireless)
+	defer os.RemoveAll(dir)
+
+	w := &Wireless{HostProc: dir}
+	var acc testutil.Accumulator
+	require.NoError(t, w.Gather(&acc))
+
+	assert.Equal(t, 2, len(acc.Metrics))
+	acc.AssertContainsTaggedFields(t, "wireless", map[string]interface{}{
+		"status":        int64(0),
+		"link":          int64(60),
+		"level":         int64(-50),
+		"noise":         int64(-256),
+		"nwid":          int64(0),
+		"crypt":         int64(0),
+		"frag":          int64(0),
+		"retry":         int64(12),
+		"misc":          int64(3),
+		"missed_beacon": int64(7),
+	}, map[string]string{"interface": "wlan0"})
+	acc.AssertContainsTaggedFields(t, "wireless", map[string]interface{}{
+		"status":        int64(1),
+		"link":          int64(35),
+		"level":         int64(-75),
+		"noise":         int64(-256),
+		"nwid":          int64(1),
+		"crypt":         int64(2),
+		"frag":          int64(0),
+		"retry":         int64(0),
+		"misc":          int64(0),
+		"missed_beacon": int64(0),
+	}, map[string]string{"interface": "wlp2s0"})
+}
+
+func TestGatherErrors(t *testing.T) {
+	w := &Wireless{HostProc: "/nonexistent"}
+	var acc testutil.Accumulator
+	assert.Error(t, w.Gather(&acc))
+
+	dir := writeProc(t, " wlan0: 0000   sixty.  -50.  -256  0  0  0  0  0  0\n")
+	defer os.RemoveAll(dir)
+	w = &Wireless{HostProc: dir}
+	err := w.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "link of wlan0")
+}
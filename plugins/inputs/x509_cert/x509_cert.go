@@ -0,0 +1,359 @@
+package x509_cert
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// X509Cert reads the certificates of files or of servers, and reports their
+// validity and the time until they expire
+type X509Cert struct {
+	Sources       []string
+	Timeout       internal.Duration
+	TLSServerName string `toml:"tls_server_name"`
+
+	// TLS options of the connections to the servers
+	SSLCA   string `toml:"ssl_ca"`
+	SSLCert string `toml:"ssl_cert"`
+	SSLKey  string `toml:"ssl_key"`
+
+	tlsConfig *tls.Config
+}
+
+var sampleConfig = `
+  # Sources of the certificates: PEM files, or servers, with their TLS
+  # handshake with tcp:// or https://, or their STARTTLS command with
+  # smtp:// or ldap://
+  sources = [
+    "/etc/ssl/certs/ssl-cert-snakeoil.pem",
+    "https://example.org:443",
+    "tcp://example.org:993",
+    "smtp://mail.example.org:25",
+    "ldap://ldap.example.org:389",
+  ]
+
+  # Timeout of the connections to the servers
+  # timeout = "5s"
+
+  # Server name sent with SNI, the host of the source by default
+  # tls_server_name = "example.org"
+
+  # CA verifying the certificates, the CAs of the system by default, and
+  # client certificate of the connections to the servers
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+`
+
+func (c *X509Cert) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *X509Cert) Description() string {
+	return "Reads the certificates of files or servers and reports their expiry"
+}
+
+// Init creates the TLS config of the connections to the servers. Their
+// certificates are not verified by the handshake, the expired or invalid
+// certificates being reported too.
+func (c *X509Cert) Init() error {
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              c.SSLCA,
+		SSLCert:            c.SSLCert,
+		SSLKey:             c.SSLKey,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return err
+	}
+	c.tlsConfig = tlsConfig
+	return nil
+}
+
+func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
+	var errorStrings []string
+	now := time.Now()
+	for _, source := range c.Sources {
+		certs, err := c.getCerts(source)
+		if err != nil {
+			errorStrings = append(errorStrings,
+				fmt.Sprintf("%s: %s", source, err))
+			continue
+		}
+
+		// The certificates are verified with the others as intermediates,
+		// the first one being the certificate of the server
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		for i, cert := range certs {
+			tags := map[string]string{
+				"source":             source,
+				"common_name":        cert.Subject.CommonName,
+				"issuer_common_name": cert.Issuer.CommonName,
+				"serial_number":      fmt.Sprintf("%x", cert.SerialNumber),
+			}
+			if len(cert.Subject.Organization) > 0 {
+				tags["organization"] = cert.Subject.Organization[0]
+			}
+			if len(cert.DNSNames) > 0 {
+				tags["san"] = strings.Join(cert.DNSNames, ",")
+			}
+
+			expiry := cert.NotAfter.Sub(now)
+			fields := map[string]interface{}{
+				"age":               int64(now.Sub(cert.NotBefore).Seconds()),
+				"expiry":            int64(expiry.Seconds()),
+				"days_until_expiry": int64(expiry.Hours() / 24),
+				"startdate":         cert.NotBefore.Unix(),
+				"enddate":           cert.NotAfter.Unix(),
+			}
+			opts := x509.VerifyOptions{
+				Roots:         c.tlsConfig.RootCAs,
+				Intermediates: intermediates,
+				CurrentTime:   now,
+			}
+			// The name of the server is verified with its certificate
+			if i == 0 {
+				opts.DNSName = c.serverName(source)
+			}
+			if _, err := cert.Verify(opts); err != nil {
+				fields["verification"] = "invalid"
+				fields["verification_code"] = 1
+				fields["verification_error"] = err.Error()
+			} else {
+				fields["verification"] = "valid"
+				fields["verification_code"] = 0
+			}
+			acc.AddFields("x509_cert", fields, tags, now)
+		}
+	}
+
+	if len(errorStrings) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errorStrings, "\n"))
+}
+
+// serverName returns the name of the server of the source, verified with its
+// certificate, or an empty name for the files
+func (c *X509Cert) serverName(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	if c.TLSServerName != "" {
+		return c.TLSServerName
+	}
+	host, _, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return u.Host
+	}
+	return host
+}
+
+// getCerts returns the certificates of the source, the certificate chain of
+// the servers, the certificate of the server first. The sources without a
+// scheme, or with the drive of a Windows path as scheme, are files.
+func (c *X509Cert) getCerts(source string) ([]*x509.Certificate, error) {
+	u, err := url.Parse(source)
+	if err != nil || len(u.Scheme) <= 1 || u.Scheme == "file" {
+		path := source
+		if err == nil && u.Scheme == "file" {
+			path = u.Path
+		}
+		return readCerts(path)
+	}
+
+	switch u.Scheme {
+	case "tcp", "https", "smtp", "ldap":
+	default:
+		return nil, fmt.Errorf("unknown scheme %q, must be tcp, https, "+
+			"smtp or ldap", u.Scheme)
+	}
+
+	timeout := c.Timeout.Duration
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", u.Host, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	config := &tls.Config{
+		Certificates:       c.tlsConfig.Certificates,
+		RootCAs:            c.tlsConfig.RootCAs,
+		InsecureSkipVerify: true,
+		ServerName:         c.serverName(source),
+	}
+
+	var state tls.ConnectionState
+	switch u.Scheme {
+	case "tcp", "https":
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		state = tlsConn.ConnectionState()
+	case "smtp":
+		client, err := smtp.NewClient(conn, config.ServerName)
+		if err != nil {
+			return nil, err
+		}
+		if err := client.StartTLS(config); err != nil {
+			return nil, err
+		}
+		var ok bool
+		if state, ok = client.TLSConnectionState(); !ok {
+			return nil, errors.New("no TLS connection")
+		}
+		client.Quit()
+	case "ldap":
+		if err := ldapStartTLS(conn); err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		state = tlsConn.ConnectionState()
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no certificate")
+	}
+	return state.PeerCertificates, nil
+}
+
+// readCerts returns the certificates of the PEM file
+func readCerts(path string) ([]*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate")
+	}
+	return certs, nil
+}
+
+// ldapStartTLSRequest is the StartTLS extended request of LDAP (RFC 4511),
+// message 1, in BER:
+//
+//	LDAPMessage ::= SEQUENCE {
+//		messageID  1,
+//		extendedReq [APPLICATION 23] SEQUENCE {
+//			requestName [0] "1.3.6.1.4.1.1466.20037" } }
+var ldapStartTLSRequest = append([]byte{
+	0x30, 0x1d,
+	0x02, 0x01, 0x01,
+	0x77, 0x18,
+	0x80, 0x16}, "1.3.6.1.4.1.1466.20037"...)
+
+// ldapStartTLS sends the StartTLS extended request of LDAP and checks the
+// result code of its response, after which the TLS handshake starts
+func ldapStartTLS(conn net.Conn) error {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	tag, message, err := readBER(r)
+	if err != nil {
+		return err
+	}
+	if tag != 0x30 {
+		return errors.New("invalid LDAP response")
+	}
+	// The message id, then the extendedResp [APPLICATION 24], whose
+	// resultCode is an ENUMERATED
+	mr := bufio.NewReader(strings.NewReader(string(message)))
+	if _, _, err := readBER(mr); err != nil {
+		return err
+	}
+	tag, resp, err := readBER(mr)
+	if err != nil {
+		return err
+	}
+	if tag != 0x78 || len(resp) < 3 || resp[0] != 0x0a || resp[1] != 1 {
+		return errors.New("invalid LDAP StartTLS response")
+	}
+	if resp[2] != 0 {
+		return fmt.Errorf("LDAP StartTLS failed with result code %d", resp[2])
+	}
+	return nil
+}
+
+// readBER reads the tag and the value of a BER element
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length := int(b)
+	// The long form of the length is its size followed by its bytes
+	if b&0x80 != 0 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 4 {
+			return 0, nil, errors.New("invalid BER length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return 0, nil, err
+			}
+			length = length<<8 | int(b)
+		}
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return 0, nil, err
+	}
+	return tag, value, nil
+}
+
+func init() {
+	inputs.Add("x509_cert", func() telegraf.Input {
+		return &X509Cert{}
+	})
+}
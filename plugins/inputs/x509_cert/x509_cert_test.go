@@ -0,0 +1,234 @@
+package x509_cert
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCert returns a self-signed certificate of localhost expiring in 10 days
+func newCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0x2a),
+		Subject: pkix.Name{
+			CommonName:   "localhost",
+			Organization: []string{"Telegraf"},
+		},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(10*24*time.Hour + time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeCA writes the certificate as a PEM file in dir
+func writeCA(t *testing.T, dir string, cert tls.Certificate) string {
+	path := filepath.Join(dir, "cert.pem")
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	return path
+}
+
+// serve runs handle on the connections of l
+func serve(l net.Listener, handle func(c net.Conn)) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer c.Close()
+			handle(c)
+		}()
+	}
+}
+
+func TestGatherFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "x509_cert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeCA(t, dir, newCert(t))
+
+	// The self-signed certificate is valid with itself as CA only
+	for _, tt := range []struct {
+		ca           string
+		verification string
+	}{
+		{"", "invalid"},
+		{path, "valid"},
+	} {
+		c := &X509Cert{Sources: []string{path}, SSLCA: tt.ca}
+		require.NoError(t, c.Init())
+		var acc testutil.Accumulator
+		require.NoError(t, c.Gather(&acc))
+
+		m, ok := acc.Get("x509_cert")
+		require.True(t, ok)
+		assert.Equal(t, map[string]string{
+			"source":             path,
+			"common_name":        "localhost",
+			"issuer_common_name": "localhost",
+			"organization":       "Telegraf",
+			"serial_number":      "2a",
+			"san":                "localhost",
+		}, m.Tags)
+		assert.Equal(t, int64(10), m.Fields["days_until_expiry"])
+		assert.Equal(t, tt.verification, m.Fields["verification"])
+		expiry := m.Fields["expiry"].(int64)
+		assert.True(t, expiry > 10*24*3600 && expiry <= 10*24*3600+3600)
+	}
+}
+
+func TestGatherTCP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "x509_cert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cert := newCert(t)
+	ca := writeCA(t, dir, cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0",
+		&tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	defer l.Close()
+	go serve(l, func(c net.Conn) { c.(*tls.Conn).Handshake() })
+
+	source := "tcp://" + l.Addr().String()
+	c := &X509Cert{Sources: []string{source}, SSLCA: ca}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	m, ok := acc.Get("x509_cert")
+	require.True(t, ok)
+	// The certificate is not valid for 127.0.0.1
+	assert.Equal(t, "invalid", m.Fields["verification"])
+
+	c = &X509Cert{
+		Sources:       []string{source},
+		SSLCA:         ca,
+		TLSServerName: "localhost",
+	}
+	require.NoError(t, c.Init())
+	acc = testutil.Accumulator{}
+	require.NoError(t, c.Gather(&acc))
+	m, ok = acc.Get("x509_cert")
+	require.True(t, ok)
+	assert.Equal(t, "valid", m.Fields["verification"])
+	assert.Equal(t, 0, m.Fields["verification_code"])
+}
+
+func TestGatherSMTP(t *testing.T) {
+	cert := newCert(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go serve(l, func(c net.Conn) {
+		var conn io.ReadWriter = c
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch line[:4] {
+			case "EHLO":
+				fmt.Fprint(conn, "250-localhost\r\n250 STARTTLS\r\n")
+			case "STAR":
+				// The session goes on over TLS
+				fmt.Fprint(conn, "220 Ready to start TLS\r\n")
+				conn = tls.Server(c, &tls.Config{
+					Certificates: []tls.Certificate{cert},
+				})
+				r = bufio.NewReader(conn)
+			case "QUIT":
+				fmt.Fprint(conn, "221 Bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "502 Not implemented\r\n")
+			}
+		}
+	})
+
+	c := &X509Cert{Sources: []string{"smtp://" + l.Addr().String()}}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	m, ok := acc.Get("x509_cert")
+	require.True(t, ok)
+	assert.Equal(t, "localhost", m.Tags["common_name"])
+}
+
+func TestGatherLDAP(t *testing.T) {
+	cert := newCert(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go serve(l, func(c net.Conn) {
+		req := make([]byte, len(ldapStartTLSRequest))
+		if _, err := io.ReadFull(c, req); err != nil {
+			return
+		}
+		// The success of the StartTLS extended response of message 1
+		c.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01,
+			0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+		tls.Server(c, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+		}).Handshake()
+	})
+
+	c := &X509Cert{Sources: []string{"ldap://" + l.Addr().String()}}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, c.Gather(&acc))
+	m, ok := acc.Get("x509_cert")
+	require.True(t, ok)
+	assert.Equal(t, "localhost", m.Tags["common_name"])
+}
+
+func TestGatherErrors(t *testing.T) {
+	// The port of a closed listener is refusing the connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	l.Close()
+
+	c := &X509Cert{Sources: []string{
+		"/nonexistent/cert.pem",
+		"tcp://" + l.Addr().String(),
+		"ftp://localhost:21",
+	}}
+	require.NoError(t, c.Init())
+	var acc testutil.Accumulator
+	err = c.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/nonexistent/cert.pem")
+	assert.Contains(t, err.Error(), l.Addr().String())
+	assert.Contains(t, err.Error(), "unknown scheme")
+}
@@ -1,20 +1,34 @@
 package zfs
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
+// Runner runs zpool, zfs or sysctl with the arguments, killing it after the
+// timeout, and returns its output. It is replaced by a mock in the tests.
+type Runner func(timeout time.Duration, path string, args ...string) (string, error)
+
 type Zfs struct {
-	KstatPath    string
-	KstatMetrics []string
-	PoolMetrics  bool
+	KstatPath      string
+	KstatMetrics   []string
+	PoolMetrics    bool
+	PoolStatus     bool
+	DatasetMetrics bool
+	Timeout        internal.Duration
+
+	runner Runner
 }
 
 type poolInfo struct {
@@ -33,6 +47,16 @@ var sampleConfig = `
   #
   # By default, don't gather zpool stats
   # poolMetrics = false
+  #
+  # Gather the health, size, capacity and fragmentation of the pools with
+  # zpool list, added to the zpool stats
+  # poolStatus = false
+  #
+  # Gather the usage of the datasets with zfs list
+  # datasetMetrics = false
+  #
+  # Timeout of zpool, zfs, and sysctl which reads the stats on FreeBSD
+  # timeout = "5s"
 `
 
 func (z *Zfs) SampleConfig() string {
@@ -40,7 +64,7 @@ func (z *Zfs) SampleConfig() string {
 }
 
 func (z *Zfs) Description() string {
-	return "Read metrics of ZFS from arcstats, zfetchstats and vdev_cache_stats"
+	return "Read metrics of ZFS from arcstats, zfetchstats and vdev_cache_stats, and of the pools and datasets"
 }
 
 func getPools(kstatPath string) []poolInfo {
@@ -69,7 +93,8 @@ func getTags(pools []poolInfo) map[string]string {
 	return map[string]string{"pools": poolNames}
 }
 
-func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
+// gatherPoolStats adds the io stats of the pool to its fields
+func gatherPoolStats(pool poolInfo, fields map[string]interface{}) error {
 	lines, err := internal.ReadLines(pool.ioFilename)
 	if err != nil {
 		return err
@@ -88,8 +113,6 @@ func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
 		return fmt.Errorf("Key and value count don't match Keys:%v Values:%v", keys, values)
 	}
 
-	tag := map[string]string{"pool": pool.name}
-	fields := make(map[string]interface{})
 	for i := 0; i < keyCount; i++ {
 		value, err := strconv.ParseInt(values[i], 10, 64)
 		if err != nil {
@@ -97,12 +120,150 @@ func gatherPoolStats(pool poolInfo, acc telegraf.Accumulator) error {
 		}
 		fields[keys[i]] = value
 	}
-	acc.AddFields("zfs_pool", fields, tag)
 
 	return nil
 }
 
+// poolStatusFields are the fields of the columns of zpool list, after the
+// name of the pool
+var poolStatusFields = []string{
+	"health",
+	"size",
+	"allocated",
+	"free",
+	"fragmentation",
+	"capacity",
+	"dedupratio",
+}
+
+// gatherPoolStatus adds the status of the pools, as listed by
+// zpool list -Hp, to their fields. The sizes are in bytes, the fragmentation
+// and capacity in percents, the fragmentation being - if unknown.
+func (z *Zfs) gatherPoolStatus(poolFields map[string]map[string]interface{}) error {
+	out, err := z.run("zpool", "list", "-Hp", "-o",
+		"name,"+strings.Join(poolStatusFields, ","))
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) != 1+len(poolStatusFields) {
+			continue
+		}
+		fields, ok := poolFields[cols[0]]
+		if !ok {
+			fields = make(map[string]interface{})
+			poolFields[cols[0]] = fields
+		}
+		fields["health"] = cols[1]
+		for i, name := range poolStatusFields[1:] {
+			value := strings.TrimSuffix(strings.TrimSuffix(cols[2+i], "%"),
+				"x")
+			if name == "dedupratio" {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					fields[name] = v
+				}
+				continue
+			}
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fields[name] = v
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// datasetFields are the fields of the columns of zfs list, after the name
+// of the dataset
+var datasetFields = []string{"avail", "used", "usedsnap", "usedds"}
+
+// gatherDatasets adds the usage of the filesystems and volumes, as listed by
+// zfs list -Hp, in bytes
+func (z *Zfs) gatherDatasets(acc telegraf.Accumulator) error {
+	out, err := z.run("zfs", "list", "-Hp", "-t", "filesystem,volume", "-o",
+		"name,"+strings.Join(datasetFields, ","))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) != 1+len(datasetFields) {
+			continue
+		}
+		fields := make(map[string]interface{})
+		for i, name := range datasetFields {
+			if v, err := strconv.ParseInt(cols[1+i], 10, 64); err == nil {
+				fields[name] = v
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		acc.AddFields("zfs_dataset", fields,
+			map[string]string{"dataset": cols[0]}, now)
+	}
+	return scanner.Err()
+}
+
+// sysctlPools returns the names of the pools on FreeBSD, which has no kstat
+// files, as listed by zpool list
+func (z *Zfs) sysctlPools() ([]poolInfo, error) {
+	out, err := z.run("zpool", "list", "-H", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+	var pools []poolInfo
+	for _, name := range strings.Fields(out) {
+		pools = append(pools, poolInfo{name: name})
+	}
+	return pools, nil
+}
+
+// gatherSysctl adds the stats of the kstat metrics on FreeBSD to the fields,
+// read with sysctl, listed as
+//
+//	kstat.zfs.misc.arcstats.hits: 5968846374
+func (z *Zfs) gatherSysctl(metric string, fields map[string]interface{}) error {
+	prefix := "kstat.zfs.misc." + metric + "."
+	out, err := z.run("sysctl", "-q", strings.TrimSuffix(prefix, "."))
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], prefix) {
+			continue
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			continue
+		}
+		fields[metric+"_"+strings.TrimPrefix(parts[0], prefix)] = value
+	}
+	return scanner.Err()
+}
+
+// run runs the command, returning its output with its error if it fails
+func (z *Zfs) run(path string, args ...string) (string, error) {
+	out, err := z.runner(z.Timeout.Duration, path, args...)
+	if err != nil {
+		return "", fmt.Errorf("running %s: %s: %s", path, err,
+			strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
 func (z *Zfs) Gather(acc telegraf.Accumulator) error {
+	if z.runner == nil {
+		z.runner = runCommand
+	}
+	if z.Timeout.Duration == 0 {
+		z.Timeout.Duration = 5 * time.Second
+	}
 	kstatMetrics := z.KstatMetrics
 	if len(kstatMetrics) == 0 {
 		kstatMetrics = []string{"arcstats", "zfetchstats", "vdev_cache_stats"}
@@ -113,17 +274,27 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 		kstatPath = "/proc/spl/kstat/zfs"
 	}
 
+	if runtime.GOOS == "freebsd" {
+		return z.gatherFreeBSD(kstatMetrics, acc)
+	}
+
 	pools := getPools(kstatPath)
 	tags := getTags(pools)
 
+	poolFields := make(map[string]map[string]interface{})
 	if z.PoolMetrics {
 		for _, pool := range pools {
-			err := gatherPoolStats(pool, acc)
+			fields := make(map[string]interface{})
+			err := gatherPoolStats(pool, fields)
 			if err != nil {
 				return err
 			}
+			poolFields[pool.name] = fields
 		}
 	}
+	if err := z.gatherPools(poolFields, acc); err != nil {
+		return err
+	}
 
 	fields := make(map[string]interface{})
 	for _, metric := range kstatMetrics {
@@ -146,11 +317,81 @@ func (z *Zfs) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 	acc.AddFields("zfs", fields, tags)
+
+	if z.DatasetMetrics {
+		return z.gatherDatasets(acc)
+	}
+	return nil
+}
+
+// gatherFreeBSD gathers the stats with sysctl, the io stats of the pools not
+// being available on FreeBSD
+func (z *Zfs) gatherFreeBSD(kstatMetrics []string, acc telegraf.Accumulator) error {
+	pools, err := z.sysctlPools()
+	if err != nil {
+		return err
+	}
+	tags := getTags(pools)
+
+	if err := z.gatherPools(make(map[string]map[string]interface{}),
+		acc); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	for _, metric := range kstatMetrics {
+		if err := z.gatherSysctl(metric, fields); err != nil {
+			return err
+		}
+	}
+	acc.AddFields("zfs", fields, tags)
+
+	if z.DatasetMetrics {
+		return z.gatherDatasets(acc)
+	}
+	return nil
+}
+
+// gatherPools adds a point per pool with its io stats, and its status if
+// poolStatus is set
+func (z *Zfs) gatherPools(poolFields map[string]map[string]interface{},
+	acc telegraf.Accumulator) error {
+	if z.PoolStatus {
+		if err := z.gatherPoolStatus(poolFields); err != nil {
+			return err
+		}
+	}
+	for pool, fields := range poolFields {
+		acc.AddFields("zfs_pool", fields, map[string]string{"pool": pool})
+	}
 	return nil
 }
 
+// runCommand runs the command, killing it after the timeout
+func runCommand(timeout time.Duration, path string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return out.String(), err
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+		return out.String(), fmt.Errorf("%s timed out after %s", path,
+			timeout)
+	}
+}
+
 func init() {
 	inputs.Add("zfs", func() telegraf.Input {
-		return &Zfs{}
+		return &Zfs{runner: runCommand}
 	})
 }
@@ -1,11 +1,15 @@
 package zfs
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -233,6 +237,133 @@ func TestZfsGeneratesMetrics(t *testing.T) {
 	require.NoError(t, err)
 }
 
+const zpoolListOutput = "HOME\tONLINE\t1992864825344\t1104526082048\t888338743296\t12\t55\t1.00\n" +
+	"STORAGE\tDEGRADED\t7971459301376\t2500000000000\t5471459301376\t-\t31\t1.27\n"
+
+const zfsListOutput = "HOME\t845937471488\t1104526082048\t0\t98304\n" +
+	"HOME/users\t845937471488\t1104525819904\t10485760\t1094040059904\n"
+
+const sysctlArcstatsOutput = `kstat.zfs.misc.arcstats.hits: 5968846374
+kstat.zfs.misc.arcstats.misses: 1659178751
+kstat.zfs.misc.arcstats.size: 16319887096
+`
+
+const zpoolListStatus = "zpool list -Hp -o name,health,size,allocated,free,fragmentation,capacity,dedupratio"
+
+const zfsListDatasets = "zfs list -Hp -t filesystem,volume -o name,avail,used,usedsnap,usedds"
+
+// mockRunner returns the outputs of the commands, by command line
+func mockRunner(calls *[]string, outputs map[string]string) Runner {
+	return func(timeout time.Duration, path string, args ...string) (string, error) {
+		command := path + " " + strings.Join(args, " ")
+		*calls = append(*calls, command)
+		out, ok := outputs[command]
+		if !ok {
+			return "command not found", errors.New("exit status 127")
+		}
+		return out, nil
+	}
+}
+
+func TestZfsPoolStatusAndDatasets(t *testing.T) {
+	err := os.MkdirAll(testKstatPath+"/HOME", 0755)
+	require.NoError(t, err)
+	defer os.RemoveAll(os.TempDir() + "/telegraf")
+
+	err = ioutil.WriteFile(testKstatPath+"/HOME/io", []byte(pool_ioContents), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(testKstatPath+"/arcstats", []byte(arcstatsContents), 0644)
+	require.NoError(t, err)
+
+	var calls []string
+	z := &Zfs{
+		KstatPath:      testKstatPath,
+		KstatMetrics:   []string{"arcstats"},
+		PoolMetrics:    true,
+		PoolStatus:     true,
+		DatasetMetrics: true,
+		runner: mockRunner(&calls, map[string]string{
+			zpoolListStatus: zpoolListOutput,
+			zfsListDatasets: zfsListOutput,
+		}),
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, z.Gather(&acc))
+
+	assert.Equal(t, []string{zpoolListStatus, zfsListDatasets}, calls)
+
+	// The status is added to the io stats of the pool
+	poolMetrics := getPoolMetrics()
+	poolMetrics["health"] = "ONLINE"
+	poolMetrics["size"] = int64(1992864825344)
+	poolMetrics["allocated"] = int64(1104526082048)
+	poolMetrics["free"] = int64(888338743296)
+	poolMetrics["fragmentation"] = int64(12)
+	poolMetrics["capacity"] = int64(55)
+	poolMetrics["dedupratio"] = 1.0
+	acc.AssertContainsTaggedFields(t, "zfs_pool", poolMetrics,
+		map[string]string{"pool": "HOME"})
+	// The fragmentation is unknown
+	acc.AssertContainsTaggedFields(t, "zfs_pool", map[string]interface{}{
+		"health":     "DEGRADED",
+		"size":       int64(7971459301376),
+		"allocated":  int64(2500000000000),
+		"free":       int64(5471459301376),
+		"capacity":   int64(31),
+		"dedupratio": 1.27,
+	}, map[string]string{"pool": "STORAGE"})
+
+	acc.AssertContainsTaggedFields(t, "zfs_dataset", map[string]interface{}{
+		"avail":    int64(845937471488),
+		"used":     int64(1104525819904),
+		"usedsnap": int64(10485760),
+		"usedds":   int64(1094040059904),
+	}, map[string]string{"dataset": "HOME/users"})
+	assert.Equal(t, 5, len(acc.Metrics))
+
+	// The errors of zpool are returned
+	z.runner = mockRunner(&calls, nil)
+	err = z.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "running zpool")
+}
+
+func TestZfsFreeBSD(t *testing.T) {
+	var calls []string
+	z := &Zfs{
+		PoolStatus: true,
+		runner: mockRunner(&calls, map[string]string{
+			"zpool list -H -o name":             "HOME\n",
+			zpoolListStatus:                     zpoolListOutput,
+			"sysctl -q kstat.zfs.misc.arcstats": sysctlArcstatsOutput,
+		}),
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, z.gatherFreeBSD([]string{"arcstats"}, &acc))
+
+	assert.Equal(t, []string{
+		"zpool list -H -o name",
+		zpoolListStatus,
+		"sysctl -q kstat.zfs.misc.arcstats",
+	}, calls)
+	acc.AssertContainsTaggedFields(t, "zfs", map[string]interface{}{
+		"arcstats_hits":   int64(5968846374),
+		"arcstats_misses": int64(1659178751),
+		"arcstats_size":   int64(16319887096),
+	}, map[string]string{"pools": "HOME"})
+	// The pools have no io stats on FreeBSD
+	acc.AssertContainsTaggedFields(t, "zfs_pool", map[string]interface{}{
+		"health":        "ONLINE",
+		"size":          int64(1992864825344),
+		"allocated":     int64(1104526082048),
+		"free":          int64(888338743296),
+		"fragmentation": int64(12),
+		"capacity":      int64(55),
+		"dedupratio":    1.0,
+	}, map[string]string{"pool": "HOME"})
+}
+
 func getKstatMetricsArcOnly() map[string]interface{} {
 	return map[string]interface{}{
 		"arcstats_hits":                     int64(5968846374),
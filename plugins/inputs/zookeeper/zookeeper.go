@@ -2,34 +2,58 @@ package zookeeper
 
 import (
 	"bufio"
+	"crypto/tls"
 	"fmt"
 	"net"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 // Zookeeper is a zookeeper plugin
 type Zookeeper struct {
 	Servers []string
+	Timeout internal.Duration
+
+	// TLS options of the servers, see internal.GetTLSConfig
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	tlsConfig *tls.Config
 }
 
 var sampleConfig = `
   # An array of address to gather stats about. Specify an ip or hostname
   # with port. ie localhost:2181, 10.0.0.1:2181, etc.
+  # Servers listening with TLS are given with a "tls://" prefix, ie
+  # tls://10.0.0.1:2281
 
   # If no servers are specified, then localhost is used as the host.
   # If no port is specified, 2181 is used
   servers = [":2181"]
+
+  # Timeout of the connections and the mntr command, 5s by default
+  # timeout = "5s"
+
+  # TLS options of the servers, TLS is used for all the servers if any is set
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
 `
 
 var defaultTimeout = time.Second * time.Duration(5)
 
+var mntrLine = regexp.MustCompile(`^zk_(\w+)\s+([\w\.\-]+)`)
+
 // SampleConfig returns sample configuration message
 func (z *Zookeeper) SampleConfig() string {
 	return sampleConfig
@@ -40,49 +64,91 @@ func (z *Zookeeper) Description() string {
 	return `Reads 'mntr' stats from one or many zookeeper servers`
 }
 
+// Init loads the TLS config of the servers
+func (z *Zookeeper) Init() error {
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              z.SSLCA,
+		SSLCert:            z.SSLCert,
+		SSLKey:             z.SSLKey,
+		InsecureSkipVerify: z.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	z.tlsConfig = tlsConfig
+	return nil
+}
+
 // Gather reads stats from all configured servers accumulates stats
 func (z *Zookeeper) Gather(acc telegraf.Accumulator) error {
 	if len(z.Servers) == 0 {
 		return nil
 	}
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var outerr error
 
 	for _, serverAddress := range z.Servers {
-		if err := z.gatherServer(serverAddress, acc); err != nil {
-			return err
-		}
+		wg.Add(1)
+		go func(serverAddress string) {
+			defer wg.Done()
+			if err := z.gatherServer(serverAddress, acc); err != nil {
+				errMu.Lock()
+				outerr = err
+				errMu.Unlock()
+			}
+		}(serverAddress)
 	}
-	return nil
+
+	wg.Wait()
+	return outerr
 }
 
 func (z *Zookeeper) gatherServer(address string, acc telegraf.Accumulator) error {
+	useTLS := z.tlsConfig != nil || strings.HasPrefix(address, "tls://")
+	address = strings.TrimPrefix(address, "tls://")
+
 	_, _, err := net.SplitHostPort(address)
 	if err != nil {
 		address = address + ":2181"
 	}
 
-	c, err := net.DialTimeout("tcp", address, defaultTimeout)
+	timeout := z.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var c net.Conn
+	if useTLS {
+		tlsConfig := z.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		c, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		c, err = dialer.Dial("tcp", address)
+	}
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
 		return err
 	}
 	defer c.Close()
+	c.SetDeadline(time.Now().Add(timeout))
 
 	fmt.Fprintf(c, "%s\n", "mntr")
 	rdr := bufio.NewReader(c)
 	scanner := bufio.NewScanner(rdr)
 
-	service := strings.Split(address, ":")
-	if len(service) != 2 {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
 		return fmt.Errorf("Invalid service address: %s", address)
 	}
-	tags := map[string]string{"server": service[0], "port": service[1]}
+	tags := map[string]string{"server": host, "port": port}
 
 	fields := make(map[string]interface{})
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		re := regexp.MustCompile(`^zk_(\w+)\s+([\w\.\-]+)`)
-		parts := re.FindStringSubmatch(string(line))
+		parts := mntrLine.FindStringSubmatch(string(line))
 
 		if len(parts) != 3 {
 			return fmt.Errorf("unexpected line in mntr response: %q", line)
@@ -98,6 +164,15 @@ func (z *Zookeeper) gatherServer(address string, acc telegraf.Accumulator) error
 			fields[measurement] = sValue
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading mntr response of %s: %s", address, err)
+	}
+
+	// The state of the server in the ensemble, leader, follower, observer
+	// or standalone
+	if state, ok := fields["server_state"].(string); ok {
+		tags["state"] = state
+	}
 	acc.AddFields("zookeeper", fields, tags)
 
 	return nil
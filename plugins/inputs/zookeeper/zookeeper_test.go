@@ -1,6 +1,12 @@
 package zookeeper
 
 import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
@@ -16,6 +22,7 @@ func TestZookeeperGeneratesMetrics(t *testing.T) {
 	z := &Zookeeper{
 		Servers: []string{testutil.GetLocalHost() + ":2181"},
 	}
+	require.NoError(t, z.Init())
 
 	var acc testutil.Accumulator
 
@@ -41,3 +48,117 @@ func TestZookeeperGeneratesMetrics(t *testing.T) {
 		assert.True(t, acc.HasIntField("zookeeper", metric), metric)
 	}
 }
+
+// serveMntr answers the mntr commands of the connections of l with
+// mntrOutputSample
+func serveMntr(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(c net.Conn) {
+			defer c.Close()
+			line, err := bufio.NewReader(c).ReadString('\n')
+			if err != nil || line != "mntr\n" {
+				return
+			}
+			fmt.Fprint(c, mntrOutputSample)
+		}(c)
+	}
+}
+
+func TestZookeeperGatherServers(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go serveMntr(l)
+
+	// The certificate of the TLS server is the one of httptest
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+	tl, err := tls.Listen("tcp", "127.0.0.1:0",
+		&tls.Config{Certificates: ts.TLS.Certificates})
+	require.NoError(t, err)
+	defer tl.Close()
+	go serveMntr(tl)
+
+	z := &Zookeeper{
+		Servers: []string{l.Addr().String()},
+	}
+	require.NoError(t, z.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, z.Gather(&acc))
+
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	tags := map[string]string{
+		"server": host,
+		"port":   port,
+		"state":  "leader",
+	}
+	fields := map[string]interface{}{
+		"version":                    "3.4.6-1569965",
+		"avg_latency":                int64(0),
+		"max_latency":                int64(3),
+		"min_latency":                int64(0),
+		"packets_received":           int64(70),
+		"packets_sent":               int64(69),
+		"outstanding_requests":       int64(0),
+		"server_state":               "leader",
+		"znode_count":                int64(4),
+		"watch_count":                int64(0),
+		"ephemerals_count":           int64(0),
+		"approximate_data_size":      int64(27),
+		"followers":                  int64(2),
+		"synced_followers":           int64(2),
+		"pending_syncs":              int64(0),
+		"open_file_descriptor_count": int64(23),
+		"max_file_descriptor_count":  int64(1024),
+	}
+	acc.AssertContainsTaggedFields(t, "zookeeper", fields, tags)
+
+	// Servers with a "tls://" prefix are gathered with TLS, verifying their
+	// certificate
+	z = &Zookeeper{
+		Servers: []string{
+			l.Addr().String(),
+			"tls://" + tl.Addr().String(),
+		},
+	}
+	require.NoError(t, z.Init())
+	acc = testutil.Accumulator{}
+	require.Error(t, z.Gather(&acc))
+	assert.Len(t, acc.Metrics, 1)
+
+	// All the servers are gathered with TLS once a TLS option is set
+	z = &Zookeeper{
+		Servers:            []string{tl.Addr().String()},
+		InsecureSkipVerify: true,
+	}
+	require.NoError(t, z.Init())
+	acc = testutil.Accumulator{}
+	require.NoError(t, z.Gather(&acc))
+	_, tags["port"], err = net.SplitHostPort(tl.Addr().String())
+	require.NoError(t, err)
+	acc.AssertContainsTaggedFields(t, "zookeeper", fields, tags)
+}
+
+const mntrOutputSample = `zk_version	3.4.6-1569965, built on 02/20/2014 09:09 GMT
+zk_avg_latency	0
+zk_max_latency	3
+zk_min_latency	0
+zk_packets_received	70
+zk_packets_sent	69
+zk_outstanding_requests	0
+zk_server_state	leader
+zk_znode_count	4
+zk_watch_count	0
+zk_ephemerals_count	0
+zk_approximate_data_size	27
+zk_followers	2
+zk_synced_followers	2
+zk_pending_syncs	0
+zk_open_file_descriptor_count	23
+zk_max_file_descriptor_count	1024
+`
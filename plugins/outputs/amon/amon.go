@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
@@ -14,9 +13,13 @@ import (
 )
 
 type Amon struct {
-	ServerKey    string
-	AmonInstance string
-	Timeout      internal.Duration
+	ServerKey      string
+	AmonInstance   string
+	Timeout        internal.Duration
+	HTTPProxy      string          `toml:"http_proxy"`
+	HTTPSProxy     string          `toml:"https_proxy"`
+	UseSystemProxy bool            `toml:"use_system_proxy"`
+	Log            telegraf.Logger `toml:"-"`
 
 	client *http.Client
 }
@@ -30,6 +33,13 @@ var sampleConfig = `
 
   # Connection timeout.
   # timeout = "5s"
+
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
 `
 
 type TimeSeries struct {
@@ -47,9 +57,16 @@ func (a *Amon) Connect() error {
 	if a.ServerKey == "" || a.AmonInstance == "" {
 		return fmt.Errorf("serverkey and amon_instance are required fields for amon output")
 	}
-	a.client = &http.Client{
-		Timeout: a.Timeout.Duration,
+	client, err := internal.NewHTTPClient(a.Timeout.Duration,
+		internal.HTTPProxyOptions{
+			HTTPProxy:      a.HTTPProxy,
+			HTTPSProxy:     a.HTTPSProxy,
+			UseSystemProxy: a.UseSystemProxy,
+		})
+	if err != nil {
+		return err
 	}
+	a.client = client
 	return nil
 }
 
@@ -73,7 +90,7 @@ func (a *Amon) Write(metrics []telegraf.Metric) error {
 				metricCounter++
 			}
 		} else {
-			log.Printf("unable to build Metric for %s, skipping\n", m.Name())
+			a.Log.Warnf("Unable to build Metric for %s, skipping", m.Name())
 		}
 	}
 
@@ -152,6 +169,6 @@ func (a *Amon) Close() error {
 
 func init() {
 	outputs.Add("amon", func() telegraf.Output {
-		return &Amon{}
+		return &Amon{UseSystemProxy: true}
 	})
 }
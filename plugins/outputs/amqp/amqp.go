@@ -1,43 +1,80 @@
 package amqp
 
 import (
-	"bytes"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/streadway/amqp"
 )
 
+// deliveryModes are the delivery modes of the messages
+var deliveryModes = map[string]uint8{
+	"":           amqp.Transient,
+	"transient":  amqp.Transient,
+	"persistent": amqp.Persistent,
+}
+
 type AMQP struct {
 	// AMQP brokers to send metrics to
 	URL string
-	// AMQP exchange
-	Exchange string
+	// AMQP exchange, and its type
+	Exchange     string
+	ExchangeType string `toml:"exchange_type"`
+	// Credentials, instead of the ones of the URL
+	Username string
+	Password string
 	// path to CA file
 	SslCa string
 	// path to host cert file
 	SslCert string
 	// path to cert key file
 	SslKey string
+	// path to PKCS#12 bundle of the cert and key, instead of SslCert and
+	// SslKey
+	SSLPKCS12 string `toml:"ssl_pkcs12"`
+	// password of an encrypted key or of the PKCS#12 bundle
+	SSLKeyPassword string `toml:"ssl_key_password"`
+	// Name verified against the certificate of the server, the host of
+	// the connection if empty
+	TLSServerName string `toml:"tls_server_name"`
+	// Allowed TLS versions and cipher suites
+	TLSMinVersion   string   `toml:"tls_min_version"`
+	TLSMaxVersion   string   `toml:"tls_max_version"`
+	TLSCipherSuites []string `toml:"tls_cipher_suites"`
 	// Routing Key Tag
 	RoutingTag string `toml:"routing_tag"`
+	// Routing key of the metrics without the routing tag
+	RoutingKey string `toml:"routing_key"`
+	// Delivery mode of the messages, "transient" or "persistent"
+	DeliveryMode string `toml:"delivery_mode"`
+	// Wait for the broker to confirm the messages, the write failing if
+	// they are not confirmed within Timeout
+	PublisherConfirms bool              `toml:"publisher_confirms"`
+	Timeout           internal.Duration `toml:"timeout"`
 	// InfluxDB database
 	Database string
 	// InfluxDB retention policy
 	RetentionPolicy string
 	// InfluxDB precision
 	Precision string
+	// Data format of the messages
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
 
-	channel *amqp.Channel
+	connection *amqp.Connection
+	channel    *amqp.Channel
+	confirms   chan amqp.Confirmation
+	// closed is set by Close, the connection not being reopened then
+	closed bool
 	sync.Mutex
-	headers amqp.Table
+	headers      amqp.Table
+	deliveryMode uint8
+	serializer   telegraf.Serializer
 }
 
 const (
@@ -49,16 +86,45 @@ const (
 var sampleConfig = `
   # AMQP url
   url = "amqp://localhost:5672/influxdb"
-  # AMQP exchange
+  # AMQP exchange, and its type, "topic", "direct", "fanout" or "headers"
   exchange = "telegraf"
+  # exchange_type = "topic"
+  # Credentials, instead of the ones of the url
+  # username = ""
+  # password = ""
+
   # Telegraf tag to use as a routing key
   #  ie, if this tag exists, it's value will be used as the routing key
   routing_tag = "host"
+  # Routing key of the metrics without the routing tag
+  # routing_key = "telegraf"
+
+  # Delivery mode of the messages, "transient" or "persistent", persistent
+  # messages surviving a restart of the broker in durable queues
+  # delivery_mode = "transient"
+
+  # Wait for the broker to confirm the messages, the write failing if they
+  # are not confirmed within the timeout
+  # publisher_confirms = false
+  # timeout = "5s"
 
   # Use ssl
   #ssl_ca = "/etc/telegraf/ca.pem"
   #ssl_cert = "/etc/telegraf/cert.pem"
   #ssl_key = "/etc/telegraf/key.pem"
+  # Cert and key bundled as PKCS#12, instead of ssl_cert and ssl_key
+  #ssl_pkcs12 = "/etc/telegraf/client.p12"
+  # Password of an encrypted key or PKCS#12 bundle, which can be read from a
+  # secret store, ie "@{vault:amqp_key_password}"
+  #ssl_key_password = ""
+  # Name verified against the certificate of the server, if it differs from
+  # the host of the url
+  #tls_server_name = "amqp.example.com"
+  # Minimum and maximum TLS versions, "1.0", "1.1" or "1.2"
+  #tls_min_version = "1.2"
+  #tls_max_version = "1.2"
+  # Allowed cipher suites, ie "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+  #tls_cipher_suites = []
 
   # InfluxDB retention policy
   #retention_policy = "default"
@@ -66,6 +132,9 @@ var sampleConfig = `
   #database = "telegraf"
   # InfluxDB precision
   #precision = "s"
+
+  # Data format of the messages, "influx" or "graphite"
+  # data_format = "influx"
 `
 
 func (q *AMQP) Connect() error {
@@ -77,59 +146,92 @@ func (q *AMQP) Connect() error {
 		"database":         q.Database,
 		"retention_policy": q.RetentionPolicy,
 	}
+	if q.ExchangeType == "" {
+		q.ExchangeType = "topic"
+	}
+	if q.Timeout.Duration == 0 {
+		q.Timeout.Duration = 5 * time.Second
+	}
+	deliveryMode, ok := deliveryModes[q.DeliveryMode]
+	if !ok {
+		return fmt.Errorf("invalid delivery_mode %q", q.DeliveryMode)
+	}
+	q.deliveryMode = deliveryMode
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: q.DataFormat,
+	})
+	if err != nil {
+		return err
+	}
+	q.serializer = serializer
 
-	var connection *amqp.Connection
-	var err error
-	if q.SslCert != "" && q.SslKey != "" {
-		// make new tls config
-		cfg := new(tls.Config)
-		if q.SslCa != "" {
-			// create ca pool
-			cfg.RootCAs = x509.NewCertPool()
-
-			// add self-signed cert
-			if ca, err := ioutil.ReadFile(q.SslCa); err == nil {
-				cfg.RootCAs.AppendCertsFromPEM(ca)
-			} else {
-				log.Println(err)
-			}
-		}
-		if cert, err := tls.LoadX509KeyPair(q.SslCert, q.SslKey); err == nil {
-			cfg.Certificates = append(cfg.Certificates, cert)
-		} else {
-			log.Println(err)
-		}
-		connection, err = amqp.DialTLS(q.URL, cfg)
-
-	} else {
-		connection, err = amqp.Dial(q.URL)
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:          q.SslCa,
+		SSLCert:        q.SslCert,
+		SSLKey:         q.SslKey,
+		SSLPKCS12:      q.SSLPKCS12,
+		SSLKeyPassword: q.SSLKeyPassword,
+		ServerName:     q.TLSServerName,
+		MinVersion:     q.TLSMinVersion,
+		MaxVersion:     q.TLSMaxVersion,
+		CipherSuites:   q.TLSCipherSuites,
+	})
+	if err != nil {
+		return err
+	}
+	config := amqp.Config{TLSClientConfig: tlsConfig}
+	if q.Username != "" || q.Password != "" {
+		config.SASL = []amqp.Authentication{&amqp.PlainAuth{
+			Username: q.Username,
+			Password: q.Password,
+		}}
 	}
+	connection, err := amqp.DialConfig(q.URL, config)
 	if err != nil {
 		return err
 	}
 	channel, err := connection.Channel()
 	if err != nil {
+		connection.Close()
 		return fmt.Errorf("Failed to open a channel: %s", err)
 	}
 
 	err = channel.ExchangeDeclare(
-		q.Exchange, // name
-		"topic",    // type
-		true,       // durable
-		false,      // delete when unused
-		false,      // internal
-		false,      // no-wait
-		nil,        // arguments
+		q.Exchange,     // name
+		q.ExchangeType, // type
+		true,           // durable
+		false,          // delete when unused
+		false,          // internal
+		false,          // no-wait
+		nil,            // arguments
 	)
 	if err != nil {
+		connection.Close()
 		return fmt.Errorf("Failed to declare an exchange: %s", err)
 	}
+	q.confirms = nil
+	if q.PublisherConfirms {
+		if err := channel.Confirm(false); err != nil {
+			connection.Close()
+			return fmt.Errorf("Failed to enable publisher confirms: %s", err)
+		}
+		q.confirms = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+	q.connection = connection
 	q.channel = channel
+	q.closed = false
 	go func() {
-		log.Printf("Closing: %s", <-connection.NotifyClose(make(chan *amqp.Error)))
-		log.Printf("Trying to reconnect")
+		err := <-connection.NotifyClose(make(chan *amqp.Error))
+		q.Lock()
+		closed := q.closed
+		q.Unlock()
+		if closed {
+			return
+		}
+		q.Log.Infof("Closing: %s", err)
+		q.Log.Info("Trying to reconnect")
 		for err := q.Connect(); err != nil; err = q.Connect() {
-			log.Println(err)
+			q.Log.Error(err)
 			time.Sleep(10 * time.Second)
 		}
 
@@ -138,7 +240,13 @@ func (q *AMQP) Connect() error {
 }
 
 func (q *AMQP) Close() error {
-	return q.channel.Close()
+	q.Lock()
+	defer q.Unlock()
+	q.closed = true
+	if q.connection == nil {
+		return nil
+	}
+	return q.connection.Close()
 }
 
 func (q *AMQP) SampleConfig() string {
@@ -149,25 +257,36 @@ func (q *AMQP) Description() string {
 	return "Configuration for the AMQP server to send metrics to"
 }
 
+// routingKey returns the routing key of the metric, the value of its routing
+// tag, or the routing key if it does not have it
+func (q *AMQP) routingKey(metric telegraf.Metric) string {
+	if q.RoutingTag != "" {
+		if h, ok := metric.Tags()[q.RoutingTag]; ok {
+			return h
+		}
+	}
+	return q.RoutingKey
+}
+
 func (q *AMQP) Write(metrics []telegraf.Metric) error {
 	q.Lock()
 	defer q.Unlock()
 	if len(metrics) == 0 {
 		return nil
 	}
-	var outbuf = make(map[string][][]byte)
+	if q.channel == nil {
+		return fmt.Errorf("not connected to %s", q.URL)
+	}
+	var outbuf = make(map[string][]byte)
 
 	for _, p := range metrics {
-		var value, key string
-		value = p.String()
-
-		if q.RoutingTag != "" {
-			if h, ok := p.Tags()[q.RoutingTag]; ok {
-				key = h
-			}
+		buf, err := q.serializer.Serialize(p)
+		if err != nil {
+			q.Log.Errorf("Could not serialize metric %s: %s", p.Name(), err)
+			continue
 		}
-		outbuf[key] = append(outbuf[key], []byte(value))
-
+		key := q.routingKey(p)
+		outbuf[key] = append(outbuf[key], buf...)
 	}
 	for key, buf := range outbuf {
 		err := q.channel.Publish(
@@ -176,13 +295,32 @@ func (q *AMQP) Write(metrics []telegraf.Metric) error {
 			false,      // mandatory
 			false,      // immediate
 			amqp.Publishing{
-				Headers:     q.headers,
-				ContentType: "text/plain",
-				Body:        bytes.Join(buf, []byte("\n")),
+				Headers:      q.headers,
+				ContentType:  "text/plain",
+				DeliveryMode: q.deliveryMode,
+				Body:         buf,
 			})
 		if err != nil {
 			return fmt.Errorf("FAILED to send amqp message: %s", err)
 		}
+		if q.confirms == nil {
+			continue
+		}
+		select {
+		case confirm, ok := <-q.confirms:
+			if !ok {
+				return fmt.Errorf("FAILED to send amqp message: channel closed")
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("FAILED to send amqp message: not acknowledged by the broker")
+			}
+		case <-time.After(q.Timeout.Duration):
+			// The confirmation could be received by the next write, the
+			// connection is reopened
+			q.connection.Close()
+			return fmt.Errorf("FAILED to send amqp message: not confirmed after %s",
+				q.Timeout.Duration)
+		}
 	}
 	return nil
 }
@@ -3,7 +3,10 @@ package amqp
 import (
 	"testing"
 
+	"github.com/influxdata/telegraf"
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,15 +17,50 @@ func TestConnectAndWrite(t *testing.T) {
 
 	var url = "amqp://" + testutil.GetLocalHost() + ":5672/"
 	q := &AMQP{
-		URL:      url,
-		Exchange: "telegraf_test",
+		URL:               url,
+		Exchange:          "telegraf_test",
+		DeliveryMode:      "persistent",
+		PublisherConfirms: true,
+		Log:               testutil.Logger{},
 	}
 
 	// Verify that we can connect to the AMQP broker
 	err := q.Connect()
 	require.NoError(t, err)
+	defer q.Close()
 
 	// Verify that we can successfully write data to the amqp broker
 	err = q.Write(testutil.MockMetrics())
 	require.NoError(t, err)
 }
+
+func TestRoutingKey(t *testing.T) {
+	m1, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"value": 1.0})
+	m2, _ := telegraf.NewMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1.0})
+
+	q := &AMQP{RoutingTag: "host", RoutingKey: "telegraf"}
+	assert.Equal(t, "web01", q.routingKey(m1))
+	assert.Equal(t, "telegraf", q.routingKey(m2))
+
+	q = &AMQP{RoutingKey: "telegraf"}
+	assert.Equal(t, "telegraf", q.routingKey(m1))
+	q = &AMQP{RoutingTag: "host"}
+	assert.Equal(t, "", q.routingKey(m2))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, q := range []*AMQP{
+		{DeliveryMode: "durable"},
+		{DataFormat: "unknown"},
+	} {
+		q.URL = "amqp://127.0.0.1:1/"
+		q.Log = testutil.Logger{}
+		assert.Error(t, q.Connect())
+	}
+
+	// Not connected
+	q := &AMQP{URL: "amqp://127.0.0.1:1/"}
+	assert.Error(t, q.Write(testutil.MockMetrics()))
+}
@@ -0,0 +1,157 @@
+package azure_monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// monitoringResource is the resource of the access tokens of the
+	// ingestion endpoints
+	monitoringResource = "https://monitoring.azure.com/"
+	// metadataURL is the instance metadata service of the Azure VMs
+	metadataURL = "http://169.254.169.254/metadata"
+)
+
+// tokenSource returns the access tokens of the requests, of a service
+// principal if its tenant, client ID and secret are set, else of the
+// managed identity of the VM, and keeps them until they expire
+type tokenSource struct {
+	client *http.Client
+	// Service principal
+	tenantID     string
+	clientID     string
+	clientSecret string
+	// loginURL and metadataURL are the endpoints of the tokens of the
+	// service principals and managed identities
+	loginURL    string
+	metadataURL string
+
+	sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a valid access token, requesting a new one a minute before
+// the current one expires
+func (ts *tokenSource) Token() (string, error) {
+	ts.Lock()
+	defer ts.Unlock()
+	if ts.token != "" && time.Now().Add(time.Minute).Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.clientSecret != "" {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {ts.clientID},
+			"client_secret": {ts.clientSecret},
+			"resource":      {monitoringResource},
+		}
+		req, err = http.NewRequest("POST",
+			fmt.Sprintf("%s/%s/oauth2/token", ts.loginURL, ts.tenantID),
+			strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		params := url.Values{
+			"api-version": {"2018-02-01"},
+			"resource":    {monitoringResource},
+		}
+		// The client ID selects a user assigned identity
+		if ts.clientID != "" {
+			params.Set("client_id", ts.clientID)
+		}
+		req, err = http.NewRequest("GET",
+			ts.metadataURL+"/identity/oauth2/token?"+params.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		// ExpiresIn is a number of seconds, as a string
+		ExpiresIn string `json:"expires_in"`
+	}
+	if err := getJSON(ts.client, req, &token); err != nil {
+		return "", fmt.Errorf("could not request an access token: %s", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access token in the response")
+	}
+	expiresIn, err := strconv.ParseInt(token.ExpiresIn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid expires_in %q of the access token",
+			token.ExpiresIn)
+	}
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// vmInstance returns the region and the resource ID of the VM from the
+// instance metadata service
+func vmInstance(client *http.Client, metadataURL string) (string, string,
+	error) {
+	req, err := http.NewRequest("GET",
+		metadataURL+"/instance?api-version=2017-12-01", nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Metadata", "true")
+	var instance struct {
+		Compute struct {
+			Location          string `json:"location"`
+			Name              string `json:"name"`
+			ResourceGroupName string `json:"resourceGroupName"`
+			SubscriptionID    string `json:"subscriptionId"`
+			VMScaleSetName    string `json:"vmScaleSetName"`
+		} `json:"compute"`
+	}
+	if err := getJSON(client, req, &instance); err != nil {
+		return "", "", fmt.Errorf("could not get the instance metadata: %s",
+			err)
+	}
+	c := instance.Compute
+	resourceID := fmt.Sprintf(
+		"/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/"+
+			"virtualMachines/%s", c.SubscriptionID, c.ResourceGroupName, c.Name)
+	if c.VMScaleSetName != "" {
+		resourceID = fmt.Sprintf(
+			"/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/"+
+				"virtualMachineScaleSets/%s", c.SubscriptionID,
+			c.ResourceGroupName, c.VMScaleSetName)
+	}
+	return c.Location, resourceID, nil
+}
+
+// getJSON decodes the JSON response of the request
+func getJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status,
+			strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,319 @@
+package azure_monitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	// maxRequestSize is the size of the uncompressed bodies of the requests
+	// past which the metrics are sent in another request
+	maxRequestSize = 4000000
+	// maxAge and maxFuture are the bounds of the times of the metrics
+	// accepted by the ingestion endpoints
+	maxAge    = 30 * time.Minute
+	maxFuture = 4 * time.Minute
+)
+
+type AzureMonitor struct {
+	// Region and ResourceID of the resource the metrics are written to,
+	// the ones of the VM from the instance metadata service if empty
+	Region     string `toml:"region"`
+	ResourceID string `toml:"resource_id"`
+	// EndpointURL overrides the regional ingestion endpoint
+	EndpointURL string `toml:"endpoint_url"`
+	// NamespacePrefix prefixes the measurements to make the namespaces of
+	// the metrics
+	NamespacePrefix string `toml:"namespace_prefix"`
+	Timeout         internal.Duration
+
+	// Service principal, the managed identity of the VM being used if the
+	// secret is empty, the ClientID selecting a user assigned identity
+	TenantID     string `toml:"tenant_id"`
+	ClientID     string `toml:"client_id"`
+	ClientSecret string `toml:"client_secret"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	tokens *tokenSource
+	url    string
+	// metadataURL and loginURL are the endpoints of the instance metadata
+	// and of the tokens of the service principals
+	metadataURL string
+	loginURL    string
+}
+
+var sampleConfig = `
+  # Region and resource ID of the resource the metrics are written to, the
+  # ones of the VM from the instance metadata service if not set
+  # region = "westeurope"
+  # resource_id = "/subscriptions/<subscription_id>/resourceGroups/<resource_group>/providers/Microsoft.Compute/virtualMachines/<vm_name>"
+  # Ingestion endpoint, instead of https://<region>.monitoring.azure.com
+  # endpoint_url = ""
+
+  # Prefix of the namespaces of the metrics, <prefix><measurement>
+  # namespace_prefix = "Telegraf/"
+
+  # Service principal of the requests, the managed identity of the VM being
+  # used if the client_secret is not set, the client_id of a user assigned
+  # identity selecting it
+  # tenant_id = ""
+  # client_id = ""
+  # client_secret = ""
+
+  # Timeout of the requests
+  # timeout = "20s"
+`
+
+// series are the aggregates of the values of a set of dimensions
+type series struct {
+	DimValues []string `json:"dimValues,omitempty"`
+	Min       float64  `json:"min"`
+	Max       float64  `json:"max"`
+	Sum       float64  `json:"sum"`
+	Count     int64    `json:"count"`
+}
+
+type baseData struct {
+	Metric    string    `json:"metric"`
+	Namespace string    `json:"namespace"`
+	DimNames  []string  `json:"dimNames,omitempty"`
+	Series    []*series `json:"series"`
+}
+
+// customMetric is a metric of the custom metrics API, aggregated over a
+// minute
+type customMetric struct {
+	Time string `json:"time"`
+	Data struct {
+		BaseData baseData `json:"baseData"`
+	} `json:"data"`
+}
+
+func (a *AzureMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureMonitor) Description() string {
+	return "Send aggregated metrics to Azure Monitor"
+}
+
+func (a *AzureMonitor) Connect() error {
+	if a.NamespacePrefix == "" {
+		a.NamespacePrefix = "Telegraf/"
+	}
+	if a.Timeout.Duration == 0 {
+		a.Timeout.Duration = 20 * time.Second
+	}
+	if a.metadataURL == "" {
+		a.metadataURL = metadataURL
+	}
+	if a.loginURL == "" {
+		a.loginURL = "https://login.microsoftonline.com"
+	}
+	if a.ClientSecret != "" && (a.TenantID == "" || a.ClientID == "") {
+		return fmt.Errorf("tenant_id and client_id must be set with " +
+			"client_secret")
+	}
+
+	client, err := internal.NewHTTPClient(a.Timeout.Duration,
+		internal.HTTPProxyOptions{UseSystemProxy: true})
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	region, resourceID := a.Region, a.ResourceID
+	if resourceID == "" || (region == "" && a.EndpointURL == "") {
+		r, id, err := vmInstance(client, a.metadataURL)
+		if err != nil {
+			return fmt.Errorf("no region and resource_id set, and %s", err)
+		}
+		if region == "" {
+			region = r
+		}
+		if resourceID == "" {
+			resourceID = id
+		}
+	}
+	endpoint := a.EndpointURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.monitoring.azure.com", region)
+	}
+	a.url = strings.TrimSuffix(endpoint, "/") + resourceID + "/metrics"
+
+	a.tokens = &tokenSource{
+		client:       client,
+		tenantID:     a.TenantID,
+		clientID:     a.ClientID,
+		clientSecret: a.ClientSecret,
+		loginURL:     a.loginURL,
+		metadataURL:  a.metadataURL,
+	}
+	a.Log.Infof("Writing to %s", a.url)
+	return nil
+}
+
+func (a *AzureMonitor) Close() error {
+	return nil
+}
+
+// floatValue returns the value of a field as a float, false if it has no
+// numeric representation
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// aggregate returns the custom metrics of the metrics, each field being
+// aggregated per minute and set of tags, the dimensions of the metric
+func (a *AzureMonitor) aggregate(metrics []telegraf.Metric,
+	now time.Time) []*customMetric {
+	var result []*customMetric
+	byKey := make(map[string]*customMetric)
+	seriesByKey := make(map[string]*series)
+
+	for _, m := range metrics {
+		if m.Time().Before(now.Add(-maxAge)) ||
+			m.Time().After(now.Add(maxFuture)) {
+			a.Log.Debugf("Dropping metric %s at %s, out of the time range "+
+				"accepted by Azure Monitor", m.Name(), m.Time())
+			continue
+		}
+		minute := m.Time().UTC().Truncate(time.Minute).Format(time.RFC3339)
+		dimNames := make([]string, 0, len(m.Tags()))
+		for k := range m.Tags() {
+			dimNames = append(dimNames, k)
+		}
+		sort.Strings(dimNames)
+		dimValues := make([]string, len(dimNames))
+		for i, k := range dimNames {
+			dimValues[i] = m.Tags()[k]
+		}
+		namespace := a.NamespacePrefix + m.Name()
+
+		for field, v := range m.Fields() {
+			value, ok := floatValue(v)
+			if !ok {
+				continue
+			}
+			// A custom metric per minute, metric and dimension names
+			key := strings.Join(append([]string{minute, namespace, field},
+				dimNames...), "\xff")
+			cm, ok := byKey[key]
+			if !ok {
+				cm = &customMetric{Time: minute}
+				cm.Data.BaseData = baseData{
+					Metric:    field,
+					Namespace: namespace,
+					DimNames:  dimNames,
+				}
+				byKey[key] = cm
+				result = append(result, cm)
+			}
+			// And a series per dimension values
+			skey := strings.Join(append([]string{key}, dimValues...),
+				"\xfe")
+			s, ok := seriesByKey[skey]
+			if !ok {
+				s = &series{DimValues: dimValues, Min: value, Max: value}
+				seriesByKey[skey] = s
+				cm.Data.BaseData.Series = append(cm.Data.BaseData.Series, s)
+			}
+			s.Min = math.Min(s.Min, value)
+			s.Max = math.Max(s.Max, value)
+			s.Sum += value
+			s.Count++
+		}
+	}
+	return result
+}
+
+func (a *AzureMonitor) Write(metrics []telegraf.Metric) error {
+	// The custom metrics are sent as JSON lines, in requests of up to
+	// maxRequestSize bytes
+	var body []byte
+	for _, cm := range a.aggregate(metrics, time.Now()) {
+		line, err := json.Marshal(cm)
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 && len(body)+len(line)+1 > maxRequestSize {
+			if err := a.send(body); err != nil {
+				return err
+			}
+			body = nil
+		}
+		body = append(append(body, line...), '\n')
+	}
+	if len(body) > 0 {
+		return a.send(body)
+	}
+	return nil
+}
+
+// send posts the custom metrics, gzipped
+func (a *AzureMonitor) send(body []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	token, err := a.tokens.Token()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", a.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not write to Azure Monitor: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not write to Azure Monitor: %s: %s",
+			resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("azure_monitor", func() telegraf.Output {
+		return &AzureMonitor{}
+	})
+}
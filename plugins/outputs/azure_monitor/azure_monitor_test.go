@@ -0,0 +1,134 @@
+package azure_monitor
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	now := time.Date(2016, time.January, 4, 3, 2, 30, 0, time.UTC)
+	m1, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 90.0, "state": "ok"}, now)
+	m2, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 80.0},
+		now.Add(10*time.Second))
+	m3, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu1"},
+		map[string]interface{}{"usage_idle": 70.0}, now)
+	// In the next minute
+	m4, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 60.0}, now.Add(time.Minute))
+	// Too old
+	m5, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 60.0}, now.Add(-time.Hour))
+
+	a := &AzureMonitor{NamespacePrefix: "Telegraf/", Log: testutil.Logger{}}
+	metrics := a.aggregate([]telegraf.Metric{m1, m2, m3, m4, m5}, now)
+	require.Len(t, metrics, 2)
+
+	cm := metrics[0]
+	assert.Equal(t, "2016-01-04T03:02:00Z", cm.Time)
+	assert.Equal(t, "usage_idle", cm.Data.BaseData.Metric)
+	assert.Equal(t, "Telegraf/cpu", cm.Data.BaseData.Namespace)
+	assert.Equal(t, []string{"cpu"}, cm.Data.BaseData.DimNames)
+	assert.Equal(t, []*series{
+		{DimValues: []string{"cpu0"}, Min: 80, Max: 90, Sum: 170, Count: 2},
+		{DimValues: []string{"cpu1"}, Min: 70, Max: 70, Sum: 70, Count: 1},
+	}, cm.Data.BaseData.Series)
+
+	cm = metrics[1]
+	assert.Equal(t, "2016-01-04T03:03:00Z", cm.Time)
+	assert.Equal(t, []*series{
+		{DimValues: []string{"cpu0"}, Min: 60, Max: 60, Sum: 60, Count: 1},
+	}, cm.Data.BaseData.Series)
+}
+
+func TestWrite(t *testing.T) {
+	var lines []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/metadata/instance":
+				assert.Equal(t, "true", r.Header.Get("Metadata"))
+				fmt.Fprint(w, `{"compute":{"location":"westeurope",`+
+					`"name":"vm01","resourceGroupName":"rg",`+
+					`"subscriptionId":"sub"}}`)
+			case "/metadata/identity/oauth2/token":
+				assert.Equal(t, "true", r.Header.Get("Metadata"))
+				assert.Equal(t, monitoringResource,
+					r.URL.Query().Get("resource"))
+				fmt.Fprint(w, `{"access_token":"secret","expires_in":"3600"}`)
+			case "/subscriptions/sub/resourceGroups/rg/providers/" +
+				"Microsoft.Compute/virtualMachines/vm01/metrics":
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+				gz, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				scanner := bufio.NewScanner(gz)
+				for scanner.Scan() {
+					var line map[string]interface{}
+					require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+					lines = append(lines, line)
+				}
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer ts.Close()
+
+	a := &AzureMonitor{
+		EndpointURL: ts.URL,
+		Log:         testutil.Logger{},
+		metadataURL: ts.URL + "/metadata",
+	}
+	require.NoError(t, a.Connect())
+	m, _ := telegraf.NewMetric("mem", map[string]string{},
+		map[string]interface{}{"used_percent": 42.0, "free": int64(1024)},
+		time.Now())
+	require.NoError(t, a.Write([]telegraf.Metric{m}))
+	assert.Len(t, lines, 2)
+}
+
+func TestServicePrincipalToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/tenant/oauth2/token", r.URL.Path)
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "client", r.PostForm.Get("client_id"))
+			assert.Equal(t, "password", r.PostForm.Get("client_secret"))
+			fmt.Fprint(w, `{"access_token":"secret","expires_in":"3600"}`)
+		}))
+	defer ts.Close()
+
+	tokens := &tokenSource{
+		client:       http.DefaultClient,
+		tenantID:     "tenant",
+		clientID:     "client",
+		clientSecret: "password",
+		loginURL:     ts.URL,
+	}
+	token, err := tokens.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	a := &AzureMonitor{ClientSecret: "password", Log: testutil.Logger{}}
+	assert.Error(t, a.Connect())
+
+	// Without region and resource ID outside of Azure
+	a = &AzureMonitor{Log: testutil.Logger{},
+		metadataURL: "http://127.0.0.1:1/metadata"}
+	assert.Error(t, a.Connect())
+}
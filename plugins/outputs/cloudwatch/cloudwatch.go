@@ -1,27 +1,38 @@
 package cloudwatch
 
 import (
-	"log"
 	"math"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
-	"github.com/aws/aws-sdk-go/aws/ec2metadata"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/awsconfig"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
 type CloudWatch struct {
 	Region    string // AWS Region
 	Namespace string // CloudWatch Metrics Namespace
-	svc       *cloudwatch.CloudWatch
+
+	// Credentials, see awsconfig.Config
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	Token       string `toml:"token"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	HTTPProxy      string          `toml:"http_proxy"`
+	HTTPSProxy     string          `toml:"https_proxy"`
+	UseSystemProxy bool            `toml:"use_system_proxy"`
+	Log            telegraf.Logger `toml:"-"`
+	svc            *cloudwatch.CloudWatch
 }
 
 var sampleConfig = `
@@ -30,6 +41,29 @@ var sampleConfig = `
 
   # Namespace for the CloudWatch MetricDatums
   namespace = 'InfluxData/Telegraf'
+
+  # Credentials, in order of precedence:
+  # 1) access_key, secret_key and token
+  # 2) profile of the shared_credential_file
+  # 3) the credential chain: EC2 instance role, environment variables and
+  #    shared credentials file
+  # assuming the role_arn role if set
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  # Endpoint of the service, instead of the one of the region
+  # endpoint_url = ""
+
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
 `
 
 func (c *CloudWatch) SampleConfig() string {
@@ -41,26 +75,35 @@ func (c *CloudWatch) Description() string {
 }
 
 func (c *CloudWatch) Connect() error {
-	Config := &aws.Config{
-		Region: aws.String(c.Region),
-		Credentials: credentials.NewChainCredentials(
-			[]credentials.Provider{
-				&ec2rolecreds.EC2RoleProvider{Client: ec2metadata.New(session.New())},
-				&credentials.EnvProvider{},
-				&credentials.SharedCredentialsProvider{},
-			}),
+	config := &awsconfig.Config{
+		Region:      c.Region,
+		AccessKey:   c.AccessKey,
+		SecretKey:   c.SecretKey,
+		Token:       c.Token,
+		RoleARN:     c.RoleARN,
+		Profile:     c.Profile,
+		Filename:    c.Filename,
+		EndpointURL: c.EndpointURL,
+		Proxy: internal.HTTPProxyOptions{
+			HTTPProxy:      c.HTTPProxy,
+			HTTPSProxy:     c.HTTPSProxy,
+			UseSystemProxy: c.UseSystemProxy,
+		},
 	}
-
-	svc := cloudwatch.New(session.New(Config))
+	session, err := config.NewSession()
+	if err != nil {
+		return err
+	}
+	svc := cloudwatch.New(session)
 
 	params := &cloudwatch.ListMetricsInput{
 		Namespace: aws.String(c.Namespace),
 	}
 
-	_, err := svc.ListMetrics(params) // Try a read-only call to test connection.
+	_, err = svc.ListMetrics(params) // Try a read-only call to test connection.
 
 	if err != nil {
-		log.Printf("cloudwatch: Error in ListMetrics API call : %+v \n", err.Error())
+		c.Log.Errorf("Error in ListMetrics API call: %s", err)
 	}
 
 	c.svc = svc
@@ -111,7 +154,7 @@ func (c *CloudWatch) WriteToCloudWatch(datums []*cloudwatch.MetricDatum) error {
 	_, err := c.svc.PutMetricData(params)
 
 	if err != nil {
-		log.Printf("CloudWatch: Unable to write to CloudWatch : %+v \n", err.Error())
+		c.Log.Errorf("Unable to write to CloudWatch: %s", err)
 	}
 
 	return err
@@ -142,7 +185,8 @@ func PartitionDatums(size int, datums []*cloudwatch.MetricDatum) [][]*cloudwatch
 }
 
 // Make a MetricDatum for each field in a Point. Only fields with values that can be
-// converted to float64 are supported. Non-supported fields are skipped.
+// converted to float64 are supported. Non-supported fields are skipped. The
+// fields of counters have the Count unit.
 func BuildMetricDatum(point telegraf.Metric) []*cloudwatch.MetricDatum {
 	datums := make([]*cloudwatch.MetricDatum, len(point.Fields()))
 	i := 0
@@ -179,6 +223,9 @@ func BuildMetricDatum(point telegraf.Metric) []*cloudwatch.MetricDatum {
 			Dimensions: BuildDimensions(point.Tags()),
 			Timestamp:  aws.Time(point.Time()),
 		}
+		if point.Type() == telegraf.Counter {
+			datums[i].Unit = aws.String(cloudwatch.StandardUnitCount)
+		}
 
 		i += 1
 	}
@@ -231,6 +278,6 @@ func BuildDimensions(mTags map[string]string) []*cloudwatch.Dimension {
 
 func init() {
 	outputs.Add("cloudwatch", func() telegraf.Output {
-		return &CloudWatch{}
+		return &CloudWatch{UseSystemProxy: true}
 	})
 }
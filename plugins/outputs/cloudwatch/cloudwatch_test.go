@@ -69,6 +69,17 @@ func TestBuildMetricDatums(t *testing.T) {
 	assert.Equal(0, len(BuildMetricDatum(nonValidPoint)), "Invalid type should not create a Datum")
 }
 
+func TestBuildMetricDatumsUnit(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := map[string]interface{}{"value": int64(1)}
+	gauge, _ := telegraf.NewGaugeMetric("test1", nil, fields)
+	counter, _ := telegraf.NewCounterMetric("test1", nil, fields)
+
+	assert.Nil(BuildMetricDatum(gauge)[0].Unit)
+	assert.Equal("Count", *BuildMetricDatum(counter)[0].Unit)
+}
+
 func TestPartitionDatums(t *testing.T) {
 
 	assert := assert.New(t)
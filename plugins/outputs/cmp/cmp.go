@@ -17,6 +17,9 @@ type Cmp struct {
     ResourceId   string
 	CmpInstance  string
 	Timeout      internal.Duration
+	HTTPProxy      string `toml:"http_proxy"`
+	HTTPSProxy     string `toml:"https_proxy"`
+	UseSystemProxy bool   `toml:"use_system_proxy"`
     Headers      []string
 
 	client *http.Client
@@ -33,6 +36,13 @@ var sampleConfig = `
   # Connection timeout.
   # timeout = "5s"
 
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
+
   headers = ["X-Header1:12345", "X-Header2:23456"]
 `
 
@@ -103,9 +113,16 @@ func (a *Cmp) Connect() error {
 	if a.ServerKey == "" || a.CmpInstance == "" || a.ResourceId == "" {
 		return fmt.Errorf("server_key, resource_id and cmp_instance are required fields for cmp output")
 	}
-	a.client = &http.Client{
-		Timeout: a.Timeout.Duration,
+	client, err := internal.NewHTTPClient(a.Timeout.Duration,
+		internal.HTTPProxyOptions{
+			HTTPProxy:      a.HTTPProxy,
+			HTTPSProxy:     a.HTTPSProxy,
+			UseSystemProxy: a.UseSystemProxy,
+		})
+	if err != nil {
+		return err
 	}
+	a.client = client
 	return nil
 }
 
@@ -200,6 +217,6 @@ func (a *Cmp) Close() error {
 
 func init() {
 	outputs.Add("cmp", func() telegraf.Output {
-		return &Cmp{}
+		return &Cmp{UseSystemProxy: true}
 	})
 }
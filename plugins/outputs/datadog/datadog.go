@@ -2,9 +2,11 @@ package datadog
 
 import (
 	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
@@ -12,15 +14,33 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
 type Datadog struct {
-	Apikey  string
-	Timeout internal.Duration
+	Apikey         string
+	Timeout        internal.Duration
+	KeepAlive      internal.Duration `toml:"keep_alive"`
+	MaxIdleConns   int               `toml:"max_idle_conns"`
+	Headers        map[string]string
+	HTTPProxy      string `toml:"http_proxy"`
+	HTTPSProxy     string `toml:"https_proxy"`
+	UseSystemProxy bool   `toml:"use_system_proxy"`
+	// CounterType is the type of the metrics of the counters, "count" or
+	// "rate", the increase of the counters per second
+	CounterType string `toml:"counter_type"`
+	// Compression is the encoding of the bodies of the requests, "zlib",
+	// "gzip", or "none"
+	Compression string          `toml:"compression"`
+	Log         telegraf.Logger `toml:"-"`
 
 	apiUrl string
 	client *http.Client
+
+	// counters holds the last written point of every counter series, the
+	// values of counters being sent as their increase since then
+	counters map[string]Point
 }
 
 var sampleConfig = `
@@ -29,6 +49,28 @@ var sampleConfig = `
 
   # Connection timeout.
   # timeout = "5s"
+  # Period of the TCP keep-alives, and idle connections kept open for the
+  # next writes, -1 to close the connection after every write
+  # keep_alive = "30s"
+  # max_idle_conns = 2
+
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
+
+  # Type of the metrics of the counters, sent as their increase since their
+  # previous point, "count", or "rate", the increase per second
+  # counter_type = "count"
+
+  # Compression of the requests, "zlib", "gzip" or "none"
+  # compression = "none"
+
+  # Headers added to the requests
+  # [outputs.datadog.headers]
+  #   X-Source = "telegraf"
 `
 
 type TimeSeries struct {
@@ -36,10 +78,12 @@ type TimeSeries struct {
 }
 
 type Metric struct {
-	Metric string   `json:"metric"`
-	Points [1]Point `json:"points"`
-	Host   string   `json:"host"`
-	Tags   []string `json:"tags,omitempty"`
+	Metric   string   `json:"metric"`
+	Points   [1]Point `json:"points"`
+	Host     string   `json:"host"`
+	Tags     []string `json:"tags,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	Interval int64    `json:"interval,omitempty"`
 }
 
 type Point [2]float64
@@ -48,7 +92,8 @@ const datadog_api = "https://app.datadoghq.com/api/v1/series"
 
 func NewDatadog(apiUrl string) *Datadog {
 	return &Datadog{
-		apiUrl: apiUrl,
+		apiUrl:         apiUrl,
+		UseSystemProxy: true,
 	}
 }
 
@@ -56,9 +101,34 @@ func (d *Datadog) Connect() error {
 	if d.Apikey == "" {
 		return fmt.Errorf("apikey is a required field for datadog output")
 	}
-	d.client = &http.Client{
-		Timeout: d.Timeout.Duration,
+	switch d.CounterType {
+	case "":
+		d.CounterType = "count"
+	case "count", "rate":
+	default:
+		return fmt.Errorf("invalid counter_type %q", d.CounterType)
+	}
+	switch d.Compression {
+	case "", "none", "zlib", "gzip":
+	default:
+		return fmt.Errorf("invalid compression %q", d.Compression)
+	}
+	c := httpconfig.Config{
+		Timeout:      d.Timeout.Duration,
+		KeepAlive:    d.KeepAlive.Duration,
+		MaxIdleConns: d.MaxIdleConns,
+		Headers:      d.Headers,
+		Proxy: internal.HTTPProxyOptions{
+			HTTPProxy:      d.HTTPProxy,
+			HTTPSProxy:     d.HTTPSProxy,
+			UseSystemProxy: d.UseSystemProxy,
+		},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
 	}
+	d.client = client
 	return nil
 }
 
@@ -69,6 +139,9 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	ts := TimeSeries{}
 	tempSeries := []*Metric{}
 	metricCounter := 0
+	// counters are only updated once the write succeeded, so the increase
+	// of the counters is sent again if the metrics are retried
+	counters := make(map[string]Point)
 
 	for _, m := range metrics {
 		mname := strings.Replace(m.Name(), "_", ".", -1)
@@ -88,11 +161,19 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 					Host:   m.Tags()["host"],
 				}
 				metric.Points[0] = dogM
+				switch m.Type() {
+				case telegraf.Counter:
+					if !d.setCount(metric, counters) {
+						continue
+					}
+				case telegraf.Gauge:
+					metric.Type = "gauge"
+				}
 				tempSeries = append(tempSeries, metric)
 				metricCounter++
 			}
 		} else {
-			log.Printf("unable to build Metric for %s, skipping\n", m.Name())
+			d.Log.Warnf("Unable to build Metric for %s, skipping", m.Name())
 		}
 	}
 
@@ -102,11 +183,21 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 	if err != nil {
 		return fmt.Errorf("unable to marshal TimeSeries, %s\n", err.Error())
 	}
-	req, err := http.NewRequest("POST", d.authenticatedUrl(), bytes.NewBuffer(tsBytes))
+	body, err := d.compress(tsBytes)
+	if err != nil {
+		return fmt.Errorf("unable to compress TimeSeries, %s\n", err.Error())
+	}
+	req, err := http.NewRequest("POST", d.authenticatedUrl(), body)
 	if err != nil {
 		return fmt.Errorf("unable to create http.Request, %s\n", err.Error())
 	}
 	req.Header.Add("Content-Type", "application/json")
+	switch d.Compression {
+	case "zlib":
+		req.Header.Set("Content-Encoding", "deflate")
+	case "gzip":
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
 	resp, err := d.client.Do(req)
 	if err != nil {
@@ -118,9 +209,64 @@ func (d *Datadog) Write(metrics []telegraf.Metric) error {
 		return fmt.Errorf("received bad status code, %d\n", resp.StatusCode)
 	}
 
+	if d.counters == nil {
+		d.counters = make(map[string]Point)
+	}
+	for k, p := range counters {
+		d.counters[k] = p
+	}
 	return nil
 }
 
+// compress returns the body of a request, compressed with the compression
+// of the output
+func (d *Datadog) compress(b []byte) (io.Reader, error) {
+	var w io.WriteCloser
+	var buf bytes.Buffer
+	switch d.Compression {
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	default:
+		return bytes.NewReader(b), nil
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// setCount turns the metric of a counter into a datadog count of the increase
+// of the counter since its previous point, or a rate of the increase per
+// second, recording the point in counters. It returns false if there is no
+// previous point or the counter was reset, the metric not being sent then.
+func (d *Datadog) setCount(metric *Metric, counters map[string]Point) bool {
+	key := metric.Metric + " " + strings.Join(metric.Tags, ",")
+	p := metric.Points[0]
+	prev, ok := counters[key]
+	if !ok {
+		prev, ok = d.counters[key]
+	}
+	counters[key] = p
+	if !ok || p[1] < prev[1] || p[0] <= prev[0] {
+		return false
+	}
+
+	metric.Interval = int64(p[0] - prev[0])
+	if d.CounterType == "rate" {
+		metric.Type = "rate"
+		metric.Points[0] = Point{p[0], (p[1] - prev[1]) / (p[0] - prev[0])}
+		return true
+	}
+	metric.Type = "count"
+	metric.Points[0] = Point{p[0], p[1] - prev[1]}
+	return true
+}
+
 func (d *Datadog) SampleConfig() string {
 	return sampleConfig
 }
@@ -172,6 +318,12 @@ func (p *Point) setValue(v interface{}) error {
 		p[1] = float64(d)
 	case float64:
 		p[1] = float64(d)
+	case bool:
+		if d {
+			p[1] = 1
+		} else {
+			p[1] = 0
+		}
 	default:
 		return fmt.Errorf("undeterminable type")
 	}
@@ -1,8 +1,11 @@
 package datadog
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -175,3 +178,120 @@ func TestBuildPoint(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteCounter(t *testing.T) {
+	var series []*Metric
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body TimeSeries
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		series = body.Series
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	d := NewDatadog(ts.URL)
+	d.Apikey = fakeApiKey
+	require.NoError(t, d.Connect())
+
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	counter := func(value int64, t time.Time) []telegraf.Metric {
+		m, _ := telegraf.NewCounterMetric("requests",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": value}, t)
+		return []telegraf.Metric{m}
+	}
+
+	// The first point of a counter is not sent
+	require.NoError(t, d.Write(counter(10, now)))
+	assert.Len(t, series, 0)
+
+	// The increase of the counter is kept for the retry of a failed write
+	status = http.StatusInternalServerError
+	require.Error(t, d.Write(counter(25, now.Add(10*time.Second))))
+	status = http.StatusOK
+	require.NoError(t, d.Write(counter(25, now.Add(10*time.Second))))
+	require.Len(t, series, 1)
+	assert.Equal(t, "count", series[0].Type)
+	assert.Equal(t, int64(10), series[0].Interval)
+	assert.Equal(t, 15.0, series[0].Points[0][1])
+
+	// A reset counter is not sent
+	require.NoError(t, d.Write(counter(5, now.Add(20*time.Second))))
+	assert.Len(t, series, 0)
+}
+
+func TestWriteRate(t *testing.T) {
+	var series []*Metric
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body TimeSeries
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		series = body.Series
+	}))
+	defer ts.Close()
+
+	d := NewDatadog(ts.URL)
+	d.Apikey = fakeApiKey
+	d.CounterType = "rate"
+	require.NoError(t, d.Connect())
+
+	now := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	for i, value := range []int64{10, 25} {
+		m, _ := telegraf.NewCounterMetric("requests",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": value},
+			now.Add(time.Duration(i)*10*time.Second))
+		require.NoError(t, d.Write([]telegraf.Metric{m}))
+	}
+	require.Len(t, series, 1)
+	assert.Equal(t, "rate", series[0].Type)
+	assert.Equal(t, int64(10), series[0].Interval)
+	assert.Equal(t, 1.5, series[0].Points[0][1])
+	assert.Equal(t, "localhost", series[0].Host)
+	assert.Equal(t, []string{"host:localhost"}, series[0].Tags)
+}
+
+func TestCompression(t *testing.T) {
+	for _, compression := range []string{"none", "zlib", "gzip"} {
+		var series []*Metric
+		var encoding string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding = r.Header.Get("Content-Encoding")
+			var body io.Reader = r.Body
+			var err error
+			switch encoding {
+			case "deflate":
+				body, err = zlib.NewReader(r.Body)
+			case "gzip":
+				body, err = gzip.NewReader(r.Body)
+			}
+			require.NoError(t, err)
+			var ts TimeSeries
+			require.NoError(t, json.NewDecoder(body).Decode(&ts))
+			series = ts.Series
+		}))
+
+		d := NewDatadog(ts.URL)
+		d.Apikey = fakeApiKey
+		d.Compression = compression
+		require.NoError(t, d.Connect())
+		m, _ := telegraf.NewGaugeMetric("memory",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"used_percent": 12.5, "swap": true})
+		require.NoError(t, d.Write([]telegraf.Metric{m}))
+		ts.Close()
+
+		assert.Equal(t, map[string]string{
+			"none": "", "zlib": "deflate", "gzip": "gzip",
+		}[compression], encoding)
+		require.Len(t, series, 2, compression)
+		for _, metric := range series {
+			assert.Equal(t, "gauge", metric.Type)
+		}
+	}
+
+	d := NewDatadog(fakeUrl)
+	d.Apikey = fakeApiKey
+	d.Compression = "lz4"
+	assert.Error(t, d.Connect())
+}
@@ -0,0 +1,397 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+type Elasticsearch struct {
+	// URLs of the nodes, the requests failing over to the next one
+	URLs []string `toml:"urls"`
+	// IndexName is the pattern of the names of the indexes, with the %Y,
+	// %y, %m, %d, %H and %V (ISO week) placeholders replaced by the time of
+	// the metrics in UTC
+	IndexName string `toml:"index_name"`
+	// DocType is the type of the documents, required before Elasticsearch 7
+	DocType string `toml:"doc_type"`
+	// FlushSize is the number of documents sent per bulk request
+	FlushSize int `toml:"flush_size"`
+	Timeout   internal.Duration
+
+	// Credentials of the requests, with basic auth or an API key
+	Username string
+	Password string
+	APIKey   string `toml:"api_key"`
+
+	// Optional TLS configuration
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// ManageTemplate creates the index template TemplateName matching the
+	// indexes on connect, unless it exists and OverwriteTemplate is false
+	ManageTemplate    bool   `toml:"manage_template"`
+	TemplateName      string `toml:"template_name"`
+	OverwriteTemplate bool   `toml:"overwrite_template"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	// current is the index of the URL of the last successful request
+	current int
+}
+
+var sampleConfig = `
+  # URLs of the Elasticsearch nodes, the requests failing over to the next
+  # node if one cannot be reached
+  urls = ["http://localhost:9200"] # required.
+  # Pattern of the names of the indexes, with the time of the metrics in UTC
+  # replacing %Y (year), %y (2 digits year), %m (month), %d (day), %H (hour)
+  # and %V (ISO week)
+  index_name = "telegraf-%Y.%m.%d" # required.
+  # Type of the documents, required before Elasticsearch 7
+  # doc_type = "metrics"
+
+  # Number of documents sent per bulk request
+  flush_size = 1000
+  # Timeout of the requests
+  timeout = "5s"
+
+  # Credentials of the requests, with basic auth or an API key, the base64
+  # of "id:key"
+  # username = "telegraf"
+  # password = "mypassword"
+  # api_key = ""
+
+  # Optional TLS configuration
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Create an index template matching the indexes on connect, mapping the
+  # tags as keywords, unless it exists and overwrite_template is false
+  manage_template = true
+  template_name = "telegraf"
+  overwrite_template = false
+`
+
+// bulkResponse is the response of a bulk request, with the errors of the
+// documents not indexed
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (a *Elasticsearch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Elasticsearch) Description() string {
+	return "Send metrics to Elasticsearch with the bulk API"
+}
+
+func (a *Elasticsearch) Connect() error {
+	if len(a.URLs) == 0 {
+		return fmt.Errorf("no Elasticsearch urls set")
+	}
+	if a.IndexName == "" {
+		return fmt.Errorf("no Elasticsearch index_name set")
+	}
+	if a.FlushSize <= 0 {
+		a.FlushSize = 1000
+	}
+	if a.TemplateName == "" {
+		a.TemplateName = "telegraf"
+	}
+
+	c := httpconfig.Config{
+		Timeout:  a.Timeout.Duration,
+		Username: a.Username,
+		Password: a.Password,
+		TLS: internal.TLSOptions{
+			SSLCA:              a.SSLCA,
+			SSLCert:            a.SSLCert,
+			SSLKey:             a.SSLKey,
+			InsecureSkipVerify: a.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	if a.APIKey != "" {
+		if a.Username != "" || a.Password != "" {
+			return fmt.Errorf("only one of username and password or " +
+				"api_key can be set")
+		}
+		c.Headers = map[string]string{"Authorization": "ApiKey " + a.APIKey}
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	a.client = client
+
+	if a.ManageTemplate {
+		return a.manageTemplate()
+	}
+	return nil
+}
+
+// indexName returns the name of the index of a metric of that time
+func (a *Elasticsearch) indexName(t time.Time) string {
+	t = t.UTC()
+	_, week := t.ISOWeek()
+	return strings.NewReplacer(
+		"%Y", strconv.Itoa(t.Year()),
+		"%y", fmt.Sprintf("%02d", t.Year()%100),
+		"%m", fmt.Sprintf("%02d", int(t.Month())),
+		"%d", fmt.Sprintf("%02d", t.Day()),
+		"%H", fmt.Sprintf("%02d", t.Hour()),
+		"%V", fmt.Sprintf("%02d", week),
+	).Replace(a.IndexName)
+}
+
+// manageTemplate creates the index template, unless it exists and is not
+// overwritten
+func (a *Elasticsearch) manageTemplate() error {
+	prefix := a.IndexName
+	if i := strings.Index(prefix, "%"); i >= 0 {
+		prefix = prefix[:i]
+	}
+	if prefix == "" {
+		return fmt.Errorf("index_name %q must start with a static prefix "+
+			"to manage its template", a.IndexName)
+	}
+
+	path := "/_template/" + a.TemplateName
+	if !a.OverwriteTemplate {
+		resp, err := a.request("HEAD", path, "", nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusOK:
+			a.Log.Debugf("Template %s exists, not overwriting it",
+				a.TemplateName)
+			return nil
+		case http.StatusNotFound:
+		default:
+			return fmt.Errorf("could not check template %s: %s",
+				a.TemplateName, resp.Status)
+		}
+	}
+
+	body, err := json.Marshal(a.template(prefix + "*"))
+	if err != nil {
+		return err
+	}
+	resp, err := a.request("PUT", path, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not create template %s: %s: %s",
+			a.TemplateName, resp.Status, bytes.TrimSpace(msg))
+	}
+	a.Log.Infof("Created template %s", a.TemplateName)
+	return nil
+}
+
+// template returns the index template of the indexes of the pattern, the
+// tags being mapped as keywords and the numeric fields as floats
+func (a *Elasticsearch) template(pattern string) map[string]interface{} {
+	mappings := map[string]interface{}{
+		"dynamic_templates": []interface{}{
+			map[string]interface{}{"tags": map[string]interface{}{
+				"match_mapping_type": "string",
+				"path_match":         "tag.*",
+				"mapping": map[string]interface{}{
+					"type":         "keyword",
+					"ignore_above": 512,
+				},
+			}},
+			map[string]interface{}{"metrics_long": map[string]interface{}{
+				"match_mapping_type": "long",
+				"mapping": map[string]interface{}{
+					"type":  "float",
+					"index": false,
+				},
+			}},
+			map[string]interface{}{"metrics_double": map[string]interface{}{
+				"match_mapping_type": "double",
+				"mapping": map[string]interface{}{
+					"type":  "float",
+					"index": false,
+				},
+			}},
+		},
+		"properties": map[string]interface{}{
+			"@timestamp":       map[string]interface{}{"type": "date"},
+			"measurement_name": map[string]interface{}{"type": "keyword"},
+		},
+	}
+	if a.DocType != "" {
+		mappings = map[string]interface{}{a.DocType: mappings}
+	}
+	return map[string]interface{}{
+		"index_patterns": []string{pattern},
+		"settings": map[string]interface{}{
+			"index": map[string]interface{}{
+				"refresh_interval": "10s",
+			},
+		},
+		"mappings": mappings,
+	}
+}
+
+// document returns the document of a metric, its fields being nested under
+// its name and its tags under "tag"
+func document(metric telegraf.Metric) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for k, v := range metric.Fields() {
+		// NaN and infinite floats have no JSON representation
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			continue
+		}
+		fields[k] = v
+	}
+	return map[string]interface{}{
+		"@timestamp":       metric.Time().UTC().Format(time.RFC3339Nano),
+		"measurement_name": metric.Name(),
+		"tag":              metric.Tags(),
+		metric.Name():      fields,
+	}
+}
+
+func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	for start := 0; start < len(metrics); start += a.FlushSize {
+		end := start + a.FlushSize
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+		if err := a.bulk(metrics[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulk indexes the metrics with a bulk request
+func (a *Elasticsearch) bulk(metrics []telegraf.Metric) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, metric := range metrics {
+		action := map[string]string{"_index": a.indexName(metric.Time())}
+		if a.DocType != "" {
+			action["_type"] = a.DocType
+		}
+		if err := enc.Encode(map[string]interface{}{"index": action}); err != nil {
+			return err
+		}
+		if err := enc.Encode(document(metric)); err != nil {
+			return err
+		}
+	}
+
+	resp, err := a.request("POST", "/_bulk", "application/x-ndjson",
+		buf.Bytes())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read the bulk response: %s", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("bulk request failed: %s: %s", resp.Status,
+			bytes.TrimSpace(body))
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid bulk response: %s", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	for _, item := range result.Items {
+		for _, r := range item {
+			if r.Status/100 == 2 {
+				continue
+			}
+			if failed == 0 {
+				a.Log.Errorf("Could not index a document: %s", r.Error)
+			}
+			failed++
+		}
+	}
+	return fmt.Errorf("%d of %d documents could not be indexed", failed,
+		len(metrics))
+}
+
+// request sends a request to the nodes, starting with the one of the last
+// successful request, until one of them responds
+func (a *Elasticsearch) request(method, path, contentType string,
+	body []byte) (*http.Response, error) {
+	var err error
+	for i := 0; i < len(a.URLs); i++ {
+		n := (a.current + i) % len(a.URLs)
+		var req *http.Request
+		req, err = http.NewRequest(method,
+			strings.TrimSuffix(a.URLs[n], "/")+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		var resp *http.Response
+		resp, err = a.client.Do(req)
+		if err != nil {
+			a.Log.Errorf("Request to %s failed: %s", a.URLs[n], err)
+			continue
+		}
+		a.current = n
+		return resp, nil
+	}
+	return nil, fmt.Errorf("could not reach any Elasticsearch node: %s", err)
+}
+
+func (a *Elasticsearch) Close() error {
+	a.client = nil
+	return nil
+}
+
+func init() {
+	outputs.Add("elasticsearch", func() telegraf.Output {
+		return &Elasticsearch{
+			Timeout:        internal.Duration{Duration: 5 * time.Second},
+			FlushSize:      1000,
+			ManageTemplate: true,
+			TemplateName:   "telegraf",
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexName(t *testing.T) {
+	e := &Elasticsearch{IndexName: "telegraf-%Y.%m.%d-%H"}
+	tm := time.Date(2016, time.January, 4, 3, 0, 0, 0, time.UTC)
+	assert.Equal(t, "telegraf-2016.01.04-03", e.indexName(tm))
+
+	e.IndexName = "telegraf-%y-%V"
+	assert.Equal(t, "telegraf-16-01", e.indexName(tm))
+	e.IndexName = "telegraf"
+	assert.Equal(t, "telegraf", e.indexName(tm))
+}
+
+func TestWrite(t *testing.T) {
+	var requests int
+	var lines []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/_bulk", r.URL.Path)
+			assert.Equal(t, "application/x-ndjson",
+				r.Header.Get("Content-Type"))
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				var line map[string]interface{}
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+				lines = append(lines, line)
+			}
+			fmt.Fprint(w, `{"errors":false,"items":[]}`)
+		}))
+	defer ts.Close()
+
+	e := &Elasticsearch{
+		URLs:      []string{ts.URL},
+		IndexName: "telegraf-%Y.%m.%d",
+		FlushSize: 2,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, e.Connect())
+	m := testutil.TestMetric(1.0, "cpu")
+	require.NoError(t, e.Write([]telegraf.Metric{m, m, m}))
+
+	// Two bulk requests of at most two documents
+	assert.Equal(t, 2, requests)
+	require.Len(t, lines, 6)
+	assert.Equal(t, map[string]interface{}{
+		"index": map[string]interface{}{"_index": "telegraf-2009.11.10"},
+	}, lines[0])
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":       "2009-11-10T23:00:00Z",
+		"measurement_name": "cpu",
+		"tag":              map[string]interface{}{"tag1": "value1"},
+		"cpu":              map[string]interface{}{"value": 1.0},
+	}, lines[1])
+}
+
+func TestWriteErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors":true,"items":[`+
+				`{"index":{"status":201}},`+
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+		}))
+	defer ts.Close()
+
+	e := &Elasticsearch{
+		URLs:      []string{ts.URL},
+		IndexName: "telegraf",
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, e.Connect())
+	m := testutil.TestMetric(1.0, "cpu")
+	err := e.Write([]telegraf.Metric{m, m})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 2 documents")
+}
+
+func TestFailover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors":false,"items":[]}`)
+		}))
+	defer ts.Close()
+
+	e := &Elasticsearch{
+		URLs:      []string{"http://127.0.0.1:1", ts.URL},
+		IndexName: "telegraf",
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, e.Connect())
+	require.NoError(t, e.Write(testutil.MockMetrics()))
+	assert.Equal(t, 1, e.current)
+
+	e.URLs = []string{"http://127.0.0.1:1"}
+	e.current = 0
+	assert.Error(t, e.Write(testutil.MockMetrics()))
+}
+
+func TestManageTemplate(t *testing.T) {
+	exists := false
+	var template map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/_template/telegraf", r.URL.Path)
+			assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+			switch r.Method {
+			case "HEAD":
+				if !exists {
+					w.WriteHeader(http.StatusNotFound)
+				}
+			case "PUT":
+				require.NoError(t,
+					json.NewDecoder(r.Body).Decode(&template))
+				exists = true
+			}
+		}))
+	defer ts.Close()
+
+	e := &Elasticsearch{
+		URLs:           []string{ts.URL},
+		IndexName:      "telegraf-%Y.%m.%d",
+		APIKey:         "secret",
+		ManageTemplate: true,
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, e.Connect())
+	require.NotNil(t, template)
+	assert.Equal(t, []interface{}{"telegraf-*"}, template["index_patterns"])
+
+	// The existing template is kept
+	template = nil
+	require.NoError(t, e.Connect())
+	assert.Nil(t, template)
+
+	// Unless overwritten
+	e.OverwriteTemplate = true
+	require.NoError(t, e.Connect())
+	assert.NotNil(t, template)
+
+	// Without static prefix
+	e.IndexName = "%Y.%m.%d"
+	assert.Error(t, e.Connect())
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, e := range []*Elasticsearch{
+		{IndexName: "telegraf"},
+		{URLs: []string{"http://localhost:9200"}},
+		{URLs: []string{"http://localhost:9200"}, IndexName: "telegraf",
+			Username: "telegraf", APIKey: "secret"},
+	} {
+		e.Log = testutil.Logger{}
+		assert.Error(t, e.Connect())
+	}
+}
+
+func TestSampleConfig(t *testing.T) {
+	var buf bytes.Buffer
+	config.PrintFilteredSampleConfig(&buf, config.SampleConfigFilters{
+		Sections: []string{"outputs"},
+		Outputs:  []string{"elasticsearch"},
+	})
+	assert.Contains(t, buf.String(), "replacing %Y (year), %y (2 digits year)")
+
+	f, err := ioutil.TempFile("", "elasticsearch")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfig(f.Name()))
+	require.Len(t, c.Outputs, 1)
+	e := c.Outputs[0].Output.(*Elasticsearch)
+	assert.Equal(t, "telegraf-%Y.%m.%d", e.IndexName)
+}
@@ -0,0 +1,102 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const sampleConfig = `
+  # Program to run as daemon, the first element is the executable and the
+  # remaining elements are its arguments.
+  # The program receives metrics on stdin in influx line protocol, one metric
+  # per line. Anything written to stdout or stderr is logged.
+  command = ["/usr/bin/mywriter", "--foo=bar"]
+
+  # Delay before the program is restarted after an unexpected exit
+  restart_delay = "10s"
+`
+
+type Execd struct {
+	Command      []string
+	RestartDelay internal.Duration
+	Log          telegraf.Logger `toml:"-"`
+
+	process *process.Process
+	// buf holds the metrics of a write, reused as writes are not concurrent
+	buf []byte
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run an external program as a daemon and write metrics to its stdin"
+}
+
+// Connect launches the external program, which is restarted if it exits
+// before Close is called.
+func (e *Execd) Connect() error {
+	p, err := process.New(e.Command, e.Log)
+	if err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	p.RestartDelay = e.RestartDelay.Duration
+	p.ReadStdout = e.logStdout
+	e.process = p
+
+	if err := p.Start(); err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	return nil
+}
+
+// Close closes the program's stdin and waits for it to exit, killing it if
+// it does not exit in a timely manner.
+func (e *Execd) Close() error {
+	if e.process != nil {
+		e.process.Stop()
+	}
+	return nil
+}
+
+// Write writes the metrics to the program's stdin. The metrics are kept in
+// the output buffer if the program is being restarted.
+func (e *Execd) Write(metrics []telegraf.Metric) error {
+	e.buf = e.buf[:0]
+	for _, metric := range metrics {
+		e.buf = metric.AppendTo(e.buf)
+		e.buf = append(e.buf, '\n')
+	}
+	if _, err := e.process.Write(e.buf); err != nil {
+		return fmt.Errorf("execd: unable to write metrics to %s: %s",
+			e.Command[0], err)
+	}
+	return nil
+}
+
+func (e *Execd) logStdout(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		e.Log.Infof("%s: %s", e.Command[0], scanner.Text())
+	}
+}
+
+func init() {
+	outputs.Add("execd", func() telegraf.Output {
+		return NewExecd()
+	})
+}
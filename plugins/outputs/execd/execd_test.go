@@ -0,0 +1,44 @@
+package execd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecdWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "execd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "metrics.out")
+
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"sh", "-c", "cat > " + out}
+	require.NoError(t, e.Connect())
+
+	m, err := telegraf.NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage": int64(42)},
+		time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+	require.NoError(t, e.Close())
+
+	contents, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, m.String()+"\n", string(contents))
+}
+
+func TestExecdNoCommand(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	assert.Error(t, e.Connect())
+}
@@ -0,0 +1,127 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/rotate"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type File struct {
+	// Files to write to, "stdout" writing to the standard output
+	Files []string
+
+	// Rotation of the files, once older than RotationInterval or larger than
+	// RotationMaxSize, keeping RotationMaxArchives rotated files, all of
+	// them if -1
+	RotationInterval    internal.Duration `toml:"rotation_interval"`
+	RotationMaxSize     internal.Size     `toml:"rotation_max_size"`
+	RotationMaxArchives int               `toml:"rotation_max_archives"`
+
+	// Data format of the metrics
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
+
+	writer     io.Writer
+	closers    []io.Closer
+	serializer telegraf.Serializer
+}
+
+var sampleConfig = `
+  # Files to write to, "stdout" is a specially handled file
+  files = ["stdout", "/tmp/metrics.out"]
+
+  # Rotate the files once they are older than the interval or larger than the
+  # size, no rotation happening if zero
+  # rotation_interval = "0h"
+  # rotation_max_size = "0MB"
+  # Number of rotated files to keep, all of them if -1
+  # rotation_max_archives = 5
+
+  # Data format of the metrics, "influx" or "graphite"
+  data_format = "influx"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Send telegraf metrics to file(s)"
+}
+
+func (f *File) Connect() error {
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: f.DataFormat,
+	})
+	if err != nil {
+		return err
+	}
+	f.serializer = serializer
+
+	if len(f.Files) == 0 {
+		f.Files = []string{"stdout"}
+	}
+	var writers []io.Writer
+	for _, file := range f.Files {
+		if file == "stdout" {
+			writers = append(writers, os.Stdout)
+			continue
+		}
+		w, err := rotate.NewFileWriter(file, f.RotationInterval.Duration,
+			f.RotationMaxSize.Size, f.RotationMaxArchives)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("could not open file %s: %s", file, err)
+		}
+		writers = append(writers, w)
+		f.closers = append(f.closers, w)
+	}
+	f.writer = io.MultiWriter(writers...)
+	return nil
+}
+
+func (f *File) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if e := c.Close(); e != nil {
+			err = e
+		}
+	}
+	f.closers = nil
+	return err
+}
+
+func (f *File) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	var batch []byte
+	for _, metric := range metrics {
+		buf, err := f.serializer.Serialize(metric)
+		if err != nil {
+			f.Log.Errorf("Could not serialize metric %s: %s", metric.Name(),
+				err)
+			continue
+		}
+		batch = append(batch, buf...)
+	}
+	if _, err := f.writer.Write(batch); err != nil {
+		return fmt.Errorf("failed to write the metrics: %s", err)
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("file", func() telegraf.Output {
+		return &File{
+			RotationMaxArchives: 5,
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := []string{filepath.Join(dir, "a.out"), filepath.Join(dir, "b.out")}
+	f := &File{Files: files, Log: testutil.Logger{}}
+	require.NoError(t, f.Connect())
+	metrics := testutil.MockMetrics()
+	require.NoError(t, f.Write(metrics))
+	require.NoError(t, f.Close())
+
+	for _, file := range files {
+		buf, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, metrics[0].String()+"\n", string(buf))
+	}
+}
+
+func TestWriteGraphite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "metrics.out")
+	f := &File{Files: []string{file}, DataFormat: "graphite",
+		Log: testutil.Logger{}}
+	require.NoError(t, f.Connect())
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"usage_idle": 91.5},
+		testutil.MockMetrics()[0].Time())
+	require.NoError(t, f.Write([]telegraf.Metric{m}))
+	require.NoError(t, f.Close())
+
+	buf, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "web01.cpu.usage_idle 91.5 1257894000\n", string(buf))
+}
+
+func TestRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := &File{
+		Files:               []string{filepath.Join(dir, "metrics.out")},
+		RotationMaxSize:     internal.Size{Size: 10},
+		RotationMaxArchives: 1,
+		Log:                 testutil.Logger{},
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.Write(testutil.MockMetrics()))
+	}
+
+	// The current file and a single archive
+	files, err := filepath.Glob(filepath.Join(dir, "metrics.*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	f := &File{DataFormat: "unknown", Log: testutil.Logger{}}
+	assert.Error(t, f.Connect())
+
+	f = &File{Files: []string{"/nonexistent/metrics.out"},
+		Log: testutil.Logger{}}
+	assert.Error(t, f.Connect())
+}
@@ -1,16 +1,17 @@
 package graphite
 
 import (
+	"crypto/tls"
 	"errors"
-	"fmt"
-	"github.com/influxdata/telegraf"
-	"github.com/influxdata/telegraf/plugins/outputs"
-	"log"
 	"math/rand"
 	"net"
-	"sort"
-	"strings"
 	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	_ "github.com/influxdata/telegraf/plugins/serializers/graphite"
 )
 
 type Graphite struct {
@@ -18,16 +19,53 @@ type Graphite struct {
 	Servers []string
 	Prefix  string
 	Timeout int
-	conns   []net.Conn
+	// Template is the default template of the paths, Templates the
+	// templates of the measurements matching their filter
+	Template  string
+	Templates []string
+
+	// Optional TLS configuration
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	Log telegraf.Logger `toml:"-"`
+
+	// conns are the connections to the servers, nil for the servers not
+	// connected
+	conns      []net.Conn
+	tlsConfig  *tls.Config
+	serializer telegraf.Serializer
 }
 
 var sampleConfig = `
   # TCP endpoint for your graphite instance.
+  # If multiple endpoints are configured, the output will write to a random
+  # one of them, failing over to the others if the write fails.
   servers = ["localhost:2003"]
   # Prefix metrics name
   prefix = ""
+  # Template of the paths of the metrics, "measurement" being the name of the
+  # metric, "field" the name of the field, "tags" the values of the tags not
+  # used by the template, sorted by tag key, and the other parts the value of
+  # the tag of that key
+  template = "host.tags.measurement.field"
+  # Templates of the measurements matching their filter, a glob, the first
+  # one matching being used
+  # templates = [
+  #   "cpu tags.measurement.host.field",
+  #   "net* host.measurement.interface.field",
+  # ]
   # timeout in seconds for the write connection to graphite
   timeout = 2
+
+  # Optional TLS configuration
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
 `
 
 func (g *Graphite) Connect() error {
@@ -38,22 +76,64 @@ func (g *Graphite) Connect() error {
 	if len(g.Servers) == 0 {
 		g.Servers = append(g.Servers, "localhost:2003")
 	}
-	// Get Connections
-	var conns []net.Conn
-	for _, server := range g.Servers {
-		conn, err := net.DialTimeout("tcp", server, time.Duration(g.Timeout)*time.Second)
-		if err == nil {
-			conns = append(conns, conn)
+	if g.serializer == nil {
+		serializer, err := serializers.NewSerializer(&serializers.Config{
+			DataFormat: "graphite",
+			Prefix:     g.Prefix,
+			Template:   g.Template,
+			Templates:  g.Templates,
+		})
+		if err != nil {
+			return err
 		}
+		g.serializer = serializer
 	}
-	g.conns = conns
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              g.SSLCA,
+		SSLCert:            g.SSLCert,
+		SSLKey:             g.SSLKey,
+		InsecureSkipVerify: g.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	g.tlsConfig = tlsConfig
+	g.conns = make([]net.Conn, len(g.Servers))
+	g.reconnect()
 	return nil
 }
 
+// reconnect connects to the servers not connected, the servers which cannot
+// be reached being retried on the next write
+func (g *Graphite) reconnect() {
+	timeout := time.Duration(g.Timeout) * time.Second
+	for i, server := range g.Servers {
+		if g.conns[i] != nil {
+			continue
+		}
+		var conn net.Conn
+		var err error
+		if g.tlsConfig != nil {
+			conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout},
+				"tcp", server, g.tlsConfig)
+		} else {
+			conn, err = net.DialTimeout("tcp", server, timeout)
+		}
+		if err != nil {
+			g.Log.Debugf("Could not connect to %s: %s", server, err)
+			continue
+		}
+		g.conns[i] = conn
+	}
+}
+
 func (g *Graphite) Close() error {
 	// Closing all connections
-	for _, conn := range g.conns {
-		conn.Close()
+	for i, conn := range g.conns {
+		if conn != nil {
+			conn.Close()
+			g.conns[i] = nil
+		}
 	}
 	return nil
 }
@@ -70,93 +150,43 @@ func (g *Graphite) Description() string {
 // occurs, logging each unsuccessful. If all servers fail, return error.
 func (g *Graphite) Write(metrics []telegraf.Metric) error {
 	// Prepare data
-	var bp []string
+	var batch []byte
 	for _, metric := range metrics {
-		// Get name
-		name := metric.Name()
-		// Convert UnixNano to Unix timestamps
-		timestamp := metric.UnixNano() / 1000000000
-		tag_str := buildTags(metric)
-
-		for field_name, value := range metric.Fields() {
-			// Convert value
-			value_str := fmt.Sprintf("%#v", value)
-			// Write graphite metric
-			var graphitePoint string
-			if name == field_name {
-				graphitePoint = fmt.Sprintf("%s.%s %s %d\n",
-					tag_str,
-					strings.Replace(name, ".", "_", -1),
-					value_str,
-					timestamp)
-			} else {
-				graphitePoint = fmt.Sprintf("%s.%s.%s %s %d\n",
-					tag_str,
-					strings.Replace(name, ".", "_", -1),
-					strings.Replace(field_name, ".", "_", -1),
-					value_str,
-					timestamp)
-			}
-			if g.Prefix != "" {
-				graphitePoint = fmt.Sprintf("%s.%s", g.Prefix, graphitePoint)
-			}
-			bp = append(bp, graphitePoint)
+		buf, err := g.serializer.Serialize(metric)
+		if err != nil {
+			g.Log.Errorf("Could not serialize metric %s: %s", metric.Name(),
+				err)
+			continue
 		}
+		batch = append(batch, buf...)
 	}
-	graphitePoints := strings.Join(bp, "")
+
+	g.reconnect()
 
 	// This will get set to nil if a successful write occurs
 	err := errors.New("Could not write to any Graphite server in cluster\n")
 
 	// Send data to a random server
-	p := rand.Perm(len(g.conns))
-	for _, n := range p {
-		if _, e := fmt.Fprintf(g.conns[n], graphitePoints); e != nil {
-			// Error
-			log.Println("ERROR: " + err.Error())
-			// Let's try the next one
-		} else {
-			// Success
-			err = nil
-			break
-		}
-	}
-	// try to reconnect
-	if err != nil {
-		g.Connect()
-	}
-	return err
-}
-
-func buildTags(metric telegraf.Metric) string {
-	var keys []string
-	tags := metric.Tags()
-	for k := range tags {
-		if k == "host" {
+	timeout := time.Duration(g.Timeout) * time.Second
+	for _, n := range rand.Perm(len(g.conns)) {
+		conn := g.conns[n]
+		if conn == nil {
 			continue
 		}
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	var tag_str string
-	if host, ok := tags["host"]; ok {
-		if len(keys) > 0 {
-			tag_str = strings.Replace(host, ".", "_", -1) + "."
-		} else {
-			tag_str = strings.Replace(host, ".", "_", -1)
-		}
-	}
-
-	for i, k := range keys {
-		tag_value := strings.Replace(tags[k], ".", "_", -1)
-		if i == 0 {
-			tag_str += tag_value
-		} else {
-			tag_str += "." + tag_value
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+		if _, e := conn.Write(batch); e != nil {
+			// The connection is reopened on the next write, let's try the
+			// next one
+			g.Log.Errorf("Could not write to %s: %s", g.Servers[n], e)
+			conn.Close()
+			g.conns[n] = nil
+			continue
 		}
+		// Success
+		err = nil
+		break
 	}
-	return tag_str
+	return err
 }
 
 func init() {
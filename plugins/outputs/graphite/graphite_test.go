@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,6 +20,7 @@ func TestGraphiteError(t *testing.T) {
 	g := Graphite{
 		Servers: []string{"127.0.0.1:2003", "127.0.0.1:12003"},
 		Prefix:  "my.prefix",
+		Log:     testutil.Logger{},
 	}
 	// Init metrics
 	m1, _ := telegraf.NewMetric(
@@ -43,6 +45,7 @@ func TestGraphiteOK(t *testing.T) {
 	// Init plugin
 	g := Graphite{
 		Prefix: "my.prefix",
+		Log:    testutil.Logger{},
 	}
 	// Init metrics
 	m1, _ := telegraf.NewMetric(
@@ -103,31 +106,40 @@ func TCPServer(t *testing.T, wg *sync.WaitGroup) {
 	wg.Done()
 }
 
-func TestGraphiteTags(t *testing.T) {
-	m1, _ := telegraf.NewMetric(
-		"mymeasurement",
-		map[string]string{"host": "192.168.0.1"},
-		map[string]interface{}{"value": float64(3.14)},
-		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
-	)
-	m2, _ := telegraf.NewMetric(
-		"mymeasurement",
-		map[string]string{"host": "192.168.0.1", "afoo": "first", "bfoo": "second"},
-		map[string]interface{}{"value": float64(3.14)},
-		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
-	)
-	m3, _ := telegraf.NewMetric(
-		"mymeasurement",
-		map[string]string{"afoo": "first", "bfoo": "second"},
-		map[string]interface{}{"value": float64(3.14)},
+func TestGraphiteFailover(t *testing.T) {
+	// A server which is not listening anymore
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down.Close()
+	up, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer up.Close()
+
+	g := Graphite{
+		Servers:  []string{down.Addr().String(), up.Addr().String()},
+		Template: "measurement.host.field",
+		Log:      testutil.Logger{},
+	}
+	require.NoError(t, g.Connect())
+	defer g.Close()
+
+	m, _ := telegraf.NewMetric(
+		"cpu",
+		map[string]string{"host": "web01"},
+		map[string]interface{}{"usage": int64(3)},
 		time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC),
 	)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, g.Write([]telegraf.Metric{m}))
+	}
 
-	tags1 := buildTags(m1)
-	tags2 := buildTags(m2)
-	tags3 := buildTags(m3)
-
-	assert.Equal(t, "192_168_0_1", tags1)
-	assert.Equal(t, "192_168_0_1.first.second", tags2)
-	assert.Equal(t, "first.second", tags3)
+	conn, err := up.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	for i := 0; i < 3; i++ {
+		line, err := tp.ReadLine()
+		require.NoError(t, err)
+		assert.Equal(t, "cpu.web01.usage 3 1289430000", line)
+	}
 }
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const defaultContentType = "text/plain; charset=utf-8"
+
+type HTTP struct {
+	URL string `toml:"url"`
+	// Method of the requests, "POST", "PUT" or "PATCH"
+	Method string
+	// ContentEncoding of the bodies, "identity" or "gzip"
+	ContentEncoding string `toml:"content_encoding"`
+
+	// Options of the HTTP client, see httpconfig.Config
+	Timeout            internal.Duration
+	Headers            map[string]string
+	Username           string
+	Password           string
+	BearerToken        string   `toml:"bearer_token"`
+	OAuth2ClientID     string   `toml:"oauth2_client_id"`
+	OAuth2ClientSecret string   `toml:"oauth2_client_secret"`
+	OAuth2TokenURL     string   `toml:"oauth2_token_url"`
+	OAuth2Scopes       []string `toml:"oauth2_scopes"`
+	SSLCA              string   `toml:"ssl_ca"`
+	SSLCert            string   `toml:"ssl_cert"`
+	SSLKey             string   `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Data format of the bodies
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
+
+	client     *http.Client
+	serializer telegraf.Serializer
+}
+
+var sampleConfig = `
+  # URL the metrics are sent to
+  url = "http://127.0.0.1:8080/telegraf"
+
+  # HTTP method of the requests, "POST", "PUT" or "PATCH"
+  # method = "POST"
+  # Timeout of the requests
+  # timeout = "5s"
+
+  # Content encoding of the bodies, "identity" or "gzip"
+  # content_encoding = "identity"
+
+  # Credentials of the requests, either basic auth, a bearer token, or an
+  # OAuth2 client requesting the bearer tokens from oauth2_token_url
+  # username = ""
+  # password = ""
+  # bearer_token = ""
+  # oauth2_client_id = ""
+  # oauth2_client_secret = ""
+  # oauth2_token_url = ""
+  # oauth2_scopes = []
+
+  # TLS options of https urls
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Data format of the bodies, "influx" or "graphite"
+  data_format = "influx"
+
+  # Headers added to the requests, overriding the default
+  # "text/plain; charset=utf-8" Content-Type
+  # [outputs.http.headers]
+  #   Content-Type = "text/plain; charset=utf-8"
+`
+
+func (h *HTTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HTTP) Description() string {
+	return "Send the metrics in one of the data formats to an HTTP endpoint"
+}
+
+func (h *HTTP) Connect() error {
+	if h.URL == "" {
+		return fmt.Errorf("url must be set")
+	}
+	if h.Method == "" {
+		h.Method = "POST"
+	}
+	h.Method = strings.ToUpper(h.Method)
+	switch h.Method {
+	case "POST", "PUT", "PATCH":
+	default:
+		return fmt.Errorf("invalid method %q, must be POST, PUT or PATCH",
+			h.Method)
+	}
+	switch h.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content_encoding %q, must be identity "+
+			"or gzip", h.ContentEncoding)
+	}
+	if h.Timeout.Duration == 0 {
+		h.Timeout.Duration = 5 * time.Second
+	}
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: h.DataFormat,
+	})
+	if err != nil {
+		return err
+	}
+
+	c := httpconfig.Config{
+		Timeout:     h.Timeout.Duration,
+		Headers:     h.Headers,
+		Username:    h.Username,
+		Password:    h.Password,
+		BearerToken: h.BearerToken,
+		OAuth2: httpconfig.OAuth2Config{
+			ClientID:     h.OAuth2ClientID,
+			ClientSecret: h.OAuth2ClientSecret,
+			TokenURL:     h.OAuth2TokenURL,
+			Scopes:       h.OAuth2Scopes,
+		},
+		TLS: internal.TLSOptions{
+			SSLCA:              h.SSLCA,
+			SSLCert:            h.SSLCert,
+			SSLKey:             h.SSLKey,
+			InsecureSkipVerify: h.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	h.client = client
+	h.serializer = serializer
+	return nil
+}
+
+func (h *HTTP) Close() error {
+	return nil
+}
+
+// Write sends the metrics in the body of a single request
+func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	if h.client == nil {
+		return fmt.Errorf("not connected to %s", h.URL)
+	}
+
+	var body bytes.Buffer
+	for _, m := range metrics {
+		buf, err := h.serializer.Serialize(m)
+		if err != nil {
+			h.Log.Errorf("Could not serialize metric %s: %s", m.Name(), err)
+			continue
+		}
+		body.Write(buf)
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+	data := body.Bytes()
+	if h.ContentEncoding == "gzip" {
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		if _, err := gz.Write(data); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		data = compressed.Bytes()
+	}
+
+	req, err := http.NewRequest(h.Method, h.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	// The headers of the config override these ones
+	req.Header.Set("Content-Type", defaultContentType)
+	req.Header.Set("User-Agent", "Telegraf")
+	if h.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not write to %s: %s", h.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not write to %s: %s: %s", h.URL,
+			resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("http", func() telegraf.Output {
+		return &HTTP{Method: "POST"}
+	})
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
+)
+
+// request is a request received by the test server
+type request struct {
+	method  string
+	header  http.Header
+	body    string
+	encoded bool
+}
+
+func newServer(t *testing.T, status int) (*httptest.Server, chan request) {
+	requests := make(chan request, 10)
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body := r.Body
+			encoded := r.Header.Get("Content-Encoding") == "gzip"
+			if encoded {
+				gz, err := gzip.NewReader(r.Body)
+				require.NoError(t, err)
+				body = gz
+			}
+			buf, err := ioutil.ReadAll(body)
+			require.NoError(t, err)
+			requests <- request{r.Method, r.Header, string(buf), encoded}
+			w.WriteHeader(status)
+			w.Write([]byte("not allowed\n"))
+		}))
+	return ts, requests
+}
+
+func TestWrite(t *testing.T) {
+	ts, requests := newServer(t, http.StatusNoContent)
+	defer ts.Close()
+
+	h := &HTTP{URL: ts.URL + "/telegraf", Log: testutil.Logger{}}
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	r := <-requests
+	assert.Equal(t, "POST", r.method)
+	assert.Equal(t, "text/plain; charset=utf-8", r.header.Get("Content-Type"))
+	assert.False(t, r.encoded)
+	assert.Equal(t, "test1,tag1=value1 value=1 1257894000000000000\n", r.body)
+
+	// Nothing is sent without metrics
+	require.NoError(t, h.Write(nil))
+	assert.Len(t, requests, 0)
+}
+
+func TestWriteOptions(t *testing.T) {
+	ts, requests := newServer(t, http.StatusOK)
+	defer ts.Close()
+
+	h := &HTTP{
+		URL:             ts.URL,
+		Method:          "put",
+		ContentEncoding: "gzip",
+		Username:        "telegraf",
+		Password:        "secret",
+		Headers:         map[string]string{"Content-Type": "text/csv"},
+		DataFormat:      "graphite",
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, h.Connect())
+	require.NoError(t, h.Write(testutil.MockMetrics()))
+
+	r := <-requests
+	assert.Equal(t, "PUT", r.method)
+	assert.Equal(t, "text/csv", r.header.Get("Content-Type"))
+	assert.True(t, r.encoded)
+	assert.Equal(t, "Basic dGVsZWdyYWY6c2VjcmV0", r.header.Get("Authorization"))
+	assert.Equal(t, "value1.test1.value 1 1257894000\n", r.body)
+}
+
+func TestWriteError(t *testing.T) {
+	ts, requests := newServer(t, http.StatusForbidden)
+	defer ts.Close()
+
+	h := &HTTP{URL: ts.URL, Log: testutil.Logger{}}
+	require.NoError(t, h.Connect())
+	err := h.Write(testutil.MockMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden: not allowed")
+	<-requests
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, h := range []*HTTP{
+		{},
+		{URL: "http://localhost", Method: "GET"},
+		{URL: "http://localhost", ContentEncoding: "deflate"},
+		{URL: "http://localhost", DataFormat: "xml"},
+		{URL: "http://localhost", Username: "telegraf", BearerToken: "x"},
+	} {
+		assert.Error(t, h.Connect(), "%+v", h)
+	}
+}
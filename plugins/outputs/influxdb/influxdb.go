@@ -3,7 +3,6 @@ package influxdb
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"net/url"
 	"strings"
@@ -17,8 +16,7 @@ import (
 )
 
 type InfluxDB struct {
-	// URL is only for backwards compatability
-	URL        string
+	URL        string   `deprecated:"0.10.1;;use 'urls' instead" migrate:"URLs"`
 	URLs       []string `toml:"urls"`
 	Username   string
 	Password   string
@@ -26,7 +24,8 @@ type InfluxDB struct {
 	UserAgent  string
 	Precision  string
 	Timeout    internal.Duration
-	UDPPayload int `toml:"udp_payload"`
+	UDPPayload int             `toml:"udp_payload"`
+	Log        telegraf.Logger `toml:"-"`
 
 	conns []client.Client
 }
@@ -55,19 +54,8 @@ var sampleConfig = `
 `
 
 func (i *InfluxDB) Connect() error {
-	var urls []string
-	for _, u := range i.URLs {
-		urls = append(urls, u)
-	}
-
-	// Backward-compatability with single Influx URL config files
-	// This could eventually be removed in favor of specifying the urls as a list
-	if i.URL != "" {
-		urls = append(urls, i.URL)
-	}
-
 	var conns []client.Client
-	for _, u := range urls {
+	for _, u := range i.URLs {
 		switch {
 		case strings.HasPrefix(u, "udp"):
 			parsed_url, err := url.Parse(u)
@@ -105,7 +93,7 @@ func (i *InfluxDB) Connect() error {
 			})
 
 			if e != nil {
-				log.Println("Database creation failed: " + e.Error())
+				i.Log.Errorf("Database creation failed: %s", e)
 			}
 
 			conns = append(conns, c)
@@ -148,7 +136,7 @@ func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
 	p := rand.Perm(len(i.conns))
 	for _, n := range p {
 		if e := i.conns[n].Write(bp); e != nil {
-			log.Println("ERROR: " + e.Error())
+			i.Log.Error(e)
 		} else {
 			err = nil
 			break
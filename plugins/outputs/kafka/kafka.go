@@ -2,13 +2,12 @@ package kafka
 
 import (
 	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/Shopify/sarama"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
-	"io/ioutil"
 )
 
 type Kafka struct {
@@ -19,16 +18,36 @@ type Kafka struct {
 	// Routing Key Tag
 	RoutingTag string `toml:"routing_tag"`
 	// TLS client certificate
-	Certificate string
+	SSLCert string `toml:"ssl_cert"`
 	// TLS client key
-	Key string
+	SSLKey string `toml:"ssl_key"`
 	// TLS certificate authority
-	CA string
+	SSLCA string `toml:"ssl_ca"`
+	// PKCS#12 bundle of the client certificate and key, instead of ssl_cert
+	// and ssl_key
+	SSLPKCS12 string `toml:"ssl_pkcs12"`
+	// Password of an encrypted client key or of the PKCS#12 bundle
+	SSLKeyPassword string `toml:"ssl_key_password"`
+	// Name verified against the certificate of the server, the host of
+	// the connection if empty
+	TLSServerName string `toml:"tls_server_name"`
+	// Allowed TLS versions and cipher suites
+	TLSMinVersion   string   `toml:"tls_min_version"`
+	TLSMaxVersion   string   `toml:"tls_max_version"`
+	TLSCipherSuites []string `toml:"tls_cipher_suites"`
+	// Period at which the certificate files are checked, the producer being
+	// reconnected with the new certificates once they are modified
+	TLSReloadInterval internal.Duration `toml:"tls_reload_interval"`
+
+	Certificate string `deprecated:"0.10.2;0.12.0;use 'ssl_cert' instead" migrate:"SSLCert"`
+	Key         string `deprecated:"0.10.2;0.12.0;use 'ssl_key' instead" migrate:"SSLKey"`
+	CA          string `deprecated:"0.10.2;0.12.0;use 'ssl_ca' instead" migrate:"SSLCA"`
 	// Verfiy SSL certificate chain
 	VerifySsl bool
+	Log       telegraf.Logger `toml:"-"`
 
-	tlsConfig tls.Config
-	producer  sarama.SyncProducer
+	producer    sarama.SyncProducer
+	certWatcher *internal.FileWatcher
 }
 
 var sampleConfig = `
@@ -42,39 +61,53 @@ var sampleConfig = `
 
   # Optional TLS configuration:
   # Client certificate
-  certificate = ""
+  ssl_cert = ""
   # Client key
-  key = ""
+  ssl_key = ""
   # Certificate authority file
-  ca = ""
+  ssl_ca = ""
+  # Client certificate and key bundled as PKCS#12, instead of ssl_cert and
+  # ssl_key
+  # ssl_pkcs12 = "/etc/telegraf/client.p12"
+  # Password of an encrypted client key or PKCS#12 bundle, which can be read
+  # from a secret store, ie "@{vault:kafka_key_password}"
+  # ssl_key_password = ""
+  # Name verified against the certificate of the brokers, if it differs from
+  # the address of the brokers
+  # tls_server_name = "kafka.example.com"
+  # Minimum and maximum TLS versions, "1.0", "1.1" or "1.2"
+  # tls_min_version = "1.2"
+  # tls_max_version = "1.2"
+  # Allowed cipher suites, ie "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+  # tls_cipher_suites = []
+  # Reconnect with the new certificates once the certificate, key or CA files
+  # are modified, ie rotated short-lived certificates, checking them at this
+  # interval. Disabled if empty.
+  # tls_reload_interval = "1m"
   # Verify SSL certificate chain
   verify_ssl = false
 `
 
-func createTlsConfiguration(k *Kafka) (t *tls.Config, err error) {
-	if k.Certificate != "" && k.Key != "" && k.CA != "" {
-		cert, err := tls.LoadX509KeyPair(k.Certificate, k.Key)
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("Cout not load Kafka TLS client key/certificate: %s",
-				err))
-		}
-
-		caCert, err := ioutil.ReadFile(k.CA)
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("Cout not load Kafka TLS CA: %s",
-				err))
-		}
-
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-
-		t = &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			RootCAs:            caCertPool,
-			InsecureSkipVerify: k.VerifySsl,
-		}
+func createTlsConfiguration(k *Kafka) (*tls.Config, error) {
+	t, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:          k.SSLCA,
+		SSLCert:        k.SSLCert,
+		SSLKey:         k.SSLKey,
+		SSLPKCS12:      k.SSLPKCS12,
+		SSLKeyPassword: k.SSLKeyPassword,
+		ServerName:     k.TLSServerName,
+		MinVersion:     k.TLSMinVersion,
+		MaxVersion:     k.TLSMaxVersion,
+		CipherSuites:   k.TLSCipherSuites,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not load Kafka TLS configuration: %s",
+			err)
 	}
 	// will be nil by default if nothing is provided
+	if t != nil {
+		t.InsecureSkipVerify = k.VerifySsl
+	}
 	return t, nil
 }
 
@@ -82,6 +115,10 @@ func (k *Kafka) Connect() error {
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll // Wait for all in-sync replicas to ack the message
 	config.Producer.Retry.Max = 10                   // Retry up to 10 times to produce the message
+	if k.TLSReloadInterval.Duration > 0 {
+		k.certWatcher = internal.NewFileWatcher(k.TLSReloadInterval.Duration,
+			k.SSLCA, k.SSLCert, k.SSLKey, k.SSLPKCS12)
+	}
 	tlsConfig, err := createTlsConfiguration(k)
 	if err != nil {
 		return err
@@ -101,6 +138,9 @@ func (k *Kafka) Connect() error {
 }
 
 func (k *Kafka) Close() error {
+	if k.producer == nil {
+		return nil
+	}
 	return k.producer.Close()
 }
 
@@ -117,6 +157,21 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	// The connections of the producer keep the certificates they were opened
+	// with, the producer is recreated once they are rotated
+	if k.certWatcher != nil && k.certWatcher.Changed() {
+		k.Log.Info("Certificates modified, reconnecting")
+		if err := k.producer.Close(); err != nil {
+			k.Log.Warnf("Closing the producer: %s", err)
+		}
+		k.producer = nil
+	}
+	if k.producer == nil {
+		if err := k.Connect(); err != nil {
+			return err
+		}
+	}
+
 	for _, p := range metrics {
 		value := p.String()
 
@@ -2,43 +2,114 @@ package kinesis
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"sync/atomic"
+	"math/rand"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
-	"github.com/aws/aws-sdk-go/aws/ec2metadata"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/kinesis"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/awsconfig"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Limits of the PutRecords requests
+const (
+	maxRecordsPerRequest = 500
+	maxRequestSize       = 5 * 1024 * 1024
+	// maxRecordSize is the maximum size of the data and partition key of a
+	// record
+	maxRecordSize = 1024 * 1024
 )
 
 type KinesisOutput struct {
-	Region       string `toml:"region"`
-	StreamName   string `toml:"streamname"`
+	Region string `toml:"region"`
+
+	// Credentials, see awsconfig.Config
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	Token       string `toml:"token"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	StreamName string `toml:"streamname"`
+	// PartitionKey is the partition key of the records with the static
+	// partition method
 	PartitionKey string `toml:"partitionkey"`
-	Format       string `toml:"format"`
-	Debug        bool   `toml:"debug"`
-	svc          *kinesis.Kinesis
+	// PartitionMethod is the partition key of the records: "static", the
+	// partitionkey, "random", "tag", the value of the PartitionTag tag, or
+	// PartitionDefault if the metric does not have it, or "measurement", the
+	// name of the metric
+	PartitionMethod  string `toml:"partition_method"`
+	PartitionTag     string `toml:"partition_tag"`
+	PartitionDefault string `toml:"partition_default"`
+	// Aggregate puts the metrics of a partition key in as few records as
+	// possible
+	Aggregate bool `toml:"aggregate"`
+
+	Format         string          `toml:"format" deprecated:"0.10.2;0.12.0;use 'data_format' instead"`
+	DataFormat     string          `toml:"data_format"`
+	Debug          bool            `toml:"debug"`
+	HTTPProxy      string          `toml:"http_proxy"`
+	HTTPSProxy     string          `toml:"https_proxy"`
+	UseSystemProxy bool            `toml:"use_system_proxy"`
+	Log            telegraf.Logger `toml:"-"`
+	svc            *kinesis.Kinesis
+	serializer     telegraf.Serializer
 }
 
 var sampleConfig = `
   # Amazon REGION of kinesis endpoint.
   region = "ap-southeast-2"
+
+  # Credentials, in order of precedence:
+  # 1) access_key, secret_key and token
+  # 2) profile of the shared_credential_file
+  # 3) the credential chain: EC2 instance role, environment variables and
+  #    shared credentials file
+  # assuming the role_arn role if set
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  # Endpoint of the service, instead of the one of the region
+  # endpoint_url = ""
+
   # Kinesis StreamName must exist prior to starting telegraf.
   streamname = "StreamName"
-  # PartitionKey as used for sharding data.
+  # Partition key of the records, spreading them over the shards:
+  # "static", the partitionkey, "random", "tag", the value of the
+  # partition_tag tag, partition_default if the metric does not have it, or
+  # "measurement", the name of the metric
+  partition_method = "static"
   partitionkey = "PartitionKey"
-  # format of the Data payload in the kinesis PutRecord, supported
-  # String and Custom.
-  format = "string"
+  # partition_tag = "host"
+  # partition_default = "telegraf"
+
+  # Put the metrics of a partition key in as few records as possible, each
+  # record holding up to 1MB of metrics, to stay under the records per
+  # second limit of the shards
+  # aggregate = false
+
+  # Data format of the records, "influx" or "graphite"
+  data_format = "influx"
   # debug will show upstream aws messages.
   debug = false
+
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
 `
 
 func (k *KinesisOutput) SampleConfig() string {
@@ -49,54 +120,64 @@ func (k *KinesisOutput) Description() string {
 	return "Configuration for the AWS Kinesis output."
 }
 
-func checkstream(l []*string, s string) bool {
-	// Check if the StreamName exists in the slice returned from the ListStreams API request.
-	for _, stream := range l {
-		if *stream == s {
-			return true
+func (k *KinesisOutput) Connect() error {
+	switch k.PartitionMethod {
+	case "", "static", "random", "measurement":
+	case "tag":
+		if k.PartitionTag == "" {
+			return fmt.Errorf("partition_tag must be set with the tag " +
+				"partition_method")
 		}
+	default:
+		return fmt.Errorf("invalid partition_method %q", k.PartitionMethod)
+	}
+	// The records are formatted with the legacy format if set alone
+	if k.Format == "" || k.DataFormat != "" {
+		serializer, err := serializers.NewSerializer(&serializers.Config{
+			DataFormat: k.DataFormat,
+		})
+		if err != nil {
+			return err
+		}
+		k.serializer = serializer
 	}
-	return false
-}
 
-func (k *KinesisOutput) Connect() error {
-	// We attempt first to create a session to Kinesis using an IAMS role, if that fails it will fall through to using
-	// environment variables, and then Shared Credentials.
 	if k.Debug {
-		log.Printf("kinesis: Establishing a connection to Kinesis in %+v", k.Region)
+		k.Log.Infof("Establishing a connection to Kinesis in %s", k.Region)
 	}
-	Config := &aws.Config{
-		Region: aws.String(k.Region),
-		Credentials: credentials.NewChainCredentials(
-			[]credentials.Provider{
-				&ec2rolecreds.EC2RoleProvider{Client: ec2metadata.New(session.New())},
-				&credentials.EnvProvider{},
-				&credentials.SharedCredentialsProvider{},
-			}),
+	config := &awsconfig.Config{
+		Region:      k.Region,
+		AccessKey:   k.AccessKey,
+		SecretKey:   k.SecretKey,
+		Token:       k.Token,
+		RoleARN:     k.RoleARN,
+		Profile:     k.Profile,
+		Filename:    k.Filename,
+		EndpointURL: k.EndpointURL,
+		Proxy: internal.HTTPProxyOptions{
+			HTTPProxy:      k.HTTPProxy,
+			HTTPSProxy:     k.HTTPSProxy,
+			UseSystemProxy: k.UseSystemProxy,
+		},
 	}
-	svc := kinesis.New(session.New(Config))
-
-	KinesisParams := &kinesis.ListStreamsInput{
-		Limit: aws.Int64(100),
+	session, err := config.NewSession()
+	if err != nil {
+		return err
 	}
+	svc := kinesis.New(session)
 
-	resp, err := svc.ListStreams(KinesisParams)
-
+	_, err = svc.DescribeStream(&kinesis.DescribeStreamInput{
+		StreamName: aws.String(k.StreamName),
+	})
 	if err != nil {
-		log.Printf("kinesis: Error in ListSteams API call : %+v \n", err)
+		return fmt.Errorf("could not describe stream %s, it must exist "+
+			"prior to starting telegraf: %s", k.StreamName, err)
 	}
-
-	if checkstream(resp.StreamNames, k.StreamName) {
-		if k.Debug {
-			log.Printf("kinesis: Stream Exists")
-		}
-		k.svc = svc
-		return nil
-	} else {
-		log.Printf("kinesis : You have configured a StreamName %+v which does not exist. exiting.", k.StreamName)
-		os.Exit(1)
+	if k.Debug {
+		k.Log.Info("Stream Exists")
 	}
-	return err
+	k.svc = svc
+	return nil
 }
 
 func (k *KinesisOutput) Close() error {
@@ -115,64 +196,159 @@ func FormatMetric(k *KinesisOutput, point telegraf.Metric) (string, error) {
 	}
 }
 
-func writekinesis(k *KinesisOutput, r []*kinesis.PutRecordsRequestEntry) time.Duration {
+// partitionKey returns the partition key of the metric
+func (k *KinesisOutput) partitionKey(metric telegraf.Metric) string {
+	switch k.PartitionMethod {
+	case "random":
+		return strconv.FormatInt(rand.Int63(), 16)
+	case "tag":
+		if v := metric.Tags()[k.PartitionTag]; v != "" {
+			return v
+		}
+		return k.PartitionDefault
+	case "measurement":
+		return metric.Name()
+	default:
+		return k.PartitionKey
+	}
+}
+
+// records returns the records of the metrics, the metrics of a partition
+// key being aggregated in records of up to maxRecordSize bytes with
+// Aggregate
+func (k *KinesisOutput) records(
+	metrics []telegraf.Metric,
+) []*kinesis.PutRecordsRequestEntry {
+	var records []*kinesis.PutRecordsRequestEntry
+	// The aggregated records of each partition key, in the order of their
+	// first metric
+	var keys []string
+	aggregates := make(map[string]*kinesis.PutRecordsRequestEntry)
+
+	for _, p := range metrics {
+		var data []byte
+		if k.serializer != nil {
+			buf, err := k.serializer.Serialize(p)
+			if err != nil {
+				k.Log.Errorf("Could not serialize metric %s: %s", p.Name(),
+					err)
+				continue
+			}
+			data = buf
+		} else {
+			metric, _ := FormatMetric(k, p)
+			data = []byte(metric)
+		}
+		key := k.partitionKey(p)
+		if len(data)+len(key) > maxRecordSize {
+			k.Log.Errorf("Dropping metric %s of %d bytes, larger than a "+
+				"record", p.Name(), len(data))
+			continue
+		}
+		if !k.Aggregate {
+			records = append(records, &kinesis.PutRecordsRequestEntry{
+				Data:         data,
+				PartitionKey: aws.String(key),
+			})
+			continue
+		}
+
+		// The random keys are drawn per record
+		group := key
+		if k.PartitionMethod == "random" {
+			group = ""
+		}
+		r, ok := aggregates[group]
+		if ok && len(r.Data)+len(data)+len(*r.PartitionKey) > maxRecordSize {
+			records = append(records, r)
+			ok = false
+		}
+		if !ok {
+			r = &kinesis.PutRecordsRequestEntry{PartitionKey: aws.String(key)}
+			if _, seen := aggregates[group]; !seen {
+				keys = append(keys, group)
+			}
+			aggregates[group] = r
+		}
+		r.Data = append(r.Data, data...)
+	}
+	for _, group := range keys {
+		records = append(records, aggregates[group])
+	}
+	return records
+}
+
+// batches splits the records in the batches of the PutRecords requests
+func batches(
+	records []*kinesis.PutRecordsRequestEntry,
+) [][]*kinesis.PutRecordsRequestEntry {
+	var batches [][]*kinesis.PutRecordsRequestEntry
+	var batch []*kinesis.PutRecordsRequestEntry
+	size := 0
+	for _, r := range records {
+		rsize := len(r.Data) + len(*r.PartitionKey)
+		if len(batch) == maxRecordsPerRequest ||
+			(len(batch) > 0 && size+rsize > maxRequestSize) {
+			batches = append(batches, batch)
+			batch = nil
+			size = 0
+		}
+		batch = append(batch, r)
+		size += rsize
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func writekinesis(k *KinesisOutput, r []*kinesis.PutRecordsRequestEntry) (time.Duration, error) {
 	start := time.Now()
 	payload := &kinesis.PutRecordsInput{
 		Records:    r,
 		StreamName: aws.String(k.StreamName),
 	}
 
+	resp, err := k.svc.PutRecords(payload)
+	if err != nil {
+		return time.Since(start), fmt.Errorf("Unable to write to Kinesis: %s",
+			err)
+	}
 	if k.Debug {
-		resp, err := k.svc.PutRecords(payload)
-		if err != nil {
-			log.Printf("kinesis: Unable to write to Kinesis : %+v \n", err.Error())
-		}
-		log.Printf("%+v \n", resp)
-
-	} else {
-		_, err := k.svc.PutRecords(payload)
-		if err != nil {
-			log.Printf("kinesis: Unable to write to Kinesis : %+v \n", err.Error())
+		k.Log.Infof("%+v", resp)
+	}
+	if resp.FailedRecordCount != nil && *resp.FailedRecordCount > 0 {
+		for _, entry := range resp.Records {
+			if entry.ErrorCode != nil {
+				k.Log.Errorf("Could not put a record: %s: %s",
+					*entry.ErrorCode, aws.StringValue(entry.ErrorMessage))
+				break
+			}
 		}
+		return time.Since(start), fmt.Errorf("Unable to write %d of %d "+
+			"records to Kinesis", *resp.FailedRecordCount, len(r))
 	}
-	return time.Since(start)
+	return time.Since(start), nil
 }
 
 func (k *KinesisOutput) Write(metrics []telegraf.Metric) error {
-	var sz uint32 = 0
-
 	if len(metrics) == 0 {
 		return nil
 	}
 
-	r := []*kinesis.PutRecordsRequestEntry{}
-
-	for _, p := range metrics {
-		atomic.AddUint32(&sz, 1)
-
-		metric, _ := FormatMetric(k, p)
-		d := kinesis.PutRecordsRequestEntry{
-			Data:         []byte(metric),
-			PartitionKey: aws.String(k.PartitionKey),
-		}
-		r = append(r, &d)
-
-		if sz == 500 {
-			// Max Messages Per PutRecordRequest is 500
-			elapsed := writekinesis(k, r)
-			log.Printf("Wrote a %+v point batch to Kinesis in %+v.\n", sz, elapsed)
-			atomic.StoreUint32(&sz, 0)
-			r = nil
+	for _, batch := range batches(k.records(metrics)) {
+		elapsed, err := writekinesis(k, batch)
+		if err != nil {
+			return err
 		}
+		k.Log.Debugf("Wrote a %d record batch to Kinesis in %s.",
+			len(batch), elapsed)
 	}
-
-	writekinesis(k, r)
-
 	return nil
 }
 
 func init() {
 	outputs.Add("kinesis", func() telegraf.Output {
-		return &KinesisOutput{}
+		return &KinesisOutput{UseSystemProxy: true}
 	})
 }
@@ -1,9 +1,15 @@
 package kinesis
 
 import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
 func TestFormatMetric(t *testing.T) {
@@ -37,3 +43,111 @@ func TestFormatMetric(t *testing.T) {
 	}
 	require.NoError(t, err)
 }
+
+func TestPartitionKey(t *testing.T) {
+	m1, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"value": 1.0})
+	m2, _ := telegraf.NewMetric("mem", map[string]string{},
+		map[string]interface{}{"value": 1.0})
+
+	k := &KinesisOutput{PartitionKey: "telegraf"}
+	assert.Equal(t, "telegraf", k.partitionKey(m1))
+	k.PartitionMethod = "static"
+	assert.Equal(t, "telegraf", k.partitionKey(m1))
+
+	k = &KinesisOutput{PartitionMethod: "tag", PartitionTag: "host",
+		PartitionDefault: "none"}
+	assert.Equal(t, "web01", k.partitionKey(m1))
+	assert.Equal(t, "none", k.partitionKey(m2))
+
+	k = &KinesisOutput{PartitionMethod: "measurement"}
+	assert.Equal(t, "cpu", k.partitionKey(m1))
+	assert.Equal(t, "mem", k.partitionKey(m2))
+
+	k = &KinesisOutput{PartitionMethod: "random"}
+	assert.NotEqual(t, "", k.partitionKey(m1))
+	assert.NotEqual(t, k.partitionKey(m1), k.partitionKey(m1))
+}
+
+func TestRecords(t *testing.T) {
+	m1, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"value": 1.0})
+	m2, _ := telegraf.NewMetric("mem", map[string]string{},
+		map[string]interface{}{"value": 2.0})
+	m3, _ := telegraf.NewMetric("disk", map[string]string{"host": "web01"},
+		map[string]interface{}{"value": 3.0})
+	metrics := []telegraf.Metric{m1, m2, m3}
+
+	k := &KinesisOutput{
+		PartitionMethod:  "tag",
+		PartitionTag:     "host",
+		PartitionDefault: "none",
+		serializer:       &influx.Influx{},
+		Log:              testutil.Logger{},
+	}
+	records := k.records(metrics)
+	require.Len(t, records, 3)
+	assert.Equal(t, m1.String()+"\n", string(records[0].Data))
+	assert.Equal(t, "web01", *records[0].PartitionKey)
+	assert.Equal(t, "none", *records[1].PartitionKey)
+
+	// The metrics of a partition key are aggregated
+	k.Aggregate = true
+	records = k.records(metrics)
+	require.Len(t, records, 2)
+	assert.Equal(t, m1.String()+"\n"+m3.String()+"\n",
+		string(records[0].Data))
+	assert.Equal(t, "web01", *records[0].PartitionKey)
+	assert.Equal(t, m2.String()+"\n", string(records[1].Data))
+	assert.Equal(t, "none", *records[1].PartitionKey)
+
+	// In a single record with random keys
+	k.PartitionMethod = "random"
+	records = k.records(metrics)
+	require.Len(t, records, 1)
+
+	// With the legacy format
+	k = &KinesisOutput{Format: "string", Log: testutil.Logger{}}
+	records = k.records(metrics)
+	require.Len(t, records, 3)
+	assert.Equal(t, m1.String(), string(records[0].Data))
+}
+
+func TestBatches(t *testing.T) {
+	var records []*kinesis.PutRecordsRequestEntry
+	for i := 0; i < maxRecordsPerRequest+1; i++ {
+		records = append(records, &kinesis.PutRecordsRequestEntry{
+			Data:         []byte("cpu value=1\n"),
+			PartitionKey: aws.String("telegraf"),
+		})
+	}
+	b := batches(records)
+	require.Len(t, b, 2)
+	assert.Len(t, b[0], maxRecordsPerRequest)
+	assert.Len(t, b[1], 1)
+
+	// Batches of at most maxRequestSize bytes
+	large := make([]byte, maxRecordSize-10)
+	records = nil
+	for i := 0; i < 6; i++ {
+		records = append(records, &kinesis.PutRecordsRequestEntry{
+			Data:         large,
+			PartitionKey: aws.String("telegraf"),
+		})
+	}
+	b = batches(records)
+	require.Len(t, b, 2)
+	assert.Len(t, b[0], 5)
+	assert.Len(t, b[1], 1)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, k := range []*KinesisOutput{
+		{PartitionMethod: "unknown"},
+		{PartitionMethod: "tag"},
+		{DataFormat: "unknown"},
+	} {
+		k.Log = testutil.Logger{}
+		assert.Error(t, k.Connect())
+	}
+}
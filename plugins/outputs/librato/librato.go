@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/influxdata/telegraf"
@@ -13,10 +12,14 @@ import (
 )
 
 type Librato struct {
-	ApiUser   string
-	ApiToken  string
-	SourceTag string
-	Timeout   internal.Duration
+	ApiUser        string
+	ApiToken       string
+	SourceTag      string
+	Timeout        internal.Duration
+	HTTPProxy      string          `toml:"http_proxy"`
+	HTTPSProxy     string          `toml:"https_proxy"`
+	UseSystemProxy bool            `toml:"use_system_proxy"`
+	Log            telegraf.Logger `toml:"-"`
 
 	apiUrl string
 	client *http.Client
@@ -38,6 +41,13 @@ var sampleConfig = `
 
   # Connection timeout.
   # timeout = "5s"
+
+  # Proxies of the http and https requests, ie "http://proxy:3128". Requests
+  # without a proxy set use the proxy of the HTTP_PROXY, HTTPS_PROXY and
+  # NO_PROXY environment variables, unless use_system_proxy is false.
+  # http_proxy = ""
+  # https_proxy = ""
+  # use_system_proxy = true
 `
 
 type LMetrics struct {
@@ -55,7 +65,8 @@ const librato_api = "https://metrics-api.librato.com/v1/metrics"
 
 func NewLibrato(apiUrl string) *Librato {
 	return &Librato{
-		apiUrl: apiUrl,
+		apiUrl:         apiUrl,
+		UseSystemProxy: true,
 	}
 }
 
@@ -63,9 +74,16 @@ func (l *Librato) Connect() error {
 	if l.ApiUser == "" || l.ApiToken == "" {
 		return fmt.Errorf("api_user and api_token are required fields for librato output")
 	}
-	l.client = &http.Client{
-		Timeout: l.Timeout.Duration,
+	client, err := internal.NewHTTPClient(l.Timeout.Duration,
+		internal.HTTPProxyOptions{
+			HTTPProxy:      l.HTTPProxy,
+			HTTPSProxy:     l.HTTPSProxy,
+			UseSystemProxy: l.UseSystemProxy,
+		})
+	if err != nil {
+		return err
 	}
+	l.client = client
 	return nil
 }
 
@@ -84,7 +102,7 @@ func (l *Librato) Write(metrics []telegraf.Metric) error {
 				metricCounter++
 			}
 		} else {
-			log.Printf("unable to build Gauge for %s, skipping\n", m.Name())
+			l.Log.Warnf("Unable to build Gauge for %s, skipping", m.Name())
 		}
 	}
 
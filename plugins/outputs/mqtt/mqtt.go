@@ -1,24 +1,30 @@
 package mqtt
 
 import (
+	"bytes"
 	"crypto/rand"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io/ioutil"
+	"os"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	paho "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
 const MaxClientIdLen = 8
 const MaxRetryCount = 3
 const ClientIdPrefix = "telegraf"
 
+// topicSanitizer replaces the separator and the wildcards of the topics in
+// the values of their templates
+var topicSanitizer = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
 type MQTT struct {
 	Servers     []string `toml:"servers"`
 	Username    string
@@ -26,46 +32,147 @@ type MQTT struct {
 	Database    string
 	Timeout     internal.Duration
 	TopicPrefix string
+	// Topic is the template of the topics of the metrics, the legacy
+	// "<topic_prefix>/host/<hostname>/<name>/<suffix>" topics being used if
+	// it is empty
+	Topic string `toml:"topic"`
+	// QoS and retain flag of the messages
+	QoS    int  `toml:"qos"`
+	Retain bool `toml:"retain"`
+	// Batch publishes the metrics of a topic in a single message per write
+	Batch     bool              `toml:"batch"`
+	ClientID  string            `toml:"client_id"`
+	KeepAlive internal.Duration `toml:"keep_alive"`
+
+	// Last will and testament, published by the broker if the connection
+	// is lost
+	WillTopic   string `toml:"will_topic"`
+	WillPayload string `toml:"will_payload"`
+	WillQoS     int    `toml:"will_qos"`
+	WillRetain  bool   `toml:"will_retain"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
 
-	Client *paho.Client
-	Opts   *paho.ClientOptions
+	// Data format of the messages
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
+
+	client     *paho.Client
+	topic      *template.Template
+	serializer telegraf.Serializer
 	sync.Mutex
 }
 
+// topicData is the data of the topic templates
+type topicData struct {
+	// Hostname is the host tag of the metric, the hostname of the agent if
+	// it is not set
+	Hostname string
+	Name     string
+	Tags     map[string]string
+}
+
 var sampleConfig = `
   servers = ["localhost:1883"] # required.
 
-  # MQTT outputs send metrics to this topic format
+  # Template of the topics of the metrics, with the fields .Hostname, the
+  # host tag or the hostname of the agent, .Name, the measurement, and
+  # .Tags, ie "telegraf/{{ .Hostname }}/{{ .Name }}/{{ .Tags.cpu }}".
+  # The "/", "+" and "#" of the values are replaced by underscores.
+  # If not set, metrics are sent to this topic format
   #    "<topic_prefix>/host/<hostname>/<pluginname>/"
   #   ex: prefix/host/web01.example.com/mem/available
+  # topic = "telegraf/{{ .Hostname }}/{{ .Name }}"
   # topic_prefix = "prefix"
 
+  # QoS of the messages, 0, 1 or 2, and whether they are retained by the
+  # broker for the subscribers to come
+  # qos = 0
+  # retain = false
+  # Publish the metrics of a topic in a single message per write
+  # batch = false
+
+  # Timeout of the connection and of the messages of QoS 1 and 2
+  # timeout = "5s"
+  # Interval of the keep alive pings
+  # keep_alive = "30s"
+  # client_id = "telegraf"
+
   # username and password to connect MQTT server.
   # username = "telegraf"
   # password = "metricsmetricsmetricsmetrics"
+
+  # Last will and testament, published by the broker if telegraf loses its
+  # connection
+  # will_topic = "telegraf/status"
+  # will_payload = "offline"
+  # will_qos = 0
+  # will_retain = false
+
+  # TLS options, connecting with ssl:// when set
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Data format of the messages, "influx" or "graphite"
+  # data_format = "influx"
 `
 
 func (m *MQTT) Connect() error {
-	var err error
 	m.Lock()
 	defer m.Unlock()
 
-	m.Opts, err = m.CreateOpts()
+	if m.Timeout.Duration == 0 {
+		m.Timeout.Duration = 5 * time.Second
+	}
+	if m.QoS < 0 || m.QoS > 2 {
+		return fmt.Errorf("invalid qos %d, must be 0, 1 or 2", m.QoS)
+	}
+	if m.WillQoS < 0 || m.WillQoS > 2 {
+		return fmt.Errorf("invalid will_qos %d, must be 0, 1 or 2", m.WillQoS)
+	}
+	m.topic = nil
+	if m.Topic != "" {
+		topic, err := newTopicTemplate(m.Topic)
+		if err != nil {
+			return err
+		}
+		m.topic = topic
+	}
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: m.DataFormat,
+	})
 	if err != nil {
 		return err
 	}
+	m.serializer = serializer
 
-	m.Client = paho.NewClient(m.Opts)
-	if token := m.Client.Connect(); token.Wait() && token.Error() != nil {
+	opts, err := m.createOpts()
+	if err != nil {
+		return err
+	}
+	client := paho.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(m.Timeout.Duration) {
+		return fmt.Errorf("could not connect to MQTT server: timed out after %s",
+			m.Timeout.Duration)
+	}
+	if token.Error() != nil {
 		return token.Error()
 	}
-
+	m.client = client
 	return nil
 }
 
 func (m *MQTT) Close() error {
-	if m.Client.IsConnected() {
-		m.Client.Disconnect(20)
+	m.Lock()
+	defer m.Unlock()
+	if m.client != nil && m.client.IsConnected() {
+		m.client.Disconnect(20)
 	}
 	return nil
 }
@@ -78,33 +185,105 @@ func (m *MQTT) Description() string {
 	return "Configuration for MQTT server to send metrics to"
 }
 
+// legacyTopic returns the topic of the metric without topic template
+func (m *MQTT) legacyTopic(metric telegraf.Metric, hostname string) string {
+	var t []string
+	if m.TopicPrefix != "" {
+		t = append(t, m.TopicPrefix)
+	}
+	tm := strings.Split(metric.Name(), "_")
+	if len(tm) < 2 {
+		tm = []string{metric.Name(), "stat"}
+	}
+	t = append(t, "host", hostname, tm[0], tm[1])
+	return strings.Join(t, "/")
+}
+
+// newTopicTemplate parses a topic template, the missing tags being empty
+func newTopicTemplate(topic string) (*template.Template, error) {
+	t, err := template.New("topic").Option("missingkey=zero").Parse(topic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid topic %q: %s", topic, err)
+	}
+	return t, nil
+}
+
+// topicOf returns the topic of the metric built from the topic template
+func (m *MQTT) topicOf(metric telegraf.Metric) (string, error) {
+	data := topicData{
+		Name: topicSanitizer.Replace(metric.Name()),
+		Tags: make(map[string]string),
+	}
+	for k, v := range metric.Tags() {
+		data.Tags[k] = topicSanitizer.Replace(v)
+	}
+	data.Hostname = data.Tags["host"]
+	if data.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		data.Hostname = topicSanitizer.Replace(hostname)
+	}
+
+	var buf bytes.Buffer
+	if err := m.topic.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("empty topic")
+	}
+	return buf.String(), nil
+}
+
 func (m *MQTT) Write(metrics []telegraf.Metric) error {
 	m.Lock()
 	defer m.Unlock()
 	if len(metrics) == 0 {
 		return nil
 	}
+	if m.client == nil {
+		return fmt.Errorf("not connected to MQTT server")
+	}
 	hostname, ok := metrics[0].Tags()["host"]
 	if !ok {
 		hostname = ""
 	}
 
+	// The batches are published in the order of their first metric
+	var topics []string
+	batches := make(map[string][]byte)
 	for _, p := range metrics {
-		var t []string
-		if m.TopicPrefix != "" {
-			t = append(t, m.TopicPrefix)
+		var topic string
+		if m.topic == nil {
+			topic = m.legacyTopic(p, hostname)
+		} else {
+			var err error
+			topic, err = m.topicOf(p)
+			if err != nil {
+				m.Log.Errorf("Could not build the topic of metric %s: %s",
+					p.Name(), err)
+				continue
+			}
 		}
-		tm := strings.Split(p.Name(), "_")
-		if len(tm) < 2 {
-			tm = []string{p.Name(), "stat"}
-		}
-
-		t = append(t, "host", hostname, tm[0], tm[1])
-		topic := strings.Join(t, "/")
-
-		value := p.String()
-		err := m.publish(topic, value)
+		buf, err := m.serializer.Serialize(p)
 		if err != nil {
+			m.Log.Errorf("Could not serialize metric %s: %s", p.Name(), err)
+			continue
+		}
+		if !m.Batch {
+			if err := m.publish(topic, buf); err != nil {
+				return fmt.Errorf("Could not write to MQTT server, %s", err)
+			}
+			continue
+		}
+		if _, ok := batches[topic]; !ok {
+			topics = append(topics, topic)
+		}
+		batches[topic] = append(batches[topic], buf...)
+	}
+	for _, topic := range topics {
+		if err := m.publish(topic, batches[topic]); err != nil {
 			return fmt.Errorf("Could not write to MQTT server, %s", err)
 		}
 	}
@@ -112,51 +291,60 @@ func (m *MQTT) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
-func (m *MQTT) publish(topic, body string) error {
-	token := m.Client.Publish(topic, 0, false, body)
-	token.Wait()
-	if token.Error() != nil {
-		return token.Error()
+// publish publishes the message, waiting for its acknowledgement up to the
+// timeout with QoS 1 and 2
+func (m *MQTT) publish(topic string, body []byte) error {
+	token := m.client.Publish(topic, byte(m.QoS), m.Retain, body)
+	if !token.WaitTimeout(m.Timeout.Duration) {
+		return fmt.Errorf("publishing to %s timed out after %s", topic,
+			m.Timeout.Duration)
 	}
-	return nil
+	return token.Error()
 }
 
-func (m *MQTT) CreateOpts() (*paho.ClientOptions, error) {
+func (m *MQTT) createOpts() (*paho.ClientOptions, error) {
 	opts := paho.NewClientOptions()
 
-	clientId := getRandomClientId()
-	opts.SetClientID(clientId)
+	if m.ClientID == "" {
+		opts.SetClientID(getRandomClientId())
+	} else {
+		opts.SetClientID(m.ClientID)
+	}
+	if m.KeepAlive.Duration > 0 {
+		opts.SetKeepAlive(m.KeepAlive.Duration)
+	}
+	if m.WillTopic != "" {
+		opts.SetWill(m.WillTopic, m.WillPayload, byte(m.WillQoS),
+			m.WillRetain)
+	}
 
-	TLSConfig := &tls.Config{InsecureSkipVerify: false}
-	ca := "" // TODO
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              m.SSLCA,
+		SSLCert:            m.SSLCert,
+		SSLKey:             m.SSLKey,
+		InsecureSkipVerify: m.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
 	scheme := "tcp"
-	if ca != "" {
+	if tlsConfig != nil {
 		scheme = "ssl"
-		certPool, err := getCertPool(ca)
-		if err != nil {
-			return nil, err
-		}
-		TLSConfig.RootCAs = certPool
+		opts.SetTLSConfig(tlsConfig)
 	}
-	TLSConfig.InsecureSkipVerify = true // TODO
-	opts.SetTLSConfig(TLSConfig)
 
-	user := m.Username
-	if user == "" {
-		opts.SetUsername(user)
+	if m.Username != "" {
+		opts.SetUsername(m.Username)
 	}
-	password := m.Password
-	if password != "" {
-		opts.SetPassword(password)
+	if m.Password != "" {
+		opts.SetPassword(m.Password)
 	}
 
 	if len(m.Servers) == 0 {
-		return opts, fmt.Errorf("could not get host infomations")
+		return nil, fmt.Errorf("could not get host infomations")
 	}
 	for _, host := range m.Servers {
-		server := fmt.Sprintf("%s://%s", scheme, host)
-
-		opts.AddBroker(server)
+		opts.AddBroker(fmt.Sprintf("%s://%s", scheme, host))
 	}
 	opts.SetAutoReconnect(true)
 	return opts, nil
@@ -172,17 +360,6 @@ func getRandomClientId() string {
 	return ClientIdPrefix + "-" + string(bytes)
 }
 
-func getCertPool(pemPath string) (*x509.CertPool, error) {
-	certs := x509.NewCertPool()
-
-	pemData, err := ioutil.ReadFile(pemPath)
-	if err != nil {
-		return nil, err
-	}
-	certs.AppendCertsFromPEM(pemData)
-	return certs, nil
-}
-
 func init() {
 	outputs.Add("mqtt", func() telegraf.Output {
 		return &MQTT{}
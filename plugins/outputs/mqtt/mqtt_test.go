@@ -1,9 +1,12 @@
 package mqtt
 
 import (
+	"os"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,6 +18,7 @@ func TestConnectAndWrite(t *testing.T) {
 	var url = testutil.GetLocalHost() + ":1883"
 	m := &MQTT{
 		Servers: []string{url},
+		Log:     testutil.Logger{},
 	}
 
 	// Verify that we can connect to the MQTT broker
@@ -24,4 +28,67 @@ func TestConnectAndWrite(t *testing.T) {
 	// Verify that we can successfully write data to the mqtt broker
 	err = m.Write(testutil.MockMetrics())
 	require.NoError(t, err)
+
+	// And with a topic template, QoS 1 and batches
+	m.Close()
+	m.Topic = "telegraf/{{ .Hostname }}/{{ .Name }}"
+	m.QoS = 1
+	m.Batch = true
+	require.NoError(t, m.Connect())
+	defer m.Close()
+	require.NoError(t, m.Write(testutil.MockMetrics()))
+}
+
+func TestLegacyTopic(t *testing.T) {
+	m1, _ := telegraf.NewMetric("mem", map[string]string{"host": "web01"},
+		map[string]interface{}{"available": 1.0})
+	m2, _ := telegraf.NewMetric("net_packets", map[string]string{},
+		map[string]interface{}{"value": 1.0})
+
+	m := &MQTT{TopicPrefix: "prefix"}
+	assert.Equal(t, "prefix/host/web01/mem/stat", m.legacyTopic(m1, "web01"))
+	assert.Equal(t, "prefix/host/web01/net/packets",
+		m.legacyTopic(m2, "web01"))
+}
+
+func TestTopicTemplate(t *testing.T) {
+	m1, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "web01", "cpu": "cpu0", "site": "a/b+#"},
+		map[string]interface{}{"value": 1.0})
+	m2, _ := telegraf.NewMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1.0})
+
+	tmpl, err := newTopicTemplate(
+		"telegraf/{{ .Hostname }}/{{ .Name }}/{{ .Tags.site }}/{{ .Tags.cpu }}")
+	require.NoError(t, err)
+	m := &MQTT{topic: tmpl}
+	topic, err := m.topicOf(m1)
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf/web01/cpu/a_b__/cpu0", topic)
+
+	// The hostname of the agent without host tag, the missing tags empty
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	topic, err = m.topicOf(m2)
+	require.NoError(t, err)
+	assert.Equal(t, "telegraf/"+topicSanitizer.Replace(hostname)+"/cpu//",
+		topic)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, m := range []*MQTT{
+		{Servers: []string{"127.0.0.1:1"}, QoS: 3},
+		{Servers: []string{"127.0.0.1:1"}, WillQoS: -1},
+		{Servers: []string{"127.0.0.1:1"}, Topic: "telegraf/{{ .Name"},
+		{Servers: []string{"127.0.0.1:1"}, DataFormat: "unknown"},
+		// No servers
+		{},
+	} {
+		m.Log = testutil.Logger{}
+		assert.Error(t, m.Connect())
+	}
+
+	// Not connected
+	m := &MQTT{Servers: []string{"127.0.0.1:1"}}
+	assert.Error(t, m.Write(testutil.MockMetrics()))
 }
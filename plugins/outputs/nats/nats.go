@@ -0,0 +1,253 @@
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/nats-io/nats"
+)
+
+type NATS struct {
+	Servers []string
+	// Subject the batches of metrics are published to
+	Subject  string
+	Username string
+	Password string
+
+	// JetStream publishes the batches as requests, the write failing unless
+	// the stream of the subject acknowledges them within Timeout
+	JetStream bool              `toml:"jetstream"`
+	Timeout   internal.Duration `toml:"timeout"`
+
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Data format of the messages
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	conn       *nats.Conn
+	serializer telegraf.Serializer
+}
+
+// pubAck is the acknowledgement of a message by a JetStream stream
+type pubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+var sampleConfig = `
+  servers = ["nats://localhost:4222"]
+  # Subject the metrics are published to
+  subject = "telegraf"
+
+  # username and password to connect to the NATS servers
+  # username = "telegraf"
+  # password = "metricsmetricsmetricsmetrics"
+
+  # Wait for the JetStream stream of the subject to acknowledge the messages,
+  # the write failing if they are not acknowledged within the timeout
+  # jetstream = false
+  # Timeout of the connection, and of the flushes or acknowledgements
+  # timeout = "5s"
+
+  # TLS options
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Data format of the messages, "influx" or "graphite"
+  # data_format = "influx"
+`
+
+func (n *NATS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NATS) Description() string {
+	return "Send metrics to a NATS subject"
+}
+
+func (n *NATS) Connect() error {
+	n.Lock()
+	defer n.Unlock()
+
+	if n.Subject == "" {
+		return fmt.Errorf("no NATS subject set")
+	}
+	if n.Timeout.Duration == 0 {
+		n.Timeout.Duration = 5 * time.Second
+	}
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: n.DataFormat,
+	})
+	if err != nil {
+		return err
+	}
+	n.serializer = serializer
+
+	opts, err := n.createOpts()
+	if err != nil {
+		return err
+	}
+	conn, err := opts.Connect()
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	return nil
+}
+
+func (n *NATS) Close() error {
+	n.Lock()
+	defer n.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
+
+// batches returns the serialized metrics in messages of at most maxSize
+// bytes, the metrics larger than that being dropped
+func (n *NATS) batches(metrics []telegraf.Metric, maxSize int) [][]byte {
+	var batches [][]byte
+	var batch []byte
+	for _, metric := range metrics {
+		buf, err := n.serializer.Serialize(metric)
+		if err != nil {
+			n.Log.Errorf("Could not serialize metric %s: %s", metric.Name(),
+				err)
+			continue
+		}
+		if len(buf) > maxSize {
+			n.Log.Errorf("Dropping metric %s of %d bytes, larger than the "+
+				"maximum payload of %d bytes", metric.Name(), len(buf), maxSize)
+			continue
+		}
+		if len(batch)+len(buf) > maxSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+		batch = append(batch, buf...)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// checkPubAck returns the error of the acknowledgement of a message by
+// JetStream
+func checkPubAck(data []byte) error {
+	var ack pubAck
+	if err := json.Unmarshal(data, &ack); err != nil {
+		return fmt.Errorf("invalid JetStream acknowledgement %q: %s",
+			string(data), err)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("JetStream error %d: %s", ack.Error.Code,
+			ack.Error.Description)
+	}
+	if ack.Stream == "" {
+		return fmt.Errorf("invalid JetStream acknowledgement %q", string(data))
+	}
+	return nil
+}
+
+func (n *NATS) Write(metrics []telegraf.Metric) error {
+	n.Lock()
+	defer n.Unlock()
+	if len(metrics) == 0 {
+		return nil
+	}
+	if n.conn == nil {
+		return fmt.Errorf("not connected to NATS")
+	}
+
+	for _, batch := range n.batches(metrics, int(n.conn.MaxPayload())) {
+		if n.JetStream {
+			msg, err := n.conn.Request(n.Subject, batch, n.Timeout.Duration)
+			if err != nil {
+				return fmt.Errorf("could not publish to subject %s: %s",
+					n.Subject, err)
+			}
+			if err := checkPubAck(msg.Data); err != nil {
+				return fmt.Errorf("could not publish to subject %s: %s",
+					n.Subject, err)
+			}
+			continue
+		}
+		if err := n.conn.Publish(n.Subject, batch); err != nil {
+			return fmt.Errorf("could not publish to subject %s: %s",
+				n.Subject, err)
+		}
+	}
+	if !n.JetStream {
+		// The messages are buffered by the client until flushed
+		if err := n.conn.FlushTimeout(n.Timeout.Duration); err != nil {
+			return fmt.Errorf("could not flush to NATS: %s", err)
+		}
+	}
+	return nil
+}
+
+func (n *NATS) createOpts() (nats.Options, error) {
+	opts := nats.DefaultOptions
+	if len(n.Servers) == 0 {
+		return opts, fmt.Errorf("no NATS servers set")
+	}
+	// The credentials are given by the URLs of the servers
+	for _, server := range n.Servers {
+		u, err := url.Parse(server)
+		if err != nil {
+			return opts, fmt.Errorf("invalid NATS server %q: %s", server, err)
+		}
+		if n.Username != "" {
+			u.User = url.UserPassword(n.Username, n.Password)
+		}
+		opts.Servers = append(opts.Servers, u.String())
+	}
+
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              n.SSLCA,
+		SSLCert:            n.SSLCert,
+		SSLKey:             n.SSLKey,
+		InsecureSkipVerify: n.InsecureSkipVerify,
+	})
+	if err != nil {
+		return opts, err
+	}
+	if tlsConfig != nil {
+		opts.Secure = true
+		opts.TLSConfig = tlsConfig
+	}
+
+	opts.Name = "telegraf"
+	opts.Timeout = n.Timeout.Duration
+	// Reconnect forever, the messages published while disconnected being
+	// buffered by the client
+	opts.MaxReconnect = -1
+	return opts, nil
+}
+
+func init() {
+	outputs.Add("nats", func() telegraf.Output {
+		return &NATS{}
+	})
+}
@@ -0,0 +1,82 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectAndWrite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	server := []string{"nats://" + testutil.GetLocalHost() + ":4222"}
+	n := &NATS{
+		Servers: server,
+		Subject: "telegraf",
+		Log:     testutil.Logger{},
+	}
+
+	// Verify that we can connect to the NATS daemon
+	err := n.Connect()
+	require.NoError(t, err)
+	defer n.Close()
+
+	// Verify that we can successfully write data to the NATS daemon
+	err = n.Write(testutil.MockMetrics())
+	require.NoError(t, err)
+}
+
+func TestBatches(t *testing.T) {
+	var metrics []telegraf.Metric
+	for _, name := range []string{"cpu", "mem", "net", "swp"} {
+		m, _ := telegraf.NewMetric(name, map[string]string{},
+			map[string]interface{}{"value": 1.0})
+		metrics = append(metrics, m)
+	}
+	// A metric larger than the maximum payload
+	m, _ := telegraf.NewMetric("large",
+		map[string]string{"tag": "a very long tag value"},
+		map[string]interface{}{"value": 1.0})
+	metrics = append(metrics, m)
+
+	n := &NATS{serializer: &influx.Influx{}, Log: testutil.Logger{}}
+	size := len(metrics[0].String()+"\n") + len(metrics[1].String()+"\n")
+	batches := n.batches(metrics, size)
+	require.Len(t, batches, 2)
+	assert.Equal(t, metrics[0].String()+"\n"+metrics[1].String()+"\n",
+		string(batches[0]))
+	assert.Equal(t, metrics[2].String()+"\n"+metrics[3].String()+"\n",
+		string(batches[1]))
+
+	assert.Empty(t, n.batches(nil, size))
+}
+
+func TestCheckPubAck(t *testing.T) {
+	assert.NoError(t, checkPubAck([]byte(`{"stream":"telegraf","seq":1}`)))
+	assert.Error(t, checkPubAck(
+		[]byte(`{"error":{"code":503,"description":"no responders"}}`)))
+	assert.Error(t, checkPubAck([]byte(`{}`)))
+	assert.Error(t, checkPubAck([]byte(`+OK`)))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, n := range []*NATS{
+		{Servers: []string{"nats://127.0.0.1:1"}},
+		{Subject: "telegraf"},
+		{Servers: []string{"nats://127.0.0.1:1"}, Subject: "telegraf",
+			DataFormat: "unknown"},
+	} {
+		n.Log = testutil.Logger{}
+		assert.Error(t, n.Connect())
+	}
+
+	// Not connected
+	n := &NATS{Servers: []string{"nats://127.0.0.1:1"}, Subject: "telegraf"}
+	assert.Error(t, n.Write(testutil.MockMetrics()))
+}
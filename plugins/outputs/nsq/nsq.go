@@ -11,6 +11,9 @@ type NSQ struct {
 	Server   string
 	Topic    string
 	producer *nsq.Producer
+	// buf holds the metric being published, reused as writes are not
+	// concurrent
+	buf []byte
 }
 
 var sampleConfig = `
@@ -51,9 +54,9 @@ func (n *NSQ) Write(metrics []telegraf.Metric) error {
 	}
 
 	for _, p := range metrics {
-		value := p.String()
+		n.buf = p.AppendTo(n.buf[:0])
 
-		err := n.producer.Publish(n.Topic, []byte(value))
+		err := n.producer.Publish(n.Topic, n.buf)
 
 		if err != nil {
 			return fmt.Errorf("FAILED to send NSQD message: %s", err)
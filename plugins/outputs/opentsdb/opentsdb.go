@@ -1,37 +1,86 @@
 package opentsdb
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"net/http"
+	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
+// invalidChars are the characters not allowed by OpenTSDB in the names of
+// the metrics and the tag keys and values
+var invalidChars = regexp.MustCompile(`[^a-zA-Z0-9\-_./\p{L}]`)
+
 type OpenTSDB struct {
 	Prefix string
 
+	// Host is the address of the server, prefixed by http:// or https:// in
+	// HTTP mode
 	Host string
 	Port int
 
+	// HTTPBatchSize is the number of data points sent per request in HTTP
+	// mode, HTTPPath the path of the put endpoint
+	HTTPBatchSize int    `toml:"http_batch_size"`
+	HTTPPath      string `toml:"http_path"`
+
+	Timeout  internal.Duration
+	Username string
+	Password string
+
+	// Optional TLS configuration of the HTTPS mode
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
 	Debug bool
+	Log   telegraf.Logger `toml:"-"`
+
+	client *http.Client
 }
 
 var sampleConfig = `
   # prefix for metrics keys
   prefix = "my.specific.prefix."
 
-  ## Telnet Mode ##
-  # DNS name of the OpenTSDB server in telnet mode
+  # DNS name of the OpenTSDB server, using the telnet mode, or its URL
+  # prefixed by http:// or https:// using the HTTP API, ie
+  # "http://opentsdb.example.com"
   host = "opentsdb.example.com"
 
-  # Port of the OpenTSDB server in telnet mode
+  # Port of the OpenTSDB server
   port = 4242
 
+  ## HTTP Mode ##
+  # Number of data points sent per request
+  http_batch_size = 50
+  # Path of the put endpoint
+  http_path = "/api/put"
+  # Timeout of the connections and requests
+  # timeout = "5s"
+  # Credentials of the requests, with basic auth
+  # username = ""
+  # password = ""
+  # Optional TLS configuration of https
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
   # Debug true - Prints OpenTSDB communication
   debug = false
 `
@@ -43,14 +92,66 @@ type MetricLine struct {
 	Tags      string
 }
 
+// httpMetric is a data point of the HTTP API
+type httpMetric struct {
+	Metric    string            `json:"metric"`
+	Timestamp int64             `json:"timestamp"`
+	Value     json.Number       `json:"value"`
+	Tags      map[string]string `json:"tags"`
+}
+
+// url returns the URL of the server, its scheme being tcp in telnet mode
+func (o *OpenTSDB) url() (*url.URL, error) {
+	host := o.Host
+	if !strings.Contains(host, "://") {
+		host = "tcp://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("OpenTSDB: invalid host %q: %s", o.Host, err)
+	}
+	switch u.Scheme {
+	case "tcp", "http", "https":
+	default:
+		return nil, fmt.Errorf("OpenTSDB: unsupported scheme %q", u.Scheme)
+	}
+	if _, _, err := net.SplitHostPort(u.Host); err != nil {
+		u.Host = net.JoinHostPort(u.Host, strconv.Itoa(o.Port))
+	}
+	return u, nil
+}
+
 func (o *OpenTSDB) Connect() error {
-	// Test Connection to OpenTSDB Server
-	uri := fmt.Sprintf("%s:%d", o.Host, o.Port)
-	tcpAddr, err := net.ResolveTCPAddr("tcp", uri)
+	if o.Timeout.Duration == 0 {
+		o.Timeout.Duration = 5 * time.Second
+	}
+	u, err := o.url()
 	if err != nil {
-		return fmt.Errorf("OpenTSDB: TCP address cannot be resolved")
+		return err
+	}
+	if u.Scheme != "tcp" {
+		c := httpconfig.Config{
+			Timeout:  o.Timeout.Duration,
+			Username: o.Username,
+			Password: o.Password,
+			TLS: internal.TLSOptions{
+				SSLCA:              o.SSLCA,
+				SSLCert:            o.SSLCert,
+				SSLKey:             o.SSLKey,
+				InsecureSkipVerify: o.InsecureSkipVerify,
+			},
+			Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+		}
+		client, err := c.CreateClient()
+		if err != nil {
+			return err
+		}
+		o.client = client
+		return nil
 	}
-	connection, err := net.DialTCP("tcp", nil, tcpAddr)
+
+	// Test Connection to OpenTSDB Server
+	connection, err := net.DialTimeout("tcp", u.Host, o.Timeout.Duration)
 	if err != nil {
 		return fmt.Errorf("OpenTSDB: Telnet connect fail")
 	}
@@ -62,61 +163,143 @@ func (o *OpenTSDB) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
-	now := time.Now()
+	u, err := o.url()
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "tcp" {
+		return o.writeHTTP(u, metrics)
+	}
 
 	// Send Data with telnet / socket communication
-	uri := fmt.Sprintf("%s:%d", o.Host, o.Port)
-	tcpAddr, _ := net.ResolveTCPAddr("tcp", uri)
-	connection, err := net.DialTCP("tcp", nil, tcpAddr)
+	connection, err := net.DialTimeout("tcp", u.Host, o.Timeout.Duration)
 	if err != nil {
 		return fmt.Errorf("OpenTSDB: Telnet connect fail")
 	}
 	defer connection.Close()
 
+	var buf bytes.Buffer
 	for _, m := range metrics {
-		for _, metric := range buildMetrics(m, now, o.Prefix) {
+		for _, metric := range o.buildMetrics(m) {
 			messageLine := fmt.Sprintf("put %s %v %s %s\n",
 				metric.Metric, metric.Timestamp, metric.Value, metric.Tags)
 			if o.Debug {
 				fmt.Print(messageLine)
 			}
-			_, err := connection.Write([]byte(messageLine))
-			if err != nil {
-				return fmt.Errorf("OpenTSDB: Telnet writing error %s", err.Error())
+			buf.WriteString(messageLine)
+		}
+	}
+	connection.SetWriteDeadline(time.Now().Add(o.Timeout.Duration))
+	if _, err := connection.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("OpenTSDB: Telnet writing error %s", err.Error())
+	}
+	return nil
+}
+
+// writeHTTP puts the data points of the metrics with the HTTP API, in batches
+// of http_batch_size data points
+func (o *OpenTSDB) writeHTTP(u *url.URL, metrics []telegraf.Metric) error {
+	batchSize := o.HTTPBatchSize
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	path := o.HTTPPath
+	if path == "" {
+		path = "/api/put"
+	}
+	endpoint := *u
+	endpoint.Path = path
+
+	var batch []httpMetric
+	for _, m := range metrics {
+		for _, metric := range o.buildMetrics(m) {
+			batch = append(batch, httpMetric{
+				Metric:    metric.Metric,
+				Timestamp: metric.Timestamp,
+				Value:     json.Number(metric.Value),
+				Tags:      sanitizeTags(m.Tags()),
+			})
+			if len(batch) == batchSize {
+				if err := o.put(endpoint.String(), batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
 			}
 		}
 	}
+	if len(batch) > 0 {
+		return o.put(endpoint.String(), batch)
+	}
+	return nil
+}
 
+// put posts a batch of data points
+func (o *OpenTSDB) put(endpoint string, batch []httpMetric) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	if o.Debug {
+		fmt.Printf("%s\n", body)
+	}
+	resp, err := o.client.Post(endpoint, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("OpenTSDB: HTTP put error %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("OpenTSDB: HTTP put error %s: %s", resp.Status,
+			bytes.TrimSpace(msg))
+	}
 	return nil
 }
 
-func buildTags(mTags map[string]string) []string {
-	tags := make([]string, len(mTags))
-	index := 0
+// sanitize replaces the characters not allowed by OpenTSDB by underscores
+func sanitize(value string) string {
+	return invalidChars.ReplaceAllString(value, "_")
+}
+
+// sanitizeTags returns the sanitized tags, without the tags with an empty
+// value, which OpenTSDB does not allow
+func sanitizeTags(mTags map[string]string) map[string]string {
+	tags := make(map[string]string, len(mTags))
 	for k, v := range mTags {
-		tags[index] = fmt.Sprintf("%s=%s", k, v)
-		index += 1
+		if v == "" {
+			continue
+		}
+		tags[sanitize(k)] = sanitize(v)
+	}
+	return tags
+}
+
+func buildTags(mTags map[string]string) []string {
+	tags := make([]string, 0, len(mTags))
+	for k, v := range sanitizeTags(mTags) {
+		tags = append(tags, fmt.Sprintf("%s=%s", k, v))
 	}
 	sort.Strings(tags)
 	return tags
 }
 
-func buildMetrics(m telegraf.Metric, now time.Time, prefix string) []*MetricLine {
+func (o *OpenTSDB) buildMetrics(m telegraf.Metric) []*MetricLine {
 	ret := []*MetricLine{}
+	tags := strings.Join(buildTags(m.Tags()), " ")
 	for fieldName, value := range m.Fields() {
 		metric := &MetricLine{
-			Metric:    fmt.Sprintf("%s%s_%s", prefix, m.Name(), fieldName),
-			Timestamp: now.Unix(),
+			Metric: sanitize(fmt.Sprintf("%s%s_%s", o.Prefix, m.Name(),
+				fieldName)),
+			Timestamp: m.Time().Unix(),
 		}
 
 		metricValue, buildError := buildValue(value)
 		if buildError != nil {
-			fmt.Printf("OpenTSDB: %s\n", buildError.Error())
+			o.Log.Debugf("OpenTSDB: %s", buildError.Error())
 			continue
 		}
 		metric.Value = metricValue
-		tagsSlice := buildTags(m.Tags())
-		metric.Tags = fmt.Sprint(strings.Join(tagsSlice, " "))
+		metric.Tags = tags
 		ret = append(ret, metric)
 	}
 	return ret
@@ -131,6 +314,12 @@ func buildValue(v interface{}) (string, error) {
 		retv = UIntToString(uint64(p))
 	case float64:
 		retv = FloatToString(float64(p))
+	case bool:
+		if p {
+			retv = "1"
+		} else {
+			retv = "0"
+		}
 	default:
 		return retv, fmt.Errorf("unexpected type %T with value %v for OpenTSDB", v, v)
 	}
@@ -163,6 +352,9 @@ func (o *OpenTSDB) Close() error {
 
 func init() {
 	outputs.Add("opentsdb", func() telegraf.Output {
-		return &OpenTSDB{}
+		return &OpenTSDB{
+			HTTPBatchSize: 50,
+			HTTPPath:      "/api/put",
+		}
 	})
 }
@@ -1,10 +1,19 @@
 package opentsdb
 
 import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +38,10 @@ func TestBuildTagsTelnet(t *testing.T) {
 			map[string]string{},
 			[]string{},
 		},
+		{
+			map[string]string{"path": "/var/lib", "url": "http://a b", "dc": ""},
+			[]string{"path=/var/lib", "url=http_//a_b"},
+		},
 	}
 	for _, tt := range tagtests {
 		tags := buildTags(tt.ptIn)
@@ -47,6 +60,7 @@ func TestWrite(t *testing.T) {
 		Host:   testutil.GetLocalHost(),
 		Port:   4242,
 		Prefix: "prefix.test.",
+		Log:    testutil.Logger{},
 	}
 
 	// Verify that we can connect to the OpenTSDB instance
@@ -69,3 +83,112 @@ func TestWrite(t *testing.T) {
 	require.NoError(t, err)
 
 }
+
+func testMetrics(t *testing.T) []telegraf.Metric {
+	ts := time.Unix(1441910356, 0)
+	m1, err := telegraf.NewMetric("system",
+		map[string]string{"host": "web01", "dc": "eu west"},
+		map[string]interface{}{"load1": 0.43, "uptime": int64(3655970)}, ts)
+	require.NoError(t, err)
+	m2, err := telegraf.NewMetric("ping",
+		map[string]string{"host": "web01", "url": "www.google.com"},
+		map[string]interface{}{"up": true, "result": "ok"}, ts)
+	require.NoError(t, err)
+	return []telegraf.Metric{m1, m2}
+}
+
+func TestWriteTelnet(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	portNum, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	o := &OpenTSDB{
+		Host:   host,
+		Port:   portNum,
+		Prefix: "telegraf.",
+		Log:    testutil.Logger{},
+	}
+	// The connection test of Connect
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	require.NoError(t, o.Connect())
+
+	done := make(chan []string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(done)
+			return
+		}
+		defer conn.Close()
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		done <- lines
+	}()
+	require.NoError(t, o.Write(testMetrics(t)))
+
+	lines := <-done
+	assert.Len(t, lines, 3)
+	for _, line := range []string{
+		"put telegraf.system_load1 1441910356 0.430000 dc=eu_west host=web01",
+		"put telegraf.system_uptime 1441910356 3655970 dc=eu_west host=web01",
+		"put telegraf.ping_up 1441910356 1 host=web01 url=www.google.com",
+	} {
+		assert.Contains(t, lines, line)
+	}
+}
+
+func TestWriteHTTP(t *testing.T) {
+	var batches [][]httpMetric
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			username, password, _ := r.BasicAuth()
+			if r.URL.Path != "/api/put" || username != "telegraf" ||
+				password != "secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var batch []httpMetric
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+			batches = append(batches, batch)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	defer ts.Close()
+
+	o := &OpenTSDB{
+		Host:          ts.URL,
+		HTTPBatchSize: 2,
+		Username:      "telegraf",
+		Password:      "secret",
+		Log:           testutil.Logger{},
+	}
+	require.NoError(t, o.Connect())
+	require.NoError(t, o.Write(testMetrics(t)))
+
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	require.Len(t, batches[1], 1)
+	assert.Equal(t, httpMetric{
+		Metric:    "ping_up",
+		Timestamp: 1441910356,
+		Value:     "1",
+		Tags:      map[string]string{"host": "web01", "url": "www.google.com"},
+	}, batches[1][0])
+
+	o.Password = "wrong"
+	require.NoError(t, o.Connect())
+	err := o.Write(testMetrics(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
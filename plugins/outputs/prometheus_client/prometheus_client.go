@@ -1,43 +1,171 @@
 package prometheus_client
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var (
+	invalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+)
+
+// PrometheusClient exposes the metrics written on an HTTP endpoint scraped
+// by Prometheus, each field being a metric named after its measurement and
+// field, labelled with the tags of the metric
 type PrometheusClient struct {
-	Listen  string
-	metrics map[string]*prometheus.UntypedVec
+	Listen string
+	// ExpirationInterval is the time the metrics are exposed after their
+	// last write, never expiring if zero
+	ExpirationInterval internal.Duration `toml:"expiration_interval"`
+
+	// Credentials of the scrapers, with basic auth
+	BasicUsername string `toml:"basic_username"`
+	BasicPassword string `toml:"basic_password"`
+
+	// TLS certificate and key of the listener, and CAs of the client
+	// certificates it accepts
+	TLSCert           string   `toml:"tls_cert"`
+	TLSKey            string   `toml:"tls_key"`
+	TLSAllowedCACerts []string `toml:"tls_allowed_cacerts"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	families map[string]*family
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// sample is the value of a metric with a set of labels
+type sample struct {
+	labels     map[string]string
+	value      float64
+	expiration time.Time
+}
+
+// family is the samples of a metric name, the labels of the samples missing
+// some of the labels of the family being collapsed to empty values
+type family struct {
+	valueType prometheus.ValueType
+	samples   map[string]*sample
+	// labels counts the samples having each label
+	labels map[string]int
 }
 
 var sampleConfig = `
-  # Address to listen on
+  # Address to listen on, exposing the metrics on /metrics
   # listen = ":9126"
+
+  # Time the metrics are exposed after their last write, never expiring
+  # if zero
+  # expiration_interval = "60s"
+
+  # Credentials of the scrapers, with basic auth
+  # basic_username = ""
+  # basic_password = ""
+
+  # TLS certificate and key of the listener, only accepting the clients
+  # presenting a certificate signed by one of the allowed CAs if set
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
 `
 
 func (p *PrometheusClient) Start() error {
+	p.Lock()
+	defer p.Unlock()
+
 	if p.Listen == "" {
 		p.Listen = "localhost:9126"
 	}
+	tlsConfig, err := internal.GetServerTLSConfig(internal.ServerTLSOptions{
+		TLSCert:        p.TLSCert,
+		TLSKey:         p.TLSKey,
+		AllowedCACerts: p.TLSAllowedCACerts,
+	})
+	if err != nil {
+		return err
+	}
 
-	http.Handle("/metrics", prometheus.Handler())
-	server := &http.Server{
-		Addr: p.Listen,
+	if p.families == nil {
+		p.families = make(map[string]*family)
+	}
+	if err := prometheus.Register(p); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", p.Listen)
+	if err != nil {
+		prometheus.Unregister(p)
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
 	}
+	p.listener = listener
 
-	p.metrics = make(map[string]*prometheus.UntypedVec)
-	go server.ListenAndServe()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", p.authenticate(prometheus.Handler()))
+	server := &http.Server{Handler: mux}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		// Serve returns an error once the listener is closed by Stop
+		server.Serve(listener)
+	}()
+	p.Log.Infof("Exposing the metrics on %s/metrics", p.Listen)
 	return nil
 }
 
+// Addr returns the address listened on
+func (p *PrometheusClient) Addr() net.Addr {
+	return p.listener.Addr()
+}
+
 func (p *PrometheusClient) Stop() {
-	// TODO: Use a listener for http.Server that counts active connections
-	//       that can be stopped and closed gracefully
+	p.Lock()
+	listener := p.listener
+	p.Unlock()
+	if listener == nil {
+		return
+	}
+	listener.Close()
+	p.wg.Wait()
+	prometheus.Unregister(p)
+}
+
+// authenticate checks the credentials of the requests if basic_username or
+// basic_password are set
+func (p *PrometheusClient) authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.BasicUsername == "" && p.BasicPassword == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		username, password, _ := r.BasicAuth()
+		if subtle.ConstantTimeCompare([]byte(username),
+			[]byte(p.BasicUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password),
+				[]byte(p.BasicPassword)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="telegraf"`)
+			http.Error(w, "authorization failed", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
 }
 
 func (p *PrometheusClient) Connect() error {
@@ -55,64 +183,179 @@ func (p *PrometheusClient) SampleConfig() string {
 }
 
 func (p *PrometheusClient) Description() string {
-	return "Configuration for the Prometheus client to spawn"
+	return "Expose the metrics on an HTTP endpoint scraped by Prometheus"
+}
+
+// Describe implements prometheus.Collector, with a placeholder descriptor,
+// the metrics collected being only known once written
+func (p *PrometheusClient) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "telegraf_prometheus_client",
+		Help: "Telegraf prometheus_client output",
+	}).Describe(ch)
+}
+
+// Collect implements prometheus.Collector, the samples expired being dropped
+func (p *PrometheusClient) Collect(ch chan<- prometheus.Metric) {
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now()
+	for name, fam := range p.families {
+		for key, s := range fam.samples {
+			if !s.expiration.IsZero() && now.After(s.expiration) {
+				fam.remove(key)
+			}
+		}
+		if len(fam.samples) == 0 {
+			delete(p.families, name)
+			continue
+		}
+
+		labels := make([]string, 0, len(fam.labels))
+		for label := range fam.labels {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		desc := prometheus.NewDesc(name,
+			fmt.Sprintf("Telegraf collected metric '%s'", name), labels, nil)
+		for _, s := range fam.samples {
+			values := make([]string, len(labels))
+			for i, label := range labels {
+				values[i] = s.labels[label]
+			}
+			m, err := prometheus.NewConstMetric(desc, fam.valueType, s.value,
+				values...)
+			if err != nil {
+				p.Log.Errorf("Could not create metric %s: %s", name, err)
+				continue
+			}
+			ch <- m
+		}
+	}
+}
+
+// remove removes the sample of the key, with its labels
+func (f *family) remove(key string) {
+	for label := range f.samples[key].labels {
+		f.labels[label]--
+		if f.labels[label] == 0 {
+			delete(f.labels, label)
+		}
+	}
+	delete(f.samples, key)
+}
+
+// add sets the sample of the labels
+func (f *family) add(labels map[string]string, value float64,
+	expiration time.Time) {
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, label := range names {
+		pairs[i] = label + "=" + labels[label]
+	}
+	key := strings.Join(pairs, "\xff")
+
+	if _, ok := f.samples[key]; !ok {
+		for label := range labels {
+			f.labels[label]++
+		}
+	}
+	f.samples[key] = &sample{
+		labels:     labels,
+		value:      value,
+		expiration: expiration,
+	}
+}
+
+// valueType returns the Prometheus type of the metrics of the value type
+func valueType(t telegraf.ValueType) prometheus.ValueType {
+	switch t {
+	case telegraf.Counter:
+		return prometheus.CounterValue
+	case telegraf.Gauge:
+		return prometheus.GaugeValue
+	default:
+		return prometheus.UntypedValue
+	}
+}
+
+// sanitize replaces the characters not valid in a name by underscores,
+// prefixing it with an underscore if it starts with a digit
+func sanitize(name string, invalid *regexp.Regexp) string {
+	name = invalid.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
 }
 
 func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 	if len(metrics) == 0 {
 		return nil
 	}
+	p.Lock()
+	defer p.Unlock()
 
+	if p.families == nil {
+		p.families = make(map[string]*family)
+	}
+	var expiration time.Time
+	if p.ExpirationInterval.Duration > 0 {
+		expiration = time.Now().Add(p.ExpirationInterval.Duration)
+	}
 	for _, point := range metrics {
-		var labels []string
-		key := point.Name()
-
-		for k, _ := range point.Tags() {
+		labels := make(map[string]string)
+		for k, v := range point.Tags() {
 			if len(k) > 0 {
-				labels = append(labels, k)
+				labels[sanitize(k, invalidLabelChars)] = v
 			}
 		}
 
-		if _, ok := p.metrics[key]; !ok {
-			p.metrics[key] = prometheus.NewUntypedVec(
-				prometheus.UntypedOpts{
-					Name: key,
-					Help: fmt.Sprintf("Telegraf collected point '%s'", key),
-				},
-				labels,
-			)
-			prometheus.MustRegister(p.metrics[key])
-		}
-
-		l := prometheus.Labels{}
-		for tk, tv := range point.Tags() {
-			l[tk] = tv
-		}
-
-		for _, val := range point.Fields() {
+		for field, val := range point.Fields() {
+			var value float64
 			switch val := val.(type) {
-			default:
-				log.Printf("Prometheus output, unsupported type. key: %s, type: %T\n",
-					key, val)
 			case int64:
-				m, err := p.metrics[key].GetMetricWith(l)
-				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
-						key, l, err.Error())
-					continue
-				}
-				m.Set(float64(val))
+				value = float64(val)
+			case uint64:
+				value = float64(val)
 			case float64:
-				m, err := p.metrics[key].GetMetricWith(l)
-				if err != nil {
-					log.Printf("ERROR Getting metric in Prometheus output, "+
-						"key: %s, labels: %v,\nerr: %s\n",
-						key, l, err.Error())
-					continue
+				value = val
+			case bool:
+				if val {
+					value = 1
 				}
-				m.Set(val)
+			case string:
+				// Strings have no Prometheus representation
+				continue
+			default:
+				p.Log.Warnf("Unsupported type, key: %s, type: %T",
+					point.Name(), val)
+				continue
+			}
+
+			// The field "value" is exposed with the name of the measurement
+			name := point.Name()
+			if field != "value" {
+				name += "_" + field
 			}
+			name = sanitize(name, invalidNameChars)
+
+			// The type of a metric is the value type of its first point
+			fam, ok := p.families[name]
+			if !ok {
+				fam = &family{
+					valueType: valueType(point.Type()),
+					samples:   make(map[string]*sample),
+					labels:    make(map[string]int),
+				}
+				p.families[name] = fam
+			}
+			fam.add(labels, value, expiration)
 		}
 	}
 	return nil
@@ -120,6 +363,8 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 
 func init() {
 	outputs.Add("prometheus_client", func() telegraf.Output {
-		return &PrometheusClient{}
+		return &PrometheusClient{
+			ExpirationInterval: internal.Duration{Duration: 60 * time.Second},
+		}
 	})
 }
@@ -1,11 +1,19 @@
 package prometheus_client
 
 import (
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	prom "github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -16,13 +24,17 @@ func TestPrometheusWritePointEmptyTag(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
 	}
-	pTesting = &PrometheusClient{Listen: "localhost:9127"}
+	pTesting = &PrometheusClient{
+		Listen: "localhost:9127",
+		Log:    testutil.Logger{},
+	}
 	pTesting.Start()
 	defer pTesting.Stop()
 
 	p := &prometheus.Prometheus{
 		Urls: []string{"http://localhost:9127/metrics"},
 	}
+	require.NoError(t, p.Init())
 	tags := make(map[string]string)
 	pt1, _ := telegraf.NewMetric(
 		"test_point_1",
@@ -85,3 +97,155 @@ func TestPrometheusWritePointEmptyTag(t *testing.T) {
 			map[string]interface{}{"value": e.value})
 	}
 }
+
+func newClient(t *testing.T, p *PrometheusClient) (*PrometheusClient, string) {
+	p.Listen = "127.0.0.1:0"
+	p.Log = testutil.Logger{}
+	require.NoError(t, p.Start())
+	return p, "http://" + p.Addr().String() + "/metrics"
+}
+
+// scrape returns the lines of the exposition of the metrics of the client
+func scrape(t *testing.T, req *http.Request) (int, []string) {
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var lines []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, "test_") {
+			lines = append(lines, line)
+		}
+	}
+	return resp.StatusCode, lines
+}
+
+func newMetric(
+	t *testing.T,
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+) telegraf.Metric {
+	m, err := telegraf.NewMetric(name, tags, fields)
+	require.NoError(t, err)
+	return m
+}
+
+func TestCollapsedLabels(t *testing.T) {
+	p, url := newClient(t, &PrometheusClient{})
+	defer p.Stop()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		newMetric(t, "test_cpu", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0, "usage-idle": int64(2),
+				"state": "ok"}),
+		newMetric(t, "test_cpu", map[string]string{"host": "b", "cpu": "0"},
+			map[string]interface{}{"value": 3.0, "healthy": true}),
+	}))
+
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	status, lines := scrape(t, req)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{
+		`test_cpu{cpu="",host="a"} 1`,
+		`test_cpu{cpu="0",host="b"} 3`,
+		`test_cpu_healthy{cpu="0",host="b"} 1`,
+		`test_cpu_usage_idle{host="a"} 2`,
+	}, lines)
+}
+
+func TestExpiration(t *testing.T) {
+	p, url := newClient(t, &PrometheusClient{
+		ExpirationInterval: internal.Duration{Duration: 50 * time.Millisecond},
+	})
+	defer p.Stop()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		newMetric(t, "test_mem", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0}),
+	}))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, p.Write([]telegraf.Metric{
+		newMetric(t, "test_mem", map[string]string{"host": "b"},
+			map[string]interface{}{"value": 2.0}),
+	}))
+
+	// The label of the sample expired is not kept
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	_, lines := scrape(t, req)
+	assert.Equal(t, []string{`test_mem{host="b"} 2`}, lines)
+}
+
+func TestBasicAuth(t *testing.T) {
+	p, url := newClient(t, &PrometheusClient{
+		BasicUsername: "prometheus",
+		BasicPassword: "secret",
+	})
+	defer p.Stop()
+	require.NoError(t, p.Write([]telegraf.Metric{
+		newMetric(t, "test_disk", nil, map[string]interface{}{"used": 1.0}),
+	}))
+
+	req, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+	status, lines := scrape(t, req)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Empty(t, lines)
+
+	req.SetBasicAuth("prometheus", "wrong")
+	status, _ = scrape(t, req)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	req.SetBasicAuth("prometheus", "secret")
+	status, lines = scrape(t, req)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"test_disk_used 1"}, lines)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "disk_io_read_bytes",
+		sanitize("disk.io-read bytes", invalidNameChars))
+	assert.Equal(t, "node:cpu", sanitize("node:cpu", invalidNameChars))
+	assert.Equal(t, "node_cpu", sanitize("node:cpu", invalidLabelChars))
+	assert.Equal(t, "_1m", sanitize("1m", invalidNameChars))
+}
+
+func TestValueTypes(t *testing.T) {
+	p := &PrometheusClient{Log: testutil.Logger{}}
+	for _, valueType := range []telegraf.ValueType{
+		telegraf.Counter, telegraf.Gauge, telegraf.Untyped,
+	} {
+		m, err := telegraf.NewTypedMetric(valueType,
+			"test_point_"+valueType.String(),
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": int64(42)})
+		require.NoError(t, err)
+		require.NoError(t, p.Write([]telegraf.Metric{m}))
+	}
+
+	ch := make(chan prom.Metric, 3)
+	p.Collect(ch)
+	close(ch)
+	types := make(map[string]*dto.Metric)
+	for metric := range ch {
+		var m dto.Metric
+		require.NoError(t, metric.Write(&m))
+		require.Len(t, m.Label, 1)
+		assert.Equal(t, "localhost", m.Label[0].GetValue())
+		switch {
+		case m.Counter != nil:
+			assert.Equal(t, 42.0, m.Counter.GetValue())
+			types["counter"] = &m
+		case m.Gauge != nil:
+			assert.Equal(t, 42.0, m.Gauge.GetValue())
+			types["gauge"] = &m
+		case m.Untyped != nil:
+			assert.Equal(t, 42.0, m.Untyped.GetValue())
+			types["untyped"] = &m
+		}
+	}
+	assert.Len(t, types, 3)
+}
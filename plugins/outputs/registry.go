@@ -11,3 +11,12 @@ var Outputs = map[string]Creator{}
 func Add(name string, creator Creator) {
 	Outputs[name] = creator
 }
+
+// Deprecations holds the deprecated outputs, warned about when configured
+var Deprecations = map[string]telegraf.DeprecationInfo{}
+
+// AddDeprecated registers a deprecated output
+func AddDeprecated(name string, creator Creator, info telegraf.DeprecationInfo) {
+	Add(name, creator)
+	Deprecations[name] = info
+}
@@ -0,0 +1,225 @@
+package socket_writer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// SocketWriter writes the metrics in a data format to a UDP, TCP or unix
+// socket
+type SocketWriter struct {
+	Address         string            `toml:"address"`
+	Timeout         internal.Duration `toml:"timeout"`
+	KeepAlivePeriod internal.Duration `toml:"keep_alive_period"`
+
+	// Optional TLS configuration of the tcp:// addresses
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	// Data format of the metrics
+	DataFormat string          `toml:"data_format"`
+	Log        telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	network    string
+	address    string
+	tlsConfig  *tls.Config
+	serializer telegraf.Serializer
+	// conn is the connection to the address, nil until it is reopened by the
+	// next write if it failed
+	conn net.Conn
+}
+
+var sampleConfig = `
+  # Address to write to, tcp://, udp://, unix:// or unixgram://, ie
+  # "tcp://127.0.0.1:8094", "udp://127.0.0.1:8094" or
+  # "unix:///tmp/telegraf.sock". The metrics of a write are sent as a stream
+  # over the tcp:// and unix:// sockets, and one datagram per metric over
+  # the udp:// and unixgram:// sockets.
+  address = "tcp://127.0.0.1:8094"
+
+  # Timeout of the connections and writes
+  # timeout = "5s"
+
+  # Period of the TCP keep-alives of the connection, the default of the
+  # system if 0
+  # keep_alive_period = "0s"
+
+  # Optional TLS configuration of tcp:// addresses
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+
+  # Data format of the metrics, "influx" or "graphite"
+  # data_format = "influx"
+`
+
+func (s *SocketWriter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SocketWriter) Description() string {
+	return "Write metrics in a data format to a UDP, TCP or unix socket"
+}
+
+func (s *SocketWriter) Connect() error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %s", s.Address, err)
+	}
+	s.network = u.Scheme
+	s.address = u.Host
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	case "unix", "unixgram":
+		// The address of the unix sockets is their path
+		s.address = u.Host + u.Path
+	default:
+		return fmt.Errorf("unsupported address %q, must be tcp://, udp://, "+
+			"unix:// or unixgram://", s.Address)
+	}
+	if s.Timeout.Duration == 0 {
+		s.Timeout.Duration = 5 * time.Second
+	}
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              s.SSLCA,
+		SSLCert:            s.SSLCert,
+		SSLKey:             s.SSLKey,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil && !s.isTCP() {
+		return fmt.Errorf("the TLS options require a tcp address")
+	}
+	s.tlsConfig = tlsConfig
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: s.DataFormat,
+	})
+	if err != nil {
+		return err
+	}
+	s.serializer = serializer
+
+	return s.connect()
+}
+
+func (s *SocketWriter) isTCP() bool {
+	return s.network == "tcp" || s.network == "tcp4" || s.network == "tcp6"
+}
+
+// isPacket returns whether the socket is a packet socket, written to datagram
+// by datagram
+func (s *SocketWriter) isPacket() bool {
+	switch s.network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return true
+	}
+	return false
+}
+
+// connect opens the connection to the address
+func (s *SocketWriter) connect() error {
+	dialer := &net.Dialer{Timeout: s.Timeout.Duration}
+	if s.KeepAlivePeriod.Duration > 0 {
+		dialer.KeepAlive = s.KeepAlivePeriod.Duration
+	}
+	var conn net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, s.network, s.address,
+			s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.network, s.address)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %s", s.Address, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *SocketWriter) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Write writes the metrics to the connection, which is reopened if a
+// previous write failed
+func (s *SocketWriter) Write(metrics []telegraf.Metric) error {
+	s.Lock()
+	defer s.Unlock()
+	if len(metrics) == 0 {
+		return nil
+	}
+	if s.serializer == nil {
+		return fmt.Errorf("not connected to %s", s.Address)
+	}
+
+	// The metrics are written in a single write to the stream sockets, and
+	// in a write per metric to the packet sockets
+	var batch []byte
+	var writes [][]byte
+	for _, m := range metrics {
+		buf, err := s.serializer.Serialize(m)
+		if err != nil {
+			s.Log.Errorf("Could not serialize metric %s: %s", m.Name(), err)
+			continue
+		}
+		if s.isPacket() {
+			writes = append(writes, buf)
+		} else {
+			batch = append(batch, buf...)
+		}
+	}
+	if len(batch) > 0 {
+		writes = append(writes, batch)
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	for _, buf := range writes {
+		s.conn.SetWriteDeadline(time.Now().Add(s.Timeout.Duration))
+		if _, err := s.conn.Write(buf); err != nil {
+			// The connection is reopened by the next write
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("could not write to %s: %s", s.Address, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("socket_writer", func() telegraf.Output {
+		return &SocketWriter{}
+	})
+}
@@ -0,0 +1,213 @@
+package socket_writer
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
+)
+
+func testMetrics() []telegraf.Metric {
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"host": "b"},
+		map[string]interface{}{"value": 2.0},
+		time.Unix(0, 1422568543702900257))
+	return append(testutil.MockMetrics(), m)
+}
+
+const lines = "test1,tag1=value1 value=1 1257894000000000000\n" +
+	"cpu,host=b value=2 1422568543702900257\n"
+
+// readLines reads the lines of the first connection accepted by the listener
+func readLines(t *testing.T, l net.Listener, n int) chan string {
+	c := make(chan string, n)
+	go func() {
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < n; i++ {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			c <- line
+		}
+		close(c)
+	}()
+	return c
+}
+
+func assertStream(t *testing.T, c chan string) {
+	var received string
+	for line := range c {
+		received += line
+	}
+	assert.Equal(t, lines, received)
+}
+
+func TestSocketWriterTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	c := readLines(t, l, 2)
+
+	s := &SocketWriter{
+		Address: "tcp://" + l.Addr().String(),
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics()))
+	assertStream(t, c)
+}
+
+func TestSocketWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s := &SocketWriter{
+		Address: "udp://" + conn.LocalAddr().String(),
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics()))
+
+	// A datagram per metric
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var received string
+	for i := 0; i < 2; i++ {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		received += string(buf[:n])
+	}
+	assert.Equal(t, lines, received)
+}
+
+func TestSocketWriterUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_writer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "telegraf.sock")
+
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+	c := readLines(t, l, 2)
+
+	s := &SocketWriter{Address: "unix://" + path, Log: testutil.Logger{}}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics()))
+	assertStream(t, c)
+}
+
+func TestSocketWriterReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s := &SocketWriter{
+		Address: "tcp://" + l.Addr().String(),
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	// The write fails once the server closed the connection, the next write
+	// reconnecting
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	conn.Close()
+	for i := 0; i < 100 && s.Write(testMetrics()) == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, s.conn)
+
+	c := readLines(t, l, 2)
+	require.NoError(t, s.Write(testMetrics()))
+	assertStream(t, c)
+}
+
+// tlsListener returns a TLS listener with a self-signed certificate, and the
+// file of the certificate
+func tlsListener(t *testing.T, dir string) (net.Listener, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "telegraf"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+	require.NoError(t, err)
+	certFile := filepath.Join(dir, "cert.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0600))
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0",
+		&tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	return l, certFile
+}
+
+func TestSocketWriterTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket_writer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	l, certFile := tlsListener(t, dir)
+	defer l.Close()
+	c := readLines(t, l, 2)
+
+	s := &SocketWriter{
+		Address: "tcp://" + l.Addr().String(),
+		SSLCA:   certFile,
+		Log:     testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testMetrics()))
+	assertStream(t, c)
+}
+
+func TestSocketWriterInvalidConfig(t *testing.T) {
+	for _, s := range []*SocketWriter{
+		{Address: "http://127.0.0.1:8094"},
+		{Address: "udp://127.0.0.1:8094", InsecureSkipVerify: true},
+		{Address: "udp://127.0.0.1:8094", DataFormat: "xml"},
+	} {
+		assert.Error(t, s.Connect(), s.Address)
+	}
+}
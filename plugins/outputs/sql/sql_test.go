@@ -0,0 +1,358 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2016, time.January, 4, 3, 0, 0, 0, time.UTC)
+
+// fakeDatabase is a database of the fake driver, recording the statements
+// executed
+type fakeDatabase struct {
+	sync.Mutex
+	// tables are the columns of the existing tables
+	tables     map[string][]string
+	statements []string
+	queries    int
+	commits    int
+	rollbacks  int
+	// failInserts makes the inserts fail
+	failInserts bool
+}
+
+var (
+	fakeDatabasesLock sync.Mutex
+	fakeDatabases     = make(map[string]*fakeDatabase)
+)
+
+// newFakeDatabase returns the database of the fake driver of the data source
+// name
+func newFakeDatabase(dsn string) *fakeDatabase {
+	fakeDatabasesLock.Lock()
+	defer fakeDatabasesLock.Unlock()
+	db := &fakeDatabase{tables: make(map[string][]string)}
+	fakeDatabases[dsn] = db
+	return db
+}
+
+var createTable = regexp.MustCompile(`^CREATE TABLE "([^"]+)"`)
+var selectTable = regexp.MustCompile(`^SELECT \* FROM "([^"]+)" WHERE 1=0$`)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDatabasesLock.Lock()
+	defer fakeDatabasesLock.Unlock()
+	db, ok := fakeDatabases[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %s", name)
+	}
+	return &fakeConn{db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDatabase
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.db, query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return &fakeTx{c.db}, nil
+}
+
+type fakeTx struct {
+	db *fakeDatabase
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.db.Lock()
+	defer tx.db.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.Lock()
+	defer tx.db.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDatabase
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.Lock()
+	defer s.db.Unlock()
+	if s.db.failInserts && strings.HasPrefix(s.query, "INSERT") {
+		return nil, fmt.Errorf("disk full")
+	}
+	statement := s.query
+	if len(args) > 0 {
+		statement += fmt.Sprint(args)
+	}
+	s.db.statements = append(s.db.statements, statement)
+	if match := createTable.FindStringSubmatch(s.query); match != nil {
+		s.db.tables[match[1]] = nil
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.Lock()
+	defer s.db.Unlock()
+	s.db.queries++
+	match := selectTable.FindStringSubmatch(s.query)
+	if match == nil {
+		return nil, fmt.Errorf("unexpected query %s", s.query)
+	}
+	columns, ok := s.db.tables[match[1]]
+	if !ok {
+		return nil, fmt.Errorf("no such table: %s", match[1])
+	}
+	return &fakeRows{columns}, nil
+}
+
+type fakeRows struct {
+	columns []string
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+func init() {
+	gosql.Register("sqltest", fakeDriver{})
+	dialects["sqltest"] = dialects["postgres"]
+}
+
+func newSQL(dsn string) *SQL {
+	return &SQL{
+		Driver:         "sqltest",
+		DataSourceName: dsn,
+		CreateTables:   true,
+		Log:            testutil.Logger{},
+	}
+}
+
+func testMetrics() []telegraf.Metric {
+	cpu0, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.5}, testTime)
+	cpu1, _ := telegraf.NewMetric("cpu",
+		map[string]string{"cpu": "cpu1", "host": "web01"},
+		map[string]interface{}{"usage_idle": 98.5, "throttled": true},
+		testTime)
+	mem, _ := telegraf.NewMetric("mem", map[string]string{},
+		map[string]interface{}{"free": int64(42), "state": "ok"}, testTime)
+	return []telegraf.Metric{cpu0, mem, cpu1}
+}
+
+func TestRow(t *testing.T) {
+	s := newSQL("")
+	s.Schema = "table_per_measurement"
+	s.TimestampColumn = "time"
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "web01", "cpu": "cpu0", "time": "now",
+			"usage": "high"},
+		map[string]interface{}{"usage": 1.5, "idle": int64(2)}, testTime)
+	r := s.rowOf(m)
+	assert.Equal(t, "cpu", r.table)
+	assert.Equal(t, []string{"time", "cpu", "host", "idle", "usage"},
+		r.columns)
+	assert.Equal(t, []interface{}{testTime, "cpu0", "web01", int64(2), 1.5},
+		r.values)
+
+	s.Schema = "single_table"
+	s.Table = "metrics"
+	s.MeasurementColumn = "name"
+	r = s.rowOf(m)
+	assert.Equal(t, "metrics", r.table)
+	assert.Equal(t, []string{"time", "name", "cpu", "host", "idle", "usage"},
+		r.columns)
+	assert.Equal(t, "cpu", r.values[1])
+}
+
+func TestQueries(t *testing.T) {
+	s := newSQL("")
+	r := row{table: `my"table`, columns: []string{"timestamp", "value"}}
+
+	s.dialect = dialects["postgres"]
+	assert.Equal(t, `INSERT INTO "my""table" ("timestamp","value") VALUES ($1,$2)`,
+		s.insertQuery(r))
+	assert.Equal(t, s.dialect.types.Real, s.columnType(1.5))
+	assert.Equal(t, `CREATE TABLE "cpu" ("timestamp" TIMESTAMP,"value" DOUBLE PRECISION)`,
+		s.createTableQuery("cpu", r.columns, map[string]string{
+			"timestamp": s.columnType(testTime),
+			"value":     s.columnType(1.5),
+		}))
+
+	s.dialect = dialects["mysql"]
+	assert.Equal(t, "INSERT INTO `my\"table` (`timestamp`,`value`) VALUES (?,?)",
+		s.insertQuery(r))
+	assert.Equal(t, "BIGINT", s.columnType(int64(1)))
+	assert.Equal(t, "BOOL", s.columnType(true))
+	assert.Equal(t, "TEXT", s.columnType("ok"))
+}
+
+func TestWrite(t *testing.T) {
+	dsn := "write"
+	db := newFakeDatabase(dsn)
+	s := newSQL(dsn)
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+	ts := testTime.String()
+	assert.Equal(t, []string{
+		`CREATE TABLE "cpu" ("timestamp" TIMESTAMP,"cpu" TEXT,` +
+			`"usage_idle" DOUBLE PRECISION,"host" TEXT,"throttled" BOOLEAN)`,
+		`CREATE TABLE "mem" ("timestamp" TIMESTAMP,"free" BIGINT,"state" TEXT)`,
+		`INSERT INTO "cpu" ("timestamp","cpu","usage_idle") VALUES ($1,$2,$3)` +
+			"[" + ts + " cpu0 99.5]",
+		`INSERT INTO "cpu" ("timestamp","cpu","host","throttled","usage_idle") ` +
+			`VALUES ($1,$2,$3,$4,$5)[` + ts + " cpu1 web01 true 98.5]",
+		`INSERT INTO "mem" ("timestamp","free","state") VALUES ($1,$2,$3)` +
+			"[" + ts + " 42 ok]",
+	}, db.statements)
+	assert.Equal(t, 1, db.commits)
+	assert.Equal(t, 2, db.queries)
+
+	// The columns of the tables are known, the new ones being added
+	db.statements = nil
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 97.5, "usage_user": 2.5},
+		testTime)
+	require.NoError(t, s.Write([]telegraf.Metric{m}))
+	assert.Equal(t, []string{
+		`ALTER TABLE "cpu" ADD COLUMN "usage_user" DOUBLE PRECISION`,
+		`INSERT INTO "cpu" ("timestamp","cpu","usage_idle","usage_user") ` +
+			"VALUES ($1,$2,$3,$4)[" + ts + " cpu0 97.5 2.5]",
+	}, db.statements)
+	assert.Equal(t, 2, db.commits)
+	assert.Equal(t, 2, db.queries)
+}
+
+func TestWriteSingleTable(t *testing.T) {
+	dsn := "single_table"
+	db := newFakeDatabase(dsn)
+	db.tables["telegraf"] = []string{"timestamp", "measurement", "cpu",
+		"usage_idle"}
+	s := newSQL(dsn)
+	s.Schema = "single_table"
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+	require.Len(t, db.statements, 7)
+	assert.Equal(t, []string{
+		`ALTER TABLE "telegraf" ADD COLUMN "free" BIGINT`,
+		`ALTER TABLE "telegraf" ADD COLUMN "state" TEXT`,
+		`ALTER TABLE "telegraf" ADD COLUMN "host" TEXT`,
+		`ALTER TABLE "telegraf" ADD COLUMN "throttled" BOOLEAN`,
+	}, db.statements[:4])
+	assert.True(t, strings.HasPrefix(db.statements[5],
+		`INSERT INTO "telegraf" ("timestamp","measurement","free","state") `))
+	assert.Equal(t, 1, db.commits)
+}
+
+func TestWriteErrors(t *testing.T) {
+	dsn := "errors"
+	db := newFakeDatabase(dsn)
+	s := newSQL(dsn)
+	s.CreateTables = false
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	// The missing tables are not created
+	err := s.Write(testMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "table cpu does not exist")
+	assert.Len(t, db.statements, 0)
+
+	// The transaction is rolled back if an insert fails, the columns of the
+	// table being read again by the next write
+	db.tables["cpu"] = []string{"timestamp", "cpu", "usage_idle"}
+	db.tables["mem"] = []string{"timestamp", "free", "state"}
+	db.failInserts = true
+	err = s.Write(testMetrics())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+	assert.Equal(t, 1, db.rollbacks)
+	assert.Equal(t, 0, db.commits)
+	assert.Len(t, s.columns, 1)
+
+	db.failInserts = false
+	require.NoError(t, s.Write(testMetrics()))
+	assert.Equal(t, 1, db.commits)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, s := range []*SQL{
+		{Driver: "oracle"},
+		{Driver: "sqltest", Schema: "star"},
+		{Driver: "sqltest", DataSourceName: "unknown"},
+	} {
+		assert.Error(t, s.Connect(), "%+v", s)
+	}
+}
+
+func TestPostgresIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	s := &SQL{
+		Driver: "postgres",
+		DataSourceName: fmt.Sprintf("host=%s user=postgres sslmode=disable",
+			testutil.GetLocalHost()),
+		CreateTables: true,
+		Log:          testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	_, err := s.db.Exec(`DROP TABLE IF EXISTS "cpu", "mem"`)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(testMetrics()))
+	var count int
+	require.NoError(t, s.db.QueryRow(
+		`SELECT COUNT(*) FROM "cpu" WHERE "usage_idle" > 98`).Scan(&count))
+	assert.Equal(t, 2, count)
+}
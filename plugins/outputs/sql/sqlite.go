@@ -0,0 +1,6 @@
+// +build cgo
+
+package sql
+
+// The SQLite driver requires cgo
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,189 @@
+package stackdriver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// monitoringScope is the OAuth2 scope of the writes of time series
+	monitoringScope = "https://www.googleapis.com/auth/monitoring.write"
+	// metadataTokenURL is the URL of the tokens of the service account of a
+	// Compute Engine instance
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/" +
+		"instance/service-accounts/default/token"
+)
+
+// serviceAccount is the JSON key of a service account
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// tokenSource returns the access tokens of the requests, requested with the
+// JWT of a service account key, or from the metadata server of Compute
+// Engine without key, and kept until they expire
+type tokenSource struct {
+	client  *http.Client
+	account *serviceAccount
+	key     *rsa.PrivateKey
+	// metadataURL is the URL of the tokens without key
+	metadataURL string
+
+	sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newTokenSource returns the token source of the service account key file,
+// or of the metadata server if empty
+func newTokenSource(client *http.Client, keyFile string) (*tokenSource, error) {
+	ts := &tokenSource{client: client, metadataURL: metadataTokenURL}
+	if keyFile == "" {
+		return ts, nil
+	}
+
+	buf, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(buf, &account); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %s", keyFile,
+			err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("invalid credentials file %s: not a service "+
+			"account key", keyFile)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key in %s", keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in %s: %s", keyFile, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key in %s: not an RSA key",
+			keyFile)
+	}
+	ts.account = &account
+	ts.key = rsaKey
+	return ts, nil
+}
+
+// Token returns a valid access token, requesting a new one a minute before
+// the current one expires
+func (ts *tokenSource) Token() (string, error) {
+	ts.Lock()
+	defer ts.Unlock()
+	if ts.token != "" && time.Now().Add(time.Minute).Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.account != nil {
+		var assertion string
+		assertion, err = ts.assertion(time.Now())
+		if err != nil {
+			return "", err
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		req, err = http.NewRequest("POST", ts.account.TokenURI,
+			strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req, err = http.NewRequest("GET", ts.metadataURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+	}
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not request an access token: %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read the access token: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not request an access token: %s: %s",
+			resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("invalid access token response: %s", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no access token in the response")
+	}
+	ts.token = token.AccessToken
+	ts.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// assertion returns the JWT of the service account, signed with its key
+func (ts *tokenSource) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   ts.account.ClientEmail,
+		"scope": monitoringScope,
+		"aud":   ts.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := encodeSegment(header) + "." + encodeSegment(claims)
+	hash := sha256.Sum256([]byte(payload))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256,
+		hash[:])
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + encodeSegment(signature), nil
+}
+
+// encodeSegment encodes a segment of a JWT, in base64url without padding
+func encodeSegment(b []byte) string {
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(b), "=")
+}
@@ -0,0 +1,321 @@
+package stackdriver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// maxTimeSeriesPerRequest is the maximum number of time series written per
+// request by the Monitoring API
+const maxTimeSeriesPerRequest = 200
+
+type Stackdriver struct {
+	// Project is the GCP project the metrics are written to
+	Project string `toml:"project"`
+	// Namespace of the metric types, custom.googleapis.com/<namespace>/
+	// <measurement>/<field>
+	Namespace string `toml:"namespace"`
+	// Monitored resource of the time series
+	ResourceType   string            `toml:"resource_type"`
+	ResourceLabels map[string]string `toml:"resource_labels"`
+	// CredentialsFile is the JSON key of a service account, the service
+	// account of the Compute Engine instance being used if empty
+	CredentialsFile string `toml:"credentials_file"`
+	// Endpoint of the Monitoring API
+	Endpoint string `toml:"endpoint"`
+	Timeout  internal.Duration
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+	tokens *tokenSource
+	// starts are the start times of the cumulative time series
+	starts map[string]time.Time
+}
+
+var sampleConfig = `
+  # GCP project to write the metrics to
+  project = "erudite-bloom-151019" # required.
+  # Namespace of the metric types, custom.googleapis.com/<namespace>/
+  # <measurement>/<field>
+  namespace = "telegraf"
+
+  # Monitored resource of the time series, and its labels, the project_id
+  # label being set to the project if missing
+  # resource_type = "global"
+  # [outputs.stackdriver.resource_labels]
+  #   node_id = "edge-01"
+  #   location = "eu-west1"
+  #   namespace = "factory"
+
+  # JSON key of a service account, the service account of the Compute Engine
+  # instance being used if not set
+  # credentials_file = "/etc/telegraf/service-account.json"
+
+  # Timeout of the requests
+  # timeout = "5s"
+`
+
+// timeSeries is a point of a time series of the Monitoring API
+type timeSeries struct {
+	Metric     metric   `json:"metric"`
+	Resource   resource `json:"resource"`
+	MetricKind string   `json:"metricKind"`
+	ValueType  string   `json:"valueType"`
+	Points     []point  `json:"points"`
+}
+
+type metric struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type resource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+}
+
+type point struct {
+	Interval interval               `json:"interval"`
+	Value    map[string]interface{} `json:"value"`
+}
+
+type interval struct {
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime"`
+}
+
+func (s *Stackdriver) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Stackdriver) Description() string {
+	return "Send metrics to Google Cloud Monitoring (Stackdriver)"
+}
+
+func (s *Stackdriver) Connect() error {
+	if s.Project == "" {
+		return fmt.Errorf("no Stackdriver project set")
+	}
+	if s.Namespace == "" {
+		s.Namespace = "telegraf"
+	}
+	if s.ResourceType == "" {
+		s.ResourceType = "global"
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://monitoring.googleapis.com"
+	}
+	if s.Timeout.Duration == 0 {
+		s.Timeout.Duration = 5 * time.Second
+	}
+	if s.ResourceLabels == nil {
+		s.ResourceLabels = make(map[string]string)
+	}
+	if _, ok := s.ResourceLabels["project_id"]; !ok {
+		s.ResourceLabels["project_id"] = s.Project
+	}
+
+	client, err := internal.NewHTTPClient(s.Timeout.Duration,
+		internal.HTTPProxyOptions{UseSystemProxy: true})
+	if err != nil {
+		return err
+	}
+	tokens, err := newTokenSource(client, s.CredentialsFile)
+	if err != nil {
+		return err
+	}
+	s.client = client
+	s.tokens = tokens
+	if s.starts == nil {
+		s.starts = make(map[string]time.Time)
+	}
+	return nil
+}
+
+func (s *Stackdriver) Close() error {
+	return nil
+}
+
+// typedValue returns the value of a field and its value type, false for the
+// values without representation, ie strings
+func typedValue(v interface{}) (map[string]interface{}, string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return map[string]interface{}{
+			"int64Value": strconv.FormatInt(v, 10),
+		}, "INT64", true
+	case uint64:
+		if v > math.MaxInt64 {
+			v = math.MaxInt64
+		}
+		return map[string]interface{}{
+			"int64Value": strconv.FormatUint(v, 10),
+		}, "INT64", true
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, "", false
+		}
+		return map[string]interface{}{"doubleValue": v}, "DOUBLE", true
+	case bool:
+		return map[string]interface{}{"boolValue": v}, "BOOL", true
+	}
+	return nil, "", false
+}
+
+// seriesKey returns the key of the time series of the metric type and
+// labels
+func seriesKey(metricType string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := []string{metricType}
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	return strings.Join(pairs, "\xff")
+}
+
+// timeSeries returns the time series of the fields of the metric with their
+// key, the counters being cumulative since their first point
+func (s *Stackdriver) timeSeries(m telegraf.Metric) ([]*timeSeries, []string) {
+	var series []*timeSeries
+	var keys []string
+
+	names := make([]string, 0, len(m.Fields()))
+	for name := range m.Fields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, valueType, ok := typedValue(m.Fields()[name])
+		if !ok {
+			s.Log.Debugf("Skipping field %s of %s of type %T", name,
+				m.Name(), m.Fields()[name])
+			continue
+		}
+		ts := &timeSeries{
+			Metric: metric{
+				Type: path.Join("custom.googleapis.com", s.Namespace,
+					m.Name(), name),
+				Labels: m.Tags(),
+			},
+			Resource: resource{
+				Type:   s.ResourceType,
+				Labels: s.ResourceLabels,
+			},
+			MetricKind: "GAUGE",
+			ValueType:  valueType,
+			Points: []point{{
+				Interval: interval{
+					EndTime: m.Time().UTC().Format(time.RFC3339Nano),
+				},
+				Value: value,
+			}},
+		}
+		key := seriesKey(ts.Metric.Type, ts.Metric.Labels)
+
+		// The cumulative time series start before their first point, the
+		// booleans having no cumulative representation
+		if m.Type() == telegraf.Counter && valueType != "BOOL" {
+			ts.MetricKind = "CUMULATIVE"
+			start, ok := s.starts[key]
+			if !ok || !start.Before(m.Time()) {
+				start = m.Time().Add(-time.Millisecond)
+				s.starts[key] = start
+			}
+			ts.Points[0].Interval.StartTime =
+				start.UTC().Format(time.RFC3339Nano)
+		}
+		series = append(series, ts)
+		keys = append(keys, key)
+	}
+	return series, keys
+}
+
+// batches returns the time series in the batches of the requests, of at
+// most maxTimeSeriesPerRequest time series, a time series being written
+// once per request
+func (s *Stackdriver) batches(metrics []telegraf.Metric) [][]*timeSeries {
+	var batches [][]*timeSeries
+	var batch []*timeSeries
+	inBatch := make(map[string]bool)
+	for _, m := range metrics {
+		series, keys := s.timeSeries(m)
+		for i, ts := range series {
+			if len(batch) == maxTimeSeriesPerRequest || inBatch[keys[i]] {
+				batches = append(batches, batch)
+				batch = nil
+				inBatch = make(map[string]bool)
+			}
+			batch = append(batch, ts)
+			inBatch[keys[i]] = true
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+func (s *Stackdriver) Write(metrics []telegraf.Metric) error {
+	for _, batch := range s.batches(metrics) {
+		if err := s.write(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write creates the points of a batch of time series
+func (s *Stackdriver) write(batch []*timeSeries) error {
+	body, err := json.Marshal(map[string]interface{}{"timeSeries": batch})
+	if err != nil {
+		return err
+	}
+	token, err := s.tokens.Token()
+	if err != nil {
+		return err
+	}
+	u := fmt.Sprintf("%s/v3/projects/%s/timeSeries",
+		strings.TrimSuffix(s.Endpoint, "/"), s.Project)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not write to Stackdriver: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not write to Stackdriver: %s: %s",
+			resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("stackdriver", func() telegraf.Output {
+		return &Stackdriver{}
+	})
+}
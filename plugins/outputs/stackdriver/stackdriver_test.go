@@ -0,0 +1,183 @@
+package stackdriver
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyFile returns a copy of the service account key of testdata, its
+// tokens requested from tokenURL
+func keyFile(t *testing.T, tokenURL string) string {
+	buf, err := ioutil.ReadFile("testdata/service-account.json")
+	require.NoError(t, err)
+	var account map[string]string
+	require.NoError(t, json.Unmarshal(buf, &account))
+	account["token_uri"] = tokenURL
+	buf, err = json.Marshal(account)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "service-account")
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write(buf)
+	require.NoError(t, err)
+	return f.Name()
+}
+
+func decodeSegment(t *testing.T, s string) []byte {
+	if m := len(s) % 4; m != 0 {
+		s += strings.Repeat("=", 4-m)
+	}
+	b, err := base64.URLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestWrite(t *testing.T) {
+	var tokens int
+	var requests []map[string][]*timeSeries
+	var ts *tokenSource
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/token":
+				tokens++
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t,
+					"urn:ietf:params:oauth:grant-type:jwt-bearer",
+					r.PostForm.Get("grant_type"))
+				// The assertion is signed with the key of the account
+				parts := strings.Split(r.PostForm.Get("assertion"), ".")
+				require.Len(t, parts, 3)
+				hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+				assert.NoError(t, rsa.VerifyPKCS1v15(&ts.key.PublicKey,
+					crypto.SHA256, hash[:], decodeSegment(t, parts[2])))
+				var claims map[string]interface{}
+				require.NoError(t,
+					json.Unmarshal(decodeSegment(t, parts[1]), &claims))
+				assert.Equal(t, monitoringScope, claims["scope"])
+				fmt.Fprint(w, `{"access_token":"secret","expires_in":3600}`)
+			case "/v3/projects/telegraf-test/timeSeries":
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				var body map[string][]*timeSeries
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				requests = append(requests, body)
+				fmt.Fprint(w, "{}")
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	file := keyFile(t, server.URL+"/token")
+	defer os.Remove(file)
+	s := &Stackdriver{
+		Project:         "telegraf-test",
+		CredentialsFile: file,
+		Endpoint:        server.URL,
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, s.Connect())
+	ts = s.tokens
+
+	now := time.Now()
+	m1, _ := telegraf.NewGaugeMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "state": "ok"}, now)
+	m2, _ := telegraf.NewCounterMetric("net", map[string]string{},
+		map[string]interface{}{"bytes_recv": int64(42)}, now)
+	// The same time series, written in another request
+	m3, _ := telegraf.NewGaugeMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 90.5}, now.Add(time.Minute))
+	require.NoError(t, s.Write([]telegraf.Metric{m1, m2, m3}))
+	require.NoError(t, s.Write([]telegraf.Metric{m2}))
+
+	// A single token
+	assert.Equal(t, 1, tokens)
+	require.Len(t, requests, 3)
+	require.Len(t, requests[0]["timeSeries"], 2)
+	require.Len(t, requests[1]["timeSeries"], 1)
+
+	gauge := requests[0]["timeSeries"][0]
+	assert.Equal(t, "custom.googleapis.com/telegraf/cpu/usage_idle",
+		gauge.Metric.Type)
+	assert.Equal(t, map[string]string{"cpu": "cpu0"}, gauge.Metric.Labels)
+	assert.Equal(t, "global", gauge.Resource.Type)
+	assert.Equal(t, map[string]string{"project_id": "telegraf-test"},
+		gauge.Resource.Labels)
+	assert.Equal(t, "GAUGE", gauge.MetricKind)
+	assert.Equal(t, "DOUBLE", gauge.ValueType)
+	assert.Equal(t, 91.5, gauge.Points[0].Value["doubleValue"])
+	assert.Equal(t, "", gauge.Points[0].Interval.StartTime)
+
+	// The counters are cumulative since their first point
+	counter := requests[0]["timeSeries"][1]
+	assert.Equal(t, "CUMULATIVE", counter.MetricKind)
+	assert.Equal(t, "INT64", counter.ValueType)
+	assert.Equal(t, "42", counter.Points[0].Value["int64Value"])
+	assert.NotEqual(t, "", counter.Points[0].Interval.StartTime)
+	assert.Equal(t, counter.Points[0].Interval.StartTime,
+		requests[2]["timeSeries"][0].Points[0].Interval.StartTime)
+}
+
+func TestBatches(t *testing.T) {
+	s := &Stackdriver{
+		Project: "telegraf-test",
+		Log:     testutil.Logger{},
+		starts:  make(map[string]time.Time),
+	}
+	var metrics []telegraf.Metric
+	for i := 0; i < maxTimeSeriesPerRequest+1; i++ {
+		m, _ := telegraf.NewMetric("cpu",
+			map[string]string{"cpu": fmt.Sprintf("cpu%d", i)},
+			map[string]interface{}{"usage_idle": 91.5})
+		metrics = append(metrics, m)
+	}
+	batches := s.batches(metrics)
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], maxTimeSeriesPerRequest)
+	assert.Len(t, batches[1], 1)
+}
+
+func TestMetadataToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"access_token":"secret","expires_in":3600}`)
+		}))
+	defer server.Close()
+
+	ts, err := newTokenSource(http.DefaultClient, "")
+	require.NoError(t, err)
+	ts.metadataURL = server.URL
+	token, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	s := &Stackdriver{Log: testutil.Logger{}}
+	assert.Error(t, s.Connect())
+
+	s = &Stackdriver{Project: "telegraf-test",
+		CredentialsFile: "testdata/missing.json", Log: testutil.Logger{}}
+	assert.Error(t, s.Connect())
+}
@@ -0,0 +1,462 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+// rfc5424Time is the format of the timestamps, with at most 6 digits of
+// fractions of seconds
+const rfc5424Time = "2006-01-02T15:04:05.999999Z07:00"
+
+var severityNames = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp",
+	"cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6",
+	"local7",
+}
+
+// headerFields are the fields of the metrics setting the header or message
+// of the syslog messages, like the fields of the syslog input
+var headerFields = map[string]bool{
+	"version": true, "severity_code": true, "facility_code": true,
+	"timestamp": true, "procid": true, "msgid": true, "message": true,
+}
+
+// headerTags are the tags of the metrics setting the header of the syslog
+// messages
+var headerTags = map[string]bool{
+	"severity": true, "facility": true, "hostname": true, "source": true,
+	"host": true, "appname": true,
+}
+
+// sdEscaper escapes the characters of the structured data values
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// Syslog sends the metrics as RFC 5424 syslog messages over UDP, TCP or TLS
+type Syslog struct {
+	Address string
+	// Framing of the messages over TCP, "octet-counting", each message
+	// prefixed by its length, or "non-transparent", each message ended by a
+	// newline
+	Framing string
+	Timeout internal.Duration
+
+	// SDIDs are the IDs of the structured data elements set by the fields
+	// prefixed by the ID and the SDParamSeparator, DefaultSDID the element
+	// of the other fields and tags, which are dropped if it is empty
+	SDIDs            []string `toml:"sdids"`
+	SDParamSeparator string   `toml:"sdparam_separator"`
+	DefaultSDID      string   `toml:"default_sdid"`
+
+	// Header of the messages of the metrics without the fields and tags
+	// setting it
+	DefaultSeverityCode int    `toml:"default_severity_code"`
+	DefaultFacilityCode int    `toml:"default_facility_code"`
+	DefaultAppname      string `toml:"default_appname"`
+
+	// Optional TLS configuration of the tcp:// addresses
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	network   string
+	address   string
+	hostname  string
+	tlsConfig *tls.Config
+	// conn is the connection to the address, nil until it is reopened by the
+	// next write if it failed
+	conn net.Conn
+}
+
+var sampleConfig = `
+  # Address of the syslog server, tcp://, udp:// or with TLS tcp:// and the
+  # TLS options, ie "tcp://127.0.0.1:6514" or "udp://127.0.0.1:514"
+  address = "tcp://127.0.0.1:6514"
+
+  # Framing of the messages over TCP, "octet-counting" (RFC 5425 and 6587),
+  # each message prefixed by its length, or "non-transparent", each message
+  # ended by a newline
+  # framing = "octet-counting"
+
+  # Timeout of the connections and writes
+  # timeout = "5s"
+
+  # The header of the messages is set by the fields and tags of the syslog
+  # input: the severity_code and facility_code fields, or severity and
+  # facility tags, the timestamp field, the time of the metric otherwise, the
+  # hostname, source or host tag, the appname tag, the procid field, and the
+  # msgid field, the name of the metric otherwise. The message is the message
+  # field.
+
+  # IDs of the structured data elements set by the fields prefixed by the ID
+  # and the separator, ie the origin_ip field of the origin element
+  # sdids = ["origin", "meta"]
+  # sdparam_separator = "_"
+  # ID of the structured data element of the other fields and tags, which
+  # are dropped if it is empty, ie "telegraf@32473"
+  # default_sdid = ""
+
+  # Severity, facility and appname of the metrics not setting them
+  # default_severity_code = 5
+  # default_facility_code = 1
+  # default_appname = "Telegraf"
+
+  # Optional TLS configuration of tcp:// addresses
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+`
+
+func (s *Syslog) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Syslog) Description() string {
+	return "Send the metrics as RFC 5424 syslog messages over UDP, TCP or TLS"
+}
+
+func (s *Syslog) Connect() error {
+	s.Lock()
+	defer s.Unlock()
+
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %s", s.Address, err)
+	}
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unsupported address %q, must be tcp:// or udp://",
+			s.Address)
+	}
+	s.network = u.Scheme
+	s.address = u.Host
+	switch s.Framing {
+	case "":
+		s.Framing = "octet-counting"
+	case "octet-counting", "non-transparent":
+	default:
+		return fmt.Errorf("unknown framing %q, must be octet-counting or "+
+			"non-transparent", s.Framing)
+	}
+	if s.DefaultSeverityCode < 0 || s.DefaultSeverityCode > 7 {
+		return fmt.Errorf("invalid default_severity_code %d",
+			s.DefaultSeverityCode)
+	}
+	if s.DefaultFacilityCode < 0 || s.DefaultFacilityCode > 23 {
+		return fmt.Errorf("invalid default_facility_code %d",
+			s.DefaultFacilityCode)
+	}
+	if s.SDParamSeparator == "" {
+		s.SDParamSeparator = "_"
+	}
+	if s.Timeout.Duration == 0 {
+		s.Timeout.Duration = 5 * time.Second
+	}
+	tlsConfig, err := internal.GetTLSConfig(internal.TLSOptions{
+		SSLCA:              s.SSLCA,
+		SSLCert:            s.SSLCert,
+		SSLKey:             s.SSLKey,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	})
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil && s.isUDP() {
+		return fmt.Errorf("the TLS options require a tcp address")
+	}
+	s.tlsConfig = tlsConfig
+	if s.hostname, err = os.Hostname(); err != nil {
+		return err
+	}
+
+	return s.connect()
+}
+
+func (s *Syslog) isUDP() bool {
+	return strings.HasPrefix(s.network, "udp")
+}
+
+// connect opens the connection to the address
+func (s *Syslog) connect() error {
+	dialer := &net.Dialer{Timeout: s.Timeout.Duration}
+	var conn net.Conn
+	var err error
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, s.network, s.address,
+			s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.network, s.address)
+	}
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %s", s.Address, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Syslog) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Write sends a message per metric, in a datagram each over UDP, and in a
+// single write in their framing over TCP
+func (s *Syslog) Write(metrics []telegraf.Metric) error {
+	s.Lock()
+	defer s.Unlock()
+	if len(metrics) == 0 {
+		return nil
+	}
+	if s.network == "" {
+		return fmt.Errorf("not connected to %s", s.Address)
+	}
+
+	var writes [][]byte
+	var batch []byte
+	for _, m := range metrics {
+		msg := s.message(m)
+		switch {
+		case s.isUDP():
+			writes = append(writes, msg)
+		case s.Framing == "octet-counting":
+			batch = append(batch, strconv.Itoa(len(msg))+" "...)
+			batch = append(batch, msg...)
+		default:
+			batch = append(batch, msg...)
+			batch = append(batch, '\n')
+		}
+	}
+	if len(batch) > 0 {
+		writes = append(writes, batch)
+	}
+
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+	for _, buf := range writes {
+		s.conn.SetWriteDeadline(time.Now().Add(s.Timeout.Duration))
+		if _, err := s.conn.Write(buf); err != nil {
+			// The connection is reopened by the next write
+			s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("could not write to %s: %s", s.Address, err)
+		}
+	}
+	return nil
+}
+
+// message returns the RFC 5424 syslog message of the metric,
+// "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG"
+func (s *Syslog) message(m telegraf.Metric) []byte {
+	fields := m.Fields()
+	tags := m.Tags()
+
+	severity := code(fields["severity_code"], tags["severity"],
+		severityNames, s.DefaultSeverityCode)
+	facility := code(fields["facility_code"], tags["facility"],
+		facilityNames, s.DefaultFacilityCode)
+
+	t := m.Time()
+	if ts, ok := fields["timestamp"].(int64); ok {
+		t = time.Unix(0, ts)
+	}
+	hostname := s.hostname
+	for _, key := range []string{"hostname", "source", "host"} {
+		if tags[key] != "" {
+			hostname = tags[key]
+			break
+		}
+	}
+	appname := s.DefaultAppname
+	if tags["appname"] != "" {
+		appname = tags["appname"]
+	}
+	msgid := m.Name()
+	if v, ok := fields["msgid"]; ok {
+		msgid = formatValue(v)
+	}
+	var procid string
+	if v, ok := fields["procid"]; ok {
+		procid = formatValue(v)
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s %s", facility*8+severity,
+		t.UTC().Format(rfc5424Time), headerValue(hostname, 255),
+		headerValue(appname, 48), headerValue(procid, 128),
+		headerValue(msgid, 32), s.structuredData(fields, tags))
+	if v, ok := fields["message"]; ok {
+		msg += " " + formatValue(v)
+	}
+	return []byte(msg)
+}
+
+// structuredData returns the structured data of the fields and tags, "-" if
+// there is none
+func (s *Syslog) structuredData(
+	fields map[string]interface{},
+	tags map[string]string,
+) string {
+	// The parameters of the elements, by their ID
+	elements := make(map[string]map[string]string)
+	param := func(id, name, value string) {
+		if elements[id] == nil {
+			elements[id] = make(map[string]string)
+		}
+		if name != "" {
+			elements[id][name] = value
+		}
+	}
+
+FIELDS:
+	for k, v := range fields {
+		if headerFields[k] {
+			continue
+		}
+		for _, id := range s.SDIDs {
+			if k == id {
+				// An element without parameters
+				if b, ok := v.(bool); ok && b {
+					param(id, "", "")
+				}
+				continue FIELDS
+			}
+			if strings.HasPrefix(k, id+s.SDParamSeparator) {
+				param(id, k[len(id)+len(s.SDParamSeparator):], formatValue(v))
+				continue FIELDS
+			}
+		}
+		if s.DefaultSDID != "" {
+			param(s.DefaultSDID, k, formatValue(v))
+		}
+	}
+	if s.DefaultSDID != "" {
+		for k, v := range tags {
+			if !headerTags[k] {
+				param(s.DefaultSDID, k, v)
+			}
+		}
+	}
+	if len(elements) == 0 {
+		return "-"
+	}
+
+	ids := make([]string, 0, len(elements))
+	for id := range elements {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var sd []string
+	for _, id := range ids {
+		names := make([]string, 0, len(elements[id]))
+		for name := range elements[id] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		element := "[" + sdName(id)
+		for _, name := range names {
+			element += fmt.Sprintf(` %s="%s"`, sdName(name),
+				sdEscaper.Replace(elements[id][name]))
+		}
+		sd = append(sd, element+"]")
+	}
+	return strings.Join(sd, "")
+}
+
+// code returns the severity or facility code of the field, or of the name of
+// the tag, the default code if none is valid
+func code(field interface{}, tag string, names []string, def int) int {
+	if c, ok := field.(int64); ok && c >= 0 && int(c) < len(names) {
+		return int(c)
+	}
+	for c, name := range names {
+		if name == tag {
+			return c
+		}
+	}
+	return def
+}
+
+// formatValue formats the value of a field
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// printable replaces the characters not allowed in the header values and the
+// names of the structured data by underscores, and truncates the value to
+// max characters
+func printable(value string, max int, forbidden string) string {
+	b := []byte(value)
+	for i, c := range b {
+		if c <= ' ' || c >= 127 || strings.IndexByte(forbidden, c) != -1 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// headerValue returns the value of a header part, "-" if it is empty
+func headerValue(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	return printable(value, max, "")
+}
+
+// sdName returns the ID of an element or the name of a parameter of the
+// structured data
+func sdName(name string) string {
+	return printable(name, 32, `= ]"`)
+}
+
+func init() {
+	outputs.Add("syslog", func() telegraf.Output {
+		return &Syslog{
+			Framing:             "octet-counting",
+			SDParamSeparator:    "_",
+			DefaultSeverityCode: 5,
+			DefaultFacilityCode: 1,
+			DefaultAppname:      "Telegraf",
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2016, time.January, 4, 3, 0, 0, 123456789, time.UTC)
+
+func newSyslog() *Syslog {
+	return &Syslog{
+		SDParamSeparator:    "_",
+		DefaultSeverityCode: 5,
+		DefaultFacilityCode: 1,
+		DefaultAppname:      "Telegraf",
+		Log:                 testutil.Logger{},
+		hostname:            "agent01",
+	}
+}
+
+func TestMessage(t *testing.T) {
+	s := newSyslog()
+
+	// A metric of the syslog input
+	m, _ := telegraf.NewMetric("syslog",
+		map[string]string{"severity": "err", "facility": "daemon",
+			"hostname": "web01", "source": "10.0.0.1", "appname": "nginx"},
+		map[string]interface{}{"version": 1, "severity_code": 3,
+			"facility_code": 3, "procid": "42", "msgid": "ID47",
+			"timestamp": int64(1451876400000000000),
+			"message":   "upstream timed out"},
+		testTime)
+	assert.Equal(t, "<27>1 2016-01-04T03:00:00Z web01 nginx 42 ID47 - "+
+		"upstream timed out", string(s.message(m)))
+
+	// Defaults, without message
+	m, _ = telegraf.NewMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 99.5}, testTime)
+	assert.Equal(t, "<13>1 2016-01-04T03:00:00.123456Z agent01 Telegraf - cpu -",
+		string(s.message(m)))
+
+	// The codes of the fields override the names of the tags, the names
+	// being used if the codes are not valid
+	m, _ = telegraf.NewMetric("cpu",
+		map[string]string{"severity": "debug", "facility": "local0",
+			"host": "db 01"},
+		map[string]interface{}{"severity_code": 2, "facility_code": 42,
+			"procid": int64(7)},
+		testTime)
+	assert.Equal(t, "<130>1 2016-01-04T03:00:00.123456Z db_01 Telegraf 7 cpu -",
+		string(s.message(m)))
+}
+
+func TestStructuredData(t *testing.T) {
+	s := newSyslog()
+	s.SDIDs = []string{"origin", "meta"}
+
+	m, _ := telegraf.NewMetric("syslog",
+		map[string]string{"hostname": "web01", "dc": "eu"},
+		map[string]interface{}{"origin_ip": "10.0.0.1", "meta": true,
+			"origin_software": `nginx "1.9]"`, "value": 1.5},
+		testTime)
+	assert.Equal(t, `<13>1 2016-01-04T03:00:00.123456Z web01 Telegraf - syslog `+
+		`[meta][origin ip="10.0.0.1" software="nginx \"1.9\]\""]`,
+		string(s.message(m)))
+
+	// The other fields and tags with a default element
+	s.DefaultSDID = "telegraf@32473"
+	assert.Equal(t, `<13>1 2016-01-04T03:00:00.123456Z web01 Telegraf - syslog `+
+		`[meta][origin ip="10.0.0.1" software="nginx \"1.9\]\""]`+
+		`[telegraf@32473 dc="eu" value="1.5"]`,
+		string(s.message(m)))
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	for framing, expected := range map[string]string{
+		"octet-counting":  "58 <13>1 2016-01-04T03:00:00.123456Z agent01 Telegraf - cpu -",
+		"non-transparent": "<13>1 2016-01-04T03:00:00.123456Z agent01 Telegraf - cpu -\n",
+	} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		received := make(chan string, 1)
+		go func() {
+			conn, err := l.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+			buf := make([]byte, len(expected))
+			_, err = io.ReadFull(conn, buf)
+			require.NoError(t, err)
+			received <- string(buf)
+		}()
+
+		s := newSyslog()
+		s.Address = "tcp://" + l.Addr().String()
+		s.Framing = framing
+		require.NoError(t, s.Connect())
+		s.hostname = "agent01"
+		m, _ := telegraf.NewMetric("cpu", map[string]string{},
+			map[string]interface{}{"value": 1.0}, testTime)
+		require.NoError(t, s.Write([]telegraf.Metric{m}))
+		assert.Equal(t, expected, <-received, framing)
+		s.Close()
+		l.Close()
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s := newSyslog()
+	s.Address = "udp://" + conn.LocalAddr().String()
+	require.NoError(t, s.Connect())
+	defer s.Close()
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<13>1 2009-11-10T23:00:00Z \S+ Telegraf - test1 -$`,
+		string(buf[:n]))
+}
+
+func TestSyslogReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	s := newSyslog()
+	s.Address = "tcp://" + l.Addr().String()
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	// The write fails once the server closed the connection, the next write
+	// reconnecting
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	conn.Close()
+	for i := 0; i < 100 && s.Write(testutil.MockMetrics()) == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, s.conn)
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+		line, err := bufio.NewReader(conn).ReadString(' ')
+		require.NoError(t, err)
+		received <- line
+	}()
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	assert.Regexp(t, `^\d+ $`, <-received)
+}
+
+func TestSyslogInvalidConfig(t *testing.T) {
+	for _, s := range []*Syslog{
+		{Address: "unix:///tmp/syslog.sock"},
+		{Address: "udp://127.0.0.1:514", Framing: "line"},
+		{Address: "udp://127.0.0.1:514", DefaultSeverityCode: 8},
+		{Address: "udp://127.0.0.1:514", DefaultFacilityCode: -1},
+		{Address: "udp://127.0.0.1:514", InsecureSkipVerify: true},
+	} {
+		assert.Error(t, s.Connect(), "%+v", s)
+	}
+}
@@ -0,0 +1,347 @@
+package wavefront
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpconfig"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var (
+	// invalidNameChars are the characters not allowed in the metric names
+	invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9._\-/,~]`)
+	// invalidTagChars are the characters not allowed in the point tag keys
+	invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9._\-]`)
+	// tagValueEscaper escapes the point tag values, which are quoted
+	tagValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// maxTagLength is the maximum length of the key and value of a point tag
+const maxTagLength = 254
+
+type Wavefront struct {
+	// URL of the Wavefront cluster, with direct ingestion, and its API token
+	URL   string
+	Token string
+	// Host and Port of the Wavefront proxy, without URL
+	Host string
+	Port int
+
+	Prefix          string
+	MetricSeparator string `toml:"metric_separator"`
+	// ConvertPaths replaces the underscores of the metric names by the
+	// metric separator
+	ConvertPaths bool `toml:"convert_paths"`
+	// SourceOverride are the tags used as the source of the points, the
+	// first one set being used, instead of the host tag
+	SourceOverride []string `toml:"source_override"`
+	// ConvertBool sends the boolean fields as 1 and 0, skipped otherwise
+	ConvertBool bool `toml:"convert_bool"`
+	// StringToNumber maps the values of the string fields to numbers, per
+	// field, the other string fields being skipped
+	StringToNumber map[string]map[string]float64 `toml:"string_to_number"`
+
+	// HTTPBatchSize is the number of points sent per request with direct
+	// ingestion
+	HTTPBatchSize int `toml:"http_batch_size"`
+	Timeout       internal.Duration
+
+	// Optional TLS configuration of the direct ingestion
+	SSLCA              string `toml:"ssl_ca"`
+	SSLCert            string `toml:"ssl_cert"`
+	SSLKey             string `toml:"ssl_key"`
+	InsecureSkipVerify bool
+
+	Log telegraf.Logger `toml:"-"`
+
+	client   *http.Client
+	hostname string
+}
+
+var sampleConfig = `
+  # URL of the Wavefront cluster, with direct ingestion, and its API token
+  # url = "https://metrics.wavefront.com"
+  # token = ""
+
+  # Address of the Wavefront proxy, without url
+  host = "wavefront.example.com"
+  port = 2878
+
+  # Prefix of the metric names
+  # prefix = "telegraf."
+  # Separator of the measurement and field names, which replaces the
+  # underscores of the names with convert_paths
+  # metric_separator = "."
+  # convert_paths = true
+
+  # Tags used as the source of the points, the first one set being used,
+  # instead of the host tag
+  # source_override = ["hostname", "agent_host", "node_host"]
+
+  # Send the boolean fields as 1 and 0, skipped otherwise
+  # convert_bool = true
+  # Values of string fields mapped to numbers, the other string fields being
+  # skipped
+  # [outputs.wavefront.string_to_number.status]
+  #   green = 1.0
+  #   yellow = 0.5
+  #   red = 0.0
+
+  # Number of points sent per request with direct ingestion
+  # http_batch_size = 10000
+  # Timeout of the connections and requests
+  # timeout = "5s"
+
+  # Optional TLS configuration of the direct ingestion
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  # insecure_skip_verify = false
+`
+
+func (w *Wavefront) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *Wavefront) Description() string {
+	return "Send metrics to Wavefront, directly or through a proxy"
+}
+
+func (w *Wavefront) Connect() error {
+	if w.URL == "" && w.Host == "" {
+		return fmt.Errorf("one of url or host must be set")
+	}
+	if w.MetricSeparator == "" {
+		w.MetricSeparator = "."
+	}
+	if w.Port == 0 {
+		w.Port = 2878
+	}
+	if w.HTTPBatchSize <= 0 {
+		w.HTTPBatchSize = 10000
+	}
+	if w.Timeout.Duration == 0 {
+		w.Timeout.Duration = 5 * time.Second
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	w.hostname = hostname
+
+	if w.URL == "" {
+		// Test Connection to the proxy
+		conn, err := net.DialTimeout("tcp", w.proxyAddr(), w.Timeout.Duration)
+		if err != nil {
+			return fmt.Errorf("could not connect to Wavefront proxy: %s", err)
+		}
+		return conn.Close()
+	}
+	if w.Token == "" {
+		return fmt.Errorf("token must be set with url")
+	}
+	c := httpconfig.Config{
+		Timeout:     w.Timeout.Duration,
+		BearerToken: w.Token,
+		TLS: internal.TLSOptions{
+			SSLCA:              w.SSLCA,
+			SSLCert:            w.SSLCert,
+			SSLKey:             w.SSLKey,
+			InsecureSkipVerify: w.InsecureSkipVerify,
+		},
+		Proxy: internal.HTTPProxyOptions{UseSystemProxy: true},
+	}
+	client, err := c.CreateClient()
+	if err != nil {
+		return err
+	}
+	w.client = client
+	return nil
+}
+
+func (w *Wavefront) proxyAddr() string {
+	return net.JoinHostPort(w.Host, strconv.Itoa(w.Port))
+}
+
+func (w *Wavefront) Close() error {
+	return nil
+}
+
+// value returns the value of a field, false if it is skipped
+func (w *Wavefront) value(field string, v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		if !w.ConvertBool {
+			return "", false
+		}
+		if v {
+			return "1", true
+		}
+		return "0", true
+	case string:
+		if n, ok := w.StringToNumber[field][v]; ok {
+			return strconv.FormatFloat(n, 'f', -1, 64), true
+		}
+	}
+	return "", false
+}
+
+// sourceTag returns the tag of the source of the metric, empty if it has
+// none of the source tags
+func (w *Wavefront) sourceTag(tags map[string]string) string {
+	for _, tag := range w.SourceOverride {
+		if tags[tag] != "" {
+			return tag
+		}
+	}
+	if tags["host"] != "" {
+		return "host"
+	}
+	return ""
+}
+
+// sanitizeName returns the name of the metric of a field
+func (w *Wavefront) sanitizeName(name string) string {
+	if w.ConvertPaths {
+		name = strings.Replace(name, "_", w.MetricSeparator, -1)
+	}
+	return invalidNameChars.ReplaceAllString(name, "-")
+}
+
+// lines returns the lines of the points of the metric in the Wavefront
+// data format, a point per numeric or converted field
+func (w *Wavefront) lines(m telegraf.Metric) []string {
+	tags := m.Tags()
+	source := w.hostname
+	sourceTag := w.sourceTag(tags)
+	if sourceTag != "" {
+		source = tags[sourceTag]
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if k != sourceTag && v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var pointTags []string
+	for _, k := range keys {
+		key := invalidTagChars.ReplaceAllString(k, "-")
+		value := tags[k]
+		if len(key) >= maxTagLength {
+			continue
+		}
+		if len(key)+len(value) > maxTagLength {
+			value = value[:maxTagLength-len(key)]
+		}
+		pointTags = append(pointTags, fmt.Sprintf(`%s="%s"`, key,
+			tagValueEscaper.Replace(value)))
+	}
+	suffix := fmt.Sprintf(` %d source="%s"`, m.Time().Unix(),
+		tagValueEscaper.Replace(source))
+	if len(pointTags) > 0 {
+		suffix += " " + strings.Join(pointTags, " ")
+	}
+
+	fields := make([]string, 0, len(m.Fields()))
+	for name := range m.Fields() {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	var lines []string
+	for _, field := range fields {
+		value, ok := w.value(field, m.Fields()[field])
+		if !ok {
+			w.Log.Debugf("Skipping field %s of %s of type %T", field,
+				m.Name(), m.Fields()[field])
+			continue
+		}
+		name := w.sanitizeName(w.Prefix + m.Name() + w.MetricSeparator +
+			field)
+		if field == "value" {
+			name = w.sanitizeName(w.Prefix + m.Name())
+		}
+		lines = append(lines, name+" "+value+suffix+"\n")
+	}
+	return lines
+}
+
+func (w *Wavefront) Write(metrics []telegraf.Metric) error {
+	var lines []string
+	for _, m := range metrics {
+		lines = append(lines, w.lines(m)...)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if w.URL != "" {
+		for start := 0; start < len(lines); start += w.HTTPBatchSize {
+			end := start + w.HTTPBatchSize
+			if end > len(lines) {
+				end = len(lines)
+			}
+			if err := w.report(lines[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", w.proxyAddr(), w.Timeout.Duration)
+	if err != nil {
+		return fmt.Errorf("could not connect to Wavefront proxy: %s", err)
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(w.Timeout.Duration))
+	if _, err := conn.Write([]byte(strings.Join(lines, ""))); err != nil {
+		return fmt.Errorf("could not write to Wavefront proxy: %s", err)
+	}
+	return nil
+}
+
+// report sends the lines to the report endpoint of direct ingestion
+func (w *Wavefront) report(lines []string) error {
+	u := strings.TrimSuffix(w.URL, "/") + "/report?f=wavefront"
+	resp, err := w.client.Post(u, "application/octet-stream",
+		strings.NewReader(strings.Join(lines, "")))
+	if err != nil {
+		return fmt.Errorf("could not write to Wavefront: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("could not write to Wavefront: %s: %s", resp.Status,
+			bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("wavefront", func() telegraf.Output {
+		return &Wavefront{
+			MetricSeparator: ".",
+			ConvertPaths:    true,
+			ConvertBool:     true,
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package wavefront
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2016, time.January, 4, 3, 0, 0, 0, time.UTC)
+
+func newWavefront() *Wavefront {
+	return &Wavefront{
+		MetricSeparator: ".",
+		ConvertPaths:    true,
+		ConvertBool:     true,
+		Log:             testutil.Logger{},
+		hostname:        "agent01",
+	}
+}
+
+func TestLines(t *testing.T) {
+	w := newWavefront()
+	w.Prefix = "telegraf."
+	m, _ := telegraf.NewMetric("disk_io",
+		map[string]string{"host": "web01", "path": `/var/"log"`,
+			"bad key!": "x", "empty": ""},
+		map[string]interface{}{"read_bytes": int64(42), "value": 1.5,
+			"mounted": true, "state": "ok"},
+		testTime)
+	assert.Equal(t, []string{
+		`telegraf.disk.io.mounted 1 1451876400 source="web01" bad-key-="x" path="/var/\"log\""` + "\n",
+		`telegraf.disk.io.read.bytes 42 1451876400 source="web01" bad-key-="x" path="/var/\"log\""` + "\n",
+		`telegraf.disk.io 1.5 1451876400 source="web01" bad-key-="x" path="/var/\"log\""` + "\n",
+	}, w.lines(m))
+
+	// Without path conversion, bool conversion and host tag
+	w = newWavefront()
+	w.ConvertPaths = false
+	w.ConvertBool = false
+	m, _ = telegraf.NewMetric("disk_io", map[string]string{},
+		map[string]interface{}{"read_bytes": int64(42), "mounted": true},
+		testTime)
+	assert.Equal(t, []string{
+		`disk_io.read_bytes 42 1451876400 source="agent01"` + "\n",
+	}, w.lines(m))
+}
+
+func TestSourceOverride(t *testing.T) {
+	w := newWavefront()
+	w.SourceOverride = []string{"hostname", "node_host"}
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "web01", "node_host": "node01"},
+		map[string]interface{}{"value": 1.0}, testTime)
+	assert.Equal(t, []string{
+		`cpu 1 1451876400 source="node01" host="web01"` + "\n",
+	}, w.lines(m))
+}
+
+func TestStringToNumber(t *testing.T) {
+	w := newWavefront()
+	w.StringToNumber = map[string]map[string]float64{
+		"status": {"green": 1, "yellow": 0.5},
+	}
+	m, _ := telegraf.NewMetric("es", map[string]string{"host": "web01"},
+		map[string]interface{}{"status": "yellow", "name": "cluster"},
+		testTime)
+	assert.Equal(t, []string{
+		`es.status 0.5 1451876400 source="web01"` + "\n",
+	}, w.lines(m))
+}
+
+func TestLongTags(t *testing.T) {
+	w := newWavefront()
+	m, _ := telegraf.NewMetric("cpu",
+		map[string]string{"host": "web01", "long": strings.Repeat("x", 300)},
+		map[string]interface{}{"value": 1.0}, testTime)
+	lines := w.lines(m)
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0],
+		`long="`+strings.Repeat("x", maxTagLength-len("long"))+`"`)
+}
+
+func TestWriteProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				received <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+
+	w := newWavefront()
+	w.Host = "127.0.0.1"
+	w.Port = listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, w.Connect())
+
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"usage_idle": 91.5}, testTime)
+	require.NoError(t, w.Write([]telegraf.Metric{m}))
+	select {
+	case line := <-received:
+		assert.Equal(t, `cpu.usage.idle 91.5 1451876400 source="web01"`, line)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+	}
+}
+
+func TestWriteDirect(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/report", r.URL.Path)
+			assert.Equal(t, "wavefront", r.URL.Query().Get("f"))
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+		}))
+	defer ts.Close()
+
+	w := newWavefront()
+	w.URL = ts.URL
+	w.Token = "secret"
+	w.HTTPBatchSize = 2
+	require.NoError(t, w.Connect())
+	m, _ := telegraf.NewMetric("cpu", map[string]string{"host": "web01"},
+		map[string]interface{}{"usage_idle": 91.5, "usage_user": 4.5,
+			"usage_system": 4.0}, testTime)
+	require.NoError(t, w.Write([]telegraf.Metric{m}))
+	require.Len(t, bodies, 2)
+	assert.Equal(t, 2, strings.Count(bodies[0], "\n"))
+	assert.Equal(t, 1, strings.Count(bodies[1], "\n"))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, w := range []*Wavefront{
+		{},
+		{URL: "https://metrics.wavefront.com"},
+	} {
+		w.Log = testutil.Logger{}
+		assert.Error(t, w.Connect())
+	}
+}
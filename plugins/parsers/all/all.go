@@ -0,0 +1,11 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/parsers/csv"
+	_ "github.com/influxdata/telegraf/plugins/parsers/grok"
+	_ "github.com/influxdata/telegraf/plugins/parsers/influx"
+	_ "github.com/influxdata/telegraf/plugins/parsers/json"
+	_ "github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	_ "github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	_ "github.com/influxdata/telegraf/plugins/parsers/xml"
+)
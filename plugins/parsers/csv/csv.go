@@ -0,0 +1,99 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// CSV parses each row of a CSV document as a metric, with a field per column
+// and the tag keys columns as tags. The column names are read from the first
+// row unless given.
+type CSV struct {
+	MetricName  string
+	TagKeys     []string
+	ColumnNames []string
+	Delimiter   rune
+}
+
+func (p *CSV) Parse(buf []byte) ([]telegraf.Metric, error) {
+	r := csv.NewReader(bytes.NewReader(buf))
+	r.Comma = p.Delimiter
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	columns := p.ColumnNames
+	if len(columns) == 0 {
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to parse CSV header: %s", err)
+		}
+		columns = header
+	}
+	isTag := make(map[string]bool, len(p.TagKeys))
+	for _, key := range p.TagKeys {
+		isTag[key] = true
+	}
+
+	now := time.Now()
+	var metrics []telegraf.Metric
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return metrics, fmt.Errorf("unable to parse CSV: %s", err)
+		}
+		if len(row) != len(columns) {
+			return metrics, fmt.Errorf("unable to parse CSV: row has %d "+
+				"columns, expected %d", len(row), len(columns))
+		}
+
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		for i, value := range row {
+			if isTag[columns[i]] {
+				tags[columns[i]] = value
+			} else if value != "" {
+				fields[columns[i]] = parsers.ParseValue(value)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		m, err := telegraf.NewMetric(p.MetricName, tags, fields, now)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func init() {
+	parsers.Add("csv", func(c *parsers.Config) (telegraf.Parser, error) {
+		delimiter := ','
+		if c.CSVDelimiter != "" {
+			var size int
+			delimiter, size = utf8.DecodeRuneInString(c.CSVDelimiter)
+			if size != len(c.CSVDelimiter) {
+				return nil, fmt.Errorf("csv_delimiter must be a single "+
+					"character, got %q", c.CSVDelimiter)
+			}
+		}
+		return &CSV{
+			MetricName:  c.MetricName,
+			TagKeys:     c.TagKeys,
+			ColumnNames: c.CSVColumnNames,
+			Delimiter:   delimiter,
+		}, nil
+	})
+}
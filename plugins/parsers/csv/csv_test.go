@@ -0,0 +1,55 @@
+package csv
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeader(t *testing.T) {
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "csv",
+		MetricName: "http",
+		TagKeys:    []string{"host"},
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse([]byte("host,load,up,state\n" +
+		"a,1.5,10,ok\n" +
+		"b, 2,,\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]string{"host": "a"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"load":  1.5,
+		"up":    int64(10),
+		"state": "ok",
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]interface{}{"load": int64(2)},
+		metrics[1].Fields())
+
+	_, err = p.Parse([]byte("host,load\na,1,2\n"))
+	assert.Error(t, err)
+}
+
+func TestParseColumnNames(t *testing.T) {
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:     "csv",
+		MetricName:     "http",
+		CSVColumnNames: []string{"load", "up"},
+		CSVDelimiter:   ";",
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse([]byte("1.5;true\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"load": 1.5, "up": true},
+		metrics[0].Fields())
+
+	_, err = parsers.NewParser(&parsers.Config{
+		DataFormat:   "csv",
+		CSVDelimiter: ";;",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,320 @@
+package grok
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// timeLayouts are the layouts of the ts-<name> modifiers, and the layouts
+// tried in order by the ts modifier
+var timeLayouts = []struct {
+	name   string
+	layout string
+}{
+	{"rfc3339", time.RFC3339},
+	{"rfc3339nano", time.RFC3339Nano},
+	{"httpd", "02/Jan/2006:15:04:05 -0700"},
+	{"syslog", "Jan _2 15:04:05"},
+	{"ansic", time.ANSIC},
+	{"unix", time.UnixDate},
+	{"rubydate", time.RubyDate},
+	{"rfc822", time.RFC822},
+	{"rfc822z", time.RFC822Z},
+	{"rfc850", time.RFC850},
+	{"rfc1123", time.RFC1123},
+	{"rfc1123z", time.RFC1123Z},
+}
+
+// reference matches the references to patterns in the grok patterns,
+// %{NAME}, %{NAME:field} or %{NAME:field:modifier}
+var reference = regexp.MustCompile(`%{(\w+)(?::([^:}]+)(?::([^}]+))?)?}`)
+
+// capture is a field captured by a pattern, and its modifier: its type, tag
+// or drop, or the layout of a timestamp
+type capture struct {
+	name     string
+	modifier string
+}
+
+// Grok parses each line matching one of its patterns as a metric, the fields
+// of the patterns, %{PATTERN:field:modifier}, being its fields, tags or
+// timestamp. The lines matching no pattern are skipped.
+type Grok struct {
+	MetricName string
+	Patterns   []string
+	// CustomPatterns are definitions of patterns, "NAME regexp" per line
+	CustomPatterns string
+	// CustomPatternFiles are files of definitions of patterns
+	CustomPatternFiles []string
+	// Timezone is the location of the timestamps without a timezone, UTC if
+	// empty, "Local" being the local time
+	Timezone string
+
+	definitions map[string]string
+	regexps     []*regexp.Regexp
+	captures    map[string]capture
+	loc         *time.Location
+}
+
+// Compile expands the patterns, and compiles them to regular expressions
+func (p *Grok) Compile() error {
+	if len(p.Patterns) == 0 {
+		return fmt.Errorf("no grok patterns set")
+	}
+	p.definitions = make(map[string]string)
+	if err := p.addDefinitions(defaultPatterns); err != nil {
+		return err
+	}
+	for _, file := range p.CustomPatternFiles {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := p.addDefinitions(string(b)); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+	}
+	if err := p.addDefinitions(p.CustomPatterns); err != nil {
+		return err
+	}
+
+	p.loc = time.UTC
+	if p.Timezone != "" {
+		loc, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid grok timezone %q: %s", p.Timezone, err)
+		}
+		p.loc = loc
+	}
+
+	p.regexps = nil
+	p.captures = make(map[string]capture)
+	for _, pattern := range p.Patterns {
+		expanded, err := p.expand(pattern, 0)
+		if err != nil {
+			return fmt.Errorf("invalid grok pattern %q: %s", pattern, err)
+		}
+		re, err := regexp.Compile("^" + expanded + "$")
+		if err != nil {
+			return fmt.Errorf("invalid grok pattern %q: %s", pattern, err)
+		}
+		p.regexps = append(p.regexps, re)
+	}
+	return nil
+}
+
+// addDefinitions adds the definitions of patterns, "NAME regexp" per line,
+// the empty lines and comments starting with # being skipped
+func (p *Grok) addDefinitions(defs string) error {
+	scanner := bufio.NewScanner(strings.NewReader(defs))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i == -1 {
+			return fmt.Errorf("invalid pattern definition %q", line)
+		}
+		p.definitions[line[:i]] = strings.TrimSpace(line[i:])
+	}
+	return scanner.Err()
+}
+
+// expand returns the regular expression of the pattern, replacing the
+// references to patterns by their definition, in groups named after the
+// index of their capture if they capture a field
+func (p *Grok) expand(pattern string, depth int) (string, error) {
+	// The definitions referring to themselves would expand forever
+	if depth > 32 {
+		return "", fmt.Errorf("patterns nested too deeply")
+	}
+	var err error
+	expanded := reference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := reference.FindStringSubmatch(ref)
+		def, ok := p.definitions[m[1]]
+		if !ok {
+			err = fmt.Errorf("undefined pattern %s", m[1])
+			return ""
+		}
+		var sub string
+		if sub, err = p.expand(def, depth+1); err != nil {
+			return ""
+		}
+		if m[2] == "" {
+			return "(?:" + sub + ")"
+		}
+		if err = checkModifier(m[3]); err != nil {
+			return ""
+		}
+		group := "c" + strconv.Itoa(len(p.captures))
+		p.captures[group] = capture{name: m[2], modifier: m[3]}
+		return "(?P<" + group + ">" + sub + ")"
+	})
+	return expanded, err
+}
+
+// checkModifier returns an error if the modifier of a capture is unknown
+func checkModifier(modifier string) error {
+	switch modifier {
+	case "", "string", "int", "float", "duration", "tag", "drop", "ts",
+		"ts-epoch", "ts-epochmilli", "ts-epochnano":
+		return nil
+	}
+	if strings.HasPrefix(modifier, `ts-"`) && strings.HasSuffix(modifier, `"`) {
+		return nil
+	}
+	for _, l := range timeLayouts {
+		if modifier == "ts-"+l.name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown modifier %q", modifier)
+}
+
+func (p *Grok) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if p.regexps == nil {
+		if err := p.Compile(); err != nil {
+			return nil, err
+		}
+	}
+
+	var metrics []telegraf.Metric
+	var lastErr error
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		m, err := p.parseLine(scanner.Text())
+		if err != nil {
+			lastErr = err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return metrics, err
+	}
+	return metrics, lastErr
+}
+
+// parseLine returns the metric of the first pattern matching the line, nil
+// if none is matching
+func (p *Grok) parseLine(line string) (telegraf.Metric, error) {
+	for _, re := range p.regexps {
+		values := re.FindStringSubmatch(line)
+		if values == nil {
+			continue
+		}
+
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		t := time.Now()
+		for i, group := range re.SubexpNames() {
+			c, ok := p.captures[group]
+			if !ok || values[i] == "" {
+				continue
+			}
+			value := values[i]
+			var err error
+			switch {
+			case c.modifier == "" || c.modifier == "string":
+				fields[c.name] = value
+			case c.modifier == "int":
+				fields[c.name], err = strconv.ParseInt(value, 10, 64)
+			case c.modifier == "float":
+				fields[c.name], err = strconv.ParseFloat(value, 64)
+			case c.modifier == "duration":
+				var d time.Duration
+				d, err = time.ParseDuration(value)
+				fields[c.name] = int64(d)
+			case c.modifier == "tag":
+				tags[c.name] = value
+			case c.modifier == "drop":
+			default:
+				t, err = p.parseTime(c.modifier, value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse grok %s of %q: %s",
+					c.name, line, err)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, nil
+		}
+		return telegraf.NewMetric(p.MetricName, tags, fields, t)
+	}
+	return nil, nil
+}
+
+// parseTime parses the timestamp of a ts modifier
+func (p *Grok) parseTime(modifier string, value string) (time.Time, error) {
+	switch modifier {
+	case "ts-epoch":
+		f, err := strconv.ParseFloat(value, 64)
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)), err
+	case "ts-epochmilli":
+		ms, err := strconv.ParseInt(value, 10, 64)
+		return time.Unix(0, ms*int64(time.Millisecond)), err
+	case "ts-epochnano":
+		ns, err := strconv.ParseInt(value, 10, 64)
+		return time.Unix(0, ns), err
+	case "ts":
+		for _, l := range timeLayouts {
+			if t, err := p.parseLayout(l.layout, value); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unknown timestamp format")
+	}
+
+	if strings.HasPrefix(modifier, `ts-"`) {
+		return p.parseLayout(strings.Trim(modifier[3:], `"`), value)
+	}
+	for _, l := range timeLayouts {
+		if modifier == "ts-"+l.name {
+			return p.parseLayout(l.layout, value)
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown modifier %q", modifier)
+}
+
+// parseLayout parses the timestamp in the timezone of the parser. The
+// timestamps without a year, ie of syslog, are of the last twelve months.
+func (p *Grok) parseLayout(layout string, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, value, p.loc)
+	if err != nil || t.Year() != 0 {
+		return t, err
+	}
+	now := time.Now().In(p.loc)
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.AddDate(0, 0, 1)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, nil
+}
+
+func init() {
+	parsers.Add("grok", func(c *parsers.Config) (telegraf.Parser, error) {
+		p := &Grok{
+			MetricName:         c.MetricName,
+			Patterns:           c.GrokPatterns,
+			CustomPatterns:     c.GrokCustomPatterns,
+			CustomPatternFiles: c.GrokCustomPatternFiles,
+			Timezone:           c.GrokTimezone,
+		}
+		return p, p.Compile()
+	})
+}
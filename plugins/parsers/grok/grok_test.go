@@ -0,0 +1,112 @@
+package grok
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCombinedLogFormat(t *testing.T) {
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat:   "grok",
+		MetricName:   "access_log",
+		GrokPatterns: []string{"%{COMBINED_LOG_FORMAT}"},
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse([]byte(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] ` +
+		`"GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" ` +
+		`"Mozilla/4.08 [en] (Win98; I ;Nav)"` + "\nnot an access log\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	m := metrics[0]
+	assert.Equal(t, "access_log", m.Name())
+	assert.Equal(t, map[string]string{"verb": "GET", "resp_code": "200"},
+		m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"client_ip":    "127.0.0.1",
+		"ident":        "-",
+		"auth":         "frank",
+		"request":      "/apache_pb.gif",
+		"http_version": float64(1),
+		"resp_bytes":   int64(2326),
+		"referrer":     `"http://www.example.com/start.html"`,
+		"agent":        `"Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+	}, m.Fields())
+	assert.Equal(t, int64(971211336), m.Time().Unix())
+}
+
+func TestParseCustomPatterns(t *testing.T) {
+	p := &Grok{
+		MetricName: "app",
+		Patterns: []string{
+			`%{TIMESTAMP_ISO8601:time:ts-rfc3339} %{LOGLEVEL:level:tag} took %{DURATION:took:duration}`,
+			`%{NUMBER:time:ts-epoch} %{WORD:queue:tag} size=%{INT:size:int} load=%{NUMBER:load:float}`,
+			`%{DATE_US:date:ts-"01/02/2006"} %{GREEDYDATA:message}`,
+		},
+		CustomPatterns: "# durations of Go\nDURATION [0-9.]+(?:ns|us|ms|s|m|h)",
+	}
+	require.NoError(t, p.Compile())
+	metrics, err := p.Parse([]byte("2016-01-27T10:00:00Z INFO took 1.5s\n" +
+		"1453888800.5 mail size=42 load=0.75\n" +
+		"01/27/2016 hello world\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	assert.Equal(t, map[string]string{"level": "INFO"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{"took": int64(1500 * time.Millisecond)},
+		metrics[0].Fields())
+	assert.Equal(t, time.Date(2016, 1, 27, 10, 0, 0, 0, time.UTC).UnixNano(),
+		metrics[0].Time().UnixNano())
+
+	assert.Equal(t, map[string]string{"queue": "mail"}, metrics[1].Tags())
+	assert.Equal(t, map[string]interface{}{"size": int64(42), "load": 0.75},
+		metrics[1].Fields())
+	assert.Equal(t, int64(1453888800500000000), metrics[1].Time().UnixNano())
+
+	assert.Equal(t, map[string]interface{}{"message": "hello world"},
+		metrics[2].Fields())
+	assert.Equal(t, time.Date(2016, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano(),
+		metrics[2].Time().UnixNano())
+}
+
+func TestParseSyslogTimestamp(t *testing.T) {
+	p := &Grok{
+		MetricName: "syslog",
+		Patterns:   []string{`%{SYSLOGBASE} %{GREEDYDATA:message}`},
+		Timezone:   "UTC",
+	}
+	require.NoError(t, p.Compile())
+	now := time.Now().UTC()
+	line := now.Format("Jan _2 15:04:05") + " web1 sshd[42]: session opened"
+	metrics, err := p.Parse([]byte(line))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]string{"logsource": "web1"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"program": "sshd",
+		"pid":     int64(42),
+		"message": "session opened",
+	}, metrics[0].Fields())
+	assert.Equal(t, now.Unix(), metrics[0].Time().Unix())
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, p := range []*Grok{
+		{},
+		{Patterns: []string{"%{MISSING}"}},
+		{Patterns: []string{"%{INT:value:bytes}"}},
+		{Patterns: []string{"%{LOOP}"}, CustomPatterns: "LOOP %{LOOP}"},
+		{Patterns: []string{"%{WORD"}, CustomPatterns: "BAD"},
+		{Patterns: []string{"%{INT}"}, Timezone: "Nowhere/Nothing"},
+	} {
+		assert.Error(t, p.Compile(), "%v", p.Patterns)
+	}
+
+	p := &Grok{Patterns: []string{"%{WORD:value:int}"}}
+	_, err := p.Parse([]byte("hello"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,19 @@
+package influx
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Influx parses metrics in line protocol
+type Influx struct{}
+
+func (p *Influx) Parse(buf []byte) ([]telegraf.Metric, error) {
+	return telegraf.ParseMetrics(buf)
+}
+
+func init() {
+	parsers.Add("influx", func(c *parsers.Config) (telegraf.Parser, error) {
+		return &Influx{}, nil
+	})
+}
@@ -0,0 +1,77 @@
+package json
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// JSON parses a JSON object, or an array of objects, as one metric per
+// object. The numbers of the objects are flattened into fields, see
+// internal.JSONFlattener, and the strings of the top level tag keys are tags.
+type JSON struct {
+	MetricName string
+	TagKeys    []string
+}
+
+func (p *JSON) Parse(buf []byte) ([]telegraf.Metric, error) {
+	var v interface{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return nil, fmt.Errorf("unable to parse JSON: %s", err)
+	}
+
+	var objects []map[string]interface{}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		objects = append(objects, t)
+	case []interface{}:
+		for _, o := range t {
+			object, ok := o.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unable to parse JSON: got %T in "+
+					"array, expected objects", o)
+			}
+			objects = append(objects, object)
+		}
+	default:
+		return nil, fmt.Errorf("unable to parse JSON: got %T, expected an "+
+			"object or array of objects", v)
+	}
+
+	now := time.Now()
+	metrics := make([]telegraf.Metric, 0, len(objects))
+	for _, object := range objects {
+		tags := make(map[string]string)
+		for _, key := range p.TagKeys {
+			if s, ok := object[key].(string); ok {
+				tags[key] = s
+			}
+			delete(object, key)
+		}
+
+		f := internal.JSONFlattener{}
+		if err := f.FlattenJSON("", object); err != nil {
+			return metrics, err
+		}
+		// Objects without numbers are no metrics
+		if len(f.Fields) == 0 {
+			continue
+		}
+		m, err := telegraf.NewMetric(p.MetricName, tags, f.Fields, now)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func init() {
+	parsers.Add("json", func(c *parsers.Config) (telegraf.Parser, error) {
+		return &JSON{MetricName: c.MetricName, TagKeys: c.TagKeys}, nil
+	})
+}
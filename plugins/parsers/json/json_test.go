@@ -0,0 +1,39 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseObject(t *testing.T) {
+	p := &JSON{MetricName: "http", TagKeys: []string{"role"}}
+	metrics, err := p.Parse([]byte(`{"role": "master", "uptime": 10,
+		"mem": {"used": 1.5, "free": 3}, "version": "1.0"}`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "http", metrics[0].Name())
+	assert.Equal(t, map[string]string{"role": "master"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"uptime":   float64(10),
+		"mem_used": 1.5,
+		"mem_free": float64(3),
+	}, metrics[0].Fields())
+}
+
+func TestParseArray(t *testing.T) {
+	p := &JSON{MetricName: "http", TagKeys: []string{"host"}}
+	metrics, err := p.Parse([]byte(`[{"host": "a", "load": 1},
+		{"host": "b", "load": 2}, {"host": "c"}]`))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]string{"host": "b"}, metrics[1].Tags())
+	assert.Equal(t, map[string]interface{}{"load": float64(2)},
+		metrics[1].Fields())
+
+	for _, invalid := range []string{`{"a": `, `[1, 2]`, `"a"`} {
+		_, err := p.Parse([]byte(invalid))
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,130 @@
+package logfmt
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Logfmt parses each line of logfmt, key=value pairs separated by spaces, as
+// a metric with a field per pair and the tag keys pairs as tags. The values
+// can be quoted, keeping them strings, and the keys without a value are true
+// booleans.
+type Logfmt struct {
+	MetricName string
+	TagKeys    []string
+}
+
+func (p *Logfmt) Parse(buf []byte) ([]telegraf.Metric, error) {
+	isTag := make(map[string]bool, len(p.TagKeys))
+	for _, key := range p.TagKeys {
+		isTag[key] = true
+	}
+
+	now := time.Now()
+	var metrics []telegraf.Metric
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		pairs, err := parseLine(scanner.Text())
+		if err != nil {
+			return metrics, fmt.Errorf("unable to parse logfmt: %s", err)
+		}
+
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		for _, pair := range pairs {
+			switch {
+			case isTag[pair.key]:
+				tags[pair.key] = pair.value
+			case pair.value == "" && !pair.quoted:
+				fields[pair.key] = true
+			case pair.quoted:
+				fields[pair.key] = pair.value
+			default:
+				fields[pair.key] = parsers.ParseValue(pair.value)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		m, err := telegraf.NewMetric(p.MetricName, tags, fields, now)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, scanner.Err()
+}
+
+// pair is a key and its value, an empty value not quoted being a key alone
+type pair struct {
+	key    string
+	value  string
+	quoted bool
+}
+
+// parseLine returns the key=value pairs of the line
+func parseLine(line string) ([]pair, error) {
+	var pairs []pair
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return pairs, nil
+		}
+
+		i := strings.IndexAny(line, "= \t")
+		if i == 0 {
+			return pairs, fmt.Errorf("missing key before %q", line)
+		}
+		if i == -1 {
+			i = len(line)
+		}
+		key := line[:i]
+		line = line[i:]
+		if !strings.HasPrefix(line, "=") {
+			pairs = append(pairs, pair{key: key})
+			continue
+		}
+		line = line[1:]
+
+		if !strings.HasPrefix(line, `"`) {
+			i = strings.IndexAny(line, " \t")
+			if i == -1 {
+				i = len(line)
+			}
+			pairs = append(pairs, pair{key: key, value: line[:i]})
+			line = line[i:]
+			continue
+		}
+
+		// The quoted values end at the first quote not escaped
+		end := 1
+		for end < len(line) && line[end] != '"' {
+			if line[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(line) {
+			return pairs, fmt.Errorf("unterminated quoted value of %s", key)
+		}
+		value, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return pairs, fmt.Errorf("invalid quoted value of %s: %s", key, err)
+		}
+		pairs = append(pairs, pair{key: key, value: value, quoted: true})
+		line = line[end+1:]
+	}
+}
+
+func init() {
+	parsers.Add("logfmt", func(c *parsers.Config) (telegraf.Parser, error) {
+		return &Logfmt{MetricName: c.MetricName, TagKeys: c.TagKeys}, nil
+	})
+}
@@ -0,0 +1,55 @@
+package logfmt
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "logfmt",
+		MetricName: "app",
+		TagKeys:    []string{"level"},
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse([]byte(
+		`level=info msg="request done" path=/ duration=0.25 status=200 cached` +
+			"\n\nlevel=warn retries=3 id=\"42\" msg=\"say \\\"hi\\\"\"\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	assert.Equal(t, "app", metrics[0].Name())
+	assert.Equal(t, map[string]string{"level": "info"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"msg":      "request done",
+		"path":     "/",
+		"duration": 0.25,
+		"status":   int64(200),
+		"cached":   true,
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]interface{}{
+		"retries": int64(3),
+		"id":      "42",
+		"msg":     `say "hi"`,
+	}, metrics[1].Fields())
+}
+
+func TestParseErrors(t *testing.T) {
+	p := &Logfmt{MetricName: "app"}
+	for _, line := range []string{
+		`msg="unterminated`,
+		`=value`,
+	} {
+		_, err := p.Parse([]byte(line))
+		assert.Error(t, err, line)
+	}
+
+	// The lines with tags alone are no metrics
+	p.TagKeys = []string{"level"}
+	metrics, err := p.Parse([]byte("level=info\n"))
+	require.NoError(t, err)
+	assert.Empty(t, metrics)
+}
@@ -0,0 +1,67 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// Prometheus parses the samples of the Prometheus text format as metrics, the
+// same way as the prometheus input: a metric per sample named after it with a
+// "prometheus_" prefix, its labels as tags and a "value" field.
+type Prometheus struct{}
+
+func (p *Prometheus) Parse(buf []byte) ([]telegraf.Metric, error) {
+	now := time.Now()
+	sampleDecoder := &expfmt.SampleDecoder{
+		Dec:  expfmt.NewDecoder(bytes.NewReader(buf), expfmt.FmtText),
+		Opts: &expfmt.DecodeOptions{Timestamp: model.TimeFromUnixNano(now.UnixNano())},
+	}
+
+	var metrics []telegraf.Metric
+	for {
+		var samples model.Vector
+		err := sampleDecoder.Decode(&samples)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return metrics, fmt.Errorf("unable to parse Prometheus samples: %s",
+				err)
+		}
+		for _, sample := range samples {
+			// NaN is not a valid field value
+			if math.IsNaN(float64(sample.Value)) {
+				continue
+			}
+			tags := make(map[string]string)
+			for key, value := range sample.Metric {
+				if key == model.MetricNameLabel {
+					continue
+				}
+				tags[string(key)] = string(value)
+			}
+			m, err := telegraf.NewMetric(
+				"prometheus_"+string(sample.Metric[model.MetricNameLabel]),
+				tags, map[string]interface{}{"value": float64(sample.Value)},
+				now)
+			if err != nil {
+				return metrics, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func init() {
+	parsers.Add("prometheus", func(c *parsers.Config) (telegraf.Parser, error) {
+		return &Prometheus{}, nil
+	})
+}
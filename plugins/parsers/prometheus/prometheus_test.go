@@ -0,0 +1,40 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleTextFormat = `# HELP go_gc_duration_seconds A summary of the GC invocation durations.
+# TYPE go_gc_duration_seconds summary
+go_gc_duration_seconds{quantile="0"} 0.00010425500000000001
+go_gc_duration_seconds{quantile="1"} NaN
+go_gc_duration_seconds_sum 0.0018183950000000002
+go_gc_duration_seconds_count 7
+# HELP go_goroutines Number of goroutines that currently exist.
+# TYPE go_goroutines gauge
+go_goroutines 15
+`
+
+func TestParse(t *testing.T) {
+	p := &Prometheus{}
+	metrics, err := p.Parse([]byte(sampleTextFormat))
+	require.NoError(t, err)
+	require.Len(t, metrics, 4)
+
+	values := make(map[string]interface{})
+	for _, m := range metrics {
+		values[m.Name()+m.Tags()["quantile"]] = m.Fields()["value"]
+	}
+	assert.Equal(t, map[string]interface{}{
+		"prometheus_go_gc_duration_seconds0":      0.00010425500000000001,
+		"prometheus_go_gc_duration_seconds_sum":   0.0018183950000000002,
+		"prometheus_go_gc_duration_seconds_count": float64(7),
+		"prometheus_go_goroutines":                float64(15),
+	}, values)
+
+	_, err = p.Parse([]byte("go_goroutines{ 15\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Config are the options of the parsers. The plugins reading data in a
+// data_format declare the options they support as their own fields and copy
+// them to a Config:
+//
+//	DataFormat     string   `toml:"data_format"`
+//	TagKeys        []string `toml:"tag_keys"`
+//	CSVColumnNames []string `toml:"csv_column_names"`
+//	CSVDelimiter   string   `toml:"csv_delimiter"`
+//	XMLPath        string   `toml:"xml_path"`
+//
+// and for the grok data format:
+//
+//	GrokPatterns           []string `toml:"grok_patterns"`
+//	GrokCustomPatterns     string   `toml:"grok_custom_patterns"`
+//	GrokCustomPatternFiles []string `toml:"grok_custom_pattern_files"`
+//	GrokTimezone           string   `toml:"grok_timezone"`
+type Config struct {
+	// DataFormat is the name of the parser
+	DataFormat string
+
+	// MetricName is the name of the metrics of the formats without names:
+	// json, csv, xml, logfmt and grok
+	MetricName string
+	// TagKeys are the keys tagging the metrics with their value instead of
+	// being fields: top level keys of json, columns of csv and attributes or
+	// child elements of xml
+	TagKeys []string
+
+	// CSVColumnNames are the names of the columns, read from the first row
+	// if empty
+	CSVColumnNames []string
+	// CSVDelimiter separates the columns, "," if empty
+	CSVDelimiter string
+
+	// XMLPath is the path of the elements parsed as metrics, their names
+	// separated by "/", ie "/stats/server"
+	XMLPath string
+
+	// GrokPatterns are the patterns matched against the lines, the first
+	// one matching being used, ie "%{COMBINED_LOG_FORMAT}"
+	GrokPatterns []string
+	// GrokCustomPatterns are definitions of patterns used by the patterns,
+	// "NAME regexp" per line
+	GrokCustomPatterns string
+	// GrokCustomPatternFiles are files of definitions of patterns
+	GrokCustomPatternFiles []string
+	// GrokTimezone is the location of the timestamps without a timezone,
+	// UTC if empty, or "Local"
+	GrokTimezone string
+}
+
+type Creator func(c *Config) (telegraf.Parser, error)
+
+var Parsers = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Parsers[name] = creator
+}
+
+// NewParser returns the parser of the data format of the config, influx if
+// not set.
+func NewParser(c *Config) (telegraf.Parser, error) {
+	dataFormat := c.DataFormat
+	if dataFormat == "" {
+		dataFormat = "influx"
+	}
+	creator, ok := Parsers[dataFormat]
+	if !ok {
+		return nil, fmt.Errorf("unsupported data_format %q", dataFormat)
+	}
+	return creator(c)
+}
+
+// ParseValue returns the field value of a string read by a text format: an
+// integer, float or boolean if it parses as one, the string otherwise.
+func ParseValue(s string) interface{} {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return s
+}
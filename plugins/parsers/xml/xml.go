@@ -0,0 +1,144 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// XML parses the elements at a path of an XML document as metrics. The
+// attributes and child elements holding text of an element are its fields,
+// or its tags for the tag keys.
+type XML struct {
+	MetricName string
+	TagKeys    []string
+	// Path are the names of the elements from the root to the metrics, the
+	// root element if empty
+	Path []string
+}
+
+// element is an element of the document
+type element struct {
+	name     string
+	attrs    []xml.Attr
+	children []*element
+	text     string
+}
+
+func (p *XML) Parse(buf []byte) ([]telegraf.Metric, error) {
+	root, err := parseDocument(buf)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, nil
+	}
+
+	// Select the elements of the path, starting from the root element
+	elements := []*element{root}
+	if len(p.Path) > 0 && root.name != p.Path[0] {
+		elements = nil
+	}
+	for i := 1; i < len(p.Path); i++ {
+		var next []*element
+		for _, e := range elements {
+			for _, child := range e.children {
+				if child.name == p.Path[i] {
+					next = append(next, child)
+				}
+			}
+		}
+		elements = next
+	}
+
+	isTag := make(map[string]bool, len(p.TagKeys))
+	for _, key := range p.TagKeys {
+		isTag[key] = true
+	}
+	now := time.Now()
+	var metrics []telegraf.Metric
+	for _, e := range elements {
+		tags := make(map[string]string)
+		fields := make(map[string]interface{})
+		add := func(name, value string) {
+			value = strings.TrimSpace(value)
+			if isTag[name] {
+				tags[name] = value
+			} else if value != "" {
+				fields[name] = parsers.ParseValue(value)
+			}
+		}
+		for _, attr := range e.attrs {
+			add(attr.Name.Local, attr.Value)
+		}
+		for _, child := range e.children {
+			if len(child.children) == 0 {
+				add(child.name, child.text)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		m, err := telegraf.NewMetric(p.MetricName, tags, fields, now)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// parseDocument returns the root element of the document, nil if empty
+func parseDocument(buf []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(buf))
+	var root *element
+	var stack []*element
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to parse XML: %s", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	return root, nil
+}
+
+func init() {
+	parsers.Add("xml", func(c *parsers.Config) (telegraf.Parser, error) {
+		var path []string
+		for _, name := range strings.Split(c.XMLPath, "/") {
+			if name != "" {
+				path = append(path, name)
+			}
+		}
+		return &XML{
+			MetricName: c.MetricName,
+			TagKeys:    c.TagKeys,
+			Path:       path,
+		}, nil
+	})
+}
@@ -0,0 +1,54 @@
+package xml
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const stats = `<?xml version="1.0"?>
+<stats>
+  <server name="a" role="master">
+    <load>1.5</load>
+    <connections>10</connections>
+    <disks><disk>sda</disk></disks>
+  </server>
+  <server name="b" role="slave">
+    <load>2</load>
+  </server>
+  <version>1.0</version>
+</stats>`
+
+func TestParsePath(t *testing.T) {
+	p, err := parsers.NewParser(&parsers.Config{
+		DataFormat: "xml",
+		MetricName: "http",
+		TagKeys:    []string{"name"},
+		XMLPath:    "/stats/server",
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse([]byte(stats))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, map[string]string{"name": "a"}, metrics[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"role":        "master",
+		"load":        1.5,
+		"connections": int64(10),
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{"name": "b"}, metrics[1].Tags())
+
+	_, err = p.Parse([]byte("<stats><server>"))
+	assert.Error(t, err)
+}
+
+func TestParseRoot(t *testing.T) {
+	p := &XML{MetricName: "http"}
+	metrics, err := p.Parse([]byte(stats))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	assert.Equal(t, map[string]interface{}{"version": 1.0},
+		metrics[0].Fields())
+}
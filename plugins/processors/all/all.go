@@ -0,0 +1,9 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/processors/execd"
+	_ "github.com/influxdata/telegraf/plugins/processors/ifname"
+	_ "github.com/influxdata/telegraf/plugins/processors/override"
+	_ "github.com/influxdata/telegraf/plugins/processors/reverse_dns"
+	_ "github.com/influxdata/telegraf/plugins/processors/tag_limit"
+)
@@ -0,0 +1,152 @@
+package execd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const sampleConfig = `
+  # Program to run as daemon, the first element is the executable and the
+  # remaining elements are its arguments.
+  # The program receives metrics on stdin in the data format, one metric per
+  # line, and must write the transformed metrics to stdout in the same
+  # format. Anything written to stderr is logged.
+  command = ["/usr/bin/myprocessor", "--foo=bar"]
+
+  # Data format of the metrics written to and read from the program, which
+  # must be both a serializer and a parser format
+  data_format = "influx"
+
+  # Delay before the program is restarted after an unexpected exit
+  restart_delay = "10s"
+`
+
+type Execd struct {
+	Command      []string
+	DataFormat   string `toml:"data_format"`
+	RestartDelay internal.Duration
+	Log          telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	process    *process.Process
+	serializer telegraf.Serializer
+	parser     telegraf.Parser
+	// metrics are the metrics read from the program's stdout, returned by
+	// the next call to Apply
+	metrics []telegraf.Metric
+}
+
+func NewExecd() *Execd {
+	return &Execd{
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run metrics through an external program over stdin/stdout"
+}
+
+// Start launches the external program and begins reading the metrics it
+// writes to stdout on a goroutine of its own. The program is restarted if it
+// exits before Stop is called.
+func (e *Execd) Start() error {
+	serializer, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: e.DataFormat,
+	})
+	if err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	e.serializer = serializer
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat: e.DataFormat,
+		MetricName: "execd",
+	})
+	if err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	e.parser = parser
+
+	p, err := process.New(e.Command, e.Log)
+	if err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	p.RestartDelay = e.RestartDelay.Duration
+	p.ReadStdout = e.readMetrics
+	e.process = p
+
+	if err := p.Start(); err != nil {
+		return fmt.Errorf("execd: %s", err)
+	}
+	return nil
+}
+
+// Stop closes the program's stdin and waits for it to exit, killing it if it
+// does not exit in a timely manner. The output of the program is read until it
+// exits, the metrics it wrote since the last call to Apply being returned by
+// the next one.
+func (e *Execd) Stop() {
+	if e.process != nil {
+		e.process.Stop()
+	}
+}
+
+// Apply writes the given metrics to the program's stdin and returns any
+// metrics that the program has written to stdout since the last call.
+func (e *Execd) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		b, err := e.serializer.Serialize(metric)
+		if err != nil {
+			e.Log.Errorf("Unable to serialize metric: %s", err)
+			continue
+		}
+		if _, err := e.process.Write(b); err != nil {
+			e.Log.Errorf("Unable to write metric to %s: %s",
+				e.Command[0], err)
+			break
+		}
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	out := e.metrics
+	e.metrics = nil
+	return out
+}
+
+func (e *Execd) readMetrics(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		metrics, err := e.parser.Parse(scanner.Bytes())
+		if err != nil {
+			e.Log.Errorf("Unable to parse output of %s: %s",
+				e.Command[0], err)
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+
+		e.Lock()
+		e.metrics = append(e.metrics, metrics...)
+		e.Unlock()
+	}
+}
+
+func init() {
+	processors.Add("execd", func() telegraf.Processor {
+		return NewExecd()
+	})
+}
@@ -0,0 +1,104 @@
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/influxdata/telegraf/plugins/parsers/all"
+	_ "github.com/influxdata/telegraf/plugins/serializers/all"
+)
+
+func newMetric(t *testing.T, value int64) telegraf.Metric {
+	m, err := telegraf.NewMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"usage": value},
+		time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	return m
+}
+
+// waitForMetrics calls Apply until at least n metrics have been returned.
+func waitForMetrics(e *Execd, n int) []telegraf.Metric {
+	var out []telegraf.Metric
+	deadline := time.Now().Add(5 * time.Second)
+	for len(out) < n && time.Now().Before(deadline) {
+		out = append(out, e.Apply()...)
+		time.Sleep(10 * time.Millisecond)
+	}
+	return out
+}
+
+func TestExecdApply(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"cat"}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	out := e.Apply(newMetric(t, 42), newMetric(t, 43))
+	out = append(out, waitForMetrics(e, 2-len(out))...)
+
+	require.Len(t, out, 2)
+	assert.Equal(t, "cpu", out[0].Name())
+	assert.Equal(t, map[string]string{"host": "localhost"}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"usage": int64(42)}, out[0].Fields())
+	assert.Equal(t, time.Unix(1453831884, 0).UnixNano(), out[0].UnixNano())
+	assert.Equal(t, map[string]interface{}{"usage": int64(43)}, out[1].Fields())
+}
+
+func TestExecdRestart(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	// Echo a single line back, then exit
+	e.Command = []string{"sh", "-c", "read line; echo \"$line\""}
+	e.RestartDelay = internal.Duration{Duration: 10 * time.Millisecond}
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	e.Apply(newMetric(t, 1))
+	require.Len(t, waitForMetrics(e, 1), 1)
+
+	// Wait for the program to be restarted before writing to it again
+	time.Sleep(100 * time.Millisecond)
+	e.Apply(newMetric(t, 2))
+	out := waitForMetrics(e, 1)
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{"usage": int64(2)}, out[0].Fields())
+}
+
+func TestExecdStop(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	// sort only writes its output once its stdin is closed
+	e.Command = []string{"sort"}
+	require.NoError(t, e.Start())
+
+	assert.Empty(t, e.Apply(newMetric(t, 1), newMetric(t, 2)))
+	e.Stop()
+
+	// The output read until the program exited is returned once stopped
+	out := e.Apply()
+	require.Len(t, out, 2)
+	assert.Equal(t, map[string]interface{}{"usage": int64(1)}, out[0].Fields())
+	assert.Equal(t, map[string]interface{}{"usage": int64(2)}, out[1].Fields())
+}
+
+func TestExecdInvalidDataFormat(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"cat"}
+	e.DataFormat = "xml"
+	assert.Error(t, e.Start())
+}
+
+func TestExecdNoCommand(t *testing.T) {
+	e := NewExecd()
+	e.Log = testutil.Logger{}
+	assert.Error(t, e.Start())
+}
@@ -0,0 +1,300 @@
+package ifname
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soniah/gosnmp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  # Name of tag holding the interface number
+  tag = "ifIndex"
+
+  # Name of output tag where service name will be added
+  dest = "ifName"
+
+  # Name of tag of the SNMP agent to request the interface name from
+  agent = "agent"
+
+  # Timeout for each request.
+  timeout = "5s"
+
+  # SNMP version, values can be 1 or 2. Default is 2.
+  version = 2
+
+  # SNMP community string.
+  community = "public"
+
+  # Number of retries to attempt within timeout.
+  retries = 3
+
+  # How long the interface names of an agent are cached before they are
+  # requested again. Failed requests are retried after a minute, then after
+  # twice as long on each failure, up to cache_ttl.
+  cache_ttl = "8h"
+`
+
+const (
+	// ifName from IF-MIB::ifXTable
+	ifNameOid = ".1.3.6.1.2.1.31.1.1.1.1"
+	// ifDescr from IF-MIB::ifTable, used when the agent has no ifXTable
+	ifDescrOid = ".1.3.6.1.2.1.2.2.1.2"
+
+	// retryDelay is the delay before the names of an agent are requested
+	// again after a failure, doubled on each consecutive failure
+	retryDelay = time.Minute
+)
+
+// fetched is called once the result of a request for the names of an agent
+// is cached, it is overridden in tests
+var fetched = func(agent string) {}
+
+type nameTable struct {
+	names   map[uint64]string
+	expires time.Time
+	// failures is the number of consecutive failed requests
+	failures uint
+}
+
+type IfName struct {
+	Tag       string
+	Dest      string
+	Agent     string
+	Timeout   internal.Duration
+	Version   uint8
+	Community string
+	Retries   int
+	CacheTTL  internal.Duration
+	Log       telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	cache map[string]nameTable
+	// pending are the agents whose interface names are being requested
+	pending map[string]bool
+
+	// getTable requests the interface names of an agent, it is overridden
+	// in tests
+	getTable func(agent string) (map[uint64]string, error)
+}
+
+func NewIfName() *IfName {
+	return &IfName{
+		Tag:       "ifIndex",
+		Dest:      "ifName",
+		Agent:     "agent",
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+		Version:   2,
+		Community: "public",
+		Retries:   3,
+		CacheTTL:  internal.Duration{Duration: 8 * time.Hour},
+	}
+}
+
+func (d *IfName) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *IfName) Description() string {
+	return "Add a tag of the network interface name looked up over SNMP by interface number"
+}
+
+func (d *IfName) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		name, ok := d.lookup(metric)
+		if !ok {
+			out = append(out, metric)
+			continue
+		}
+
+		tags := make(map[string]string)
+		for k, v := range metric.Tags() {
+			tags[k] = v
+		}
+		tags[d.Dest] = name
+
+		m, err := telegraf.NewTypedMetric(metric.Type(), metric.Name(), tags,
+			metric.Fields(), metric.Time())
+		if err != nil {
+			d.Log.Errorf("Unable to modify metric %s: %s",
+				metric.Name(), err)
+			out = append(out, metric)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// lookup returns the interface name for the metric's agent and ifIndex
+func (d *IfName) lookup(metric telegraf.Metric) (string, bool) {
+	tags := metric.Tags()
+	agent, ok := tags[d.Agent]
+	if !ok {
+		return "", false
+	}
+	index, err := strconv.ParseUint(tags[d.Tag], 10, 64)
+	if err != nil {
+		return "", false
+	}
+
+	names := d.table(agent)
+	name, ok := names[index]
+	return name, ok
+}
+
+// table returns the cached interface names of the agent. They are requested
+// over SNMP in the background if they aren't cached or expired, the metrics
+// being passed through without name, or with the expired names, until the
+// request returns.
+func (d *IfName) table(agent string) map[uint64]string {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.cache == nil {
+		d.cache = make(map[string]nameTable)
+		d.pending = make(map[string]bool)
+	}
+	if d.getTable == nil {
+		d.getTable = d.getTableSNMP
+	}
+
+	t, ok := d.cache[agent]
+	if ok && time.Now().Before(t.expires) {
+		return t.names
+	}
+	if !d.pending[agent] {
+		d.pending[agent] = true
+		go d.fetch(agent)
+	}
+	return t.names
+}
+
+// fetch requests the interface names of the agent and caches them. On
+// failure the names cached before are kept, and requested again after a
+// delay growing with the consecutive failures, up to the cache TTL.
+func (d *IfName) fetch(agent string) {
+	defer fetched(agent)
+
+	names, err := d.getTable(agent)
+
+	d.Lock()
+	defer d.Unlock()
+	delete(d.pending, agent)
+	if err == nil {
+		d.cache[agent] = nameTable{
+			names:   names,
+			expires: time.Now().Add(d.CacheTTL.Duration),
+		}
+		return
+	}
+
+	t := d.cache[agent]
+	delay := retryDelay
+	for i := uint(0); i < t.failures && delay < d.CacheTTL.Duration; i++ {
+		delay *= 2
+	}
+	if delay > d.CacheTTL.Duration {
+		delay = d.CacheTTL.Duration
+	}
+	t.failures++
+	t.expires = time.Now().Add(delay)
+	d.cache[agent] = t
+	d.Log.Errorf("Unable to get interface names from %s, retrying in %s: %s",
+		agent, delay, err)
+}
+
+func (d *IfName) getTableSNMP(agent string) (map[uint64]string, error) {
+	client, err := d.snmpClient(agent)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Conn.Close()
+
+	names, err := walkNames(client, ifNameOid)
+	if err == nil && len(names) == 0 {
+		names, err = walkNames(client, ifDescrOid)
+	}
+	return names, err
+}
+
+func (d *IfName) snmpClient(agent string) (*gosnmp.GoSNMP, error) {
+	version := gosnmp.Version2c
+	if d.Version == 1 {
+		version = gosnmp.Version1
+	}
+
+	host, portStr, err := net.SplitHostPort(agent)
+	if err != nil {
+		host = agent
+		portStr = "161"
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in agent %s", agent)
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    host,
+		Port:      uint16(port),
+		Community: d.Community,
+		Version:   version,
+		Timeout:   d.Timeout.Duration,
+		Retries:   d.Retries,
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func walkNames(client *gosnmp.GoSNMP, oid string) (map[uint64]string, error) {
+	var pdus []gosnmp.SnmpPDU
+	var err error
+	if client.Version == gosnmp.Version1 {
+		pdus, err = client.WalkAll(oid)
+	} else {
+		pdus, err = client.BulkWalkAll(oid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buildTable(oid, pdus), nil
+}
+
+// buildTable converts the PDUs of a table column into a map of row index
+// to value.
+func buildTable(oid string, pdus []gosnmp.SnmpPDU) map[uint64]string {
+	names := make(map[uint64]string)
+	for _, pdu := range pdus {
+		if !strings.HasPrefix(pdu.Name, oid+".") {
+			continue
+		}
+		index, err := strconv.ParseUint(pdu.Name[len(oid)+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch v := pdu.Value.(type) {
+		case []byte:
+			names[index] = string(v)
+		case string:
+			names[index] = v
+		}
+	}
+	return names
+}
+
+func init() {
+	processors.Add("ifname", func() telegraf.Processor {
+		return NewIfName()
+	})
+}
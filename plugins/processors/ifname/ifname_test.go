@@ -0,0 +1,143 @@
+package ifname
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soniah/gosnmp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, tags map[string]string) telegraf.Metric {
+	m, err := telegraf.NewMetric("interface", tags,
+		map[string]interface{}{"in_octets": int64(100)},
+		time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	return m
+}
+
+// waitFetched makes the requests of the names signal on the returned channel
+// once cached, until the returned function is called
+func waitFetched() (chan string, func()) {
+	fetchedC := make(chan string, 10)
+	fetched = func(agent string) { fetchedC <- agent }
+	return fetchedC, func() { fetched = func(agent string) {} }
+}
+
+func TestIfName(t *testing.T) {
+	fetchedC, restore := waitFetched()
+	defer restore()
+
+	calls := 0
+	d := NewIfName()
+	d.Log = testutil.Logger{}
+	d.getTable = func(agent string) (map[uint64]string, error) {
+		// Only called from one goroutine at a time by the test
+		calls++
+		switch agent {
+		case "10.0.0.1":
+			return map[uint64]string{1: "lo", 2: "eth0"}, nil
+		default:
+			return nil, errors.New("timeout")
+		}
+	}
+
+	// The metrics are passed through untagged while the names are requested
+	out := d.Apply(
+		newMetric(t, map[string]string{"agent": "10.0.0.1", "ifIndex": "2"}))
+	require.Len(t, out, 1)
+	assert.NotContains(t, out[0].Tags(), "ifName")
+	assert.Equal(t, "10.0.0.1", <-fetchedC)
+
+	for i := 0; i < 2; i++ {
+		out := d.Apply(
+			newMetric(t, map[string]string{"agent": "10.0.0.1", "ifIndex": "2"}),
+			newMetric(t, map[string]string{"agent": "10.0.0.1", "ifIndex": "3"}),
+			newMetric(t, map[string]string{"agent": "10.0.0.2", "ifIndex": "1"}),
+			newMetric(t, map[string]string{"ifIndex": "1"}),
+		)
+		require.Len(t, out, 4)
+		assert.Equal(t, map[string]string{
+			"agent":   "10.0.0.1",
+			"ifIndex": "2",
+			"ifName":  "eth0",
+		}, out[0].Tags())
+		assert.NotContains(t, out[1].Tags(), "ifName")
+		assert.NotContains(t, out[2].Tags(), "ifName")
+		assert.NotContains(t, out[3].Tags(), "ifName")
+		if i == 0 {
+			assert.Equal(t, "10.0.0.2", <-fetchedC)
+		}
+	}
+
+	// Both agents, including the failed one, are cached
+	assert.Equal(t, 2, calls)
+}
+
+func TestIfNameRetry(t *testing.T) {
+	fetchedC, restore := waitFetched()
+	defer restore()
+
+	var err error
+	d := NewIfName()
+	d.Log = testutil.Logger{}
+	d.getTable = func(agent string) (map[uint64]string, error) {
+		if err != nil {
+			return nil, err
+		}
+		return map[uint64]string{2: "eth0"}, nil
+	}
+	// expire makes the names of the agent requested on the next metric,
+	// returning the delay they were cached for
+	expire := func() time.Duration {
+		d.Lock()
+		defer d.Unlock()
+		table := d.cache["10.0.0.1"]
+		delay := table.expires.Sub(time.Now())
+		table.expires = time.Now()
+		d.cache["10.0.0.1"] = table
+		return delay
+	}
+	apply := func() telegraf.Metric {
+		out := d.Apply(
+			newMetric(t, map[string]string{"agent": "10.0.0.1", "ifIndex": "2"}))
+		require.Len(t, out, 1)
+		return out[0]
+	}
+
+	apply()
+	<-fetchedC
+	assert.Equal(t, "eth0", apply().Tags()["ifName"])
+	assert.InDelta(t, 8*time.Hour, expire(), float64(time.Second))
+
+	// The names cached before are kept while the requests fail, the delay
+	// before the next request doubling on each failure
+	err = errors.New("timeout")
+	for _, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		assert.Equal(t, "eth0", apply().Tags()["ifName"])
+		<-fetchedC
+		assert.InDelta(t, delay, expire(), float64(time.Second))
+	}
+
+	err = nil
+	apply()
+	<-fetchedC
+	assert.InDelta(t, 8*time.Hour, expire(), float64(time.Second))
+}
+
+func TestBuildTable(t *testing.T) {
+	pdus := []gosnmp.SnmpPDU{
+		{Name: ifNameOid + ".1", Type: gosnmp.OctetString, Value: []byte("lo")},
+		{Name: ifNameOid + ".2", Type: gosnmp.OctetString, Value: []byte("eth0")},
+		{Name: ifNameOid + ".x", Type: gosnmp.OctetString, Value: []byte("bad")},
+		{Name: ifDescrOid + ".3", Type: gosnmp.OctetString, Value: []byte("other")},
+	}
+
+	assert.Equal(t, map[uint64]string{1: "lo", 2: "eth0"},
+		buildTable(ifNameOid, pdus))
+}
@@ -0,0 +1,85 @@
+package override
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  # All modifications on inputs and outputs can be overridden here:
+  # name_override = "new_name"
+  # name_prefix = "new_name_prefix"
+  # name_suffix = "new_name_suffix"
+
+  # Tags to be added or overwritten (all values must be strings)
+  # [processors.override.tags]
+  #   additional_tag = "tag_value"
+
+  # Default values for fields that are missing or are empty strings
+  # [processors.override.defaults]
+  #   status = "unknown"
+  #   retries = 0
+`
+
+type Override struct {
+	NameOverride string
+	NamePrefix   string
+	NameSuffix   string
+	Tags         map[string]string
+	Defaults     map[string]interface{}
+	Log          telegraf.Logger `toml:"-"`
+}
+
+func (o *Override) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *Override) Description() string {
+	return "Apply metric modifications using override semantics."
+}
+
+func (o *Override) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		name := metric.Name()
+		if len(o.NameOverride) > 0 {
+			name = o.NameOverride
+		}
+		name = o.NamePrefix + name + o.NameSuffix
+
+		tags := make(map[string]string)
+		for k, v := range metric.Tags() {
+			tags[k] = v
+		}
+		for k, v := range o.Tags {
+			tags[k] = v
+		}
+
+		fields := make(map[string]interface{})
+		for k, v := range metric.Fields() {
+			fields[k] = v
+		}
+		for k, v := range o.Defaults {
+			if current, ok := fields[k]; !ok || current == "" {
+				fields[k] = v
+			}
+		}
+
+		m, err := telegraf.NewTypedMetric(metric.Type(), name, tags, fields,
+			metric.Time())
+		if err != nil {
+			o.Log.Errorf("Unable to modify metric %s: %s",
+				metric.Name(), err)
+			out = append(out, metric)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("override", func() telegraf.Processor {
+		return &Override{}
+	})
+}
@@ -0,0 +1,78 @@
+package override
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(
+	t *testing.T,
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+) telegraf.Metric {
+	m, err := telegraf.NewMetric(name, tags, fields, time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestOverrideName(t *testing.T) {
+	o := &Override{
+		Log:          testutil.Logger{},
+		NameOverride: "bar",
+		NamePrefix:   "pre_",
+		NameSuffix:   "_suf",
+	}
+
+	out := o.Apply(newMetric(t, "foo", nil,
+		map[string]interface{}{"value": int64(1)}))
+	require.Len(t, out, 1)
+	assert.Equal(t, "pre_bar_suf", out[0].Name())
+}
+
+func TestOverrideTags(t *testing.T) {
+	o := &Override{
+		Log: testutil.Logger{},
+		Tags: map[string]string{
+			"existing": "new",
+			"added":    "value",
+		},
+	}
+
+	out := o.Apply(newMetric(t, "foo",
+		map[string]string{"existing": "old", "other": "kept"},
+		map[string]interface{}{"value": int64(1)}))
+	require.Len(t, out, 1)
+	assert.Equal(t, "foo", out[0].Name())
+	assert.Equal(t, map[string]string{
+		"existing": "new",
+		"added":    "value",
+		"other":    "kept",
+	}, out[0].Tags())
+	assert.Equal(t, time.Unix(1453831884, 0).UnixNano(), out[0].UnixNano())
+}
+
+func TestOverrideDefaults(t *testing.T) {
+	o := &Override{
+		Log: testutil.Logger{},
+		Defaults: map[string]interface{}{
+			"status":  "unknown",
+			"retries": int64(0),
+			"value":   int64(42),
+		},
+	}
+
+	out := o.Apply(newMetric(t, "foo", nil,
+		map[string]interface{}{"value": int64(1), "status": ""}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]interface{}{
+		"status":  "unknown",
+		"retries": int64(0),
+		"value":   int64(1),
+	}, out[0].Fields())
+}
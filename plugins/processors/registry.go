@@ -0,0 +1,20 @@
+package processors
+
+import "github.com/influxdata/telegraf"
+
+type Creator func() telegraf.Processor
+
+var Processors = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Processors[name] = creator
+}
+
+// Deprecations holds the deprecated processors, warned about when configured
+var Deprecations = map[string]telegraf.DeprecationInfo{}
+
+// AddDeprecated registers a deprecated processor
+func AddDeprecated(name string, creator Creator, info telegraf.DeprecationInfo) {
+	Add(name, creator)
+	Deprecations[name] = info
+}
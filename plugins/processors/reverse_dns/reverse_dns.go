@@ -0,0 +1,337 @@
+package reverse_dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  # For optimal performance, you may want to limit which metrics are passed to
+  # this processor, eg:
+  # pass = ["netflow*"]
+
+  # How long should resolved names be cached for. Failed lookups are cached
+  # for the same amount of time.
+  cache_ttl = "24h"
+
+  # How long should we wait for a single lookup before giving up
+  lookup_timeout = "3s"
+
+  # The maximum number of lookups that may be in flight at any one time
+  max_parallel_lookups = 10
+
+  [[processors.reverse_dns.lookup]]
+    # Get the IP from the field "source_ip", and put the result in the field
+    # "source_name"
+    field = "source_ip"
+    dest = "source_name"
+
+  [[processors.reverse_dns.lookup]]
+    # Get the IP from the tag "destination_ip", and put the result in the tag
+    # "destination_name".
+    tag = "destination_ip"
+    dest = "destination_name"
+`
+
+var ErrTimeout = errors.New("lookup timed out")
+
+type LookupEntry struct {
+	Tag   string
+	Field string
+	Dest  string
+}
+
+type cacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+type ReverseDNS struct {
+	CacheTTL           internal.Duration
+	LookupTimeout      internal.Duration
+	MaxParallelLookups int
+	Lookup             []LookupEntry
+	Log                telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+	cache       map[string]cacheEntry
+	lastCleanup time.Time
+	sem         chan struct{}
+	// queue are the metrics being resolved, in the order they were applied
+	queue []*pendingMetric
+
+	// lookupAddr resolves an address into names, it is overridden in tests
+	lookupAddr func(addr string) ([]string, error)
+}
+
+func NewReverseDNS() *ReverseDNS {
+	return &ReverseDNS{
+		CacheTTL:           internal.Duration{Duration: 24 * time.Hour},
+		LookupTimeout:      internal.Duration{Duration: 3 * time.Second},
+		MaxParallelLookups: 10,
+		lookupAddr:         net.LookupAddr,
+	}
+}
+
+func (r *ReverseDNS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *ReverseDNS) Description() string {
+	return "Resolve IP addresses in tags or fields into hostnames using reverse DNS"
+}
+
+// resolvedValue is the result of looking up the value of a single
+// LookupEntry in a metric
+type resolvedValue struct {
+	entry LookupEntry
+	addr  string
+	name  string
+	ok    bool
+}
+
+// pendingMetric is a metric whose addresses are being resolved, done being
+// closed once all the lookups returned
+type pendingMetric struct {
+	metric  telegraf.Metric
+	results []resolvedValue
+	done    chan struct{}
+}
+
+func (r *ReverseDNS) Start() error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.cache = make(map[string]cacheEntry)
+	r.lastCleanup = time.Now()
+	n := r.MaxParallelLookups
+	if n < 1 {
+		n = 1
+	}
+	r.sem = make(chan struct{}, n)
+	if r.lookupAddr == nil {
+		r.lookupAddr = net.LookupAddr
+	}
+	return nil
+}
+
+// Stop waits for the pending lookups, the metrics being returned by the next
+// call to Apply.
+func (r *ReverseDNS) Stop() {
+	r.Lock()
+	queue := r.queue
+	r.Unlock()
+
+	for _, pending := range queue {
+		<-pending.done
+	}
+}
+
+// Apply starts resolving the addresses of the metrics in the background, and
+// returns the metrics resolved so far, in the order they were applied. A
+// metric whose lookups are still running holds back the ones applied after
+// it.
+func (r *ReverseDNS) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	r.cleanup()
+
+	r.Lock()
+	defer r.Unlock()
+
+	for _, metric := range in {
+		r.queue = append(r.queue, r.resolveMetric(metric))
+	}
+
+	var out []telegraf.Metric
+	for len(r.queue) > 0 {
+		pending := r.queue[0]
+		select {
+		case <-pending.done:
+		default:
+			return out
+		}
+		out = append(out, r.modify(pending.metric, pending.results))
+		r.queue[0] = nil
+		r.queue = r.queue[1:]
+	}
+	return out
+}
+
+// modify returns the metric with the resolved names added
+func (r *ReverseDNS) modify(
+	metric telegraf.Metric,
+	results []resolvedValue,
+) telegraf.Metric {
+	if !anyResolved(results) {
+		return metric
+	}
+
+	tags := make(map[string]string)
+	for k, v := range metric.Tags() {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	for k, v := range metric.Fields() {
+		fields[k] = v
+	}
+	for _, result := range results {
+		if !result.ok {
+			continue
+		}
+		if result.entry.Tag != "" {
+			tags[result.entry.Dest] = result.name
+		} else {
+			fields[result.entry.Dest] = result.name
+		}
+	}
+
+	m, err := telegraf.NewTypedMetric(metric.Type(), metric.Name(), tags,
+		fields, metric.Time())
+	if err != nil {
+		r.Log.Errorf("Unable to modify metric %s: %s", metric.Name(), err)
+		return metric
+	}
+	return m
+}
+
+func anyResolved(results []resolvedValue) bool {
+	for _, result := range results {
+		if result.ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanup periodically removes the expired entries, so the cache doesn't
+// grow forever
+func (r *ReverseDNS) cleanup() {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.lastCleanup) > r.CacheTTL.Duration {
+		for ip, entry := range r.cache {
+			if now.After(entry.expires) {
+				delete(r.cache, ip)
+			}
+		}
+		r.lastCleanup = now
+	}
+}
+
+// resolveMetric starts looking up all the configured entries of the metric
+// in parallel, the results being in the order the entries are configured.
+// The lock must be held.
+func (r *ReverseDNS) resolveMetric(metric telegraf.Metric) *pendingMetric {
+	pending := &pendingMetric{metric: metric, done: make(chan struct{})}
+	for _, entry := range r.Lookup {
+		var addr string
+		if entry.Tag != "" {
+			addr = metric.Tags()[entry.Tag]
+		} else if v, ok := metric.Fields()[entry.Field].(string); ok {
+			addr = v
+		}
+		if net.ParseIP(addr) == nil {
+			continue
+		}
+		pending.results = append(pending.results,
+			resolvedValue{entry: entry, addr: addr})
+	}
+
+	// The cached names are resolved right away, the metric being done if
+	// all of them are
+	var wg sync.WaitGroup
+	lookups := 0
+	for i := range pending.results {
+		result := &pending.results[i]
+		if entry, ok := r.cache[result.addr]; ok &&
+			time.Now().Before(entry.expires) {
+			result.name, result.ok = entry.name, entry.name != ""
+			continue
+		}
+		lookups++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result.name, result.ok = r.resolve(result.addr)
+		}()
+	}
+	if lookups == 0 {
+		close(pending.done)
+		return pending
+	}
+	go func() {
+		wg.Wait()
+		close(pending.done)
+	}()
+	return pending
+}
+
+// resolve looks up the hostname of the given IP address, caching it.
+func (r *ReverseDNS) resolve(addr string) (string, bool) {
+	name, err := r.lookup(addr)
+	if err == ErrTimeout {
+		// Timeouts are not cached, the server may only be slow right now
+		return "", false
+	}
+
+	// Failed lookups are cached as well, with an empty name, to avoid
+	// hammering the DNS server with addresses that will never resolve.
+	r.Lock()
+	r.cache[addr] = cacheEntry{
+		name:    name,
+		expires: time.Now().Add(r.CacheTTL.Duration),
+	}
+	r.Unlock()
+	return name, name != ""
+}
+
+// lookup resolves the address, giving up once the lookup timeout elapses,
+// the wait for one of the max_parallel_lookups slots included. The slot is
+// only released once the lookup returns, even if it timed out.
+func (r *ReverseDNS) lookup(addr string) (string, error) {
+	timeout := time.After(r.LookupTimeout.Duration)
+	select {
+	case r.sem <- struct{}{}:
+	case <-timeout:
+		return "", ErrTimeout
+	}
+
+	type result struct {
+		names []string
+		err   error
+	}
+	// Buffered, so the lookup goroutine can exit after a timeout
+	resultC := make(chan result, 1)
+	go func() {
+		defer func() { <-r.sem }()
+		names, err := r.lookupAddr(addr)
+		resultC <- result{names, err}
+	}()
+
+	select {
+	case res := <-resultC:
+		if res.err != nil {
+			return "", res.err
+		}
+		if len(res.names) == 0 {
+			return "", errors.New("no names found")
+		}
+		return strings.TrimSuffix(res.names[0], "."), nil
+	case <-timeout:
+		return "", ErrTimeout
+	}
+}
+
+func init() {
+	processors.Add("reverse_dns", func() telegraf.Processor {
+		return NewReverseDNS()
+	})
+}
@@ -0,0 +1,202 @@
+package reverse_dns
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	sync.Mutex
+	calls int
+	names map[string]string
+	delay time.Duration
+	// running and maxRunning count the lookups running in parallel
+	running    int
+	maxRunning int
+}
+
+func (f *fakeResolver) lookupAddr(addr string) ([]string, error) {
+	f.Lock()
+	f.calls++
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.Unlock()
+	time.Sleep(f.delay)
+	f.Lock()
+	f.running--
+	f.Unlock()
+	if name, ok := f.names[addr]; ok {
+		return []string{name + "."}, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func newMetric(
+	t *testing.T,
+	tags map[string]string,
+	fields map[string]interface{},
+) telegraf.Metric {
+	m, err := telegraf.NewMetric("flow", tags, fields, time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func newReverseDNS(t *testing.T, resolver *fakeResolver) *ReverseDNS {
+	r := NewReverseDNS()
+	r.Log = testutil.Logger{}
+	r.lookupAddr = resolver.lookupAddr
+	r.Lookup = []LookupEntry{
+		{Tag: "dst_ip", Dest: "dst_name"},
+		{Field: "src_ip", Dest: "src_name"},
+	}
+	require.NoError(t, r.Start())
+	return r
+}
+
+// apply returns the metrics once all their lookups returned
+func apply(r *ReverseDNS, in ...telegraf.Metric) []telegraf.Metric {
+	out := r.Apply(in...)
+	r.Stop()
+	return append(out, r.Apply()...)
+}
+
+func TestReverseDNSTagsAndFields(t *testing.T) {
+	resolver := &fakeResolver{names: map[string]string{
+		"127.0.0.1": "localhost",
+		"10.0.0.1":  "router.example.com",
+	}}
+	r := newReverseDNS(t, resolver)
+
+	out := apply(r, newMetric(t,
+		map[string]string{"dst_ip": "10.0.0.1"},
+		map[string]interface{}{"src_ip": "127.0.0.1", "bytes": int64(100)}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{
+		"dst_ip":   "10.0.0.1",
+		"dst_name": "router.example.com",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{
+		"src_ip":   "127.0.0.1",
+		"src_name": "localhost",
+		"bytes":    int64(100),
+	}, out[0].Fields())
+}
+
+func TestReverseDNSCache(t *testing.T) {
+	resolver := &fakeResolver{names: map[string]string{
+		"10.0.0.1": "router.example.com",
+	}}
+	r := newReverseDNS(t, resolver)
+
+	for i := 0; i < 3; i++ {
+		out := apply(r, newMetric(t,
+			map[string]string{"dst_ip": "10.0.0.1"},
+			map[string]interface{}{"src_ip": "10.0.0.2"}))
+		require.Len(t, out, 1)
+		assert.Equal(t, "router.example.com", out[0].Tags()["dst_name"])
+		assert.NotContains(t, out[0].Fields(), "src_name")
+	}
+	// One lookup each for the resolvable and unresolvable address
+	assert.Equal(t, 2, resolver.calls)
+
+	// Expire the cache
+	for addr, entry := range r.cache {
+		entry.expires = time.Now().Add(-time.Second)
+		r.cache[addr] = entry
+	}
+	apply(r, newMetric(t,
+		map[string]string{"dst_ip": "10.0.0.1"},
+		map[string]interface{}{"value": int64(1)}))
+	assert.Equal(t, 3, resolver.calls)
+}
+
+func TestReverseDNSTimeout(t *testing.T) {
+	resolver := &fakeResolver{
+		names: map[string]string{"10.0.0.1": "router.example.com"},
+		delay: 100 * time.Millisecond,
+	}
+	r := newReverseDNS(t, resolver)
+	r.LookupTimeout = internal.Duration{Duration: time.Millisecond}
+
+	m := newMetric(t,
+		map[string]string{"dst_ip": "10.0.0.1"},
+		map[string]interface{}{"value": int64(1)})
+	out := apply(r, m)
+	require.Len(t, out, 1)
+	assert.Equal(t, m, out[0])
+}
+
+func TestReverseDNSIgnoresNonIP(t *testing.T) {
+	resolver := &fakeResolver{}
+	r := newReverseDNS(t, resolver)
+
+	out := apply(r, newMetric(t,
+		map[string]string{"dst_ip": "not-an-ip"},
+		map[string]interface{}{"src_ip": int64(1)}))
+	require.Len(t, out, 1)
+	assert.Equal(t, 0, resolver.calls)
+}
+
+func TestReverseDNSOrder(t *testing.T) {
+	resolver := &fakeResolver{
+		names: map[string]string{"10.0.0.1": "router.example.com"},
+		delay: 50 * time.Millisecond,
+	}
+	r := newReverseDNS(t, resolver)
+
+	// The metric without address is held back by the one being resolved
+	assert.Empty(t, r.Apply(
+		newMetric(t, map[string]string{"dst_ip": "10.0.0.1"},
+			map[string]interface{}{"value": int64(1)}),
+		newMetric(t, nil, map[string]interface{}{"value": int64(2)})))
+	r.Stop()
+	out := r.Apply(newMetric(t, nil, map[string]interface{}{"value": int64(3)}))
+	require.Len(t, out, 3)
+	assert.Equal(t, "router.example.com", out[0].Tags()["dst_name"])
+	for i, m := range out {
+		assert.Equal(t, int64(i+1), m.Fields()["value"])
+	}
+}
+
+func TestReverseDNSParallelMetrics(t *testing.T) {
+	resolver := &fakeResolver{delay: 50 * time.Millisecond}
+	r := newReverseDNS(t, resolver)
+
+	// The lookups of all the metrics run in parallel
+	var in []telegraf.Metric
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		in = append(in, newMetric(t, map[string]string{"dst_ip": addr},
+			map[string]interface{}{"value": int64(1)}))
+	}
+	require.Len(t, apply(r, in...), 3)
+	assert.Equal(t, 3, resolver.maxRunning)
+}
+
+func TestReverseDNSMaxParallelLookups(t *testing.T) {
+	resolver := &fakeResolver{delay: 50 * time.Millisecond}
+	r := newReverseDNS(t, resolver)
+	r.LookupTimeout = internal.Duration{Duration: 10 * time.Millisecond}
+	r.MaxParallelLookups = 1
+	require.NoError(t, r.Start())
+
+	// The slot of a lookup which timed out is only released once it returns
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2"} {
+		require.Len(t, apply(r, newMetric(t,
+			map[string]string{"dst_ip": addr},
+			map[string]interface{}{"value": int64(1)})), 1)
+	}
+	time.Sleep(100 * time.Millisecond)
+	resolver.Lock()
+	defer resolver.Unlock()
+	assert.Equal(t, 1, resolver.maxRunning)
+}
@@ -0,0 +1,91 @@
+package tag_limit
+
+import (
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  # Maximum number of tags to preserve
+  limit = 10
+
+  # List of tags to preferentially preserve
+  keep = ["foo", "bar", "baz"]
+`
+
+type TagLimit struct {
+	Limit int
+	Keep  []string
+	Log   telegraf.Logger `toml:"-"`
+
+	keepTags map[string]bool
+}
+
+func (d *TagLimit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *TagLimit) Description() string {
+	return "Restricts the number of tags that can pass through this filter and chooses which tags to preserve when over the limit."
+}
+
+func (d *TagLimit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if d.keepTags == nil {
+		d.keepTags = make(map[string]bool)
+		for _, k := range d.Keep {
+			d.keepTags[k] = true
+		}
+		if len(d.keepTags) > d.Limit {
+			d.Log.Warnf("%d tags to keep exceeds the limit of %d",
+				len(d.keepTags), d.Limit)
+		}
+	}
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, metric := range in {
+		tags := metric.Tags()
+		if len(tags) <= d.Limit {
+			out = append(out, metric)
+			continue
+		}
+
+		// Sort the tag keys so the same tags are dropped from every metric
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		kept := make(map[string]string)
+		for k, v := range tags {
+			kept[k] = v
+		}
+		for _, k := range keys {
+			if len(kept) <= d.Limit {
+				break
+			}
+			if !d.keepTags[k] {
+				delete(kept, k)
+			}
+		}
+
+		m, err := telegraf.NewTypedMetric(metric.Type(), metric.Name(), kept,
+			metric.Fields(), metric.Time())
+		if err != nil {
+			d.Log.Errorf("Unable to modify metric %s: %s",
+				metric.Name(), err)
+			out = append(out, metric)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func init() {
+	processors.Add("tag_limit", func() telegraf.Processor {
+		return &TagLimit{}
+	})
+}
@@ -0,0 +1,66 @@
+package tag_limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(t *testing.T, tags map[string]string) telegraf.Metric {
+	m, err := telegraf.NewMetric("foo", tags,
+		map[string]interface{}{"value": int64(1)}, time.Unix(1453831884, 0))
+	require.NoError(t, err)
+	return m
+}
+
+func TestUnderLimit(t *testing.T) {
+	d := &TagLimit{Limit: 3, Log: testutil.Logger{}}
+	tags := map[string]string{"a": "1", "b": "2"}
+
+	out := d.Apply(newMetric(t, tags))
+	require.Len(t, out, 1)
+	assert.Equal(t, tags, out[0].Tags())
+}
+
+func TestTrim(t *testing.T) {
+	d := &TagLimit{
+		Log:   testutil.Logger{},
+		Limit: 3,
+		Keep:  []string{"e", "d"},
+	}
+
+	out := d.Apply(newMetric(t, map[string]string{
+		"a": "1",
+		"b": "2",
+		"c": "3",
+		"d": "4",
+		"e": "5",
+	}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{
+		"c": "3",
+		"d": "4",
+		"e": "5",
+	}, out[0].Tags())
+	assert.Equal(t, map[string]interface{}{"value": int64(1)}, out[0].Fields())
+}
+
+func TestKeepExceedsLimit(t *testing.T) {
+	d := &TagLimit{
+		Log:   testutil.Logger{},
+		Limit: 1,
+		Keep:  []string{"a", "b"},
+	}
+
+	out := d.Apply(newMetric(t, map[string]string{
+		"a": "1",
+		"b": "2",
+		"c": "3",
+	}))
+	require.Len(t, out, 1)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, out[0].Tags())
+}
@@ -0,0 +1,9 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/secretstores/aws_secrets_manager"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/env"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/file"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/os"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/vault"
+)
@@ -0,0 +1,117 @@
+package aws_secrets_manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/awsconfig"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  # Unique id of the store, secrets are referenced as @{<id>:<key>}
+  # The key is the name or ARN of the secret, optionally followed by
+  # "#<field>" to select a field of a secret holding a JSON object,
+  # ie, @{aws:telegraf/influxdb#password}
+  id = "aws"
+  # Amazon region of the secrets
+  region = "us-east-1"
+
+  # Credentials, in order of precedence:
+  # 1) access_key, secret_key and token
+  # 2) profile of the shared_credential_file
+  # 3) the credential chain: EC2 instance role, environment variables and
+  #    shared credentials file
+  # assuming the role_arn role if set
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  # Endpoint of the service, instead of the one of the region
+  # endpoint_url = ""
+`
+
+type AWSSecretsManager struct {
+	Region string `toml:"region"`
+
+	// Credentials, see awsconfig.Config
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	Token       string `toml:"token"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	svc *secretsManager
+}
+
+func (s *AWSSecretsManager) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *AWSSecretsManager) Description() string {
+	return "Read secrets from AWS Secrets Manager"
+}
+
+func (s *AWSSecretsManager) Get(key string) (string, error) {
+	id, field := key, ""
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		id, field = key[:i], key[i+1:]
+	}
+
+	if s.svc == nil {
+		config := &awsconfig.Config{
+			Region:      s.Region,
+			AccessKey:   s.AccessKey,
+			SecretKey:   s.SecretKey,
+			Token:       s.Token,
+			RoleARN:     s.RoleARN,
+			Profile:     s.Profile,
+			Filename:    s.Filename,
+			EndpointURL: s.EndpointURL,
+		}
+		session, err := config.NewSession()
+		if err != nil {
+			return "", err
+		}
+		s.svc = newSecretsManager(session)
+	}
+
+	output, err := s.svc.getSecretValue(id)
+	if err != nil {
+		return "", fmt.Errorf("reading secret %s from AWS Secrets Manager: %s",
+			id, err)
+	}
+	secret := string(output.SecretBinary)
+	if output.SecretString != nil {
+		secret = *output.SecretString
+	}
+	if field == "" {
+		return secret, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %s", id, err)
+	}
+	val, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", id, field)
+	}
+	if str, ok := val.(string); ok {
+		return str, nil
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+func init() {
+	secretstores.Add("aws_secrets_manager", func() telegraf.SecretStore {
+		return &AWSSecretsManager{}
+	})
+}
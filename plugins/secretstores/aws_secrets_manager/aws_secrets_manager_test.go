@@ -0,0 +1,70 @@
+package aws_secrets_manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.Contains(r.Header.Get("Authorization"), "AKID") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var input struct {
+			SecretId string
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+		switch input.SecretId {
+		case "telegraf/influxdb":
+			fmt.Fprintln(w, `{"Name":"telegraf/influxdb","SecretString":"{\"password\":\"hunter2\",\"port\":8086}"}`)
+		case "telegraf/token":
+			fmt.Fprintln(w, `{"Name":"telegraf/token","SecretBinary":"dG9rZW4="}`)
+		default:
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`)
+		}
+	}))
+	defer ts.Close()
+
+	s := &AWSSecretsManager{
+		Region:      "us-east-1",
+		AccessKey:   "AKID",
+		SecretKey:   "SECRET",
+		EndpointURL: ts.URL,
+	}
+
+	val, err := s.Get("telegraf/influxdb")
+	require.NoError(t, err)
+	assert.Equal(t, `{"password":"hunter2","port":8086}`, val)
+
+	val, err = s.Get("telegraf/influxdb#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", val)
+
+	val, err = s.Get("telegraf/influxdb#port")
+	require.NoError(t, err)
+	assert.Equal(t, "8086", val)
+
+	val, err = s.Get("telegraf/token")
+	require.NoError(t, err)
+	assert.Equal(t, "token", val)
+
+	_, err = s.Get("telegraf/influxdb#missing")
+	assert.Error(t, err)
+
+	_, err = s.Get("telegraf/token#field")
+	assert.Error(t, err)
+
+	_, err = s.Get("telegraf/missing")
+	assert.Error(t, err)
+}
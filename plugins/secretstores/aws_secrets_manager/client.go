@@ -0,0 +1,71 @@
+package aws_secrets_manager
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+	"github.com/aws/aws-sdk-go/private/signer/v4"
+)
+
+const serviceName = "secretsmanager"
+
+// secretsManager is a client of the GetSecretValue action of AWS Secrets
+// Manager, for which the vendored aws-sdk-go has no service. It is built as
+// the JSON services of the SDK are.
+type secretsManager struct {
+	*client.Client
+}
+
+func newSecretsManager(p client.ConfigProvider) *secretsManager {
+	c := p.ClientConfig(serviceName)
+	svc := &secretsManager{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   serviceName,
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2017-10-17",
+				JSONVersion:   "1.1",
+				TargetPrefix:  "secretsmanager",
+			},
+			c.Handlers,
+		),
+	}
+	svc.Handlers.Sign.PushBack(v4.Sign)
+	svc.Handlers.Build.PushBack(jsonrpc.Build)
+	svc.Handlers.Unmarshal.PushBack(jsonrpc.Unmarshal)
+	svc.Handlers.UnmarshalMeta.PushBack(jsonrpc.UnmarshalMeta)
+	svc.Handlers.UnmarshalError.PushBack(jsonrpc.UnmarshalError)
+	return svc
+}
+
+type getSecretValueInput struct {
+	_ struct{} `type:"structure"`
+
+	SecretId *string `type:"string" required:"true"`
+}
+
+type getSecretValueOutput struct {
+	_ struct{} `type:"structure"`
+
+	// SecretString is set for text secrets, SecretBinary for binary ones
+	SecretString *string `type:"string"`
+	SecretBinary []byte  `type:"blob"`
+}
+
+// getSecretValue returns the current version of the secret with the given
+// name or ARN
+func (s *secretsManager) getSecretValue(id string) (*getSecretValueOutput, error) {
+	op := &request.Operation{
+		Name:       "GetSecretValue",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	output := &getSecretValueOutput{}
+	req := s.NewRequest(op, &getSecretValueInput{SecretId: aws.String(id)},
+		output)
+	return output, req.Send()
+}
@@ -0,0 +1,44 @@
+package env
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  # Unique id of the store, secrets are referenced as @{<id>:<key>}
+  id = "env"
+  # Prefix prepended to the key to get the name of the environment variable,
+  # ie, with prefix "TELEGRAF_" @{env:PASSWORD} reads $TELEGRAF_PASSWORD
+  prefix = ""
+`
+
+type Env struct {
+	Prefix string
+}
+
+func (e *Env) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Env) Description() string {
+	return "Read secrets from environment variables"
+}
+
+func (e *Env) Get(key string) (string, error) {
+	name := e.Prefix + key
+	val, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return val, nil
+}
+
+func init() {
+	secretstores.Add("env", func() telegraf.SecretStore {
+		return &Env{}
+	})
+}
@@ -0,0 +1,22 @@
+package env
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	os.Setenv("TELEGRAF_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("TELEGRAF_TEST_PASSWORD")
+
+	e := &Env{Prefix: "TELEGRAF_TEST_"}
+	val, err := e.Get("PASSWORD")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", val)
+
+	_, err = e.Get("UNSET")
+	assert.Error(t, err)
+}
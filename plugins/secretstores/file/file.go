@@ -0,0 +1,53 @@
+package file
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  # Unique id of the store, secrets are referenced as @{<id>:<key>}
+  id = "file"
+  # Directory holding one file per secret, named after the key of the secret.
+  # Trailing newlines of the files are removed.
+  directory = "/run/secrets"
+`
+
+type File struct {
+	Directory string
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from the files of a directory"
+}
+
+func (f *File) Get(key string) (string, error) {
+	if f.Directory == "" {
+		return "", fmt.Errorf("no directory configured")
+	}
+	// Keys must name a file within the directory
+	if key == "" || key != filepath.Base(key) || key == ".." {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(f.Directory, key))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
@@ -0,0 +1,36 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600)
+	require.NoError(t, err)
+
+	f := &File{Directory: dir}
+	val, err := f.Get("password")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", val)
+
+	_, err = f.Get("missing")
+	assert.Error(t, err)
+}
+
+func TestGetInvalidKey(t *testing.T) {
+	f := &File{Directory: "/run/secrets"}
+	for _, key := range []string{"", "..", "../etc/passwd", "a/b"} {
+		_, err := f.Get(key)
+		assert.Error(t, err, key)
+	}
+}
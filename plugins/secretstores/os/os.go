@@ -0,0 +1,48 @@
+package os
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  # Unique id of the store, secrets are referenced as @{<id>:<key>}
+  id = "os"
+  # Service the secrets are stored under in the keyring, the key of a
+  # secret being its account, or its user name on Windows
+  service = "telegraf"
+`
+
+type OS struct {
+	Service string
+
+	// lookup returns the secret of the keyring stored for the service and
+	// key
+	lookup func(service, key string) (string, error)
+}
+
+func (o *OS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OS) Description() string {
+	return "Read secrets from the keyring of the operating system"
+}
+
+func (o *OS) Get(key string) (string, error) {
+	if o.Service == "" {
+		return "", fmt.Errorf("no service configured")
+	}
+	if key == "" {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return o.lookup(o.Service, key)
+}
+
+func init() {
+	secretstores.Add("os", func() telegraf.SecretStore {
+		return &OS{Service: "telegraf", lookup: lookupSecret}
+	})
+}
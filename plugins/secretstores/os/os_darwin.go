@@ -0,0 +1,10 @@
+// +build darwin
+
+package os
+
+// lookupCommand returns the command printing the secret of the macOS
+// keychain, a generic password of the service whose account is the key
+func lookupCommand(service, key string) []string {
+	return []string{"security", "find-generic-password", "-s", service,
+		"-a", key, "-w"}
+}
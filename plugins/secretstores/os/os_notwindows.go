@@ -0,0 +1,28 @@
+// +build !windows
+
+package os
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// lookupSecret runs the command of lookupCommand, the secret being its
+// output
+func lookupSecret(service, key string) (string, error) {
+	args := lookupCommand(service, key)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%s: %s", err, msg)
+		}
+		return "", fmt.Errorf("reading secret %s of service %s from the "+
+			"keyring: %s", key, service, err)
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
@@ -0,0 +1,35 @@
+package os
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	o := &OS{
+		Service: "telegraf",
+		lookup: func(service, key string) (string, error) {
+			if service == "telegraf" && key == "influxdb" {
+				return "hunter2", nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+
+	val, err := o.Get("influxdb")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", val)
+
+	_, err = o.Get("missing")
+	assert.Error(t, err)
+
+	_, err = o.Get("")
+	assert.Error(t, err)
+
+	o.Service = ""
+	_, err = o.Get("influxdb")
+	assert.Error(t, err)
+}
@@ -0,0 +1,11 @@
+// +build !darwin,!windows
+
+package os
+
+// lookupCommand returns the command printing the secret of the Secret
+// Service keyring, such as GNOME Keyring or KWallet, whose service and
+// account attributes are the service and the key
+func lookupCommand(service, key string) []string {
+	return []string{"secret-tool", "lookup", "service", service,
+		"account", key}
+}
@@ -0,0 +1,60 @@
+// +build windows
+
+package os
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	libadvapi32   = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW = libadvapi32.NewProc("CredReadW")
+	procCredFree  = libadvapi32.NewProc("CredFree")
+)
+
+const credTypeGeneric = 1
+
+// credential is the CREDENTIALW structure of the Credential Manager
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// lookupSecret reads the generic credential of the Credential Manager whose
+// target is "<service>:<key>", the secret being its password
+func lookupSecret(service, key string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)),
+		credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		return "", fmt.Errorf("reading credential %s:%s: %s", service, key,
+			err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	// The password is stored in UTF-16, as by cmdkey and the Credential
+	// Manager
+	n := int(cred.CredentialBlobSize / 2)
+	if n == 0 {
+		return "", nil
+	}
+	blob := (*[1 << 20]uint16)(unsafe.Pointer(cred.CredentialBlob))[:n:n]
+	return syscall.UTF16ToString(blob), nil
+}
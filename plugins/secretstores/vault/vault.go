@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  # Unique id of the store, secrets are referenced as @{<id>:<key>}
+  # The key is the path of the secret within the mount, optionally followed
+  # by "#<field>" to select a field other than the default field,
+  # ie, @{vault:telegraf/influxdb#password}
+  id = "vault"
+  # Address of the Vault server
+  address = "http://127.0.0.1:8200"
+  # Token used to authenticate with Vault
+  token = "${VAULT_TOKEN}"
+  # Mount path of the key/value secrets engine
+  mount = "secret"
+  # Version of the key/value secrets engine, 1 or 2
+  kv_version = 2
+  # Field of the secret used if the key doesn't select one
+  field = "value"
+  # Timeout of requests to Vault
+  timeout = "5s"
+`
+
+type Vault struct {
+	Address   string
+	Token     string
+	Mount     string
+	KVVersion int `toml:"kv_version"`
+	Field     string
+	Timeout   internal.Duration
+
+	client *http.Client
+}
+
+func NewVault() *Vault {
+	return &Vault{
+		Address:   "http://127.0.0.1:8200",
+		Mount:     "secret",
+		KVVersion: 2,
+		Field:     "value",
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func (v *Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vault) Description() string {
+	return "Read secrets from a HashiCorp Vault key/value secrets engine"
+}
+
+func (v *Vault) Get(key string) (string, error) {
+	path, field := key, v.Field
+	if i := strings.LastIndex(key, "#"); i >= 0 {
+		path, field = key[:i], key[i+1:]
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/%s", strings.TrimRight(v.Address, "/"),
+		strings.Trim(v.Mount, "/"), strings.TrimLeft(path, "/"))
+	if v.KVVersion == 2 {
+		url = fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(v.Address, "/"),
+			strings.Trim(v.Mount, "/"), strings.TrimLeft(path, "/"))
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	if v.client == nil {
+		v.client = &http.Client{Timeout: v.Timeout.Duration}
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reading secret %s from vault: %s", path,
+			resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+
+	data := body.Data
+	if v.KVVersion == 2 {
+		data, _ = body.Data["data"].(map[string]interface{})
+	}
+	val, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", path, field)
+	}
+	if s, ok := val.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", val), nil
+}
+
+func init() {
+	secretstores.Add("vault", func() telegraf.SecretStore {
+		return NewVault()
+	})
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetKVv2(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/telegraf/influxdb" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, `{"data":{"data":{"value":"secret","password":"hunter2"},"metadata":{"version":1}}}`)
+	}))
+	defer ts.Close()
+
+	v := NewVault()
+	v.Address = ts.URL
+	v.Token = "token"
+
+	val, err := v.Get("telegraf/influxdb")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", val)
+
+	val, err = v.Get("telegraf/influxdb#password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", val)
+
+	_, err = v.Get("telegraf/influxdb#missing")
+	assert.Error(t, err)
+
+	_, err = v.Get("telegraf/missing")
+	assert.Error(t, err)
+
+	v.Token = "wrong"
+	_, err = v.Get("telegraf/influxdb")
+	assert.Error(t, err)
+}
+
+func TestGetKVv1(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/telegraf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, `{"data":{"value":"secret"}}`)
+	}))
+	defer ts.Close()
+
+	v := NewVault()
+	v.Address = ts.URL
+	v.Mount = "kv"
+	v.KVVersion = 1
+
+	val, err := v.Get("telegraf")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", val)
+}
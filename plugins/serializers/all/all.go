@@ -0,0 +1,6 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/serializers/graphite"
+	_ "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
@@ -0,0 +1,173 @@
+package graphite
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// DefaultTemplate is the template of the paths if none is configured
+const DefaultTemplate = "host.tags.measurement.field"
+
+// sanitizer replaces the characters of the names and tag values which are
+// separators in the plaintext protocol
+var sanitizer = strings.NewReplacer(".", "_", " ", "_", "\t", "_", "\n", "_")
+
+// template is the template of the paths of the measurements matching filter,
+// a glob, a nil filter matching all of them
+type template struct {
+	filter *string
+	parts  []string
+}
+
+// Graphite serializes metrics in the graphite plaintext protocol, a line
+// "path value timestamp" per numeric or boolean field.
+//
+// The paths are built from templates, their parts separated by dots:
+// "measurement" is the name of the metric, "field" the name of the field,
+// omitted if it is the name of the metric, "tags" the values of the tags not
+// used by other parts, sorted by tag key, and any other part the value of
+// the tag of that key. The parts without a value are omitted.
+type Graphite struct {
+	Prefix    string
+	templates []template
+}
+
+// NewGraphite returns a serializer with the default template and the
+// templates, "filter template" with filter a glob of the measurements or
+// "template" matching all of them
+func NewGraphite(prefix string, defaultTemplate string,
+	templates []string) (*Graphite, error) {
+	if defaultTemplate == "" {
+		defaultTemplate = DefaultTemplate
+	}
+	g := &Graphite{Prefix: prefix}
+	for _, t := range templates {
+		words := strings.Fields(t)
+		switch len(words) {
+		case 1:
+			g.templates = append(g.templates,
+				template{parts: strings.Split(words[0], ".")})
+		case 2:
+			filter := words[0]
+			g.templates = append(g.templates, template{
+				filter: &filter,
+				parts:  strings.Split(words[1], "."),
+			})
+		default:
+			return nil, fmt.Errorf("invalid graphite template %q", t)
+		}
+	}
+	g.templates = append(g.templates,
+		template{parts: strings.Split(defaultTemplate, ".")})
+	for _, t := range g.templates {
+		for _, part := range t.parts {
+			if part == "" {
+				return nil, fmt.Errorf("empty part in graphite template %q",
+					strings.Join(t.parts, "."))
+			}
+		}
+	}
+	return g, nil
+}
+
+func (s *Graphite) Serialize(metric telegraf.Metric) ([]byte, error) {
+	var t template
+	for _, t = range s.templates {
+		if t.filter == nil || internal.Glob(*t.filter, metric.Name()) {
+			break
+		}
+	}
+	timestamp := metric.UnixNano() / 1000000000
+
+	fields := metric.Fields()
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		value, ok := formatValue(fields[name])
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s %s %d\n", s.path(t, metric, name), value,
+			timestamp)
+	}
+	return buf.Bytes(), nil
+}
+
+// path returns the path of the field of the metric built from the template
+func (s *Graphite) path(t template, metric telegraf.Metric,
+	field string) string {
+	tags := metric.Tags()
+	used := make(map[string]bool)
+	for _, part := range t.parts {
+		used[part] = true
+	}
+
+	var path []string
+	if s.Prefix != "" {
+		path = append(path, s.Prefix)
+	}
+	for _, part := range t.parts {
+		switch part {
+		case "measurement":
+			path = append(path, sanitizer.Replace(metric.Name()))
+		case "field":
+			if field != metric.Name() {
+				path = append(path, sanitizer.Replace(field))
+			}
+		case "tags":
+			var keys []string
+			for k, v := range tags {
+				if !used[k] && v != "" {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				path = append(path, sanitizer.Replace(tags[k]))
+			}
+		default:
+			if v := tags[part]; v != "" {
+				path = append(path, sanitizer.Replace(v))
+			}
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+// formatValue returns the value of a field in the plaintext protocol, false
+// if it has no numeric representation
+func formatValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	}
+	return "", false
+}
+
+func init() {
+	serializers.Add("graphite",
+		func(c *serializers.Config) (telegraf.Serializer, error) {
+			return NewGraphite(c.Prefix, c.Template, c.Templates)
+		})
+}
@@ -0,0 +1,99 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ts = time.Date(2010, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+func serialize(t *testing.T, s telegraf.Serializer, name string,
+	tags map[string]string, fields map[string]interface{}) string {
+	m, err := telegraf.NewMetric(name, tags, fields, ts)
+	require.NoError(t, err)
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestSerializeTags(t *testing.T) {
+	s, err := serializers.NewSerializer(&serializers.Config{
+		DataFormat: "graphite",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "192_168_0_1.mymeasurement.value 3.14 1289430000\n",
+		serialize(t, s, "mymeasurement",
+			map[string]string{"host": "192.168.0.1"},
+			map[string]interface{}{"value": 3.14}))
+	assert.Equal(t,
+		"192_168_0_1.first.second.mymeasurement.value 3.14 1289430000\n",
+		serialize(t, s, "mymeasurement",
+			map[string]string{"host": "192.168.0.1", "afoo": "first",
+				"bfoo": "second"},
+			map[string]interface{}{"value": 3.14}))
+	assert.Equal(t, "first.second.mymeasurement.value 3.14 1289430000\n",
+		serialize(t, s, "mymeasurement",
+			map[string]string{"afoo": "first", "bfoo": "second"},
+			map[string]interface{}{"value": 3.14}))
+}
+
+func TestSerializeFields(t *testing.T) {
+	s, err := NewGraphite("telegraf", "", nil)
+	require.NoError(t, err)
+
+	// The strings are not written, the field named after the measurement
+	// is omitted
+	assert.Equal(t, "telegraf.web01.disk_io 1 1289430000\n"+
+		"telegraf.web01.disk_io.enabled 1 1289430000\n"+
+		"telegraf.web01.disk_io.read_bytes 1024 1289430000\n"+
+		"telegraf.web01.disk_io.time 0.5 1289430000\n",
+		serialize(t, s, "disk.io", map[string]string{"host": "web01"},
+			map[string]interface{}{
+				"disk.io":    int64(1),
+				"enabled":    true,
+				"read bytes": int64(1024),
+				"time":       0.5,
+				"state":      "ok",
+			}))
+}
+
+func TestSerializeTemplates(t *testing.T) {
+	s, err := NewGraphite("", "measurement.field", []string{
+		"cpu tags.measurement.host.field",
+		"net* host.measurement.interface.field",
+	})
+	require.NoError(t, err)
+
+	tags := map[string]string{
+		"host":      "web01",
+		"cpu":       "cpu0",
+		"interface": "eth0",
+		"dc":        "",
+	}
+	fields := map[string]interface{}{"usage": int64(3)}
+	assert.Equal(t, "cpu0.eth0.cpu.web01.usage 3 1289430000\n",
+		serialize(t, s, "cpu", tags, fields))
+	assert.Equal(t, "web01.netstat.eth0.usage 3 1289430000\n",
+		serialize(t, s, "netstat", tags, fields))
+	assert.Equal(t, "mem.usage 3 1289430000\n",
+		serialize(t, s, "mem", tags, fields))
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	for _, templates := range [][]string{
+		{"cpu measurement.field extra"},
+		{"cpu measurement..field"},
+		{""},
+	} {
+		_, err := NewGraphite("", "", templates)
+		assert.Error(t, err, "%v", templates)
+	}
+	_, err := NewGraphite("", ".measurement", nil)
+	assert.Error(t, err)
+}
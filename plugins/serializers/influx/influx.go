@@ -0,0 +1,20 @@
+package influx
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Influx serializes metrics in line protocol
+type Influx struct{}
+
+func (s *Influx) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return []byte(metric.String() + "\n"), nil
+}
+
+func init() {
+	serializers.Add("influx",
+		func(c *serializers.Config) (telegraf.Serializer, error) {
+			return &Influx{}, nil
+		})
+}
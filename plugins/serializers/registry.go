@@ -0,0 +1,51 @@
+package serializers
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Config are the options of the serializers. The plugins writing data in a
+// data_format declare the options they support as their own fields and copy
+// them to a Config:
+//
+//	DataFormat string   `toml:"data_format"`
+//	Prefix     string   `toml:"prefix"`
+//	Template   string   `toml:"template"`
+//	Templates  []string `toml:"templates"`
+type Config struct {
+	// DataFormat is the name of the serializer
+	DataFormat string
+
+	// Prefix is prepended to the paths of the graphite format
+	Prefix string
+	// Template is the default template of the paths of the graphite format,
+	// "host.tags.measurement.field" if empty
+	Template string
+	// Templates are the templates of the paths of the graphite format for
+	// the measurements matching their filter, "filter template"
+	Templates []string
+}
+
+type Creator func(c *Config) (telegraf.Serializer, error)
+
+var Serializers = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Serializers[name] = creator
+}
+
+// NewSerializer returns the serializer of the data format of the config,
+// influx if not set.
+func NewSerializer(c *Config) (telegraf.Serializer, error) {
+	dataFormat := c.DataFormat
+	if dataFormat == "" {
+		dataFormat = "influx"
+	}
+	creator, ok := Serializers[dataFormat]
+	if !ok {
+		return nil, fmt.Errorf("unsupported data_format %q", dataFormat)
+	}
+	return creator(c)
+}
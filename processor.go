@@ -0,0 +1,33 @@
+package telegraf
+
+type Processor interface {
+	// SampleConfig returns the default configuration of the Processor
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Processor
+	Description() string
+
+	// Apply the processor to the given metrics, returning the metrics that
+	// should continue on to the outputs
+	Apply(in ...Metric) []Metric
+}
+
+type ServiceProcessor interface {
+	// SampleConfig returns the default configuration of the Processor
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Processor
+	Description() string
+
+	// Apply the processor to the given metrics, returning the metrics that
+	// should continue on to the outputs
+	Apply(in ...Metric) []Metric
+
+	// Start starts the ServiceProcessor's service, whatever that may be
+	Start() error
+
+	// Stop stops the services and closes any necessary channels and
+	// connections. The metrics the processor still holds once stopped are
+	// returned by a last call to Apply without metrics.
+	Stop()
+}
@@ -0,0 +1,13 @@
+package telegraf
+
+type SecretStore interface {
+	// SampleConfig returns the default configuration of the SecretStore
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the SecretStore
+	Description() string
+
+	// Get returns the secret stored under the given key. Secrets are
+	// referenced in the config as @{id:key}, where id is the id of the store
+	Get(key string) (string, error)
+}
@@ -0,0 +1,206 @@
+// Package selfstat is a registry of statistics about telegraf and its
+// plugins, such as the bytes sent by an output or the lines an input failed
+// to parse. The registered statistics are reported by the internal input, as
+// measurements prefixed with "internal_".
+//
+// Plugins register their statistics when they start, update them while
+// running and unregister them when they stop:
+//
+//	s.parseErrors = selfstat.Register("statsd", "parse_errors",
+//		map[string]string{"address": s.ServiceAddress})
+//	...
+//	s.parseErrors.Incr(1)
+//	...
+//	selfstat.Unregister(s.parseErrors)
+package selfstat
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Stat is a statistic, a field of a measurement reported by the internal
+// input. It is safe for concurrent use.
+type Stat interface {
+	// Name is the name of the measurement, without the "internal_" prefix
+	Name() string
+
+	// FieldName is the name of the field of the measurement
+	FieldName() string
+
+	// Tags are the tags of the measurement
+	Tags() map[string]string
+
+	// Type is the value type of the field, counter or gauge
+	Type() telegraf.ValueType
+
+	// Incr adds v to the value of the statistic
+	Incr(v int64)
+
+	// Set sets the value of the statistic
+	Set(v int64)
+
+	// Get returns the value of the statistic
+	Get() int64
+}
+
+var (
+	registryLock sync.Mutex
+	// registry maps the hash of the measurement and tags of the statistics to
+	// the statistics by field name
+	registry = make(map[uint64]map[string]*stat)
+)
+
+// Register registers a counter, a statistic only increasing, like a number of
+// errors. Registering a statistic of the same measurement, field and tags as
+// a registered one returns the registered statistic.
+func Register(measurement, field string, tags map[string]string) Stat {
+	return register(measurement, field, tags, telegraf.Counter)
+}
+
+// RegisterGauge registers a gauge, a statistic going up and down, like the
+// size of a queue, see Register.
+func RegisterGauge(measurement, field string, tags map[string]string) Stat {
+	return register(measurement, field, tags, telegraf.Gauge)
+}
+
+// Unregister removes the statistic from the registry, so that it is not
+// reported anymore, ie when its plugin is stopped.
+func Unregister(s Stat) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	key := hashID(s.Name(), s.Tags())
+	if fields, ok := registry[key]; ok {
+		delete(fields, s.FieldName())
+		if len(fields) == 0 {
+			delete(registry, key)
+		}
+	}
+}
+
+// Metrics returns the metrics of the registered statistics, the statistics of
+// the same measurement, tags and type being fields of one metric.
+func Metrics() []telegraf.Metric {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	now := time.Now()
+	var metrics []telegraf.Metric
+	for _, fields := range registry {
+		byType := make(map[telegraf.ValueType][]*stat)
+		for _, s := range fields {
+			byType[s.valueType] = append(byType[s.valueType], s)
+		}
+		for valueType, stats := range byType {
+			values := make(map[string]interface{}, len(stats))
+			for _, s := range stats {
+				values[s.field] = s.Get()
+			}
+			m, err := telegraf.NewTypedMetric(valueType,
+				"internal_"+stats[0].measurement, stats[0].Tags(), values, now)
+			if err != nil {
+				continue
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+func register(
+	measurement string,
+	field string,
+	tags map[string]string,
+	valueType telegraf.ValueType,
+) Stat {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	key := hashID(measurement, tags)
+	fields, ok := registry[key]
+	if !ok {
+		fields = make(map[string]*stat)
+		registry[key] = fields
+	}
+	if s, ok := fields[field]; ok {
+		return s
+	}
+
+	s := &stat{
+		measurement: measurement,
+		field:       field,
+		tags:        make(map[string]string, len(tags)),
+		valueType:   valueType,
+	}
+	for k, v := range tags {
+		s.tags[k] = v
+	}
+	fields[field] = s
+	return s
+}
+
+// hashID identifies the measurement and tags of a statistic
+func hashID(measurement string, tags map[string]string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(measurement))
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte("\n"))
+		h.Write([]byte(k))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tags[k]))
+	}
+	return h.Sum64()
+}
+
+type stat struct {
+	value       int64
+	measurement string
+	field       string
+	tags        map[string]string
+	valueType   telegraf.ValueType
+}
+
+func (s *stat) Name() string {
+	return s.measurement
+}
+
+func (s *stat) FieldName() string {
+	return s.field
+}
+
+// Tags returns a copy of the tags of the statistic
+func (s *stat) Tags() map[string]string {
+	tags := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func (s *stat) Type() telegraf.ValueType {
+	return s.valueType
+}
+
+func (s *stat) Incr(v int64) {
+	atomic.AddInt64(&s.value, v)
+}
+
+func (s *stat) Set(v int64) {
+	atomic.StoreInt64(&s.value, v)
+}
+
+func (s *stat) Get() int64 {
+	return atomic.LoadInt64(&s.value)
+}
@@ -0,0 +1,102 @@
+package selfstat
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetRegistry() {
+	registryLock.Lock()
+	registry = make(map[uint64]map[string]*stat)
+	registryLock.Unlock()
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistry()
+	tags := map[string]string{"address": ":8125"}
+
+	s := Register("statsd", "parse_errors", tags)
+	s.Incr(2)
+	s.Incr(1)
+	assert.Equal(t, int64(3), s.Get())
+	assert.Equal(t, "statsd", s.Name())
+	assert.Equal(t, "parse_errors", s.FieldName())
+	assert.Equal(t, tags, s.Tags())
+	assert.Equal(t, telegraf.Counter, s.Type())
+
+	// The statistic is shared by the plugins registering it
+	same := Register("statsd", "parse_errors",
+		map[string]string{"address": ":8125"})
+	assert.Equal(t, int64(3), same.Get())
+
+	other := Register("statsd", "parse_errors",
+		map[string]string{"address": ":8126"})
+	assert.Equal(t, int64(0), other.Get())
+
+	g := RegisterGauge("statsd", "queue_size", tags)
+	g.Set(10)
+	g.Set(5)
+	assert.Equal(t, int64(5), g.Get())
+	assert.Equal(t, telegraf.Gauge, g.Type())
+}
+
+func TestMetrics(t *testing.T) {
+	resetRegistry()
+	tags := map[string]string{"address": ":8125"}
+	Register("statsd", "parse_errors", tags).Incr(1)
+	Register("statsd", "packets_dropped", tags).Incr(2)
+	RegisterGauge("statsd", "queue_size", tags).Set(3)
+
+	metrics := Metrics()
+	require.Len(t, metrics, 2)
+	for _, m := range metrics {
+		assert.Equal(t, "internal_statsd", m.Name())
+		assert.Equal(t, tags, m.Tags())
+		switch m.Type() {
+		case telegraf.Counter:
+			assert.Equal(t, map[string]interface{}{
+				"parse_errors":    int64(1),
+				"packets_dropped": int64(2),
+			}, m.Fields())
+		case telegraf.Gauge:
+			assert.Equal(t, map[string]interface{}{
+				"queue_size": int64(3),
+			}, m.Fields())
+		default:
+			t.Errorf("unexpected value type %s", m.Type())
+		}
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	resetRegistry()
+	s := Register("execd", "parse_errors", nil)
+	s.Incr(1)
+	Unregister(s)
+	assert.Len(t, Metrics(), 0)
+
+	// Registering it again starts from zero
+	assert.Equal(t, int64(0), Register("execd", "parse_errors", nil).Get())
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	resetRegistry()
+	s := Register("test", "count", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Incr(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), s.Get())
+}
@@ -0,0 +1,8 @@
+package telegraf
+
+type Serializer interface {
+	// Serialize returns the data of the metric in the data format of the
+	// Serializer, written by an output. The data of the line based formats
+	// ends with a newline.
+	Serialize(metric Metric) ([]byte, error)
+}
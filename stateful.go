@@ -0,0 +1,17 @@
+package telegraf
+
+// StatefulPlugin is implemented by plugins keeping a state which should
+// survive a restart of telegraf, such as the offsets of the files they read
+// or the position of a consumer, so that they resume where they left off
+// instead of reading data again or missing it.
+type StatefulPlugin interface {
+	// GetState returns the current state of the plugin, called when
+	// telegraf stops. The state must be serializable to JSON.
+	GetState() interface{}
+
+	// SetState restores the state returned by GetState before the last
+	// stop, called before the plugin is started or gathered. The state has
+	// the type of the value returned by GetState, a pointer to it if
+	// GetState returns a pointer.
+	SetState(state interface{}) error
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,6 +18,7 @@ type Metric struct {
 	Tags        map[string]string
 	Fields      map[string]interface{}
 	Time        time.Time
+	Type        telegraf.ValueType
 }
 
 func (p *Metric) String() string {
@@ -48,6 +50,36 @@ func (a *Accumulator) AddFields(
 	fields map[string]interface{},
 	tags map[string]string,
 	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Untyped, timestamp...)
+}
+
+// AddGauge adds a measurement point of gauges
+func (a *Accumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Gauge, timestamp...)
+}
+
+// AddCounter adds a measurement point of counters
+func (a *Accumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Counter, timestamp...)
+}
+
+func (a *Accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	valueType telegraf.ValueType,
+	timestamp ...time.Time,
 ) {
 	a.Lock()
 	defer a.Unlock()
@@ -79,6 +111,7 @@ func (a *Accumulator) AddFields(
 		Fields:      fields,
 		Tags:        tags,
 		Time:        t,
+		Type:        valueType,
 	}
 
 	a.Metrics = append(a.Metrics, p)
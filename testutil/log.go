@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"fmt"
+	"log"
+)
+
+// Logger is a telegraf.Logger for plugins under test, logging all levels
+// through the standard logger.
+type Logger struct {
+	Name string
+}
+
+func (l Logger) output(level string, msg string) {
+	log.Print(level + " [" + l.Name + "] " + msg)
+}
+
+// Errorf logs an error message, patterned after log.Printf.
+func (l Logger) Errorf(format string, args ...interface{}) {
+	l.output("E!", fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message, patterned after log.Print.
+func (l Logger) Error(args ...interface{}) {
+	l.output("E!", fmt.Sprint(args...))
+}
+
+// Warnf logs a warning message, patterned after log.Printf.
+func (l Logger) Warnf(format string, args ...interface{}) {
+	l.output("W!", fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message, patterned after log.Print.
+func (l Logger) Warn(args ...interface{}) {
+	l.output("W!", fmt.Sprint(args...))
+}
+
+// Infof logs an information message, patterned after log.Printf.
+func (l Logger) Infof(format string, args ...interface{}) {
+	l.output("I!", fmt.Sprintf(format, args...))
+}
+
+// Info logs an information message, patterned after log.Print.
+func (l Logger) Info(args ...interface{}) {
+	l.output("I!", fmt.Sprint(args...))
+}
+
+// Debugf logs a debug message, patterned after log.Printf.
+func (l Logger) Debugf(format string, args ...interface{}) {
+	l.output("D!", fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message, patterned after log.Print.
+func (l Logger) Debug(args ...interface{}) {
+	l.output("D!", fmt.Sprint(args...))
+}